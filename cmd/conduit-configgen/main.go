@@ -0,0 +1,611 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schema is the subset of JSON schema describing plugin configs, extended
+// with x-go-name and x-go-type to control the generated fields.
+type schema struct {
+	Title                string            `json:"title"`
+	Description          string            `json:"description"`
+	Type                 string            `json:"type"`
+	Format               string            `json:"format"`
+	Properties           properties        `json:"properties"`
+	Required             []string          `json:"required"`
+	Items                *schema           `json:"items"`
+	AdditionalProperties *schema           `json:"additionalProperties"`
+	Default              json.RawMessage   `json:"default"`
+	Enum                 []json.RawMessage `json:"enum"`
+	Pattern              string            `json:"pattern"`
+	Minimum              *float64          `json:"minimum"`
+	Maximum              *float64          `json:"maximum"`
+	MinItems             *int              `json:"minItems"`
+	MaxItems             *int              `json:"maxItems"`
+
+	// GoName is the name of the field, by default the key in camel case.
+	GoName string `json:"x-go-name"`
+	// GoType overrides the type of scalar fields, e.g. uint64.
+	GoType string `json:"x-go-type"`
+	// PluginName is the conduit-docs file name of the top level config.
+	PluginName string `json:"x-plugin-name"`
+}
+
+type property struct {
+	key    string
+	schema *schema
+}
+
+// properties keeps the properties in the order of the schema file, which is
+// the order of the generated fields.
+type properties []property
+
+func (p *properties) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("properties must be an object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var s schema
+		if err := dec.Decode(&s); err != nil {
+			return fmt.Errorf("%s: %w", tok, err)
+		}
+		*p = append(*p, property{key: tok.(string), schema: &s})
+	}
+	return nil
+}
+
+// scalarTypes are the allowed x-go-type values by schema type.
+var scalarTypes = map[string][]string{
+	"string":  {"string"},
+	"integer": {"int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64"},
+	"number":  {"float64", "float32"},
+	"boolean": {"bool"},
+}
+
+// initialisms are upper cased in field names.
+var initialisms = map[string]bool{
+	"api": true, "db": true, "http": true, "id": true, "ip": true, "json": true,
+	"sql": true, "tcp": true, "tls": true, "url": true,
+}
+
+// goName converts a yaml key such as "connection-string" to a field name.
+func goName(key string) string {
+	var name string
+	for _, word := range strings.FieldsFunc(key, func(r rune) bool { return r == '-' || r == '_' }) {
+		if initialisms[word] {
+			name += strings.ToUpper(word)
+		} else {
+			name += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return name
+}
+
+// field is a generated struct field.
+type field struct {
+	key    string
+	name   string
+	goType string
+	schema *schema
+	// object is the generated type of object fields and arrays of objects.
+	object *object
+	// required is set by the parent's required list.
+	required bool
+}
+
+// object is a generated struct type.
+type object struct {
+	name   string
+	doc    string
+	fields []*field
+}
+
+type generator struct {
+	objects []*object
+	imports map[string]bool
+	// patterns are the package level regexps, in order.
+	patterns []pattern
+}
+
+type pattern struct {
+	name string
+	expr string
+}
+
+// addObject adds the struct type of an object schema and its nested objects.
+func (g *generator) addObject(path, name string, s *schema) (*object, error) {
+	obj := &object{name: name, doc: s.Description}
+	if path != "" {
+		// The description of a nested object is the doc of its field.
+		obj.doc = fmt.Sprintf("%s is the config of <code>%s</code>.", name, strings.TrimSuffix(path, "[]"))
+	}
+	g.objects = append(g.objects, obj)
+	required := make(map[string]bool)
+	for _, key := range s.Required {
+		required[key] = true
+	}
+	for _, p := range s.Properties {
+		f, err := g.makeField(joinPath(path, p.key), p.key, p.schema)
+		if err != nil {
+			return nil, err
+		}
+		f.required = required[p.key]
+		delete(required, p.key)
+		if err := checkField(f); err != nil {
+			return nil, fmt.Errorf("%s: %w", joinPath(path, p.key), err)
+		}
+		obj.fields = append(obj.fields, f)
+	}
+	for key := range required {
+		return nil, fmt.Errorf("%s: required key is not a property", joinPath(path, key))
+	}
+	return obj, nil
+}
+
+func (g *generator) makeField(path, key string, s *schema) (*field, error) {
+	f := &field{key: key, name: s.GoName, schema: s}
+	if f.name == "" {
+		f.name = goName(key)
+	}
+	var err error
+	f.goType, f.object, err = g.goType(path, f.name, s)
+	return f, err
+}
+
+// goType returns the type of the field, adding the struct types of objects.
+func (g *generator) goType(path, name string, s *schema) (string, *object, error) {
+	switch s.Type {
+	case "string", "integer", "number", "boolean":
+		if s.Type == "string" && s.Format == "duration" {
+			g.imports["time"] = true
+			return "time.Duration", nil, nil
+		}
+		if s.GoType == "" {
+			return map[string]string{"string": "string", "integer": "int", "number": "float64", "boolean": "bool"}[s.Type], nil, nil
+		}
+		for _, t := range scalarTypes[s.Type] {
+			if t == s.GoType {
+				return t, nil, nil
+			}
+		}
+		return "", nil, fmt.Errorf("%s: x-go-type %s is not a %s type", path, s.GoType, s.Type)
+	case "array":
+		if s.Items == nil {
+			return "", nil, fmt.Errorf("%s: arrays require items", path)
+		}
+		t, obj, err := g.goType(path+"[]", name+"Item", s.Items)
+		return "[]" + t, obj, err
+	case "object":
+		if len(s.Properties) > 0 {
+			typeName := s.Title
+			if typeName == "" {
+				typeName = name + "Config"
+			}
+			obj, err := g.addObject(path, typeName, s)
+			return typeName, obj, err
+		}
+		if s.AdditionalProperties == nil {
+			return "map[string]interface{}", nil, nil
+		}
+		if s.AdditionalProperties.Type == "object" || s.AdditionalProperties.Type == "array" {
+			return "", nil, fmt.Errorf("%s: only maps of scalars are supported", path)
+		}
+		t, _, err := g.goType(path, name, s.AdditionalProperties)
+		return "map[string]" + t, nil, err
+	default:
+		return "", nil, fmt.Errorf("%s: unsupported type '%s'", path, s.Type)
+	}
+}
+
+// checkField rejects the keywords which do not apply to the field.
+func checkField(f *field) error {
+	s := f.schema
+	switch {
+	case f.required && (s.Type == "boolean" || f.object != nil && s.Type == "object"):
+		return fmt.Errorf("%s fields cannot be required", s.Type)
+	case s.Default != nil && !isScalar(s):
+		return fmt.Errorf("only scalar fields have defaults")
+	case s.Default != nil && s.Type == "boolean":
+		return fmt.Errorf("boolean fields cannot have defaults, the zero value is false")
+	case s.Pattern != "" && s.Type != "string":
+		return fmt.Errorf("only strings have patterns, the pattern of array items is in items")
+	case (s.Minimum != nil || s.Maximum != nil) && s.Type != "integer" && s.Type != "number" && f.goType != "time.Duration":
+		return fmt.Errorf("only numbers and durations have a minimum or maximum")
+	case (s.MinItems != nil || s.MaxItems != nil) && s.Type != "array":
+		return fmt.Errorf("only arrays have minItems or maxItems")
+	case len(s.Enum) > 0 && !isScalar(s):
+		return fmt.Errorf("only scalar fields have an enum")
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	if s.Default != nil {
+		if _, err := literal(f.goType, s.Default); err != nil {
+			return fmt.Errorf("invalid default: %w", err)
+		}
+	}
+	for _, v := range s.Enum {
+		if _, err := literal(f.goType, v); err != nil {
+			return fmt.Errorf("invalid enum value: %w", err)
+		}
+	}
+	return nil
+}
+
+func isScalar(s *schema) bool {
+	return s.Type == "string" || s.Type == "integer" || s.Type == "number" || s.Type == "boolean"
+}
+
+// literal returns the Go literal of a JSON value of the type.
+func literal(goType string, v json.RawMessage) (string, error) {
+	switch {
+	case goType == "time.Duration":
+		var str string
+		if err := json.Unmarshal(v, &str); err != nil {
+			return "", err
+		}
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return "", err
+		}
+		return durationLiteral(d), nil
+	case goType == "string":
+		var str string
+		if err := json.Unmarshal(v, &str); err != nil {
+			return "", err
+		}
+		return strconv.Quote(str), nil
+	case goType == "bool":
+		var b bool
+		err := json.Unmarshal(v, &b)
+		return strconv.FormatBool(b), err
+	case strings.HasPrefix(goType, "float"):
+		var n float64
+		err := json.Unmarshal(v, &n)
+		return strconv.FormatFloat(n, 'g', -1, 64), err
+	default:
+		var n json.Number
+		if err := json.Unmarshal(v, &n); err != nil {
+			return "", err
+		}
+		if _, err := strconv.ParseInt(n.String(), 10, 64); err != nil {
+			if _, err := strconv.ParseUint(n.String(), 10, 64); err != nil {
+				return "", fmt.Errorf("%s is not an integer", n)
+			}
+		}
+		return n.String(), nil
+	}
+}
+
+func durationLiteral(d time.Duration) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{{time.Hour, "time.Hour"}, {time.Minute, "time.Minute"}, {time.Second, "time.Second"}, {time.Millisecond, "time.Millisecond"}}
+	for _, u := range units {
+		if d != 0 && d%u.unit == 0 {
+			return fmt.Sprintf("%d * %s", d/u.unit, u.name)
+		}
+	}
+	return fmt.Sprintf("%d", d)
+}
+
+// bound returns the literal of a minimum or maximum, durations are in seconds.
+func bound(goType string, v float64) (lit string, display string) {
+	switch {
+	case goType == "time.Duration":
+		d := time.Duration(v * float64(time.Second))
+		return durationLiteral(d), d.String()
+	case strings.HasPrefix(goType, "float"):
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		return s, s
+	default:
+		s := strconv.FormatFloat(v, 'f', 0, 64)
+		return s, s
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// code accumulates the generated source.
+type code struct {
+	bytes.Buffer
+}
+
+func (c *code) line(format string, args ...interface{}) {
+	fmt.Fprintf(&c.Buffer, format+"\n", args...)
+}
+
+// comment writes a doc comment in the style of the hand written configs,
+// multi-line descriptions are in a block comment.
+func (c *code) comment(indent, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if !strings.Contains(text, "\n") {
+		c.line("%s// %s", indent, text)
+		return
+	}
+	c.line("%s/* %s", indent, strings.ReplaceAll(text, "\n", "\n"+indent))
+	c.line("%s*/", indent)
+}
+
+func (g *generator) patternName(obj *object, f *field, s *schema) string {
+	name := strings.ToLower(obj.name[:1]) + obj.name[1:] + f.name + "Pattern"
+	g.patterns = append(g.patterns, pattern{name: name, expr: s.Pattern})
+	return name
+}
+
+// zero returns the zero value comparison of a scalar type.
+func zero(goType string) string {
+	if goType == "string" {
+		return `""`
+	}
+	return "0"
+}
+
+// writeChecks writes the checks of a scalar value at the path expression.
+func (g *generator) writeChecks(c *code, obj *object, f *field, s *schema, goType, value, path string) {
+	if s.Pattern != "" {
+		name := g.patternName(obj, f, s)
+		c.line("if %s != \"\" && !%s.MatchString(%s) {", value, name, value)
+		c.line("errs.Addf(%s, \"must match %%s\", %s)", path, name)
+		c.line("}")
+	}
+	if len(s.Enum) > 0 {
+		var conds, values []string
+		for _, v := range s.Enum {
+			lit, _ := literal(goType, v)
+			conds = append(conds, fmt.Sprintf("%s != %s", value, lit))
+			values = append(values, strings.Trim(string(v), `"`))
+		}
+		c.line("if %s != %s && %s {", value, zero(goType), strings.Join(conds, " && "))
+		c.line("errs.Addf(%s, %q)", path, "must be one of "+strings.ReplaceAll(strings.Join(values, ", "), "%", "%%"))
+		c.line("}")
+	}
+	// The minimum of unsigned fields is at least 0.
+	if s.Minimum != nil && !(*s.Minimum <= 0 && strings.HasPrefix(goType, "uint")) {
+		lit, display := bound(goType, *s.Minimum)
+		c.line("if %s < %s {", value, lit)
+		c.line("errs.Addf(%s, %q)", path, "must be at least "+display)
+		c.line("}")
+	}
+	if s.Maximum != nil {
+		lit, display := bound(goType, *s.Maximum)
+		c.line("if %s > %s {", value, lit)
+		c.line("errs.Addf(%s, %q)", path, "must be at most "+display)
+		c.line("}")
+	}
+}
+
+func (g *generator) writeObject(c *code, obj *object) {
+	c.comment("", obj.doc)
+	c.line("type %s struct {", obj.name)
+	for _, f := range obj.fields {
+		c.comment("\t", "<code>"+f.key+"</code> "+f.schema.Description)
+		c.line("\t%s %s `yaml:\"%s\"`", f.name, f.goType, f.key)
+	}
+	c.line("}")
+	c.line("")
+
+	c.line("// SetDefaults fills in the defaults of the fields which are not set.")
+	c.line("func (cfg *%s) SetDefaults() {", obj.name)
+	for _, f := range obj.fields {
+		value := "cfg." + f.name
+		switch {
+		case f.schema.Default != nil:
+			lit, _ := literal(f.goType, f.schema.Default)
+			c.line("if %s == %s {", value, zero(f.goType))
+			c.line("%s = %s", value, lit)
+			c.line("}")
+		case f.object != nil && f.schema.Type == "object":
+			c.line("%s.SetDefaults()", value)
+		case f.object != nil:
+			c.line("for i := range %s {", value)
+			c.line("%s[i].SetDefaults()", value)
+			c.line("}")
+		}
+	}
+	c.line("}")
+	c.line("")
+
+	c.line("// Validate checks the fields, the errors are plugins.FieldErrors.")
+	c.line("func (cfg *%s) Validate() error {", obj.name)
+	c.line("var errs plugins.FieldErrors")
+	for _, f := range obj.fields {
+		s := f.schema
+		value := "cfg." + f.name
+		path := strconv.Quote(f.key)
+		if f.required {
+			if s.Type == "array" || s.Type == "object" {
+				c.line("if len(%s) == 0 {", value)
+			} else {
+				c.line("if %s == %s {", value, zero(f.goType))
+			}
+			c.line("errs.Addf(%s, \"is required\")", path)
+			c.line("}")
+		}
+		switch s.Type {
+		case "object":
+			if f.object != nil {
+				c.line("errs.Merge(%s, %s.Validate())", path, value)
+			}
+		case "array":
+			if s.MinItems != nil {
+				c.line("if len(%s) < %d {", value, *s.MinItems)
+				c.line("errs.Addf(%s, \"must have at least %d items\")", path, *s.MinItems)
+				c.line("}")
+			}
+			if s.MaxItems != nil {
+				c.line("if len(%s) > %d {", value, *s.MaxItems)
+				c.line("errs.Addf(%s, \"must have at most %d items\")", path, *s.MaxItems)
+				c.line("}")
+			}
+			itemPath := fmt.Sprintf("fmt.Sprintf(\"%s[%%d]\", i)", f.key)
+			if f.object != nil {
+				g.imports["fmt"] = true
+				c.line("for i := range %s {", value)
+				c.line("errs.Merge(%s, %s[i].Validate())", itemPath, value)
+				c.line("}")
+			} else if s.Items.Pattern != "" || len(s.Items.Enum) > 0 || s.Items.Minimum != nil || s.Items.Maximum != nil {
+				g.imports["fmt"] = true
+				c.line("for i, item := range %s {", value)
+				g.writeChecks(c, obj, f, s.Items, strings.TrimPrefix(f.goType, "[]"), "item", itemPath)
+				c.line("}")
+			}
+		default:
+			g.writeChecks(c, obj, f, s, f.goType, value, path)
+		}
+	}
+	c.line("return errs.Err()")
+	c.line("}")
+	c.line("")
+}
+
+// rootDir returns the relative path from dir to the directory with go.mod.
+func rootDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	rel := "."
+	for {
+		if _, err := os.Stat(filepath.Join(abs, "go.mod")); err == nil {
+			return filepath.ToSlash(rel), nil
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return "", fmt.Errorf("go.mod not found above %s", dir)
+		}
+		abs = parent
+		rel = filepath.Join(rel, "..")
+	}
+}
+
+// generate returns the source of the config types described by the schema.
+func generate(schemaBytes []byte, pkg string, root string) ([]byte, error) {
+	var s schema
+	if err := json.Unmarshal(schemaBytes, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if s.Type != "object" || len(s.Properties) == 0 {
+		return nil, fmt.Errorf("the schema must be an object with properties")
+	}
+	name := s.Title
+	if name == "" {
+		name = "Config"
+	}
+	g := &generator{imports: map[string]bool{"github.com/algorand/conduit/conduit/plugins": true}}
+	if _, err := g.addObject("", name, &s); err != nil {
+		return nil, err
+	}
+
+	var body code
+	for _, obj := range g.objects {
+		g.writeObject(&body, obj)
+	}
+
+	var c code
+	c.line("// Code generated by conduit-configgen. DO NOT EDIT.")
+	c.line("")
+	c.line("package %s", pkg)
+	c.line("")
+	if s.PluginName != "" {
+		c.line("//go:generate go run %s/cmd/conduit-docs/main.go %s/conduit-docs/", root, root)
+		c.line("")
+		c.line("//PluginName: %s", s.PluginName)
+		c.line("")
+	}
+	if len(g.patterns) > 0 {
+		g.imports["regexp"] = true
+	}
+	// The standard library imports are grouped before the others.
+	var std, other []string
+	for imp := range g.imports {
+		if strings.Contains(imp, ".") {
+			other = append(other, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	c.line("import (")
+	for _, imp := range std {
+		c.line("%q", imp)
+	}
+	c.line("")
+	for _, imp := range other {
+		c.line("%q", imp)
+	}
+	c.line(")")
+	c.line("")
+	if len(g.patterns) > 0 {
+		c.line("var (")
+		for _, p := range g.patterns {
+			expr := "`" + p.expr + "`"
+			if strings.Contains(p.expr, "`") {
+				expr = strconv.Quote(p.expr)
+			}
+			c.line("%s = regexp.MustCompile(%s)", p.name, expr)
+		}
+		c.line(")")
+		c.line("")
+	}
+	c.Write(body.Bytes())
+	return format.Source(c.Bytes())
+}
+
+func main() {
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package of the generated file, defaults to $GOPACKAGE")
+	flag.Parse()
+
+	usage := "USAGE: //go:generate conduit-configgen [-package name] <schema.json> <output.go>"
+	if len(flag.Args()) != 2 || *pkg == "" {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+	err := func() error {
+		schemaBytes, err := os.ReadFile(flag.Arg(0))
+		if err != nil {
+			return err
+		}
+		root, err := rootDir(filepath.Dir(flag.Arg(1)))
+		if err != nil {
+			return err
+		}
+		src, err := generate(schemaBytes, *pkg, root)
+		if err != nil {
+			return fmt.Errorf("%s: %w", flag.Arg(0), err)
+		}
+		return os.WriteFile(flag.Arg(1), src, 0644)
+	}()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
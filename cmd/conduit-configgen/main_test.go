@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+  "description": "Config of the test plugin",
+  "type": "object",
+  "x-plugin-name": "conduit_exporters_test",
+  "required": ["connection-string", "brokers"],
+  "properties": {
+    "connection-string": {"description": "is required.", "type": "string"},
+    "mode": {"description": "is the mode.", "type": "string", "enum": ["fast", "slow"], "default": "fast"},
+    "poll-interval": {"description": "is the delay.\nDefault: 1s.", "type": "string", "format": "duration", "default": "1s", "minimum": 0.5},
+    "retain-rounds": {"type": "integer", "x-go-type": "uint64", "minimum": 0, "maximum": 1000},
+    "tls": {"type": "object", "properties": {"cert-file": {"type": "string"}}, "required": ["cert-file"]},
+    "brokers": {"type": "array", "minItems": 1, "items": {"type": "string", "pattern": "^[a-z]+:[0-9]+$"}},
+    "routes": {"type": "array", "items": {"title": "Route", "type": "object", "properties": {"table-id": {"type": "integer"}}}},
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+  }
+}`
+
+func TestGenerate(t *testing.T) {
+	src, err := generate([]byte(testSchema), "example", "../..")
+	require.NoError(t, err)
+	out := string(src)
+
+	expected := []string{
+		"// Code generated by conduit-configgen. DO NOT EDIT.",
+		"//go:generate go run ../../cmd/conduit-docs/main.go ../../conduit-docs/",
+		"//PluginName: conduit_exporters_test",
+		"configBrokersPattern = regexp.MustCompile(`^[a-z]+:[0-9]+$`)",
+		"// Config of the test plugin\ntype Config struct {",
+		"\t// <code>connection-string</code> is required.\n\tConnectionString string `yaml:\"connection-string\"`",
+		"\t/* <code>poll-interval</code> is the delay.\n\tDefault: 1s.\n\t*/\n\tPollInterval time.Duration `yaml:\"poll-interval\"`",
+		"RetainRounds uint64 `yaml:\"retain-rounds\"`",
+		"TLS TLSConfig `yaml:\"tls\"`",
+		"Routes []Route `yaml:\"routes\"`",
+		"Labels map[string]string `yaml:\"labels\"`",
+		"// TLSConfig is the config of <code>tls</code>.\ntype TLSConfig struct {",
+		"// Route is the config of <code>routes</code>.\ntype Route struct {",
+		"TableID int `yaml:\"table-id\"`",
+		"cfg.Mode = \"fast\"",
+		"cfg.PollInterval = 1 * time.Second",
+		"cfg.TLS.SetDefaults()",
+		"cfg.Routes[i].SetDefaults()",
+		"errs.Addf(\"connection-string\", \"is required\")",
+		"if cfg.Mode != \"\" && cfg.Mode != \"fast\" && cfg.Mode != \"slow\" {",
+		"errs.Addf(\"mode\", \"must be one of fast, slow\")",
+		"if cfg.PollInterval < 500*time.Millisecond {",
+		"errs.Addf(\"poll-interval\", \"must be at least 500ms\")",
+		"errs.Addf(\"retain-rounds\", \"must be at most 1000\")",
+		"errs.Merge(\"tls\", cfg.TLS.Validate())",
+		"errs.Addf(\"brokers\", \"must have at least 1 items\")",
+		"errs.Addf(fmt.Sprintf(\"brokers[%d]\", i), \"must match %s\", configBrokersPattern)",
+		"errs.Merge(fmt.Sprintf(\"routes[%d]\", i), cfg.Routes[i].Validate())",
+		"errs.Addf(\"cert-file\", \"is required\")",
+	}
+	for _, e := range expected {
+		assert.Contains(t, out, e)
+	}
+	// The minimum of unsigned fields is not checked.
+	assert.NotContains(t, out, "cfg.RetainRounds < 0")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{"not an object", `{"type": "string"}`, "the schema must be an object with properties"},
+		{"invalid json", `{"type": `, "invalid schema: unexpected end of JSON input"},
+		{"unknown type", `{"type": "object", "properties": {"a": {"type": "date"}}}`, "a: unsupported type 'date'"},
+		{"go type", `{"type": "object", "properties": {"a": {"type": "integer", "x-go-type": "string"}}}`, "a: x-go-type string is not a integer type"},
+		{"required", `{"type": "object", "required": ["b"], "properties": {"a": {"type": "string"}}}`, "b: required key is not a property"},
+		{"default", `{"type": "object", "properties": {"a": {"type": "string", "format": "duration", "default": "soon"}}}`, `a: invalid default: time: invalid duration "soon"`},
+		{"boolean default", `{"type": "object", "properties": {"a": {"type": "boolean", "default": true}}}`, "a: boolean fields cannot have defaults, the zero value is false"},
+		{"pattern", `{"type": "object", "properties": {"a": {"type": "string", "pattern": "("}}}`, "a: invalid pattern: error parsing regexp: missing closing ): `(`"},
+		{"nested", `{"type": "object", "properties": {"a": {"type": "object", "properties": {"b": {"type": "integer", "pattern": "x"}}}}}`, "a.b: only strings have patterns, the pattern of array items is in items"},
+		{"array", `{"type": "object", "properties": {"a": {"type": "array"}}}`, "a: arrays require items"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := generate([]byte(tc.schema), "example", ".")
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
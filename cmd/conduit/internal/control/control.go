@@ -0,0 +1,167 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/pipeline"
+)
+
+// PauseCommand is the pause command to embed in a root cobra command.
+var PauseCommand = makeCommand("pause", "pauses a running pipeline before its next round", `Pauses a running conduit pipeline through its status API. The in-flight round
+is finished, then the pipeline holds before the next round while the importer
+and exporters stay connected. Use "conduit resume" to continue, or
+"conduit step" to process a few rounds at a time.`)
+
+// ResumeCommand is the resume command to embed in a root cobra command.
+var ResumeCommand = makeCommand("resume", "resumes a paused pipeline", `Resumes a conduit pipeline which was paused with "conduit pause".`)
+
+// StepCommand is the step command to embed in a root cobra command.
+var StepCommand = makeCommand("step", "processes rounds of a paused pipeline", `Lets a conduit pipeline which was paused with "conduit pause" process some
+rounds, then it holds again. Useful to debug a processor one round at a time.`)
+
+// CutoverCommand is the cutover command to embed in a root cobra command.
+var CutoverCommand = makeCommand("cutover", "retires the old exporter of a verified migration", `Retires the from exporter of the configured exporter migration through the
+status API. The cutover is rejected until the new exporter has matched the old
+one for the configured window of rounds, the progress is reported by /status.`)
+
+// PromoteCommand is the promote command to embed in a root cobra command.
+var PromoteCommand = makeCommand("promote", "promotes the standby exporter and demotes the primary", `Swaps the primary and standby exporters of the configured standby through the
+status API. The in-flight round is finished and the primary is committed, then
+the standby receives the rounds from the next round on while the primary is
+kept initialized as the new standby. The promotion is rejected if the standby
+fails its health check.`)
+
+// RollbackCommand is the rollback command to embed in a root cobra command.
+var RollbackCommand = makeCommand("rollback", "rewinds a running pipeline to an earlier round", `Rolls a running conduit pipeline back to an earlier round through the status
+API, for example after a bad deploy of a processor. The processors and
+exporters which support it delete their data from the round on, then the
+pipeline resumes from the round. Plugins which do not support it keep their
+data, a warning is logged for each of them.`)
+
+// apiURL returns the base URL of the status API of the pipeline configured in
+// dataDir, addr overrides the configured address.
+func apiURL(dataDir, addr string) (string, error) {
+	if addr == "" {
+		if dataDir == "" {
+			dataDir = os.Getenv("CONDUIT_DATA_DIR")
+		}
+		pCfg, err := pipeline.MakePipelineConfig(&conduit.Args{ConduitDataDir: dataDir})
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case pCfg.API.Addr != "":
+			addr = pCfg.API.Addr
+		case pCfg.Metrics.Mode == "ON":
+			addr = pCfg.Metrics.Addr
+		default:
+			return "", fmt.Errorf("the status API is not enabled, configure api.addr or enable metrics")
+		}
+	}
+	if strings.HasPrefix(addr, "http://") || strings.HasPrefix(addr, "https://") {
+		return strings.TrimSuffix(addr, "/"), nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %s: %w", addr, err)
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port), nil
+}
+
+// post sends the control request and returns the response body.
+func post(url string) (map[string]interface{}, error) {
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(url, "application/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var body map[string]interface{}
+	if err = json.Unmarshal(b, &body); err != nil {
+		return nil, fmt.Errorf("unexpected response (%s): %s", resp.Status, b)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %v", resp.Status, body["error"])
+	}
+	return body, nil
+}
+
+// runControl sends the action, n is the number of rounds to step or the round
+// to roll back to.
+func runControl(action, dataDir, addr string, n uint64) error {
+	base, err := apiURL(dataDir, addr)
+	if err != nil {
+		return fmt.Errorf("runControl(): %w", err)
+	}
+	url := base + "/" + action
+	switch action {
+	case "step":
+		url = fmt.Sprintf("%s?rounds=%d", url, n)
+	case "rollback":
+		url = fmt.Sprintf("%s?round=%d", url, n)
+	}
+	body, err := post(url)
+	if err != nil {
+		return fmt.Errorf("runControl(): %s failed: %w", action, err)
+	}
+	switch action {
+	case "pause":
+		fmt.Println("Pipeline paused, it holds after the in-flight round.")
+	case "resume":
+		fmt.Println("Pipeline resumed.")
+	case "step":
+		fmt.Printf("Pipeline stepping %d round(s).\n", n)
+	case "cutover":
+		fmt.Println("Cutover requested, the old exporter is retired after the next round.")
+	case "promote":
+		fmt.Printf("Exporter (%v) promoted, exporter (%v) is now the standby.\n", body["primary"], body["standby"])
+	case "rollback":
+		fmt.Printf("Pipeline rolled back, resuming from round %d.\n", n)
+	}
+	return nil
+}
+
+func makeCommand(action, short, long string) *cobra.Command {
+	var dataDir string
+	var addr string
+	var n uint64
+	cmd := &cobra.Command{
+		Use:     action,
+		Short:   short,
+		Long:    long,
+		Example: fmt.Sprintf("conduit %s -d /path/to/data", action),
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runControl(action, dataDir, addr, n)
+		},
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringVarP(&dataDir, "data-dir", "d", "", "conduit data directory, used to find the status API address")
+	cmd.Flags().StringVar(&addr, "addr", "", "status API address. Defaults to api.addr, or the metrics address, in the data directory config")
+	switch action {
+	case "step":
+		cmd.Flags().Uint64VarP(&n, "rounds", "n", 1, "number of rounds to process before holding again")
+	case "rollback":
+		cmd.Flags().Uint64VarP(&n, "round", "r", 0, "round to resume from, the data of this round and later rounds is removed")
+		_ = cmd.MarkFlagRequired("round")
+		cmd.Example = fmt.Sprintf("conduit %s -d /path/to/data --round 1000", action)
+	}
+	return cmd
+}
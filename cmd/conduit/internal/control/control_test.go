@@ -0,0 +1,30 @@
+package control
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIURL(t *testing.T) {
+	tests := []struct {
+		addr string
+		url  string
+	}{
+		{":8981", "http://localhost:8981"},
+		{"0.0.0.0:8981", "http://localhost:8981"},
+		{"10.0.0.1:8981", "http://10.0.0.1:8981"},
+		{"https://conduit.example.com/", "https://conduit.example.com"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.addr, func(t *testing.T) {
+			url, err := apiURL("", tc.addr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.url, url)
+		})
+	}
+
+	_, err := apiURL("", "localhost")
+	assert.Error(t, err)
+}
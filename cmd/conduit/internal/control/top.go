@@ -0,0 +1,129 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/algorand/conduit/conduit/pipeline"
+)
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+// TopCommand is the top command to embed in a root cobra command.
+var TopCommand = makeTopCommand()
+
+// getActivity fetches the /activity snapshot.
+func getActivity(client *http.Client, base string) (pipeline.Activity, error) {
+	var activity pipeline.Activity
+	resp, err := client.Get(base + "/activity")
+	if err != nil {
+		return activity, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return activity, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return activity, fmt.Errorf("unexpected response (%s): %s", resp.Status, b)
+	}
+	if err = json.Unmarshal(b, &activity); err != nil {
+		return activity, fmt.Errorf("unexpected response (%s): %s", resp.Status, b)
+	}
+	return activity, nil
+}
+
+// formatActivity renders the snapshot as a table of the operations by stage.
+func formatActivity(activity pipeline.Activity) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "conduit top - %s - next round %d\n\n", activity.Time.Format(time.RFC3339), activity.NextRound)
+	if len(activity.Buffers) > 0 {
+		sb.WriteString("BUFFER      USED / CAPACITY\n")
+		for _, buf := range activity.Buffers {
+			fmt.Fprintf(&sb, "%-10s  %4d / %d\n", buf.Name, buf.Used, buf.Capacity)
+		}
+		sb.WriteString("\n")
+	}
+	counts := make(map[string]int)
+	for _, op := range activity.Operations {
+		counts[op.Stage]++
+	}
+	fmt.Fprintf(&sb, "IN FLIGHT   importer %d, processor %d, exporter %d\n\n", counts["importer"], counts["processor"], counts["exporter"])
+	sb.WriteString("STAGE      ROUND       ELAPSED     PLUGIN\n")
+	for _, op := range activity.Operations {
+		fmt.Fprintf(&sb, "%-9s  %-10d  %-10s  %s\n", op.Stage, op.Round, op.Elapsed.Round(time.Millisecond), op.Plugin)
+	}
+	if activity.Slowest != nil {
+		fmt.Fprintf(&sb, "\nslowest: %s (%s) on round %d for %s\n", activity.Slowest.Plugin, activity.Slowest.Stage, activity.Slowest.Round, activity.Slowest.Elapsed.Round(time.Millisecond))
+	}
+	return sb.String()
+}
+
+// runTop prints the activity every interval until it is interrupted, or once.
+func runTop(dataDir, addr string, interval time.Duration, once bool) error {
+	base, err := apiURL(dataDir, addr)
+	if err != nil {
+		return fmt.Errorf("runTop(): %w", err)
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		activity, err := getActivity(client, base)
+		if err != nil {
+			return fmt.Errorf("runTop(): %w", err)
+		}
+		if once {
+			fmt.Print(formatActivity(activity))
+			return nil
+		}
+		fmt.Print(clearScreen + formatActivity(activity))
+		select {
+		case <-interrupt:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func makeTopCommand() *cobra.Command {
+	var dataDir string
+	var addr string
+	var interval time.Duration
+	var once bool
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "shows the rounds in flight in a running pipeline",
+		Long: `Shows a live view of a running conduit pipeline through its status API: the
+plugin calls in progress in each stage with their round, the occupancy of the
+prefetch and batch buffers, and the slowest operation. Useful to see where
+rounds wait with prefetch-rounds or concurrent rounds.workers.`,
+		Example: "conduit top -d /path/to/data",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interval <= 0 {
+				return fmt.Errorf("interval must be positive")
+			}
+			return runTop(dataDir, addr, interval, once)
+		},
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringVarP(&dataDir, "data-dir", "d", "", "conduit data directory, used to find the status API address")
+	cmd.Flags().StringVar(&addr, "addr", "", "status API address. Defaults to api.addr, or the metrics address, in the data directory config")
+	cmd.Flags().DurationVarP(&interval, "interval", "i", time.Second, "how often the view is refreshed")
+	cmd.Flags().BoolVar(&once, "once", false, "print the view once and exit")
+	return cmd
+}
@@ -0,0 +1,46 @@
+package control
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/pipeline"
+)
+
+func TestGetActivity(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/activity", r.URL.Path)
+		w.Write([]byte(`{"next-round": 7, "operations": [{"stage": "importer", "plugin": "algod", "round": 7, "elapsed": 1500000000}]}`))
+	}))
+	defer srv.Close()
+
+	activity, err := getActivity(srv.Client(), srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), activity.NextRound)
+	require.Len(t, activity.Operations, 1)
+	assert.Equal(t, 1500*time.Millisecond, activity.Operations[0].Elapsed)
+}
+
+func TestFormatActivity(t *testing.T) {
+	slowest := pipeline.Operation{Stage: "exporter", Plugin: "postgresql", Round: 5, Elapsed: 2 * time.Second}
+	out := formatActivity(pipeline.Activity{
+		NextRound: 5,
+		Operations: []pipeline.Operation{
+			{Stage: "importer", Plugin: "algod", Round: 6, Elapsed: 300 * time.Millisecond},
+			{Stage: "importer", Plugin: "algod", Round: 7, Elapsed: 100 * time.Millisecond},
+			slowest,
+		},
+		Buffers: []pipeline.Buffer{{Name: "prefetch", Used: 2, Capacity: 4}},
+		Slowest: &slowest,
+	})
+	assert.Contains(t, out, "next round 5")
+	assert.Contains(t, out, "prefetch       2 / 4")
+	assert.Contains(t, out, "IN FLIGHT   importer 2, processor 0, exporter 1")
+	assert.Contains(t, out, "importer   6           300ms       algod")
+	assert.Contains(t, out, "slowest: postgresql (exporter) on round 5 for 2s")
+}
@@ -0,0 +1,179 @@
+package convert
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/loggers"
+	"github.com/algorand/conduit/conduit/pipeline"
+	"github.com/algorand/conduit/conduit/plugins/exporters/filewriter"
+	fileimporter "github.com/algorand/conduit/conduit/plugins/importers/filereader"
+)
+
+// Command is the convert command to embed in a root cobra command.
+var Command = makeCommand()
+
+// archive describes the layout of a file_writer archive.
+type archive struct {
+	dir           string
+	pattern       string
+	chunkPattern  string
+	roundsPerFile uint64
+}
+
+type options struct {
+	from            archive
+	to              archive
+	start           uint64
+	end             uint64
+	dropCertificate bool
+	logLevel        string
+}
+
+// valid validates the command line options.
+func (opts options) valid() error {
+	if opts.from.dir == "" || opts.to.dir == "" {
+		return fmt.Errorf("both --from and --to directories are required")
+	}
+	if path.Clean(opts.from.dir) == path.Clean(opts.to.dir) {
+		return fmt.Errorf("--from and --to must be different directories")
+	}
+	if opts.end == 0 {
+		return fmt.Errorf("--end is required")
+	}
+	if opts.start > opts.end {
+		return fmt.Errorf("--start (%d) must not be after --end (%d)", opts.start, opts.end)
+	}
+	return nil
+}
+
+// makeConfig creates the offline pipeline which reads the source archive with
+// the file_reader importer and writes it with the file_writer exporter.
+func makeConfig(opts options, dataDir string) *pipeline.Config {
+	return &pipeline.Config{
+		ConduitArgs:      &conduit.Args{ConduitDataDir: dataDir},
+		PipelineLogLevel: opts.logLevel,
+		Metrics:          pipeline.Metrics{Prefix: conduit.DefaultMetricsPrefix},
+		Importer: pipeline.NameConfigPair{
+			Name: fileimporter.PluginName,
+			Config: map[string]interface{}{
+				"block-dir":              opts.from.dir,
+				"filename-pattern":       opts.from.pattern,
+				"chunk-filename-pattern": opts.from.chunkPattern,
+				"rounds-per-file":        opts.from.roundsPerFile,
+			},
+		},
+		Exporter: pipeline.NameConfigPair{
+			Name: filewriter.PluginName,
+			Config: map[string]interface{}{
+				"block-dir":              opts.to.dir,
+				"filename-pattern":       opts.to.pattern,
+				"chunk-filename-pattern": opts.to.chunkPattern,
+				"rounds-per-file":        opts.to.roundsPerFile,
+				"drop-certificate":       opts.dropCertificate,
+			},
+		},
+		Rounds: pipeline.Rounds{Start: opts.start, End: opts.end},
+	}
+}
+
+// copyGenesis copies genesis.json, which the file_reader importer requires, to
+// the converted archive.
+func copyGenesis(from, to string) error {
+	src, err := os.Open(path.Join(from, "genesis.json"))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(path.Join(to, "genesis.json"))
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func runConvert(opts options) error {
+	if err := opts.valid(); err != nil {
+		return fmt.Errorf("runConvert(): %w", err)
+	}
+	level, err := log.ParseLevel(opts.logLevel)
+	if err != nil {
+		return fmt.Errorf("runConvert(): invalid log level: %w", err)
+	}
+	if err = os.MkdirAll(opts.to.dir, 0755); err != nil {
+		return fmt.Errorf("runConvert(): unable to create %s: %w", opts.to.dir, err)
+	}
+	if err = copyGenesis(opts.from.dir, opts.to.dir); err != nil {
+		return fmt.Errorf("runConvert(): unable to copy the genesis file: %w", err)
+	}
+
+	// The pipeline metadata is only needed while converting.
+	dataDir, err := os.MkdirTemp("", "conduit-convert")
+	if err != nil {
+		return fmt.Errorf("runConvert(): unable to create a data directory: %w", err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	logger := loggers.MakeThreadSafeLoggerWithWriter(level, os.Stdout)
+	p, err := pipeline.MakePipeline(context.Background(), makeConfig(opts, dataDir), logger)
+	if err != nil {
+		return fmt.Errorf("runConvert(): %w", err)
+	}
+	if err = p.Init(); err != nil {
+		return fmt.Errorf("runConvert(): %w", err)
+	}
+	stopSignals := pipeline.StopOnSignal(p, logger)
+	defer stopSignals()
+	p.Start()
+	defer p.Stop()
+	p.Wait()
+	if err = p.Error(); err != nil {
+		return fmt.Errorf("runConvert(): %w", err)
+	}
+	fmt.Printf("Converted rounds %d to %d from %s to %s\n", opts.start, opts.end, opts.from.dir, opts.to.dir)
+	return nil
+}
+
+func makeCommand() *cobra.Command {
+	var opts options
+	cmd := &cobra.Command{
+		Use:   "convert",
+		Short: "converts a file_writer archive to another layout",
+		Long: `Reads the rounds of an archive written by the file_writer exporter with the
+file_reader importer and writes them again with the file_writer exporter, so
+that the layout of an archive can be changed after it was written. For example
+gzip the blocks by using a '.gz' filename pattern, or split or merge chunk files
+with a different rounds-per-file. No conduit data directory is needed, and the
+source archive is not modified.`,
+		Example: "conduit convert --from ./blocks --to ./chunks --end 1000 --to-rounds-per-file 100",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConvert(opts)
+		},
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringVar(&opts.from.dir, "from", "", "directory of the archive to convert")
+	cmd.Flags().StringVar(&opts.from.pattern, "from-filename-pattern", filewriter.FilePattern, "filename pattern of the archive to convert")
+	cmd.Flags().StringVar(&opts.from.chunkPattern, "from-chunk-filename-pattern", filewriter.ChunkFilePattern, "chunk filename pattern of the archive to convert")
+	cmd.Flags().Uint64Var(&opts.from.roundsPerFile, "from-rounds-per-file", 0, "rounds per chunk file of the archive to convert")
+	cmd.Flags().StringVar(&opts.to.dir, "to", "", "directory of the converted archive, it is created if needed")
+	cmd.Flags().StringVar(&opts.to.pattern, "to-filename-pattern", filewriter.FilePattern, "filename pattern of the converted archive, use a '.gz' extension to gzip the blocks")
+	cmd.Flags().StringVar(&opts.to.chunkPattern, "to-chunk-filename-pattern", filewriter.ChunkFilePattern, "chunk filename pattern of the converted archive")
+	cmd.Flags().Uint64Var(&opts.to.roundsPerFile, "to-rounds-per-file", 0, "rounds per chunk file of the converted archive")
+	cmd.Flags().Uint64Var(&opts.start, "start", 0, "first round to convert")
+	cmd.Flags().Uint64Var(&opts.end, "end", 0, "last round to convert")
+	cmd.Flags().BoolVar(&opts.dropCertificate, "drop-certificate", false, "remove the vote certificates from the converted blocks")
+	cmd.Flags().StringVar(&opts.logLevel, "log-level", "warn", "log level while converting")
+	return cmd
+}
@@ -0,0 +1,47 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsValid(t *testing.T) {
+	valid := options{from: archive{dir: "blocks"}, to: archive{dir: "chunks"}, end: 10}
+	assert.NoError(t, valid.valid())
+
+	tests := []struct {
+		name   string
+		modify func(*options)
+		errMsg string
+	}{
+		{"missing from", func(o *options) { o.from.dir = "" }, "both --from and --to directories are required"},
+		{"same directory", func(o *options) { o.to.dir = "./blocks/" }, "--from and --to must be different directories"},
+		{"missing end", func(o *options) { o.end = 0 }, "--end is required"},
+		{"start after end", func(o *options) { o.start = 11 }, "--start (11) must not be after --end (10)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := valid
+			tc.modify(&opts)
+			assert.EqualError(t, opts.valid(), tc.errMsg)
+		})
+	}
+}
+
+func TestMakeConfig(t *testing.T) {
+	opts := options{
+		from:     archive{dir: "blocks", pattern: "%[1]d_block.json"},
+		to:       archive{dir: "chunks", chunkPattern: "%[1]d_blocks.chunk.gz", roundsPerFile: 100},
+		start:    5,
+		end:      10,
+		logLevel: "warn",
+	}
+	cfg := makeConfig(opts, t.TempDir())
+	require.NoError(t, cfg.Valid())
+	assert.Equal(t, "blocks", cfg.Importer.Config["block-dir"])
+	assert.Equal(t, uint64(100), cfg.Exporter.Config["rounds-per-file"])
+	assert.Equal(t, uint64(5), cfg.Rounds.Start)
+	assert.Equal(t, uint64(10), cfg.Rounds.End)
+}
@@ -0,0 +1,64 @@
+package deadletter
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/loggers"
+	"github.com/algorand/conduit/conduit/pipeline"
+)
+
+// ReplayCommand is the replay-deadletter command to embed in a root cobra command.
+var ReplayCommand = makeReplayCommand()
+
+func runReplay(dataDir string, rounds []uint) error {
+	if dataDir == "" {
+		dataDir = os.Getenv("CONDUIT_DATA_DIR")
+	}
+
+	pCfg, err := pipeline.MakePipelineConfig(&conduit.Args{ConduitDataDir: dataDir})
+	if err != nil {
+		return fmt.Errorf("runReplay(): %w", err)
+	}
+	level, err := log.ParseLevel(pCfg.PipelineLogLevel)
+	if err != nil {
+		return fmt.Errorf("runReplay(): invalid log level: %w", err)
+	}
+	logger := loggers.MakeThreadSafeLoggerWithWriter(level, os.Stdout)
+
+	selected := make([]uint64, 0, len(rounds))
+	for _, round := range rounds {
+		selected = append(selected, uint64(round))
+	}
+	replayed, err := pipeline.ReplayDeadLetters(context.Background(), pCfg, logger, selected)
+	fmt.Printf("Replayed %d dead letter round(s): %v\n", len(replayed), replayed)
+	return err
+}
+
+func makeReplayCommand() *cobra.Command {
+	var dataDir string
+	var rounds []uint
+	cmd := &cobra.Command{
+		Use:   "replay-deadletter",
+		Short: "replays rounds which were written to the dead letter directory",
+		Long: `Re-injects rounds saved by the "on-failure: dead-letter" mode into the
+configured processors and exporters. Successfully replayed rounds are removed
+from the dead letter directory and metadata.json. Stop conduit before running
+this command, and note that exporters which require consecutive rounds may
+reject rounds older than their current round.`,
+		Example: "conduit replay-deadletter -d /path/to/data --round 1234",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReplay(dataDir, rounds)
+		},
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringVarP(&dataDir, "data-dir", "d", "", "conduit data directory")
+	cmd.Flags().UintSliceVarP(&rounds, "round", "r", nil, "rounds to replay. Defaults to all dead letter rounds")
+	return cmd
+}
@@ -0,0 +1,102 @@
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/loggers"
+	"github.com/algorand/conduit/conduit/pipeline"
+)
+
+// Command is the diff command to embed in a root cobra command.
+var Command = makeCommand()
+
+type diffArgs struct {
+	dataDir    string
+	configFile string
+	first      uint64
+	last       uint64
+	jsonOutput bool
+	verbose    bool
+}
+
+func runDiff(args diffArgs) error {
+	if args.dataDir == "" {
+		args.dataDir = os.Getenv("CONDUIT_DATA_DIR")
+	}
+	conduitArgs := &conduit.Args{ConduitDataDir: args.dataDir}
+	var pCfg *pipeline.Config
+	var err error
+	if args.configFile != "" {
+		pCfg, err = pipeline.MakePipelineConfigFromFile(conduitArgs, args.configFile)
+	} else {
+		pCfg, err = pipeline.MakePipelineConfig(conduitArgs)
+	}
+	if err != nil {
+		return fmt.Errorf("runDiff(): %w", err)
+	}
+	level, err := log.ParseLevel(pCfg.PipelineLogLevel)
+	if err != nil {
+		return fmt.Errorf("runDiff(): invalid log level: %w", err)
+	}
+	var logOutput io.Writer = io.Discard
+	if args.verbose {
+		logOutput = os.Stderr
+	}
+	logger := loggers.MakeThreadSafeLoggerWithWriter(level, logOutput)
+
+	report, err := pipeline.DiffRounds(context.Background(), pCfg, logger, args.first, args.last)
+	if err != nil {
+		return fmt.Errorf("runDiff(): %w", err)
+	}
+	if args.jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.Write(os.Stdout)
+	}
+	if err != nil {
+		return fmt.Errorf("runDiff(): %w", err)
+	}
+	return nil
+}
+
+func makeCommand() *cobra.Command {
+	var args diffArgs
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "compares the output of a config with what the exporters already have",
+		Long: `Processes a range of rounds with the config, by default conduit.yml of the data
+directory, and compares the blocks each exporter would receive with the rounds
+it already exported instead of writing them. The report lists the rounds which
+a config change would add, remove or change, with the IDs of the transactions
+which differ. Only exporters which can read back their rounds are compared,
+such as file_writer. Nothing is written, but the importer must be able to
+provide the rounds and processors which keep state see them again.`,
+		Example: "conduit diff -d /path/to/data --config candidate.yml --first 1000 --last 1100",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if !cmd.Flags().Changed("last") {
+				args.last = args.first
+			}
+			return runDiff(args)
+		},
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringVarP(&args.dataDir, "data-dir", "d", "", "conduit data directory")
+	cmd.Flags().StringVarP(&args.configFile, "config", "c", "", "the config to compare, defaults to conduit.yml of the data directory")
+	cmd.Flags().Uint64Var(&args.first, "first", 0, "the first round to compare")
+	cmd.Flags().Uint64Var(&args.last, "last", 0, "the last round to compare, defaults to the first round")
+	cmd.Flags().BoolVar(&args.jsonOutput, "json", false, "print the report as JSON")
+	cmd.Flags().BoolVarP(&args.verbose, "verbose", "v", false, "print the pipeline logs to stderr")
+	_ = cmd.MarkFlagRequired("first")
+	return cmd
+}
@@ -0,0 +1,54 @@
+package doctor
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/algorand/conduit/conduit/pipeline"
+)
+
+// Command is the doctor command to embed in a root cobra command.
+var Command = makeCommand()
+
+func runDoctor(dataDir string, repair bool) error {
+	if dataDir == "" {
+		dataDir = os.Getenv("CONDUIT_DATA_DIR")
+	}
+	if dataDir == "" {
+		return fmt.Errorf("runDoctor(): a data directory is required")
+	}
+
+	report := pipeline.Doctor(dataDir, repair)
+	if err := report.Write(os.Stdout); err != nil {
+		return fmt.Errorf("runDoctor(): %w", err)
+	}
+	if !report.Healthy() {
+		return fmt.Errorf("the data directory has problems which could not be repaired")
+	}
+	return nil
+}
+
+func makeCommand() *cobra.Command {
+	var dataDir string
+	var repair bool
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "checks the integrity of a data directory",
+		Long: `Checks a conduit data directory: the configuration, a stale pid file,
+metadata.json and its commit intents, plugin directories, temporary files left
+by interrupted writes, disk space and permissions. With --repair the problems
+which can be fixed without losing data are repaired, nothing is repaired while
+conduit is running. Exits with an error if a problem remains.`,
+		Example: "conduit doctor -d /path/to/data --repair",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor(dataDir, repair)
+		},
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringVarP(&dataDir, "data-dir", "d", "", "conduit data directory")
+	cmd.Flags().BoolVar(&repair, "repair", false, "repair the problems which can be fixed safely")
+	return cmd
+}
@@ -41,75 +41,76 @@ func formatArrayObject(obj string) string {
 
 }
 
-func runConduitInit(path string, importerFlag string, processorsFlag []string, exporterFlag string) error {
-	var location string
+// sampleConfigOf returns the sample config of the named plugin.
+func sampleConfigOf(pluginType string, metadata []conduit.Metadata, name string) (string, error) {
+	for _, m := range metadata {
+		if m.Name == name {
+			return m.SampleConfig, nil
+		}
+	}
+	return "", fmt.Errorf("unknown %s name: %v", pluginType, name)
+}
+
+// dataDirectory returns the data directory to initialize and a description of
+// its location.
+func dataDirectory(path string) (string, string) {
 	if path == "" {
-		path = defaultDataDirectory
-		location = "in the current working directory"
-	} else {
-		location = fmt.Sprintf("at '%s'", path)
+		return defaultDataDirectory, "in the current working directory"
 	}
+	return path, fmt.Sprintf("at '%s'", path)
+}
 
+// writeConfig writes the config file with the importer, processors and
+// exporter sections into the data directory, creating it if needed.
+func writeConfig(path string, importer string, processors string, exporter string) (string, error) {
 	if err := os.MkdirAll(path, 0755); err != nil {
-		return err
+		return "", err
 	}
 
 	configFilePath := filepath.Join(path, conduit.DefaultConfigName)
 	f, err := os.Create(configFilePath)
 	if err != nil {
-		return fmt.Errorf("runConduitInit(): failed to create %s", configFilePath)
+		return "", fmt.Errorf("failed to create %s", configFilePath)
 	}
 	defer f.Close()
 
-	var importer string
+	config := fmt.Sprintf(sampleConfig, importer, processors, exporter)
+	if _, err = f.WriteString(config); err != nil {
+		return "", fmt.Errorf("failed to write sample config: %w", err)
+	}
+	return configFilePath, nil
+}
+
+func runConduitInit(path string, importerFlag string, processorsFlag []string, exporterFlag string) error {
+	path, location := dataDirectory(path)
+
 	if importerFlag == "" {
 		importerFlag = algodimporter.PluginName
 	}
-	for _, metadata := range pipeline.ImporterMetadata() {
-		if metadata.Name == importerFlag {
-			importer = metadata.SampleConfig
-			break
-		}
-	}
-	if importer == "" {
-		return fmt.Errorf("runConduitInit(): unknown importer name: %v", importerFlag)
+	importer, err := sampleConfigOf("importer", pipeline.ImporterMetadata(), importerFlag)
+	if err != nil {
+		return fmt.Errorf("runConduitInit(): %w", err)
 	}
 
-	var exporter string
 	if exporterFlag == "" {
 		exporterFlag = filewriter.PluginName
 	}
-	for _, metadata := range pipeline.ExporterMetadata() {
-		if metadata.Name == exporterFlag {
-			exporter = metadata.SampleConfig
-			break
-		}
-	}
-	if exporter == "" {
-		return fmt.Errorf("runConduitInit(): unknown exporter name: %v", exporterFlag)
+	exporter, err := sampleConfigOf("exporter", pipeline.ExporterMetadata(), exporterFlag)
+	if err != nil {
+		return fmt.Errorf("runConduitInit(): %w", err)
 	}
 
 	var processors string
 	for _, processorName := range processorsFlag {
-		found := false
-		for _, metadata := range pipeline.ProcessorMetadata() {
-			if metadata.Name == processorName {
-				processors = processors + formatArrayObject(metadata.SampleConfig)
-				found = true
-				break
-			}
-		}
-		if !found {
-			return fmt.Errorf("runConduitInit(): unknown processor name: %v", processorName)
+		processor, err := sampleConfigOf("processor", pipeline.ProcessorMetadata(), processorName)
+		if err != nil {
+			return fmt.Errorf("runConduitInit(): %w", err)
 		}
+		processors = processors + formatArrayObject(processor)
 	}
 
-	config := fmt.Sprintf(sampleConfig, importer, processors, exporter)
-
-	_, _ = f.WriteString(config)
-
-	if err != nil {
-		return fmt.Errorf("runConduitInit(): failed to write sample config: %w", err)
+	if _, err = writeConfig(path, importer, processors, exporter); err != nil {
+		return fmt.Errorf("runConduitInit(): %w", err)
 	}
 
 	fmt.Printf("A data directory has been created %s.\n", location)
@@ -122,12 +123,24 @@ func runConduitInit(path string, importerFlag string, processorsFlag []string, e
 	return nil
 }
 
+// completePluginNames completes the names of the plugins.
+func completePluginNames(metadata func() []conduit.Metadata) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		var names []string
+		for _, m := range metadata() {
+			names = append(names, fmt.Sprintf("%s\t%s", m.Name, m.Description))
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
 // makeInitCmd creates a sample data directory.
 func makeInitCmd() *cobra.Command {
 	var data string
 	var importer string
 	var exporter string
 	var processors []string
+	var interactive bool
 	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "initializes a Conduit data directory",
@@ -139,10 +152,16 @@ different flags.
 Once initialized the conduit.yml file needs to be modified. Refer to the file
 comments for details.
 
-Once configured, launch conduit with './conduit -d /path/to/data'.`,
+Once configured, launch conduit with './conduit -d /path/to/data'.
+
+With --interactive a wizard asks for the plugins, their settings and a filter
+preset instead, then validates the config and tests the plugin connections.`,
 		Example: "conduit init  -d /path/to/data -i importer -p processor1,processor2 -e exporter",
 		Args:    cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive {
+				return runInteractiveInit(data, cmd.InOrStdin(), cmd.OutOrStdout())
+			}
 			return runConduitInit(data, importer, processors, exporter)
 		},
 		SilenceUsage: true,
@@ -152,5 +171,10 @@ Once configured, launch conduit with './conduit -d /path/to/data'.`,
 	cmd.Flags().StringVarP(&importer, "importer", "i", "", "data importer name.")
 	cmd.Flags().StringSliceVarP(&processors, "processors", "p", []string{}, "comma-separated list of processors.")
 	cmd.Flags().StringVarP(&exporter, "exporter", "e", "", "data exporter name.")
+	cmd.Flags().BoolVar(&interactive, "interactive", false, "select and configure the plugins with a wizard.")
+	_ = cmd.MarkFlagDirname("data")
+	_ = cmd.RegisterFlagCompletionFunc("importer", completePluginNames(pipeline.ImporterMetadata))
+	_ = cmd.RegisterFlagCompletionFunc("processors", completePluginNames(pipeline.ProcessorMetadata))
+	_ = cmd.RegisterFlagCompletionFunc("exporter", completePluginNames(pipeline.ExporterMetadata))
 	return cmd
 }
@@ -0,0 +1,319 @@
+package initialize
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	log "github.com/sirupsen/logrus"
+
+	algodimporter "github.com/algorand/indexer/conduit/plugins/importers/algod"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/pipeline"
+	"github.com/algorand/conduit/conduit/plugins/exporters/filewriter"
+	"github.com/algorand/conduit/conduit/plugins/processors/filterprocessor"
+)
+
+// filterPreset is a filter_processor config offered by the wizard.
+type filterPreset struct {
+	name        string
+	description string
+	// match is the filter_processor match config, the address preset asks
+	// for the address.
+	match string
+}
+
+var filterPresets = []filterPreset{
+	{name: "none", description: "keep every transaction"},
+	{name: "payments", description: "keep payments", match: `tx-types: ["pay"]`},
+	{name: "assets", description: "keep asset transfers, configurations and freezes", match: `tx-types: ["axfer", "acfg", "afrz"]`},
+	{name: "apps", description: "keep application calls", match: `tx-types: ["appl"]`},
+	{name: "address", description: "keep the transactions sent or received by an address"},
+}
+
+// settingLine matches a "key: value" line of a sample config.
+var settingLine = regexp.MustCompile(`^(\s*)([A-Za-z0-9_-]+):(.*)$`)
+
+// wizard asks the questions of the interactive init.
+type wizard struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// ask prints the question and returns the answer, or def if it is empty.
+func (w *wizard) ask(question string, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+	if !w.in.Scan() {
+		if err := w.in.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("input ended before the wizard was complete")
+	}
+	answer := strings.TrimSpace(w.in.Text())
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// confirm asks a yes or no question.
+func (w *wizard) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer, err := w.ask(fmt.Sprintf("%s (%s)", question, hint), "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintf(w.out, "Please answer y or n.\n")
+	}
+}
+
+// choose lists the options and returns the selected name, options are
+// selected by number or by name.
+func (w *wizard) choose(title string, names []string, descriptions []string, def string) (string, error) {
+	fmt.Fprintf(w.out, "\nAvailable %ss:\n", title)
+	for idx, name := range names {
+		fmt.Fprintf(w.out, "  %d) %s - %s\n", idx+1, name, descriptions[idx])
+	}
+	for {
+		answer, err := w.ask(fmt.Sprintf("Select the %s", title), def)
+		if err != nil {
+			return "", err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(names) {
+			return names[n-1], nil
+		}
+		for _, name := range names {
+			if name == answer {
+				return name, nil
+			}
+		}
+		fmt.Fprintf(w.out, "Unknown %s (%s), enter a number or a name from the list.\n", title, answer)
+	}
+}
+
+// choosePlugin selects one of the plugins and returns its sample config.
+func (w *wizard) choosePlugin(pluginType string, metadata []conduit.Metadata, def string) (string, error) {
+	sort.Slice(metadata, func(i, j int) bool {
+		return metadata[i].Name < metadata[j].Name
+	})
+	names := make([]string, 0, len(metadata))
+	descriptions := make([]string, 0, len(metadata))
+	for _, m := range metadata {
+		if m.Deprecated {
+			continue
+		}
+		names = append(names, m.Name)
+		descriptions = append(descriptions, m.Description)
+	}
+	name, err := w.choose(pluginType, names, descriptions, def)
+	if err != nil {
+		return "", err
+	}
+	return sampleConfigOf(pluginType, metadata, name)
+}
+
+// configure asks for each setting directly under the config key of a sample
+// config, using the sample values as defaults. The comments above a setting
+// are shown as its description. Nested settings are left as they are, and the
+// lines are edited in place so that the comments of the sample are kept.
+func (w *wizard) configure(sample string) (string, error) {
+	lines := strings.Split(sample, "\n")
+	configIndent := -1
+	settingIndent := -1
+	var help []string
+	for idx, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			help = append(help, strings.TrimSpace(strings.TrimPrefix(trimmed, "#")))
+			continue
+		}
+		comments := help
+		help = nil
+		m := settingLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		indent, key, value := len(m[1]), m[2], strings.TrimSpace(m[3])
+		if configIndent < 0 {
+			if key == "config" {
+				configIndent = indent
+			}
+			continue
+		}
+		if indent <= configIndent {
+			break
+		}
+		if settingIndent < 0 {
+			settingIndent = indent
+		}
+		if indent != settingIndent || value == "" {
+			continue
+		}
+
+		quoted := strings.HasPrefix(value, `"`)
+		def := value
+		if quoted {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				def = unquoted
+			}
+		}
+		fmt.Fprintln(w.out)
+		for _, c := range comments {
+			fmt.Fprintf(w.out, "  %s\n", c)
+		}
+		answer, err := w.ask(fmt.Sprintf("  %s", key), def)
+		if err != nil {
+			return "", err
+		}
+		if answer == def {
+			continue
+		}
+		if quoted {
+			answer = strconv.Quote(answer)
+		}
+		lines[idx] = fmt.Sprintf("%s%s: %s", m[1], key, answer)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// chooseFilter returns the processors section of the selected filter preset.
+func (w *wizard) chooseFilter() (string, error) {
+	names := make([]string, 0, len(filterPresets))
+	descriptions := make([]string, 0, len(filterPresets))
+	for _, preset := range filterPresets {
+		names = append(names, preset.name)
+		descriptions = append(descriptions, preset.description)
+	}
+	name, err := w.choose("filter preset", names, descriptions, filterPresets[0].name)
+	if err != nil {
+		return "", err
+	}
+
+	var match string
+	for _, preset := range filterPresets {
+		if preset.name == name {
+			match = preset.match
+		}
+	}
+	if name == "address" {
+		for {
+			address, err := w.ask("Address", "")
+			if err != nil {
+				return "", err
+			}
+			if _, err = sdk.DecodeAddress(address); err == nil {
+				match = fmt.Sprintf(`addresses: ["%s"]`, address)
+				break
+			}
+			fmt.Fprintf(w.out, "Invalid address (%s): %v\n", address, err)
+		}
+	}
+	if match == "" {
+		return "", nil
+	}
+	processor := fmt.Sprintf("name: %s\nconfig:\n  match:\n    %s", filterprocessor.PluginName, match)
+	return formatArrayObject(processor), nil
+}
+
+// testConnections initializes every plugin of the config without processing
+// rounds, which verifies that the configured node and services are reachable.
+func testConnections(args *conduit.Args) error {
+	cfg, err := pipeline.MakePipelineConfig(args)
+	if err != nil {
+		return err
+	}
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	p, err := pipeline.MakePipeline(context.Background(), cfg, logger)
+	if err != nil {
+		return err
+	}
+	_, err = p.Validate()
+	return err
+}
+
+// runInteractiveInit walks through the selection and settings of the plugins,
+// writes the config and validates it.
+func runInteractiveInit(path string, in io.Reader, out io.Writer) error {
+	w := &wizard{in: bufio.NewScanner(in), out: out}
+	fmt.Fprintf(out, "This wizard creates a Conduit data directory with a conduit.yml file.\n")
+	fmt.Fprintf(out, "Press enter to accept the value in brackets.\n\n")
+
+	if path == "" {
+		var err error
+		if path, err = w.ask("Data directory", defaultDataDirectory); err != nil {
+			return fmt.Errorf("runInteractiveInit(): %w", err)
+		}
+	}
+
+	importer, err := w.choosePlugin("importer", pipeline.ImporterMetadata(), algodimporter.PluginName)
+	if err == nil {
+		importer, err = w.configure(importer)
+	}
+	if err != nil {
+		return fmt.Errorf("runInteractiveInit(): %w", err)
+	}
+
+	processors, err := w.chooseFilter()
+	if err != nil {
+		return fmt.Errorf("runInteractiveInit(): %w", err)
+	}
+
+	exporter, err := w.choosePlugin("exporter", pipeline.ExporterMetadata(), filewriter.PluginName)
+	if err == nil {
+		exporter, err = w.configure(exporter)
+	}
+	if err != nil {
+		return fmt.Errorf("runInteractiveInit(): %w", err)
+	}
+
+	configFilePath, err := writeConfig(path, importer, processors, exporter)
+	if err != nil {
+		return fmt.Errorf("runInteractiveInit(): %w", err)
+	}
+	fmt.Fprintf(out, "\nThe config was written to %s.\n", configFilePath)
+
+	args := &conduit.Args{ConduitDataDir: path}
+	if _, err = pipeline.MakePipelineConfig(args); err != nil {
+		return fmt.Errorf("runInteractiveInit(): the config is invalid, update %s: %w", configFilePath, err)
+	}
+
+	test, err := w.confirm("Test the connections of the plugins now?", true)
+	if err != nil {
+		return fmt.Errorf("runInteractiveInit(): %w", err)
+	}
+	if test {
+		if err = testConnections(args); err != nil {
+			fmt.Fprintf(out, "Connection test failed: %v\n", err)
+			fmt.Fprintf(out, "Update %s and check it again with:\n  ./conduit -d %s --dry-run\n", configFilePath, path)
+			return nil
+		}
+		fmt.Fprintf(out, "The plugins connected successfully.\n")
+	}
+
+	fmt.Fprintf(out, "\nStart Conduit with:\n  ./conduit -d %s\n", path)
+	return nil
+}
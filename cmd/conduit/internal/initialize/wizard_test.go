@@ -0,0 +1,130 @@
+package initialize
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit/pipeline"
+	noopExporter "github.com/algorand/conduit/conduit/plugins/exporters/noop"
+	fileimporter "github.com/algorand/conduit/conduit/plugins/importers/filereader"
+	"github.com/algorand/conduit/conduit/plugins/processors/filterprocessor"
+)
+
+func makeWizard(input ...string) (*wizard, *bytes.Buffer) {
+	var out bytes.Buffer
+	in := strings.NewReader(strings.Join(input, "\n") + "\n")
+	return &wizard{in: bufio.NewScanner(in), out: &out}, &out
+}
+
+const wizardSample = `  name: algod
+  config:
+    # The mode of operation, either "archival" or "follower"
+    mode: "archival"
+    # Algod netaddr string
+    netaddr: "http://url"
+    retries: 5
+    catchup:
+      token: ""
+`
+
+// TestWizardConfigure tests that the settings of a sample config are edited
+// in place and the sample defaults are kept.
+func TestWizardConfigure(t *testing.T) {
+	w, out := makeWizard("follower", "", "7")
+	config, err := w.configure(wizardSample)
+	require.NoError(t, err)
+	assert.Equal(t, `  name: algod
+  config:
+    # The mode of operation, either "archival" or "follower"
+    mode: "follower"
+    # Algod netaddr string
+    netaddr: "http://url"
+    retries: 7
+    catchup:
+      token: ""
+`, config)
+	assert.Contains(t, out.String(), "  The mode of operation, either \"archival\" or \"follower\"\n  mode [archival]: ")
+	assert.Contains(t, out.String(), "  netaddr [http://url]: ")
+	assert.NotContains(t, out.String(), "token")
+
+	// The wizard stops if the input ends.
+	w, _ = makeWizard("follower")
+	_, err = w.configure(wizardSample)
+	assert.EqualError(t, err, "input ended before the wizard was complete")
+}
+
+func TestWizardChoose(t *testing.T) {
+	w, out := makeWizard("", "3", "asdf", "b")
+	names := []string{"a", "b", "c"}
+	descriptions := []string{"first", "second", "third"}
+
+	name, err := w.choose("thing", names, descriptions, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", name)
+	assert.Contains(t, out.String(), "  2) b - second\n")
+
+	name, err = w.choose("thing", names, descriptions, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "c", name)
+
+	name, err = w.choose("thing", names, descriptions, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "b", name)
+	assert.Contains(t, out.String(), "Unknown thing (asdf), enter a number or a name from the list.")
+}
+
+func TestWizardChooseFilter(t *testing.T) {
+	address := sdk.ZeroAddress.String()
+	tests := []struct {
+		input    []string
+		expected string
+	}{
+		{[]string{""}, ""},
+		{[]string{"payments"}, "  - name: filter_processor\n    config:\n      match:\n        tx-types: [\"pay\"]\n"},
+		{[]string{"address", "not-an-address", address}, fmt.Sprintf("  - name: filter_processor\n    config:\n      match:\n        addresses: [\"%s\"]\n", address)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input[0], func(t *testing.T) {
+			w, _ := makeWizard(tc.input...)
+			processors, err := w.chooseFilter()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, processors)
+		})
+	}
+}
+
+// TestRunInteractiveInit tests that the wizard writes a valid config with the
+// selected plugins and settings.
+func TestRunInteractiveInit(t *testing.T) {
+	dataDirectory := t.TempDir()
+	blockDir := t.TempDir()
+	input := []string{fileimporter.PluginName, blockDir, "", "", "", "", "", "", "payments", noopExporter.PluginName, "n"}
+	var out bytes.Buffer
+	err := runInteractiveInit(dataDirectory, strings.NewReader(strings.Join(input, "\n")+"\n"), &out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "Start Conduit with:")
+
+	data, err := os.ReadFile(filepath.Join(dataDirectory, "conduit.yml"))
+	require.NoError(t, err)
+	var cfg pipeline.Config
+	require.NoError(t, yaml.Unmarshal(data, &cfg))
+	assert.Equal(t, fileimporter.PluginName, cfg.Importer.Name)
+	assert.Equal(t, blockDir, cfg.Importer.Config["block-dir"])
+	assert.Equal(t, "5s", cfg.Importer.Config["retry-duration"])
+	assert.Equal(t, "", cfg.Importer.Config["compression"])
+	require.Len(t, cfg.Processors, 1)
+	assert.Equal(t, filterprocessor.PluginName, cfg.Processors[0].Name)
+	assert.Equal(t, noopExporter.PluginName, cfg.Exporter.Name)
+	// The sample comments are kept.
+	assert.Contains(t, string(data), "# RetryDuration controls the delay")
+}
@@ -32,6 +32,16 @@ func makeDetailsCommand(use string, data func() []conduit.Metadata) *cobra.Comma
 		Aliases: []string{use},
 		Short:   fmt.Sprintf("usage detail for %s plugins", use),
 		Args:    cobra.MaximumNArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			var names []string
+			for _, m := range data() {
+				names = append(names, fmt.Sprintf("%s\t%s", m.Name, m.Description))
+			}
+			return names, cobra.ShellCompDirectiveNoFileComp
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
 				printMetadata(os.Stdout, data(), 0)
@@ -46,6 +56,7 @@ func init() {
 	Command.AddCommand(makeDetailsCommand("importer", pipeline.ImporterMetadata))
 	Command.AddCommand(makeDetailsCommand("processor", pipeline.ProcessorMetadata))
 	Command.AddCommand(makeDetailsCommand("exporter", pipeline.ExporterMetadata))
+	Command.AddCommand(makeDetailsCommand("observer", pipeline.ObserverMetadata))
 }
 
 func printDetails(name string, plugins []conduit.Metadata) {
@@ -91,4 +102,6 @@ func printAll() {
 	printMetadata(os.Stdout, pipeline.ProcessorMetadata(), 2)
 	fmt.Fprint(os.Stdout, "\nexporters:\n")
 	printMetadata(os.Stdout, pipeline.ExporterMetadata(), 2)
+	fmt.Fprint(os.Stdout, "\nobservers:\n")
+	printMetadata(os.Stdout, pipeline.ObserverMetadata(), 2)
 }
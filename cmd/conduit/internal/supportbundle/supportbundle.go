@@ -0,0 +1,71 @@
+package supportbundle
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/pipeline"
+)
+
+// Command is the support-bundle command to embed in a root cobra command.
+var Command = makeCommand()
+
+func runSupportBundle(dataDir string, output string) error {
+	if dataDir == "" {
+		dataDir = os.Getenv("CONDUIT_DATA_DIR")
+	}
+	if dataDir == "" {
+		return fmt.Errorf("runSupportBundle(): a data directory is required")
+	}
+
+	// Collect as much as possible even if the configuration is invalid.
+	bundle := pipeline.SupportBundle{
+		DataDir: dataDir,
+		Reason:  "requested with conduit support-bundle",
+	}
+	bundle.Config, bundle.ConfigErr = pipeline.MakePipelineConfig(&conduit.Args{ConduitDataDir: dataDir})
+
+	if output == "" {
+		output = pipeline.SupportBundlePath(dataDir, time.Now())
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("runSupportBundle(): unable to create %s: %w", output, err)
+	}
+	defer f.Close()
+	if err = bundle.Write(f); err != nil {
+		return fmt.Errorf("runSupportBundle(): %w", err)
+	}
+
+	fmt.Printf("Support bundle written to %s\n", output)
+	if bundle.ConfigErr != nil {
+		fmt.Printf("Warning: the configuration could not be loaded: %v\n", bundle.ConfigErr)
+	}
+	return nil
+}
+
+func makeCommand() *cobra.Command {
+	var dataDir string
+	var output string
+	cmd := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "creates a support bundle for bug reports",
+		Long: `Creates a zip archive with the information needed to debug a problem: the
+conduit configuration with plugin configs and other strings redacted,
+metadata.json, the tail of the log file and runtime profiles. Attach the
+archive to bug reports.`,
+		Example: "conduit support-bundle -d /path/to/data",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSupportBundle(dataDir, output)
+		},
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringVarP(&dataDir, "data-dir", "d", "", "conduit data directory")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "output file. Defaults to a timestamped file in the data directory")
+	return cmd
+}
@@ -0,0 +1,87 @@
+package testrunner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/algorand/conduit/conduit/loggers"
+	"github.com/algorand/conduit/conduit/pipeline"
+)
+
+// Command is the test command to embed in a root cobra command.
+var Command = makeCommand()
+
+func runTests(paths []string, run string, verbose bool) error {
+	var pattern *regexp.Regexp
+	if run != "" {
+		var err error
+		if pattern, err = regexp.Compile(run); err != nil {
+			return fmt.Errorf("runTests(): invalid --run pattern: %w", err)
+		}
+	}
+	files, err := pipeline.FindTestFiles(paths)
+	if err != nil {
+		return fmt.Errorf("runTests(): %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("runTests(): no test files were found, their names end with %v", pipeline.TestFileSuffixes)
+	}
+
+	var logOutput io.Writer = io.Discard
+	if verbose {
+		logOutput = os.Stdout
+	}
+	logger := loggers.MakeThreadSafeLoggerWithWriter(log.InfoLevel, logOutput)
+	report, err := pipeline.RunPipelineTests(context.Background(), files, pattern, logger)
+	if err != nil {
+		return fmt.Errorf("runTests(): %w", err)
+	}
+	if err = report.Write(os.Stdout); err != nil {
+		return fmt.Errorf("runTests(): %w", err)
+	}
+	if !report.Passed() {
+		return fmt.Errorf("some pipeline tests failed")
+	}
+	return nil
+}
+
+func makeRunCommand() *cobra.Command {
+	var run string
+	var verbose bool
+	cmd := &cobra.Command{
+		Use:   "run [paths]",
+		Short: "runs the pipeline tests",
+		Long: `Runs the pipeline tests of the given files and directories, directories are
+searched recursively for files ending with .test.yml or .test.yaml. Each test
+imports its input blocks into the pipeline config under test, in a temporary
+data directory, and checks what the exporters received and the metrics.
+Exits with an error if a test fails.`,
+		Example: "conduit test run ./tests/ --run keyreg",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTests(args, run, verbose)
+		},
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringVar(&run, "run", "", "only run the tests whose name matches the regular expression")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "print the pipeline logs")
+	return cmd
+}
+
+func makeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "tests pipeline configs with declarative YAML tests",
+		Long: `Tests pipeline configs like code: a test file declares the input blocks, the
+pipeline config under test and the expected exporter outputs and metrics.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.AddCommand(makeRunCommand())
+	return cmd
+}
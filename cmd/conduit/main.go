@@ -12,13 +12,21 @@ import (
 
 	"github.com/algorand/indexer/version"
 
+	"github.com/algorand/conduit/cmd/conduit/internal/control"
+	"github.com/algorand/conduit/cmd/conduit/internal/convert"
+	"github.com/algorand/conduit/cmd/conduit/internal/deadletter"
+	"github.com/algorand/conduit/cmd/conduit/internal/diff"
+	"github.com/algorand/conduit/cmd/conduit/internal/doctor"
 	"github.com/algorand/conduit/cmd/conduit/internal/initialize"
 	"github.com/algorand/conduit/cmd/conduit/internal/list"
+	"github.com/algorand/conduit/cmd/conduit/internal/supportbundle"
+	"github.com/algorand/conduit/cmd/conduit/internal/testrunner"
 	"github.com/algorand/conduit/conduit"
 	"github.com/algorand/conduit/conduit/loggers"
 	"github.com/algorand/conduit/conduit/pipeline"
 	_ "github.com/algorand/conduit/conduit/plugins/exporters/all"
 	_ "github.com/algorand/conduit/conduit/plugins/importers/all"
+	_ "github.com/algorand/conduit/conduit/plugins/observers/all"
 	_ "github.com/algorand/conduit/conduit/plugins/processors/all"
 )
 
@@ -33,6 +41,19 @@ var (
 func init() {
 	conduitCmd.AddCommand(initialize.InitCommand)
 	conduitCmd.AddCommand(list.Command)
+	conduitCmd.AddCommand(supportbundle.Command)
+	conduitCmd.AddCommand(deadletter.ReplayCommand)
+	conduitCmd.AddCommand(convert.Command)
+	conduitCmd.AddCommand(doctor.Command)
+	conduitCmd.AddCommand(control.PauseCommand)
+	conduitCmd.AddCommand(control.ResumeCommand)
+	conduitCmd.AddCommand(control.StepCommand)
+	conduitCmd.AddCommand(control.CutoverCommand)
+	conduitCmd.AddCommand(control.PromoteCommand)
+	conduitCmd.AddCommand(control.RollbackCommand)
+	conduitCmd.AddCommand(control.TopCommand)
+	conduitCmd.AddCommand(testrunner.Command)
+	conduitCmd.AddCommand(diff.Command)
 }
 
 // runConduitCmdWithConfig run the main logic with a supplied conduit config
@@ -54,15 +75,25 @@ func runConduitCmdWithConfig(args *conduit.Args) error {
 		return fmt.Errorf("runConduitCmdWithConfig(): invalid log level: %s", err)
 	}
 
-	logger, err = loggers.MakeThreadSafeLogger(level, pCfg.LogFile)
-	if err != nil {
-		return fmt.Errorf("runConduitCmdWithConfig(): failed to create logger: %w", err)
+	// Pretty output is only used for the console, and not when a log format
+	// is configured.
+	if pCfg.LogFile != "" || pCfg.LogFormat != "" {
+		args.Pretty = false
+	}
+	if args.Pretty {
+		logger = loggers.MakePrettyThreadSafeLoggerWithWriter(level, os.Stdout)
+	} else {
+		logger, err = loggers.MakeThreadSafeLogger(level, pCfg.LogFile)
+		if err != nil {
+			return fmt.Errorf("runConduitCmdWithConfig(): failed to create logger: %w", err)
+		}
+		logger.SetFormatter(pipeline.MakeLogFormatter(pCfg.LogFormat, "Conduit", "main"))
 	}
 
 	logger.Infof("Using data directory: %s", args.ConduitDataDir)
 	logger.Info("Conduit configuration is valid")
 
-	if !pCfg.HideBanner {
+	if !pCfg.HideBanner && !args.DryRun {
 		fmt.Print(banner)
 	}
 
@@ -73,7 +104,7 @@ func runConduitCmdWithConfig(args *conduit.Args) error {
 	}
 
 	ctx := context.Background()
-	pipeline, err := pipeline.MakePipeline(ctx, pCfg, logger)
+	p, err := pipeline.MakePipeline(ctx, pCfg, logger)
 	if err != nil {
 		err = fmt.Errorf("pipeline creation error: %w", err)
 
@@ -85,14 +116,30 @@ func runConduitCmdWithConfig(args *conduit.Args) error {
 		return err
 	}
 
-	err = pipeline.Init()
+	if args.DryRun {
+		summary, err := p.Validate()
+		if err != nil {
+			return fmt.Errorf("pipeline validation error: %w", err)
+		}
+		fmt.Print(summary)
+		fmt.Println("Pipeline is valid, exiting without processing rounds.")
+		return nil
+	}
+
+	err = p.Init()
 	if err != nil {
 		return fmt.Errorf("pipeline init error: %w", err)
 	}
-	pipeline.Start()
-	defer pipeline.Stop()
-	pipeline.Wait()
-	return pipeline.Error()
+	stopSignals := pipeline.StopOnSignal(p, logger)
+	defer stopSignals()
+	reloadSignals := pipeline.ReloadOnSignal(p, logger, func() (*pipeline.Config, error) {
+		return pipeline.MakePipelineConfig(args)
+	})
+	defer reloadSignals()
+	p.Start()
+	defer p.Stop()
+	p.Wait()
+	return p.Error()
 }
 
 // makeConduitCmd creates the main cobra command, initializes flags
@@ -105,6 +152,9 @@ func makeConduitCmd() *cobra.Command {
 		Long:  "run the conduit framework",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("pretty") {
+				cfg.Pretty = isTerminal(os.Stdout)
+			}
 			return runConduitCmdWithConfig(cfg)
 		},
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
@@ -119,12 +169,24 @@ func makeConduitCmd() *cobra.Command {
 		SilenceErrors: true,
 	}
 	cmd.Flags().StringVarP(&cfg.ConduitDataDir, "data-dir", "d", "", "set the data directory for the conduit binary")
+	_ = cmd.MarkFlagDirname("data-dir")
 	cmd.Flags().Uint64VarP(&cfg.NextRoundOverride, "next-round-override", "r", 0, "set the starting round. Overrides next-round in metadata.json")
 	cmd.Flags().BoolVarP(&vFlag, "version", "v", false, "print the conduit version")
+	cmd.Flags().BoolVar(&cfg.DryRun, "dry-run", false, "initialize every plugin, validate the config and connectivity, print the resolved config and exit without processing rounds")
+	cmd.Flags().BoolVar(&cfg.Pretty, "pretty", false, "human-friendly colored console output. Enabled by default when writing to a terminal")
 
 	return cmd
 }
 
+// isTerminal reports whether the file is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 func main() {
 	// Hidden command to generate docs in a given directory
 	// conduit generate-docs [path]
@@ -0,0 +1,197 @@
+package conduit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// AmountEncoding is how amounts, such as payment and asset transfer amounts,
+// fees and rewards, are represented by exporters which write text formats.
+type AmountEncoding string
+
+const (
+	// AmountInteger writes amounts as JSON integers of microalgos or asset
+	// base units, like algod. It is the default. Consumers which parse JSON
+	// numbers as floats lose precision above 2^53.
+	AmountInteger AmountEncoding = "integer"
+	// AmountString writes amounts as strings of microalgos or asset base
+	// units.
+	AmountString AmountEncoding = "string"
+	// AmountDecimal writes amounts as decimal strings adjusted by the decimals
+	// of the asset, Algo amounts have 6 decimals. Amounts of assets whose
+	// decimals are unknown are written as strings of base units.
+	AmountDecimal AmountEncoding = "decimal"
+)
+
+// AlgoDecimals is the number of decimals of the Algo, amounts are microalgos.
+const AlgoDecimals = 6
+
+// MaxAssetDecimals is the largest number of decimals of an asset.
+const MaxAssetDecimals = 19
+
+// Valid validates the amount encoding, empty is the default integer.
+func (e AmountEncoding) Valid() error {
+	switch e {
+	case "", AmountInteger, AmountString, AmountDecimal:
+		return nil
+	}
+	return fmt.Errorf("unknown amount encoding (%s), expected %s, %s or %s", e, AmountInteger, AmountString, AmountDecimal)
+}
+
+// FormatDecimal returns the decimal representation of an integer amount
+// string with the number of decimals, for example 1500000 with 6 decimals is
+// "1.500000".
+func FormatDecimal(amount string, decimals uint32) string {
+	if decimals == 0 {
+		return amount
+	}
+	if pad := int(decimals) + 1 - len(amount); pad > 0 {
+		amount = strings.Repeat("0", pad) + amount
+	}
+	point := len(amount) - int(decimals)
+	return amount[:point] + "." + amount[point:]
+}
+
+// AmountFormat rewrites the amounts of JSON encoded blocks and transactions
+// with an amount encoding. The decimals of assets are configured, or learned
+// from the asset creations it rewrites. It is shared by the exporters of a
+// pipeline and is safe for concurrent use.
+type AmountFormat struct {
+	encoding AmountEncoding
+
+	mu       sync.RWMutex
+	decimals map[uint64]uint32
+}
+
+// MakeAmountFormat returns an AmountFormat with the decimals of the assets.
+func MakeAmountFormat(encoding AmountEncoding, assetDecimals map[uint64]uint32) *AmountFormat {
+	decimals := make(map[uint64]uint32, len(assetDecimals))
+	for id, d := range assetDecimals {
+		decimals[id] = d
+	}
+	return &AmountFormat{encoding: encoding, decimals: decimals}
+}
+
+// Encoding returns the amount encoding.
+func (f *AmountFormat) Encoding() AmountEncoding {
+	return f.encoding
+}
+
+// AssetDecimals returns the decimals of the asset, if they are known.
+func (f *AmountFormat) AssetDecimals(id uint64) (uint32, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	d, ok := f.decimals[id]
+	return d, ok
+}
+
+func (f *AmountFormat) learn(id uint64, decimals uint32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.decimals[id]; !ok {
+		f.decimals[id] = decimals
+	}
+}
+
+// Rewrite rewrites the amounts of the signed transactions in the JSON
+// document, such as a block or a message with a transaction, and encodes it
+// again with the indent. Documents are returned as is with the integer
+// encoding.
+func (f *AmountFormat) Rewrite(doc []byte, indent string) ([]byte, error) {
+	if f.encoding == "" || f.encoding == AmountInteger {
+		return doc, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	// Numbers are kept as their text so that no precision is lost.
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("AmountFormat.Rewrite(): unable to decode: %w", err)
+	}
+	f.walk(v)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("AmountFormat.Rewrite(): unable to encode: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// walk rewrites each signed transaction, which is an object with a "txn"
+// transaction that has a type. Inner transactions are walked after the
+// transaction which issued them, so that their asset creations are learned in
+// order.
+func (f *AmountFormat) walk(v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if txn, ok := t["txn"].(map[string]interface{}); ok && txn["type"] != nil {
+			f.rewriteTxn(t, txn)
+		}
+		for _, child := range t {
+			f.walk(child)
+		}
+	case []interface{}:
+		for _, child := range t {
+			f.walk(child)
+		}
+	}
+}
+
+// rewriteTxn rewrites the amounts of the transaction, and of the apply data
+// in the signed transaction.
+func (f *AmountFormat) rewriteTxn(stxn, txn map[string]interface{}) {
+	for _, key := range []string{"fee", "amt"} {
+		f.rewriteAmount(txn, key, AlgoDecimals, true)
+	}
+	for _, key := range []string{"ca", "rs", "rr", "rc"} {
+		f.rewriteAmount(stxn, key, AlgoDecimals, true)
+	}
+
+	if xaid, ok := uintField(txn, "xaid"); ok {
+		decimals, known := f.AssetDecimals(xaid)
+		f.rewriteAmount(txn, "aamt", decimals, known)
+		f.rewriteAmount(stxn, "aca", decimals, known)
+	}
+
+	// The decimals of an asset are set when it is created, the created asset
+	// is in the apply data.
+	if apar, ok := txn["apar"].(map[string]interface{}); ok {
+		decimals, _ := uintField(apar, "dc")
+		if _, reconfig := txn["caid"]; !reconfig {
+			if id, ok := uintField(stxn, "caid"); ok {
+				f.learn(id, uint32(decimals))
+			}
+		}
+		f.rewriteAmount(apar, "t", uint32(decimals), true)
+	}
+}
+
+func (f *AmountFormat) rewriteAmount(obj map[string]interface{}, key string, decimals uint32, known bool) {
+	n, ok := obj[key].(json.Number)
+	if !ok {
+		return
+	}
+	amount := n.String()
+	if _, err := strconv.ParseUint(amount, 10, 64); err != nil {
+		return
+	}
+	if f.encoding == AmountDecimal && known {
+		amount = FormatDecimal(amount, decimals)
+	}
+	obj[key] = amount
+}
+
+func uintField(obj map[string]interface{}, key string) (uint64, bool) {
+	n, ok := obj[key].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(n.String(), 10, 64)
+	return v, err == nil
+}
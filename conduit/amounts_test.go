@@ -0,0 +1,125 @@
+package conduit
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmountEncodingValid(t *testing.T) {
+	for _, e := range []AmountEncoding{"", AmountInteger, AmountString, AmountDecimal} {
+		assert.NoError(t, e.Valid())
+	}
+	assert.EqualError(t, AmountEncoding("float").Valid(), "unknown amount encoding (float), expected integer, string or decimal")
+}
+
+func TestFormatDecimal(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals uint32
+		expected string
+	}{
+		{"1500000", 6, "1.500000"},
+		{"1", 6, "0.000001"},
+		{"0", 2, "0.00"},
+		{"123", 0, "123"},
+		{"18446744073709551615", 19, "1.8446744073709551615"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, FormatDecimal(tc.amount, tc.decimals))
+	}
+}
+
+// amountsBlock has an asset creation, a transfer of the created asset and of
+// an unknown asset, and a payment with a closing amount in an inner
+// transaction.
+const amountsBlock = `{
+  "rnd": 10,
+  "txns": [
+    {"txn": {"type": "acfg", "fee": 1000, "apar": {"t": 1000000, "dc": 2, "un": "<u&>"}}, "caid": 7},
+    {"txn": {"type": "axfer", "xaid": 7, "aamt": 12345}, "aca": 5},
+    {"txn": {"type": "axfer", "xaid": 8, "aamt": 18446744073709551615}},
+    {"txn": {"type": "appl"}, "dt": {"itx": [{"txn": {"type": "pay", "amt": 1500000}, "ca": 1}]}}
+  ]
+}`
+
+func rewriteBlock(t *testing.T, f *AmountFormat, indent string) map[string]interface{} {
+	b, err := f.Rewrite([]byte(amountsBlock), indent)
+	require.NoError(t, err)
+	var out map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	require.NoError(t, dec.Decode(&out))
+	return out
+}
+
+func TestAmountFormatRewrite(t *testing.T) {
+	field := func(blk map[string]interface{}, path ...interface{}) interface{} {
+		var v interface{} = blk
+		for _, p := range path {
+			switch k := p.(type) {
+			case string:
+				v = v.(map[string]interface{})[k]
+			case int:
+				v = v.([]interface{})[k]
+			}
+		}
+		return v
+	}
+	tests := []struct {
+		encoding AmountEncoding
+		fee      interface{}
+		total    interface{}
+		aamt     interface{}
+		aca      interface{}
+		unknown  interface{}
+		amt      interface{}
+		ca       interface{}
+	}{
+		{AmountString, "1000", "1000000", "12345", "5", "18446744073709551615", "1500000", "1"},
+		{AmountDecimal, "0.001000", "10000.00", "123.45", "0.05", "18446744073709551615", "1.500000", "0.000001"},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.encoding), func(t *testing.T) {
+			blk := rewriteBlock(t, MakeAmountFormat(tc.encoding, nil), "")
+			assert.Equal(t, tc.fee, field(blk, "txns", 0, "txn", "fee"))
+			assert.Equal(t, tc.total, field(blk, "txns", 0, "txn", "apar", "t"))
+			assert.Equal(t, "<u&>", field(blk, "txns", 0, "txn", "apar", "un"))
+			assert.Equal(t, tc.aamt, field(blk, "txns", 1, "txn", "aamt"))
+			assert.Equal(t, tc.aca, field(blk, "txns", 1, "aca"))
+			assert.Equal(t, tc.unknown, field(blk, "txns", 2, "txn", "aamt"))
+			assert.Equal(t, tc.amt, field(blk, "txns", 3, "dt", "itx", 0, "txn", "amt"))
+			assert.Equal(t, tc.ca, field(blk, "txns", 3, "dt", "itx", 0, "ca"))
+			// Other numbers are not rewritten.
+			assert.Equal(t, json.Number("10"), field(blk, "rnd"))
+		})
+	}
+
+	// Configured decimals are used for assets created before.
+	f := MakeAmountFormat(AmountDecimal, map[uint64]uint32{8: 19})
+	blk := rewriteBlock(t, f, "  ")
+	assert.Equal(t, "1.8446744073709551615", field(blk, "txns", 2, "txn", "aamt"))
+	decimals, ok := f.AssetDecimals(7)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(2), decimals)
+}
+
+func TestAmountFormatInteger(t *testing.T) {
+	doc := []byte(`{"txn":{"type":"pay","amt":1}}`)
+	for _, e := range []AmountEncoding{"", AmountInteger} {
+		b, err := MakeAmountFormat(e, nil).Rewrite(doc, "")
+		require.NoError(t, err)
+		assert.Equal(t, doc, b)
+	}
+
+	// Messages with a transaction are rewritten, and HTML is not escaped.
+	b, err := MakeAmountFormat(AmountString, nil).Rewrite([]byte(`{"round":5,"txn":{"txn":{"type":"pay","amt":1,"note":"<>"}}}`), "")
+	require.NoError(t, err)
+	assert.Equal(t, `{"round":5,"txn":{"txn":{"amt":"1","note":"<>","type":"pay"}}}`, string(b))
+
+	_, err = MakeAmountFormat(AmountString, nil).Rewrite([]byte(`{`), "")
+	assert.ErrorContains(t, err, "AmountFormat.Rewrite(): unable to decode")
+}
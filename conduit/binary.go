@@ -0,0 +1,110 @@
+package conduit
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/json"
+	"github.com/algorand/go-codec/codec"
+)
+
+// BinaryEncoding is how binary fields, such as notes, application arguments
+// and box values, are represented by exporters which write text formats.
+type BinaryEncoding string
+
+const (
+	// BinaryBase64 writes binary fields as standard base64, like algod. It is
+	// the default.
+	BinaryBase64 BinaryEncoding = "base64"
+	// BinaryHex writes binary fields as lower case hex.
+	BinaryHex BinaryEncoding = "hex"
+	// BinaryUTF8 writes binary fields as UTF-8 text, invalid sequences are
+	// replaced with U+FFFD. It is lossy, the output cannot be decoded back
+	// into the original bytes.
+	BinaryUTF8 BinaryEncoding = "utf8"
+)
+
+// Valid validates the binary encoding, empty is the default base64.
+func (e BinaryEncoding) Valid() error {
+	switch e {
+	case "", BinaryBase64, BinaryHex, BinaryUTF8:
+		return nil
+	}
+	return fmt.Errorf("unknown binary encoding (%s), expected %s, %s or %s", e, BinaryBase64, BinaryHex, BinaryUTF8)
+}
+
+// EncodeToString returns the text representation of b.
+func (e BinaryEncoding) EncodeToString(b []byte) string {
+	switch e {
+	case BinaryHex:
+		return hex.EncodeToString(b)
+	case BinaryUTF8:
+		return strings.ToValidUTF8(string(b), "\uFFFD")
+	default:
+		return base64.StdEncoding.EncodeToString(b)
+	}
+}
+
+// DecodeString decodes the text representation of binary data. UTF-8 text
+// is returned as is.
+func (e BinaryEncoding) DecodeString(s string) ([]byte, error) {
+	switch e {
+	case BinaryHex:
+		return hex.DecodeString(s)
+	case BinaryUTF8:
+		return []byte(s), nil
+	default:
+		return base64.StdEncoding.DecodeString(s)
+	}
+}
+
+// JSONHandle returns a JSON handle with the go-algorand-sdk settings which
+// writes binary fields with the encoding.
+func (e BinaryEncoding) JSONHandle(indent int8) *codec.JsonHandle {
+	handle := new(codec.JsonHandle)
+	handle.ErrorIfNoField = json.CodecHandle.ErrorIfNoField
+	handle.ErrorIfNoArrayExpand = json.CodecHandle.ErrorIfNoArrayExpand
+	handle.Canonical = json.CodecHandle.Canonical
+	handle.RecursiveEmptyCheck = json.CodecHandle.RecursiveEmptyCheck
+	handle.HTMLCharsAsIs = json.CodecHandle.HTMLCharsAsIs
+	handle.MapKeyAsString = true
+	handle.Indent = indent
+	if e != "" && e != BinaryBase64 {
+		// The handle writes base64 without an extension.
+		handle.RawBytesExt = binaryExt{encoding: e}
+	}
+	return handle
+}
+
+// binaryExt is the codec extension which encodes raw bytes.
+type binaryExt struct {
+	encoding BinaryEncoding
+}
+
+func (x binaryExt) ConvertExt(v interface{}) interface{} {
+	switch b := v.(type) {
+	case []byte:
+		return x.encoding.EncodeToString(b)
+	case *[]byte:
+		return x.encoding.EncodeToString(*b)
+	}
+	return v
+}
+
+func (x binaryExt) UpdateExt(dst interface{}, src interface{}) {
+	out, ok := dst.(*[]byte)
+	if !ok {
+		return
+	}
+	s, ok := src.(string)
+	if !ok {
+		return
+	}
+	b, err := x.encoding.DecodeString(s)
+	if err != nil {
+		panic(fmt.Errorf("unable to decode %s binary field: %w", x.encoding, err))
+	}
+	*out = b
+}
@@ -0,0 +1,58 @@
+package conduit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryEncodingValid(t *testing.T) {
+	for _, e := range []BinaryEncoding{"", BinaryBase64, BinaryHex, BinaryUTF8} {
+		assert.NoError(t, e.Valid())
+	}
+	assert.EqualError(t, BinaryEncoding("base32").Valid(), "unknown binary encoding (base32), expected base64, hex or utf8")
+}
+
+func TestBinaryEncodingEncode(t *testing.T) {
+	tests := []struct {
+		encoding BinaryEncoding
+		input    []byte
+		expected string
+	}{
+		{"", []byte("hello"), "aGVsbG8="},
+		{BinaryBase64, []byte("hello"), "aGVsbG8="},
+		{BinaryHex, []byte("hello"), "68656c6c6f"},
+		{BinaryUTF8, []byte("hello"), "hello"},
+		{BinaryUTF8, []byte{'h', 0xff, 'i'}, "h�i"},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.encoding)+"/"+tc.expected, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.encoding.EncodeToString(tc.input))
+
+			ext := binaryExt{encoding: tc.encoding}
+			assert.Equal(t, tc.expected, ext.ConvertExt(tc.input))
+			assert.Equal(t, tc.expected, ext.ConvertExt(&tc.input))
+
+			var out []byte
+			ext.UpdateExt(&out, tc.expected)
+			decoded, err := tc.encoding.DecodeString(tc.expected)
+			require.NoError(t, err)
+			assert.Equal(t, decoded, out)
+		})
+	}
+}
+
+func TestBinaryEncodingJSONHandle(t *testing.T) {
+	assert.Nil(t, BinaryBase64.JSONHandle(0).RawBytesExt)
+	assert.Nil(t, BinaryEncoding("").JSONHandle(0).RawBytesExt)
+	h := BinaryHex.JSONHandle(2)
+	assert.Equal(t, binaryExt{encoding: BinaryHex}, h.RawBytesExt)
+	assert.Equal(t, int8(2), h.Indent)
+	assert.True(t, h.MapKeyAsString)
+
+	assert.Panics(t, func() {
+		var out []byte
+		binaryExt{encoding: BinaryHex}.UpdateExt(&out, "zz")
+	})
+}
@@ -0,0 +1,245 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/hamba/avro/v2"
+)
+
+func init() {
+	RegisterEncoding(Avro, avroEncoding{})
+}
+
+// AvroSchema is the schema of the "avro" encoding, in the Parsing Canonical
+// Form of the Avro specification. A Value is a union of the types of the
+// msgpack values, the integers greater than the largest long are a Uint64
+// with the big-endian bytes of the integer. A Map has the entries of a map
+// sorted by the encoding of their key.
+const AvroSchema = `{"name":"conduit.codec.Value","type":"record","fields":[{"name":"value","type":["null","boolean","long",{"name":"conduit.codec.Uint64","type":"fixed","size":8},"double","bytes","string",{"type":"array","items":"conduit.codec.Value"},{"name":"conduit.codec.Map","type":"record","fields":[{"name":"entries","type":{"type":"array","items":{"name":"conduit.codec.Entry","type":"record","fields":[{"name":"key","type":"conduit.codec.Value"},{"name":"value","type":"conduit.codec.Value"}]}}}]}]}]}`
+
+// The named types of AvroSchema, which are the branches of the Value union
+// besides the primitive types and the array.
+const (
+	avroUint64 = "conduit.codec.Uint64"
+	avroMap    = "conduit.codec.Map"
+)
+
+// avroMarker starts the values of the Avro single-object encoding, it is
+// followed by the fingerprint of the schema.
+var avroMarker = []byte{0xC3, 0x01}
+
+var avroSchema = avro.MustParse(AvroSchema)
+
+// avroFingerprint is the CRC-64-AVRO fingerprint of AvroSchema.
+var avroFingerprint = mustFingerprint(avroSchema)
+
+func mustFingerprint(schema avro.Schema) uint64 {
+	fingerprint, err := schema.FingerprintUsing(avro.CRC64Avro)
+	if err != nil {
+		panic(err)
+	}
+	return binary.BigEndian.Uint64(fingerprint)
+}
+
+// avroEncoding writes each value with the Avro single-object encoding: the
+// marker, the fingerprint of AvroSchema and the binary encoding of a Value.
+type avroEncoding struct{}
+
+func (avroEncoding) Extension() string {
+	return ".avro"
+}
+
+func (avroEncoding) Encode(w io.Writer, v interface{}) error {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return fmt.Errorf("avro: %w", err)
+	}
+	native, err := toAvro(generic)
+	if err != nil {
+		return fmt.Errorf("avro: %w", err)
+	}
+	body, err := avro.Marshal(avroSchema, native)
+	if err != nil {
+		return fmt.Errorf("avro: %w", err)
+	}
+	msg := append([]byte{}, avroMarker...)
+	msg = binary.LittleEndian.AppendUint64(msg, avroFingerprint)
+	_, err = w.Write(append(msg, body...))
+	return err
+}
+
+func (avroEncoding) Decode(r io.Reader, v interface{}) error {
+	var header [10]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return err
+	}
+	if !bytes.Equal(header[:2], avroMarker) {
+		return errors.New("avro: not a single-object encoded value")
+	}
+	if fingerprint := binary.LittleEndian.Uint64(header[2:]); fingerprint != avroFingerprint {
+		return fmt.Errorf("avro: unknown schema fingerprint %#x", fingerprint)
+	}
+	// The reader reads a byte at a time, so that the next value is not read.
+	ar := avro.NewReader(r, 1)
+	var native interface{}
+	ar.ReadVal(avroSchema, &native)
+	if ar.Error != nil {
+		return fmt.Errorf("avro: %w", unexpectedEOF(ar.Error))
+	}
+	generic, err := fromAvro(native)
+	if err != nil {
+		return fmt.Errorf("avro: %w", err)
+	}
+	return fromGeneric(generic, v)
+}
+
+// avroValue is a Value record with the union branch and its value.
+func avroValue(branch string, v interface{}) map[string]interface{} {
+	return map[string]interface{}{"value": map[string]interface{}{branch: v}}
+}
+
+// toAvro returns the Value record of a generic value, in the form written by
+// the avro package.
+func toAvro(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil:
+		return map[string]interface{}{"value": nil}, nil
+	case bool:
+		return avroValue("boolean", v), nil
+	case int64:
+		return avroValue("long", v), nil
+	case uint64:
+		if v <= math.MaxInt64 {
+			return avroValue("long", int64(v)), nil
+		}
+		var x [8]byte
+		binary.BigEndian.PutUint64(x[:], v)
+		return avroValue(avroUint64, x), nil
+	case float64:
+		return avroValue("double", v), nil
+	case []byte:
+		return avroValue("bytes", v), nil
+	case string:
+		return avroValue("string", v), nil
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if items[i], err = toAvro(item); err != nil {
+				return nil, err
+			}
+		}
+		return avroValue("array", items), nil
+	case map[interface{}]interface{}:
+		entries := make([]interface{}, 0, len(v))
+		for key, value := range v {
+			avroKey, err := toAvro(key)
+			if err != nil {
+				return nil, err
+			}
+			avroValue, err := toAvro(value)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, map[string]interface{}{"key": avroKey, "value": avroValue})
+		}
+		// The entries are sorted by the encoding of their key, so that a value
+		// is always encoded to the same bytes.
+		keys := make([][]byte, len(entries))
+		for i, entry := range entries {
+			var err error
+			if keys[i], err = avro.Marshal(avroSchema, entry.(map[string]interface{})["key"]); err != nil {
+				return nil, err
+			}
+		}
+		sort.Sort(byKey{keys: keys, entries: entries})
+		return avroValue(avroMap, map[string]interface{}{"entries": entries}), nil
+	}
+	return nil, unsupportedValue(v)
+}
+
+// byKey sorts the entries of a Map by their encoded keys.
+type byKey struct {
+	keys    [][]byte
+	entries []interface{}
+}
+
+func (b byKey) Len() int {
+	return len(b.keys)
+}
+
+func (b byKey) Less(i, j int) bool {
+	return bytes.Compare(b.keys[i], b.keys[j]) < 0
+}
+
+func (b byKey) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.entries[i], b.entries[j] = b.entries[j], b.entries[i]
+}
+
+// fromAvro returns the generic value of a Value record read by the avro
+// package.
+func fromAvro(native interface{}) (interface{}, error) {
+	record, ok := native.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected value of type %T", native)
+	}
+	union, ok := record["value"].(map[string]interface{})
+	if !ok || len(union) != 1 {
+		// The null branch.
+		return nil, nil
+	}
+	for branch, v := range union {
+		switch branch {
+		case "boolean", "double", "bytes", "string":
+			return v, nil
+		case "long":
+			switch x := v.(type) {
+			case int64:
+				return x, nil
+			case int:
+				return int64(x), nil
+			}
+		case avroUint64:
+			if x, ok := v.([8]byte); ok {
+				return binary.BigEndian.Uint64(x[:]), nil
+			}
+		case "array":
+			items, _ := v.([]interface{})
+			list := make([]interface{}, len(items))
+			for i, item := range items {
+				var err error
+				if list[i], err = fromAvro(item); err != nil {
+					return nil, err
+				}
+			}
+			return list, nil
+		case avroMap:
+			m := make(map[interface{}]interface{})
+			entries, _ := v.(map[string]interface{})["entries"].([]interface{})
+			for _, entry := range entries {
+				kv, _ := entry.(map[string]interface{})
+				key, err := fromAvro(kv["key"])
+				if err != nil {
+					return nil, err
+				}
+				value, err := fromAvro(kv["value"])
+				if err != nil {
+					return nil, err
+				}
+				if err = setMapEntry(m, key, value); err != nil {
+					return nil, err
+				}
+			}
+			return m, nil
+		}
+		return nil, fmt.Errorf("unexpected %s value of type %T", branch, v)
+	}
+	return nil, nil
+}
@@ -0,0 +1,142 @@
+// Package codec is the registry of the compressions and encodings which the
+// plugins reference by name in their config. A plugin which writes with a
+// codec and a plugin which reads with the same codec use the same code, so
+// anything one plugin writes another can read.
+package codec
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// The names of the registered compressions.
+const (
+	None   = "none"
+	Gzip   = "gzip"
+	Zstd   = "zstd"
+	LZ4    = "lz4"
+	Snappy = "snappy"
+)
+
+// The names of the registered encodings.
+const (
+	JSON     = "json"
+	Msgpack  = "msgpack"
+	Protobuf = "protobuf"
+	Avro     = "avro"
+)
+
+// Compression compresses and decompresses streams.
+type Compression interface {
+	// Extension is the filename extension of the compressed files, such as
+	// ".gz". It is empty for None.
+	Extension() string
+	// NewWriter returns a writer compressing to w, the compressed stream is
+	// complete once it is closed. Closing it does not close w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader decompressing r. Closing it does not close r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// Encoding serializes values.
+type Encoding interface {
+	// Extension is the filename extension of the encoded files, such as
+	// ".json".
+	Extension() string
+	Encode(w io.Writer, v interface{}) error
+	// Decode decodes into v, unknown fields are ignored.
+	Decode(r io.Reader, v interface{}) error
+}
+
+// Compressions are the registered compressions, by name.
+var Compressions = make(map[string]Compression)
+
+// Encodings are the registered encodings, by name.
+var Encodings = make(map[string]Encoding)
+
+// RegisterCompression is used to register compressions, it is called from
+// init functions.
+func RegisterCompression(name string, compression Compression) {
+	Compressions[name] = compression
+}
+
+// RegisterEncoding is used to register encodings, it is called from init
+// functions.
+func RegisterEncoding(name string, encoding Encoding) {
+	Encodings[name] = encoding
+}
+
+func compressionNames() []string {
+	names := make([]string, 0, len(Compressions))
+	for name := range Compressions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func encodingNames() []string {
+	names := make([]string, 0, len(Encodings))
+	for name := range Encodings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CompressionByName returns the compression, empty is None.
+func CompressionByName(name string) (Compression, error) {
+	if name == "" {
+		name = None
+	}
+	compression, ok := Compressions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown compression (%s), expected one of: %s", name, strings.Join(compressionNames(), ", "))
+	}
+	return compression, nil
+}
+
+// EncodingByName returns the encoding.
+func EncodingByName(name string) (Encoding, error) {
+	encoding, ok := Encodings[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding (%s), expected one of: %s", name, strings.Join(encodingNames(), ", "))
+	}
+	return encoding, nil
+}
+
+// CompressionOf returns the compression of a file from its extension, files
+// without the extension of a registered compression are not compressed. The
+// compressions are checked by name, so that the result does not depend on the
+// map order when extensions overlap.
+func CompressionOf(filename string) Compression {
+	for _, name := range compressionNames() {
+		compression := Compressions[name]
+		if ext := compression.Extension(); ext != "" && strings.HasSuffix(filename, ext) {
+			return compression
+		}
+	}
+	return Compressions[None]
+}
+
+// WithCompression adds the extension of the named compression to a filename
+// pattern which does not have it. An empty name returns the pattern as is, its
+// extension selects the compression.
+func WithCompression(pattern, name string) (string, error) {
+	if name == "" {
+		return pattern, nil
+	}
+	compression, err := CompressionByName(name)
+	if err != nil {
+		return "", err
+	}
+	if ext := CompressionOf(pattern).Extension(); ext != "" && ext != compression.Extension() {
+		return "", fmt.Errorf("the extension of %s does not match the compression (%s)", pattern, name)
+	}
+	if strings.HasSuffix(pattern, compression.Extension()) {
+		return pattern, nil
+	}
+	return pattern + compression.Extension(), nil
+}
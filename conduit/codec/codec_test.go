@@ -0,0 +1,236 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/pierrec/lz4/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+)
+
+func TestCompressionByName(t *testing.T) {
+	for _, name := range []string{"", None, Gzip, Zstd, LZ4, Snappy} {
+		_, err := CompressionByName(name)
+		assert.NoError(t, err, name)
+	}
+	_, err := CompressionByName("brotli")
+	assert.EqualError(t, err, "unknown compression (brotli), expected one of: gzip, lz4, none, snappy, zstd")
+	_, err = EncodingByName("thrift")
+	assert.EqualError(t, err, "unknown encoding (thrift), expected one of: avro, json, msgpack, protobuf")
+}
+
+func TestCompressionRoundTrip(t *testing.T) {
+	// Larger than the lz4 blocks.
+	input := []byte(strings.Repeat("conduit ", 20000))
+	for _, name := range []string{None, Gzip, Zstd, LZ4, Snappy} {
+		t.Run(name, func(t *testing.T) {
+			compression, err := CompressionByName(name)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			w, err := compression.NewWriter(&buf)
+			require.NoError(t, err)
+			_, err = w.Write(input)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			if name != None {
+				assert.Less(t, buf.Len(), len(input))
+			}
+
+			r, err := compression.NewReader(&buf)
+			require.NoError(t, err)
+			output, err := ioutil.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			assert.Equal(t, input, output)
+		})
+	}
+}
+
+func TestCompressionCorruptInput(t *testing.T) {
+	for _, name := range []string{Gzip, Zstd, LZ4, Snappy} {
+		t.Run(name, func(t *testing.T) {
+			compression, err := CompressionByName(name)
+			require.NoError(t, err)
+			r, err := compression.NewReader(strings.NewReader("not compressed data"))
+			if err == nil {
+				_, err = io.Copy(ioutil.Discard, r)
+				r.Close()
+			}
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestCommandFrames reads the output of the zstd and lz4 commands.
+func TestCommandFrames(t *testing.T) {
+	input := "conduit conduit conduit conduit conduit conduit\n"
+	tests := []struct {
+		name  string
+		frame string
+	}{
+		// lz4 -c -BX, with block checksums.
+		{LZ4, "04224d187440bd120000008f636f6e647569742008001050647569740a0c16583f00000000f32d0d3f"},
+		// zstd -c
+		{Zstd, "28b52ffd04587d000048636f6e64756974200a010056162e358ecce9"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			frame, err := hex.DecodeString(tc.frame)
+			require.NoError(t, err)
+			compression, err := CompressionByName(tc.name)
+			require.NoError(t, err)
+			r, err := compression.NewReader(bytes.NewReader(frame))
+			require.NoError(t, err)
+			output, err := ioutil.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, input, string(output))
+		})
+	}
+}
+
+func TestLZ4Checksums(t *testing.T) {
+	// The empty frame of the lz4 command.
+	var buf bytes.Buffer
+	w, err := lz4Compression{}.NewWriter(&buf)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Equal(t, "04224d186440a700000000055dcc02", hex.EncodeToString(buf.Bytes()))
+
+	// A frame with a wrong content checksum.
+	frame, err := hex.DecodeString("04224d187440bd120000008f636f6e647569742008001050647569740a0c16583f00000000f32d0d3e")
+	require.NoError(t, err)
+	r, err := lz4Compression{}.NewReader(bytes.NewReader(frame))
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(r)
+	assert.ErrorIs(t, err, lz4.ErrInvalidFrameChecksum)
+}
+
+func testBlock() sdk.Block {
+	var stxn sdk.SignedTxnInBlock
+	stxn.Txn.Type = sdk.PaymentTx
+	stxn.Txn.Sender[0] = 1
+	stxn.Txn.Fee = 1000
+	stxn.Txn.Note = []byte("conduit")
+	stxn.Txn.Amount = math.MaxUint64
+	stxn.EvalDelta.LocalDeltas = map[uint64]sdk.StateDelta{
+		1: {"key": {Action: sdk.SetUintAction, Uint: 5}},
+	}
+	return sdk.Block{
+		BlockHeader: sdk.BlockHeader{Round: 7, GenesisID: "testnet-v1.0"},
+		Payset:      []sdk.SignedTxnInBlock{stxn},
+	}
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	block := testBlock()
+	for _, name := range []string{JSON, Msgpack, Protobuf, Avro} {
+		t.Run(name, func(t *testing.T) {
+			encoding, err := EncodingByName(name)
+			require.NoError(t, err)
+			var buf bytes.Buffer
+			require.NoError(t, encoding.Encode(&buf, block))
+			var decoded sdk.Block
+			require.NoError(t, encoding.Decode(&buf, &decoded))
+			assert.Equal(t, block, decoded)
+		})
+	}
+}
+
+// TestEncodingStream tests that the values written one after the other are
+// read back one at a time, and that a value is always encoded to the same
+// bytes.
+func TestEncodingStream(t *testing.T) {
+	for _, name := range []string{Msgpack, Protobuf, Avro} {
+		t.Run(name, func(t *testing.T) {
+			encoding, err := EncodingByName(name)
+			require.NoError(t, err)
+			var first, second bytes.Buffer
+			for round := uint64(1); round <= 3; round++ {
+				block := testBlock()
+				block.Round = sdk.Round(round)
+				require.NoError(t, encoding.Encode(&first, block))
+				require.NoError(t, encoding.Encode(&second, block))
+			}
+			assert.Equal(t, first.Bytes(), second.Bytes())
+
+			// A reader which is not an io.ByteReader.
+			r := io.MultiReader(&first)
+			for round := uint64(1); round <= 3; round++ {
+				var decoded sdk.Block
+				require.NoError(t, encoding.Decode(r, &decoded))
+				assert.Equal(t, sdk.Round(round), decoded.Round)
+			}
+		})
+	}
+}
+
+func TestEncodingUnknownSchema(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, avroEncoding{}.Encode(&buf, testBlock()))
+	msg := buf.Bytes()
+	msg[2]++
+	var decoded sdk.Block
+	assert.ErrorContains(t, avroEncoding{}.Decode(bytes.NewReader(msg), &decoded), "avro: unknown schema fingerprint")
+}
+
+func TestJSONEncoding(t *testing.T) {
+	encoding, err := EncodingByName(JSON)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, encoding.Encode(&buf, map[uint64]string{1: "a"}))
+	assert.Equal(t, `{"1":"a"}`, buf.String())
+}
+
+func TestCompressionOf(t *testing.T) {
+	tests := []struct {
+		filename string
+		ext      string
+	}{
+		{"1_block.json", ""},
+		{"1_block.json.gz", ".gz"},
+		{"1_block.json.zst", ".zst"},
+		{"1_blocks.chunk.lz4", ".lz4"},
+		{"1_block.json.sz", ".sz"},
+		{"1_block.gzip", ""},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.ext, CompressionOf(tc.filename).Extension(), tc.filename)
+	}
+}
+
+func TestWithCompression(t *testing.T) {
+	tests := []struct {
+		name        string
+		pattern     string
+		compression string
+		want        string
+		err         string
+	}{
+		{"default", "%[1]d_block.json", "", "%[1]d_block.json", ""},
+		{"add extension", "%[1]d_block.json", Zstd, "%[1]d_block.json.zst", ""},
+		{"has extension", "%[1]d_block.json.gz", Gzip, "%[1]d_block.json.gz", ""},
+		{"none", "%[1]d_block.json", None, "%[1]d_block.json", ""},
+		{"mismatch", "%[1]d_block.json.gz", LZ4, "", "the extension of %[1]d_block.json.gz does not match the compression (lz4)"},
+		{"unknown", "%[1]d_block.json", "brotli", "", "unknown compression (brotli), expected one of: gzip, lz4, none, snappy, zstd"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pattern, err := WithCompression(tc.pattern, tc.compression)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, pattern)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package codec
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+func init() {
+	RegisterCompression(None, noCompression{})
+	RegisterCompression(Gzip, gzipCompression{})
+	RegisterCompression(Zstd, zstdCompression{})
+	RegisterCompression(LZ4, lz4Compression{})
+	RegisterCompression(Snappy, snappyCompression{})
+}
+
+type noCompression struct{}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func (noCompression) Extension() string {
+	return ""
+}
+
+func (noCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+func (noCompression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(r), nil
+}
+
+type gzipCompression struct{}
+
+func (gzipCompression) Extension() string {
+	return ".gz"
+}
+
+func (gzipCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make gzip reader: %w", err)
+	}
+	return gz, nil
+}
+
+// zstdCompression compresses with a single goroutine, the files are small and
+// several of them are written at the same time.
+type zstdCompression struct{}
+
+func (zstdCompression) Extension() string {
+	return ".zst"
+}
+
+func (zstdCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	zw, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to make zstd writer: %w", err)
+	}
+	return zw, nil
+}
+
+func (zstdCompression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to make zstd reader: %w", err)
+	}
+	return zr.IOReadCloser(), nil
+}
+
+// snappyCompression uses the snappy framing format, the format of the .sz
+// files written by the snappy tools.
+type snappyCompression struct{}
+
+func (snappyCompression) Extension() string {
+	return ".sz"
+}
+
+func (snappyCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return snappy.NewBufferedWriter(w), nil
+}
+
+func (snappyCompression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(snappy.NewReader(r)), nil
+}
+
+// lz4Compression writes LZ4 frames with independent 64KB blocks and a content
+// checksum, the frames of the lz4 command with any of its options are read.
+type lz4Compression struct{}
+
+func (lz4Compression) Extension() string {
+	return ".lz4"
+}
+
+func (lz4Compression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	zw := lz4.NewWriter(w)
+	err := zw.Apply(lz4.BlockSizeOption(lz4.Block64Kb), lz4.ChecksumOption(true), lz4.ConcurrencyOption(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to make lz4 writer: %w", err)
+	}
+	return zw, nil
+}
+
+func (lz4Compression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(lz4.NewReader(r)), nil
+}
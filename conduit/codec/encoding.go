@@ -0,0 +1,53 @@
+package codec
+
+import (
+	"io"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/json"
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	"github.com/algorand/go-codec/codec"
+)
+
+// jsonHandle writes JSON with the go-algorand-sdk settings on a single line,
+// the integer map keys are written as strings so that the output is valid
+// JSON.
+var jsonHandle *codec.JsonHandle
+
+func init() {
+	jsonHandle = new(codec.JsonHandle)
+	jsonHandle.ErrorIfNoField = json.CodecHandle.ErrorIfNoField
+	jsonHandle.ErrorIfNoArrayExpand = json.CodecHandle.ErrorIfNoArrayExpand
+	jsonHandle.Canonical = json.CodecHandle.Canonical
+	jsonHandle.RecursiveEmptyCheck = json.CodecHandle.RecursiveEmptyCheck
+	jsonHandle.HTMLCharsAsIs = json.CodecHandle.HTMLCharsAsIs
+	jsonHandle.MapKeyAsString = true
+
+	RegisterEncoding(JSON, NewJSONEncoding(jsonHandle))
+	RegisterEncoding(Msgpack, handleEncoding{extension: ".msgp", encode: msgpack.CodecHandle, decode: msgpack.LenientCodecHandle})
+}
+
+// NewJSONEncoding returns the json encoding which writes with the handle, such
+// as a handle which writes binary fields with the pipeline binary-encoding.
+func NewJSONEncoding(handle *codec.JsonHandle) Encoding {
+	return handleEncoding{extension: ".json", encode: handle, decode: json.LenientCodecHandle}
+}
+
+// handleEncoding encodes with the go-algorand-sdk handles, the values are
+// encoded like algod encodes them.
+type handleEncoding struct {
+	extension string
+	encode    codec.Handle
+	decode    codec.Handle
+}
+
+func (e handleEncoding) Extension() string {
+	return e.extension
+}
+
+func (e handleEncoding) Encode(w io.Writer, v interface{}) error {
+	return codec.NewEncoder(w, e.encode).Encode(v)
+}
+
+func (e handleEncoding) Decode(r io.Reader, v interface{}) error {
+	return codec.NewDecoder(r, e.decode).Decode(v)
+}
@@ -0,0 +1,129 @@
+package codec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	"github.com/algorand/go-codec/codec"
+)
+
+// genericHandle converts the msgpack encoding of a value to and from generic
+// values: nil, bool, int64, uint64, float64, []byte, string, []interface{}
+// and map[interface{}]interface{}. The protobuf and avro encodings write the
+// generic values, so they have the fields and the names of the msgpack
+// encoding, which algod uses.
+var genericHandle = &codec.MsgpackHandle{WriteExt: true, RawToString: true}
+
+// toGeneric returns the generic values of v.
+func toGeneric(v interface{}) (interface{}, error) {
+	var encoded []byte
+	if err := codec.NewEncoderBytes(&encoded, msgpack.CodecHandle).Encode(v); err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := codec.NewDecoderBytes(encoded, genericHandle).Decode(&generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// fromGeneric decodes the generic values into v, unknown fields are ignored.
+func fromGeneric(generic interface{}, v interface{}) error {
+	var encoded []byte
+	if err := codec.NewEncoderBytes(&encoded, genericHandle).Encode(generic); err != nil {
+		return err
+	}
+	return codec.NewDecoderBytes(encoded, msgpack.LenientCodecHandle).Decode(v)
+}
+
+// sortedEntries returns the encoded entries of a map sorted by their encoded
+// key, so that a value is always encoded to the same bytes.
+func sortedEntries(m map[interface{}]interface{}, encode func(interface{}) ([]byte, error)) ([][2][]byte, error) {
+	entries := make([][2][]byte, 0, len(m))
+	for key, value := range m {
+		encodedKey, err := encode(key)
+		if err != nil {
+			return nil, err
+		}
+		encodedValue, err := encode(value)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, [2][]byte{encodedKey, encodedValue})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i][0], entries[j][0]) < 0
+	})
+	return entries, nil
+}
+
+// setMapEntry adds an entry to a generic map. Binary keys are stored as
+// strings, like msgpack decodes them, and keys which are lists or maps are not
+// supported.
+func setMapEntry(m map[interface{}]interface{}, key, value interface{}) error {
+	switch k := key.(type) {
+	case []byte:
+		key = string(k)
+	case []interface{}, map[interface{}]interface{}:
+		return fmt.Errorf("unsupported map key of type %T", key)
+	}
+	m[key] = value
+	return nil
+}
+
+func unsupportedValue(v interface{}) error {
+	return fmt.Errorf("unsupported value of type %T", v)
+}
+
+// reader is read one value at a time, the lengths of the values are varints.
+type reader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// byteReader reads the bytes of readers which are not an io.ByteReader one at
+// a time, so that a value is not read past its end.
+type byteReader struct {
+	io.Reader
+}
+
+func (r byteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(r.Reader, b[:])
+	return b[0], err
+}
+
+func newReader(r io.Reader) reader {
+	if br, ok := r.(reader); ok {
+		return br
+	}
+	return byteReader{r}
+}
+
+// readBytes reads n bytes, it does not allocate them upfront as the length of
+// a corrupt value may be anything.
+func readBytes(r io.Reader, n int64) ([]byte, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("negative length %d", n)
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, n); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unexpectedEOF reports the end of the input within a value as
+// io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,263 @@
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func init() {
+	RegisterEncoding(Protobuf, protobufEncoding{})
+}
+
+// The field numbers of the messages of value.proto.
+const (
+	protoBool   protowire.Number = 1
+	protoInt    protowire.Number = 2
+	protoUint   protowire.Number = 3
+	protoFloat  protowire.Number = 4
+	protoBytes  protowire.Number = 5
+	protoString protowire.Number = 6
+	protoList   protowire.Number = 7
+	protoMap    protowire.Number = 8
+
+	protoListValues protowire.Number = 1
+	protoMapEntries protowire.Number = 1
+	protoEntryKey   protowire.Number = 1
+	protoEntryValue protowire.Number = 2
+)
+
+// protoMaxMsgBytes is the largest message, the size of the protobuf messages
+// is an int32.
+const protoMaxMsgBytes = math.MaxInt32
+
+// protobufEncoding writes each value as a length-delimited Value message of
+// value.proto. It is written with protowire, the messages are generic so
+// there is no generated code.
+type protobufEncoding struct{}
+
+func (protobufEncoding) Extension() string {
+	return ".pb"
+}
+
+func (protobufEncoding) Encode(w io.Writer, v interface{}) error {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return fmt.Errorf("protobuf: %w", err)
+	}
+	value, err := encodeProtoValue(generic)
+	if err != nil {
+		return fmt.Errorf("protobuf: %w", err)
+	}
+	msg := protowire.AppendVarint(make([]byte, 0, len(value)+binary.MaxVarintLen64), uint64(len(value)))
+	_, err = w.Write(append(msg, value...))
+	return err
+}
+
+func (protobufEncoding) Decode(r io.Reader, v interface{}) error {
+	br := newReader(r)
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		return err
+	}
+	if size > protoMaxMsgBytes {
+		return fmt.Errorf("protobuf: message of %d bytes is too large", size)
+	}
+	msg, err := readBytes(br, int64(size))
+	if err != nil {
+		return fmt.Errorf("protobuf: %w", err)
+	}
+	generic, err := parseProtoValue(msg)
+	if err != nil {
+		return fmt.Errorf("protobuf: %w", err)
+	}
+	return fromGeneric(generic, v)
+}
+
+func encodeProtoValue(v interface{}) ([]byte, error) {
+	var b []byte
+	switch v := v.(type) {
+	case nil:
+	case bool:
+		b = protowire.AppendTag(b, protoBool, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(v))
+	case int64:
+		if v >= 0 {
+			return encodeProtoValue(uint64(v))
+		}
+		b = protowire.AppendTag(b, protoInt, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeZigZag(v))
+	case uint64:
+		b = protowire.AppendTag(b, protoUint, protowire.VarintType)
+		b = protowire.AppendVarint(b, v)
+	case float64:
+		b = protowire.AppendTag(b, protoFloat, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(v))
+	case []byte:
+		b = protowire.AppendTag(b, protoBytes, protowire.BytesType)
+		b = protowire.AppendBytes(b, v)
+	case string:
+		b = protowire.AppendTag(b, protoString, protowire.BytesType)
+		b = protowire.AppendString(b, v)
+	case []interface{}:
+		var list []byte
+		for _, item := range v {
+			value, err := encodeProtoValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list = protowire.AppendTag(list, protoListValues, protowire.BytesType)
+			list = protowire.AppendBytes(list, value)
+		}
+		b = protowire.AppendTag(b, protoList, protowire.BytesType)
+		b = protowire.AppendBytes(b, list)
+	case map[interface{}]interface{}:
+		entries, err := sortedEntries(v, encodeProtoValue)
+		if err != nil {
+			return nil, err
+		}
+		var m []byte
+		for _, kv := range entries {
+			var entry []byte
+			entry = protowire.AppendTag(entry, protoEntryKey, protowire.BytesType)
+			entry = protowire.AppendBytes(entry, kv[0])
+			entry = protowire.AppendTag(entry, protoEntryValue, protowire.BytesType)
+			entry = protowire.AppendBytes(entry, kv[1])
+			m = protowire.AppendTag(m, protoMapEntries, protowire.BytesType)
+			m = protowire.AppendBytes(m, entry)
+		}
+		b = protowire.AppendTag(b, protoMap, protowire.BytesType)
+		b = protowire.AppendBytes(b, m)
+	default:
+		return nil, unsupportedValue(v)
+	}
+	return b, nil
+}
+
+// parseFields calls field with each field of a message, the field returns the
+// number of bytes of its value it consumed or a protowire error code.
+func parseFields(b []byte, field func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n, err := field(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// parseProtoValue returns the generic value of a Value, the last field of the
+// oneof wins like with the protobuf libraries.
+func parseProtoValue(b []byte) (interface{}, error) {
+	var value interface{}
+	err := parseFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == protoBool && typ == protowire.VarintType:
+			x, n := protowire.ConsumeVarint(b)
+			value = protowire.DecodeBool(x)
+			return n, nil
+		case num == protoInt && typ == protowire.VarintType:
+			x, n := protowire.ConsumeVarint(b)
+			value = protowire.DecodeZigZag(x)
+			return n, nil
+		case num == protoUint && typ == protowire.VarintType:
+			x, n := protowire.ConsumeVarint(b)
+			value = x
+			return n, nil
+		case num == protoFloat && typ == protowire.Fixed64Type:
+			x, n := protowire.ConsumeFixed64(b)
+			value = math.Float64frombits(x)
+			return n, nil
+		case num == protoBytes && typ == protowire.BytesType:
+			x, n := protowire.ConsumeBytes(b)
+			value = append([]byte{}, x...)
+			return n, nil
+		case num == protoString && typ == protowire.BytesType:
+			x, n := protowire.ConsumeString(b)
+			value = x
+			return n, nil
+		case num == protoList && typ == protowire.BytesType:
+			x, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			list, err := parseProtoList(x)
+			value = list
+			return n, err
+		case num == protoMap && typ == protowire.BytesType:
+			x, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			m, err := parseProtoMap(x)
+			value = m
+			return n, err
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	return value, err
+}
+
+func parseProtoList(b []byte) ([]interface{}, error) {
+	list := []interface{}{}
+	err := parseFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != protoListValues || typ != protowire.BytesType {
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+		x, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return n, nil
+		}
+		item, err := parseProtoValue(x)
+		list = append(list, item)
+		return n, err
+	})
+	return list, err
+}
+
+func parseProtoMap(b []byte) (map[interface{}]interface{}, error) {
+	m := make(map[interface{}]interface{})
+	err := parseFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != protoMapEntries || typ != protowire.BytesType {
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+		x, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return n, nil
+		}
+		var key, value interface{}
+		err := parseFields(x, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+			if typ != protowire.BytesType || (num != protoEntryKey && num != protoEntryValue) {
+				return protowire.ConsumeFieldValue(num, typ, b), nil
+			}
+			x, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			v, err := parseProtoValue(x)
+			if num == protoEntryKey {
+				key = v
+			} else {
+				value = v
+			}
+			return n, err
+		})
+		if err != nil {
+			return n, err
+		}
+		return n, setMapEntry(m, key, value)
+	})
+	return m, err
+}
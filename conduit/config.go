@@ -23,4 +23,9 @@ var DefaultMetricsPrefix = "conduit"
 type Args struct {
 	ConduitDataDir    string `yaml:"data-dir"`
 	NextRoundOverride uint64 `yaml:"next-round-override"`
+	// Pretty enables human-friendly, colored console output.
+	Pretty bool `yaml:"pretty"`
+	// DryRun initializes and validates the pipeline, then exits without
+	// processing any rounds.
+	DryRun bool `yaml:"dry-run"`
 }
@@ -0,0 +1,157 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// PostgresType coordinates through a postgres table.
+	PostgresType = "postgres"
+	// RedisType coordinates through redis keys.
+	RedisType = "redis"
+
+	// DefaultKey identifies the rounds of a pipeline which is shared by instances.
+	DefaultKey = "conduit"
+	// DefaultTable is the postgres table which holds the claims.
+	DefaultTable = "conduit_round_claims"
+	// DefaultLease is how long a claim is held before another instance may take it over.
+	DefaultLease = time.Minute
+	// DefaultRetention is how long completed rounds are remembered.
+	DefaultRetention = 24 * time.Hour
+)
+
+// ClaimResult is the outcome of a claim.
+type ClaimResult int
+
+const (
+	// Claimed means this instance holds the round and should export it.
+	Claimed ClaimResult = iota
+	// Completed means another instance already exported the round.
+	Completed
+	// Held means another instance is exporting the round.
+	Held
+)
+
+func (r ClaimResult) String() string {
+	switch r {
+	case Claimed:
+		return "claimed"
+	case Completed:
+		return "completed"
+	case Held:
+		return "held"
+	default:
+		return fmt.Sprintf("ClaimResult(%d)", int(r))
+	}
+}
+
+// Coordinator lets several instances of a pipeline run concurrently while
+// each round is exported by only one of them.
+type Coordinator interface {
+	// Claim claims the round for this instance. Claiming a round which this
+	// instance already holds renews the lease.
+	Claim(ctx context.Context, round uint64) (ClaimResult, error)
+	// Complete marks a claimed round as exported.
+	Complete(ctx context.Context, round uint64) error
+	// Close releases the resources of the coordinator.
+	Close() error
+}
+
+// Config selects and configures the coordination backend.
+type Config struct {
+	// Type is "postgres" or "redis", empty disables coordination.
+	Type string `yaml:"type"`
+	// Key identifies the pipeline, instances with the same key share rounds.
+	// Defaults to "conduit".
+	Key string `yaml:"key"`
+	// InstanceID identifies this instance. Defaults to the hostname and pid.
+	InstanceID string `yaml:"instance-id"`
+	// Lease is how long a claim is held before another instance may take over
+	// the round. It must be longer than exporting a round. Defaults to 1m.
+	Lease time.Duration `yaml:"lease"`
+	// Retention is how long completed rounds are remembered. Defaults to 24h.
+	Retention time.Duration `yaml:"retention"`
+
+	// ConnectionString is the postgres connection string.
+	ConnectionString string `yaml:"connection-string"`
+	// Table is the postgres table, it is created if it does not exist.
+	// Defaults to "conduit_round_claims".
+	Table string `yaml:"table"`
+
+	// Address is the redis host:port.
+	Address string `yaml:"address"`
+	// Password authenticates with redis when set.
+	Password string `yaml:"password"`
+	// DB is the redis database number.
+	DB int `yaml:"db"`
+}
+
+// Enabled reports whether coordination is configured.
+func (cfg Config) Enabled() bool {
+	return cfg.Type != ""
+}
+
+// Valid validates the coordination config.
+func (cfg Config) Valid() error {
+	switch cfg.Type {
+	case "":
+		return nil
+	case PostgresType:
+		if cfg.ConnectionString == "" {
+			return fmt.Errorf("connection-string is required for %s coordination", PostgresType)
+		}
+	case RedisType:
+		if cfg.Address == "" {
+			return fmt.Errorf("address is required for %s coordination", RedisType)
+		}
+	default:
+		return fmt.Errorf("type must be '%s' or '%s', found '%s'", PostgresType, RedisType, cfg.Type)
+	}
+	if cfg.Lease < 0 {
+		return fmt.Errorf("lease (%s) must not be negative", cfg.Lease)
+	}
+	if cfg.Retention < 0 {
+		return fmt.Errorf("retention (%s) must not be negative", cfg.Retention)
+	}
+	return nil
+}
+
+// withDefaults fills in the optional settings.
+func (cfg Config) withDefaults() Config {
+	if cfg.Key == "" {
+		cfg.Key = DefaultKey
+	}
+	if cfg.InstanceID == "" {
+		hostname, _ := os.Hostname()
+		cfg.InstanceID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	if cfg.Lease == 0 {
+		cfg.Lease = DefaultLease
+	}
+	if cfg.Retention == 0 {
+		cfg.Retention = DefaultRetention
+	}
+	if cfg.Table == "" {
+		cfg.Table = DefaultTable
+	}
+	return cfg
+}
+
+// New creates the configured coordinator.
+func New(ctx context.Context, cfg Config) (Coordinator, error) {
+	if err := cfg.Valid(); err != nil {
+		return nil, fmt.Errorf("New(): %w", err)
+	}
+	cfg = cfg.withDefaults()
+	switch cfg.Type {
+	case PostgresType:
+		return makePostgresCoordinator(ctx, cfg)
+	case RedisType:
+		return makeRedisCoordinator(cfg), nil
+	default:
+		return nil, fmt.Errorf("New(): coordination is not configured")
+	}
+}
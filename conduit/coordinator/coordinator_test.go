@@ -0,0 +1,70 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		errMsg string
+	}{
+		{name: "disabled", cfg: Config{}},
+		{name: "postgres", cfg: Config{Type: PostgresType, ConnectionString: "host=localhost"}},
+		{name: "postgres missing connection", cfg: Config{Type: PostgresType}, errMsg: "connection-string is required for postgres coordination"},
+		{name: "redis", cfg: Config{Type: RedisType, Address: "localhost:6379"}},
+		{name: "redis missing address", cfg: Config{Type: RedisType}, errMsg: "address is required for redis coordination"},
+		{name: "negative lease", cfg: Config{Type: RedisType, Address: "localhost:6379", Lease: -time.Second}, errMsg: "lease (-1s) must not be negative"},
+		{name: "negative retention", cfg: Config{Type: RedisType, Address: "localhost:6379", Retention: -time.Second}, errMsg: "retention (-1s) must not be negative"},
+		{name: "unknown", cfg: Config{Type: "zookeeper"}, errMsg: "type must be 'postgres' or 'redis', found 'zookeeper'"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Valid()
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.errMsg)
+			}
+		})
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	cfg := Config{Type: RedisType}.withDefaults()
+	assert.Equal(t, DefaultKey, cfg.Key)
+	assert.Equal(t, DefaultLease, cfg.Lease)
+	assert.Equal(t, DefaultRetention, cfg.Retention)
+	assert.Equal(t, DefaultTable, cfg.Table)
+	assert.NotEmpty(t, cfg.InstanceID)
+
+	cfg = Config{Key: "mainnet", InstanceID: "a", Lease: time.Second}.withDefaults()
+	assert.Equal(t, "mainnet", cfg.Key)
+	assert.Equal(t, "a", cfg.InstanceID)
+	assert.Equal(t, time.Second, cfg.Lease)
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(context.Background(), Config{Type: RedisType, Address: "localhost:6379"})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultKey, c.(*redisCoordinator).cfg.Key)
+
+	_, err = New(context.Background(), Config{})
+	assert.EqualError(t, err, "New(): coordination is not configured")
+	_, err = New(context.Background(), Config{Type: RedisType})
+	assert.EqualError(t, err, "New(): address is required for redis coordination")
+}
+
+func TestClaimResultString(t *testing.T) {
+	assert.Equal(t, "claimed", Claimed.String())
+	assert.Equal(t, "completed", Completed.String())
+	assert.Equal(t, "held", Held.String())
+	assert.Equal(t, "ClaimResult(7)", ClaimResult(7).String())
+}
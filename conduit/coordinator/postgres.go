@@ -0,0 +1,107 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// pruneInterval is the number of completed rounds between deleting the rows
+// which are older than the retention.
+const pruneInterval = 1000
+
+// postgresCoordinator keeps a row per round. Claims use an upsert which only
+// succeeds when the round is not completed and the claim is ours or expired.
+// The instances export the rounds in order, so a round is completed once a
+// later round is: pruning keeps the last completed row, and an instance which
+// restarts from an older round does not export the pruned rounds again.
+type postgresCoordinator struct {
+	cfg   Config
+	table string
+
+	mu        sync.Mutex
+	conn      *pgx.Conn
+	completed int
+}
+
+func makePostgresCoordinator(ctx context.Context, cfg Config) (*postgresCoordinator, error) {
+	conn, err := pgx.Connect(ctx, cfg.ConnectionString)
+	if err != nil {
+		return nil, fmt.Errorf("makePostgresCoordinator(): unable to connect: %w", err)
+	}
+	c := &postgresCoordinator{
+		cfg:   cfg,
+		table: pgx.Identifier{cfg.Table}.Sanitize(),
+		conn:  conn,
+	}
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (key text NOT NULL, round bigint NOT NULL, owner text NOT NULL, claimed_at timestamptz NOT NULL, completed boolean NOT NULL DEFAULT false, PRIMARY KEY (key, round))", c.table)
+	if _, err = conn.Exec(ctx, query); err != nil {
+		conn.Close(ctx)
+		return nil, fmt.Errorf("makePostgresCoordinator(): unable to create table %s: %w", c.table, err)
+	}
+	return c, nil
+}
+
+// Claim inserts or takes over the row of the round, unless it or a later
+// round is completed.
+func (c *postgresCoordinator) Claim(ctx context.Context, round uint64) (ClaimResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	query := fmt.Sprintf(`INSERT INTO %[1]s AS t (key, round, owner, claimed_at)
+SELECT $1::text, $2::bigint, $3::text, now()
+WHERE NOT EXISTS (SELECT 1 FROM %[1]s WHERE key = $1 AND round >= $2 AND completed)
+ON CONFLICT (key, round) DO UPDATE SET owner = EXCLUDED.owner, claimed_at = now()
+WHERE NOT t.completed AND (t.owner = EXCLUDED.owner OR t.claimed_at < now() - make_interval(secs => $4))
+RETURNING owner`, c.table)
+	var owner string
+	err := c.conn.QueryRow(ctx, query, c.cfg.Key, int64(round), c.cfg.InstanceID, c.cfg.Lease.Seconds()).Scan(&owner)
+	if err == nil {
+		return Claimed, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return Held, fmt.Errorf("Claim(): %w", err)
+	}
+
+	var completed bool
+	query = fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE key = $1 AND round >= $2 AND completed)", c.table)
+	if err = c.conn.QueryRow(ctx, query, c.cfg.Key, int64(round)).Scan(&completed); err != nil {
+		return Held, fmt.Errorf("Claim(): %w", err)
+	}
+	if completed {
+		return Completed, nil
+	}
+	return Held, nil
+}
+
+// Complete marks the row of the round as completed.
+func (c *postgresCoordinator) Complete(ctx context.Context, round uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	query := fmt.Sprintf("UPDATE %s SET completed = true WHERE key = $1 AND round = $2 AND owner = $3", c.table)
+	tag, err := c.conn.Exec(ctx, query, c.cfg.Key, int64(round), c.cfg.InstanceID)
+	if err != nil {
+		return fmt.Errorf("Complete(): %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Complete(): round %d is no longer claimed by %s, the lease expired", round, c.cfg.InstanceID)
+	}
+
+	c.completed++
+	if c.completed%pruneInterval == 0 {
+		query = fmt.Sprintf(`DELETE FROM %[1]s WHERE key = $1 AND completed AND claimed_at < now() - make_interval(secs => $2)
+AND round < (SELECT max(round) FROM %[1]s WHERE key = $1 AND completed)`, c.table)
+		if _, err = c.conn.Exec(ctx, query, c.cfg.Key, c.cfg.Retention.Seconds()); err != nil {
+			return fmt.Errorf("Complete(): unable to prune completed rounds: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *postgresCoordinator) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.Close(context.Background())
+}
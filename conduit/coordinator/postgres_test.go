@@ -0,0 +1,98 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostgresCoordinator claims rounds from two instances, it needs a server
+// in TEST_PG.
+func TestPostgresCoordinator(t *testing.T) {
+	connStr := os.Getenv("TEST_PG")
+	if connStr == "" {
+		t.Skip("TEST_PG is not set")
+	}
+	ctx := context.Background()
+	cfg := Config{Type: PostgresType, ConnectionString: connStr, Table: fmt.Sprintf("conduit_test_%d", time.Now().UnixNano())}.withDefaults()
+	conn, err := pgx.Connect(ctx, connStr)
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+	defer conn.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", cfg.Table))
+	cfgA, cfgB := cfg, cfg
+	cfgA.InstanceID = "a"
+	cfgB.InstanceID = "b"
+	a, err := makePostgresCoordinator(ctx, cfgA)
+	require.NoError(t, err)
+	defer a.Close()
+	b, err := makePostgresCoordinator(ctx, cfgB)
+	require.NoError(t, err)
+	defer b.Close()
+
+	result, err := a.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, Claimed, result)
+	result, err = b.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, Held, result)
+	require.NoError(t, a.Complete(ctx, 1))
+	result, err = b.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, Completed, result)
+	assert.EqualError(t, b.Complete(ctx, 1), "Complete(): round 1 is no longer claimed by b, the lease expired")
+}
+
+// TestPostgresCoordinatorPrune tests that the pruned rounds are still
+// completed, it needs a server in TEST_PG.
+func TestPostgresCoordinatorPrune(t *testing.T) {
+	connStr := os.Getenv("TEST_PG")
+	if connStr == "" {
+		t.Skip("TEST_PG is not set")
+	}
+	ctx := context.Background()
+	cfg := Config{Type: PostgresType, ConnectionString: connStr, Table: fmt.Sprintf("conduit_test_%d", time.Now().UnixNano())}.withDefaults()
+	cfg.Retention = time.Millisecond
+	conn, err := pgx.Connect(ctx, connStr)
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+	defer conn.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", cfg.Table))
+	cfgA, cfgB := cfg, cfg
+	cfgA.InstanceID = "a"
+	cfgB.InstanceID = "b"
+	a, err := makePostgresCoordinator(ctx, cfgA)
+	require.NoError(t, err)
+	defer a.Close()
+	b, err := makePostgresCoordinator(ctx, cfgB)
+	require.NoError(t, err)
+	defer b.Close()
+
+	for round := uint64(1); round <= 3; round++ {
+		result, err := a.Claim(ctx, round)
+		require.NoError(t, err)
+		require.Equal(t, Claimed, result)
+		if round == 3 {
+			// prune with the next completion
+			time.Sleep(10 * time.Millisecond)
+			a.completed = pruneInterval - 1
+		}
+		require.NoError(t, a.Complete(ctx, round))
+	}
+	var rounds int
+	require.NoError(t, conn.QueryRow(ctx, fmt.Sprintf("SELECT count(*) FROM %s", cfg.Table)).Scan(&rounds))
+	assert.Equal(t, 1, rounds, "the last completed round is kept")
+
+	for round := uint64(1); round <= 3; round++ {
+		result, err := b.Claim(ctx, round)
+		require.NoError(t, err)
+		assert.Equal(t, Completed, result, "round %d", round)
+	}
+	result, err := b.Claim(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, Claimed, result)
+}
@@ -0,0 +1,109 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	completedValue = "completed"
+	claimPrefix    = "claimed:"
+)
+
+// claimScript atomically claims a round which is not completed, or renews our
+// own claim. It returns "claimed", "completed" or "held". Rounds up to the
+// completed watermark are completed, their keys may have expired.
+var claimScript = redis.NewScript(`local through = tonumber(redis.call('GET', KEYS[2]))
+if through and through >= tonumber(ARGV[3]) then
+  return 'completed'
+end
+local v = redis.call('GET', KEYS[1])
+if not v then
+  redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+  return 'claimed'
+end
+if v == 'completed' then
+  return 'completed'
+end
+if v == ARGV[1] then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  return 'claimed'
+end
+return 'held'`)
+
+// completeScript marks a round as completed if we still hold the claim, and
+// raises the completed watermark.
+var completeScript = redis.NewScript(`if redis.call('GET', KEYS[1]) == ARGV[1] then
+  redis.call('SET', KEYS[1], 'completed', 'PX', ARGV[2])
+  local through = tonumber(redis.call('GET', KEYS[2]))
+  if not through or through < tonumber(ARGV[3]) then
+    redis.call('SET', KEYS[2], ARGV[3])
+  end
+  return 1
+end
+return 0`)
+
+// redisCoordinator keeps a key per round, which expires with the lease while
+// claimed and with the retention once completed. The highest completed round
+// is kept without expiry: the instances export the rounds in order, so the
+// rounds up to it are completed once their keys expired, and an instance
+// which restarts from an older round does not export them again.
+type redisCoordinator struct {
+	cfg    Config
+	client *redis.Client
+}
+
+func makeRedisCoordinator(cfg Config) *redisCoordinator {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Address,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	return &redisCoordinator{cfg: cfg, client: client}
+}
+
+func (c *redisCoordinator) roundKey(round uint64) string {
+	return fmt.Sprintf("%s:round:%d", c.cfg.Key, round)
+}
+
+func (c *redisCoordinator) completedKey() string {
+	return fmt.Sprintf("%s:completed", c.cfg.Key)
+}
+
+// Claim runs the claim script on the key of the round.
+func (c *redisCoordinator) Claim(ctx context.Context, round uint64) (ClaimResult, error) {
+	keys := []string{c.roundKey(round), c.completedKey()}
+	reply, err := claimScript.Run(ctx, c.client, keys, claimPrefix+c.cfg.InstanceID, c.cfg.Lease.Milliseconds(), round).Text()
+	if err != nil {
+		return Held, fmt.Errorf("Claim(): %w", err)
+	}
+	switch reply {
+	case "claimed":
+		return Claimed, nil
+	case completedValue:
+		return Completed, nil
+	case "held":
+		return Held, nil
+	default:
+		return Held, fmt.Errorf("Claim(): unexpected reply %v", reply)
+	}
+}
+
+// Complete runs the complete script on the key of the round.
+func (c *redisCoordinator) Complete(ctx context.Context, round uint64) error {
+	keys := []string{c.roundKey(round), c.completedKey()}
+	reply, err := completeScript.Run(ctx, c.client, keys, claimPrefix+c.cfg.InstanceID, c.cfg.Retention.Milliseconds(), round).Int64()
+	if err != nil {
+		return fmt.Errorf("Complete(): %w", err)
+	}
+	if reply != 1 {
+		return fmt.Errorf("Complete(): round %d is no longer claimed by %s, the lease expired", round, c.cfg.InstanceID)
+	}
+	return nil
+}
+
+func (c *redisCoordinator) Close() error {
+	return c.client.Close()
+}
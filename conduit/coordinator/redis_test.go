@@ -0,0 +1,119 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisCoordinator(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	cfg := Config{Type: RedisType, Address: server.Addr()}.withDefaults()
+	cfgA, cfgB := cfg, cfg
+	cfgA.InstanceID = "a"
+	cfgB.InstanceID = "b"
+	a := makeRedisCoordinator(cfgA)
+	b := makeRedisCoordinator(cfgB)
+	defer a.Close()
+	defer b.Close()
+
+	result, err := a.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, Claimed, result)
+	// claiming again renews the lease
+	result, err = a.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, Claimed, result)
+
+	result, err = b.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, Held, result)
+
+	require.NoError(t, a.Complete(ctx, 1))
+	result, err = b.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, Completed, result)
+
+	// b takes over round 2 once a's lease expired
+	result, err = a.Claim(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, Claimed, result)
+	server.FastForward(DefaultLease + time.Second)
+	result, err = b.Claim(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, Claimed, result)
+	assert.EqualError(t, a.Complete(ctx, 2), "Complete(): round 2 is no longer claimed by a, the lease expired")
+	require.NoError(t, b.Complete(ctx, 2))
+}
+
+// TestRedisCoordinatorRetention tests that the rounds whose keys expired after
+// the retention are still completed.
+func TestRedisCoordinatorRetention(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	cfg := Config{Type: RedisType, Address: server.Addr()}.withDefaults()
+	cfgA, cfgB := cfg, cfg
+	cfgA.InstanceID = "a"
+	cfgB.InstanceID = "b"
+	a := makeRedisCoordinator(cfgA)
+	b := makeRedisCoordinator(cfgB)
+	defer a.Close()
+	defer b.Close()
+
+	for round := uint64(1); round <= 3; round++ {
+		result, err := a.Claim(ctx, round)
+		require.NoError(t, err)
+		require.Equal(t, Claimed, result)
+		require.NoError(t, a.Complete(ctx, round))
+	}
+	server.FastForward(DefaultRetention + time.Second)
+	assert.False(t, server.Exists("conduit:round:2"))
+
+	for round := uint64(1); round <= 3; round++ {
+		result, err := b.Claim(ctx, round)
+		require.NoError(t, err)
+		assert.Equal(t, Completed, result, "round %d", round)
+	}
+	result, err := b.Claim(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, Claimed, result)
+}
+
+func TestRedisCoordinatorAuth(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+	ctx := context.Background()
+	cfg := Config{Type: RedisType, Address: server.Addr(), InstanceID: "a", Password: "secret"}.withDefaults()
+	c := makeRedisCoordinator(cfg)
+	defer c.Close()
+	result, err := c.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, Claimed, result)
+
+	cfg.Password = "wrong"
+	c = makeRedisCoordinator(cfg)
+	defer c.Close()
+	_, err = c.Claim(ctx, 1)
+	assert.ErrorContains(t, err, "Claim(): WRONGPASS")
+}
+
+func TestRedisCoordinatorReconnect(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	c := makeRedisCoordinator(Config{Type: RedisType, Address: server.Addr(), InstanceID: "a"}.withDefaults())
+	defer c.Close()
+	_, err := c.Claim(ctx, 1)
+	require.NoError(t, err)
+
+	// the connection is opened again after the server restarted
+	server.Close()
+	require.NoError(t, server.Restart())
+	result, err := c.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, Claimed, result)
+}
@@ -32,6 +32,109 @@ type BlockData struct {
 
 	// Certificate contains voting data that certifies the block. The certificate is non deterministic, a node stops collecting votes once the voting threshold is reached.
 	Certificate *map[string]interface{} `json:"cert,omitempty"`
+
+	// Aggregates are the time buckets which were closed by this block, they are emitted by aggregating processors.
+	Aggregates []Aggregate `json:"aggregates,omitempty"`
+
+	// AccountEvents are the account lifecycle events of the block, they are emitted by the account_lifecycle processor.
+	AccountEvents []AccountEvent `json:"account-events,omitempty"`
+
+	// FeeSponsorships are the fees which transactions paid for other transactions of their group, they are emitted by
+	// the fee_sponsor processor.
+	FeeSponsorships []FeeSponsorship `json:"fee-sponsorships,omitempty"`
+
+	// UnknownTxns are the transactions of a type which conduit does not know, they are listed by the unknown-txn-types
+	// tag policy.
+	UnknownTxns []UnknownTxn `json:"unknown-txns,omitempty"`
+
+	// ClockSkew is set when the block timestamp is far from the wall clock, it is added by the clock-skew annotate
+	// policy.
+	ClockSkew *ClockSkew `json:"clock-skew,omitempty"`
+}
+
+// Aggregate summarizes the transactions of a time bucket, based on the block timestamps.
+type Aggregate struct {
+	// Name identifies the processor which emitted the aggregate.
+	Name string `json:"name"`
+	// BucketStart and BucketEnd are the unix time bounds of the bucket, BucketEnd is exclusive.
+	BucketStart int64 `json:"bucket-start"`
+	BucketEnd   int64 `json:"bucket-end"`
+	// Group is the value of the group-by key, it is empty when the transactions are not grouped.
+	Group      string `json:"group,omitempty"`
+	FirstRound uint64 `json:"first-round"`
+	LastRound  uint64 `json:"last-round"`
+	// Txns is the number of top level transactions.
+	Txns uint64 `json:"txns"`
+	// Fees is the sum of the transaction fees in microalgos.
+	Fees uint64 `json:"fees"`
+	// Amount is the sum of the payment amounts in microalgos.
+	Amount uint64 `json:"amount"`
+}
+
+// The types of account lifecycle events.
+const (
+	// AccountCreated is emitted when an account receives algos for the first time.
+	AccountCreated = "created"
+	// AccountFunded is emitted when the balance of an account rises above its minimum balance.
+	AccountFunded = "funded"
+	// AccountClosed is emitted when an account is closed by a payment with a close-to address.
+	AccountClosed = "closed"
+	// AccountReopened is emitted when a closed account receives algos again.
+	AccountReopened = "reopened"
+)
+
+// AccountEvent is a change in the lifecycle of an account.
+type AccountEvent struct {
+	// Type is one of "created", "funded", "closed" or "reopened".
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	Round   uint64 `json:"round"`
+	// Intra is the index in the payset of the top level transaction which caused the event.
+	Intra uint64 `json:"intra"`
+	// Balance and MinBalance are the balance of the account and its minimum balance in microalgos at the end of the
+	// round, they are only known when the block includes the state delta.
+	Balance    uint64 `json:"balance,omitempty"`
+	MinBalance uint64 `json:"min-balance,omitempty"`
+}
+
+// The types of fee sponsorships.
+const (
+	// FeeSponsored is emitted when a transaction paid the fee of a transaction with a different sender.
+	FeeSponsored = "sponsored"
+	// FeePooled is emitted when a transaction paid the fee of another transaction with the same sender.
+	FeePooled = "pooled"
+)
+
+// FeeSponsorship is a part of the minimum fee of a transaction which was paid by another transaction of its group.
+type FeeSponsorship struct {
+	// Type is "sponsored" or "pooled".
+	Type  string `json:"type"`
+	Round uint64 `json:"round"`
+	// Intra and Sender identify the top level transaction whose fee was paid.
+	Intra  uint64 `json:"intra"`
+	Sender string `json:"sender"`
+	// SponsorIntra and Sponsor identify the top level transaction which paid the fee.
+	SponsorIntra uint64 `json:"sponsor-intra"`
+	Sponsor      string `json:"sponsor"`
+	// Amount is the part of the fee which was paid, in microalgos.
+	Amount uint64 `json:"amount"`
+}
+
+// UnknownTxn is a top level transaction of a type which conduit does not know, or with an inner transaction of such a
+// type. These are usually types introduced by a protocol upgrade.
+type UnknownTxn struct {
+	// Intra is the index in the payset of the top level transaction.
+	Intra uint64 `json:"intra"`
+	// Type is the unknown type, of the transaction or of its first inner transaction of an unknown type.
+	Type string `json:"type"`
+}
+
+// ClockSkew is the difference between the timestamp of a block and the wall clock when it was imported.
+type ClockSkew struct {
+	// Seconds is the block timestamp minus the wall clock, it is positive for blocks dated in the future.
+	Seconds int64 `json:"seconds"`
+	// ObservedAt is the unix time of the wall clock when the block was imported.
+	ObservedAt int64 `json:"observed-at"`
 }
 
 // MakeBlockDataFromValidatedBlock makes BlockData from agreement.ValidatedBlock
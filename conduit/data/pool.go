@@ -0,0 +1,43 @@
+package data
+
+import (
+	"sync"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+)
+
+// maxPooledPayset is the largest payset capacity kept for reuse, so that a
+// single very large block does not pin its memory for the rest of the run.
+const maxPooledPayset = 1 << 16
+
+// paysetPool holds released paysets as *[]sdk.SignedTxnInBlock.
+var paysetPool sync.Pool
+
+// AcquirePayset returns an empty payset with room for at least n transactions,
+// reusing a released payset when one is available. Importers should decode
+// blocks into it so that its capacity is reused.
+func AcquirePayset(n int) []sdk.SignedTxnInBlock {
+	if ptr, ok := paysetPool.Get().(*[]sdk.SignedTxnInBlock); ok && cap(*ptr) >= n {
+		return (*ptr)[:0]
+	}
+	return make([]sdk.SignedTxnInBlock, 0, n)
+}
+
+// Release returns the payset of the block for reuse by AcquirePayset and
+// clears the block. It must only be called once the exporters and OnComplete
+// callbacks have finished with the round, neither the block nor its
+// transactions may be used afterwards.
+func (blkData *BlockData) Release() {
+	payset := blkData.Payset
+	*blkData = BlockData{}
+	if cap(payset) == 0 || cap(payset) > maxPooledPayset {
+		return
+	}
+	// Drop the references held by the transactions before reuse.
+	payset = payset[:cap(payset)]
+	for i := range payset {
+		payset[i] = sdk.SignedTxnInBlock{}
+	}
+	payset = payset[:0]
+	paysetPool.Put(&payset)
+}
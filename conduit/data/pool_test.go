@@ -0,0 +1,67 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+)
+
+func TestAcquirePayset(t *testing.T) {
+	payset := AcquirePayset(10)
+	assert.Empty(t, payset)
+	assert.GreaterOrEqual(t, cap(payset), 10)
+}
+
+func TestRelease(t *testing.T) {
+	payset := AcquirePayset(2)
+	payset = append(payset, sdk.SignedTxnInBlock{}, sdk.SignedTxnInBlock{})
+	payset[0].Txn.Note = []byte("note")
+	delta := sdk.LedgerStateDelta{}
+	blk := BlockData{
+		BlockHeader: sdk.BlockHeader{Round: 5},
+		Payset:      payset,
+		Delta:       &delta,
+		Aggregates:  []Aggregate{{Name: "a"}},
+	}
+
+	blk.Release()
+	assert.Equal(t, BlockData{}, blk)
+	// The transactions are cleared so that the pool does not keep their data.
+	assert.Nil(t, payset[0].Txn.Note)
+
+	// Releasing an empty block does nothing.
+	blk.Release()
+	assert.Equal(t, BlockData{}, blk)
+}
+
+func TestReleaseLargePayset(t *testing.T) {
+	payset := make([]sdk.SignedTxnInBlock, 1, maxPooledPayset+1)
+	payset[0].Txn.Note = []byte("note")
+	blk := BlockData{Payset: payset}
+	blk.Release()
+	// Large paysets are left to the garbage collector.
+	assert.Equal(t, []byte("note"), payset[0].Txn.Note)
+}
+
+// BenchmarkPayset compares allocating a payset for each round with reusing
+// released paysets.
+func BenchmarkPayset(b *testing.B) {
+	const txns = 5000
+	b.Run("alloc", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			blk := BlockData{Payset: make([]sdk.SignedTxnInBlock, 0, txns)}
+			blk.Payset = append(blk.Payset, make([]sdk.SignedTxnInBlock, txns)...)
+		}
+	})
+	b.Run("pool", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			blk := BlockData{Payset: AcquirePayset(txns)}
+			blk.Payset = append(blk.Payset, make([]sdk.SignedTxnInBlock, txns)...)
+			blk.Release()
+		}
+	})
+}
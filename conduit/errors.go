@@ -1,5 +1,13 @@
 package conduit
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+)
+
 // CriticalError an error that causes the entire conduit pipeline to
 // stop
 type CriticalError struct{}
@@ -7,3 +15,149 @@ type CriticalError struct{}
 func (e *CriticalError) Error() string {
 	return "critical error occurred"
 }
+
+// SchemaDriftError is returned by exporters when the tables or columns they
+// write no longer match what they expect, for example after a migration by
+// another tool. The pipeline pauses instead of retrying or dead-lettering the
+// round until the schema is restored.
+type SchemaDriftError struct {
+	// Differences describes each missing or mismatched table or column.
+	Differences []string
+}
+
+func (e *SchemaDriftError) Error() string {
+	return fmt.Sprintf("schema drift detected: %s", strings.Join(e.Differences, "; "))
+}
+
+// DuplicateRoundError is returned by exporters which already have the round
+// they receive, for example after the pipeline metadata was restored from a
+// backup older than the exporter's data. Instead of retrying the round, the
+// pipeline logs a warning and treats it as exported.
+type DuplicateRoundError struct {
+	Round uint64
+	Err   error
+}
+
+// MakeDuplicateRoundError wraps err in a *DuplicateRoundError for the round,
+// nil is returned unchanged.
+func MakeDuplicateRoundError(round uint64, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &DuplicateRoundError{Round: round, Err: err}
+}
+
+func (e *DuplicateRoundError) Error() string {
+	return fmt.Sprintf("round %d already exported: %v", e.Round, e.Err)
+}
+
+func (e *DuplicateRoundError) Unwrap() error {
+	return e.Err
+}
+
+// RetryableError marks a plugin error as transient. When a retry policy sets
+// retryable-only, other errors are treated as fatal and the round is not
+// retried.
+type RetryableError struct {
+	Err error
+}
+
+// MakeRetryableError wraps err in a *RetryableError, nil is returned unchanged.
+func MakeRetryableError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryableError{Err: err}
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// AuthError is returned by plugins when a service rejects their credentials,
+// for example an expired API token. Retrying does not help until the
+// credentials are replaced, so the pipeline reports it distinctly from other
+// errors, and stops immediately when auth-failure is halt.
+type AuthError struct {
+	Err error
+}
+
+// MakeAuthError wraps err in an *AuthError, nil is returned unchanged.
+func MakeAuthError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &AuthError{Err: err}
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("credentials rejected: %v", e.Err)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// IsAuthStatus reports whether an HTTP status code rejects the credentials of
+// the request, 401 Unauthorized or 403 Forbidden.
+func IsAuthStatus(code int) bool {
+	return code == 401 || code == 403
+}
+
+// sqlStateError is implemented by Postgres errors, such as *pgconn.PgError.
+type sqlStateError interface {
+	SQLState() string
+}
+
+// MakeSQLAuthError wraps err in an *AuthError if it is, or wraps, a Postgres
+// error which rejects the credentials or lacks a privilege, SQLSTATE class 28
+// or 42501. Other errors are returned unchanged.
+func MakeSQLAuthError(err error) error {
+	var sqlErr sqlStateError
+	if !errors.As(err, &sqlErr) {
+		return err
+	}
+	state := sqlErr.SQLState()
+	if strings.HasPrefix(state, "28") || state == "42501" {
+		return MakeAuthError(err)
+	}
+	return err
+}
+
+// TxnError is a failure to process a single transaction of a block.
+type TxnError struct {
+	// Intra is the index of the transaction in the payset of the imported block.
+	Intra int
+	// Txn is the transaction which failed.
+	Txn sdk.SignedTxnInBlock
+	Err error
+}
+
+func (e TxnError) Error() string {
+	return fmt.Sprintf("txn %d: %v", e.Intra, e.Err)
+}
+
+func (e TxnError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError is returned by processors which processed a block except for
+// some of its transactions. The block returned with it must not contain the
+// failed transactions. When on-failure is skip or dead-letter the pipeline
+// exports the block and isolates the failed transactions instead of retrying
+// the round, otherwise it is retried like any other error.
+type PartialError struct {
+	Failed []TxnError
+}
+
+func (e *PartialError) Error() string {
+	msgs := make([]string, 0, len(e.Failed))
+	for _, failed := range e.Failed {
+		msgs = append(msgs, failed.Error())
+	}
+	return fmt.Sprintf("%d transactions failed: %s", len(e.Failed), strings.Join(msgs, "; "))
+}
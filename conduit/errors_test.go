@@ -0,0 +1,63 @@
+package conduit
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pgError is a Postgres error with a SQLSTATE, like *pgconn.PgError.
+type pgError struct {
+	code string
+}
+
+func (e *pgError) Error() string {
+	return "ERROR: (SQLSTATE " + e.code + ")"
+}
+
+func (e *pgError) SQLState() string {
+	return e.code
+}
+
+func TestAuthError(t *testing.T) {
+	assert.NoError(t, MakeAuthError(nil))
+	err := fmt.Errorf("Receive(): %w", MakeAuthError(fmt.Errorf("HTTP 401: Invalid API Token")))
+	assert.EqualError(t, err, "Receive(): credentials rejected: HTTP 401: Invalid API Token")
+	var authErr *AuthError
+	assert.True(t, errors.As(err, &authErr))
+
+	for code, auth := range map[int]bool{401: true, 403: true, 400: false, 404: false, 500: false} {
+		assert.Equal(t, auth, IsAuthStatus(code), code)
+	}
+}
+
+func TestMakeSQLAuthError(t *testing.T) {
+	tests := []struct {
+		err  error
+		auth bool
+	}{
+		{&pgError{code: "28P01"}, true},
+		{fmt.Errorf("connect: %w", &pgError{code: "28000"}), true},
+		{&pgError{code: "42501"}, true},
+		{&pgError{code: "23505"}, false},
+		{fmt.Errorf("connection refused"), false},
+	}
+	for _, tc := range tests {
+		err := MakeSQLAuthError(tc.err)
+		var authErr *AuthError
+		assert.Equal(t, tc.auth, errors.As(err, &authErr), tc.err.Error())
+		assert.ErrorIs(t, err, tc.err)
+	}
+	assert.NoError(t, MakeSQLAuthError(nil))
+}
+
+func TestDuplicateRoundError(t *testing.T) {
+	assert.NoError(t, MakeDuplicateRoundError(5, nil))
+	err := fmt.Errorf("Receive(): %w", MakeDuplicateRoundError(5, fmt.Errorf("expected round 7")))
+	assert.EqualError(t, err, "Receive(): round 5 already exported: expected round 7")
+	var dupErr *DuplicateRoundError
+	assert.True(t, errors.As(err, &dupErr))
+	assert.Equal(t, uint64(5), dupErr.Round)
+}
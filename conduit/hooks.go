@@ -1,9 +1,13 @@
 package conduit
 
 import (
+	"context"
+	"crypto/ed25519"
+
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
 )
 
 // OnCompleteFunc is the signature for the Completed functional interface.
@@ -24,3 +28,217 @@ type ProvideMetricsFunc func() []prometheus.Collector
 type PluginMetrics interface {
 	ProvideMetrics(subsystem string) []prometheus.Collector
 }
+
+// ConfigReloader is for plugins which can apply a new config without being
+// re-initialized.
+type ConfigReloader interface {
+	// OnConfigReload will be called by the Conduit framework between rounds
+	// when the plugin config changed. Plugins which do not implement it are
+	// closed and initialized again with the new config.
+	OnConfigReload(cfg plugins.PluginConfig) error
+}
+
+// RoundProvider is for exporters which keep track of the rounds they have
+// exported, for example in a database, and are authoritative over the next
+// round to process.
+type RoundProvider interface {
+	// NextRound will be called by the Conduit framework after the exporter is
+	// initialized and before the other plugins are initialized. The pipeline
+	// starts from the returned round.
+	NextRound() (uint64, error)
+}
+
+// SchemaVerifier is for exporters which write to a schema that other tools
+// could modify, such as a database.
+type SchemaVerifier interface {
+	// VerifySchema will be called by the Conduit framework before a round at
+	// the configured schema-check-interval, and while the pipeline is paused
+	// because of drift. It returns a *SchemaDriftError when the schema does
+	// not match.
+	VerifySchema() error
+}
+
+// HealthChecker is for exporters which can check that their destination is
+// reachable and writable without receiving a round.
+type HealthChecker interface {
+	// HealthCheck will be called by the Conduit framework at the standby
+	// health-check-interval while the exporter is the standby, and before it
+	// is promoted. It returns an error if the exporter could not receive the
+	// next round.
+	HealthCheck() error
+}
+
+// ForceCommitter is for exporters which buffer data before it is durable,
+// for example in memory or in an open file.
+type ForceCommitter interface {
+	// ForceCommit will be called by the Conduit framework between rounds when
+	// a checkpoint is requested. It makes every round which was received
+	// durable before returning.
+	ForceCommit() error
+}
+
+// ConfigValidator is for plugins which can check their config without
+// side effects, such as connecting or creating files.
+type ConfigValidator interface {
+	// ValidateConfig will be called by the Conduit framework before Init when
+	// the pipeline is validated, for example with --dry-run. It receives the
+	// same config as Init.
+	ValidateConfig(cfg plugins.PluginConfig) error
+}
+
+// Warmer is for plugins which are much slower on their first rounds, for
+// example because they fill caches or open connection pools on demand.
+type Warmer interface {
+	// Warmup will be called by the Conduit framework after every plugin is
+	// initialized and before the first round. It must return when ctx is
+	// cancelled, which happens after the configured warm-up timeout.
+	Warmup(ctx context.Context) error
+}
+
+// Signer signs data with the pipeline's signing key.
+type Signer interface {
+	// Sign returns the ed25519 signature of msg.
+	Sign(msg []byte) []byte
+	// PublicKey returns the key which verifies the signatures.
+	PublicKey() ed25519.PublicKey
+}
+
+// OutputSigner is for exporters which write artifacts, such as files or
+// messages, that downstream consumers may want to verify.
+type OutputSigner interface {
+	// SetSigner will be called by the Conduit framework after the exporter is
+	// initialized when a signing key is configured. The exporter publishes a
+	// signature of each artifact it writes.
+	SetSigner(signer Signer)
+}
+
+// BinaryEncoder is for exporters which write text formats, such as JSON.
+type BinaryEncoder interface {
+	// SetBinaryEncoding will be called by the Conduit framework after the
+	// exporter is initialized when binary-encoding is configured. The
+	// exporter writes binary fields with the encoding.
+	SetBinaryEncoding(encoding BinaryEncoding)
+}
+
+// AmountEncoder is for exporters which write text formats, such as JSON.
+type AmountEncoder interface {
+	// SetAmountFormat will be called by the Conduit framework after the
+	// exporter is initialized when an amount encoding other than integer is
+	// configured. The exporter rewrites the amounts of its JSON output with
+	// AmountFormat.Rewrite.
+	SetAmountFormat(format *AmountFormat)
+}
+
+// ParityReporter is for exporters which can summarize what they exported for a
+// round, so that an exporter migration can verify that two exporters agree.
+type ParityReporter interface {
+	// ParitySummary will be called by the Conduit framework after the round
+	// is exported during a migration. It returns a summary of the round, such
+	// as a checksum or row counts, which is equal for exporters which
+	// exported the same data.
+	ParitySummary(round uint64) (string, error)
+}
+
+// RoundRewinder is for stateful processors and exporters which can remove
+// what they stored for recent rounds, so that the pipeline can be rolled back,
+// for example after a bad deploy of a processor.
+type RoundRewinder interface {
+	// RewindRound will be called by the Conduit framework between rounds when
+	// the pipeline is rolled back. It removes the data of round and every
+	// later round, the next round received is round. It may be called again
+	// with the same round if the rollback failed.
+	RewindRound(round uint64) error
+}
+
+// RoundReader is for exporters which can read back the rounds they exported,
+// so that the output of a config change can be compared with them before it
+// is deployed.
+type RoundReader interface {
+	// ReadRound will be called by the Conduit framework when rounds are
+	// diffed. It returns the block of the round as it was exported, and false
+	// if the round was not exported.
+	ReadRound(round uint64) (data.BlockData, bool, error)
+}
+
+// TipProvider is for importers which know the latest round of the chain, for
+// example the last round of the node they read from.
+type TipProvider interface {
+	// LatestRound will be called by the Conduit framework between rounds when
+	// the throttle lag-rounds is configured. It returns the latest round
+	// which the importer can provide.
+	LatestRound() (uint64, error)
+}
+
+// VersionRecorder is for exporters which keep their data across restarts,
+// such as a database, and can store the versions of the pipeline which wrote
+// it next to the data.
+type VersionRecorder interface {
+	// RecordedVersions will be called by the Conduit framework after every
+	// exporter is initialized. It returns the versions saved by the last
+	// RecordVersions, or nil if none were saved.
+	RecordedVersions() (*WriterVersions, error)
+	// RecordVersions will be called after the recorded versions were checked
+	// and before the first round is exported.
+	RecordVersions(versions WriterVersions) error
+}
+
+// VersionReporter is for plugins which are released separately from Conduit,
+// such as external plugins. The version of other plugins is the Conduit
+// version.
+type VersionReporter interface {
+	// PluginVersion returns the semantic version of the plugin.
+	PluginVersion() string
+}
+
+// BlockCache is a read-only view of the most recently imported blocks.
+type BlockCache interface {
+	// Block returns a copy of the imported block of the round, before any
+	// processor modified it. It returns false if the round is not cached.
+	Block(round uint64) (data.BlockData, bool, error)
+	// Rounds returns the cached rounds, oldest first.
+	Rounds() []uint64
+}
+
+// BlockCacheReader is for processors which look back at recent rounds, for
+// example to match the parts of a group split across adjacent rounds.
+type BlockCacheReader interface {
+	// SetBlockCache will be called by the Conduit framework after the
+	// processor is initialized when block-cache is configured. The cache
+	// already holds the block of the round being processed.
+	SetBlockCache(cache BlockCache)
+}
+
+// PayloadChecker checks the payloads written by an exporter.
+type PayloadChecker interface {
+	// Check returns false if the payload of the round must not be written,
+	// for example because it violated the output schema and was saved to the
+	// dead letter directory. It returns an error to fail the round.
+	Check(round uint64, payload []byte) (bool, error)
+}
+
+// PayloadValidator is for exporters which write JSON payloads, such as files
+// or messages, that downstream consumers parse.
+type PayloadValidator interface {
+	// SetPayloadChecker will be called by the Conduit framework after the
+	// exporter is initialized when an output-schema is configured. The
+	// exporter checks each JSON payload before writing it, after every other
+	// encoding option was applied.
+	SetPayloadChecker(checker PayloadChecker)
+}
+
+// TrafficMeter counts the bytes a plugin transfers over the network.
+type TrafficMeter interface {
+	// Add counts bytes received from and sent to a destination, such as the
+	// host:port of a node, a broker or a listener.
+	Add(destination string, in, out uint64)
+}
+
+// TrafficReporter is for plugins which transfer data over the network, so
+// that the bandwidth of each destination can be accounted for.
+type TrafficReporter interface {
+	// SetTrafficMeter will be called by the Conduit framework before the
+	// plugin is initialized, so that connections opened by Init are counted.
+	// MeteredConn, MeteredListener and MeteredTransport count the bytes of a
+	// connection, a plugin may also call Add with the size of its payloads.
+	SetTrafficMeter(meter TrafficMeter)
+}
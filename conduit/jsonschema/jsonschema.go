@@ -0,0 +1,636 @@
+// Package jsonschema validates JSON documents against a JSON Schema. It
+// supports the validation keywords which describe the shape of a payload:
+// type, enum, const, properties, required, additionalProperties, items,
+// minItems, maxItems, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// minLength, maxLength, pattern, allOf, anyOf, oneOf, not and local $ref to
+// $defs or definitions. Annotations such as title and format are ignored, and
+// other keywords are rejected by Compile so that a schema is never silently
+// weaker than it reads.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// annotations are the keywords which do not validate anything.
+var annotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"$defs":       true,
+	"definitions": true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+	"format":      true,
+	"readOnly":    true,
+	"writeOnly":   true,
+	"deprecated":  true,
+}
+
+var types = map[string]bool{
+	"null":    true,
+	"boolean": true,
+	"object":  true,
+	"array":   true,
+	"number":  true,
+	"integer": true,
+	"string":  true,
+}
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	root *node
+}
+
+// node is a compiled schema or subschema.
+type node struct {
+	// always is set for the true and false schemas.
+	always *bool
+
+	types      []string
+	enum       []interface{}
+	hasConst   bool
+	constValue interface{}
+
+	properties map[string]*node
+	required   []string
+	// additional is nil when additional properties are allowed.
+	additional *node
+
+	items    *node
+	minItems *int
+	maxItems *int
+
+	minimum          *big.Rat
+	maximum          *big.Rat
+	exclusiveMinimum *big.Rat
+	exclusiveMaximum *big.Rat
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	allOf []*node
+	anyOf []*node
+	oneOf []*node
+	not   *node
+
+	// ref is the target of $ref, it is validated with the other keywords.
+	ref *node
+}
+
+// compiler resolves the $ref of a schema document.
+type compiler struct {
+	doc  interface{}
+	refs map[string]*node
+	// referrers are the nodes with a $ref, by reference.
+	referrers map[string][]*node
+}
+
+// Compile parses a JSON Schema document.
+func Compile(schema []byte) (*Schema, error) {
+	doc, err := decode(schema)
+	if err != nil {
+		return nil, fmt.Errorf("Compile(): invalid JSON: %w", err)
+	}
+	c := &compiler{doc: doc, refs: make(map[string]*node), referrers: make(map[string][]*node)}
+	root, err := c.compile(doc, "#")
+	if err != nil {
+		return nil, fmt.Errorf("Compile(): %w", err)
+	}
+	c.refs["#"] = root
+	if err = c.resolve(); err != nil {
+		return nil, fmt.Errorf("Compile(): %w", err)
+	}
+	return &Schema{root: root}, nil
+}
+
+// Validate returns an error describing the first violation of the schema by
+// the JSON document.
+func (s *Schema) Validate(document []byte) error {
+	doc, err := decode(document)
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return s.root.validate(doc, "")
+}
+
+// decode parses JSON, keeping numbers as json.Number so that large integers
+// such as amounts are compared exactly.
+func decode(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the document")
+	}
+	return v, nil
+}
+
+func (c *compiler) compile(v interface{}, loc string) (*node, error) {
+	if b, ok := v.(bool); ok {
+		return &node{always: &b}, nil
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: a schema must be an object or a boolean", loc)
+	}
+	n := &node{}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := obj[key]
+		at := loc + "/" + key
+		var err error
+		switch key {
+		case "type":
+			n.types, err = compileTypes(value)
+		case "enum":
+			values, ok := value.([]interface{})
+			if !ok {
+				err = fmt.Errorf("must be an array")
+			}
+			n.enum = values
+		case "const":
+			n.hasConst = true
+			n.constValue = value
+		case "properties":
+			props, ok := value.(map[string]interface{})
+			if !ok {
+				err = fmt.Errorf("must be an object")
+				break
+			}
+			n.properties = make(map[string]*node, len(props))
+			for name, sub := range props {
+				if n.properties[name], err = c.compile(sub, at+"/"+name); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			n.required, err = compileStrings(value)
+		case "additionalProperties":
+			n.additional, err = c.compile(value, at)
+		case "items":
+			n.items, err = c.compile(value, at)
+		case "minItems":
+			n.minItems, err = compileCount(value)
+		case "maxItems":
+			n.maxItems, err = compileCount(value)
+		case "minLength":
+			n.minLength, err = compileCount(value)
+		case "maxLength":
+			n.maxLength, err = compileCount(value)
+		case "minimum":
+			n.minimum, err = compileNumber(value)
+		case "maximum":
+			n.maximum, err = compileNumber(value)
+		case "exclusiveMinimum":
+			n.exclusiveMinimum, err = compileNumber(value)
+		case "exclusiveMaximum":
+			n.exclusiveMaximum, err = compileNumber(value)
+		case "pattern":
+			s, ok := value.(string)
+			if !ok {
+				err = fmt.Errorf("must be a string")
+				break
+			}
+			n.pattern, err = regexp.Compile(s)
+		case "allOf":
+			n.allOf, err = c.compileList(value, at)
+		case "anyOf":
+			n.anyOf, err = c.compileList(value, at)
+		case "oneOf":
+			n.oneOf, err = c.compileList(value, at)
+		case "not":
+			n.not, err = c.compile(value, at)
+		case "$ref":
+			s, ok := value.(string)
+			if !ok || !strings.HasPrefix(s, "#") {
+				err = fmt.Errorf("only local references starting with '#' are supported")
+				break
+			}
+			c.referrers[s] = append(c.referrers[s], n)
+			if _, ok := c.refs[s]; !ok {
+				c.refs[s] = nil
+			}
+		default:
+			if !annotations[key] {
+				err = fmt.Errorf("unsupported keyword")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", at, err)
+		}
+	}
+	return n, nil
+}
+
+func (c *compiler) compileList(v interface{}, loc string) ([]*node, error) {
+	values, ok := v.([]interface{})
+	if !ok || len(values) == 0 {
+		return nil, fmt.Errorf("must be a non-empty array")
+	}
+	nodes := make([]*node, len(values))
+	for idx, value := range values {
+		var err error
+		if nodes[idx], err = c.compile(value, fmt.Sprintf("%s/%d", loc, idx)); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// resolve compiles the targets of the $ref found while compiling, which may
+// find more references.
+func (c *compiler) resolve() error {
+	for {
+		var pending []string
+		for ref, n := range c.refs {
+			if n == nil {
+				pending = append(pending, ref)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		sort.Strings(pending)
+		for _, ref := range pending {
+			target, err := pointer(c.doc, ref)
+			if err != nil {
+				return fmt.Errorf("$ref %s: %w", ref, err)
+			}
+			// A recursive $ref is only registered while compiling, the
+			// referrers are linked once every target is compiled.
+			if c.refs[ref], err = c.compile(target, ref); err != nil {
+				return err
+			}
+		}
+	}
+	for ref, nodes := range c.referrers {
+		for _, n := range nodes {
+			n.ref = c.refs[ref]
+		}
+	}
+	return nil
+}
+
+// pointer returns the value of the JSON pointer fragment ref in doc.
+func pointer(doc interface{}, ref string) (interface{}, error) {
+	fragment := strings.TrimPrefix(ref, "#")
+	if fragment == "" {
+		return doc, nil
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		return nil, fmt.Errorf("only JSON pointers are supported")
+	}
+	value := doc
+	for _, token := range strings.Split(fragment[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch v := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if value, ok = v[token]; !ok {
+				return nil, fmt.Errorf("not found")
+			}
+		case []interface{}:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, fmt.Errorf("not found")
+			}
+			value = v[idx]
+		default:
+			return nil, fmt.Errorf("not found")
+		}
+	}
+	return value, nil
+}
+
+func compileTypes(v interface{}) ([]string, error) {
+	var names []string
+	if s, ok := v.(string); ok {
+		names = []string{s}
+	} else {
+		var err error
+		if names, err = compileStrings(v); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range names {
+		if !types[name] {
+			return nil, fmt.Errorf("unknown type '%s'", name)
+		}
+	}
+	return names, nil
+}
+
+func compileStrings(v interface{}) ([]string, error) {
+	values, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be an array of strings")
+	}
+	strs := make([]string, len(values))
+	for idx, value := range values {
+		if strs[idx], ok = value.(string); !ok {
+			return nil, fmt.Errorf("must be an array of strings")
+		}
+	}
+	return strs, nil
+}
+
+func compileCount(v interface{}) (*int, error) {
+	num, ok := v.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("must be a non-negative integer")
+	}
+	count, err := strconv.Atoi(num.String())
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("must be a non-negative integer")
+	}
+	return &count, nil
+}
+
+func compileNumber(v interface{}) (*big.Rat, error) {
+	num, ok := v.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("must be a number")
+	}
+	r, ok := new(big.Rat).SetString(num.String())
+	if !ok {
+		return nil, fmt.Errorf("must be a number")
+	}
+	return r, nil
+}
+
+// typeOf returns the JSON type of a decoded value, numbers with an integral
+// value are integers.
+func typeOf(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case json.Number:
+		if r, ok := new(big.Rat).SetString(val.String()); ok && r.IsInt() {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func hasType(v interface{}, name string) bool {
+	actual := typeOf(v)
+	return actual == name || (name == "number" && actual == "integer")
+}
+
+// equal compares decoded values, numbers are compared by value.
+func equal(a, b interface{}) bool {
+	switch va := a.(type) {
+	case json.Number:
+		vb, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		ra, okA := new(big.Rat).SetString(va.String())
+		rb, okB := new(big.Rat).SetString(vb.String())
+		return okA && okB && ra.Cmp(rb) == 0
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for key, value := range va {
+			other, ok := vb[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for idx := range va {
+			if !equal(va[idx], vb[idx]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// violation is an error at a location of the document.
+func violation(at string, format string, args ...interface{}) error {
+	if at == "" {
+		at = "/"
+	}
+	return fmt.Errorf("%s: %s", at, fmt.Sprintf(format, args...))
+}
+
+func (n *node) validate(v interface{}, at string) error {
+	if n.always != nil {
+		if !*n.always {
+			return violation(at, "no value is allowed")
+		}
+		return nil
+	}
+	if n.ref != nil {
+		if err := n.ref.validate(v, at); err != nil {
+			return err
+		}
+	}
+	if len(n.types) > 0 {
+		ok := false
+		for _, name := range n.types {
+			if hasType(v, name) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return violation(at, "expected %s, found %s", strings.Join(n.types, " or "), typeOf(v))
+		}
+	}
+	if n.enum != nil {
+		ok := false
+		for _, value := range n.enum {
+			if equal(v, value) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return violation(at, "value is not one of the enum values")
+		}
+	}
+	if n.hasConst && !equal(v, n.constValue) {
+		return violation(at, "value does not match the const value")
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if err := n.validateObject(val, at); err != nil {
+			return err
+		}
+	case []interface{}:
+		if err := n.validateArray(val, at); err != nil {
+			return err
+		}
+	case json.Number:
+		if err := n.validateNumber(val, at); err != nil {
+			return err
+		}
+	case string:
+		if err := n.validateString(val, at); err != nil {
+			return err
+		}
+	}
+
+	for _, sub := range n.allOf {
+		if err := sub.validate(v, at); err != nil {
+			return err
+		}
+	}
+	if len(n.anyOf) > 0 {
+		var first error
+		for _, sub := range n.anyOf {
+			err := sub.validate(v, at)
+			if err == nil {
+				first = nil
+				break
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		if first != nil {
+			return violation(at, "value does not match any schema of anyOf: %v", first)
+		}
+	}
+	if len(n.oneOf) > 0 {
+		matches := 0
+		for _, sub := range n.oneOf {
+			if sub.validate(v, at) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return violation(at, "value matches %d schemas of oneOf, expected 1", matches)
+		}
+	}
+	if n.not != nil && n.not.validate(v, at) == nil {
+		return violation(at, "value matches the schema of not")
+	}
+	return nil
+}
+
+func (n *node) validateObject(obj map[string]interface{}, at string) error {
+	for _, name := range n.required {
+		if _, ok := obj[name]; !ok {
+			return violation(at, "missing required property '%s'", name)
+		}
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		sub, ok := n.properties[key]
+		if !ok {
+			sub = n.additional
+		}
+		if sub == nil {
+			continue
+		}
+		if sub.always != nil && !*sub.always && !ok {
+			return violation(at, "additional property '%s' is not allowed", key)
+		}
+		if err := sub.validate(obj[key], at+"/"+escape(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *node) validateArray(arr []interface{}, at string) error {
+	if n.minItems != nil && len(arr) < *n.minItems {
+		return violation(at, "expected at least %d items, found %d", *n.minItems, len(arr))
+	}
+	if n.maxItems != nil && len(arr) > *n.maxItems {
+		return violation(at, "expected at most %d items, found %d", *n.maxItems, len(arr))
+	}
+	if n.items != nil {
+		for idx, item := range arr {
+			if err := n.items.validate(item, fmt.Sprintf("%s/%d", at, idx)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (n *node) validateNumber(num json.Number, at string) error {
+	r, ok := new(big.Rat).SetString(num.String())
+	if !ok {
+		return violation(at, "invalid number %s", num)
+	}
+	if n.minimum != nil && r.Cmp(n.minimum) < 0 {
+		return violation(at, "%s is less than the minimum %s", num, n.minimum.RatString())
+	}
+	if n.maximum != nil && r.Cmp(n.maximum) > 0 {
+		return violation(at, "%s is greater than the maximum %s", num, n.maximum.RatString())
+	}
+	if n.exclusiveMinimum != nil && r.Cmp(n.exclusiveMinimum) <= 0 {
+		return violation(at, "%s is not greater than the exclusive minimum %s", num, n.exclusiveMinimum.RatString())
+	}
+	if n.exclusiveMaximum != nil && r.Cmp(n.exclusiveMaximum) >= 0 {
+		return violation(at, "%s is not less than the exclusive maximum %s", num, n.exclusiveMaximum.RatString())
+	}
+	return nil
+}
+
+func (n *node) validateString(s string, at string) error {
+	length := utf8.RuneCountInString(s)
+	if n.minLength != nil && length < *n.minLength {
+		return violation(at, "expected at least %d characters, found %d", *n.minLength, length)
+	}
+	if n.maxLength != nil && length > *n.maxLength {
+		return violation(at, "expected at most %d characters, found %d", *n.maxLength, length)
+	}
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		return violation(at, "value does not match the pattern %s", n.pattern)
+	}
+	return nil
+}
+
+// escape encodes a property name as a JSON pointer token.
+func escape(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,120 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const blockSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "block",
+	"type": "object",
+	"required": ["block"],
+	"properties": {
+		"block": {
+			"type": "object",
+			"required": ["rnd"],
+			"properties": {"rnd": {"type": "integer", "minimum": 1}}
+		},
+		"payset": {"type": "array", "maxItems": 2, "items": {"$ref": "#/$defs/txn"}},
+		"tree": {"$ref": "#/$defs/tree"}
+	},
+	"additionalProperties": false,
+	"$defs": {
+		"txn": {
+			"type": "object",
+			"properties": {
+				"amt": {"type": "integer", "maximum": 18446744073709551615},
+				"type": {"enum": ["pay", "axfer"]}
+			}
+		},
+		"tree": {
+			"type": "object",
+			"properties": {
+				"child": {"$ref": "#/$defs/tree"},
+				"name": {"type": "string", "pattern": "^a", "maxLength": 3}
+			}
+		}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile([]byte(blockSchema))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		document string
+		err      string
+	}{
+		{name: "valid", document: `{"block": {"rnd": 5}}`},
+		{name: "integral number", document: `{"block": {"rnd": 5.0}}`},
+		{name: "minimum", document: `{"block": {"rnd": 0}}`, err: "/block/rnd: 0 is less than the minimum 1"},
+		{name: "integer", document: `{"block": {"rnd": 5.5}}`, err: "/block/rnd: expected integer, found number"},
+		{name: "required", document: `{"block": {}}`, err: "/block: missing required property 'rnd'"},
+		{name: "additional property", document: `{"block": {"rnd": 5}, "cert": {}}`, err: "/: additional property 'cert' is not allowed"},
+		{name: "type", document: `[]`, err: "/: expected object, found array"},
+		{name: "enum", document: `{"block": {"rnd": 5}, "payset": [{"type": "pay"}, {"type": "appl"}]}`, err: "/payset/1/type: value is not one of the enum values"},
+		{name: "max items", document: `{"block": {"rnd": 5}, "payset": [{}, {}, {}]}`, err: "/payset: expected at most 2 items, found 3"},
+		{name: "uint64", document: `{"block": {"rnd": 5}, "payset": [{"amt": 18446744073709551615}]}`},
+		{name: "uint64 overflow", document: `{"block": {"rnd": 5}, "payset": [{"amt": 18446744073709551616}]}`, err: "/payset/0/amt: 18446744073709551616 is greater than the maximum 18446744073709551615"},
+		{name: "recursive", document: `{"block": {"rnd": 5}, "tree": {"child": {"child": {"name": "abc"}}}}`},
+		{name: "pattern", document: `{"block": {"rnd": 5}, "tree": {"child": {"name": "b"}}}`, err: "/tree/child/name: value does not match the pattern ^a"},
+		{name: "max length", document: `{"block": {"rnd": 5}, "tree": {"name": "abcd"}}`, err: "/tree/name: expected at most 3 characters, found 4"},
+		{name: "invalid JSON", document: `{"block": {"rnd": 5}} {}`, err: "invalid JSON: unexpected data after the document"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := schema.Validate([]byte(tc.document))
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestValidateCombinators(t *testing.T) {
+	schema, err := Compile([]byte(`{
+		"anyOf": [{"type": "string"}, {"type": "integer"}],
+		"oneOf": [{"type": "integer"}, {"const": "x"}, {"type": "string", "minLength": 2}],
+		"not": {"const": 3}
+	}`))
+	require.NoError(t, err)
+	assert.NoError(t, schema.Validate([]byte(`1`)))
+	assert.NoError(t, schema.Validate([]byte(`"x"`)))
+	assert.EqualError(t, schema.Validate([]byte(`true`)), "/: value does not match any schema of anyOf: /: expected string, found boolean")
+	assert.EqualError(t, schema.Validate([]byte(`""`)), "/: value matches 0 schemas of oneOf, expected 1")
+	assert.EqualError(t, schema.Validate([]byte(`3.0`)), "/: value matches the schema of not")
+
+	schema, err = Compile([]byte(`false`))
+	require.NoError(t, err)
+	assert.EqualError(t, schema.Validate([]byte(`{}`)), "/: no value is allowed")
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{schema: `{`, err: "Compile(): invalid JSON: unexpected EOF"},
+		{schema: `1`, err: "Compile(): #: a schema must be an object or a boolean"},
+		{schema: `{"type": "int"}`, err: "Compile(): #/type: unknown type 'int'"},
+		{schema: `{"uniqueItems": true}`, err: "Compile(): #/uniqueItems: unsupported keyword"},
+		{schema: `{"properties": {"a": 1}}`, err: "Compile(): #/properties/a: a schema must be an object or a boolean"},
+		{schema: `{"anyOf": []}`, err: "Compile(): #/anyOf: must be a non-empty array"},
+		{schema: `{"minItems": -1}`, err: "Compile(): #/minItems: must be a non-negative integer"},
+		{schema: `{"pattern": "("}`, err: "Compile(): #/pattern: error parsing regexp: missing closing ): `(`"},
+		{schema: `{"$ref": "other.json"}`, err: "Compile(): #/$ref: only local references starting with '#' are supported"},
+		{schema: `{"$ref": "#/$defs/none"}`, err: "Compile(): $ref #/$defs/none: not found"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.schema, func(t *testing.T) {
+			_, err := Compile([]byte(tc.schema))
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
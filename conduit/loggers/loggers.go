@@ -33,6 +33,18 @@ func MakeThreadSafeLoggerWithWriter(level log.Level, writer io.Writer) *log.Logg
 	return logger
 }
 
+// MakePrettyThreadSafeLoggerWithWriter creates a logger with colored, human-friendly output using a
+// ThreadSafeWriter output.
+func MakePrettyThreadSafeLoggerWithWriter(level log.Level, writer io.Writer) *log.Logger {
+	logger := MakeThreadSafeLoggerWithWriter(level, writer)
+	logger.SetFormatter(&pipeline.PrettyLogFormatter{
+		Type:  "conduit",
+		Name:  "main",
+		Color: true,
+	})
+	return logger
+}
+
 // MakeThreadSafeLogger returns a logger that is synchronized with the internal mutex, if no file is provided write
 // to os.Stdout.
 func MakeThreadSafeLogger(level log.Level, logFile string) (*log.Logger, error) {
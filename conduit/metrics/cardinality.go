@@ -0,0 +1,66 @@
+package metrics
+
+import "fmt"
+
+// LabelMode controls how a high-cardinality label dimension is reported.
+type LabelMode string
+
+const (
+	// LabelModeFull reports every distinct label value. This is the default.
+	LabelModeFull LabelMode = "full"
+	// LabelModeBucket groups label values into a small, fixed set of buckets.
+	LabelModeBucket LabelMode = "bucket"
+	// LabelModeOff collapses the dimension into a single AllLabelValue.
+	LabelModeOff LabelMode = "off"
+)
+
+// AllLabelValue is the label value reported when a dimension is disabled.
+const AllLabelValue = "all"
+
+// txnTypeBuckets groups transaction types into coarse categories.
+var txnTypeBuckets = map[string]string{
+	"pay":    "payment",
+	"keyreg": "consensus",
+	"stpf":   "consensus",
+	"acfg":   "asset",
+	"axfer":  "asset",
+	"afrz":   "asset",
+	"appl":   "application",
+}
+
+// ValidateLabelMode returns an error if the mode is not supported. The empty
+// string is accepted and treated as LabelModeFull.
+func ValidateLabelMode(mode LabelMode, allowBucket bool) error {
+	switch mode {
+	case "", LabelModeFull, LabelModeOff:
+		return nil
+	case LabelModeBucket:
+		if allowBucket {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported label mode '%s'", mode)
+}
+
+// TxnTypeLabel maps a transaction type to the label value used for the given mode.
+func TxnTypeLabel(mode LabelMode, txnType string) string {
+	switch mode {
+	case LabelModeOff:
+		return AllLabelValue
+	case LabelModeBucket:
+		if bucket, ok := txnTypeBuckets[txnType]; ok {
+			return bucket
+		}
+		return "other"
+	default:
+		return txnType
+	}
+}
+
+// ProcessorLabel maps a processor name to the label value used for the given mode.
+func ProcessorLabel(mode LabelMode, processorName string) string {
+	if mode == LabelModeOff {
+		return AllLabelValue
+	}
+	return processorName
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxnTypeLabel(t *testing.T) {
+	tests := []struct {
+		mode     LabelMode
+		txnType  string
+		expected string
+	}{
+		{"", "pay", "pay"},
+		{LabelModeFull, "axfer", "axfer"},
+		{LabelModeBucket, "axfer", "asset"},
+		{LabelModeBucket, "afrz", "asset"},
+		{LabelModeBucket, "appl", "application"},
+		{LabelModeBucket, "future", "other"},
+		{LabelModeOff, "pay", AllLabelValue},
+	}
+	for _, test := range tests {
+		t.Run(string(test.mode)+"_"+test.txnType, func(t *testing.T) {
+			assert.Equal(t, test.expected, TxnTypeLabel(test.mode, test.txnType))
+		})
+	}
+}
+
+func TestProcessorLabel(t *testing.T) {
+	assert.Equal(t, "filter_processor", ProcessorLabel("", "filter_processor"))
+	assert.Equal(t, "filter_processor", ProcessorLabel(LabelModeFull, "filter_processor"))
+	assert.Equal(t, AllLabelValue, ProcessorLabel(LabelModeOff, "filter_processor"))
+}
+
+func TestValidateLabelMode(t *testing.T) {
+	assert.NoError(t, ValidateLabelMode("", false))
+	assert.NoError(t, ValidateLabelMode(LabelModeFull, false))
+	assert.NoError(t, ValidateLabelMode(LabelModeOff, false))
+	assert.NoError(t, ValidateLabelMode(LabelModeBucket, true))
+	assert.ErrorContains(t, ValidateLabelMode(LabelModeBucket, false), "unsupported label mode 'bucket'")
+	assert.ErrorContains(t, ValidateLabelMode("asdf", true), "unsupported label mode 'asdf'")
+}
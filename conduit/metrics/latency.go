@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LatencyType is the metric type of the stage latencies.
+type LatencyType string
+
+const (
+	// LatencySummary reports the stage latencies as summaries with quantiles
+	// computed by conduit. This is the default.
+	LatencySummary LatencyType = "summary"
+	// LatencyHistogram reports the stage latencies as histograms, which can be
+	// aggregated across instances and carry the trace ID of the round as an
+	// exemplar when tracing is enabled.
+	LatencyHistogram LatencyType = "histogram"
+)
+
+// ExemplarTraceIDLabel is the exemplar label holding the trace ID of a round.
+const ExemplarTraceIDLabel = "trace_id"
+
+// latencyBuckets are the histogram buckets of the stage latencies, from 5ms
+// to about 40s.
+var latencyBuckets = prometheus.ExponentialBuckets(0.005, 2, 14)
+
+// latencyType is the type of the stage latencies created by
+// RegisterPrometheusMetrics.
+var latencyType = LatencySummary
+
+// Latency is a stage latency metric, a summary or a histogram.
+type Latency interface {
+	prometheus.Collector
+	prometheus.Observer
+}
+
+// LatencyVec is a stage latency metric with labels, a summary or a histogram.
+type LatencyVec interface {
+	prometheus.Collector
+	WithLabelValues(lvs ...string) prometheus.Observer
+}
+
+// ValidateLatencyType returns an error if the type is not supported. The
+// empty string is accepted and treated as LatencySummary.
+func ValidateLatencyType(t LatencyType) error {
+	switch t {
+	case "", LatencySummary, LatencyHistogram:
+		return nil
+	}
+	return fmt.Errorf("unsupported latency type '%s', expected %s or %s", t, LatencySummary, LatencyHistogram)
+}
+
+// SetLatencyType sets the type of the stage latencies, it applies to the
+// metrics created by the next call to RegisterPrometheusMetrics.
+func SetLatencyType(t LatencyType) {
+	if t == "" {
+		t = LatencySummary
+	}
+	latencyType = t
+}
+
+func newLatency(subsystem, name, help string) Latency {
+	if latencyType == LatencyHistogram {
+		return prometheus.NewHistogram(prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      name,
+			Help:      help,
+			Buckets:   latencyBuckets,
+		})
+	}
+	return prometheus.NewSummary(prometheus.SummaryOpts{
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      help,
+	})
+}
+
+func newLatencyVec(subsystem, name, help string, labels []string) LatencyVec {
+	if latencyType == LatencyHistogram {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      name,
+			Help:      help,
+			Buckets:   latencyBuckets,
+		}, labels)
+	}
+	return prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      help,
+	}, labels)
+}
+
+// ObserveLatency observes a latency in seconds. The trace ID is attached as an
+// exemplar when it is set and the metric supports exemplars, which histograms
+// do and summaries do not.
+func ObserveLatency(o prometheus.Observer, seconds float64, traceID string) {
+	if traceID != "" {
+		if e, ok := o.(prometheus.ExemplarObserver); ok {
+			e.ObserveWithExemplar(seconds, prometheus.Labels{ExemplarTraceIDLabel: traceID})
+			return
+		}
+	}
+	o.Observe(seconds)
+}
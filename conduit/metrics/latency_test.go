@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingObserver struct {
+	values    []float64
+	exemplars []prometheus.Labels
+}
+
+func (o *recordingObserver) Observe(v float64) {
+	o.values = append(o.values, v)
+}
+
+type recordingExemplarObserver struct {
+	recordingObserver
+}
+
+func (o *recordingExemplarObserver) ObserveWithExemplar(v float64, exemplar prometheus.Labels) {
+	o.values = append(o.values, v)
+	o.exemplars = append(o.exemplars, exemplar)
+}
+
+func TestValidateLatencyType(t *testing.T) {
+	assert.NoError(t, ValidateLatencyType(""))
+	assert.NoError(t, ValidateLatencyType(LatencySummary))
+	assert.NoError(t, ValidateLatencyType(LatencyHistogram))
+	assert.EqualError(t, ValidateLatencyType("gauge"), "unsupported latency type 'gauge', expected summary or histogram")
+}
+
+func TestObserveLatency(t *testing.T) {
+	// Summaries do not support exemplars.
+	summary := &recordingObserver{}
+	ObserveLatency(summary, 1.5, "4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.Equal(t, []float64{1.5}, summary.values)
+
+	histogram := &recordingExemplarObserver{}
+	ObserveLatency(histogram, 0.5, "")
+	ObserveLatency(histogram, 2, "4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.Equal(t, []float64{0.5, 2}, histogram.values)
+	assert.Equal(t, []prometheus.Labels{{ExemplarTraceIDLabel: "4bf92f3577b34da6a3ce929d0e0e4736"}}, histogram.exemplars)
+}
+
+func TestHistogramLatency(t *testing.T) {
+	SetLatencyType(LatencyHistogram)
+	RegisterPrometheusMetrics("histogram_test")
+	defer func() {
+		SetLatencyType("")
+		RegisterPrometheusMetrics("uninitialized")
+	}()
+
+	_, ok := ImporterTimeSeconds.(prometheus.ExemplarObserver)
+	assert.True(t, ok)
+	_, ok = ProcessorTimeSeconds.WithLabelValues("noop").(prometheus.ExemplarObserver)
+	assert.True(t, ok)
+}
@@ -24,6 +24,33 @@ func RegisterPrometheusMetrics(subsystem string) {
 	_ = prometheus.Register(ProcessorTimeSeconds)
 	_ = prometheus.Register(ExporterTimeSeconds)
 	_ = prometheus.Register(PipelineRetryCount)
+	_ = prometheus.Register(PipelineInfo)
+	_ = prometheus.Register(ProcessorMismatches)
+	_ = prometheus.Register(PluginCPUSeconds)
+	_ = prometheus.Register(PluginAllocBytes)
+	_ = prometheus.Register(PluginAllocObjects)
+	_ = prometheus.Register(PluginGoroutines)
+	_ = prometheus.Register(ExporterSchemaDrift)
+	_ = prometheus.Register(CoordinationClaims)
+	_ = prometheus.Register(InvalidBlocks)
+	_ = prometheus.Register(UnknownProtocolBlocks)
+	_ = prometheus.Register(UnknownTxnTypes)
+	_ = prometheus.Register(AuthFailures)
+	_ = prometheus.Register(ObserverErrors)
+	_ = prometheus.Register(ObserverDroppedRounds)
+	_ = prometheus.Register(ClockSkewBlocks)
+	_ = prometheus.Register(ExporterVerifications)
+	_ = prometheus.Register(ConfigDrift)
+	_ = prometheus.Register(SLORounds)
+	_ = prometheus.Register(SLOBurnRate)
+	_ = prometheus.Register(SLOBudgetRemaining)
+	_ = prometheus.Register(PayloadValidations)
+	_ = prometheus.Register(PriorityLaneTxns)
+	_ = prometheus.Register(PluginBytesIn)
+	_ = prometheus.Register(PluginBytesOut)
+	_ = prometheus.Register(ExporterDuplicateRounds)
+	_ = prometheus.Register(WatchdogStalls)
+	_ = prometheus.Register(WatchdogRestartBudget)
 }
 func deregister() {
 	// Use ImportedTxns as a sentinel value. None or all should be initialized.
@@ -37,16 +64,38 @@ func deregister() {
 		prometheus.Unregister(ProcessorTimeSeconds)
 		prometheus.Unregister(ExporterTimeSeconds)
 		prometheus.Unregister(PipelineRetryCount)
+		prometheus.Unregister(PipelineInfo)
+		prometheus.Unregister(ProcessorMismatches)
+		prometheus.Unregister(PluginCPUSeconds)
+		prometheus.Unregister(PluginAllocBytes)
+		prometheus.Unregister(PluginAllocObjects)
+		prometheus.Unregister(PluginGoroutines)
+		prometheus.Unregister(ExporterSchemaDrift)
+		prometheus.Unregister(CoordinationClaims)
+		prometheus.Unregister(InvalidBlocks)
+		prometheus.Unregister(UnknownProtocolBlocks)
+		prometheus.Unregister(UnknownTxnTypes)
+		prometheus.Unregister(AuthFailures)
+		prometheus.Unregister(ObserverErrors)
+		prometheus.Unregister(ObserverDroppedRounds)
+		prometheus.Unregister(ClockSkewBlocks)
+		prometheus.Unregister(ExporterVerifications)
+		prometheus.Unregister(ConfigDrift)
+		prometheus.Unregister(SLORounds)
+		prometheus.Unregister(SLOBurnRate)
+		prometheus.Unregister(SLOBudgetRemaining)
+		prometheus.Unregister(PayloadValidations)
+		prometheus.Unregister(PriorityLaneTxns)
+		prometheus.Unregister(PluginBytesIn)
+		prometheus.Unregister(PluginBytesOut)
+		prometheus.Unregister(ExporterDuplicateRounds)
+		prometheus.Unregister(WatchdogStalls)
+		prometheus.Unregister(WatchdogRestartBudget)
 	}
 }
 
 func instantiateCollectors(subsystem string) {
-	BlockImportTimeSeconds = prometheus.NewSummary(
-		prometheus.SummaryOpts{
-			Subsystem: subsystem,
-			Name:      BlockImportTimeName,
-			Help:      "Total block upload and processing time in seconds.",
-		})
+	BlockImportTimeSeconds = newLatency(subsystem, BlockImportTimeName, "Total block upload and processing time in seconds.")
 
 	ImportedTxnsPerBlock = prometheus.NewSummary(
 		prometheus.SummaryOpts{
@@ -72,48 +121,293 @@ func instantiateCollectors(subsystem string) {
 			Help:      "The most recent round indexer has imported.",
 		})
 
-	ImporterTimeSeconds = prometheus.NewSummary(
-		prometheus.SummaryOpts{
+	ImporterTimeSeconds = newLatency(subsystem, ImporterTimeName, "Time spent at importer step")
+
+	ProcessorTimeSeconds = newLatencyVec(subsystem, ProcessorTimeName, "Time spent running a processor", []string{"processor_name"})
+
+	ExporterTimeSeconds = newLatency(subsystem, ExporterTimeName, "Time spent at exporter step")
+
+	PipelineRetryCount = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
 			Subsystem: subsystem,
-			Name:      ImporterTimeName,
-			Help:      "Time spent at importer step",
+			Name:      PipelineRetryCountName,
+			Help:      "Total pipeline retries since last successful run",
 		})
 
-	ProcessorTimeSeconds = prometheus.NewSummaryVec(
-		prometheus.SummaryOpts{
+	PipelineInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      PipelineInfoName,
+			Help:      "Pipeline composition and configuration fingerprint, always 1",
+		},
+		[]string{"importer", "processors_hash", "exporter", "config_hash"},
+	)
+
+	ProcessorMismatches = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
 			Subsystem: subsystem,
-			Name:      ProcessorTimeName,
-			Help:      "Time spent running a processor",
+			Name:      ProcessorMismatchesName,
+			Help:      "Rounds where replaying a processor produced different output",
 		},
 		[]string{"processor_name"},
 	)
 
-	ExporterTimeSeconds = prometheus.NewSummary(
-		prometheus.SummaryOpts{
+	PluginCPUSeconds = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      PluginCPUSecondsName,
+			Help:      "Process CPU time used while a plugin was called",
+		},
+		[]string{"plugin_type", "plugin_name"},
+	)
+
+	PluginAllocBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      PluginAllocBytesName,
+			Help:      "Heap bytes allocated while a plugin was called",
+		},
+		[]string{"plugin_type", "plugin_name"},
+	)
+
+	PluginAllocObjects = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      PluginAllocObjectsName,
+			Help:      "Heap objects allocated while a plugin was called",
+		},
+		[]string{"plugin_type", "plugin_name"},
+	)
+
+	PluginGoroutines = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      PluginGoroutinesName,
+			Help:      "Running goroutines started by a plugin",
+		},
+		[]string{"plugin_type", "plugin_name"},
+	)
+
+	ExporterSchemaDrift = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      ExporterSchemaDriftName,
+			Help:      "1 while the pipeline is paused because the exporter schema drifted",
+		},
+		[]string{"exporter_name"},
+	)
+
+	CoordinationClaims = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      CoordinationClaimsName,
+			Help:      "Round claims grouped by result (claimed, completed or held)",
+		},
+		[]string{"result"},
+	)
+
+	InvalidBlocks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      InvalidBlocksName,
+			Help:      "Imported blocks which failed validation, grouped by the failed check",
+		},
+		[]string{"check"},
+	)
+
+	UnknownProtocolBlocks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      UnknownProtocolBlocksName,
+			Help:      "Imported blocks built with an unknown consensus protocol, grouped by protocol",
+		},
+		[]string{"protocol"},
+	)
+
+	UnknownTxnTypes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      UnknownTxnTypesName,
+			Help:      "Imported transactions of an unknown type, grouped by type",
+		},
+		[]string{"type"},
+	)
+
+	AuthFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      AuthFailuresName,
+			Help:      "Rounds which failed because a service rejected the credentials of a plugin",
+		},
+		[]string{"plugin_type", "plugin_name"},
+	)
+
+	ObserverErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      ObserverErrorsName,
+			Help:      "Rounds which an observer failed to observe",
+		},
+		[]string{"observer_name"},
+	)
+
+	ObserverDroppedRounds = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      ObserverDroppedRoundsName,
+			Help:      "Rounds which were not sent to an observer because it was too far behind",
+		},
+		[]string{"observer_name"},
+	)
+
+	ClockSkewBlocks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      ClockSkewBlocksName,
+			Help:      "Imported blocks whose timestamp is too far from the wall clock, grouped by direction",
+		},
+		[]string{"direction"},
+	)
+
+	ExporterVerifications = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      ExporterVerificationsName,
+			Help:      "Sampled rounds which were compared with the reference exporter, grouped by exporter and result (match or diverged)",
+		},
+		[]string{"exporter_name", "result"},
+	)
+
+	ConfigDrift = prometheus.NewGauge(
+		prometheus.GaugeOpts{
 			Subsystem: subsystem,
-			Name:      ExporterTimeName,
-			Help:      "Time spent at exporter step",
+			Name:      ConfigDriftName,
+			Help:      "1 while the config file differs from the running config",
 		})
 
-	PipelineRetryCount = prometheus.NewHistogram(
-		prometheus.HistogramOpts{
+	SLORounds = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
 			Subsystem: subsystem,
-			Name:      PipelineRetryCountName,
-			Help:      "Total pipeline retries since last successful run",
+			Name:      SLORoundsName,
+			Help:      "Exported rounds grouped by whether they met the latency SLO (in_time or late)",
+		},
+		[]string{"result"},
+	)
+
+	SLOBurnRate = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      SLOBurnRateName,
+			Help:      "Rate at which the latency SLO error budget is spent over its window, above 1 the target is missed",
+		})
+
+	SLOBudgetRemaining = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      SLOBudgetRemainingName,
+			Help:      "Fraction of the latency SLO error budget left in its window",
+		})
+
+	PayloadValidations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      PayloadValidationsName,
+			Help:      "Exporter payloads which were validated with the output schema, grouped by exporter and result (valid or violation)",
+		},
+		[]string{"exporter_name", "result"},
+	)
+
+	PriorityLaneTxns = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      PriorityLaneTxnsName,
+			Help:      "Transactions delivered to the exporters of the priority lanes, grouped by lane",
+		},
+		[]string{"lane"},
+	)
+
+	PluginBytesIn = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      PluginBytesInName,
+			Help:      "Bytes received by the plugins over the network, grouped by destination",
+		},
+		[]string{"plugin_type", "plugin_name", "destination"},
+	)
+
+	PluginBytesOut = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      PluginBytesOutName,
+			Help:      "Bytes sent by the plugins over the network, grouped by destination",
+		},
+		[]string{"plugin_type", "plugin_name", "destination"},
+	)
+
+	ExporterDuplicateRounds = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      ExporterDuplicateRoundsName,
+			Help:      "Rounds which an exporter already had, they were treated as exported",
+		},
+		[]string{"exporter_name"},
+	)
+
+	WatchdogStalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      WatchdogStallsName,
+			Help:      "Stalls of the pipeline loop detected by the watchdog, grouped by action (restarted or stopped)",
+		},
+		[]string{"action"},
+	)
+
+	WatchdogRestartBudget = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      WatchdogRestartBudgetName,
+			Help:      "Restarts of the pipeline loop which the watchdog may still do",
 		})
 }
 
 // Prometheus metric names broken out for reuse.
 const (
-	BlockImportTimeName      = "import_time_sec"
-	ImportedTxnsPerBlockName = "imported_tx_per_block"
-	ImportedRoundGaugeName   = "imported_round"
-	GetAlgodRawBlockTimeName = "get_algod_raw_block_time_sec"
-	ImportedTxnsName         = "imported_txns"
-	ImporterTimeName         = "importer_time_sec"
-	ProcessorTimeName        = "processor_time_sec"
-	ExporterTimeName         = "exporter_time_sec"
-	PipelineRetryCountName   = "pipeline_retry_count"
+	BlockImportTimeName         = "import_time_sec"
+	ImportedTxnsPerBlockName    = "imported_tx_per_block"
+	ImportedRoundGaugeName      = "imported_round"
+	GetAlgodRawBlockTimeName    = "get_algod_raw_block_time_sec"
+	ImportedTxnsName            = "imported_txns"
+	ImporterTimeName            = "importer_time_sec"
+	ProcessorTimeName           = "processor_time_sec"
+	ExporterTimeName            = "exporter_time_sec"
+	PipelineRetryCountName      = "pipeline_retry_count"
+	PipelineInfoName            = "pipeline_info"
+	ProcessorMismatchesName     = "processor_mismatches"
+	PluginCPUSecondsName        = "plugin_cpu_sec"
+	PluginAllocBytesName        = "plugin_alloc_bytes"
+	PluginAllocObjectsName      = "plugin_alloc_objects"
+	PluginGoroutinesName        = "plugin_goroutines"
+	ExporterSchemaDriftName     = "exporter_schema_drift"
+	CoordinationClaimsName      = "coordination_claims"
+	InvalidBlocksName           = "invalid_blocks"
+	UnknownProtocolBlocksName   = "unknown_protocol_blocks"
+	UnknownTxnTypesName         = "unknown_txn_types"
+	AuthFailuresName            = "auth_failures"
+	ObserverErrorsName          = "observer_errors"
+	ObserverDroppedRoundsName   = "observer_dropped_rounds"
+	ClockSkewBlocksName         = "clock_skew_blocks"
+	ExporterVerificationsName   = "exporter_verifications"
+	ConfigDriftName             = "config_drift"
+	SLORoundsName               = "slo_rounds"
+	SLOBurnRateName             = "slo_burn_rate"
+	SLOBudgetRemainingName      = "slo_budget_remaining"
+	PayloadValidationsName      = "payload_validations"
+	PriorityLaneTxnsName        = "priority_lane_txns"
+	PluginBytesInName           = "plugin_bytes_in"
+	PluginBytesOutName          = "plugin_bytes_out"
+	ExporterDuplicateRoundsName = "exporter_duplicate_rounds"
+	WatchdogStallsName          = "watchdog_stalls"
+	WatchdogRestartBudgetName   = "watchdog_restart_budget"
 )
 
 // AllMetricNames is a reference for all the custom metric names.
@@ -126,18 +420,72 @@ var AllMetricNames = []string{
 	ProcessorTimeName,
 	ExporterTimeName,
 	PipelineRetryCountName,
+	PipelineInfoName,
+	ProcessorMismatchesName,
+	PluginCPUSecondsName,
+	PluginAllocBytesName,
+	PluginAllocObjectsName,
+	PluginGoroutinesName,
+	ExporterSchemaDriftName,
+	CoordinationClaimsName,
+	InvalidBlocksName,
+	UnknownProtocolBlocksName,
+	UnknownTxnTypesName,
+	AuthFailuresName,
+	ObserverErrorsName,
+	ObserverDroppedRoundsName,
+	ClockSkewBlocksName,
+	ExporterVerificationsName,
+	ConfigDriftName,
+	SLORoundsName,
+	SLOBurnRateName,
+	SLOBudgetRemainingName,
+	PayloadValidationsName,
+	PriorityLaneTxnsName,
+	PluginBytesInName,
+	PluginBytesOutName,
+	ExporterDuplicateRoundsName,
+	WatchdogStallsName,
+	WatchdogRestartBudgetName,
 }
 
 // Initialize the prometheus objects.
 var (
 	// used by pipeline
 
-	BlockImportTimeSeconds prometheus.Summary
-	ImportedTxnsPerBlock   prometheus.Summary
-	ImportedTxns           *prometheus.GaugeVec
-	ImportedRoundGauge     prometheus.Gauge
-	ImporterTimeSeconds    prometheus.Summary
-	ProcessorTimeSeconds   *prometheus.SummaryVec
-	ExporterTimeSeconds    prometheus.Summary
-	PipelineRetryCount     prometheus.Histogram
+	BlockImportTimeSeconds  Latency
+	ImportedTxnsPerBlock    prometheus.Summary
+	ImportedTxns            *prometheus.GaugeVec
+	ImportedRoundGauge      prometheus.Gauge
+	ImporterTimeSeconds     Latency
+	ProcessorTimeSeconds    LatencyVec
+	ExporterTimeSeconds     Latency
+	PipelineRetryCount      prometheus.Histogram
+	PipelineInfo            *prometheus.GaugeVec
+	ProcessorMismatches     *prometheus.CounterVec
+	PluginCPUSeconds        *prometheus.CounterVec
+	PluginAllocBytes        *prometheus.CounterVec
+	PluginAllocObjects      *prometheus.CounterVec
+	PluginGoroutines        *prometheus.GaugeVec
+	ExporterSchemaDrift     *prometheus.GaugeVec
+	CoordinationClaims      *prometheus.CounterVec
+	InvalidBlocks           *prometheus.CounterVec
+	UnknownProtocolBlocks   *prometheus.CounterVec
+	UnknownTxnTypes         *prometheus.CounterVec
+	AuthFailures            *prometheus.CounterVec
+	ObserverErrors          *prometheus.CounterVec
+	ObserverDroppedRounds   *prometheus.CounterVec
+	ClockSkewBlocks         *prometheus.CounterVec
+	ExporterVerifications   *prometheus.CounterVec
+	ConfigDrift             prometheus.Gauge
+	SLORounds               *prometheus.CounterVec
+	SLOBurnRate             prometheus.Gauge
+	SLOBudgetRemaining      prometheus.Gauge
+	PayloadValidations      *prometheus.CounterVec
+	PriorityLaneTxns        *prometheus.CounterVec
+	PluginBytesIn           *prometheus.CounterVec
+	PluginBytesOut          *prometheus.CounterVec
+	ExporterDuplicateRounds *prometheus.CounterVec
+	WatchdogStalls          *prometheus.CounterVec
+	WatchdogRestartBudget   prometheus.Gauge
 )
@@ -0,0 +1,136 @@
+package pipeline
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Operation is a plugin call in progress.
+type Operation struct {
+	// Stage is importer, processor or exporter.
+	Stage  string `json:"stage"`
+	Plugin string `json:"plugin"`
+	// Round is the round being imported, processed or exported, the first
+	// round of a batch.
+	Round   uint64        `json:"round"`
+	Started time.Time     `json:"started"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// Buffer is the occupancy of a buffer between the stages.
+type Buffer struct {
+	Name     string `json:"name"`
+	Used     int    `json:"used"`
+	Capacity int    `json:"capacity"`
+}
+
+// Activity is a live view of the rounds in flight in each stage.
+type Activity struct {
+	Time      time.Time `json:"time"`
+	NextRound uint64    `json:"next-round"`
+	// Operations are ordered by stage, then round.
+	Operations []Operation `json:"operations"`
+	Buffers    []Buffer    `json:"buffers"`
+	// Slowest is the operation which has been running the longest.
+	Slowest *Operation `json:"slowest,omitempty"`
+}
+
+// activityTracker records the plugin calls in progress. The zero value is
+// ready to use, and a nil *activityTracker records nothing.
+type activityTracker struct {
+	mu         sync.Mutex
+	nextID     uint64
+	operations map[uint64]Operation
+	prefetch   *prefetcher
+	batch      Buffer
+}
+
+// start records a plugin call and returns its ID for done.
+func (t *activityTracker) start(stage, plugin string, round uint64) uint64 {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.operations == nil {
+		t.operations = make(map[uint64]Operation)
+	}
+	t.nextID++
+	t.operations[t.nextID] = Operation{Stage: stage, Plugin: plugin, Round: round, Started: time.Now()}
+	return t.nextID
+}
+
+// done removes a plugin call recorded by start.
+func (t *activityTracker) done(id uint64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.operations, id)
+}
+
+// setPrefetcher sets the prefetcher whose buffer is reported.
+func (t *activityTracker) setPrefetcher(prefetch *prefetcher) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prefetch = prefetch
+}
+
+// setBatch sets the number of rounds waiting for the batch exporters.
+func (t *activityTracker) setBatch(rounds, size uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.batch = Buffer{Name: "batch", Used: int(rounds), Capacity: int(size)}
+}
+
+var stageOrder = map[string]int{importerType: 0, processorType: 1, exporterType: 2}
+
+// snapshot returns the operations and buffers at now.
+func (t *activityTracker) snapshot(now time.Time) Activity {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	activity := Activity{Time: now, Operations: make([]Operation, 0, len(t.operations))}
+	for _, op := range t.operations {
+		op.Elapsed = now.Sub(op.Started)
+		activity.Operations = append(activity.Operations, op)
+	}
+	sort.Slice(activity.Operations, func(i, j int) bool {
+		a, b := activity.Operations[i], activity.Operations[j]
+		if a.Stage != b.Stage {
+			return stageOrder[a.Stage] < stageOrder[b.Stage]
+		}
+		if a.Round != b.Round {
+			return a.Round < b.Round
+		}
+		return a.Plugin < b.Plugin
+	})
+	for idx, op := range activity.Operations {
+		if activity.Slowest == nil || op.Elapsed > activity.Slowest.Elapsed {
+			activity.Slowest = &activity.Operations[idx]
+		}
+	}
+	if t.prefetch != nil {
+		activity.Buffers = append(activity.Buffers, Buffer{Name: "prefetch", Used: len(t.prefetch.results), Capacity: cap(t.prefetch.results)})
+	}
+	if t.batch.Capacity > 0 {
+		activity.Buffers = append(activity.Buffers, t.batch)
+	}
+	return activity
+}
+
+// Activity returns a live view of the plugin calls in progress and of the
+// buffers between the stages.
+func (p *pipelineImpl) Activity() Activity {
+	activity := p.activity.snapshot(time.Now())
+	p.mu.RLock()
+	activity.NextRound = p.status.NextRound
+	p.mu.RUnlock()
+	return activity
+}
+
+func (p *pipelineImpl) handleActivity(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, p.Activity())
+}
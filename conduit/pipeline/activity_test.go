@@ -0,0 +1,62 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityTracker(t *testing.T) {
+	var nilTracker *activityTracker
+	nilTracker.done(nilTracker.start(importerType, "algod", 1))
+
+	var tracker activityTracker
+	export := tracker.start(exporterType, "postgresql", 10)
+	time.Sleep(time.Millisecond)
+	tracker.start(importerType, "algod", 12)
+	tracker.start(importerType, "algod", 11)
+	tracker.start(processorType, "filter_processor", 10)
+	done := tracker.start(importerType, "algod", 13)
+	tracker.done(done)
+	tracker.setBatch(3, 10)
+
+	activity := tracker.snapshot(time.Now())
+	require.Len(t, activity.Operations, 4)
+	var order []string
+	for _, op := range activity.Operations {
+		order = append(order, op.Stage+" "+op.Plugin)
+	}
+	assert.Equal(t, []string{"importer algod", "importer algod", "processor filter_processor", "exporter postgresql"}, order)
+	assert.Equal(t, uint64(11), activity.Operations[0].Round)
+	require.NotNil(t, activity.Slowest)
+	assert.Equal(t, "postgresql", activity.Slowest.Plugin)
+	assert.Equal(t, []Buffer{{Name: "batch", Used: 3, Capacity: 10}}, activity.Buffers)
+
+	tracker.done(export)
+	assert.Len(t, tracker.snapshot(time.Now()).Operations, 3)
+}
+
+func TestActivityEndpoint(t *testing.T) {
+	p := &pipelineImpl{cfg: &Config{}}
+	p.status.NextRound = 42
+	p.activity.setPrefetcher(&prefetcher{results: make(chan fetchResult, 4)})
+	p.activity.start(importerType, "algod", 42)
+
+	mux := http.NewServeMux()
+	p.registerAPIHandlers(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/activity", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var activity Activity
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &activity))
+	assert.Equal(t, uint64(42), activity.NextRound)
+	require.Len(t, activity.Operations, 1)
+	assert.Equal(t, "algod", activity.Operations[0].Plugin)
+	assert.Equal(t, []Buffer{{Name: "prefetch", Used: 0, Capacity: 4}}, activity.Buffers)
+}
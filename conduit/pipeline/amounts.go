@@ -0,0 +1,47 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+)
+
+// Amounts configs how exporters which write text formats represent amounts.
+type Amounts struct {
+	// Encoding is "integer" (default), "string" or "decimal".
+	Encoding conduit.AmountEncoding `yaml:"encoding"`
+	// AssetDecimals are the decimals of assets for the decimal encoding, by
+	// asset ID. Assets created in the exported rounds are added to them.
+	AssetDecimals map[uint64]uint32 `yaml:"asset-decimals"`
+}
+
+// Valid validates the amounts config.
+func (a Amounts) Valid() error {
+	if err := a.Encoding.Valid(); err != nil {
+		return err
+	}
+	for id, decimals := range a.AssetDecimals {
+		if decimals > conduit.MaxAssetDecimals {
+			return fmt.Errorf("asset-decimals of asset %d must be at most %d, found %d", id, conduit.MaxAssetDecimals, decimals)
+		}
+	}
+	return nil
+}
+
+// setAmountFormat passes the amount format to the exporter if it writes a
+// text format. The exporters share the format, so that the decimals learned
+// by one are used by the others.
+func (p *pipelineImpl) setAmountFormat(exporter exporters.Exporter) {
+	if p.cfg.Amounts.Encoding == "" || p.cfg.Amounts.Encoding == conduit.AmountInteger {
+		return
+	}
+	if p.amountFormat == nil {
+		p.amountFormat = conduit.MakeAmountFormat(p.cfg.Amounts.Encoding, p.cfg.Amounts.AssetDecimals)
+	}
+	if e, ok := exporter.(conduit.AmountEncoder); ok {
+		e.SetAmountFormat(p.amountFormat)
+	} else {
+		p.logger.Warnf("Exporter (%s) does not support the amounts encoding", exporter.Metadata().Name)
+	}
+}
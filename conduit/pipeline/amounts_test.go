@@ -0,0 +1,50 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+// amountExporter records the amount format it is given.
+type amountExporter struct {
+	roundExporter
+	format *conduit.AmountFormat
+}
+
+func (e *amountExporter) SetAmountFormat(format *conduit.AmountFormat) {
+	e.format = format
+}
+
+func TestAmountsValid(t *testing.T) {
+	assert.NoError(t, Amounts{}.Valid())
+	assert.NoError(t, Amounts{Encoding: conduit.AmountDecimal, AssetDecimals: map[uint64]uint32{31566704: 6}}.Valid())
+	assert.EqualError(t, Amounts{Encoding: "float"}.Valid(), "unknown amount encoding (float), expected integer, string or decimal")
+	assert.EqualError(t, Amounts{AssetDecimals: map[uint64]uint32{5: 20}}.Valid(), "asset-decimals of asset 5 must be at most 19, found 20")
+}
+
+func TestSetAmountFormat(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	p := &pipelineImpl{cfg: &Config{Amounts: Amounts{Encoding: conduit.AmountInteger}}, logger: logger}
+	exp := &amountExporter{roundExporter: roundExporter{name: "json"}}
+	p.setAmountFormat(exp)
+	assert.Nil(t, exp.format)
+
+	// The exporters share the format.
+	p.cfg.Amounts = Amounts{Encoding: conduit.AmountDecimal, AssetDecimals: map[uint64]uint32{5: 2}}
+	other := &amountExporter{roundExporter: roundExporter{name: "other"}}
+	p.setAmountFormat(exp)
+	p.setAmountFormat(other)
+	assert.Same(t, exp.format, other.format)
+	assert.Equal(t, conduit.AmountDecimal, exp.format.Encoding())
+	decimals, ok := exp.format.AssetDecimals(5)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(2), decimals)
+	assert.Empty(t, hook.AllEntries())
+
+	p.setAmountFormat(&roundExporter{name: "binary"})
+	assert.Equal(t, "Exporter (binary) does not support the amounts encoding", hook.LastEntry().Message)
+}
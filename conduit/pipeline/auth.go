@@ -0,0 +1,50 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/metrics"
+	"github.com/algorand/conduit/conduit/plugins"
+)
+
+const (
+	// authFailureRetry retries the round like any other error.
+	authFailureRetry = "retry"
+	// authFailureHalt stops the pipeline instead of retrying with rejected
+	// credentials.
+	authFailureHalt = "halt"
+)
+
+func validAuthFailure(policy string) error {
+	switch policy {
+	case "", authFailureRetry, authFailureHalt:
+		return nil
+	default:
+		return fmt.Errorf("auth-failure must be '%s' or '%s', found '%s'", authFailureRetry, authFailureHalt, policy)
+	}
+}
+
+// onAuthFailure reports err if it is a *conduit.AuthError of the plugin, it
+// returns true if the pipeline stops because auth-failure is halt. The error
+// must already be set with setError.
+func (p *pipelineImpl) onAuthFailure(pluginType plugins.PluginType, plugin conduit.PluginMetadata, err error) bool {
+	var authErr *conduit.AuthError
+	if !errors.As(err, &authErr) {
+		return false
+	}
+	name := plugin.Metadata().Name
+	metrics.AuthFailures.WithLabelValues(string(pluginType), name).Inc()
+	p.mu.Lock()
+	p.authFailure = fmt.Sprintf("%s (%s)", pluginType, name)
+	p.mu.Unlock()
+	logger := p.logger.WithField("alert", "auth-failure")
+	if p.cfg.AuthFailure == authFailureHalt {
+		logger.Errorf("%s (%s) credentials were rejected, replace them and restart conduit - stopping...", pluginType, name)
+		p.writeSupportBundle(err.Error())
+		return true
+	}
+	logger.Errorf("%s (%s) credentials were rejected, the round is retried until they are replaced", pluginType, name)
+	return false
+}
@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// authImporter fails round 2 with rejected credentials the first time, or
+// every time if alwaysFail is set.
+type authImporter struct {
+	namedImporter
+	alwaysFail bool
+	failed     bool
+}
+
+func (r *authImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	if rnd == 2 && (!r.failed || r.alwaysFail) {
+		r.failed = true
+		return data.BlockData{}, fmt.Errorf("GetBlock(): %w", conduit.MakeAuthError(fmt.Errorf("HTTP 401: Invalid API Token")))
+	}
+	return r.namedImporter.GetBlock(rnd)
+}
+
+func TestValidAuthFailure(t *testing.T) {
+	for _, policy := range []string{"", authFailureRetry, authFailureHalt} {
+		assert.NoError(t, validAuthFailure(policy))
+	}
+	assert.EqualError(t, validAuthFailure("skip"), "auth-failure must be 'retry' or 'halt', found 'skip'")
+}
+
+func TestOnAuthFailure(t *testing.T) {
+	metrics.RegisterPrometheusMetrics("auth_test")
+	logger, hook := test.NewNullLogger()
+	p := &pipelineImpl{cfg: &Config{}, logger: logger}
+	exp := &roundExporter{name: "kafka"}
+
+	err := fmt.Errorf("import failed")
+	p.setError(err)
+	assert.False(t, p.onAuthFailure(plugins.Importer, &namedImporter{}, err))
+	assert.Empty(t, hook.AllEntries())
+	assert.Empty(t, p.Status().AuthFailure)
+
+	err = fmt.Errorf("Receive(): %w", conduit.MakeAuthError(fmt.Errorf("kafka error 58 (SASL_AUTHENTICATION_FAILED)")))
+	p.setError(err)
+	assert.False(t, p.onAuthFailure(plugins.Exporter, exp, err))
+	assert.Equal(t, "auth-failure", hook.LastEntry().Data["alert"])
+	assert.Equal(t, "exporter (kafka)", p.Status().AuthFailure)
+	assert.Equal(t, "Receive(): credentials rejected: kafka error 58 (SASL_AUTHENTICATION_FAILED)", p.Status().LastError)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.AuthFailures.WithLabelValues("exporter", "kafka")))
+
+	p.cfg.AuthFailure = authFailureHalt
+	assert.True(t, p.onAuthFailure(plugins.Exporter, exp, err))
+
+	// The auth failure is cleared with the error.
+	p.setError(nil)
+	assert.Empty(t, p.Status().AuthFailure)
+}
+
+func TestPipelineAuthFailure(t *testing.T) {
+	metrics.RegisterPrometheusMetrics("auth_pipeline_test")
+	tests := []struct {
+		policy      string
+		received    []uint64
+		authFailure string
+	}{
+		{policy: authFailureRetry, received: []uint64{0, 1, 2, 3}},
+		{policy: authFailureHalt, received: []uint64{0, 1}, authFailure: "importer (test_importer)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.policy, func(t *testing.T) {
+			exp := &roundExporter{name: "exporter"}
+			pImpl := makeCheckpointPipeline(t, exp)
+			var pImporter importers.Importer = &authImporter{namedImporter: namedImporter{roundImporter{failRound: 1000}}}
+			pImpl.importer = &pImporter
+			pImpl.cfg.AuthFailure = tc.policy
+			pImpl.cfg.Rounds.End = 3
+
+			pImpl.Start()
+			pImpl.Wait()
+			assert.Equal(t, tc.received, exp.received())
+			assert.Equal(t, tc.authFailure, pImpl.Status().AuthFailure)
+			if tc.policy == authFailureHalt {
+				var authErr *conduit.AuthError
+				require.ErrorAs(t, pImpl.Error(), &authErr)
+			}
+		})
+	}
+}
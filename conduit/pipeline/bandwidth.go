@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+// bytesPerGB is the unit of the cost estimate, clouds bill egress by decimal GB.
+const bytesPerGB = 1e9
+
+// Bandwidth configs the accounting of the network traffic of the plugins
+// which implement conduit.TrafficReporter.
+type Bandwidth struct {
+	// LogInterval is how often the traffic of each plugin and destination is
+	// logged with its estimated cost, zero disables the log. The traffic is
+	// always counted by the plugin_bytes_in and plugin_bytes_out metrics.
+	LogInterval time.Duration `yaml:"log-interval"`
+	// CostPerGB is the price of a GB sent, used to estimate the egress cost.
+	CostPerGB float64 `yaml:"cost-per-gb"`
+}
+
+// Valid validates the bandwidth config.
+func (b Bandwidth) Valid() error {
+	if b.LogInterval < 0 {
+		return fmt.Errorf("log-interval must not be negative (%s)", b.LogInterval)
+	}
+	if b.CostPerGB < 0 {
+		return fmt.Errorf("cost-per-gb must not be negative (%v)", b.CostPerGB)
+	}
+	return nil
+}
+
+// trafficKey identifies the traffic of a plugin with a destination.
+type trafficKey struct {
+	pluginType  string
+	pluginName  string
+	destination string
+}
+
+// trafficEntry is the traffic of a plugin with a destination.
+type trafficEntry struct {
+	trafficKey
+	in  uint64
+	out uint64
+}
+
+// trafficAccount sums the traffic of the plugins between two reports.
+type trafficAccount struct {
+	mu      sync.Mutex
+	entries map[trafficKey]*trafficEntry
+}
+
+func makeTrafficAccount() *trafficAccount {
+	return &trafficAccount{entries: make(map[trafficKey]*trafficEntry)}
+}
+
+func (a *trafficAccount) add(key trafficKey, in, out uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry, ok := a.entries[key]
+	if !ok {
+		entry = &trafficEntry{trafficKey: key}
+		a.entries[key] = entry
+	}
+	entry.in += in
+	entry.out += out
+}
+
+// take returns the traffic since the last call, sorted by plugin and
+// destination.
+func (a *trafficAccount) take() []trafficEntry {
+	a.mu.Lock()
+	entries := make([]trafficEntry, 0, len(a.entries))
+	for _, entry := range a.entries {
+		entries = append(entries, *entry)
+	}
+	a.entries = make(map[trafficKey]*trafficEntry)
+	a.mu.Unlock()
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].pluginType != entries[j].pluginType {
+			return entries[i].pluginType < entries[j].pluginType
+		}
+		if entries[i].pluginName != entries[j].pluginName {
+			return entries[i].pluginName < entries[j].pluginName
+		}
+		return entries[i].destination < entries[j].destination
+	})
+	return entries
+}
+
+// pluginMeter implements conduit.TrafficMeter for a plugin.
+type pluginMeter struct {
+	pluginType string
+	pluginName string
+	account    *trafficAccount
+}
+
+// Add counts the traffic in the metrics and in the account of the next report.
+func (m *pluginMeter) Add(destination string, in, out uint64) {
+	if in > 0 {
+		metrics.PluginBytesIn.WithLabelValues(m.pluginType, m.pluginName, destination).Add(float64(in))
+	}
+	if out > 0 {
+		metrics.PluginBytesOut.WithLabelValues(m.pluginType, m.pluginName, destination).Add(float64(out))
+	}
+	if m.account != nil {
+		m.account.add(trafficKey{pluginType: m.pluginType, pluginName: m.pluginName, destination: destination}, in, out)
+	}
+}
+
+// setTrafficMeter passes a meter to the plugin if it reports its traffic. It
+// is called before the plugin is initialized.
+func (p *pipelineImpl) setTrafficMeter(pluginType, pluginName string, plugin interface{}) {
+	if r, ok := plugin.(conduit.TrafficReporter); ok {
+		r.SetTrafficMeter(&pluginMeter{pluginType: pluginType, pluginName: pluginName, account: p.traffic})
+	}
+}
+
+// reportTraffic logs the traffic of the plugins every interval until the
+// pipeline stops.
+func (p *pipelineImpl) reportTraffic(interval time.Duration, loopDone <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-loopDone:
+			return
+		case <-ticker.C:
+			p.logTraffic(interval, p.traffic.take())
+		}
+	}
+}
+
+// logTraffic logs the traffic of each plugin and destination, with the
+// egress cost estimated from cost-per-gb.
+func (p *pipelineImpl) logTraffic(interval time.Duration, entries []trafficEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	costPerGB := p.cfg.Bandwidth.CostPerGB
+	var totalIn, totalOut uint64
+	for _, entry := range entries {
+		totalIn += entry.in
+		totalOut += entry.out
+		p.logger.Infof("Traffic of %s (%s) with %s in the last %s: %.3f GB received, %.3f GB sent, estimated egress cost %.2f",
+			entry.pluginType, entry.pluginName, entry.destination, interval,
+			float64(entry.in)/bytesPerGB, float64(entry.out)/bytesPerGB, float64(entry.out)/bytesPerGB*costPerGB)
+	}
+	p.logger.Infof("Traffic of the pipeline in the last %s: %.3f GB received, %.3f GB sent, estimated egress cost %.2f, about %.2f per day",
+		interval, float64(totalIn)/bytesPerGB, float64(totalOut)/bytesPerGB, float64(totalOut)/bytesPerGB*costPerGB,
+		float64(totalOut)/bytesPerGB*costPerGB*float64(24*time.Hour)/float64(interval))
+}
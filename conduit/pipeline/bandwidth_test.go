@@ -0,0 +1,80 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+func TestBandwidthValid(t *testing.T) {
+	assert.NoError(t, Bandwidth{}.Valid())
+	assert.NoError(t, Bandwidth{LogInterval: time.Hour, CostPerGB: 0.09}.Valid())
+	assert.EqualError(t, Bandwidth{LogInterval: -time.Second}.Valid(), "log-interval must not be negative (-1s)")
+	assert.EqualError(t, Bandwidth{CostPerGB: -1}.Valid(), "cost-per-gb must not be negative (-1)")
+}
+
+func TestPluginMeter(t *testing.T) {
+	metrics.RegisterPrometheusMetrics("bandwidth_test")
+	account := makeTrafficAccount()
+	kafka := &pluginMeter{pluginType: exporterType, pluginName: "kafka", account: account}
+	algod := &pluginMeter{pluginType: importerType, pluginName: "algod", account: account}
+
+	kafka.Add("broker-1:9092", 10, 1000)
+	kafka.Add("broker-1:9092", 5, 500)
+	kafka.Add("broker-2:9092", 0, 200)
+	algod.Add("node:8080", 3000, 0)
+
+	assert.Equal(t, float64(1500), testutil.ToFloat64(metrics.PluginBytesOut.WithLabelValues(exporterType, "kafka", "broker-1:9092")))
+	assert.Equal(t, float64(15), testutil.ToFloat64(metrics.PluginBytesIn.WithLabelValues(exporterType, "kafka", "broker-1:9092")))
+	assert.Equal(t, float64(3000), testutil.ToFloat64(metrics.PluginBytesIn.WithLabelValues(importerType, "algod", "node:8080")))
+
+	entries := account.take()
+	assert.Equal(t, []trafficEntry{
+		{trafficKey: trafficKey{exporterType, "kafka", "broker-1:9092"}, in: 15, out: 1500},
+		{trafficKey: trafficKey{exporterType, "kafka", "broker-2:9092"}, out: 200},
+		{trafficKey: trafficKey{importerType, "algod", "node:8080"}, in: 3000},
+	}, entries)
+	// the account restarts after each report.
+	assert.Empty(t, account.take())
+
+	// the metrics are counted without an account.
+	(&pluginMeter{pluginType: exporterType, pluginName: "kafka"}).Add("broker-2:9092", 0, 100)
+	assert.Equal(t, float64(300), testutil.ToFloat64(metrics.PluginBytesOut.WithLabelValues(exporterType, "kafka", "broker-2:9092")))
+}
+
+// meteredExporter implements conduit.TrafficReporter.
+type meteredExporter struct {
+	mockExporter
+	meter conduit.TrafficMeter
+}
+
+func (m *meteredExporter) SetTrafficMeter(meter conduit.TrafficMeter) {
+	m.meter = meter
+}
+
+func TestLogTraffic(t *testing.T) {
+	l, hook := test.NewNullLogger()
+	p := pipelineImpl{cfg: &Config{Bandwidth: Bandwidth{LogInterval: time.Hour, CostPerGB: 0.1}}, logger: l, traffic: makeTrafficAccount()}
+
+	exp := &meteredExporter{}
+	p.setTrafficMeter(exporterType, "kafka", exp)
+	require.NotNil(t, exp.meter)
+	p.setTrafficMeter(exporterType, "noop", &mockExporter{})
+
+	p.logTraffic(time.Hour, p.traffic.take())
+	assert.Empty(t, hook.AllEntries())
+
+	exp.meter.Add("broker:9092", 1e9, 5e9)
+	p.logTraffic(time.Hour, p.traffic.take())
+	entries := hook.AllEntries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "Traffic of exporter (kafka) with broker:9092 in the last 1h0m0s: 1.000 GB received, 5.000 GB sent, estimated egress cost 0.50", entries[0].Message)
+	assert.Equal(t, "Traffic of the pipeline in the last 1h0m0s: 1.000 GB received, 5.000 GB sent, estimated egress cost 0.50, about 12.00 per day", entries[1].Message)
+}
@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+)
+
+// Batch configs for exporters which implement exporters.BatchExporter.
+type Batch struct {
+	// Size is the number of rounds sent to each batch exporter at once.
+	// Batching is disabled when it is 0 or 1.
+	Size uint64 `yaml:"size"`
+	// MaxDelay flushes a smaller batch once its first round has waited this
+	// long. Zero only flushes full batches.
+	MaxDelay time.Duration `yaml:"max-delay"`
+}
+
+// Valid validates the batch config.
+func (b Batch) Valid() error {
+	if b.MaxDelay < 0 {
+		return fmt.Errorf("max-delay must not be negative (%s)", b.MaxDelay)
+	}
+	return nil
+}
+
+func (b Batch) enabled() bool {
+	return b.Size > 1
+}
+
+// batchState holds the rounds which have been processed but not flushed to
+// the batch exporters.
+type batchState struct {
+	cfg Batch
+	// exporters are the batch exporters by exporter index, nil for the others.
+	exporters []exporters.BatchExporter
+	// blocks are the blocks each batch exporter has not received yet.
+	blocks [][]data.BlockData
+	// first is the first round of the batch, the pipeline resumes from it.
+	first   uint64
+	rounds  uint64
+	started time.Time
+}
+
+// makeBatch returns the batch state, or nil if batching is disabled or no
+// exporter supports it.
+func (p *pipelineImpl) makeBatch() *batchState {
+	if !p.cfg.Batch.enabled() {
+		return nil
+	}
+	b := &batchState{
+		cfg:       p.cfg.Batch,
+		exporters: make([]exporters.BatchExporter, len(p.exporters)),
+		blocks:    make([][]data.BlockData, len(p.exporters)),
+	}
+	found := false
+	for idx, exporter := range p.exporters {
+		// The exporters of priority lanes receive each round as it is processed.
+		if be, ok := (*exporter).(exporters.BatchExporter); ok && !p.isPriority(idx) {
+			b.exporters[idx] = be
+			found = true
+		}
+	}
+	if !found {
+		p.logger.Warnf("batch size is configured but no exporter supports batches")
+		return nil
+	}
+	return b
+}
+
+// add buffers the block if the exporter at idx receives batches.
+func (b *batchState) add(idx int, blk data.BlockData) bool {
+	if b == nil || b.exporters[idx] == nil {
+		return false
+	}
+	b.blocks[idx] = append(b.blocks[idx], blk)
+	return true
+}
+
+// roundDone counts an exported round.
+func (b *batchState) roundDone(round uint64, now time.Time) {
+	if b == nil {
+		return
+	}
+	if b.rounds == 0 {
+		b.first = round
+		b.started = now
+	}
+	b.rounds++
+}
+
+// pending reports whether rounds are waiting to be flushed.
+func (b *batchState) pending() bool {
+	return b != nil && b.rounds > 0
+}
+
+// due reports whether the batch is full or has waited MaxDelay.
+func (b *batchState) due(now time.Time) bool {
+	if !b.pending() {
+		return false
+	}
+	return b.rounds >= b.cfg.Size || (b.cfg.MaxDelay > 0 && now.Sub(b.started) >= b.cfg.MaxDelay)
+}
+
+// batchError identifies the exporter whose batch failed.
+type batchError struct {
+	idx int
+	err error
+}
+
+func (e batchError) Error() string {
+	return e.err.Error()
+}
+
+func (e batchError) Unwrap() error {
+	return e.err
+}
+
+// flushBatch sends the pending rounds to the batch exporters and saves the
+// pipeline metadata, an error saving it is only logged like after a round.
+// After a failure only the exporters which did not receive their batch are
+// retried.
+func (p *pipelineImpl) flushBatch() error {
+	b := p.batch
+	if !b.pending() {
+		return nil
+	}
+	start := time.Now()
+	for idx, exporter := range b.exporters {
+		if exporter == nil || len(b.blocks[idx]) == 0 {
+			continue
+		}
+		var err error
+		name := p.exporterName(idx)
+		op := p.activity.start(exporterType, name, b.first)
+		p.telemetry.exporter(idx, func() {
+			err = exporter.ReceiveBatch(b.blocks[idx])
+		})
+		p.activity.done(op)
+		if err != nil && p.duplicateRound(name, err) {
+			err = nil
+		}
+		if err != nil && p.isBestEffort(idx) {
+			p.logger.Warnf("best-effort exporter (%s) skipped rounds %d to %d: %v", name, b.first, b.first+b.rounds-1, err)
+		} else if err != nil {
+			return batchError{idx: idx, err: fmt.Errorf("flushBatch(): exporter (%s) could not receive rounds %d to %d: %w", name, b.first, b.first+b.rounds-1, err)}
+		}
+		b.blocks[idx] = nil
+	}
+	p.logger.Infof("rounds %d to %d flushed in %s", b.first, b.first+b.rounds-1, time.Since(start))
+	b.rounds = 0
+	p.activity.setBatch(0, b.cfg.Size)
+	if err := p.saveMetadata(); err != nil {
+		p.logger.Errorf("%v", err)
+	}
+	return nil
+}
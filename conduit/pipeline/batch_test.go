@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/data"
+)
+
+// batchExporter implements exporters.BatchExporter, it records the rounds of
+// each batch and fails failCount batches.
+type batchExporter struct {
+	roundExporter
+	batches   [][]uint64
+	failCount int
+}
+
+func (b *batchExporter) ReceiveBatch(exportData []data.BlockData) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failCount > 0 {
+		b.failCount--
+		return fmt.Errorf("batch")
+	}
+	var rounds []uint64
+	for _, blk := range exportData {
+		rounds = append(rounds, blk.Round())
+	}
+	b.batches = append(b.batches, rounds)
+	return nil
+}
+
+func (b *batchExporter) received() [][]uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([][]uint64(nil), b.batches...)
+}
+
+func TestBatchValid(t *testing.T) {
+	assert.NoError(t, Batch{Size: 10, MaxDelay: time.Second}.Valid())
+	assert.EqualError(t, Batch{MaxDelay: -time.Second}.Valid(), "max-delay must not be negative (-1s)")
+}
+
+// TestPipelineBatch tests that batch exporters receive the rounds in batches
+// while the other exporters receive each round.
+func TestPipelineBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		batch     Batch
+		failCount int
+		expected  [][]uint64
+	}{
+		{
+			name:     "full batches",
+			batch:    Batch{Size: 3},
+			expected: [][]uint64{{0, 1, 2}, {3, 4, 5}, {6, 7}},
+		},
+		{
+			name:      "retry",
+			batch:     Batch{Size: 3},
+			failCount: 2,
+			expected:  [][]uint64{{0, 1, 2}, {3, 4, 5}, {6, 7}},
+		},
+		{
+			name:     "max delay",
+			batch:    Batch{Size: 100, MaxDelay: time.Nanosecond},
+			expected: [][]uint64{{0}, {1}, {2}, {3}, {4}, {5}, {6}, {7}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			batchExp := &batchExporter{roundExporter: roundExporter{name: "batch"}, failCount: tc.failCount}
+			plain := &roundExporter{name: "plain"}
+			pImpl := makeReloadPipeline(t, batchExp, plain)
+			pImpl.cfg.Batch = tc.batch
+			pImpl.cfg.Rounds.End = 7
+
+			pImpl.Start()
+			pImpl.Wait()
+			require.NoError(t, pImpl.Error())
+
+			assert.Equal(t, tc.expected, batchExp.received())
+			assert.Empty(t, batchExp.roundExporter.received())
+			assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5, 6, 7}, plain.received())
+			assert.Equal(t, uint64(8), readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir).NextRound)
+		})
+	}
+}
+
+// TestPipelineBatchCommit tests that the next round is only saved once the
+// batch is flushed.
+func TestPipelineBatchCommit(t *testing.T) {
+	batchExp := &batchExporter{roundExporter: roundExporter{name: "batch"}}
+	pImpl := makeCheckpointPipeline(t, batchExp)
+	pImpl.cfg.Batch = Batch{Size: 1000}
+	dataDir := pImpl.cfg.ConduitArgs.ConduitDataDir
+
+	pImpl.Start()
+	require.Eventually(t, func() bool { return pImpl.Status().NextRound >= 5 }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, uint64(0), readState(t, dataDir).NextRound)
+	assert.Empty(t, batchExp.received())
+
+	round, err := pImpl.Checkpoint()
+	require.NoError(t, err)
+	assert.Equal(t, round, readState(t, dataDir).NextRound)
+	batches := batchExp.received()
+	require.Len(t, batches, 1)
+	assert.Equal(t, round, uint64(len(batches[0])))
+
+	pImpl.Stop()
+	batches = batchExp.received()
+	var total int
+	for _, batch := range batches {
+		total += len(batch)
+	}
+	assert.Equal(t, readState(t, dataDir).NextRound, uint64(total))
+}
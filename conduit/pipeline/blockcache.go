@@ -0,0 +1,77 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// BlockCache configs the cache of recently imported blocks which is shared
+// with the processors implementing conduit.BlockCacheReader.
+type BlockCache struct {
+	// Rounds is the number of most recent rounds to keep, zero disables the
+	// cache.
+	Rounds int `yaml:"rounds"`
+}
+
+// Valid validates the BlockCache config.
+func (c BlockCache) Valid() error {
+	if c.Rounds < 0 {
+		return fmt.Errorf("rounds must not be negative (%d)", c.Rounds)
+	}
+	return nil
+}
+
+// blockCache is the conduit.BlockCache of the pipeline. Blocks are added by
+// the pipeline before they are processed, and processors only get copies.
+type blockCache struct {
+	mu    sync.RWMutex
+	cache *debugCache
+}
+
+// makeBlockCache returns the cache for the config, or nil if it is disabled.
+func makeBlockCache(cfg BlockCache) *blockCache {
+	if cfg.Rounds == 0 {
+		return nil
+	}
+	return &blockCache{cache: makeDebugCache(cfg.Rounds)}
+}
+
+// add caches a copy of the imported block.
+func (c *blockCache) add(blk data.BlockData) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.add(blk)
+}
+
+func (c *blockCache) Block(round uint64) (data.BlockData, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	blk, ok, err := c.cache.get(round)
+	if err != nil {
+		return blk, false, fmt.Errorf("Block(): %w", err)
+	}
+	return blk, ok, nil
+}
+
+func (c *blockCache) Rounds() []uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]uint64(nil), c.cache.rounds...)
+}
+
+// setBlockCache passes the block cache to the processor if it reads it.
+func (p *pipelineImpl) setBlockCache(processor processors.Processor) {
+	if p.blockCache == nil {
+		return
+	}
+	if r, ok := processor.(conduit.BlockCacheReader); ok {
+		r.SetBlockCache(p.blockCache)
+	}
+}
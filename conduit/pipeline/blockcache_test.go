@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+func TestBlockCache(t *testing.T) {
+	assert.Nil(t, makeBlockCache(BlockCache{}))
+
+	cache := makeBlockCache(BlockCache{Rounds: 2})
+	for rnd := 1; rnd <= 3; rnd++ {
+		cache.add(data.BlockData{BlockHeader: sdk.BlockHeader{Round: sdk.Round(rnd)}, Payset: []sdk.SignedTxnInBlock{{}}})
+	}
+	assert.Equal(t, []uint64{2, 3}, cache.Rounds())
+	_, ok, err := cache.Block(1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// the processors get copies of the cached block.
+	blk, ok, err := cache.Block(3)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, sdk.Round(3), blk.BlockHeader.Round)
+	blk.Payset = nil
+	blk, _, err = cache.Block(3)
+	require.NoError(t, err)
+	assert.Len(t, blk.Payset, 1)
+}
+
+func TestBlockCacheValid(t *testing.T) {
+	assert.NoError(t, BlockCache{Rounds: 10}.Valid())
+	assert.EqualError(t, BlockCache{Rounds: -1}.Valid(), "rounds must not be negative (-1)")
+}
+
+// cacheReaderProcessor implements conduit.BlockCacheReader.
+type cacheReaderProcessor struct {
+	mockProcessor
+	cache conduit.BlockCache
+}
+
+func (m *cacheReaderProcessor) SetBlockCache(cache conduit.BlockCache) {
+	m.cache = cache
+}
+
+// TestPipelineBlockCache tests that the cache is passed to the processors
+// which read it when it is configured.
+func TestPipelineBlockCache(t *testing.T) {
+	var pImporter importers.Importer = &mockImporter{genesis: sdk.Genesis{Network: "test"}}
+	reader := &cacheReaderProcessor{}
+	var pReader processors.Processor = reader
+	var pExporter exporters.Exporter = &mockExporter{}
+	l, _ := test.NewNullLogger()
+	pImpl := pipelineImpl{
+		cfg: &Config{
+			ConduitArgs: &conduit.Args{ConduitDataDir: t.TempDir()},
+			Processors:  []NameConfigPair{{}},
+			Exporter:    NameConfigPair{Name: "mockExporter"},
+		},
+		logger:     l,
+		importer:   &pImporter,
+		processors: []*processors.Processor{&pReader},
+		exporters:  []*exporters.Exporter{&pExporter},
+	}
+	require.NoError(t, pImpl.Init())
+	assert.Nil(t, reader.cache)
+
+	pImpl.cfg.BlockCache.Rounds = 5
+	require.NoError(t, pImpl.Init())
+	require.NotNil(t, reader.cache)
+	pImpl.blockCache.add(data.BlockData{BlockHeader: sdk.BlockHeader{Round: 7}})
+	assert.Equal(t, []uint64{7}, reader.cache.Rounds())
+}
@@ -0,0 +1,69 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+// checkpointResult is the outcome of a checkpoint, round is the next round
+// which is recovered from.
+type checkpointResult struct {
+	round uint64
+	err   error
+}
+
+// Checkpoint waits for the in-flight round, then flushes the pending batch and
+// the exporters which implement conduit.ForceCommitter and saves the pipeline
+// metadata. It returns the round which the pipeline restarts from, every
+// earlier round is durable.
+func (p *pipelineImpl) Checkpoint() (uint64, error) {
+	p.mu.RLock()
+	loopDone := p.loopDone
+	p.mu.RUnlock()
+	if loopDone == nil {
+		return 0, fmt.Errorf("Checkpoint(): pipeline is not running")
+	}
+
+	result := make(chan checkpointResult, 1)
+	select {
+	case p.checkpointCh <- result:
+	case <-loopDone:
+		return 0, fmt.Errorf("Checkpoint(): pipeline is not running")
+	}
+	res := <-result
+	return res.round, res.err
+}
+
+// checkpoint is called by the pipeline loop between rounds.
+func (p *pipelineImpl) checkpoint() checkpointResult {
+	round := p.pipelineMetadata.NextRound
+	if err := p.flushBatch(); err != nil {
+		err = fmt.Errorf("Checkpoint(): %w", err)
+		p.logger.Error(err)
+		return checkpointResult{round: round, err: err}
+	}
+	for idx, exporter := range p.exporters {
+		committer, ok := (*exporter).(conduit.ForceCommitter)
+		if !ok {
+			continue
+		}
+		if err := committer.ForceCommit(); err != nil {
+			err = fmt.Errorf("Checkpoint(): exporter (%s) could not commit: %w", p.exporterName(idx), err)
+			p.logger.Error(err)
+			return checkpointResult{round: round, err: err}
+		}
+	}
+	if err := p.saveMetadata(); err != nil {
+		err = fmt.Errorf("Checkpoint(): could not save metadata: %w", err)
+		p.logger.Error(err)
+		return checkpointResult{round: round, err: err}
+	}
+
+	p.mu.Lock()
+	p.status.LastCheckpoint = time.Now()
+	p.mu.Unlock()
+	p.logger.Infof("Checkpoint saved, the pipeline resumes from round %d", round)
+	return checkpointResult{round: round}
+}
@@ -0,0 +1,114 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// commitExporter implements conduit.ForceCommitter, it records how many rounds
+// were received at each commit.
+type commitExporter struct {
+	roundExporter
+	commits []int
+	err     error
+}
+
+func (e *commitExporter) ForceCommit() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err != nil {
+		return e.err
+	}
+	e.commits = append(e.commits, len(e.rounds))
+	return nil
+}
+
+// makeCheckpointPipeline uses a named importer since checkpoints are reported in the status.
+func makeCheckpointPipeline(t *testing.T, exps ...exporters.Exporter) *pipelineImpl {
+	pImpl := makeReloadPipeline(t, exps...)
+	var pImporter importers.Importer = &namedImporter{roundImporter{failRound: 1000}}
+	pImpl.importer = &pImporter
+	return pImpl
+}
+
+// TestPipelineCheckpoint tests that a checkpoint commits the exporters between
+// rounds and saves the round which the pipeline resumes from.
+func TestPipelineCheckpoint(t *testing.T) {
+	exp := &commitExporter{roundExporter: roundExporter{name: "committer"}}
+	pImpl := makeCheckpointPipeline(t, exp, &roundExporter{name: "plain"})
+
+	_, err := pImpl.Checkpoint()
+	assert.EqualError(t, err, "Checkpoint(): pipeline is not running")
+
+	pImpl.Start()
+	require.Eventually(t, func() bool { return len(exp.received()) >= 3 }, 5*time.Second, time.Millisecond)
+	round, err := pImpl.Checkpoint()
+	require.NoError(t, err)
+
+	exp.mu.Lock()
+	require.Equal(t, []int{int(round)}, exp.commits)
+	exp.mu.Unlock()
+	assert.False(t, pImpl.Status().LastCheckpoint.IsZero())
+	assert.GreaterOrEqual(t, readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir).NextRound, round)
+
+	pImpl.cf()
+	pImpl.Wait()
+	_, err = pImpl.Checkpoint()
+	assert.EqualError(t, err, "Checkpoint(): pipeline is not running")
+}
+
+// TestPipelineCheckpointFailure tests that a failed commit is returned and the
+// pipeline keeps running.
+func TestPipelineCheckpointFailure(t *testing.T) {
+	exp := &commitExporter{roundExporter: roundExporter{name: "committer"}, err: errors.New("disk full")}
+	pImpl := makeCheckpointPipeline(t, exp)
+
+	pImpl.Start()
+	defer func() {
+		pImpl.cf()
+		pImpl.Wait()
+	}()
+	require.Eventually(t, func() bool { return len(exp.received()) >= 1 }, 5*time.Second, time.Millisecond)
+	_, err := pImpl.Checkpoint()
+	assert.EqualError(t, err, "Checkpoint(): exporter (committer) could not commit: disk full")
+	assert.True(t, pImpl.Status().LastCheckpoint.IsZero())
+
+	received := len(exp.received())
+	require.Eventually(t, func() bool { return len(exp.received()) > received }, 5*time.Second, time.Millisecond)
+}
+
+func TestCheckpointAPI(t *testing.T) {
+	exp := &commitExporter{roundExporter: roundExporter{name: "committer"}}
+	pImpl := makeCheckpointPipeline(t, exp)
+	mux := http.NewServeMux()
+	pImpl.registerAPIHandlers(mux)
+	request := func(method string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, "/checkpoint", nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusMethodNotAllowed, request(http.MethodGet).Code)
+	assert.Equal(t, http.StatusInternalServerError, request(http.MethodPost).Code)
+
+	pImpl.Start()
+	defer func() {
+		pImpl.cf()
+		pImpl.Wait()
+	}()
+	rec := request(http.MethodPost)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body map[string]uint64
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Contains(t, body, "next-round")
+}
@@ -0,0 +1,108 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+const (
+	// clockSkewWarn logs a warning and processes the block.
+	clockSkewWarn = "warn"
+	// clockSkewAnnotate processes the block and records the skew in
+	// data.BlockData.ClockSkew.
+	clockSkewAnnotate = "annotate"
+	// clockSkewHalt stops the pipeline before the block is processed.
+	clockSkewHalt = "halt"
+)
+
+// The directions of a clock skew, used as the metric label.
+const (
+	skewFuture = "future"
+	skewPast   = "past"
+)
+
+// ClockSkew configs for detecting blocks whose timestamp is far from the wall
+// clock, which would be written into the wrong partition by exporters that
+// partition by time.
+type ClockSkew struct {
+	// MaxFuture is how far the block timestamp may be after the wall clock,
+	// zero disables the check.
+	MaxFuture time.Duration `yaml:"max-future"`
+	// MaxPast is how far the block timestamp may be before the wall clock,
+	// zero disables the check. Blocks are old while the pipeline catches up,
+	// so it is meant for pipelines which follow the tip of the chain.
+	MaxPast time.Duration `yaml:"max-past"`
+	// OnSkew is "warn" (default), "annotate" or "halt".
+	OnSkew string `yaml:"on-skew"`
+}
+
+// Valid validates the clock skew config.
+func (cs ClockSkew) Valid() error {
+	if cs.MaxFuture < 0 {
+		return fmt.Errorf("max-future must not be negative (%s)", cs.MaxFuture)
+	}
+	if cs.MaxPast < 0 {
+		return fmt.Errorf("max-past must not be negative (%s)", cs.MaxPast)
+	}
+	switch cs.OnSkew {
+	case "", clockSkewWarn, clockSkewAnnotate, clockSkewHalt:
+		return nil
+	default:
+		return fmt.Errorf("on-skew must be '%s', '%s' or '%s', found '%s'", clockSkewWarn, clockSkewAnnotate, clockSkewHalt, cs.OnSkew)
+	}
+}
+
+// clockSkewError is returned for a block whose timestamp is too far from the
+// wall clock.
+type clockSkewError struct {
+	round     uint64
+	direction string
+	skew      time.Duration
+}
+
+func (e clockSkewError) Error() string {
+	if e.direction == skewFuture {
+		return fmt.Sprintf("block %d is dated %s in the future, check the clocks of the node and of conduit", e.round, e.skew)
+	}
+	return fmt.Sprintf("block %d is dated %s in the past", e.round, -e.skew)
+}
+
+// skewOf returns the clockSkewError of a block timestamp, or nil if it is
+// within the limits. Blocks without a timestamp, such as test fixtures, are
+// not checked.
+func (cs ClockSkew) skewOf(round uint64, timestamp int64, now time.Time) *clockSkewError {
+	if timestamp == 0 {
+		return nil
+	}
+	skew := time.Unix(timestamp, 0).Sub(now).Truncate(time.Second)
+	if cs.MaxFuture > 0 && skew > cs.MaxFuture {
+		return &clockSkewError{round: round, direction: skewFuture, skew: skew}
+	}
+	if cs.MaxPast > 0 && -skew > cs.MaxPast {
+		return &clockSkewError{round: round, direction: skewPast, skew: skew}
+	}
+	return nil
+}
+
+// checkClockSkew applies the clock-skew policy to the block. With halt a
+// clockSkewError is returned and the block is unchanged.
+func (p *pipelineImpl) checkClockSkew(blk *data.BlockData, now time.Time) error {
+	skewed := p.cfg.ClockSkew.skewOf(blk.Round(), blk.BlockHeader.TimeStamp, now)
+	if skewed == nil {
+		return nil
+	}
+	metrics.ClockSkewBlocks.WithLabelValues(skewed.direction).Inc()
+	switch p.cfg.ClockSkew.OnSkew {
+	case clockSkewHalt:
+		return *skewed
+	case clockSkewAnnotate:
+		blk.ClockSkew = &data.ClockSkew{Seconds: int64(skewed.skew / time.Second), ObservedAt: now.Unix()}
+		p.logger.WithField("alert", "clock-skew").Warnf("%v, it is annotated", skewed)
+	default:
+		p.logger.WithField("alert", "clock-skew").Warnf("%v", skewed)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// futureImporter dates round 2 an hour in the future and the other rounds now.
+type futureImporter struct {
+	namedImporter
+}
+
+func (r *futureImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	blk, err := r.namedImporter.GetBlock(rnd)
+	blk.BlockHeader.TimeStamp = time.Now().Unix()
+	if rnd == 2 {
+		blk.BlockHeader.TimeStamp += 3600
+	}
+	return blk, err
+}
+
+func TestClockSkewValid(t *testing.T) {
+	for _, policy := range []string{"", clockSkewWarn, clockSkewAnnotate, clockSkewHalt} {
+		assert.NoError(t, ClockSkew{MaxFuture: time.Minute, OnSkew: policy}.Valid())
+	}
+	assert.EqualError(t, ClockSkew{OnSkew: "skip"}.Valid(), "on-skew must be 'warn', 'annotate' or 'halt', found 'skip'")
+	assert.EqualError(t, ClockSkew{MaxFuture: -time.Second}.Valid(), "max-future must not be negative (-1s)")
+	assert.EqualError(t, ClockSkew{MaxPast: -time.Second}.Valid(), "max-past must not be negative (-1s)")
+}
+
+func TestClockSkewOf(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	cs := ClockSkew{MaxFuture: time.Minute, MaxPast: time.Hour}
+	assert.Nil(t, cs.skewOf(1, 0, now))
+	assert.Nil(t, cs.skewOf(1, now.Unix()+60, now))
+	assert.Nil(t, cs.skewOf(1, now.Unix()-3600, now))
+	assert.Equal(t, &clockSkewError{round: 1, direction: skewFuture, skew: 61 * time.Second}, cs.skewOf(1, now.Unix()+61, now))
+	assert.Equal(t, &clockSkewError{round: 1, direction: skewPast, skew: -3601 * time.Second}, cs.skewOf(1, now.Unix()-3601, now))
+
+	// Zero limits are not checked.
+	assert.Nil(t, ClockSkew{}.skewOf(1, now.Unix()+86400, now))
+	assert.Nil(t, ClockSkew{}.skewOf(1, 1, now))
+}
+
+func TestCheckClockSkew(t *testing.T) {
+	metrics.RegisterPrometheusMetrics("clockskew_test")
+	now := time.Unix(1000000, 0)
+	tests := []struct {
+		policy    string
+		annotated *data.ClockSkew
+		err       string
+	}{
+		{policy: ""},
+		{policy: clockSkewWarn},
+		{policy: clockSkewAnnotate, annotated: &data.ClockSkew{Seconds: 120, ObservedAt: now.Unix()}},
+		{policy: clockSkewHalt, err: "block 7 is dated 2m0s in the future, check the clocks of the node and of conduit"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.policy, func(t *testing.T) {
+			logger, hook := test.NewNullLogger()
+			p := &pipelineImpl{cfg: &Config{ClockSkew: ClockSkew{MaxFuture: time.Minute, OnSkew: tc.policy}}, logger: logger}
+			blk := &data.BlockData{BlockHeader: sdk.BlockHeader{Round: 7, TimeStamp: now.Unix() + 120}}
+			err := p.checkClockSkew(blk, now)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, hook.AllEntries(), 1)
+				assert.Equal(t, "clock-skew", hook.LastEntry().Data["alert"])
+			}
+			assert.Equal(t, tc.annotated, blk.ClockSkew)
+		})
+	}
+	assert.Equal(t, float64(len(tests)), testutil.ToFloat64(metrics.ClockSkewBlocks.WithLabelValues(skewFuture)))
+
+	// Blocks within the limits are unchanged.
+	logger, hook := test.NewNullLogger()
+	p := &pipelineImpl{cfg: &Config{ClockSkew: ClockSkew{MaxPast: time.Minute, OnSkew: clockSkewHalt}}, logger: logger}
+	blk := &data.BlockData{BlockHeader: sdk.BlockHeader{Round: 7, TimeStamp: now.Unix() - 30}}
+	assert.NoError(t, p.checkClockSkew(blk, now))
+	assert.Nil(t, blk.ClockSkew)
+	assert.Empty(t, hook.AllEntries())
+}
+
+func TestPipelineClockSkew(t *testing.T) {
+	tests := []struct {
+		policy   string
+		received []uint64
+		next     uint64
+	}{
+		{policy: clockSkewWarn, received: []uint64{0, 1, 2, 3}, next: 4},
+		{policy: clockSkewHalt, received: []uint64{0, 1}, next: 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.policy, func(t *testing.T) {
+			exp := &roundExporter{name: "exporter"}
+			pImpl := makeCheckpointPipeline(t, exp)
+			var pImporter importers.Importer = &futureImporter{namedImporter{roundImporter{failRound: 1000}}}
+			pImpl.importer = &pImporter
+			pImpl.cfg.ClockSkew = ClockSkew{MaxFuture: time.Minute, OnSkew: tc.policy}
+			pImpl.cfg.Rounds.End = 3
+
+			pImpl.Start()
+			pImpl.Wait()
+			assert.Equal(t, tc.received, exp.received())
+			assert.Equal(t, tc.next, readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir).NextRound)
+			if tc.policy == clockSkewHalt {
+				var skewed clockSkewError
+				assert.ErrorAs(t, pImpl.Error(), &skewed)
+			}
+		})
+	}
+}
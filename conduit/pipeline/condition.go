@@ -0,0 +1,526 @@
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/processors/filterprocessor/fields"
+)
+
+// A condition is a compiled `when` expression, for example:
+//
+//	txn.type == 'appl' && (txn.apid == 1234 || block.round >= 30000000)
+//
+// Identifiers starting with "block." refer to the block header, all other
+// identifiers are transaction fields using the same tags as the filter
+// processor. When a transaction field is used, the condition holds if any
+// transaction in the block satisfies the expression.
+type condition struct {
+	expr    string
+	root    conditionNode
+	usesTxn bool
+}
+
+type valueKind int
+
+const (
+	kindNumber valueKind = iota
+	kindString
+	kindBool
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	default:
+		return "bool"
+	}
+}
+
+// blockFields are the identifiers available for the block header.
+var blockFields = map[string]valueKind{
+	"block.round":      kindNumber,
+	"block.timestamp":  kindNumber,
+	"block.genesis-id": kindString,
+	"block.proto":      kindString,
+	"block.txns":       kindNumber,
+}
+
+// conditionEnv is the block and transaction a condition is evaluated against.
+type conditionEnv struct {
+	block *data.BlockData
+	txn   *sdk.SignedTxnWithAD
+}
+
+type conditionNode interface {
+	eval(env conditionEnv) (bool, error)
+}
+
+type andNode struct{ left, right conditionNode }
+
+func (n andNode) eval(env conditionEnv) (bool, error) {
+	b, err := n.left.eval(env)
+	if err != nil || !b {
+		return false, err
+	}
+	return n.right.eval(env)
+}
+
+type orNode struct{ left, right conditionNode }
+
+func (n orNode) eval(env conditionEnv) (bool, error) {
+	b, err := n.left.eval(env)
+	if err != nil || b {
+		return b, err
+	}
+	return n.right.eval(env)
+}
+
+type notNode struct{ inner conditionNode }
+
+func (n notNode) eval(env conditionEnv) (bool, error) {
+	b, err := n.inner.eval(env)
+	return !b, err
+}
+
+// operand is a literal or a field reference.
+type operand struct {
+	field string
+	value interface{}
+	kind  valueKind
+}
+
+func (o operand) String() string {
+	if o.field != "" {
+		return o.field
+	}
+	return fmt.Sprintf("%v", o.value)
+}
+
+func (o operand) resolve(env conditionEnv) (interface{}, error) {
+	switch {
+	case o.field == "":
+		return o.value, nil
+	case strings.HasPrefix(o.field, "block."):
+		return lookupBlockField(o.field, env.block), nil
+	default:
+		v, err := fields.LookupFieldByTag(o.field, env.txn)
+		if err != nil {
+			return nil, err
+		}
+		v, _, err = normalizeValue(v)
+		return v, err
+	}
+}
+
+type boolOperandNode struct{ operand }
+
+func (n boolOperandNode) eval(env conditionEnv) (bool, error) {
+	v, err := n.resolve(env)
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+type compareNode struct {
+	op          string
+	left, right operand
+	re          *regexp.Regexp
+}
+
+func (n compareNode) eval(env conditionEnv) (bool, error) {
+	l, err := n.left.resolve(env)
+	if err != nil {
+		return false, err
+	}
+	if n.op == "=~" {
+		return n.re.MatchString(l.(string)), nil
+	}
+	r, err := n.right.resolve(env)
+	if err != nil {
+		return false, err
+	}
+
+	var cmp int
+	switch n.left.kind {
+	case kindNumber:
+		cmp = compareNumbers(l, r)
+	case kindString:
+		cmp = strings.Compare(l.(string), r.(string))
+	case kindBool:
+		if l.(bool) != r.(bool) {
+			cmp = 1
+		}
+	}
+
+	switch n.op {
+	case "==":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// compareNumbers compares two uint64 or int64 values.
+func compareNumbers(a, b interface{}) int {
+	ai, aSigned := a.(int64)
+	bi, bSigned := b.(int64)
+	switch {
+	case aSigned && ai < 0 && bSigned && bi < 0:
+		return compareUint64(uint64(-ai), uint64(-bi)) * -1
+	case aSigned && ai < 0:
+		return -1
+	case bSigned && bi < 0:
+		return 1
+	default:
+		return compareUint64(toUint64(a), toUint64(b))
+	}
+}
+
+func toUint64(v interface{}) uint64 {
+	if i, ok := v.(int64); ok {
+		return uint64(i)
+	}
+	return v.(uint64)
+}
+
+func compareUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// normalizeValue converts a field value to uint64, int64, string or bool.
+func normalizeValue(v interface{}) (interface{}, valueKind, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), kindNumber, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), kindNumber, nil
+	case reflect.String:
+		return rv.String(), kindString, nil
+	case reflect.Bool:
+		return rv.Bool(), kindBool, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported field type (%T)", v)
+	}
+}
+
+func lookupBlockField(name string, blk *data.BlockData) interface{} {
+	switch name {
+	case "block.round":
+		return blk.Round()
+	case "block.timestamp":
+		return blk.BlockHeader.TimeStamp
+	case "block.genesis-id":
+		return blk.BlockHeader.GenesisID
+	case "block.proto":
+		return blk.BlockHeader.CurrentProtocol
+	default:
+		return uint64(len(blk.Payset))
+	}
+}
+
+// compileCondition parses and type checks a `when` expression.
+func compileCondition(expr string) (*condition, error) {
+	tokens, err := tokenizeCondition(expr)
+	if err != nil {
+		return nil, fmt.Errorf("compileCondition(): %w", err)
+	}
+	p := conditionParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected '%s'", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("compileCondition(): invalid expression (%s): %w", expr, err)
+	}
+	return &condition{expr: expr, root: root, usesTxn: p.usesTxn}, nil
+}
+
+// match reports whether the block satisfies the condition.
+func (c *condition) match(blk *data.BlockData) (bool, error) {
+	if !c.usesTxn {
+		return c.root.eval(conditionEnv{block: blk})
+	}
+	for i := range blk.Payset {
+		b, err := c.root.eval(conditionEnv{block: blk, txn: &blk.Payset[i].SignedTxnWithAD})
+		if err != nil || b {
+			return b, err
+		}
+	}
+	return false, nil
+}
+
+// makeCondition compiles the plugin's when condition, returning nil if it has none.
+func makeCondition(pair NameConfigPair) (*condition, error) {
+	if pair.When == "" {
+		return nil, nil
+	}
+	cond, err := compileCondition(pair.When)
+	if err != nil {
+		return nil, fmt.Errorf("plugin (%s) when condition was invalid: %w", pair.Name, err)
+	}
+	return cond, nil
+}
+
+// matchCondition reports whether the plugin at idx should handle the block.
+func matchCondition(conditions []*condition, idx int, blk *data.BlockData) (bool, error) {
+	if idx >= len(conditions) || conditions[idx] == nil {
+		return true, nil
+	}
+	return conditions[idx].match(blk)
+}
+
+type tokenType int
+
+const (
+	tokenIdent tokenType = iota
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+type conditionToken struct {
+	typ  tokenType
+	text string
+}
+
+var conditionOps = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "<", ">", "!", "(", ")"}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-'
+}
+
+func tokenizeCondition(expr string) ([]conditionToken, error) {
+	var tokens []conditionToken
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, conditionToken{typ: tokenString, text: string(runes[i+1 : end])})
+			i = end + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			end := i + 1
+			for end < len(runes) && unicode.IsDigit(runes[end]) {
+				end++
+			}
+			tokens = append(tokens, conditionToken{typ: tokenNumber, text: string(runes[i:end])})
+			i = end
+		case unicode.IsLetter(r):
+			end := i + 1
+			for end < len(runes) && isIdentRune(runes[end]) {
+				end++
+			}
+			tokens = append(tokens, conditionToken{typ: tokenIdent, text: string(runes[i:end])})
+			i = end
+		default:
+			found := false
+			for _, op := range conditionOps {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, conditionToken{typ: tokenOp, text: op})
+					i += len([]rune(op))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected character '%c' at position %d", r, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// conditionParser is a recursive descent parser for:
+//
+//	or      := and ('||' and)*
+//	and     := unary ('&&' unary)*
+//	unary   := '!' unary | '(' or ')' | operand [op operand]
+type conditionParser struct {
+	tokens  []conditionToken
+	pos     int
+	usesTxn bool
+}
+
+func (p *conditionParser) peekOp(op string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].typ == tokenOp && p.tokens[p.pos].text == op
+}
+
+func (p *conditionParser) parseOr() (conditionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOp("||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (conditionNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOp("&&") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseUnary() (conditionNode, error) {
+	switch {
+	case p.peekOp("!"):
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner: inner}, nil
+	case p.peekOp("("):
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peekOp(")") {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		p.pos++
+		return inner, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos == len(p.tokens) || p.tokens[p.pos].typ != tokenOp {
+		if left.kind != kindBool {
+			return nil, fmt.Errorf("%s is a %s, not a condition", left, left.kind)
+		}
+		return boolOperandNode{left}, nil
+	}
+
+	op := p.tokens[p.pos].text
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "=~":
+	default:
+		if left.kind != kindBool {
+			return nil, fmt.Errorf("%s is a %s, not a condition", left, left.kind)
+		}
+		return boolOperandNode{left}, nil
+	}
+	p.pos++
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	node := compareNode{op: op, left: left, right: right}
+	switch {
+	case op == "=~":
+		if left.kind != kindString || right.field != "" || right.kind != kindString {
+			return nil, fmt.Errorf("'=~' requires a string field and a quoted pattern")
+		}
+		if node.re, err = regexp.Compile(right.value.(string)); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+	case left.kind != right.kind:
+		return nil, fmt.Errorf("cannot compare %s (%s) with %s (%s)", left, left.kind, right, right.kind)
+	case left.kind == kindBool && op != "==" && op != "!=":
+		return nil, fmt.Errorf("'%s' is not supported for bool values", op)
+	}
+	return node, nil
+}
+
+func (p *conditionParser) parseOperand() (operand, error) {
+	if p.pos == len(p.tokens) {
+		return operand{}, fmt.Errorf("unexpected end of expression")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok.typ {
+	case tokenString:
+		return operand{value: tok.text, kind: kindString}, nil
+	case tokenNumber:
+		if v, err := strconv.ParseUint(tok.text, 10, 64); err == nil {
+			return operand{value: v, kind: kindNumber}, nil
+		}
+		v, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return operand{}, fmt.Errorf("invalid number '%s'", tok.text)
+		}
+		return operand{value: v, kind: kindNumber}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return operand{value: true, kind: kindBool}, nil
+		case "false":
+			return operand{value: false, kind: kindBool}, nil
+		}
+		if kind, ok := blockFields[tok.text]; ok {
+			return operand{field: tok.text, kind: kind}, nil
+		}
+		if strings.HasPrefix(tok.text, "block.") {
+			return operand{}, fmt.Errorf("unknown block field '%s'", tok.text)
+		}
+		v, err := fields.LookupFieldByTag(tok.text, &sdk.SignedTxnWithAD{})
+		if err != nil {
+			return operand{}, fmt.Errorf("unknown transaction field '%s'", tok.text)
+		}
+		_, kind, err := normalizeValue(v)
+		if err != nil {
+			return operand{}, fmt.Errorf("field '%s': %w", tok.text, err)
+		}
+		p.usesTxn = true
+		return operand{field: tok.text, kind: kind}, nil
+	default:
+		return operand{}, fmt.Errorf("unexpected '%s'", tok.text)
+	}
+}
@@ -0,0 +1,103 @@
+package pipeline
+
+import (
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/data"
+)
+
+func makeAppCall(appID uint64) sdk.SignedTxnInBlock {
+	var stxn sdk.SignedTxnInBlock
+	stxn.Txn.Type = sdk.ApplicationCallTx
+	stxn.Txn.ApplicationID = sdk.AppIndex(appID)
+	return stxn
+}
+
+func makePayment(amount uint64) sdk.SignedTxnInBlock {
+	var stxn sdk.SignedTxnInBlock
+	stxn.Txn.Type = sdk.PaymentTx
+	stxn.Txn.Amount = sdk.MicroAlgos(amount)
+	return stxn
+}
+
+func TestConditionMatch(t *testing.T) {
+	blk := data.BlockData{
+		BlockHeader: sdk.BlockHeader{Round: 100, GenesisID: "mainnet-v1.0", TimeStamp: 1234},
+		Payset:      []sdk.SignedTxnInBlock{makePayment(5000), makeAppCall(1234)},
+	}
+	empty := data.BlockData{BlockHeader: sdk.BlockHeader{Round: 101}}
+
+	tests := []struct {
+		expr       string
+		match      bool
+		emptyMatch bool
+	}{
+		{"txn.type == 'appl' && txn.apid == 1234", true, false},
+		{"txn.type == 'appl' && txn.apid == 1", false, false},
+		{`txn.type == "pay" && txn.amt > 1000`, true, false},
+		{"txn.type == 'pay' && txn.amt > 5000", false, false},
+		{"txn.type != 'pay' && txn.type != 'appl'", false, false},
+		{"!(txn.type == 'axfer')", true, false},
+		{"txn.type =~ '^ap'", true, false},
+		{"block.round >= 100", true, true},
+		{"block.round < 101", true, false},
+		{"block.genesis-id == 'mainnet-v1.0'", true, false},
+		{"block.timestamp > -1", true, true},
+		{"block.txns == 0", false, true},
+		{"block.txns == 0 || txn.apid == 1234", true, false},
+		{"true", true, true},
+		{"false || (block.round == 100 && true)", true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			cond, err := compileCondition(test.expr)
+			require.NoError(t, err)
+
+			match, err := cond.match(&blk)
+			require.NoError(t, err)
+			assert.Equal(t, test.match, match)
+
+			match, err = cond.match(&empty)
+			require.NoError(t, err)
+			assert.Equal(t, test.emptyMatch, match)
+		})
+	}
+}
+
+func TestConditionCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr        string
+		errContains string
+	}{
+		{"", "unexpected end of expression"},
+		{"txn.type == 'appl", "unterminated string at position 12"},
+		{"txn.type = 'appl'", "unexpected character '=' at position 9"},
+		{"txn.nope == 1", "unknown transaction field 'txn.nope'"},
+		{"block.nope == 1", "unknown block field 'block.nope'"},
+		{"txn.type == 1", "cannot compare txn.type (string) with 1 (number)"},
+		{"txn.apid", "txn.apid is a number, not a condition"},
+		{"txn.apid =~ 'a'", "'=~' requires a string field and a quoted pattern"},
+		{"txn.type =~ '('", "invalid pattern"},
+		{"true < false", "'<' is not supported for bool values"},
+		{"(block.round == 1", "missing ')'"},
+		{"block.round == 1 block.round", "unexpected 'block.round'"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			_, err := compileCondition(test.expr)
+			assert.ErrorContains(t, err, test.errContains)
+		})
+	}
+}
+
+func TestCompareNumbers(t *testing.T) {
+	assert.Equal(t, 0, compareNumbers(uint64(5), int64(5)))
+	assert.Equal(t, 1, compareNumbers(uint64(0), int64(-1)))
+	assert.Equal(t, -1, compareNumbers(int64(-2), int64(-1)))
+	assert.Equal(t, 1, compareNumbers(uint64(1<<63), int64(1<<62)))
+	assert.Equal(t, -1, compareNumbers(int64(-1), uint64(0)))
+}
@@ -0,0 +1,132 @@
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/algorand/indexer/util"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+// ConfigDrift configs for detecting a config file which was changed after the
+// running config was loaded from it, for example when a new config was
+// deployed but conduit was never restarted or reloaded.
+type ConfigDrift struct {
+	// Interval is how often the config file is compared with the running
+	// config, zero disables the check.
+	Interval time.Duration `yaml:"interval"`
+	// AutoReload reloads the config file once it drifted. Changes which
+	// require a restart are only reported.
+	AutoReload bool `yaml:"auto-reload"`
+}
+
+// Valid validates the config drift config.
+func (cd ConfigDrift) Valid() error {
+	if cd.Interval < 0 {
+		return fmt.Errorf("interval must not be negative (%s)", cd.Interval)
+	}
+	return nil
+}
+
+// hashConfigFile returns the hex sha256 of the config file contents.
+func hashConfigFile(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
+
+// configFilePath returns the config file of the data directory.
+func configFilePath(args *conduit.Args) (string, error) {
+	path, err := util.GetConfigFromDataDir(args.ConduitDataDir, conduit.DefaultConfigBaseName, []string{"yml", "yaml"})
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", fmt.Errorf("could not find %s in data directory (%s)", conduit.DefaultConfigName, args.ConduitDataDir)
+	}
+	return path, nil
+}
+
+// watchConfigDrift compares the config file with the running config every
+// interval until the pipeline stops.
+func (p *pipelineImpl) watchConfigDrift(interval time.Duration, loopDone <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// reloaded is the hash of the last file which was automatically reloaded,
+	// so that a rejected config is only reloaded once.
+	var reloaded string
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-loopDone:
+			return
+		case <-ticker.C:
+			p.checkConfigDrift(&reloaded)
+		}
+	}
+}
+
+// checkConfigDrift hashes the config file and updates the drift status.
+func (p *pipelineImpl) checkConfigDrift(reloaded *string) {
+	p.mu.RLock()
+	cfg := p.cfg
+	p.mu.RUnlock()
+
+	path, err := configFilePath(cfg.ConduitArgs)
+	if err != nil {
+		p.logger.Warnf("Could not check the config file for drift: %v", err)
+		return
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		p.logger.Warnf("Could not check the config file for drift: %v", err)
+		return
+	}
+	hash := hashConfigFile(contents)
+	if hash == cfg.fileHash {
+		p.setConfigDrift(false, path)
+		return
+	}
+	p.setConfigDrift(true, path)
+	if !cfg.ConfigDrift.AutoReload || hash == *reloaded {
+		return
+	}
+
+	*reloaded = hash
+	p.logger.Infof("Reloading the drifted config file %s", path)
+	newCfg, err := MakePipelineConfig(cfg.ConduitArgs)
+	if err == nil {
+		err = p.Reload(newCfg)
+	}
+	if err != nil {
+		p.logger.Errorf("Drifted config was not reloaded: %v", err)
+		return
+	}
+	p.setConfigDrift(newCfg.fileHash != hash, path)
+}
+
+// setConfigDrift records whether the config file drifted, the change is logged.
+func (p *pipelineImpl) setConfigDrift(drift bool, path string) {
+	p.mu.Lock()
+	changed := p.status.ConfigDrift != drift
+	p.status.ConfigDrift = drift
+	p.mu.Unlock()
+	if drift {
+		metrics.ConfigDrift.Set(1)
+	} else {
+		metrics.ConfigDrift.Set(0)
+	}
+	if !changed {
+		return
+	}
+	if drift {
+		p.logger.Warnf("Config file %s changed since the running config was loaded, reload or restart conduit to apply it", path)
+	} else {
+		p.logger.Infof("Config file %s matches the running config again", path)
+	}
+}
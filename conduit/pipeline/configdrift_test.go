@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+// TestCheckConfigDrift tests that a changed config file is reported until it
+// matches the running config again.
+func TestCheckConfigDrift(t *testing.T) {
+	metrics.RegisterPrometheusMetrics("config_drift_test")
+	pImpl := makeReloadPipeline(t)
+	logger, hook := test.NewNullLogger()
+	pImpl.logger = logger
+	path := filepath.Join(pImpl.cfg.ConduitArgs.ConduitDataDir, conduit.DefaultConfigName)
+	original := []byte("log-level: info\n")
+	require.NoError(t, os.WriteFile(path, original, 0644))
+	pImpl.cfg.fileHash = hashConfigFile(original)
+
+	var reloaded string
+	pImpl.checkConfigDrift(&reloaded)
+	assert.False(t, pImpl.Status().ConfigDrift)
+	assert.Empty(t, hook.AllEntries())
+
+	require.NoError(t, os.WriteFile(path, []byte("log-level: debug\n"), 0644))
+	pImpl.checkConfigDrift(&reloaded)
+	pImpl.checkConfigDrift(&reloaded)
+	assert.True(t, pImpl.Status().ConfigDrift)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.ConfigDrift))
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Contains(t, hook.LastEntry().Message, "changed since the running config was loaded")
+	assert.Empty(t, reloaded)
+
+	require.NoError(t, os.WriteFile(path, original, 0644))
+	pImpl.checkConfigDrift(&reloaded)
+	assert.False(t, pImpl.Status().ConfigDrift)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.ConfigDrift))
+	assert.Contains(t, hook.LastEntry().Message, "matches the running config again")
+}
+
+// TestCheckConfigDriftAutoReload tests that a drifted file is reloaded once,
+// and that it is still reported when the reload fails.
+func TestCheckConfigDriftAutoReload(t *testing.T) {
+	pImpl := makeReloadPipeline(t)
+	logger, hook := test.NewNullLogger()
+	pImpl.logger = logger
+	pImpl.cfg.ConfigDrift.AutoReload = true
+	pImpl.cfg.fileHash = hashConfigFile([]byte("log-level: info\n"))
+	path := filepath.Join(pImpl.cfg.ConduitArgs.ConduitDataDir, conduit.DefaultConfigName)
+	require.NoError(t, os.WriteFile(path, []byte("log-level: info\nunknown-field: 1\n"), 0644))
+
+	var reloaded string
+	pImpl.checkConfigDrift(&reloaded)
+	assert.True(t, pImpl.Status().ConfigDrift)
+	assert.Contains(t, hook.LastEntry().Message, "Drifted config was not reloaded")
+	entries := len(hook.AllEntries())
+
+	pImpl.checkConfigDrift(&reloaded)
+	assert.Len(t, hook.AllEntries(), entries)
+}
+
+func TestConfigDriftValid(t *testing.T) {
+	assert.NoError(t, ConfigDrift{}.Valid())
+	assert.EqualError(t, ConfigDrift{Interval: -1}.Valid(), "interval must not be negative (-1ns)")
+}
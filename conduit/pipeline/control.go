@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"fmt"
+)
+
+// operatorPauseReason is the status pause reason while the pipeline is paused
+// with Pause.
+const operatorPauseReason = "paused by the operator"
+
+// controlState is the operator control of the pipeline loop, it is guarded by
+// p.mu.
+type controlState struct {
+	paused bool
+	// stepUntil is the round which a paused pipeline holds before, Step
+	// advances it.
+	stepUntil uint64
+	// wake is signalled when the control state changes, so that the loop
+	// re-evaluates whether it holds.
+	wake chan struct{}
+}
+
+// Pause holds the pipeline before its next round, the in-flight round is
+// finished first. The importer, plugins and status API remain available, and
+// checkpoints and reloads are still applied while paused.
+func (p *pipelineImpl) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.control.paused {
+		return
+	}
+	p.control.paused = true
+	p.control.stepUntil = p.status.NextRound
+	p.logger.Infof("Pipeline paused by the operator, holding after the in-flight round")
+	p.wakeLocked()
+}
+
+// Resume continues a paused pipeline.
+func (p *pipelineImpl) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.control.paused {
+		return
+	}
+	p.control.paused = false
+	p.logger.Infof("Pipeline resumed by the operator")
+	p.wakeLocked()
+}
+
+// Step lets a paused pipeline process n more rounds before holding again.
+func (p *pipelineImpl) Step(n uint64) error {
+	if n == 0 {
+		return fmt.Errorf("Step(): the number of rounds must be positive")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.control.paused {
+		return fmt.Errorf("Step(): pipeline is not paused")
+	}
+	if p.control.stepUntil < p.status.NextRound {
+		p.control.stepUntil = p.status.NextRound
+	}
+	p.control.stepUntil += n
+	p.logger.Infof("Pipeline stepping %d rounds, holding before round %d", n, p.control.stepUntil)
+	p.wakeLocked()
+	return nil
+}
+
+// holding reports whether the pipeline is paused before the round.
+func (p *pipelineImpl) holding(round uint64) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.control.paused && round >= p.control.stepUntil
+}
+
+// controlWake returns the channel which is signalled when the control state
+// changes.
+func (p *pipelineImpl) controlWake() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.control.wake == nil {
+		p.control.wake = make(chan struct{}, 1)
+	}
+	return p.control.wake
+}
+
+func (p *pipelineImpl) wakeLocked() {
+	select {
+	case p.control.wake <- struct{}{}:
+	default:
+	}
+}
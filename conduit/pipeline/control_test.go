@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitHeld waits until the exporter stops receiving rounds and returns the
+// number of rounds it received.
+func waitHeld(t *testing.T, exp *roundExporter) int {
+	var received int
+	require.Eventually(t, func() bool {
+		before := len(exp.received())
+		time.Sleep(20 * time.Millisecond)
+		received = len(exp.received())
+		return received == before
+	}, 5*time.Second, time.Millisecond)
+	return received
+}
+
+func TestPipelinePauseStepResume(t *testing.T) {
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeCheckpointPipeline(t, exp)
+	assert.EqualError(t, pImpl.Step(1), "Step(): pipeline is not paused")
+
+	pImpl.Start()
+	defer func() {
+		pImpl.cf()
+		pImpl.Wait()
+	}()
+	require.Eventually(t, func() bool { return len(exp.received()) >= 2 }, 5*time.Second, time.Millisecond)
+
+	pImpl.Pause()
+	received := waitHeld(t, exp)
+	status := pImpl.Status()
+	assert.True(t, status.Paused)
+	assert.Equal(t, operatorPauseReason, status.PauseReason)
+	assert.True(t, status.Running)
+
+	// Stepping processes exactly the requested rounds.
+	require.NoError(t, pImpl.Step(2))
+	require.Eventually(t, func() bool { return len(exp.received()) == received+2 }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, received+2, waitHeld(t, exp))
+	assert.EqualError(t, pImpl.Step(0), "Step(): the number of rounds must be positive")
+
+	// A checkpoint is applied while paused.
+	round, err := pImpl.Checkpoint()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(received+2), round)
+
+	pImpl.Resume()
+	assert.False(t, pImpl.Status().Paused)
+	require.Eventually(t, func() bool { return len(exp.received()) > received+2 }, 5*time.Second, time.Millisecond)
+}
+
+func TestPipelineStopWhilePaused(t *testing.T) {
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeCheckpointPipeline(t, exp)
+	pImpl.Pause()
+	pImpl.Start()
+	waitHeld(t, exp)
+	assert.Empty(t, exp.received())
+
+	pImpl.requestStop()
+	pImpl.Wait()
+	assert.False(t, pImpl.Status().Running)
+}
+
+func TestControlAPI(t *testing.T) {
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeCheckpointPipeline(t, exp)
+	mux := http.NewServeMux()
+	pImpl.registerAPIHandlers(mux)
+	request := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusMethodNotAllowed, request(http.MethodGet, "/pause").Code)
+	assert.Equal(t, http.StatusConflict, request(http.MethodPost, "/step").Code)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/pause").Code)
+	assert.True(t, pImpl.Status().Paused)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/step?rounds=many").Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/step?rounds=3").Code)
+	assert.Equal(t, uint64(3), pImpl.control.stepUntil)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/resume").Code)
+	assert.False(t, pImpl.Status().Paused)
+}
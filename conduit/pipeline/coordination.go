@@ -0,0 +1,50 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/algorand/conduit/conduit/coordinator"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+// claimPollInterval is how often a round held by another instance is claimed again.
+var claimPollInterval = time.Second
+
+// claimRound claims the next round before it is exported. A round held by
+// another instance is polled until it is completed or the lease expires,
+// without counting as a retry. ok is false if the pipeline is stopped while
+// waiting.
+func (p *pipelineImpl) claimRound() (result coordinator.ClaimResult, ok bool, err error) {
+	round := p.pipelineMetadata.NextRound
+	logged := false
+	for {
+		result, err = p.coordinator.Claim(p.ctx, round)
+		if err != nil {
+			return result, true, fmt.Errorf("claimRound(): unable to claim round %d: %w", round, err)
+		}
+		metrics.CoordinationClaims.WithLabelValues(result.String()).Inc()
+		if result != coordinator.Held {
+			return result, true, nil
+		}
+		if !logged {
+			p.logger.Infof("round %d is being exported by another instance, waiting", round)
+			logged = true
+		}
+		select {
+		case <-p.ctx.Done():
+			return result, false, nil
+		case <-p.stopCh:
+			return result, false, nil
+		case <-time.After(claimPollInterval):
+		}
+	}
+}
+
+// completeRound marks the round as exported by this instance.
+func (p *pipelineImpl) completeRound(round uint64) {
+	if err := p.coordinator.Complete(p.ctx, round); err != nil {
+		// The round was exported, but another instance may have exported it as well.
+		p.logger.Warnf("%v", err)
+	}
+}
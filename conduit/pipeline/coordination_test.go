@@ -0,0 +1,118 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/coordinator"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// memCoordinator is an in-memory coordinator shared by several pipelines.
+type memCoordinator struct {
+	mu        sync.Mutex
+	owners    map[uint64]string
+	completed map[uint64]string
+}
+
+func (m *memCoordinator) instance(id string) coordinator.Coordinator {
+	return &memInstance{shared: m, id: id}
+}
+
+type memInstance struct {
+	shared *memCoordinator
+	id     string
+}
+
+func (c *memInstance) Claim(_ context.Context, round uint64) (coordinator.ClaimResult, error) {
+	c.shared.mu.Lock()
+	defer c.shared.mu.Unlock()
+	if _, ok := c.shared.completed[round]; ok {
+		return coordinator.Completed, nil
+	}
+	if owner, ok := c.shared.owners[round]; ok && owner != c.id {
+		return coordinator.Held, nil
+	}
+	c.shared.owners[round] = c.id
+	return coordinator.Claimed, nil
+}
+
+func (c *memInstance) Complete(_ context.Context, round uint64) error {
+	c.shared.mu.Lock()
+	defer c.shared.mu.Unlock()
+	c.shared.completed[round] = c.id
+	return nil
+}
+
+func (c *memInstance) Close() error {
+	return nil
+}
+
+// TestPipelineCoordination tests that two pipelines sharing a coordinator
+// export every round exactly once.
+func TestPipelineCoordination(t *testing.T) {
+	defer func(interval time.Duration) { claimPollInterval = interval }(claimPollInterval)
+	claimPollInterval = time.Millisecond
+
+	shared := &memCoordinator{owners: make(map[uint64]string), completed: make(map[uint64]string)}
+	var exps []*roundExporter
+	var pipelines []*pipelineImpl
+	for _, id := range []string{"a", "b"} {
+		exp := &roundExporter{name: "exporter_" + id}
+		pImpl := makeReloadPipeline(t, exp)
+		var imp importers.Importer = &namedImporter{roundImporter{failRound: 1000}}
+		pImpl.importer = &imp
+		pImpl.coordinator = shared.instance(id)
+		exps = append(exps, exp)
+		pipelines = append(pipelines, pImpl)
+	}
+	for _, pImpl := range pipelines {
+		pImpl.Start()
+	}
+	require.Eventually(t, func() bool {
+		return pipelines[0].Status().NextRound > 20 && pipelines[1].Status().NextRound > 20
+	}, 5*time.Second, time.Millisecond)
+	for _, pImpl := range pipelines {
+		pImpl.cf()
+		pImpl.Wait()
+		pImpl.stop()
+	}
+
+	exported := make(map[uint64]int)
+	for _, exp := range exps {
+		for _, round := range exp.received() {
+			exported[round]++
+		}
+	}
+	for round := uint64(0); round <= 20; round++ {
+		assert.Equal(t, 1, exported[round], "round %d", round)
+	}
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	for round, count := range exported {
+		assert.Equal(t, 1, count, "round %d", round)
+		assert.Contains(t, shared.completed, round)
+	}
+}
+
+func TestPipelineCoordinationStopWhileHeld(t *testing.T) {
+	shared := &memCoordinator{owners: map[uint64]string{0: "other"}, completed: make(map[uint64]string)}
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeReloadPipeline(t, exp)
+	var imp importers.Importer = &namedImporter{roundImporter{failRound: 1000}}
+	pImpl.importer = &imp
+	pImpl.coordinator = shared.instance("a")
+
+	pImpl.Start()
+	time.Sleep(50 * time.Millisecond)
+	pImpl.Stop()
+	pImpl.Wait()
+	assert.Empty(t, exp.received())
+	assert.Equal(t, uint64(0), pImpl.Status().NextRound)
+	assert.Equal(t, uint64(0), pImpl.Status().RetryCount)
+}
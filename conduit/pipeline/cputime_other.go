@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package pipeline
+
+import "time"
+
+// processCPUTime is not supported on this platform, CPU time is not attributed to plugins.
+func processCPUTime() time.Duration {
+	return 0
+}
@@ -0,0 +1,288 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+)
+
+const (
+	// onFailureHalt stops the pipeline when a round exhausts its retries.
+	onFailureHalt = "halt"
+	// onFailureSkip records the failed round in the metadata and advances.
+	onFailureSkip = "skip"
+	// onFailureDeadLetter is like skip, but also saves the imported block so
+	// that it can be replayed later.
+	onFailureDeadLetter = "dead-letter"
+)
+
+// deadLetterDir is the directory inside the data directory where failed blocks are written.
+const deadLetterDir = "deadletter"
+
+// failedRound is a round which was skipped after exhausting its retries.
+type failedRound struct {
+	Round uint64 `json:"round"`
+	Error string `json:"error,omitempty"`
+	// File is the dead letter file name, it is empty if the block was not saved.
+	File string `json:"file,omitempty"`
+}
+
+// failedTxn is a transaction which a processor could not process, the rest
+// of its round was exported.
+type failedTxn struct {
+	Round     uint64 `json:"round"`
+	Intra     int    `json:"intra"`
+	Processor string `json:"processor"`
+	Error     string `json:"error,omitempty"`
+	// File is the dead letter file name, it is empty if the transaction was not saved.
+	File string `json:"file,omitempty"`
+}
+
+// deadLetterTxn is a transaction saved in a dead letter file.
+type deadLetterTxn struct {
+	Round     uint64               `codec:"round"`
+	Intra     int                  `codec:"intra"`
+	Processor string               `codec:"processor"`
+	Error     string               `codec:"error"`
+	Txn       sdk.SignedTxnInBlock `codec:"txn"`
+}
+
+// isolatedTxns are the transactions a processor failed in the current round.
+type isolatedTxns struct {
+	processor string
+	err       *conduit.PartialError
+}
+
+func validOnFailure(onFailure string) error {
+	switch onFailure {
+	case "", onFailureHalt, onFailureSkip, onFailureDeadLetter:
+		return nil
+	default:
+		return fmt.Errorf("on-failure must be '%s', '%s' or '%s', found '%s'", onFailureHalt, onFailureSkip, onFailureDeadLetter, onFailure)
+	}
+}
+
+// skipFailures reports whether failed rounds are skipped instead of halting the pipeline.
+func (cfg *Config) skipFailures() bool {
+	return cfg.OnFailure == onFailureSkip || cfg.OnFailure == onFailureDeadLetter
+}
+
+func deadLetterPath(dataDir string, file string) string {
+	return path.Join(dataDir, deadLetterDir, file)
+}
+
+// writeDeadLetter saves an encoded block, returning the file name.
+func (p *pipelineImpl) writeDeadLetter(round uint64, encodedBlk []byte) (string, error) {
+	file := fmt.Sprintf("round_%d.msgp", round)
+	if err := os.MkdirAll(path.Join(p.cfg.ConduitArgs.ConduitDataDir, deadLetterDir), os.ModePerm); err != nil {
+		return "", fmt.Errorf("writeDeadLetter(): unable to create dead letter directory: %w", err)
+	}
+	if err := os.WriteFile(deadLetterPath(p.cfg.ConduitArgs.ConduitDataDir, file), encodedBlk, 0644); err != nil {
+		return "", fmt.Errorf("writeDeadLetter(): unable to write round %d: %w", round, err)
+	}
+	return file, nil
+}
+
+// skipFailedRound records the current round as failed and advances past it. In
+// dead-letter mode the imported block is saved, encodedBlk is nil if the round
+// could not be imported. reason describes why the round is not retried again.
+func (p *pipelineImpl) skipFailedRound(encodedBlk []byte, reason string) {
+	round := p.pipelineMetadata.NextRound
+	failed := failedRound{Round: round}
+	if err := p.Error(); err != nil {
+		failed.Error = err.Error()
+	}
+	p.logger.Errorf("Round %d %s and was skipped: %s", round, reason, failed.Error)
+
+	if p.cfg.OnFailure == onFailureDeadLetter {
+		if encodedBlk == nil {
+			p.logger.Warnf("Round %d could not be imported, it was not written to the dead letter directory", round)
+		} else if file, err := p.writeDeadLetter(round, encodedBlk); err != nil {
+			p.logger.Errorf("%v", err)
+		} else {
+			failed.File = file
+		}
+	}
+
+	p.pipelineMetadata.FailedRounds = append(p.pipelineMetadata.FailedRounds, failed)
+	p.pipelineMetadata.NextRound++
+	p.mu.Lock()
+	p.status.NextRound = p.pipelineMetadata.NextRound
+	p.mu.Unlock()
+	if err := p.saveMetadata(); err != nil {
+		p.logger.Errorf("%v", err)
+	}
+}
+
+// isolateTxns records the transactions which processors failed in the round,
+// which was exported without them. In dead-letter mode the transactions are
+// saved to a single file for the round.
+func (p *pipelineImpl) isolateTxns(round uint64, isolated []isolatedTxns) {
+	if len(isolated) == 0 {
+		return
+	}
+	var failed []failedTxn
+	var letters []deadLetterTxn
+	for _, iso := range isolated {
+		for _, txnErr := range iso.err.Failed {
+			failed = append(failed, failedTxn{Round: round, Intra: txnErr.Intra, Processor: iso.processor, Error: txnErr.Err.Error()})
+			letters = append(letters, deadLetterTxn{Round: round, Intra: txnErr.Intra, Processor: iso.processor, Error: txnErr.Err.Error(), Txn: txnErr.Txn})
+		}
+	}
+	p.logger.Errorf("Round %d was exported without %d failed transactions", round, len(failed))
+
+	if p.cfg.OnFailure == onFailureDeadLetter {
+		file := fmt.Sprintf("round_%d_txns.msgp", round)
+		if err := os.MkdirAll(path.Join(p.cfg.ConduitArgs.ConduitDataDir, deadLetterDir), os.ModePerm); err != nil {
+			p.logger.Errorf("isolateTxns(): unable to create dead letter directory: %v", err)
+		} else if err = os.WriteFile(deadLetterPath(p.cfg.ConduitArgs.ConduitDataDir, file), msgpack.Encode(letters), 0644); err != nil {
+			p.logger.Errorf("isolateTxns(): unable to write the transactions of round %d: %v", round, err)
+		} else {
+			for idx := range failed {
+				failed[idx].File = file
+			}
+		}
+	}
+	p.pipelineMetadata.FailedTxns = append(p.pipelineMetadata.FailedTxns, failed...)
+}
+
+// replayRound runs a dead-lettered block through the processors and exporters.
+// OnComplete callbacks are not called because the pipeline has already moved
+// past the round.
+func (p *pipelineImpl) replayRound(blk data.BlockData) error {
+	for idx, proc := range p.processors {
+		match, err := matchCondition(p.processorConditions, idx, &blk)
+		if err != nil {
+			return err
+		}
+		if !match {
+			continue
+		}
+		blk, err = runProcessor(*proc, p.windows[proc], blk, false)
+		if err != nil {
+			return fmt.Errorf("processor (%s): %w", p.processorName(idx), err)
+		}
+	}
+	for idx, exporter := range p.exporters {
+		if p.isStandby(idx) {
+			continue
+		}
+		intent, err := p.intent(blk.Round(), idx)
+		if err != nil {
+			return err
+		}
+		if intent != nil && intent.committed {
+			// Committed by a previous replay which failed in a later exporter.
+			continue
+		}
+		match, err := matchCondition(p.exporterConditions, idx, &blk)
+		if err != nil {
+			return err
+		}
+		if !match {
+			continue
+		}
+		exportBlk, match, err := p.laneBlock(idx, blk)
+		if err != nil {
+			return err
+		}
+		if !match {
+			continue
+		}
+		exportBlk, err = p.processForExporter(idx, nil, exportBlk)
+		if err == nil {
+			// Like the pipeline, transactional exporters commit a round
+			// which was prepared by a previous replay.
+			err = p.receive(idx, *exporter, exportBlk)
+		}
+		if err != nil && p.duplicateRound(p.exporterName(idx), err) {
+			// The exporter received the round before it failed.
+			err = nil
+		}
+		if err != nil && p.isBestEffort(idx) {
+			p.logger.Warnf("best-effort exporter (%s) skipped round %d: %v", p.exporterName(idx), blk.Round(), err)
+		} else if err != nil {
+			return fmt.Errorf("exporter (%s): %w", p.exporterName(idx), err)
+		}
+	}
+	p.dropRoundIntents(blk.Round())
+	return nil
+}
+
+// replayDeadLetters replays the dead-lettered rounds, or all of them if rounds
+// is empty. Replayed rounds are removed from the metadata and dead letter directory.
+func (p *pipelineImpl) replayDeadLetters(rounds []uint64) ([]uint64, error) {
+	selected := make(map[uint64]bool)
+	for _, round := range rounds {
+		selected[round] = true
+	}
+
+	var replayed []uint64
+	var remaining []failedRound
+	var replayErr error
+	for _, failed := range p.pipelineMetadata.FailedRounds {
+		if replayErr != nil || (len(selected) > 0 && !selected[failed.Round]) {
+			remaining = append(remaining, failed)
+			continue
+		}
+		if failed.File == "" {
+			p.logger.Warnf("Round %d was not written to the dead letter directory and cannot be replayed", failed.Round)
+			remaining = append(remaining, failed)
+			continue
+		}
+
+		file := deadLetterPath(p.cfg.ConduitArgs.ConduitDataDir, failed.File)
+		encoded, err := os.ReadFile(file)
+		if err != nil {
+			replayErr = fmt.Errorf("replayDeadLetters(): unable to read round %d: %w", failed.Round, err)
+			remaining = append(remaining, failed)
+			continue
+		}
+		var blk data.BlockData
+		if err = msgpack.Decode(encoded, &blk); err != nil {
+			replayErr = fmt.Errorf("replayDeadLetters(): unable to decode round %d: %w", failed.Round, err)
+			remaining = append(remaining, failed)
+			continue
+		}
+		if err = p.replayRound(blk); err != nil {
+			replayErr = fmt.Errorf("replayDeadLetters(): round %d failed: %w", failed.Round, err)
+			remaining = append(remaining, failed)
+			continue
+		}
+
+		p.logger.Infof("Replayed dead letter round %d", failed.Round)
+		replayed = append(replayed, failed.Round)
+		if err = os.Remove(file); err != nil {
+			p.logger.Warnf("unable to remove dead letter file: %v", err)
+		}
+	}
+
+	p.pipelineMetadata.FailedRounds = remaining
+	if err := p.saveMetadata(); err != nil {
+		return replayed, fmt.Errorf("replayDeadLetters(): %w", err)
+	}
+	return replayed, replayErr
+}
+
+// ReplayDeadLetters initializes the pipeline and re-injects dead-lettered rounds
+// into the processors and exporters. If rounds is empty all dead letters are
+// replayed. Conduit must not be running on the same data directory.
+func ReplayDeadLetters(ctx context.Context, cfg *Config, logger *log.Logger, rounds []uint64) ([]uint64, error) {
+	p, err := MakePipeline(ctx, cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("ReplayDeadLetters(): %w", err)
+	}
+	if err = p.Init(); err != nil {
+		return nil, fmt.Errorf("ReplayDeadLetters(): %w", err)
+	}
+	defer p.Stop()
+	return p.(*pipelineImpl).replayDeadLetters(rounds)
+}
@@ -0,0 +1,264 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+	"github.com/algorand/conduit/conduit/statestore"
+)
+
+func TestValidOnFailure(t *testing.T) {
+	for _, mode := range []string{"", "halt", "skip", "dead-letter"} {
+		assert.NoError(t, validOnFailure(mode))
+	}
+	assert.EqualError(t, validOnFailure("retry"), "on-failure must be 'halt', 'skip' or 'dead-letter', found 'retry'")
+}
+
+func makeFailurePipeline(t *testing.T, imp *roundImporter, exp *roundExporter, onFailure string) *pipelineImpl {
+	var pImporter importers.Importer = imp
+	var pExporter exporters.Exporter = exp
+
+	ctx, cf := context.WithCancel(context.Background())
+	l, _ := test.NewNullLogger()
+	return &pipelineImpl{
+		ctx:        ctx,
+		cf:         cf,
+		logger:     l,
+		importer:   &pImporter,
+		processors: []*processors.Processor{},
+		exporters:  []*exporters.Exporter{&pExporter},
+		cfg: &Config{
+			RetryCount: 1,
+			OnFailure:  onFailure,
+			ConduitArgs: &conduit.Args{
+				ConduitDataDir: t.TempDir(),
+			},
+		},
+	}
+}
+
+func readState(t *testing.T, dataDir string) state {
+	b, err := os.ReadFile(statestore.MetadataPath(dataDir))
+	require.NoError(t, err)
+	var s state
+	require.NoError(t, json.Unmarshal(b, &s))
+	return s
+}
+
+// TestPipelineDeadLetter tests that a failing round is written to the dead letter
+// directory, skipped, and can be replayed later.
+func TestPipelineDeadLetter(t *testing.T) {
+	exp := &roundExporter{name: "exporter", failRound: 2, failCount: math.MaxInt}
+	pImpl := makeFailurePipeline(t, &roundImporter{}, exp, onFailureDeadLetter)
+	dataDir := pImpl.cfg.ConduitArgs.ConduitDataDir
+
+	pImpl.Start()
+	require.Eventually(t, func() bool {
+		return len(exp.received()) >= 4
+	}, 5*time.Second, time.Millisecond)
+	pImpl.cf()
+	pImpl.Wait()
+
+	assert.Equal(t, []uint64{0, 1, 3, 4}, exp.received()[:4])
+	metadata := readState(t, dataDir)
+	assert.Equal(t, []failedRound{{Round: 2, Error: "receive", File: "round_2.msgp"}}, metadata.FailedRounds)
+	assert.FileExists(t, deadLetterPath(dataDir, "round_2.msgp"))
+
+	// Replay the round once the exporter is fixed.
+	exp.mu.Lock()
+	exp.failCount = 0
+	exp.rounds = nil
+	exp.mu.Unlock()
+	replayed, err := pImpl.replayDeadLetters(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2}, replayed)
+	assert.Equal(t, []uint64{2}, exp.received())
+	assert.Empty(t, readState(t, dataDir).FailedRounds)
+	assert.NoFileExists(t, deadLetterPath(dataDir, "round_2.msgp"))
+}
+
+// TestReplayDeadLetterTransactional tests that a replayed round is committed
+// through a commit intent, and that a failed commit is retried by the next
+// replay without preparing the round again.
+func TestReplayDeadLetterTransactional(t *testing.T) {
+	exp := &txnExporter{roundExporter: roundExporter{name: "exporter"}, commitFailRound: 2, commitFailCount: math.MaxInt}
+	pImpl := makeFailurePipeline(t, &roundImporter{failRound: math.MaxUint64}, &exp.roundExporter, onFailureDeadLetter)
+	var pExporter exporters.Exporter = exp
+	pImpl.exporters = []*exporters.Exporter{&pExporter}
+	pImpl.cfg.Rounds.End = 3
+	dataDir := pImpl.cfg.ConduitArgs.ConduitDataDir
+
+	pImpl.Start()
+	pImpl.Wait()
+	assert.Equal(t, []exporters.CommitToken{"txn-2"}, exp.rolledBack)
+	require.Len(t, readState(t, dataDir).FailedRounds, 1)
+
+	exp.mu.Lock()
+	exp.commitFailCount = 1
+	exp.prepared = nil
+	exp.committed = nil
+	exp.mu.Unlock()
+	_, err := pImpl.replayDeadLetters(nil)
+	require.ErrorContains(t, err, "could not commit round 2")
+	assert.Len(t, readState(t, dataDir).CommitIntents, 1)
+
+	replayed, err := pImpl.replayDeadLetters(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2}, replayed)
+	assert.Equal(t, []uint64{2}, exp.prepared)
+	assert.Equal(t, []exporters.CommitToken{"txn-2"}, exp.committed)
+	assert.Empty(t, exp.received())
+	metadata := readState(t, dataDir)
+	assert.Empty(t, metadata.FailedRounds)
+	assert.Empty(t, metadata.CommitIntents)
+}
+
+// TestReplayDeadLetterDuplicateRound tests that a round which the exporter
+// already has is treated as replayed.
+func TestReplayDeadLetterDuplicateRound(t *testing.T) {
+	exp := &roundExporter{name: "exporter", failRound: 2, failCount: math.MaxInt}
+	pImpl := makeFailurePipeline(t, &roundImporter{failRound: math.MaxUint64}, exp, onFailureDeadLetter)
+	pImpl.cfg.Rounds.End = 3
+	dataDir := pImpl.cfg.ConduitArgs.ConduitDataDir
+
+	pImpl.Start()
+	pImpl.Wait()
+	require.Len(t, readState(t, dataDir).FailedRounds, 1)
+
+	ahead := &aheadExporter{roundExporter: roundExporter{name: "exporter"}, next: 4}
+	var pExporter exporters.Exporter = ahead
+	pImpl.exporters = []*exporters.Exporter{&pExporter}
+	replayed, err := pImpl.replayDeadLetters(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2}, replayed)
+	assert.Empty(t, ahead.received())
+	assert.Empty(t, readState(t, dataDir).FailedRounds)
+	assert.NoFileExists(t, deadLetterPath(dataDir, "round_2.msgp"))
+}
+
+// TestReplayDeadLettersSelection tests that only the requested rounds are replayed.
+func TestReplayDeadLettersSelection(t *testing.T) {
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeFailurePipeline(t, &roundImporter{}, exp, onFailureDeadLetter)
+	pImpl.pipelineMetadata.FailedRounds = []failedRound{
+		{Round: 3, Error: "import", File: ""},
+		{Round: 4, Error: "receive", File: "round_4.msgp"},
+		{Round: 5, Error: "receive", File: "round_5.msgp"},
+	}
+
+	replayed, err := pImpl.replayDeadLetters([]uint64{3, 4})
+	assert.ErrorContains(t, err, "replayDeadLetters(): unable to read round 4")
+	assert.Empty(t, replayed)
+	assert.Len(t, pImpl.pipelineMetadata.FailedRounds, 3)
+}
+
+// TestPipelineSkipImportFailure tests that rounds which cannot be imported are skipped with prefetching enabled.
+func TestPipelineSkipImportFailure(t *testing.T) {
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeFailurePipeline(t, &roundImporter{failRound: 2, alwaysFail: true}, exp, onFailureSkip)
+	pImpl.cfg.PrefetchRounds = 2
+
+	pImpl.Start()
+	require.Eventually(t, func() bool {
+		return len(exp.received()) >= 4
+	}, 5*time.Second, time.Millisecond)
+	pImpl.cf()
+	pImpl.Wait()
+
+	assert.Equal(t, []uint64{0, 1, 3, 4}, exp.received()[:4])
+	assert.Equal(t, []failedRound{{Round: 2, Error: "importer"}}, readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir).FailedRounds)
+	assert.NoDirExists(t, deadLetterPath(pImpl.cfg.ConduitArgs.ConduitDataDir, ""))
+}
+
+// partialProcessor fails the second transaction of round 2.
+type partialProcessor struct {
+	mockProcessor
+}
+
+func (p *partialProcessor) Process(input data.BlockData) (data.BlockData, error) {
+	if input.Round() != 2 {
+		return input, nil
+	}
+	failed := sdk.SignedTxnInBlock{}
+	failed.Txn.Type = sdk.PaymentTx
+	input.Payset = []sdk.SignedTxnInBlock{{}, {}}
+	return input, &conduit.PartialError{Failed: []conduit.TxnError{{Intra: 1, Txn: failed, Err: fmt.Errorf("decode")}}}
+}
+
+// TestPipelinePartialError tests that the transactions failed by a processor
+// are isolated and the rest of the round is exported.
+func TestPipelinePartialError(t *testing.T) {
+	tests := []struct {
+		onFailure string
+		file      string
+	}{
+		{onFailure: onFailureSkip},
+		{onFailure: onFailureDeadLetter, file: "round_2_txns.msgp"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.onFailure, func(t *testing.T) {
+			exp := &roundExporter{name: "exporter"}
+			pImpl := makeFailurePipeline(t, &roundImporter{failRound: math.MaxUint64}, exp, tc.onFailure)
+			var pProcessor processors.Processor = &partialProcessor{}
+			pImpl.processors = []*processors.Processor{&pProcessor}
+			pImpl.cfg.Processors = []NameConfigPair{{Name: "mockProcessor"}}
+			pImpl.cfg.Rounds.End = 4
+			dataDir := pImpl.cfg.ConduitArgs.ConduitDataDir
+
+			pImpl.Start()
+			pImpl.Wait()
+			require.NoError(t, pImpl.Error())
+
+			assert.Equal(t, []uint64{0, 1, 2, 3, 4}, exp.received())
+			metadata := readState(t, dataDir)
+			assert.Empty(t, metadata.FailedRounds)
+			assert.Equal(t, []failedTxn{{Round: 2, Intra: 1, Processor: "mockProcessor", Error: "decode", File: tc.file}}, metadata.FailedTxns)
+			if tc.file == "" {
+				assert.NoDirExists(t, deadLetterPath(dataDir, ""))
+				return
+			}
+			b, err := os.ReadFile(deadLetterPath(dataDir, tc.file))
+			require.NoError(t, err)
+			var letters []deadLetterTxn
+			require.NoError(t, msgpack.Decode(b, &letters))
+			require.Len(t, letters, 1)
+			assert.Equal(t, uint64(2), letters[0].Round)
+			assert.Equal(t, 1, letters[0].Intra)
+			assert.Equal(t, sdk.PaymentTx, letters[0].Txn.Txn.Type)
+		})
+	}
+}
+
+// TestPipelinePartialErrorHalt tests that partial errors are retried when
+// failures halt the pipeline.
+func TestPipelinePartialErrorHalt(t *testing.T) {
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeFailurePipeline(t, &roundImporter{failRound: math.MaxUint64}, exp, onFailureHalt)
+	var pProcessor processors.Processor = &partialProcessor{}
+	pImpl.processors = []*processors.Processor{&pProcessor}
+	pImpl.cfg.Processors = []NameConfigPair{{Name: "mockProcessor"}}
+
+	pImpl.Start()
+	pImpl.Wait()
+
+	require.Error(t, pImpl.Error())
+	assert.Contains(t, pImpl.Error().Error(), "1 transactions failed: txn 1: decode")
+	assert.Equal(t, []uint64{0, 1}, exp.received())
+	assert.Empty(t, readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir).FailedTxns)
+}
@@ -0,0 +1,251 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/algorand/go-algorand-sdk/v2/crypto"
+	sdkjson "github.com/algorand/go-algorand-sdk/v2/encoding/json"
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/algorand/indexer/protocol"
+	"github.com/algorand/indexer/protocol/config"
+
+	"github.com/algorand/conduit/conduit/data"
+)
+
+const (
+	// debugSourceCache is the source of a round which was cached when it was imported.
+	debugSourceCache = "cache"
+	// debugSourceImporter is the source of a round which was fetched again.
+	debugSourceImporter = "importer"
+)
+
+// DebugRound is the result of running the processors again on a past round.
+type DebugRound struct {
+	Round uint64 `json:"round"`
+	// Source is "cache" if the imported block was cached, or "importer" if
+	// it was fetched again.
+	Source string `json:"source"`
+	// Txns is the number of top level transactions of the imported block.
+	Txns       int              `json:"txns"`
+	Processors []DebugProcessor `json:"processors"`
+	// Txn follows the transaction given by the txid parameter.
+	Txn *DebugTxn `json:"txn,omitempty"`
+	// Output is the block returned by the last processor, in the JSON
+	// encoding of the exporters.
+	Output json.RawMessage `json:"output,omitempty"`
+}
+
+// DebugProcessor is the result of a processor for a DebugRound.
+type DebugProcessor struct {
+	Name string `json:"name"`
+	// Skipped is set if the when condition of the processor did not match.
+	Skipped bool `json:"skipped,omitempty"`
+	// Txns is the number of top level transactions returned by the processor.
+	Txns int `json:"txns"`
+	// Removed are the IDs of the transactions which the processor removed.
+	Removed []string `json:"removed,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// DebugTxn reports what happened to a transaction of a DebugRound.
+type DebugTxn struct {
+	ID string `json:"id"`
+	// Imported is set if the transaction is in the imported block.
+	Imported bool `json:"imported"`
+	// RemovedBy is the processor which removed the transaction.
+	RemovedBy string `json:"removed-by,omitempty"`
+	// Exported is set if the transaction is in the output block.
+	Exported bool `json:"exported"`
+}
+
+// debugRequest is a DebugRound request passed to the pipeline loop.
+type debugRequest struct {
+	round  uint64
+	txid   string
+	result chan debugResult
+}
+
+type debugResult struct {
+	round DebugRound
+	err   error
+}
+
+// debugCache keeps the encoded imported blocks of the most recent rounds.
+type debugCache struct {
+	size   int
+	rounds []uint64
+	blocks map[uint64][]byte
+}
+
+func makeDebugCache(size int) *debugCache {
+	if size <= 0 {
+		return nil
+	}
+	return &debugCache{size: size, blocks: make(map[uint64][]byte)}
+}
+
+// add caches a copy of the block, the oldest round is removed once the cache is full.
+func (c *debugCache) add(blk data.BlockData) {
+	if c == nil {
+		return
+	}
+	round := blk.Round()
+	if _, ok := c.blocks[round]; !ok {
+		if len(c.rounds) == c.size {
+			delete(c.blocks, c.rounds[0])
+			c.rounds = c.rounds[1:]
+		}
+		c.rounds = append(c.rounds, round)
+	}
+	c.blocks[round] = msgpack.Encode(blk)
+}
+
+// get returns a copy of the cached block of the round.
+func (c *debugCache) get(round uint64) (data.BlockData, bool, error) {
+	var blk data.BlockData
+	if c == nil {
+		return blk, false, nil
+	}
+	encoded, ok := c.blocks[round]
+	if !ok {
+		return blk, false, nil
+	}
+	if err := msgpack.Decode(encoded, &blk); err != nil {
+		return blk, false, fmt.Errorf("unable to decode cached round %d: %w", round, err)
+	}
+	return blk, true, nil
+}
+
+// DebugRound waits for the in-flight round, then runs the processors on the
+// imported block of a past round and reports the output of each processor.
+// The block is taken from the cache of recent rounds, see API.DebugRounds, or
+// fetched again from the importer. The exporters are not called and the
+// pipeline state is unchanged, but processors which keep state across rounds
+// see the round again.
+func (p *pipelineImpl) DebugRound(round uint64, txid string) (DebugRound, error) {
+	p.mu.RLock()
+	loopDone := p.loopDone
+	p.mu.RUnlock()
+	if loopDone == nil {
+		return DebugRound{}, fmt.Errorf("DebugRound(): pipeline is not running")
+	}
+
+	req := debugRequest{round: round, txid: txid, result: make(chan debugResult, 1)}
+	select {
+	case p.debugCh <- req:
+	case <-loopDone:
+		return DebugRound{}, fmt.Errorf("DebugRound(): pipeline is not running")
+	}
+	res := <-req.result
+	return res.round, res.err
+}
+
+// debugRound is called by the pipeline loop between rounds. The importer is
+// only called again if it is not used by a prefetcher.
+func (p *pipelineImpl) debugRound(req debugRequest, prefetching bool) (DebugRound, error) {
+	result := DebugRound{Round: req.round, Source: debugSourceCache}
+	if req.round >= p.pipelineMetadata.NextRound {
+		return result, fmt.Errorf("DebugRound(): round %d has not been processed, the next round is %d", req.round, p.pipelineMetadata.NextRound)
+	}
+	blk, ok, err := p.debugCache.get(req.round)
+	if err != nil {
+		return result, fmt.Errorf("DebugRound(): %w", err)
+	}
+	if !ok {
+		if prefetching {
+			return result, fmt.Errorf("DebugRound(): round %d is not cached and the importer is used by the prefetcher, increase api debug-rounds to cache more rounds", req.round)
+		}
+		result.Source = debugSourceImporter
+		if blk, err = (*p.importer).GetBlock(req.round); err != nil {
+			return result, fmt.Errorf("DebugRound(): importer (%s) could not fetch round %d: %w", (*p.importer).Metadata().Name, req.round, err)
+		}
+	}
+
+	ids := debugTxnIDs(blk)
+	result.Txns = len(ids)
+	if req.txid != "" {
+		result.Txn = &DebugTxn{ID: req.txid, Imported: ids[req.txid]}
+	}
+	for idx, proc := range p.processors {
+		name := (*proc).Metadata().Name
+		processed := DebugProcessor{Name: name}
+		match, err := matchCondition(p.processorConditions, idx, &blk)
+		if err == nil && !match {
+			processed.Skipped = true
+			processed.Txns = len(ids)
+			result.Processors = append(result.Processors, processed)
+			continue
+		}
+		if err == nil {
+			blk, err = runProcessor(*proc, p.windows[proc], blk, false)
+		}
+		if err != nil {
+			processed.Error = err.Error()
+			result.Processors = append(result.Processors, processed)
+			return result, nil
+		}
+		remaining := debugTxnIDs(blk)
+		for id := range ids {
+			if !remaining[id] {
+				processed.Removed = append(processed.Removed, id)
+				if result.Txn != nil && id == req.txid {
+					result.Txn.RemovedBy = name
+				}
+			}
+		}
+		sort.Strings(processed.Removed)
+		processed.Txns = len(remaining)
+		ids = remaining
+		result.Processors = append(result.Processors, processed)
+	}
+	if result.Txn != nil {
+		result.Txn.Exported = ids[req.txid]
+	}
+	result.Output = sdkjson.Encode(blk)
+	return result, nil
+}
+
+// debugTxnIDs returns the IDs of the top level transactions of the block.
+func debugTxnIDs(blk data.BlockData) map[string]bool {
+	ids := make(map[string]bool, len(blk.Payset))
+	for idx := range blk.Payset {
+		ids[txnID(&blk.BlockHeader, &blk.Payset[idx])] = true
+	}
+	return ids
+}
+
+// txnID computes the ID of a transaction in a block, which omits the genesis
+// ID and hash that are implied by the block header.
+func txnID(header *sdk.BlockHeader, stxn *sdk.SignedTxnInBlock) string {
+	txn := stxn.Txn
+	if stxn.HasGenesisID {
+		txn.GenesisID = header.GenesisID
+	}
+	// Unknown protocols are newer ones, which require the genesis hash.
+	params, ok := config.Consensus[protocol.ConsensusVersion(header.CurrentProtocol)]
+	if stxn.HasGenesisHash || !ok || params.RequireGenesisHash {
+		txn.GenesisHash = header.GenesisHash
+	}
+	return crypto.TransactionIDString(txn)
+}
+
+// handleDebugRound serves /debug/round?round=N with an optional txid.
+func (p *pipelineImpl) handleDebugRound(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query().Get("round")
+	round, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid round: %s", v)})
+		return
+	}
+	result, err := p.DebugRound(round, r.URL.Query().Get("txid"))
+	if err != nil {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
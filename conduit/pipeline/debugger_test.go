@@ -0,0 +1,155 @@
+package pipeline
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// paymentImporter returns blocks with a payment of 1 and a payment of 2.
+type paymentImporter struct {
+	namedImporter
+}
+
+func (r *paymentImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	blk, err := r.namedImporter.GetBlock(rnd)
+	blk.Payset = []sdk.SignedTxnInBlock{makePayment(1), makePayment(2)}
+	blk.Payset[0].Txn.FirstValid = sdk.Round(rnd)
+	blk.Payset[1].Txn.FirstValid = sdk.Round(rnd)
+	return blk, err
+}
+
+// dropProcessor removes the payments of an amount.
+type dropProcessor struct {
+	processors.Processor
+	amount uint64
+}
+
+func (d *dropProcessor) Metadata() conduit.Metadata {
+	return conduit.Metadata{Name: "drop"}
+}
+
+func (d *dropProcessor) Process(input data.BlockData) (data.BlockData, error) {
+	payset := input.Payset[:0]
+	for _, stxn := range input.Payset {
+		if uint64(stxn.Txn.Amount) != d.amount {
+			payset = append(payset, stxn)
+		}
+	}
+	input.Payset = payset
+	return input, nil
+}
+
+func (d *dropProcessor) Close() error {
+	return nil
+}
+
+func TestDebugCache(t *testing.T) {
+	assert.Nil(t, makeDebugCache(0))
+	var none *debugCache
+	_, ok, err := none.get(1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	cache := makeDebugCache(2)
+	for rnd := uint64(0); rnd < 3; rnd++ {
+		cache.add(data.BlockData{BlockHeader: sdk.BlockHeader{Round: sdk.Round(rnd)}, Payset: []sdk.SignedTxnInBlock{makePayment(rnd)}})
+	}
+	_, ok, err = cache.get(0)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	blk, ok, err := cache.get(2)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, sdk.MicroAlgos(2), blk.Payset[0].Txn.Amount)
+	// The cached block is a copy.
+	blk.Payset[0].Txn.Amount = 5
+	blk, _, _ = cache.get(2)
+	assert.Equal(t, sdk.MicroAlgos(2), blk.Payset[0].Txn.Amount)
+}
+
+// TestPipelineDebugRound tests that the processors run again on cached and
+// fetched rounds, and that the removed transactions are reported.
+func TestPipelineDebugRound(t *testing.T) {
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeCheckpointPipeline(t, exp)
+	var pImporter importers.Importer = &paymentImporter{namedImporter{roundImporter{failRound: 1000}}}
+	pImpl.importer = &pImporter
+	var proc processors.Processor = &dropProcessor{amount: 2}
+	pImpl.processors = []*processors.Processor{&proc}
+	pImpl.debugCache = makeDebugCache(2)
+
+	_, err := pImpl.DebugRound(1, "")
+	assert.EqualError(t, err, "DebugRound(): pipeline is not running")
+
+	pImpl.Pause()
+	pImpl.Start()
+	defer func() {
+		pImpl.cf()
+		pImpl.Wait()
+	}()
+	require.NoError(t, pImpl.Step(4))
+	require.Equal(t, 4, waitHeld(t, exp))
+
+	imported, _ := pImporter.GetBlock(3)
+	kept := txnID(&imported.BlockHeader, &imported.Payset[0])
+	dropped := txnID(&imported.BlockHeader, &imported.Payset[1])
+	result, err := pImpl.DebugRound(3, dropped)
+	require.NoError(t, err)
+	assert.Equal(t, debugSourceCache, result.Source)
+	assert.Equal(t, 2, result.Txns)
+	assert.Equal(t, []DebugProcessor{{Name: "drop", Txns: 1, Removed: []string{dropped}}}, result.Processors)
+	assert.Equal(t, &DebugTxn{ID: dropped, Imported: true, RemovedBy: "drop"}, result.Txn)
+	assert.NotEmpty(t, result.Output)
+
+	result, err = pImpl.DebugRound(3, kept)
+	require.NoError(t, err)
+	assert.Equal(t, &DebugTxn{ID: kept, Imported: true, Exported: true}, result.Txn)
+
+	// Rounds which are not cached are fetched again.
+	result, err = pImpl.DebugRound(0, "")
+	require.NoError(t, err)
+	assert.Equal(t, debugSourceImporter, result.Source)
+	assert.Nil(t, result.Txn)
+
+	_, err = pImpl.DebugRound(4, "")
+	assert.EqualError(t, err, "DebugRound(): round 4 has not been processed, the next round is 4")
+	assert.Equal(t, 4, len(exp.received()))
+}
+
+func TestDebugRoundAPI(t *testing.T) {
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeCheckpointPipeline(t, exp)
+	mux := http.NewServeMux()
+	pImpl.registerAPIHandlers(mux)
+	request := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusBadRequest, request("/debug/round").Code)
+	assert.Equal(t, http.StatusConflict, request("/debug/round?round=1").Code)
+
+	pImpl.Pause()
+	pImpl.Start()
+	defer func() {
+		pImpl.cf()
+		pImpl.Wait()
+	}()
+	require.NoError(t, pImpl.Step(2))
+	waitHeld(t, exp)
+	rec := request("/debug/round?round=1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"source":"importer"`)
+}
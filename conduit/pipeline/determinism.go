@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+const (
+	// mismatchStop stops the pipeline when a processor is not deterministic.
+	mismatchStop = "stop"
+	// mismatchWarn logs a warning and continues with the output of the first run.
+	mismatchWarn = "warn"
+)
+
+// DeterminismCheck configs for replaying processors to detect non-deterministic output.
+type DeterminismCheck struct {
+	// Interval replays every Nth round, 0 disables the check.
+	Interval uint64 `yaml:"interval"`
+	// OnMismatch is either "stop" (default) or "warn".
+	OnMismatch string `yaml:"on-mismatch"`
+}
+
+// Valid validates the determinism check config.
+func (dc DeterminismCheck) Valid() error {
+	switch dc.OnMismatch {
+	case "", mismatchStop, mismatchWarn:
+		return nil
+	default:
+		return fmt.Errorf("on-mismatch must be '%s' or '%s', found '%s'", mismatchStop, mismatchWarn, dc.OnMismatch)
+	}
+}
+
+// replay reports whether the processors should be replayed for the round.
+func (dc DeterminismCheck) replay(round uint64) bool {
+	return dc.Interval > 0 && round%dc.Interval == 0
+}
+
+// mismatchError is returned when a replayed processor produces different output.
+type mismatchError struct {
+	processor string
+	round     uint64
+}
+
+func (e mismatchError) Error() string {
+	return fmt.Sprintf("processor (%s) is not deterministic, replaying round %d produced different output", e.processor, e.round)
+}
+
+// processReplayed runs the processor on two copies of the block and compares the
+// results. Copies are used because processors are allowed to modify their input.
+// The window of a WindowProcessor only advances with the first run.
+func processReplayed(proc processors.Processor, w *roundWindow, blk data.BlockData) (data.BlockData, error) {
+	input := msgpack.Encode(blk)
+	var first, second data.BlockData
+	if err := msgpack.Decode(input, &first); err != nil {
+		return blk, fmt.Errorf("processReplayed(): unable to copy block: %w", err)
+	}
+	if err := msgpack.Decode(input, &second); err != nil {
+		return blk, fmt.Errorf("processReplayed(): unable to copy block: %w", err)
+	}
+
+	first, err := runProcessor(proc, w, first, true)
+	if err != nil {
+		return first, err
+	}
+	second, err = runProcessor(proc, w, second, false)
+	if err != nil {
+		return first, fmt.Errorf("processReplayed(): processor (%s) failed when replayed: %w", proc.Metadata().Name, err)
+	}
+
+	if !bytes.Equal(msgpack.Encode(first), msgpack.Encode(second)) {
+		return first, mismatchError{processor: proc.Metadata().Name, round: blk.Round()}
+	}
+	return first, nil
+}
+
+// checkMismatch records a determinism failure. It returns true if the pipeline should stop.
+func (p *pipelineImpl) checkMismatch(err mismatchError) bool {
+	metrics.ProcessorMismatches.WithLabelValues(metrics.ProcessorLabel(p.cfg.Metrics.ProcessorLabels, err.processor)).Inc()
+	if p.cfg.DeterminismCheck.OnMismatch == mismatchWarn {
+		p.logger.Warnf("%v", err)
+		return false
+	}
+	p.logger.Errorf("%v - stopping...", err)
+	p.setError(err)
+	p.writeSupportBundle(err.Error())
+	return true
+}
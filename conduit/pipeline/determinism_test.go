@@ -0,0 +1,126 @@
+package pipeline
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// counterProcessor modifies its input and, when nondeterministic, stamps each
+// block with the number of times it has been called.
+type counterProcessor struct {
+	processors.Processor
+	nondeterministic bool
+	calls            uint64
+}
+
+func (c *counterProcessor) Metadata() conduit.Metadata {
+	return conduit.Metadata{Name: "counter"}
+}
+
+func (c *counterProcessor) Process(input data.BlockData) (data.BlockData, error) {
+	c.calls++
+	input.Payset = append(input.Payset[:0], makePayment(input.Round()))
+	if c.nondeterministic {
+		input.BlockHeader.TimeStamp = int64(c.calls)
+	}
+	return input, nil
+}
+
+func (c *counterProcessor) Close() error {
+	return nil
+}
+
+func TestDeterminismCheckValid(t *testing.T) {
+	assert.NoError(t, DeterminismCheck{}.Valid())
+	assert.NoError(t, DeterminismCheck{OnMismatch: "warn"}.Valid())
+	assert.ErrorContains(t, DeterminismCheck{OnMismatch: "panic"}.Valid(), "on-mismatch must be 'stop' or 'warn', found 'panic'")
+
+	assert.False(t, DeterminismCheck{}.replay(0))
+	assert.True(t, DeterminismCheck{Interval: 1}.replay(7))
+	assert.True(t, DeterminismCheck{Interval: 10}.replay(20))
+	assert.False(t, DeterminismCheck{Interval: 10}.replay(21))
+}
+
+func TestProcessReplayed(t *testing.T) {
+	input := data.BlockData{
+		BlockHeader: sdk.BlockHeader{Round: 5},
+		Payset:      []sdk.SignedTxnInBlock{makeAppCall(1)},
+	}
+
+	proc := &counterProcessor{}
+	out, err := processReplayed(proc, nil, input)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), proc.calls)
+	assert.Equal(t, sdk.MicroAlgos(5), out.Payset[0].Txn.Amount)
+	// The input is not modified by the processor.
+	assert.Equal(t, sdk.AppIndex(1), input.Payset[0].Txn.ApplicationID)
+
+	_, err = processReplayed(&counterProcessor{nondeterministic: true}, nil, input)
+	assert.Equal(t, mismatchError{processor: "counter", round: 5}, err)
+	assert.EqualError(t, err, "processor (counter) is not deterministic, replaying round 5 produced different output")
+}
+
+func makeReplayPipeline(t *testing.T, proc processors.Processor, check DeterminismCheck) (*pipelineImpl, *roundExporter) {
+	var pImporter importers.Importer = &roundImporter{}
+	exp := &roundExporter{name: "exporter"}
+	var pExporter exporters.Exporter = exp
+
+	ctx, cf := context.WithCancel(context.Background())
+	l, _ := test.NewNullLogger()
+	return &pipelineImpl{
+		ctx:        ctx,
+		cf:         cf,
+		logger:     l,
+		importer:   &pImporter,
+		processors: []*processors.Processor{&proc},
+		exporters:  []*exporters.Exporter{&pExporter},
+		cfg: &Config{
+			RetryCount: math.MaxUint64,
+			ConduitArgs: &conduit.Args{
+				ConduitDataDir: t.TempDir(),
+			},
+			DeterminismCheck: check,
+		},
+	}, exp
+}
+
+// TestPipelineDeterminismStop tests that a mismatch stops the pipeline before the round is exported.
+func TestPipelineDeterminismStop(t *testing.T) {
+	pImpl, exp := makeReplayPipeline(t, &counterProcessor{nondeterministic: true}, DeterminismCheck{Interval: 3})
+	pImpl.pipelineMetadata.NextRound = 1
+
+	pImpl.Start()
+	pImpl.Wait()
+
+	assert.Equal(t, []uint64{1, 2}, exp.received())
+	assert.ErrorContains(t, pImpl.Error(), "processor (counter) is not deterministic, replaying round 3")
+	assert.Equal(t, uint64(3), pImpl.pipelineMetadata.NextRound)
+}
+
+// TestPipelineDeterminismWarn tests that a mismatch is only logged in warn mode.
+func TestPipelineDeterminismWarn(t *testing.T) {
+	pImpl, exp := makeReplayPipeline(t, &counterProcessor{nondeterministic: true}, DeterminismCheck{Interval: 1, OnMismatch: mismatchWarn})
+
+	pImpl.Start()
+	require.Eventually(t, func() bool {
+		return len(exp.received()) >= 3
+	}, 5*time.Second, time.Millisecond)
+	pImpl.cf()
+	pImpl.Wait()
+
+	assert.Equal(t, []uint64{0, 1, 2}, exp.received()[:3])
+	assert.NoError(t, pImpl.Error())
+}
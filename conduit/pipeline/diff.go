@@ -0,0 +1,311 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	sdkjson "github.com/algorand/go-algorand-sdk/v2/encoding/json"
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+)
+
+// The statuses of a RoundDiff.
+const (
+	// DiffAdded is a round which the config exports and the exporter does not have.
+	DiffAdded = "added"
+	// DiffRemoved is a round which the exporter has and the config does not export.
+	DiffRemoved = "removed"
+	// DiffChanged is a round which differs from the one the exporter has.
+	DiffChanged = "changed"
+)
+
+// diffIgnoredFields are the block fields which are not compared, the payset
+// is compared by transaction and the certificate is non deterministic.
+var diffIgnoredFields = map[string]bool{"payset": true, "cert": true}
+
+// DiffReport compares the output of a config for a range of rounds with the
+// data which the exporters already have.
+type DiffReport struct {
+	FirstRound uint64         `json:"first-round"`
+	LastRound  uint64         `json:"last-round"`
+	Exporters  []ExporterDiff `json:"exporters"`
+}
+
+// ExporterDiff are the differences of an exporter.
+type ExporterDiff struct {
+	Name string `json:"name"`
+	// Unsupported is set if the exporter does not implement
+	// conduit.RoundReader, its rounds are not compared.
+	Unsupported bool `json:"unsupported,omitempty"`
+	// Compared is the number of rounds which were compared.
+	Compared int `json:"compared"`
+	// Rounds are the rounds which differ.
+	Rounds []RoundDiff `json:"rounds,omitempty"`
+}
+
+// RoundDiff are the differences of a round.
+type RoundDiff struct {
+	Round uint64 `json:"round"`
+	// Status is "added", "removed" or "changed".
+	Status string `json:"status"`
+	// Added and Removed are the IDs of the top level transactions which only
+	// the config or only the exporter has, Changed the IDs of the
+	// transactions which both have with different contents.
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	// Fields are the other block fields which differ, such as the header or
+	// the annotations added by processors.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// Changed reports whether any round differs.
+func (r DiffReport) Changed() bool {
+	for _, exp := range r.Exporters {
+		if len(exp.Rounds) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Write prints the report.
+func (r DiffReport) Write(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "rounds %d to %d\n", r.FirstRound, r.LastRound)
+	for _, exp := range r.Exporters {
+		switch {
+		case exp.Unsupported:
+			fmt.Fprintf(&sb, "exporter (%s): not compared, it cannot read its rounds\n", exp.Name)
+			continue
+		case len(exp.Rounds) == 0:
+			fmt.Fprintf(&sb, "exporter (%s): %d rounds unchanged\n", exp.Name, exp.Compared)
+			continue
+		}
+		fmt.Fprintf(&sb, "exporter (%s): %d of %d rounds differ\n", exp.Name, len(exp.Rounds), exp.Compared)
+		for _, round := range exp.Rounds {
+			fmt.Fprintf(&sb, "  round %d %s", round.Round, round.Status)
+			if round.Status == DiffChanged {
+				fmt.Fprintf(&sb, ": %d added, %d removed, %d changed transactions", len(round.Added), len(round.Removed), len(round.Changed))
+			}
+			sb.WriteString("\n")
+			writeDiffIDs(&sb, "+", round.Added)
+			writeDiffIDs(&sb, "-", round.Removed)
+			writeDiffIDs(&sb, "~", round.Changed)
+			if len(round.Fields) > 0 {
+				fmt.Fprintf(&sb, "    fields: %s\n", strings.Join(round.Fields, ", "))
+			}
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeDiffIDs(sb *strings.Builder, prefix string, ids []string) {
+	for _, id := range ids {
+		fmt.Fprintf(sb, "    %s %s\n", prefix, id)
+	}
+}
+
+// DiffRounds initializes the pipeline like a dry run, processes the rounds
+// from first to last and compares the output of each exporter with the rounds
+// it already has, instead of exporting them. Only exporters which implement
+// conduit.RoundReader are compared. Nothing is written, but processors which
+// keep state across rounds see the rounds again.
+func DiffRounds(ctx context.Context, cfg *Config, logger *log.Logger, first, last uint64) (DiffReport, error) {
+	report := DiffReport{FirstRound: first, LastRound: last}
+	if first > last {
+		return report, fmt.Errorf("DiffRounds(): first round (%d) must not be after last round (%d)", first, last)
+	}
+	p, err := MakePipeline(ctx, cfg, logger)
+	if err != nil {
+		return report, fmt.Errorf("DiffRounds(): %w", err)
+	}
+	impl := p.(*pipelineImpl)
+	impl.dryRun = true
+	if err = p.Init(); err != nil {
+		return report, fmt.Errorf("DiffRounds(): %w", err)
+	}
+	defer p.Stop()
+	if err = impl.diffRounds(&report); err != nil {
+		return report, fmt.Errorf("DiffRounds(): %w", err)
+	}
+	return report, nil
+}
+
+// diffRounds fills the report for its rounds.
+func (p *pipelineImpl) diffRounds(report *DiffReport) error {
+	readers := make([]conduit.RoundReader, len(p.exporters))
+	for idx, exporter := range p.exporters {
+		diff := ExporterDiff{Name: p.exporterID(idx)}
+		if reader, ok := (*exporter).(conduit.RoundReader); ok {
+			readers[idx] = reader
+		} else {
+			diff.Unsupported = true
+		}
+		report.Exporters = append(report.Exporters, diff)
+	}
+
+	for round := report.FirstRound; round <= report.LastRound; round++ {
+		if err := p.ctx.Err(); err != nil {
+			return err
+		}
+		blk, err := (*p.importer).GetBlock(round)
+		if err != nil {
+			return fmt.Errorf("importer (%s) could not fetch round %d: %w", (*p.importer).Metadata().Name, round, err)
+		}
+		blk, err = p.diffProcess(blk)
+		if err != nil {
+			return fmt.Errorf("round %d: %w", round, err)
+		}
+		for idx := range p.exporters {
+			if readers[idx] == nil {
+				continue
+			}
+			name := report.Exporters[idx].Name
+			output, exported, err := p.diffOutput(idx, blk)
+			if err != nil {
+				return fmt.Errorf("round %d: exporter (%s): %w", round, name, err)
+			}
+			existing, ok, err := readers[idx].ReadRound(round)
+			if err != nil {
+				return fmt.Errorf("exporter (%s) could not read round %d: %w", name, round, err)
+			}
+			report.Exporters[idx].Compared++
+			var existingBlk *data.BlockData
+			if ok {
+				existingBlk = &existing
+			}
+			diff, err := diffBlocks(round, output, existingBlk, exported)
+			if err != nil {
+				return fmt.Errorf("round %d: exporter (%s): %w", round, name, err)
+			}
+			if diff != nil {
+				report.Exporters[idx].Rounds = append(report.Exporters[idx].Rounds, *diff)
+			}
+		}
+	}
+	return nil
+}
+
+// diffProcess runs the shared processors on the block.
+func (p *pipelineImpl) diffProcess(blk data.BlockData) (data.BlockData, error) {
+	for idx, proc := range p.processors {
+		match, err := matchCondition(p.processorConditions, idx, &blk)
+		if err != nil {
+			return blk, err
+		}
+		if !match {
+			continue
+		}
+		blk, err = runProcessor(*proc, p.windows[proc], blk, false)
+		if err != nil {
+			return blk, fmt.Errorf("processor (%s): %w", (*proc).Metadata().Name, err)
+		}
+	}
+	return blk, nil
+}
+
+// diffOutput returns the block which the exporter at idx would receive, and
+// false if it would not receive the round.
+func (p *pipelineImpl) diffOutput(idx int, blk data.BlockData) (data.BlockData, bool, error) {
+	match, err := matchCondition(p.exporterConditions, idx, &blk)
+	if err != nil || !match {
+		return blk, false, err
+	}
+	exportBlk, match, err := p.laneBlock(idx, blk)
+	if err != nil || !match {
+		return blk, false, err
+	}
+	exportBlk, err = p.processForExporter(idx, nil, exportBlk)
+	return exportBlk, err == nil, err
+}
+
+// diffBlocks compares the output of a round with the block the exporter has,
+// existing is nil if the exporter does not have the round. It returns nil if
+// they do not differ.
+func diffBlocks(round uint64, output data.BlockData, existing *data.BlockData, exported bool) (*RoundDiff, error) {
+	switch {
+	case existing == nil && !exported:
+		return nil, nil
+	case existing == nil:
+		return &RoundDiff{Round: round, Status: DiffAdded}, nil
+	case !exported:
+		return &RoundDiff{Round: round, Status: DiffRemoved}, nil
+	}
+
+	diff := RoundDiff{Round: round, Status: DiffChanged}
+	outputTxns := diffTxns(output)
+	existingTxns := diffTxns(*existing)
+	for id, encoded := range outputTxns {
+		existingEncoded, ok := existingTxns[id]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, id)
+		case !bytes.Equal(encoded, existingEncoded):
+			diff.Changed = append(diff.Changed, id)
+		}
+	}
+	for id := range existingTxns {
+		if _, ok := outputTxns[id]; !ok {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	outputFields, err := diffFields(output)
+	if err != nil {
+		return nil, err
+	}
+	existingFields, err := diffFields(*existing)
+	if err != nil {
+		return nil, err
+	}
+	for name, encoded := range outputFields {
+		if !bytes.Equal(encoded, existingFields[name]) {
+			diff.Fields = append(diff.Fields, name)
+		}
+	}
+	for name := range existingFields {
+		if _, ok := outputFields[name]; !ok {
+			diff.Fields = append(diff.Fields, name)
+		}
+	}
+	sort.Strings(diff.Fields)
+
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed)+len(diff.Fields) == 0 {
+		return nil, nil
+	}
+	return &diff, nil
+}
+
+// diffTxns returns the encoded top level transactions of a block by ID.
+func diffTxns(blk data.BlockData) map[string][]byte {
+	txns := make(map[string][]byte, len(blk.Payset))
+	for idx := range blk.Payset {
+		txns[txnID(&blk.BlockHeader, &blk.Payset[idx])] = msgpack.Encode(blk.Payset[idx])
+	}
+	return txns
+}
+
+// diffFields returns the JSON encoding of each compared block field.
+func diffFields(blk data.BlockData) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(sdkjson.Encode(blk), &fields); err != nil {
+		return nil, fmt.Errorf("unable to encode round %d: %w", blk.Round(), err)
+	}
+	for name := range diffIgnoredFields {
+		delete(fields, name)
+	}
+	return fields, nil
+}
@@ -0,0 +1,105 @@
+package pipeline
+
+import (
+	"bytes"
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+)
+
+// readerExporter has the blocks of the rounds it exported.
+type readerExporter struct {
+	roundExporter
+	blocks map[uint64]data.BlockData
+}
+
+func (r *readerExporter) ReadRound(round uint64) (data.BlockData, bool, error) {
+	blk, ok := r.blocks[round]
+	return blk, ok, nil
+}
+
+func diffTxn(sender byte, amount uint64) sdk.SignedTxnInBlock {
+	var stxn sdk.SignedTxnInBlock
+	stxn.Txn.Type = sdk.PaymentTx
+	stxn.Txn.Sender = sdk.Address{sender}
+	stxn.Txn.Amount = sdk.MicroAlgos(amount)
+	return stxn
+}
+
+func TestDiffBlocks(t *testing.T) {
+	blk := data.BlockData{BlockHeader: sdk.BlockHeader{Round: 5}, Payset: []sdk.SignedTxnInBlock{diffTxn(1, 10), diffTxn(2, 20)}}
+	id := func(stxn sdk.SignedTxnInBlock) string {
+		return txnID(&blk.BlockHeader, &stxn)
+	}
+
+	diff, err := diffBlocks(5, blk, nil, false)
+	require.NoError(t, err)
+	assert.Nil(t, diff, "neither has the round")
+	diff, err = diffBlocks(5, blk, nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, &RoundDiff{Round: 5, Status: DiffAdded}, diff)
+	diff, err = diffBlocks(5, blk, &blk, false)
+	require.NoError(t, err)
+	assert.Equal(t, &RoundDiff{Round: 5, Status: DiffRemoved}, diff)
+
+	existing := blk
+	existing.Certificate = &map[string]interface{}{"vote": 1}
+	diff, err = diffBlocks(5, blk, &existing, true)
+	require.NoError(t, err)
+	assert.Nil(t, diff, "the certificate is not compared")
+
+	// The output removed the second transaction, changed the first one and
+	// added a third one and account events.
+	output := blk
+	changed := diffTxn(1, 10)
+	changed.ApplyData.SenderRewards = 1
+	added := diffTxn(3, 30)
+	output.Payset = []sdk.SignedTxnInBlock{changed, added}
+	output.AccountEvents = []data.AccountEvent{{Type: data.AccountCreated, Round: 5}}
+	diff, err = diffBlocks(5, output, &blk, true)
+	require.NoError(t, err)
+	assert.Equal(t, &RoundDiff{
+		Round:   5,
+		Status:  DiffChanged,
+		Added:   []string{id(added)},
+		Removed: []string{id(diffTxn(2, 20))},
+		Changed: []string{id(changed)},
+		Fields:  []string{"account-events"},
+	}, diff)
+}
+
+func TestDiffRounds(t *testing.T) {
+	reader := &readerExporter{
+		roundExporter: roundExporter{name: "reader"},
+		blocks: map[uint64]data.BlockData{
+			0: {BlockHeader: sdk.BlockHeader{Round: 0}},
+			2: {BlockHeader: sdk.BlockHeader{Round: 2}, Payset: []sdk.SignedTxnInBlock{diffTxn(1, 10)}},
+		},
+	}
+	pImpl := makeFailurePipeline(t, &roundImporter{failRound: 1000}, &roundExporter{name: "plain"}, "")
+	var pReader exporters.Exporter = reader
+	pImpl.exporters = append(pImpl.exporters, &pReader)
+
+	report := DiffReport{FirstRound: 0, LastRound: 2}
+	require.NoError(t, pImpl.diffRounds(&report))
+	removed := reader.blocks[2]
+	assert.Equal(t, []ExporterDiff{
+		{Name: "plain", Unsupported: true},
+		{Name: "reader", Compared: 3, Rounds: []RoundDiff{
+			{Round: 1, Status: DiffAdded},
+			{Round: 2, Status: DiffChanged, Removed: []string{txnID(&removed.BlockHeader, &removed.Payset[0])}},
+		}},
+	}, report.Exporters)
+	assert.True(t, report.Changed())
+	assert.Empty(t, reader.received(), "nothing is exported")
+
+	var out bytes.Buffer
+	require.NoError(t, report.Write(&out))
+	assert.Contains(t, out.String(), "exporter (plain): not compared, it cannot read its rounds\n")
+	assert.Contains(t, out.String(), "exporter (reader): 2 of 3 rounds differ\n  round 1 added\n  round 2 changed: 0 added, 1 removed, 0 changed transactions\n    - ")
+}
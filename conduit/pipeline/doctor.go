@@ -0,0 +1,313 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/algorand/indexer/util"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/statestore"
+)
+
+// minFreeSpace is the free space below which the doctor warns about the disk.
+const minFreeSpace = 1 << 30
+
+// DoctorStatus is the outcome of a doctor check.
+type DoctorStatus string
+
+// The doctor check outcomes.
+const (
+	DoctorOK       DoctorStatus = "ok"
+	DoctorWarning  DoctorStatus = "warning"
+	DoctorError    DoctorStatus = "error"
+	DoctorRepaired DoctorStatus = "repaired"
+)
+
+// DoctorFinding is the result of one check of the data directory.
+type DoctorFinding struct {
+	Check   string       `json:"check"`
+	Status  DoctorStatus `json:"status"`
+	Message string       `json:"message"`
+}
+
+// DoctorReport lists the findings of Doctor.
+type DoctorReport struct {
+	DataDir  string          `json:"data-dir"`
+	Findings []DoctorFinding `json:"findings"`
+}
+
+// Healthy reports whether no check found an error.
+func (r DoctorReport) Healthy() bool {
+	for _, f := range r.Findings {
+		if f.Status == DoctorError {
+			return false
+		}
+	}
+	return true
+}
+
+// Write prints the report, one line per finding.
+func (r DoctorReport) Write(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "Data directory: %s\n", r.DataDir); err != nil {
+		return err
+	}
+	for _, f := range r.Findings {
+		if _, err := fmt.Fprintf(w, "[%-8s] %-12s %s\n", strings.ToUpper(string(f.Status)), f.Check, f.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// doctor runs the checks of a data directory.
+type doctor struct {
+	dataDir string
+	// repair is cleared when conduit is running.
+	repair bool
+	cfg    *Config
+	report DoctorReport
+}
+
+func (d *doctor) add(check string, status DoctorStatus, format string, args ...interface{}) {
+	d.report.Findings = append(d.report.Findings, DoctorFinding{Check: check, Status: status, Message: fmt.Sprintf(format, args...)})
+}
+
+// remove deletes a leftover file when repairing, otherwise it reports it.
+func (d *doctor) remove(check, filename, reason string) {
+	if !d.repair {
+		d.add(check, DoctorWarning, "%s %s, use --repair to remove it", filename, reason)
+		return
+	}
+	if err := os.Remove(filename); err != nil {
+		d.add(check, DoctorError, "%s %s, unable to remove it: %v", filename, reason, err)
+		return
+	}
+	d.add(check, DoctorRepaired, "removed %s, it %s", filename, reason)
+}
+
+// Doctor inspects the conduit data directory: the configuration, stale locks,
+// the pipeline metadata and its commit intents, plugin directories, leftover
+// temporary files, disk space and permissions. When repair is set the problems
+// which can be fixed without losing data are repaired, unless conduit is
+// running.
+func Doctor(dataDir string, repair bool) DoctorReport {
+	d := &doctor{dataDir: dataDir, repair: repair, report: DoctorReport{DataDir: dataDir}}
+	if !util.IsDir(dataDir) {
+		d.add("data-dir", DoctorError, "%s is not a directory", dataDir)
+		return d.report
+	}
+
+	var err error
+	d.cfg, err = MakePipelineConfig(&conduit.Args{ConduitDataDir: dataDir})
+	if err != nil {
+		d.add("config", DoctorError, "%v", err)
+		d.cfg = nil
+	} else {
+		d.add("config", DoctorOK, "the configuration is valid")
+	}
+
+	d.checkLock()
+	d.checkPermissions()
+	d.checkDiskSpace()
+	d.checkMetadata()
+	d.checkPluginDirs()
+	return d.report
+}
+
+// checkLock checks the pid file, a pid file of a process which no longer runs
+// is stale. Nothing is repaired while conduit is running.
+func (d *doctor) checkLock() {
+	if d.cfg == nil || d.cfg.PIDFilePath == "" {
+		return
+	}
+	b, err := os.ReadFile(d.cfg.PIDFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		d.add("lock", DoctorOK, "conduit is not running")
+		return
+	}
+	if err != nil {
+		d.add("lock", DoctorError, "unable to read %s: %v", d.cfg.PIDFilePath, err)
+		d.repair = false
+		return
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || pid <= 0 {
+		d.add("lock", DoctorWarning, "%s does not contain a pid, remove it if conduit is not running", d.cfg.PIDFilePath)
+		d.repair = false
+		return
+	}
+	if alive, known := processAlive(pid); alive || !known {
+		d.add("lock", DoctorWarning, "conduit may be running (pid %d), nothing is repaired", pid)
+		d.repair = false
+		return
+	}
+	d.remove("lock", d.cfg.PIDFilePath, fmt.Sprintf("is stale, process %d is not running", pid))
+}
+
+// checkPermissions checks that the data directory is writable and that the
+// configuration, which may contain credentials, is private.
+func (d *doctor) checkPermissions() {
+	f, err := os.CreateTemp(d.dataDir, ".doctor-*")
+	if err != nil {
+		d.add("permissions", DoctorError, "the data directory is not writable: %v", err)
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	configPath, err := util.GetConfigFromDataDir(d.dataDir, conduit.DefaultConfigBaseName, []string{"yml", "yaml"})
+	if err == nil && configPath != "" {
+		if info, err := os.Stat(configPath); err == nil && info.Mode().Perm()&0o077 != 0 {
+			d.add("permissions", DoctorWarning, "%s is accessible by other users (%s) and may contain credentials, consider chmod 600", configPath, info.Mode().Perm())
+			return
+		}
+	}
+	d.add("permissions", DoctorOK, "the data directory is writable")
+}
+
+// checkDiskSpace checks the free space of the data directory's file system.
+func (d *doctor) checkDiskSpace() {
+	free, ok := freeSpace(d.dataDir)
+	if !ok {
+		d.add("disk", DoctorWarning, "free space cannot be checked on this platform")
+		return
+	}
+	gib := float64(free) / (1 << 30)
+	if free < minFreeSpace {
+		d.add("disk", DoctorWarning, "only %.2f GiB free", gib)
+		return
+	}
+	d.add("disk", DoctorOK, "%.1f GiB free", gib)
+}
+
+// checkMetadata checks that metadata.json is valid, removes a temporary file
+// left by an interrupted save and drops commit intents of finished rounds.
+func (d *doctor) checkMetadata() {
+	if d.cfg != nil && d.cfg.StateStore.Type != "" && d.cfg.StateStore.Type != statestore.FileType {
+		d.add("metadata", DoctorOK, "the state is stored in %s and is not checked", d.cfg.StateStore.Type)
+		return
+	}
+	metadataPath := statestore.MetadataPath(d.dataDir)
+	if _, err := os.Stat(metadataPath + ".temp"); err == nil {
+		d.remove("metadata", metadataPath+".temp", "was left by an interrupted save")
+	}
+
+	store := statestore.MakeFileStore(metadataPath)
+	b, err := store.Load(context.Background())
+	if err != nil {
+		d.add("metadata", DoctorError, "%v", err)
+		return
+	}
+	if b == nil {
+		d.add("metadata", DoctorOK, "metadata.json has not been created yet")
+		return
+	}
+	var md state
+	if err = json.Unmarshal(b, &md); err != nil {
+		d.add("metadata", DoctorError, "metadata.json is not valid: %v. Restore it from a backup, or remove it and start with --next-round-override", err)
+		return
+	}
+	if md.savedNetworkID() == "" {
+		d.add("metadata", DoctorError, "metadata.json has no network ID or genesis hash. Restore it from a backup, or remove it and start with --next-round-override")
+		return
+	}
+	d.add("metadata", DoctorOK, "next round %d, network %s", md.NextRound, md.Network)
+
+	// Intents of earlier rounds were committed by every exporter, they are
+	// normally dropped once the round completes.
+	var pending, stale []commitIntent
+	for _, intent := range md.CommitIntents {
+		if intent.Round < md.NextRound {
+			stale = append(stale, intent)
+		} else {
+			pending = append(pending, intent)
+		}
+	}
+	if len(pending) > 0 {
+		d.add("intents", DoctorOK, "%d commit intents of round %d are recovered when conduit starts", len(pending), md.NextRound)
+	}
+	if len(stale) == 0 {
+		return
+	}
+	if !d.repair {
+		d.add("intents", DoctorWarning, "%d commit intents of rounds before %d are stale, use --repair to drop them", len(stale), md.NextRound)
+		return
+	}
+	md.CommitIntents = pending
+	var buf bytes.Buffer
+	if err = json.NewEncoder(&buf).Encode(md); err == nil {
+		err = store.Save(context.Background(), buf.Bytes())
+	}
+	if err != nil {
+		d.add("intents", DoctorError, "unable to drop %d stale commit intents: %v", len(stale), err)
+		return
+	}
+	d.add("intents", DoctorRepaired, "dropped %d stale commit intents of rounds before %d", len(stale), md.NextRound)
+}
+
+// checkPluginDirs reports plugin directories of plugins which are not
+// configured, and removes temporary files left in the configured ones by
+// interrupted writes.
+func (d *doctor) checkPluginDirs() {
+	if d.cfg == nil {
+		return
+	}
+	// Plugin directories are named by makeConfig.
+	expected := map[string]bool{fmt.Sprintf("%s_%s", plugins.Importer, d.cfg.Importer.Name): true}
+	for _, pair := range d.cfg.Processors {
+		expected[fmt.Sprintf("%s_%s", plugins.Processor, pair.Name)] = true
+	}
+	for _, id := range d.cfg.exporterIDs() {
+		expected[fmt.Sprintf("%s_%s", plugins.Exporter, id)] = true
+	}
+	for _, pair := range d.cfg.Observers {
+		expected[fmt.Sprintf("%s_%s", plugins.Observer, pair.Name)] = true
+	}
+
+	entries, err := os.ReadDir(d.dataDir)
+	if err != nil {
+		d.add("plugins", DoctorError, "unable to list the data directory: %v", err)
+		return
+	}
+	findings := len(d.report.Findings)
+	dirs := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		isPluginDir := strings.HasPrefix(name, plugins.Importer+"_") ||
+			strings.HasPrefix(name, plugins.Processor+"_") ||
+			strings.HasPrefix(name, plugins.Exporter+"_") ||
+			strings.HasPrefix(name, plugins.Observer+"_")
+		if !entry.IsDir() || !isPluginDir {
+			continue
+		}
+		dirs++
+		if !expected[name] {
+			d.add("plugins", DoctorWarning, "%s belongs to a plugin which is not configured, remove it once its state is no longer needed", name)
+			continue
+		}
+		dir := path.Join(d.dataDir, name)
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			d.add("plugins", DoctorError, "unable to list %s: %v", dir, err)
+			continue
+		}
+		for _, file := range files {
+			if !file.IsDir() && (strings.HasSuffix(file.Name(), ".tmp") || strings.HasSuffix(file.Name(), ".temp")) {
+				d.remove("plugins", path.Join(dir, file.Name()), "was left by an interrupted write")
+			}
+		}
+	}
+	if len(d.report.Findings) == findings {
+		d.add("plugins", DoctorOK, "%d plugin directories checked", dirs)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package pipeline
+
+// freeSpace is not supported on this platform.
+func freeSpace(string) (uint64, bool) {
+	return 0, false
+}
+
+// processAlive is not supported on this platform, the process is never known
+// to be stopped.
+func processAlive(int) (alive bool, known bool) {
+	return false, false
+}
@@ -0,0 +1,145 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/statestore"
+)
+
+// makeDoctorDataDir writes a config with a pid file and the metadata.
+func makeDoctorDataDir(t *testing.T, md *state) string {
+	dataDir := t.TempDir()
+	cfg := Config{
+		PIDFilePath: path.Join(dataDir, "conduit.pid"),
+		Importer:    NameConfigPair{Name: "algod"},
+		Exporter:    NameConfigPair{Name: "file_writer"},
+	}
+	b, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path.Join(dataDir, conduit.DefaultConfigName), b, 0600))
+	if md != nil {
+		b, err = json.Marshal(md)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(statestore.MetadataPath(dataDir), b, 0644))
+	}
+	return dataDir
+}
+
+// findings returns the status of each finding of the check.
+func findings(report DoctorReport, check string) (statuses []DoctorStatus) {
+	for _, f := range report.Findings {
+		if f.Check == check {
+			statuses = append(statuses, f.Status)
+		}
+	}
+	return statuses
+}
+
+func TestDoctorHealthy(t *testing.T) {
+	dataDir := makeDoctorDataDir(t, &state{GenesisHash: "hash", Network: "testnet", NextRound: 10})
+	require.NoError(t, os.Mkdir(path.Join(dataDir, "exporter_file_writer"), 0755))
+
+	report := Doctor(dataDir, true)
+	for _, f := range report.Findings {
+		assert.Contains(t, []DoctorStatus{DoctorOK, DoctorWarning}, f.Status, f.Message)
+	}
+	assert.True(t, report.Healthy())
+	assert.Equal(t, []DoctorStatus{DoctorOK}, findings(report, "config"))
+	assert.Equal(t, []DoctorStatus{DoctorOK}, findings(report, "lock"))
+	assert.Equal(t, []DoctorStatus{DoctorOK}, findings(report, "metadata"))
+	assert.Equal(t, []DoctorStatus{DoctorOK}, findings(report, "plugins"))
+	assert.Equal(t, []DoctorStatus{DoctorOK}, findings(report, "permissions"))
+
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf))
+	assert.Contains(t, buf.String(), "[OK      ] metadata     next round 10, network testnet\n")
+}
+
+// TestDoctorRepair tests that leftovers are only removed with repair.
+func TestDoctorRepair(t *testing.T) {
+	md := &state{
+		GenesisHash: "hash",
+		NextRound:   10,
+		CommitIntents: []commitIntent{
+			{Round: 9, Name: "committer", Token: "stale"},
+			{Round: 10, Name: "committer", Token: "pending"},
+		},
+	}
+	dataDir := makeDoctorDataDir(t, md)
+	pidFile := path.Join(dataDir, "conduit.pid")
+	// The largest pid is not a running process.
+	require.NoError(t, os.WriteFile(pidFile, []byte("2147483647\n"), 0644))
+	tempMetadata := statestore.MetadataPath(dataDir) + ".temp"
+	require.NoError(t, os.WriteFile(tempMetadata, []byte("{"), 0644))
+	pluginTemp := path.Join(dataDir, "processors_aggregate")
+	require.NoError(t, os.Mkdir(path.Join(dataDir, "exporter_file_writer"), 0755))
+	require.NoError(t, os.Mkdir(pluginTemp, 0755))
+	require.NoError(t, os.WriteFile(path.Join(dataDir, "exporter_file_writer", "state.json.tmp"), nil, 0644))
+
+	report := Doctor(dataDir, false)
+	assert.True(t, report.Healthy())
+	assert.Equal(t, []DoctorStatus{DoctorWarning}, findings(report, "lock"))
+	assert.Equal(t, []DoctorStatus{DoctorWarning, DoctorOK}, findings(report, "metadata"))
+	assert.Equal(t, []DoctorStatus{DoctorOK, DoctorWarning}, findings(report, "intents"))
+	assert.Equal(t, []DoctorStatus{DoctorWarning, DoctorWarning}, findings(report, "plugins"))
+	assert.FileExists(t, pidFile)
+	assert.FileExists(t, tempMetadata)
+
+	report = Doctor(dataDir, true)
+	assert.True(t, report.Healthy())
+	assert.Equal(t, []DoctorStatus{DoctorRepaired}, findings(report, "lock"))
+	assert.Equal(t, []DoctorStatus{DoctorRepaired, DoctorOK}, findings(report, "metadata"))
+	assert.Equal(t, []DoctorStatus{DoctorOK, DoctorRepaired}, findings(report, "intents"))
+	// The directory of a plugin which is not configured is kept.
+	assert.Equal(t, []DoctorStatus{DoctorRepaired, DoctorWarning}, findings(report, "plugins"))
+	assert.NoFileExists(t, pidFile)
+	assert.NoFileExists(t, tempMetadata)
+	assert.NoFileExists(t, path.Join(dataDir, "exporter_file_writer", "state.json.tmp"))
+	assert.DirExists(t, pluginTemp)
+	assert.Equal(t, []commitIntent{{Round: 10, Name: "committer", Token: "pending"}}, readState(t, dataDir).CommitIntents)
+
+	report = Doctor(dataDir, true)
+	assert.Equal(t, []DoctorStatus{DoctorOK}, findings(report, "lock"))
+	assert.Equal(t, []DoctorStatus{DoctorOK}, findings(report, "intents"))
+}
+
+// TestDoctorRunning tests that nothing is repaired while conduit is running.
+func TestDoctorRunning(t *testing.T) {
+	dataDir := makeDoctorDataDir(t, &state{GenesisHash: "hash", NextRound: 10})
+	require.NoError(t, os.WriteFile(path.Join(dataDir, "conduit.pid"), []byte("1"), 0644))
+	tempMetadata := statestore.MetadataPath(dataDir) + ".temp"
+	require.NoError(t, os.WriteFile(tempMetadata, []byte("{"), 0644))
+
+	report := Doctor(dataDir, true)
+	assert.Equal(t, []DoctorStatus{DoctorWarning}, findings(report, "lock"))
+	assert.Equal(t, []DoctorStatus{DoctorWarning, DoctorOK}, findings(report, "metadata"))
+	assert.FileExists(t, tempMetadata)
+}
+
+func TestDoctorErrors(t *testing.T) {
+	report := Doctor(path.Join(t.TempDir(), "missing"), true)
+	assert.False(t, report.Healthy())
+	assert.Equal(t, []DoctorStatus{DoctorError}, findings(report, "data-dir"))
+
+	// A corrupt metadata file is reported, not repaired.
+	dataDir := makeDoctorDataDir(t, nil)
+	require.NoError(t, os.WriteFile(statestore.MetadataPath(dataDir), []byte(`{"next-round": 1`), 0644))
+	report = Doctor(dataDir, true)
+	assert.False(t, report.Healthy())
+	assert.Equal(t, []DoctorStatus{DoctorError}, findings(report, "metadata"))
+
+	dataDir = t.TempDir()
+	report = Doctor(dataDir, true)
+	assert.False(t, report.Healthy())
+	assert.Equal(t, []DoctorStatus{DoctorError}, findings(report, "config"))
+	assert.Equal(t, []DoctorStatus{DoctorOK}, findings(report, "metadata"))
+}
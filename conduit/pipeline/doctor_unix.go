@@ -0,0 +1,29 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package pipeline
+
+import "syscall"
+
+// freeSpace returns the bytes available to the user on the file system of dir.
+func freeSpace(dir string) (uint64, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, false
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true
+}
+
+// processAlive reports whether a process with the pid exists, known is false
+// if it cannot be determined.
+func processAlive(pid int) (alive bool, known bool) {
+	err := syscall.Kill(pid, 0)
+	switch err {
+	case nil, syscall.EPERM:
+		return true, true
+	case syscall.ESRCH:
+		return false, true
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,132 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+// pluginSections are the config keys which hold the plugin configs, the
+// summary shows the config each plugin resolved instead.
+var pluginSections = []string{"importer", "processors", "exporter", "exporters"}
+
+// Validate checks the pipeline without processing any rounds. The plugin
+// configs are checked by the plugins which implement conduit.ConfigValidator,
+// then every plugin is initialized, which verifies that their dependencies,
+// such as databases and nodes, are reachable, and exporter schemas are
+// verified. Metadata is not written and the metrics and API servers are not
+// started. Once initialized the plugins are closed before it returns the
+// resolved config.
+func (p *pipelineImpl) Validate() (string, error) {
+	p.dryRun = true
+	if err := p.validatePluginConfigs(); err != nil {
+		return "", fmt.Errorf("Pipeline.Validate(): %w", err)
+	}
+	if err := p.Init(); err != nil {
+		return "", fmt.Errorf("Pipeline.Validate(): %w", err)
+	}
+	defer p.Stop()
+	if drift := p.verifySchemas(); drift != nil {
+		return "", fmt.Errorf("Pipeline.Validate(): %w", drift)
+	}
+	summary, err := p.summary()
+	if err != nil {
+		return "", fmt.Errorf("Pipeline.Validate(): %w", err)
+	}
+	return summary, nil
+}
+
+// validatePluginConfigs calls ValidateConfig on every plugin which implements
+// conduit.ConfigValidator, all of the failures are reported together.
+func (p *pipelineImpl) validatePluginConfigs() error {
+	var failures []string
+	validate := func(pluginType string, plugin conduit.PluginMetadata, config interface{}) {
+		validator, ok := plugin.(conduit.ConfigValidator)
+		if !ok {
+			return
+		}
+		name := plugin.Metadata().Name
+		configs, err := yaml.Marshal(config)
+		if err == nil {
+			err = validator.ValidateConfig(p.makeConfig(pluginType, name, configs))
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s (%s): %v", pluginType, name, err))
+		}
+	}
+	validate("importer", *p.importer, p.cfg.Importer.Config)
+	for idx, processor := range p.processors {
+		validate("processor", *processor, p.cfg.Processors[idx].Config)
+	}
+	exporterCfgs := p.cfg.exporterConfigs()
+	for idx, exporter := range p.exporters {
+		validate("exporter", *exporter, exporterCfgs[idx].Config)
+		if idx < len(p.exporterChains) && p.exporterChains[idx] != nil {
+			for procIdx, processor := range p.exporterChains[idx].processors {
+				validate("processor", *processor, exporterCfgs[idx].Processors[procIdx].Config)
+			}
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("invalid plugin config:\n  %s", strings.Join(failures, "\n  "))
+	}
+	return nil
+}
+
+// summary describes the resolved pipeline settings, including defaults and
+// the preset, followed by the config of each initialized plugin.
+func (p *pipelineImpl) summary() (string, error) {
+	settingBytes, err := yaml.Marshal(p.cfg)
+	if err != nil {
+		return "", fmt.Errorf("summary(): %w", err)
+	}
+	var settings map[string]interface{}
+	if err = yaml.Unmarshal(settingBytes, &settings); err != nil {
+		return "", fmt.Errorf("summary(): %w", err)
+	}
+	for _, key := range pluginSections {
+		delete(settings, key)
+	}
+	if settingBytes, err = yaml.Marshal(settings); err != nil {
+		return "", fmt.Errorf("summary(): %w", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "network: %s\nnext round: %d\n\n", p.pipelineMetadata.Network, p.pipelineMetadata.NextRound)
+	sb.WriteString("pipeline settings:\n")
+	writeIndented(&sb, string(settingBytes))
+	writePlugin := func(pluginType string, name string, config string) {
+		fmt.Fprintf(&sb, "\n%s (%s):\n", pluginType, name)
+		writeIndented(&sb, config)
+	}
+	writePlugin("importer", (*p.importer).Metadata().Name, (*p.importer).Config())
+	for _, processor := range p.processors {
+		writePlugin("processor", (*processor).Metadata().Name, (*processor).Config())
+	}
+	for idx, exporter := range p.exporters {
+		name := p.exporterName(idx)
+		writePlugin("exporter", name, (*exporter).Config())
+		if idx < len(p.exporterChains) && p.exporterChains[idx] != nil {
+			for _, processor := range p.exporterChains[idx].processors {
+				writePlugin(fmt.Sprintf("exporter (%s) processor", name), (*processor).Metadata().Name, (*processor).Config())
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// writeIndented writes each non-empty line of text indented by two spaces.
+func writeIndented(sb *strings.Builder, text string) {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		sb.WriteString("  ")
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+}
@@ -0,0 +1,122 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+type configImporter struct {
+	mockImporter
+}
+
+func (m *configImporter) Config() string {
+	return "netaddr: localhost"
+}
+
+type configProcessor struct {
+	mockProcessor
+}
+
+func (m *configProcessor) Config() string {
+	return ""
+}
+
+// validatingExporter implements conduit.ConfigValidator.
+type validatingExporter struct {
+	mockExporter
+	validated plugins.PluginConfig
+	err       error
+	closed    bool
+}
+
+func (e *validatingExporter) ValidateConfig(cfg plugins.PluginConfig) error {
+	e.validated = cfg
+	return e.err
+}
+
+func (e *validatingExporter) Config() string {
+	return "setting: 1"
+}
+
+func (e *validatingExporter) Close() error {
+	e.closed = true
+	return nil
+}
+
+func makeDryRunPipeline(t *testing.T, exps ...*validatingExporter) *pipelineImpl {
+	var pImporter importers.Importer = &configImporter{mockImporter{genesis: sdk.Genesis{Network: "test"}}}
+	var pProcessor processors.Processor = &configProcessor{}
+	l, _ := test.NewNullLogger()
+	ctx, cf := context.WithCancel(context.Background())
+	pImpl := &pipelineImpl{
+		ctx: ctx,
+		cf:  cf,
+		cfg: &Config{
+			ConduitArgs: &conduit.Args{ConduitDataDir: t.TempDir()},
+			RetryCount:  10,
+			Processors:  []NameConfigPair{{Name: "mockProcessor"}},
+			API:         API{Addr: "127.0.0.1:0"},
+		},
+		logger:     l,
+		importer:   &pImporter,
+		processors: []*processors.Processor{&pProcessor},
+	}
+	for idx := range exps {
+		var exp exporters.Exporter = exps[idx]
+		pImpl.exporters = append(pImpl.exporters, &exp)
+		pImpl.cfg.Exporters = append(pImpl.cfg.Exporters, NameConfigPair{Name: "mockExporter", Config: map[string]interface{}{"setting": idx}})
+	}
+	return pImpl
+}
+
+func TestPipelineValidate(t *testing.T) {
+	exp := &validatingExporter{}
+	pImpl := makeDryRunPipeline(t, exp)
+	dataDir := pImpl.cfg.ConduitArgs.ConduitDataDir
+
+	summary, err := pImpl.Validate()
+	require.NoError(t, err)
+
+	assert.Equal(t, "setting: 0\n", exp.validated.Config)
+	assert.True(t, exp.closed)
+	assert.Contains(t, summary, "network: test\nnext round: 0\n")
+	assert.Contains(t, summary, "  retry-count: 10\n")
+	assert.Contains(t, summary, "importer (mockImporter):\n  netaddr: localhost\n")
+	assert.Contains(t, summary, "processor (mockProcessor):\n")
+	assert.Contains(t, summary, "exporter (mockExporter):\n  setting: 1\n")
+	// The plugin sections are not in the settings, nested settings such as
+	// the retry policies may have the same keys.
+	assert.NotRegexp(t, `(?m)^  exporters:`, summary)
+
+	// A dry run does not create the pipeline metadata.
+	_, err = os.Stat(path.Join(dataDir, "metadata.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPipelineValidateConfigErrors(t *testing.T) {
+	first := &validatingExporter{err: fmt.Errorf("unknown key")}
+	second := &validatingExporter{}
+	third := &validatingExporter{err: fmt.Errorf("missing key")}
+	pImpl := makeDryRunPipeline(t, first, second, third)
+
+	_, err := pImpl.Validate()
+	require.Error(t, err)
+	assert.Equal(t, "Pipeline.Validate(): invalid plugin config:\n  exporter (mockExporter): unknown key\n  exporter (mockExporter): missing key", err.Error())
+	// The plugins were not initialized.
+	assert.Empty(t, second.cfg.Config)
+	assert.False(t, second.closed)
+}
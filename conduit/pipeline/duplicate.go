@@ -0,0 +1,22 @@
+package pipeline
+
+import (
+	"errors"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+// duplicateRound reports whether err is, or wraps, a
+// *conduit.DuplicateRoundError. The round is then treated as exported by the
+// exporter instead of being retried, which would never succeed once the
+// metadata is behind the exporter.
+func (p *pipelineImpl) duplicateRound(exporterName string, err error) bool {
+	var dup *conduit.DuplicateRoundError
+	if !errors.As(err, &dup) {
+		return false
+	}
+	p.logger.Warnf("exporter (%s) already has round %d, it is treated as exported: %v", exporterName, dup.Round, err)
+	metrics.ExporterDuplicateRounds.WithLabelValues(exporterName).Inc()
+	return true
+}
@@ -0,0 +1,49 @@
+package pipeline
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+// aheadExporter already has the rounds before next, like an exporter whose
+// data is newer than the pipeline metadata.
+type aheadExporter struct {
+	roundExporter
+	next uint64
+}
+
+func (r *aheadExporter) Receive(exportData data.BlockData) error {
+	if exportData.Round() < r.next {
+		return conduit.MakeDuplicateRoundError(exportData.Round(), fmt.Errorf("expected round %d", r.next))
+	}
+	r.next++
+	return r.roundExporter.Receive(exportData)
+}
+
+// TestPipelineDuplicateRound tests that rounds which an exporter already has
+// are treated as exported instead of being retried.
+func TestPipelineDuplicateRound(t *testing.T) {
+	metrics.RegisterPrometheusMetrics("duplicate_test")
+	ahead := &aheadExporter{roundExporter: roundExporter{name: "ahead"}, next: 2}
+	other := &roundExporter{name: "other"}
+	pImpl := makeReloadPipeline(t, ahead, other)
+	pImpl.cfg.RetryCount = 1
+	pImpl.cfg.Rounds.End = 3
+
+	pImpl.Start()
+	pImpl.Wait()
+	require.NoError(t, pImpl.Error())
+
+	assert.Equal(t, uint64(4), pImpl.pipelineMetadata.NextRound)
+	assert.Equal(t, []uint64{2, 3}, ahead.received())
+	assert.Equal(t, []uint64{0, 1, 2, 3}, other.received())
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.ExporterDuplicateRounds.WithLabelValues("ahead")))
+}
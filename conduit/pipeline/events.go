@@ -0,0 +1,163 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+)
+
+// eventQueueSize is the number of events of each type which may wait for a
+// subscriber, later events are dropped for it until it catches up.
+const eventQueueSize = 64
+
+// RoundExported is published once a round was exported by all the exporters.
+type RoundExported struct {
+	Round uint64
+	// RoundTime is the timestamp of the block.
+	RoundTime  time.Time
+	ExportTime time.Time
+}
+
+// ErrorOccurred is published each time a round fails, including the failed
+// attempts which are retried.
+type ErrorOccurred struct {
+	// Round is the round which was being processed.
+	Round      uint64
+	Err        error
+	RetryCount uint64
+	Time       time.Time
+}
+
+// LagChanged is published when the lag of the pipeline behind the network
+// changed, the time is compared at second granularity.
+type LagChanged struct {
+	// Rounds is the number of rounds between the last exported round and the
+	// latest round of the importer, it is only known when the importer reports
+	// its latest round for the throttle lag-rounds.
+	Rounds uint64
+	// Time is the age of the last exported block.
+	Time time.Duration
+}
+
+// EventSubscription delivers the pipeline events to an embedding application.
+// Events are never waited for, an event is dropped when the subscriber is
+// eventQueueSize events of the same type behind.
+type EventSubscription struct {
+	RoundExported <-chan RoundExported
+	ErrorOccurred <-chan ErrorOccurred
+	LagChanged    <-chan LagChanged
+
+	roundExported chan RoundExported
+	errorOccurred chan ErrorOccurred
+	lagChanged    chan LagChanged
+	hub           *eventHub
+}
+
+// Close unsubscribes and closes the event channels.
+func (s *EventSubscription) Close() {
+	s.hub.remove(s)
+}
+
+// eventHub fans the pipeline events out to the subscriptions.
+type eventHub struct {
+	mu   sync.Mutex
+	subs []*EventSubscription
+	// lag is the last published lag.
+	lag LagChanged
+}
+
+func (h *eventHub) add() *EventSubscription {
+	sub := &EventSubscription{
+		roundExported: make(chan RoundExported, eventQueueSize),
+		errorOccurred: make(chan ErrorOccurred, eventQueueSize),
+		lagChanged:    make(chan LagChanged, eventQueueSize),
+		hub:           h,
+	}
+	sub.RoundExported = sub.roundExported
+	sub.ErrorOccurred = sub.errorOccurred
+	sub.LagChanged = sub.lagChanged
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs = append(h.subs, sub)
+	return sub
+}
+
+func (h *eventHub) remove(sub *EventSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, s := range h.subs {
+		if s == sub {
+			h.subs = append(h.subs[:i], h.subs[i+1:]...)
+			close(sub.roundExported)
+			close(sub.errorOccurred)
+			close(sub.lagChanged)
+			return
+		}
+	}
+}
+
+func (h *eventHub) publishRoundExported(ev RoundExported) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sub := range h.subs {
+		select {
+		case sub.roundExported <- ev:
+		default:
+		}
+	}
+}
+
+func (h *eventHub) publishErrorOccurred(ev ErrorOccurred) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sub := range h.subs {
+		select {
+		case sub.errorOccurred <- ev:
+		default:
+		}
+	}
+}
+
+// publishLag publishes ev if it differs from the last published lag.
+func (h *eventHub) publishLag(ev LagChanged) {
+	ev.Time = ev.Time.Truncate(time.Second)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ev == h.lag {
+		return
+	}
+	h.lag = ev
+	for _, sub := range h.subs {
+		select {
+		case sub.lagChanged <- ev:
+		default:
+		}
+	}
+}
+
+// Events subscribes to the pipeline events, the subscription should be closed
+// once the application stops reading from it.
+func (p *pipelineImpl) Events() *EventSubscription {
+	return p.events.add()
+}
+
+// publishLag publishes the lag of the last exported round, if it changed.
+func (p *pipelineImpl) publishLag() {
+	p.mu.RLock()
+	status := p.status
+	p.mu.RUnlock()
+	if status.LastExportedRound == nil {
+		return
+	}
+	var ev LagChanged
+	if status.LatestRound > *status.LastExportedRound {
+		ev.Rounds = status.LatestRound - *status.LastExportedRound
+	}
+	if !status.LastRoundTime.IsZero() {
+		ev.Time = time.Since(status.LastRoundTime)
+		if ev.Time < 0 {
+			ev.Time = 0
+		}
+	}
+	p.events.publishLag(ev)
+}
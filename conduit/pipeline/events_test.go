@@ -0,0 +1,72 @@
+package pipeline
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// TestPipelineEvents tests that the subscriptions receive the exported rounds
+// and the failed attempts.
+func TestPipelineEvents(t *testing.T) {
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeCheckpointPipeline(t, exp)
+	pImpl.cfg.Rounds.End = 3
+	var pImporter importers.Importer = &namedImporter{roundImporter{failRound: 2}}
+	pImpl.importer = &pImporter
+
+	sub := pImpl.Events()
+	defer sub.Close()
+	pImpl.Start()
+	pImpl.Wait()
+	require.NoError(t, pImpl.Error())
+
+	var rounds []uint64
+	for len(sub.RoundExported) > 0 {
+		rounds = append(rounds, (<-sub.RoundExported).Round)
+	}
+	assert.Equal(t, []uint64{0, 1, 2, 3}, rounds)
+
+	require.Len(t, sub.ErrorOccurred, 1)
+	ev := <-sub.ErrorOccurred
+	assert.Equal(t, uint64(2), ev.Round)
+	assert.ErrorContains(t, ev.Err, "importer")
+
+	// the blocks have no timestamp, they are all decades old.
+	require.NotEmpty(t, sub.LagChanged)
+	assert.Greater(t, (<-sub.LagChanged).Time, time.Hour)
+}
+
+// TestEventsDropAndClose tests that events are dropped for a full subscription
+// and that closed subscriptions no longer receive events.
+func TestEventsDropAndClose(t *testing.T) {
+	p := &pipelineImpl{}
+	full := p.Events()
+	closed := p.Events()
+	closed.Close()
+
+	for i := 0; i <= eventQueueSize; i++ {
+		p.setError(errors.New("failed"))
+	}
+	p.setError(nil)
+	assert.Len(t, full.ErrorOccurred, eventQueueSize)
+	_, ok := <-closed.ErrorOccurred
+	assert.False(t, ok)
+
+	round := uint64(10)
+	p.status.LastExportedRound = &round
+	p.status.LatestRound = 15
+	p.publishLag()
+	p.publishLag()
+	require.Len(t, full.LagChanged, 1)
+	assert.Equal(t, LagChanged{Rounds: 5}, <-full.LagChanged)
+
+	full.Close()
+	_, ok = <-full.RoundExported
+	assert.False(t, ok)
+}
@@ -0,0 +1,156 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+	"github.com/algorand/conduit/conduit/tracing"
+)
+
+// exporterChain is the processor sub-chain of an exporter. It runs after the
+// shared processors on a copy of the block, so that the other exporters are
+// not affected.
+type exporterChain struct {
+	processors []*processors.Processor
+	loggers    []*log.Logger
+	// names are the processor names looked up during Init.
+	names []string
+}
+
+// validExporterProcessors validates the processor sub-chain of an exporter.
+// A failed sub-chain processor fails the exporter, so they do not have their
+// own when condition, retry policy or best-effort setting.
+func validExporterProcessors(exporter NameConfigPair) error {
+	for _, pair := range exporter.Processors {
+		switch {
+		case len(pair.Processors) > 0:
+			return fmt.Errorf("processor (%s) of exporter (%s) cannot have processors", pair.Name, exporter.Name)
+		case pair.When != "":
+			return fmt.Errorf("processor (%s) of exporter (%s) cannot have a when condition, set it on the exporter", pair.Name, exporter.Name)
+		case pair.RetryPolicy != nil:
+			return fmt.Errorf("processor (%s) of exporter (%s) cannot have a retry policy, it is retried with the exporter", pair.Name, exporter.Name)
+		case pair.BestEffort:
+			return fmt.Errorf("processor (%s) of exporter (%s) cannot be best-effort, set it on the exporter", pair.Name, exporter.Name)
+		case pair.OutputSchema != nil:
+			return fmt.Errorf("processor (%s) of exporter (%s) cannot have an output-schema, set it on the exporter", pair.Name, exporter.Name)
+		case pair.Alias != "":
+			return fmt.Errorf("processor (%s) of exporter (%s) cannot have an alias, only exporters are supported", pair.Name, exporter.Name)
+		}
+		if pair.LogLevel != "" {
+			if _, err := log.ParseLevel(pair.LogLevel); err != nil {
+				return fmt.Errorf("plugin (%s) log level (%s) was invalid: %w", pair.Name, pair.LogLevel, err)
+			}
+		}
+		if err := validSandbox(pair); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeExporterChains builds the processor sub-chains of the exporters, the
+// entries of exporters without one are nil.
+func makeExporterChains(cfg *Config, logger *log.Logger) ([]*exporterChain, error) {
+	exporterCfgs := cfg.exporterConfigs()
+	chains := make([]*exporterChain, len(exporterCfgs))
+	for idx, exporterConfig := range exporterCfgs {
+		if len(exporterConfig.Processors) == 0 {
+			continue
+		}
+		chain := &exporterChain{}
+		for _, processorConfig := range exporterConfig.Processors {
+			processor, err := makeProcessor(processorConfig)
+			if err != nil {
+				return nil, err
+			}
+			chain.processors = append(chain.processors, &processor)
+			logger.Infof("Found Processor: %s for Exporter: %s", processorConfig.Name, exporterConfig.Name)
+		}
+		chains[idx] = chain
+	}
+	return chains, nil
+}
+
+// initExporterChains initializes the processors of the exporter sub-chains.
+func (p *pipelineImpl) initExporterChains() error {
+	exporterCfgs := p.cfg.exporterConfigs()
+	for idx, chain := range p.exporterChains {
+		if chain == nil {
+			continue
+		}
+		exporterName := p.exporterName(idx)
+		chain.loggers = make([]*log.Logger, len(chain.processors))
+		chain.names = make([]string, len(chain.processors))
+		for procIdx, processor := range chain.processors {
+			processorCfg := exporterCfgs[idx].Processors[procIdx]
+			processorName := (*processor).Metadata().Name
+			chain.names[procIdx] = processorName
+			processorLogger := p.makePluginLogger(plugins.Processor, processorName, processorCfg.LogLevel)
+			chain.loggers[procIdx] = processorLogger
+			configs, err := yaml.Marshal(processorCfg.Config)
+			if err != nil {
+				return fmt.Errorf("Pipeline.Start(): could not serialize Exporters[%d].Processors[%d].Args : %w", idx, procIdx, err)
+			}
+			p.setTrafficMeter(processorType, processorName, *processor)
+			err = (*processor).Init(p.ctx, *p.initProvider, p.makeConfig("processor", processorName, configs), processorLogger)
+			if err != nil {
+				return fmt.Errorf("Pipeline.Init(): could not initialize processor (%s) of exporter (%s): %w", processorName, exporterName, err)
+			}
+			p.setBlockCache(*processor)
+			if err = p.initWindow(processor, fmt.Sprintf("exporter_%d_processor_%d_%s", idx, procIdx, processorName)); err != nil {
+				return fmt.Errorf("Pipeline.Init(): %w", err)
+			}
+			p.logger.Infof("Initialized Processor: %s for Exporter: %s", processorName, exporterName)
+		}
+	}
+	return nil
+}
+
+// allProcessors returns the shared processors followed by the processors of
+// every exporter sub-chain.
+func (p *pipelineImpl) allProcessors() []*processors.Processor {
+	procs := append([]*processors.Processor{}, p.processors...)
+	for _, chain := range p.exporterChains {
+		if chain != nil {
+			procs = append(procs, chain.processors...)
+		}
+	}
+	return procs
+}
+
+// processForExporter runs the processor sub-chain of the exporter at idx on a
+// copy of the block. The block is returned unchanged for exporters without a
+// sub-chain.
+func (p *pipelineImpl) processForExporter(idx int, roundSpan *tracing.Span, blk data.BlockData) (data.BlockData, error) {
+	if idx >= len(p.exporterChains) || p.exporterChains[idx] == nil {
+		return blk, nil
+	}
+	var processed data.BlockData
+	if err := msgpack.Decode(msgpack.Encode(blk), &processed); err != nil {
+		return blk, fmt.Errorf("unable to copy round %d for exporter (%s): %w", blk.Round(), p.exporterName(idx), err)
+	}
+	for procIdx, proc := range p.exporterChains[idx].processors {
+		processorName := p.chainProcessorName(idx, procIdx)
+		processorStart := time.Now()
+		span := pluginSpan(roundSpan, "processor.Process", processorName, processorStart)
+		op := p.activity.start(processorType, processorName, blk.Round())
+		var err error
+		processed, err = runProcessor(*proc, p.windows[proc], processed, true)
+		p.activity.done(op)
+		span.End(err)
+		if err != nil {
+			return blk, fmt.Errorf("processor (%s) of exporter (%s): %w", processorName, p.exporterName(idx), err)
+		}
+		metrics.ObserveLatency(metrics.ProcessorTimeSeconds.WithLabelValues(metrics.ProcessorLabel(p.cfg.Metrics.ProcessorLabels, processorName)), time.Since(processorStart).Seconds(), roundSpan.TraceID())
+	}
+	return processed, nil
+}
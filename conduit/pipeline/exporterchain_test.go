@@ -0,0 +1,126 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// paysetExporter records the number of transactions of each round.
+type paysetExporter struct {
+	roundExporter
+	mu    sync.Mutex
+	sizes []int
+}
+
+func (e *paysetExporter) Receive(exportData data.BlockData) error {
+	e.mu.Lock()
+	e.sizes = append(e.sizes, len(exportData.Payset))
+	e.mu.Unlock()
+	return e.roundExporter.Receive(exportData)
+}
+
+func (e *paysetExporter) received() []int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]int{}, e.sizes...)
+}
+
+// errProcessor always fails.
+type errProcessor struct {
+	processors.Processor
+}
+
+func (e *errProcessor) Metadata() conduit.Metadata {
+	return conduit.Metadata{Name: "fail"}
+}
+
+func (e *errProcessor) Process(input data.BlockData) (data.BlockData, error) {
+	return input, fmt.Errorf("process")
+}
+
+func TestValidExporterProcessors(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter NameConfigPair
+		err      string
+	}{
+		{"none", NameConfigPair{Name: "kafka"}, ""},
+		{"valid", NameConfigPair{Name: "kafka", Processors: []NameConfigPair{{Name: "filter_processor", LogLevel: "debug"}}}, ""},
+		{"nested", NameConfigPair{Name: "kafka", Processors: []NameConfigPair{{Name: "filter_processor", Processors: []NameConfigPair{{Name: "noop"}}}}}, "processor (filter_processor) of exporter (kafka) cannot have processors"},
+		{"when", NameConfigPair{Name: "kafka", Processors: []NameConfigPair{{Name: "filter_processor", When: "block.round > 5"}}}, "processor (filter_processor) of exporter (kafka) cannot have a when condition, set it on the exporter"},
+		{"retry policy", NameConfigPair{Name: "kafka", Processors: []NameConfigPair{{Name: "filter_processor", RetryPolicy: &RetryPolicy{}}}}, "processor (filter_processor) of exporter (kafka) cannot have a retry policy, it is retried with the exporter"},
+		{"best-effort", NameConfigPair{Name: "kafka", Processors: []NameConfigPair{{Name: "filter_processor", BestEffort: true}}}, "processor (filter_processor) of exporter (kafka) cannot be best-effort, set it on the exporter"},
+		{"log level", NameConfigPair{Name: "kafka", Processors: []NameConfigPair{{Name: "filter_processor", LogLevel: "loud"}}}, "plugin (filter_processor) log level (loud) was invalid"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validExporterProcessors(tc.exporter)
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+// TestProcessForExporter tests that the sub-chain processes a copy of the
+// block, which the processors may modify in place.
+func TestProcessForExporter(t *testing.T) {
+	pImpl := makeCheckpointPipeline(t, &roundExporter{name: "archive"}, &roundExporter{name: "analytics"}, &roundExporter{name: "broken"})
+	var drop processors.Processor = &dropProcessor{amount: 1}
+	var fail processors.Processor = &errProcessor{}
+	pImpl.exporterChains = []*exporterChain{nil, {processors: []*processors.Processor{&drop}}, {processors: []*processors.Processor{&fail}, names: []string{"fail"}}}
+
+	blk := data.BlockData{Payset: []sdk.SignedTxnInBlock{makePayment(1), makePayment(2)}}
+	archived, err := pImpl.processForExporter(0, nil, blk)
+	require.NoError(t, err)
+	assert.Len(t, archived.Payset, 2)
+
+	analyzed, err := pImpl.processForExporter(1, nil, blk)
+	require.NoError(t, err)
+	require.Len(t, analyzed.Payset, 1)
+	assert.Equal(t, sdk.MicroAlgos(2), analyzed.Payset[0].Txn.Amount)
+	// The shared block is unchanged.
+	require.Len(t, blk.Payset, 2)
+	assert.Equal(t, sdk.MicroAlgos(1), blk.Payset[0].Txn.Amount)
+
+	_, err = pImpl.processForExporter(2, nil, blk)
+	assert.EqualError(t, err, "processor (fail) of exporter (broken): process")
+}
+
+// TestPipelineExporterChain tests that each exporter receives the output of
+// its own sub-chain.
+func TestPipelineExporterChain(t *testing.T) {
+	archive := &paysetExporter{roundExporter: roundExporter{name: "archive"}}
+	analytics := &paysetExporter{roundExporter: roundExporter{name: "analytics"}}
+	pImpl := makeCheckpointPipeline(t, archive, analytics)
+	var pImporter importers.Importer = &paymentImporter{namedImporter{roundImporter{failRound: 1000}}}
+	pImpl.importer = &pImporter
+	var drop processors.Processor = &dropProcessor{amount: 2}
+	pImpl.exporterChains = []*exporterChain{nil, {processors: []*processors.Processor{&drop}}}
+
+	pImpl.Start()
+	require.Eventually(t, func() bool { return len(analytics.received()) >= 3 }, 5*time.Second, time.Millisecond)
+	pImpl.cf()
+	pImpl.Wait()
+
+	require.NotEmpty(t, archive.received())
+	for _, size := range archive.received() {
+		assert.Equal(t, 2, size)
+	}
+	for _, size := range analytics.received() {
+		assert.Equal(t, 1, size)
+	}
+}
@@ -1,14 +1,78 @@
 package pipeline
 
 import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
 	log "github.com/sirupsen/logrus"
 )
 
+// Log fields added by the RoundTag.
+const (
+	LogRoundField   = "round"
+	LogTraceIDField = "trace_id"
+)
+
+// RoundTag is the round which the pipeline is processing. The plugin log
+// formatters add it to each entry, so that the logs of the importer, the
+// processors and the exporters for a round can be correlated.
+type RoundTag struct {
+	mu      sync.RWMutex
+	set     bool
+	round   uint64
+	traceID string
+}
+
+// Set tags the following entries with the round, and the trace ID if it is
+// not empty.
+func (t *RoundTag) Set(round uint64, traceID string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.set, t.round, t.traceID = true, round, traceID
+}
+
+// Clear stops tagging the entries.
+func (t *RoundTag) Clear() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.set, t.round, t.traceID = false, 0, ""
+}
+
+// addFields adds the round fields to the entry, fields which were set by the
+// plugin are kept.
+func (t *RoundTag) addFields(entry *log.Entry) {
+	if t == nil {
+		return
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if !t.set {
+		return
+	}
+	if _, ok := entry.Data[LogRoundField]; !ok {
+		entry.Data[LogRoundField] = t.round
+	}
+	if _, ok := entry.Data[LogTraceIDField]; !ok && t.traceID != "" {
+		entry.Data[LogTraceIDField] = t.traceID
+	}
+}
+
 // PluginLogFormatter formats the log message with special conduit tags
 type PluginLogFormatter struct {
-	Formatter *log.JSONFormatter
+	Formatter log.Formatter
 	Type      string
 	Name      string
+	// Round optionally tags the entries with the current round.
+	Round *RoundTag
 }
 
 // Format allows this to be used as a logrus formatter
@@ -16,9 +80,16 @@ func (f PluginLogFormatter) Format(entry *log.Entry) ([]byte, error) {
 	// Underscores force these to be in the front in order type -> name
 	entry.Data["__type"] = f.Type
 	entry.Data["_name"] = f.Name
+	f.Round.addFields(entry)
 	return f.Formatter.Format(entry)
 }
 
+// Log formats of the log-format config.
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
 func makePluginLogFormatter(pluginType string, pluginName string) PluginLogFormatter {
 	return PluginLogFormatter{
 		Formatter: &log.JSONFormatter{
@@ -28,3 +99,96 @@ func makePluginLogFormatter(pluginType string, pluginName string) PluginLogForma
 		Name: pluginName,
 	}
 }
+
+// MakeLogFormatter returns the formatter of a log format, LogFormatText writes
+// logfmt style key=value lines and anything else writes JSON.
+func MakeLogFormatter(format string, pluginType string, pluginName string) log.Formatter {
+	return makeRoundLogFormatter(format, pluginType, pluginName, nil)
+}
+
+// makeRoundLogFormatter returns the formatter of a log format which tags the
+// entries with the round of the tag.
+func makeRoundLogFormatter(format string, pluginType string, pluginName string, round *RoundTag) log.Formatter {
+	formatter := makePluginLogFormatter(pluginType, pluginName)
+	if format == LogFormatText {
+		formatter.Formatter = &log.TextFormatter{
+			DisableColors:    true,
+			FullTimestamp:    true,
+			QuoteEmptyFields: true,
+		}
+	}
+	formatter.Round = round
+	return formatter
+}
+
+// ANSI color codes used by the PrettyLogFormatter.
+const (
+	colorRed    = 31
+	colorYellow = 33
+	colorCyan   = 36
+	colorGray   = 90
+)
+
+// PrettyLogFormatter formats log messages as compact, human-friendly lines
+// intended for an interactive console.
+type PrettyLogFormatter struct {
+	Type  string
+	Name  string
+	Color bool
+	// Round optionally tags the entries with the current round.
+	Round *RoundTag
+}
+
+// Format allows this to be used as a logrus formatter
+func (f PrettyLogFormatter) Format(entry *log.Entry) ([]byte, error) {
+	var color int
+	switch entry.Level {
+	case log.PanicLevel, log.FatalLevel, log.ErrorLevel:
+		color = colorRed
+	case log.WarnLevel:
+		color = colorYellow
+	case log.InfoLevel:
+		color = colorCyan
+	default:
+		color = colorGray
+	}
+
+	level := strings.ToUpper(entry.Level.String())
+	if len(level) > 4 {
+		level = level[:4]
+	}
+	tag := fmt.Sprintf("%s/%s", f.Type, f.Name)
+	msg := entry.Message
+	f.Round.addFields(entry)
+
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "%s ", entry.Time.Format("15:04:05"))
+	if f.Color {
+		fmt.Fprintf(b, "\x1b[%dm%-4s\x1b[0m \x1b[%dm%-24s\x1b[0m ", color, level, colorGray, tag)
+		if color == colorRed {
+			msg = fmt.Sprintf("\x1b[%dm%s\x1b[0m", colorRed, msg)
+		}
+	} else {
+		fmt.Fprintf(b, "%-4s %-24s ", level, tag)
+	}
+	b.WriteString(msg)
+	for _, k := range keys {
+		fmt.Fprintf(b, " %s=%v", k, entry.Data[k])
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func makePrettyLogFormatter(pluginType string, pluginName string) PrettyLogFormatter {
+	return PrettyLogFormatter{
+		Type:  pluginType,
+		Name:  pluginName,
+		Color: true,
+	}
+}
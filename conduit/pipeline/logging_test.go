@@ -32,3 +32,97 @@ func TestPluginLogFormatter_Format(t *testing.T) {
 	assert.Equal(t, str, "{\"__type\":\"A Question\",\"_name\":\"What's in a name?\",\"level\":\"info\",\"msg\":\"That which we call a rose by any other name would smell just as sweet.\",\"time\":\"0001-01-01T00:00:00Z\"}\n")
 
 }
+
+// TestPrettyLogFormatter_Format tests the compact console output
+func TestPrettyLogFormatter_Format(t *testing.T) {
+	entry := &log.Entry{
+		Time:    time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:   log.InfoLevel,
+		Message: "round r=5 (2 txn) exported in 1ms",
+		Data:    log.Fields{"b": 2, "a": 1},
+		Logger:  log.New(),
+	}
+
+	formatter := PrettyLogFormatter{Type: "exporter", Name: "file_writer"}
+	bytes, err := formatter.Format(entry)
+	assert.Nil(t, err)
+	assert.Equal(t, "03:04:05 INFO exporter/file_writer     round r=5 (2 txn) exported in 1ms a=1 b=2\n", string(bytes))
+
+	entry.Level = log.ErrorLevel
+	formatter.Color = true
+	bytes, err = formatter.Format(entry)
+	assert.Nil(t, err)
+	assert.Contains(t, string(bytes), "\x1b[31mERRO\x1b[0m")
+	assert.Contains(t, string(bytes), "\x1b[31mround r=5 (2 txn) exported in 1ms\x1b[0m")
+}
+
+// TestMakeLogFormatter tests the formatters of the log formats.
+func TestMakeLogFormatter(t *testing.T) {
+	entry := &log.Entry{
+		Time:    time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:   log.WarnLevel,
+		Message: "slow round",
+		Data:    log.Fields{"round": 5},
+		Logger:  log.New(),
+	}
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"", `{"__type":"exporter","_name":"file_writer","level":"warning","msg":"slow round","round":5,"time":"2023-01-02T03:04:05Z"}` + "\n"},
+		{LogFormatJSON, `{"__type":"exporter","_name":"file_writer","level":"warning","msg":"slow round","round":5,"time":"2023-01-02T03:04:05Z"}` + "\n"},
+		{LogFormatText, `time="2023-01-02T03:04:05Z" level=warning msg="slow round" __type=exporter _name=file_writer round=5` + "\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			entry.Data = log.Fields{"round": 5}
+			bytes, err := MakeLogFormatter(tc.format, "exporter", "file_writer").Format(entry)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(bytes))
+		})
+	}
+}
+
+// TestRoundTag tests that the formatters tag the entries with the round and
+// trace ID, and keep the fields set by the plugin.
+func TestRoundTag(t *testing.T) {
+	tag := &RoundTag{}
+	entry := &log.Entry{
+		Time:    time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:   log.InfoLevel,
+		Message: "exported",
+		Data:    log.Fields{},
+		Logger:  log.New(),
+	}
+	formatter := makeRoundLogFormatter(LogFormatJSON, "exporter", "file_writer", tag)
+	format := func(data log.Fields) string {
+		entry.Data = data
+		bytes, err := formatter.Format(entry)
+		assert.NoError(t, err)
+		return string(bytes)
+	}
+
+	assert.Equal(t, `{"__type":"exporter","_name":"file_writer","level":"info","msg":"exported","time":"2023-01-02T03:04:05Z"}`+"\n", format(log.Fields{}))
+	tag.Set(7, "")
+	assert.Equal(t, `{"__type":"exporter","_name":"file_writer","level":"info","msg":"exported","round":7,"time":"2023-01-02T03:04:05Z"}`+"\n", format(log.Fields{}))
+	tag.Set(8, "4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.Equal(t, `{"__type":"exporter","_name":"file_writer","level":"info","msg":"exported","round":8,"time":"2023-01-02T03:04:05Z","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"}`+"\n", format(log.Fields{}))
+	assert.Contains(t, format(log.Fields{"round": 3}), `"round":3,`)
+	tag.Clear()
+	assert.NotContains(t, format(log.Fields{}), "round")
+
+	pretty := PrettyLogFormatter{Type: "exporter", Name: "file_writer", Round: tag}
+	tag.Set(9, "")
+	entry.Data = log.Fields{}
+	bytes, err := pretty.Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "03:04:05 INFO exporter/file_writer     exported round=9\n", string(bytes))
+
+	// A nil tag does not tag the entries.
+	var none *RoundTag
+	none.Set(1, "")
+	none.Clear()
+	entry.Data = log.Fields{}
+	none.addFields(entry)
+	assert.Empty(t, entry.Data)
+}
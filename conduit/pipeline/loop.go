@@ -0,0 +1,697 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/coordinator"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/tracing"
+)
+
+// step tells the pipeline loop how to go on after a stage of a round.
+type step int
+
+const (
+	// stepNext goes on with the next stage of the round.
+	stepNext step = iota
+	// stepRestart starts over from the top of the loop, to retry the round,
+	// handle a request or go on with the next round.
+	stepRestart
+	// stepStop exits the loop.
+	stepStop
+)
+
+// roundLoop is the state of a generation of the pipeline loop, it runs the
+// rounds one after the other.
+type roundLoop struct {
+	p        *pipelineImpl
+	gen      uint64
+	prefetch *prefetcher
+	// controlWake resumes a paused loop.
+	controlWake <-chan struct{}
+
+	retry uint64
+	// pending holds a prefetched round until it has been exported, so that
+	// processor and exporter retries reuse the block instead of skipping it.
+	pending *fetchResult
+	// exported tracks which exporters have received the current round, so
+	// that a retry only sends the block to the exporters which failed.
+	exported []bool
+	// deadLetter is the encoded block saved if the round is dead-lettered.
+	deadLetter []byte
+	// isolated are the transactions which processors failed in this
+	// attempt, they are recorded once the round is exported.
+	isolated []isolatedTxns
+	// roundSpan traces the current attempt of the round, it ends when the
+	// attempt succeeds or fails.
+	roundSpan *tracing.Span
+	// backoff applies the retry policy of the stage which failed.
+	backoff retryState
+	// flushFailed is set while a batch is retried, the batch rounds cannot
+	// be skipped.
+	flushFailed bool
+}
+
+func (p *pipelineImpl) makeRoundLoop(gen uint64, prefetch *prefetcher) *roundLoop {
+	return &roundLoop{
+		p:           p,
+		gen:         gen,
+		prefetch:    prefetch,
+		controlWake: p.controlWake(),
+		exported:    make([]bool, len(p.exporters)),
+	}
+}
+
+// run runs the rounds until the pipeline stops, or the watchdog abandons the
+// loop.
+func (r *roundLoop) run() {
+	defer r.endRoundSpan()
+	for {
+		r.endRoundSpan()
+		if !r.p.watchdog.alive(r.gen, time.Now()) {
+			return
+		}
+		if r.next() == stepStop {
+			return
+		}
+	}
+}
+
+func (r *roundLoop) endRoundSpan() {
+	r.roundSpan.End(r.p.Error())
+	r.roundSpan = nil
+	r.p.roundTag.Clear()
+}
+
+// fail records a failed attempt of the round, the retry policy of the stage
+// applies.
+func (r *roundLoop) fail(stage string, idx int, err error) {
+	p := r.p
+	r.retry++
+	r.backoff.failed(RetryFailure{
+		Round:  p.pipelineMetadata.NextRound,
+		Stage:  stage,
+		Plugin: p.cfg.pluginName(stage, idx),
+		Err:    err,
+		Class:  ErrorClass(err),
+		Retry:  r.retry,
+		Policy: p.cfg.classPolicy(p.cfg.retryPolicy(stage, idx), err),
+	}, time.Now())
+}
+
+// succeeded resets the retries once the round is done with.
+func (r *roundLoop) succeeded() {
+	r.retry = 0
+	r.backoff = retryState{}
+}
+
+// resetExported forgets which exporters received the round.
+func (r *roundLoop) resetExported() {
+	for idx := range r.exported {
+		r.exported[idx] = false
+	}
+}
+
+// restartPrefetch fetches the rounds again from the next round.
+func (r *roundLoop) restartPrefetch() {
+	r.prefetch.stop()
+	r.prefetch = r.p.startPrefetch()
+}
+
+// flush sends the pending batch before the pipeline stops.
+func (r *roundLoop) flush() {
+	if err := r.p.flushBatch(); err != nil {
+		r.p.logger.Errorf("%v", err)
+		r.p.setError(err)
+	}
+}
+
+// reload applies a new config between rounds, it returns false if the
+// pipeline stops because the config could not be applied.
+func (r *roundLoop) reload(req reloadRequest) bool {
+	p := r.p
+	// Reloaded exporters are initialized at the next round, so they receive
+	// the pending batch first.
+	if err := p.flushBatch(); err != nil {
+		req.result <- fmt.Errorf("Reload(): could not flush batch: %w", err)
+		return true
+	}
+	importerChanged := !reflect.DeepEqual(p.cfg.Importer.Config, req.cfg.Importer.Config)
+	if importerChanged && r.prefetch != nil {
+		// The importer must not be called while it is reloaded.
+		r.prefetch.stop()
+		r.pending = nil
+	}
+	err := p.applyReload(req.cfg, r.exported)
+	req.result <- err
+	if err != nil {
+		p.logger.Errorf("%v - stopping...", err)
+		p.setError(err)
+		p.writeSupportBundle(err.Error())
+		return false
+	}
+	if importerChanged && r.prefetch != nil {
+		r.prefetch = p.startPrefetch()
+	}
+	return true
+}
+
+// rollback rewinds the pipeline between rounds, the in-flight round is
+// fetched again from the rolled back round.
+func (r *roundLoop) rollback(req rollbackRequest) {
+	err := r.p.rollback(req.round)
+	req.result <- err
+	if err != nil {
+		r.p.logger.Error(err)
+		return
+	}
+	if r.prefetch != nil {
+		r.restartPrefetch()
+	}
+	r.pending = nil
+	r.deadLetter = nil
+	r.isolated = nil
+	r.resetExported()
+	r.succeeded()
+}
+
+func (r *roundLoop) debug(req debugRequest) {
+	round, err := r.p.debugRound(req, r.prefetch != nil)
+	req.result <- debugResult{round: round, err: err}
+}
+
+// next skips, retries or runs the next round, unless the pipeline stops or a
+// request is pending.
+func (r *roundLoop) next() step {
+	p := r.p
+	metrics.PipelineRetryCount.Observe(float64(r.retry))
+	p.setRetryCount(r.retry)
+	if r.backoff.exhausted && p.cfg.skipFailures() && !r.flushFailed {
+		p.rollbackIntents(p.pipelineMetadata.NextRound)
+		p.skipFailedRound(r.deadLetter, r.backoff.reason())
+		if r.prefetch != nil && r.pending == nil {
+			// The import failed and the prefetcher is still retrying the round.
+			r.restartPrefetch()
+		}
+		r.pending = nil
+		r.deadLetter = nil
+		r.resetExported()
+		r.succeeded()
+		return stepRestart
+	}
+	if p.cfg.Rounds.finished(p.pipelineMetadata.NextRound) && !r.flushFailed {
+		r.flush()
+		p.logger.Infof("Pipeline finished, round %d was the last round", p.cfg.Rounds.End)
+		return stepStop
+	}
+	if r.backoff.exhausted {
+		p.logger.Errorf("Pipeline round %d %s - stopping...", p.pipelineMetadata.NextRound, r.backoff.reason())
+		p.writeSupportBundle(fmt.Sprintf("round %d %s: %v", p.pipelineMetadata.NextRound, r.backoff.reason(), p.Error()))
+		return stepStop
+	}
+
+	if r.retry > 0 {
+		select {
+		case <-p.ctx.Done():
+			return stepStop
+		case <-p.stopCh:
+			return stepStop
+		case <-time.After(r.backoff.delay):
+		}
+	}
+
+	select {
+	case <-p.ctx.Done():
+		return stepStop
+	case <-p.stopCh:
+		r.flush()
+		p.logger.Infof("Pipeline stopped after round %d", p.pipelineMetadata.NextRound)
+		return stepStop
+	case req := <-p.reloadCh:
+		if !r.reload(req) {
+			return stepStop
+		}
+		return stepRestart
+	case result := <-p.checkpointCh:
+		result <- p.checkpoint()
+		return stepRestart
+	case req := <-p.rollbackCh:
+		r.rollback(req)
+		return stepRestart
+	case result := <-p.promoteCh:
+		result <- p.promote()
+		return stepRestart
+	case req := <-p.debugCh:
+		r.debug(req)
+		return stepRestart
+	default:
+	}
+
+	if s := r.hold(); s != stepNext {
+		return s
+	}
+	if s := r.flushDueBatch(); s != stepNext {
+		return s
+	}
+	if drift := p.checkSchemas(); drift != nil {
+		if !p.pauseForSchemaDrift(*drift) {
+			return stepStop
+		}
+		return stepRestart
+	}
+	p.checkStandby(time.Now())
+	return r.runRound()
+}
+
+// hold waits while the pipeline is paused by the operator or throttled,
+// reloads and checkpoints are still applied.
+func (r *roundLoop) hold() step {
+	p := r.p
+	held := p.holding(p.pipelineMetadata.NextRound)
+	var throttled <-chan time.Time
+	if !held {
+		if delay := p.throttleDelay(p.pipelineMetadata.NextRound, time.Now()); delay > 0 {
+			held = true
+			throttled = time.After(delay)
+		}
+	}
+	if !held {
+		return stepNext
+	}
+	select {
+	case <-p.ctx.Done():
+		return stepStop
+	case <-p.stopCh:
+	case <-r.controlWake:
+	case <-throttled:
+	case req := <-p.reloadCh:
+		if !r.reload(req) {
+			return stepStop
+		}
+	case result := <-p.checkpointCh:
+		result <- p.checkpoint()
+	case req := <-p.rollbackCh:
+		r.rollback(req)
+	case result := <-p.promoteCh:
+		result <- p.promote()
+	case req := <-p.debugCh:
+		r.debug(req)
+	}
+	return stepRestart
+}
+
+// flushDueBatch sends the pending batch once it is due, a failed batch is
+// retried before the next round.
+func (r *roundLoop) flushDueBatch() step {
+	p := r.p
+	if !p.batch.due(time.Now()) {
+		return stepNext
+	}
+	err := p.flushBatch()
+	if p.watchdog.abandoned(r.gen) {
+		return stepStop
+	}
+	if err != nil {
+		p.logger.Errorf("%v", err)
+		p.setError(err)
+		idx := 0
+		var be batchError
+		if errors.As(err, &be) {
+			idx = be.idx
+		}
+		if p.onAuthFailure(plugins.Exporter, *p.exporters[idx], err) {
+			return stepStop
+		}
+		r.flushFailed = true
+		r.fail(exporterStage, idx, err)
+		return stepRestart
+	}
+	if r.flushFailed {
+		r.flushFailed = false
+		p.setError(nil)
+		r.succeeded()
+	}
+	return stepNext
+}
+
+// runRound imports, processes and exports the next round.
+func (r *roundLoop) runRound() step {
+	p := r.p
+	p.logger.Infof("Pipeline round: %v", p.pipelineMetadata.NextRound)
+	r.roundSpan = p.tracer.Start("round", time.Now(),
+		tracing.Uint64("conduit.round", p.pipelineMetadata.NextRound),
+		tracing.Uint64("conduit.retry", r.retry))
+	p.roundTag.Set(p.pipelineMetadata.NextRound, r.roundSpan.TraceID())
+
+	blkData, s := r.importRound()
+	if s != stepNext {
+		return s
+	}
+	if s = r.checkRound(&blkData); s != stepNext {
+		return s
+	}
+
+	// Start time currently measures operations after block fetching is complete.
+	// This is for backwards compatibility w/ Indexer's metrics
+	start := time.Now()
+	if s = r.processRound(&blkData); s != stepNext {
+		return s
+	}
+	if s = r.claimRound(blkData); s != stepNext {
+		return s
+	}
+	exporterStart := time.Now()
+	if s = r.exportRound(blkData); s != stepNext {
+		return s
+	}
+	return r.finishRound(blkData, start, exporterStart)
+}
+
+// importRound fetches the next round from the importer, or the prefetcher.
+func (r *roundLoop) importRound() (data.BlockData, step) {
+	p := r.p
+	var blkData data.BlockData
+	var importTime time.Duration
+	var err error
+	if r.prefetch == nil {
+		importStart := time.Now()
+		span := pluginSpan(r.roundSpan, "importer.GetBlock", p.importerName(), importStart)
+		p.importerRoundTag.Set(p.pipelineMetadata.NextRound, r.roundSpan.TraceID())
+		op := p.activity.start(importerType, p.importerName(), p.pipelineMetadata.NextRound)
+		p.telemetry.importer(func() {
+			blkData, err = (*p.importer).GetBlock(p.pipelineMetadata.NextRound)
+		})
+		p.activity.done(op)
+		p.importerRoundTag.Clear()
+		span.End(err)
+		importTime = time.Since(importStart)
+	} else {
+		if r.pending == nil {
+			result, ok := r.prefetch.next(p.ctx, p.stopCh)
+			if !ok {
+				return blkData, stepStop
+			}
+			r.pending = &result
+		}
+		blkData, importTime, err = r.pending.blk, r.pending.importTime, r.pending.err
+		span := pluginSpan(r.roundSpan, "importer.GetBlock", p.importerName(), r.pending.start)
+		span.SetAttributes(tracing.Bool("conduit.prefetched", true))
+		span.EndAt(r.pending.start.Add(importTime), err)
+		if err != nil {
+			r.pending = nil
+		}
+	}
+	if p.watchdog.abandoned(r.gen) {
+		return blkData, stepStop
+	}
+	if err == nil {
+		err = p.validateImported(&blkData)
+		if err != nil && r.pending != nil {
+			// Fetch the round again instead of retrying the invalid block.
+			r.pending = nil
+			r.restartPrefetch()
+		}
+	}
+	if err != nil {
+		p.logger.Errorf("%v", err)
+		p.setError(err)
+		if p.onAuthFailure(plugins.Importer, *p.importer, err) {
+			return blkData, stepStop
+		}
+		r.deadLetter = nil
+		r.fail(importerStage, 0, err)
+		return blkData, stepRestart
+	}
+	if !p.pace(&blkData) {
+		return blkData, stepStop
+	}
+	metrics.ObserveLatency(metrics.ImporterTimeSeconds, importTime.Seconds(), r.roundSpan.TraceID())
+	p.debugCache.add(blkData)
+	p.blockCache.add(blkData)
+	if p.cfg.OnFailure == onFailureDeadLetter {
+		// Processors may modify the block, so save a copy before running them.
+		r.deadLetter = msgpack.Encode(blkData)
+	}
+	return blkData, stepNext
+}
+
+// checkRound checks the protocol, the transaction types and the timestamp of
+// the imported round.
+func (r *roundLoop) checkRound(blkData *data.BlockData) step {
+	p := r.p
+	if err := p.checkProtocol(blkData); err != nil {
+		if p.onUnknownProtocol(err, r.deadLetter) {
+			return stepStop
+		}
+		if p.cfg.UnknownProtocol == unknownProtocolSkip {
+			if p.cfg.ReuseBlockData {
+				blkData.Release()
+			}
+			r.pending = nil
+			r.deadLetter = nil
+			r.succeeded()
+			return stepRestart
+		}
+	}
+	if err := p.checkTxnTypes(blkData); err != nil {
+		p.logger.WithField("alert", "unknown-txn-type").Errorf("%v - stopping...", err)
+		p.setError(err)
+		p.writeSupportBundle(err.Error())
+		return stepStop
+	}
+	if err := p.checkClockSkew(blkData, time.Now()); err != nil {
+		p.logger.WithField("alert", "clock-skew").Errorf("%v - stopping...", err)
+		p.setError(err)
+		p.writeSupportBundle(err.Error())
+		return stepStop
+	}
+	return stepNext
+}
+
+// processRound runs the round through the processors.
+func (r *roundLoop) processRound(blkData *data.BlockData) step {
+	p := r.p
+	replay := p.cfg.DeterminismCheck.replay(p.pipelineMetadata.NextRound)
+	r.isolated = nil
+	for idx, proc := range p.processors {
+		match, err := matchCondition(p.processorConditions, idx, blkData)
+		if err == nil && !match {
+			continue
+		}
+		processorName := p.processorName(idx)
+		processorStart := time.Now()
+		span := pluginSpan(r.roundSpan, "processor.Process", processorName, processorStart)
+		op := p.activity.start(processorType, processorName, p.pipelineMetadata.NextRound)
+		if err == nil && replay {
+			p.telemetry.processor(idx, func() {
+				*blkData, err = processReplayed(*proc, p.windows[proc], *blkData)
+			})
+			var mismatch mismatchError
+			if errors.As(err, &mismatch) {
+				if p.checkMismatch(mismatch) {
+					return stepStop
+				}
+				err = nil
+			}
+		} else if err == nil {
+			p.telemetry.processor(idx, func() {
+				*blkData, err = runProcessor(*proc, p.windows[proc], *blkData, true)
+			})
+		}
+		p.activity.done(op)
+		span.End(err)
+		if p.watchdog.abandoned(r.gen) {
+			return stepStop
+		}
+		var partial *conduit.PartialError
+		if err != nil && p.cfg.skipFailures() && errors.As(err, &partial) {
+			p.logger.Warnf("processor (%s) failed transactions of round %d, exporting the rest: %v", processorName, p.pipelineMetadata.NextRound, err)
+			r.isolated = append(r.isolated, isolatedTxns{processor: processorName, err: partial})
+			err = nil
+		}
+		if err != nil {
+			p.logger.Errorf("%v", err)
+			p.setError(err)
+			r.fail(processorStage, idx, err)
+			return stepRestart
+		}
+		metrics.ObserveLatency(metrics.ProcessorTimeSeconds.WithLabelValues(metrics.ProcessorLabel(p.cfg.Metrics.ProcessorLabels, processorName)), time.Since(processorStart).Seconds(), r.roundSpan.TraceID())
+	}
+	return stepNext
+}
+
+// claimRound claims the round from the coordinator, a round which another
+// instance exported is skipped.
+func (r *roundLoop) claimRound(blkData data.BlockData) step {
+	p := r.p
+	if p.coordinator == nil {
+		return stepNext
+	}
+	claim, ok, err := p.claimRound()
+	if !ok {
+		return stepStop
+	}
+	if err != nil {
+		p.logger.Errorf("%v", err)
+		p.setError(err)
+		r.fail("", 0, err)
+		return stepRestart
+	}
+	if claim != coordinator.Completed {
+		return stepNext
+	}
+	// Another instance exported the round.
+	p.logger.Infof("round r=%d was exported by another instance", p.pipelineMetadata.NextRound)
+	p.pipelineMetadata.NextRound++
+	p.dropIntents(p.pipelineMetadata.NextRound)
+	r.pending = nil
+	r.resetExported()
+	p.setRoundExported(p.pipelineMetadata.NextRound-1, p.pipelineMetadata.NextRound, time.Unix(blkData.BlockHeader.TimeStamp, 0))
+	if err = p.saveMetadata(); err != nil {
+		p.logger.Errorf("%v", err)
+	}
+	p.setError(nil)
+	r.succeeded()
+	return stepRestart
+}
+
+// exportRound sends the round to the exporters which did not receive it yet.
+func (r *roundLoop) exportRound(blkData data.BlockData) step {
+	p := r.p
+	for _, idx := range p.exportOrder() {
+		exporter := p.exporters[idx]
+		exporterName := p.exporterName(idx)
+		if r.exported[idx] {
+			continue
+		}
+		if p.isRetired(idx) || p.isStandby(idx) {
+			r.exported[idx] = true
+			continue
+		}
+		match, err := matchCondition(p.exporterConditions, idx, &blkData)
+		if err == nil && !match {
+			r.exported[idx] = true
+			continue
+		}
+		exportBlk := blkData
+		if err == nil {
+			exportBlk, match, err = p.laneBlock(idx, blkData)
+			if err == nil && !match {
+				r.exported[idx] = true
+				continue
+			}
+		}
+		if err == nil {
+			exportBlk, err = p.processForExporter(idx, r.roundSpan, exportBlk)
+		}
+		if err == nil && p.batch.add(idx, exportBlk) {
+			r.exported[idx] = true
+			continue
+		}
+		if err == nil {
+			span := pluginSpan(r.roundSpan, "exporter.Receive", exporterName, time.Now())
+			op := p.activity.start(exporterType, exporterName, exportBlk.Round())
+			p.telemetry.exporter(idx, func() {
+				err = p.receive(idx, *exporter, exportBlk)
+			})
+			p.activity.done(op)
+			span.End(err)
+			if p.watchdog.abandoned(r.gen) {
+				return stepStop
+			}
+		}
+		if err != nil && p.duplicateRound(exporterName, err) {
+			err = nil
+		}
+		if isSchemaDrift(err) {
+			// The block is kept and the round is not retried until the
+			// schema is restored.
+			if !p.pauseForSchemaDrift(schemaDrift{exporter: exporterName, err: err}) {
+				return stepStop
+			}
+			return stepRestart
+		}
+		if err != nil && p.isBestEffort(idx) {
+			p.logger.Warnf("best-effort exporter (%s) skipped round %d: %v", exporterName, p.pipelineMetadata.NextRound, err)
+		} else if err != nil {
+			p.logger.Errorf("%v", err)
+			p.setError(err)
+			if p.onAuthFailure(plugins.Exporter, *exporter, err) {
+				return stepStop
+			}
+			r.fail(exporterStage, idx, err)
+			return stepRestart
+		}
+		r.exported[idx] = true
+	}
+	return stepNext
+}
+
+// finishRound advances the pipeline past the exported round, saves the
+// metadata and calls the OnComplete callbacks.
+func (r *roundLoop) finishRound(blkData data.BlockData, start, exporterStart time.Time) step {
+	p := r.p
+	p.logger.Infof("round r=%d (%d txn) exported in %s", p.pipelineMetadata.NextRound, len(blkData.Payset), time.Since(start))
+	if p.coordinator != nil {
+		p.completeRound(p.pipelineMetadata.NextRound)
+	}
+
+	p.verifyMigration(p.pipelineMetadata.NextRound)
+	p.verifyRound(&blkData)
+	p.closeRetired()
+
+	// Increment Round, update metadata
+	p.isolateTxns(p.pipelineMetadata.NextRound, r.isolated)
+	r.isolated = nil
+	p.pipelineMetadata.NextRound++
+	p.dropIntents(p.pipelineMetadata.NextRound)
+	r.pending = nil
+	r.resetExported()
+	p.batch.roundDone(p.pipelineMetadata.NextRound-1, time.Now())
+	if p.batch != nil {
+		p.activity.setBatch(p.batch.rounds, p.batch.cfg.Size)
+	}
+	p.setRoundExported(p.pipelineMetadata.NextRound-1, p.pipelineMetadata.NextRound, time.Unix(blkData.BlockHeader.TimeStamp, 0))
+	exportTime := time.Now()
+	p.pipelineMetadata.LastExportTime = &exportTime
+	if err := p.saveMetadata(); err != nil {
+		p.logger.Errorf("%v", err)
+	}
+	p.observe(blkData)
+
+	// Callback Processors
+	for _, cb := range p.completeCallback {
+		err := cb(blkData)
+		if p.watchdog.abandoned(r.gen) {
+			return stepStop
+		}
+		if err != nil {
+			p.logger.Errorf("%v", err)
+			p.setError(err)
+			r.fail("", 0, err)
+			return stepRestart
+		}
+	}
+	metrics.ObserveLatency(metrics.ExporterTimeSeconds, time.Since(exporterStart).Seconds(), r.roundSpan.TraceID())
+	if err := p.telemetry.sampleGoroutines(); err != nil {
+		p.logger.Warnf("could not count plugin goroutines: %v", err)
+	}
+	// Ignore round 0 (which is empty).
+	if p.pipelineMetadata.NextRound > 1 {
+		p.addMetrics(blkData, time.Since(start), r.roundSpan.TraceID())
+	}
+	if p.cfg.ReuseBlockData {
+		blkData.Release()
+	}
+	p.setError(nil)
+	r.succeeded()
+	return stepNext
+}
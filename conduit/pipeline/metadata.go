@@ -6,6 +6,7 @@ import (
 	"github.com/algorand/conduit/conduit"
 	"github.com/algorand/conduit/conduit/plugins/exporters"
 	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/observers"
 	"github.com/algorand/conduit/conduit/plugins/processors"
 )
 
@@ -14,6 +15,7 @@ func AllMetadata() (results []conduit.Metadata) {
 	results = append(results, ImporterMetadata()...)
 	results = append(results, ProcessorMetadata()...)
 	results = append(results, ExporterMetadata()...)
+	results = append(results, ObserverMetadata()...)
 	return
 }
 
@@ -43,3 +45,12 @@ func ExporterMetadata() (results []conduit.Metadata) {
 	}
 	return
 }
+
+// ObserverMetadata gets a slice with metadata for all observers.Observer plugins.
+func ObserverMetadata() (results []conduit.Metadata) {
+	for _, constructor := range observers.Observers {
+		plugin := constructor.New()
+		results = append(results, plugin.Metadata())
+	}
+	return
+}
@@ -12,6 +12,7 @@ import (
 	_ "github.com/algorand/conduit/conduit/plugins/exporters/all"
 	_ "github.com/algorand/conduit/conduit/plugins/exporters/example"
 	_ "github.com/algorand/conduit/conduit/plugins/importers/all"
+	_ "github.com/algorand/conduit/conduit/plugins/observers/all"
 	_ "github.com/algorand/conduit/conduit/plugins/processors/all"
 )
 
@@ -0,0 +1,293 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+)
+
+const (
+	// ComparatorSummary compares the conduit.ParityReporter summaries of the
+	// exporters, it is the default.
+	ComparatorSummary = "summary"
+	// ComparatorReceived only verifies that both exporters exported the round.
+	ComparatorReceived = "received"
+)
+
+// Migration configs an exporter swap: the new exporter runs next to the
+// exporter it replaces until they have exported the same data for Window
+// rounds, then the old exporter is retired.
+type Migration struct {
+	// From is the name of the exporter being replaced.
+	From string `yaml:"from"`
+	// To is the name of the new exporter.
+	To string `yaml:"to"`
+	// Window is the number of consecutive matching rounds required before
+	// cutting over.
+	Window uint64 `yaml:"window"`
+	// Comparator is the name of the registered Comparator which verifies the
+	// rounds, the default is ComparatorSummary.
+	Comparator string `yaml:"comparator"`
+	// AutoCutover cuts over as soon as the window is verified, otherwise the
+	// cutover is requested with the /cutover endpoint.
+	AutoCutover bool `yaml:"auto-cutover"`
+}
+
+// Valid validates the migration config.
+func (m Migration) Valid(exporterNames map[string]bool) error {
+	if !m.enabled() {
+		return nil
+	}
+	if !exporterNames[m.From] {
+		return fmt.Errorf("from exporter (%s) is not configured", m.From)
+	}
+	if !exporterNames[m.To] {
+		return fmt.Errorf("to exporter (%s) is not configured", m.To)
+	}
+	if m.From == m.To {
+		return fmt.Errorf("from and to must be different exporters")
+	}
+	if m.Window == 0 {
+		return fmt.Errorf("window must be positive")
+	}
+	if _, ok := lookupComparator(m.comparator()); !ok {
+		return fmt.Errorf("unknown comparator (%s)", m.comparator())
+	}
+	return nil
+}
+
+func (m Migration) enabled() bool {
+	return m.From != "" || m.To != ""
+}
+
+func (m Migration) comparator() string {
+	if m.Comparator == "" {
+		return ComparatorSummary
+	}
+	return m.Comparator
+}
+
+// Comparator verifies that two exporters exported a round the same way.
+type Comparator interface {
+	// Compare returns an error describing the difference between the round
+	// exported by from and to.
+	Compare(round uint64, from, to exporters.Exporter) error
+}
+
+// ComparatorFunc is Comparator implemented by a function.
+type ComparatorFunc func(round uint64, from, to exporters.Exporter) error
+
+// Compare calls f.
+func (f ComparatorFunc) Compare(round uint64, from, to exporters.Exporter) error {
+	return f(round, from, to)
+}
+
+var (
+	comparatorsMu sync.RWMutex
+	comparators   = map[string]Comparator{
+		ComparatorSummary:  ComparatorFunc(compareSummaries),
+		ComparatorReceived: ComparatorFunc(func(uint64, exporters.Exporter, exporters.Exporter) error { return nil }),
+	}
+)
+
+// RegisterComparator makes a Comparator available to the migration config
+// under name. It should be called from an init function.
+func RegisterComparator(name string, comparator Comparator) {
+	comparatorsMu.Lock()
+	defer comparatorsMu.Unlock()
+	comparators[name] = comparator
+}
+
+func lookupComparator(name string) (Comparator, bool) {
+	comparatorsMu.RLock()
+	defer comparatorsMu.RUnlock()
+	c, ok := comparators[name]
+	return c, ok
+}
+
+// compareSummaries compares the conduit.ParityReporter summaries of the round.
+func compareSummaries(round uint64, from, to exporters.Exporter) error {
+	summary := func(exporter exporters.Exporter) (string, error) {
+		reporter, ok := exporter.(conduit.ParityReporter)
+		if !ok {
+			return "", fmt.Errorf("exporter (%s) does not report parity summaries", exporter.Metadata().Name)
+		}
+		s, err := reporter.ParitySummary(round)
+		if err != nil {
+			return "", fmt.Errorf("exporter (%s) summary: %w", exporter.Metadata().Name, err)
+		}
+		return s, nil
+	}
+	fromSummary, err := summary(from)
+	if err != nil {
+		return err
+	}
+	toSummary, err := summary(to)
+	if err != nil {
+		return err
+	}
+	if fromSummary != toSummary {
+		return fmt.Errorf("summaries differ: %s has %q, %s has %q", from.Metadata().Name, fromSummary, to.Metadata().Name, toSummary)
+	}
+	return nil
+}
+
+// MigrationStatus is the progress of the migration, it is saved with the
+// pipeline metadata and reported by the /status endpoint.
+type MigrationStatus struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Start is the first round exported by both exporters.
+	Start uint64 `json:"start"`
+	// Matched is the number of consecutive rounds which matched.
+	Matched uint64 `json:"matched"`
+	Window  uint64 `json:"window"`
+	// Mismatch describes the last round which did not match, it restarts the window.
+	Mismatch      string `json:"mismatch,omitempty"`
+	MismatchRound uint64 `json:"mismatch-round,omitempty"`
+	// CutOver is set once the from exporter has been retired.
+	CutOver bool `json:"cut-over"`
+}
+
+// Verified reports whether the window of matching rounds is complete.
+func (s MigrationStatus) Verified() bool {
+	return s.Matched >= s.Window
+}
+
+// migrationRun is the runtime state of the migration, it is guarded by p.mu.
+type migrationRun struct {
+	// from and to are the exporter indexes.
+	from, to   int
+	comparator Comparator
+	// cutover is set when the cutover was requested.
+	cutover bool
+	// closed is set once the retired exporter has been closed.
+	closed bool
+}
+
+// initMigration starts or resumes the configured migration, it is called once
+// the pipeline metadata is loaded.
+func (p *pipelineImpl) initMigration() error {
+	p.migration = nil
+	m := p.cfg.Migration
+	if !m.enabled() {
+		if p.pipelineMetadata.Migration != nil {
+			p.logger.Infof("Migration from %s to %s is no longer configured", p.pipelineMetadata.Migration.From, p.pipelineMetadata.Migration.To)
+			p.pipelineMetadata.Migration = nil
+		}
+		return nil
+	}
+	comparator, ok := lookupComparator(m.comparator())
+	if !ok {
+		return fmt.Errorf("initMigration(): unknown comparator (%s)", m.comparator())
+	}
+	run := &migrationRun{from: -1, to: -1, comparator: comparator}
+	for idx, id := range p.cfg.exporterIDs() {
+		switch id {
+		case m.From:
+			run.from = idx
+		case m.To:
+			run.to = idx
+		}
+	}
+	if run.from < 0 || run.to < 0 {
+		return fmt.Errorf("initMigration(): exporters %s and %s must both be configured", m.From, m.To)
+	}
+
+	status := p.pipelineMetadata.Migration
+	if status == nil || status.From != m.From || status.To != m.To {
+		status = &MigrationStatus{From: m.From, To: m.To, Start: p.pipelineMetadata.NextRound}
+		p.logger.Infof("Migration from %s to %s starts at round %d", m.From, m.To, status.Start)
+	} else if status.CutOver {
+		p.logger.Warnf("Exporter (%s) was retired by the migration to %s, remove it and the migration from the configuration", m.From, m.To)
+	}
+	status.Window = m.Window
+	p.pipelineMetadata.Migration = status
+	p.migration = run
+	return nil
+}
+
+// isRetired reports whether the exporter at idx was retired by the migration.
+func (p *pipelineImpl) isRetired(idx int) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.migration != nil && p.migration.from == idx && p.pipelineMetadata.Migration.CutOver
+}
+
+// verifyMigration compares the round exported by both exporters, then cuts
+// over if the window is verified and the cutover was requested. It is called
+// by the pipeline loop once the round was exported.
+func (p *pipelineImpl) verifyMigration(round uint64) {
+	if p.migration == nil || p.pipelineMetadata.Migration.CutOver {
+		return
+	}
+	from, to := *p.exporters[p.migration.from], *p.exporters[p.migration.to]
+	err := p.migration.comparator.Compare(round, from, to)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := p.pipelineMetadata.Migration
+	if err != nil {
+		p.logger.Warnf("Migration from %s to %s: round %d does not match, restarting the window: %v", status.From, status.To, round, err)
+		status.Matched = 0
+		status.Mismatch = err.Error()
+		status.MismatchRound = round
+		return
+	}
+	status.Matched++
+	if status.Matched == status.Window {
+		p.logger.Infof("Migration from %s to %s: %d rounds verified", status.From, status.To, status.Window)
+	}
+	if status.Verified() && (p.migration.cutover || p.cfg.Migration.AutoCutover) {
+		status.CutOver = true
+		p.logger.Infof("Migration from %s to %s: cut over after round %d, retiring %s", status.From, status.To, round, status.From)
+	}
+}
+
+// closeRetired closes the exporter retired by the migration. It is called by
+// the pipeline loop when no plugin calls are in flight.
+func (p *pipelineImpl) closeRetired() {
+	if p.migration == nil || p.migration.closed || !p.isRetired(p.migration.from) {
+		return
+	}
+	exporter := *p.exporters[p.migration.from]
+	if err := exporter.Close(); err != nil {
+		p.logger.Warnf("Exporter (%s) error on close: %v", exporter.Metadata().Name, err)
+	}
+	p.mu.Lock()
+	p.migration.closed = true
+	p.mu.Unlock()
+}
+
+// CutOver retires the from exporter of the migration once the window is
+// verified. The cutover is applied after the next round is exported by both
+// exporters.
+func (p *pipelineImpl) CutOver() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.migration == nil {
+		return fmt.Errorf("CutOver(): no migration is configured")
+	}
+	status := p.pipelineMetadata.Migration
+	if status.CutOver {
+		return nil
+	}
+	if !status.Verified() {
+		return fmt.Errorf("CutOver(): %d of %d rounds verified", status.Matched, status.Window)
+	}
+	p.migration.cutover = true
+	p.logger.Infof("Migration from %s to %s: cutover requested", status.From, status.To)
+	return nil
+}
+
+// migrationStatus returns a copy of the migration progress, it must be called
+// with p.mu held.
+func (p *pipelineImpl) migrationStatus() *MigrationStatus {
+	if p.migration == nil || p.pipelineMetadata.Migration == nil {
+		return nil
+	}
+	status := *p.pipelineMetadata.Migration
+	return &status
+}
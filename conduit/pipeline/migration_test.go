@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+)
+
+// parityExporter implements conduit.ParityReporter, the summary of a round is
+// the round number unless it is in differ.
+type parityExporter struct {
+	roundExporter
+	differ map[uint64]bool
+	closed int
+}
+
+func (e *parityExporter) ParitySummary(round uint64) (string, error) {
+	if e.differ[round] {
+		return "different", nil
+	}
+	return fmt.Sprintf("round %d", round), nil
+}
+
+func (e *parityExporter) Close() error {
+	e.closed++
+	return nil
+}
+
+func TestMigrationValid(t *testing.T) {
+	names := map[string]bool{"old": true, "new": true}
+	tests := []struct {
+		name      string
+		migration Migration
+		err       string
+	}{
+		{"disabled", Migration{}, ""},
+		{"valid", Migration{From: "old", To: "new", Window: 10}, ""},
+		{"received", Migration{From: "old", To: "new", Window: 10, Comparator: ComparatorReceived}, ""},
+		{"unknown from", Migration{From: "older", To: "new", Window: 10}, "from exporter (older) is not configured"},
+		{"unknown to", Migration{From: "old", To: "newer", Window: 10}, "to exporter (newer) is not configured"},
+		{"same exporter", Migration{From: "old", To: "old", Window: 10}, "from and to must be different exporters"},
+		{"no window", Migration{From: "old", To: "new"}, "window must be positive"},
+		{"unknown comparator", Migration{From: "old", To: "new", Window: 10, Comparator: "rows"}, "unknown comparator (rows)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.migration.Valid(names)
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func makeMigrationPipeline(t *testing.T, migration Migration, from, to exporters.Exporter) *pipelineImpl {
+	pImpl := makeCheckpointPipeline(t, from, to)
+	pImpl.cfg.Migration = migration
+	require.NoError(t, pImpl.initMigration())
+	return pImpl
+}
+
+// TestPipelineMigration tests that the old exporter is retired once the window
+// of matching rounds is verified.
+func TestPipelineMigration(t *testing.T) {
+	tests := []struct {
+		name       string
+		differ     map[uint64]bool
+		comparator string
+		// retired is the last round received by the old exporter.
+		retired  uint64
+		matched  uint64
+		mismatch uint64
+	}{
+		{name: "match", retired: 2, matched: 3},
+		{name: "mismatch", differ: map[uint64]bool{1: true}, retired: 4, matched: 3, mismatch: 1},
+		{name: "received", differ: map[uint64]bool{1: true}, comparator: ComparatorReceived, retired: 2, matched: 3},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			from := &parityExporter{roundExporter: roundExporter{name: "old"}}
+			to := &parityExporter{roundExporter: roundExporter{name: "new"}, differ: tc.differ}
+			pImpl := makeMigrationPipeline(t, Migration{From: "old", To: "new", Window: 3, Comparator: tc.comparator, AutoCutover: true}, from, to)
+			pImpl.cfg.Rounds.End = 7
+
+			pImpl.Start()
+			pImpl.Wait()
+			require.NoError(t, pImpl.Error())
+
+			var expected []uint64
+			for round := uint64(0); round <= tc.retired; round++ {
+				expected = append(expected, round)
+			}
+			assert.Equal(t, expected, from.received())
+			assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5, 6, 7}, to.received())
+			assert.Equal(t, 1, from.closed)
+
+			status := readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir).Migration
+			require.NotNil(t, status)
+			assert.True(t, status.CutOver)
+			assert.Equal(t, tc.matched, status.Matched)
+			assert.Equal(t, tc.mismatch, status.MismatchRound)
+			assert.Equal(t, status, pImpl.Status().Migration)
+
+			// The retired exporter is not closed again.
+			pImpl.Stop()
+			assert.Equal(t, 1, from.closed)
+			assert.Equal(t, 1, to.closed)
+		})
+	}
+}
+
+// TestPipelineMigrationCutOver tests that the cutover is only accepted once the
+// window is verified.
+func TestPipelineMigrationCutOver(t *testing.T) {
+	pImpl := makeCheckpointPipeline(t, &roundExporter{name: "old"})
+	assert.EqualError(t, pImpl.CutOver(), "CutOver(): no migration is configured")
+
+	from := &parityExporter{roundExporter: roundExporter{name: "old"}}
+	to := &parityExporter{roundExporter: roundExporter{name: "new"}}
+	pImpl = makeMigrationPipeline(t, Migration{From: "old", To: "new", Window: 10}, from, to)
+	pImpl.cfg.Rounds.End = 4
+	assert.EqualError(t, pImpl.CutOver(), "CutOver(): 0 of 10 rounds verified")
+
+	pImpl.Start()
+	pImpl.Wait()
+	require.NoError(t, pImpl.Error())
+	status := pImpl.Status().Migration
+	require.NotNil(t, status)
+	assert.Equal(t, uint64(5), status.Matched)
+	assert.False(t, status.CutOver, "the cutover is not automatic")
+	assert.EqualError(t, pImpl.CutOver(), "CutOver(): 5 of 10 rounds verified")
+
+	// The migration resumes after a restart, once verified the cutover is
+	// applied after the next round.
+	pImpl.pipelineMetadata.Migration.Matched = 10
+	pImpl.cfg.Rounds.End = 6
+	require.NoError(t, pImpl.CutOver())
+	pImpl.Start()
+	pImpl.Wait()
+	require.NoError(t, pImpl.Error())
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5}, from.received())
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5, 6}, to.received())
+	assert.True(t, pImpl.Status().Migration.CutOver)
+}
+
+// TestCompareSummaries tests the summary comparator.
+func TestCompareSummaries(t *testing.T) {
+	from := &parityExporter{roundExporter: roundExporter{name: "old"}}
+	to := &parityExporter{roundExporter: roundExporter{name: "new"}, differ: map[uint64]bool{2: true}}
+	assert.NoError(t, compareSummaries(1, from, to))
+	assert.EqualError(t, compareSummaries(2, from, to), `summaries differ: old has "round 2", new has "different"`)
+	assert.EqualError(t, compareSummaries(1, from, &roundExporter{name: "plain"}), "exporter (plain) does not report parity summaries")
+}
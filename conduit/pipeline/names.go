@@ -0,0 +1,66 @@
+package pipeline
+
+// pluginNames are the names of the plugins. They are looked up once when the
+// plugins are initialized, so that the rounds do not call Metadata.
+type pluginNames struct {
+	importer   string
+	processors []string
+	exporters  []string
+}
+
+// importerName returns the name of the importer, the configured name until it
+// is initialized.
+func (p *pipelineImpl) importerName() string {
+	if p.names.importer != "" {
+		return p.names.importer
+	}
+	return p.cfg.Importer.Name
+}
+
+// processorName returns the name of the processor at idx, the configured name
+// until it is initialized.
+func (p *pipelineImpl) processorName(idx int) string {
+	if idx < len(p.names.processors) && p.names.processors[idx] != "" {
+		return p.names.processors[idx]
+	}
+	if idx < len(p.cfg.Processors) {
+		return p.cfg.Processors[idx].Name
+	}
+	return ""
+}
+
+// exporterName returns the ID of the exporter at idx.
+func (p *pipelineImpl) exporterName(idx int) string {
+	if idx < len(p.names.exporters) && p.names.exporters[idx] != "" {
+		return p.names.exporters[idx]
+	}
+	if ids := p.cfg.exporterIDs(); idx < len(ids) {
+		return ids[idx]
+	}
+	return ""
+}
+
+// exporterID returns the name of the exporter plugin at idx, or its ID if the
+// exporter has an alias or shares its name with another exporter.
+func (p *pipelineImpl) exporterID(idx int) string {
+	exporterCfgs := p.cfg.exporterConfigs()
+	if ids := p.cfg.exporterIDs(); idx < len(ids) && ids[idx] != exporterCfgs[idx].Name {
+		return ids[idx]
+	}
+	return (*p.exporters[idx]).Metadata().Name
+}
+
+// chainProcessorName returns the name of the processor at procIdx of the
+// sub-chain of the exporter at idx, the configured name until it is
+// initialized.
+func (p *pipelineImpl) chainProcessorName(idx, procIdx int) string {
+	if idx < len(p.exporterChains) && p.exporterChains[idx] != nil {
+		if names := p.exporterChains[idx].names; procIdx < len(names) && names[procIdx] != "" {
+			return names[procIdx]
+		}
+	}
+	if exporterCfgs := p.cfg.exporterConfigs(); idx < len(exporterCfgs) && procIdx < len(exporterCfgs[idx].Processors) {
+		return exporterCfgs[idx].Processors[procIdx].Name
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+	"github.com/algorand/conduit/conduit/plugins/observers"
+)
+
+// observerQueueSize is the number of rounds which may wait for an observer,
+// later rounds are dropped for it until it catches up.
+const observerQueueSize = 16
+
+// observerRun feeds the exported rounds to an observer from its own goroutine,
+// so that a slow or failing observer does not hold back the pipeline.
+type observerRun struct {
+	observer observers.Observer
+	name     string
+	logger   *log.Logger
+	queue    chan data.BlockData
+	done     chan struct{}
+}
+
+func (r *observerRun) run() {
+	defer close(r.done)
+	for blk := range r.queue {
+		if err := r.observe(blk); err != nil {
+			metrics.ObserverErrors.WithLabelValues(r.name).Inc()
+			r.logger.Errorf("observer (%s) failed to observe round %d: %v", r.name, blk.Round(), err)
+		}
+	}
+}
+
+// observe calls the observer, a panic is returned as an error.
+func (r *observerRun) observe(blk data.BlockData) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("observer panicked: %v", rec)
+		}
+	}()
+	return r.observer.Observe(blk)
+}
+
+// startObservers starts feeding the initialized observers.
+func (p *pipelineImpl) startObservers() {
+	p.observerRuns = make([]*observerRun, 0, len(p.observers))
+	for _, observer := range p.observers {
+		run := &observerRun{
+			observer: *observer,
+			name:     (*observer).Metadata().Name,
+			logger:   p.logger,
+			queue:    make(chan data.BlockData, observerQueueSize),
+			done:     make(chan struct{}),
+		}
+		go run.run()
+		p.observerRuns = append(p.observerRuns, run)
+	}
+}
+
+// observe sends a copy of the exported block to each observer, so that
+// observers cannot modify the block seen by the other plugins or keep a block
+// which is released for reuse. The round is dropped for observers whose queue
+// is full.
+func (p *pipelineImpl) observe(blk data.BlockData) {
+	if len(p.observerRuns) == 0 {
+		return
+	}
+	encoded := msgpack.Encode(blk)
+	for _, run := range p.observerRuns {
+		var view data.BlockData
+		if err := msgpack.Decode(encoded, &view); err != nil {
+			p.logger.Errorf("unable to copy round %d for observer (%s): %v", blk.Round(), run.name, err)
+			continue
+		}
+		select {
+		case run.queue <- view:
+		default:
+			metrics.ObserverDroppedRounds.WithLabelValues(run.name).Inc()
+			p.logger.Warnf("observer (%s) is %d rounds behind, round %d was dropped", run.name, observerQueueSize, blk.Round())
+		}
+	}
+}
+
+// stopObservers waits for the observers to observe the rounds in their queues.
+func (p *pipelineImpl) stopObservers() {
+	for _, run := range p.observerRuns {
+		close(run.queue)
+	}
+	for _, run := range p.observerRuns {
+		<-run.done
+	}
+	p.observerRuns = nil
+}
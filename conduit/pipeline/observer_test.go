@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+	"github.com/algorand/conduit/conduit/plugins/observers"
+)
+
+// roundObserver records the rounds it observes, it fails failRound and
+// panics on panicRound. It modifies each block to verify that it only
+// receives a copy.
+type roundObserver struct {
+	observers.Observer
+	name       string
+	failRound  uint64
+	panicRound uint64
+
+	mu     sync.Mutex
+	rounds []uint64
+}
+
+func (r *roundObserver) Metadata() conduit.Metadata {
+	return conduit.Metadata{Name: r.name}
+}
+
+func (r *roundObserver) Observe(blk data.BlockData) error {
+	switch blk.Round() {
+	case r.failRound:
+		return fmt.Errorf("observe")
+	case r.panicRound:
+		panic("observe")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rounds = append(r.rounds, blk.Round())
+	blk.BlockHeader.Round += 100
+	return nil
+}
+
+func (r *roundObserver) observed() []uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]uint64(nil), r.rounds...)
+}
+
+// TestPipelineObservers tests that observers receive the exported rounds, and
+// that their errors and panics do not affect the pipeline.
+func TestPipelineObservers(t *testing.T) {
+	metrics.RegisterPrometheusMetrics("observer_test")
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeCheckpointPipeline(t, exp)
+	pImpl.cfg.Rounds.End = 3
+	recorder := &roundObserver{name: "recorder", failRound: 1000, panicRound: 1000}
+	failing := &roundObserver{name: "failing", failRound: 1, panicRound: 2}
+	for _, obs := range []observers.Observer{recorder, failing} {
+		obs := obs
+		pImpl.observers = append(pImpl.observers, &obs)
+	}
+
+	pImpl.startObservers()
+	pImpl.Start()
+	pImpl.Wait()
+	pImpl.stopObservers()
+
+	assert.Equal(t, []uint64{0, 1, 2, 3}, exp.received())
+	assert.Equal(t, []uint64{0, 1, 2, 3}, recorder.observed())
+	assert.Equal(t, []uint64{0, 3}, failing.observed())
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.ObserverErrors.WithLabelValues("failing")))
+	assert.NoError(t, pImpl.Error())
+}
+
+// TestObserveDropsRounds tests that rounds are dropped for an observer whose
+// queue is full.
+func TestObserveDropsRounds(t *testing.T) {
+	metrics.RegisterPrometheusMetrics("observer_drop_test")
+	logger, hook := test.NewNullLogger()
+	p := &pipelineImpl{logger: logger}
+	run := &observerRun{name: "slow", queue: make(chan data.BlockData, observerQueueSize)}
+	p.observerRuns = []*observerRun{run}
+
+	for i := 0; i <= observerQueueSize; i++ {
+		p.observe(data.BlockData{})
+	}
+	assert.Len(t, run.queue, observerQueueSize)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.ObserverDroppedRounds.WithLabelValues("slow")))
+	assert.Equal(t, "observer (slow) is 16 rounds behind, round 0 was dropped", hook.LastEntry().Message)
+}
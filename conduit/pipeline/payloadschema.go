@@ -0,0 +1,207 @@
+package pipeline
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/jsonschema"
+	"github.com/algorand/conduit/conduit/metrics"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+)
+
+const (
+	// violationDeadLetter saves the payload to the dead letter directory
+	// instead of writing it.
+	violationDeadLetter = "dead-letter"
+	// violationWarn logs the violation and writes the payload.
+	violationWarn = "warn"
+	// violationFail fails the round, it is retried like other exporter errors.
+	violationFail = "fail"
+)
+
+// OutputSchema configs validating the JSON payloads of an exporter with a JSON
+// Schema, so that a processor regression is caught before it reaches the
+// downstream consumers.
+type OutputSchema struct {
+	// File is the JSON Schema. A relative path is in the data directory.
+	File string `yaml:"file"`
+	// SampleRate is the fraction of the rounds whose payloads are validated,
+	// between 0 and 1. Every round is validated when it is not set.
+	SampleRate float64 `yaml:"sample-rate"`
+	// OnViolation is "dead-letter" (default), "warn" or "fail".
+	OnViolation string `yaml:"on-violation"`
+}
+
+// Valid validates the output schema config.
+func (s OutputSchema) Valid() error {
+	if s.File == "" {
+		return fmt.Errorf("file is required")
+	}
+	if s.SampleRate < 0 || s.SampleRate > 1 {
+		return fmt.Errorf("sample-rate must be between 0 and 1, found %v", s.SampleRate)
+	}
+	switch s.OnViolation {
+	case "", violationDeadLetter, violationWarn, violationFail:
+		return nil
+	default:
+		return fmt.Errorf("on-violation must be '%s', '%s' or '%s', found '%s'", violationDeadLetter, violationWarn, violationFail, s.OnViolation)
+	}
+}
+
+// sameOutputSchemas reports whether the output schemas of the exporters are
+// unchanged.
+func sameOutputSchemas(a, b []NameConfigPair) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if !reflect.DeepEqual(a[idx].OutputSchema, b[idx].OutputSchema) {
+			return false
+		}
+	}
+	return true
+}
+
+// payloadChecker implements conduit.PayloadChecker with a JSON Schema.
+type payloadChecker struct {
+	exporter    string
+	schema      *jsonschema.Schema
+	sampleRate  float64
+	onViolation string
+	// deadLetterDir is where the payloads which violate the schema are saved.
+	deadLetterDir string
+	logger        *log.Logger
+
+	mu sync.Mutex
+	// round and count number the dead-lettered payloads of a round, an
+	// exporter may write several payloads for a round.
+	round uint64
+	count int
+}
+
+// makePayloadChecker compiles the schema of the exporter.
+func makePayloadChecker(exporter string, cfg OutputSchema, dataDir string, logger *log.Logger) (*payloadChecker, error) {
+	file := cfg.File
+	if !filepath.IsAbs(file) && dataDir != "" {
+		file = filepath.Join(dataDir, file)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("makePayloadChecker(): unable to read the output-schema of exporter (%s): %w", exporter, err)
+	}
+	schema, err := jsonschema.Compile(b)
+	if err != nil {
+		return nil, fmt.Errorf("makePayloadChecker(): invalid output-schema %s of exporter (%s): %w", file, exporter, err)
+	}
+	onViolation := cfg.OnViolation
+	if onViolation == "" {
+		onViolation = violationDeadLetter
+	}
+	return &payloadChecker{
+		exporter:      exporter,
+		schema:        schema,
+		sampleRate:    cfg.SampleRate,
+		onViolation:   onViolation,
+		deadLetterDir: path.Join(dataDir, deadLetterDir),
+		logger:        logger,
+	}, nil
+}
+
+// sampled reports whether the payloads of the round are validated. The rounds
+// are sampled by hash, so that every payload of a round, and a round which is
+// exported again, are sampled alike.
+func (c *payloadChecker) sampled(round uint64) bool {
+	if c.sampleRate == 0 || c.sampleRate >= 1 {
+		return true
+	}
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatUint(round, 10)))
+	return float64(h.Sum64()%10000) < c.sampleRate*10000
+}
+
+// Check validates the payload with the schema of the exporter.
+func (c *payloadChecker) Check(round uint64, payload []byte) (bool, error) {
+	if !c.sampled(round) {
+		return true, nil
+	}
+	verr := c.schema.Validate(payload)
+	if verr == nil {
+		metrics.PayloadValidations.WithLabelValues(c.exporter, "valid").Inc()
+		return true, nil
+	}
+	metrics.PayloadValidations.WithLabelValues(c.exporter, "violation").Inc()
+	switch c.onViolation {
+	case violationWarn:
+		c.logger.Warnf("Exporter (%s) payload of round %d violates the output schema: %v", c.exporter, round, verr)
+		return true, nil
+	case violationFail:
+		return false, fmt.Errorf("payload of round %d violates the output schema: %w", round, verr)
+	}
+	file, err := c.writeDeadLetter(round, payload)
+	if err != nil {
+		return false, fmt.Errorf("payload of round %d violates the output schema (%v) and could not be saved: %w", round, verr, err)
+	}
+	c.logger.Errorf("Exporter (%s) payload of round %d violates the output schema and was written to %s: %v", c.exporter, round, file, verr)
+	return false, nil
+}
+
+// writeDeadLetter saves a payload which violated the schema, returning the
+// file name.
+func (c *payloadChecker) writeDeadLetter(round uint64, payload []byte) (string, error) {
+	c.mu.Lock()
+	if c.round != round {
+		c.round, c.count = round, 0
+	}
+	c.count++
+	file := fmt.Sprintf("payload_%s_round_%d_%d.json", c.exporter, round, c.count)
+	c.mu.Unlock()
+	if err := os.MkdirAll(c.deadLetterDir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("writeDeadLetter(): unable to create dead letter directory: %w", err)
+	}
+	if err := os.WriteFile(path.Join(c.deadLetterDir, file), payload, 0644); err != nil {
+		return "", fmt.Errorf("writeDeadLetter(): %w", err)
+	}
+	return file, nil
+}
+
+// setPayloadChecker passes the payload checker of the exporter at idx if it
+// has an output-schema. The schema is compiled on the first call.
+func (p *pipelineImpl) setPayloadChecker(idx int, cfg NameConfigPair, exporter exporters.Exporter) error {
+	if cfg.OutputSchema == nil {
+		return nil
+	}
+	name := exporter.Metadata().Name
+	if idx < len(p.names.exporters) && p.names.exporters[idx] != "" {
+		name = p.names.exporters[idx]
+	}
+	e, ok := exporter.(conduit.PayloadValidator)
+	if !ok {
+		return fmt.Errorf("setPayloadChecker(): exporter (%s) does not support an output-schema", name)
+	}
+	checker, ok := p.payloadCheckers[idx]
+	if !ok {
+		var dataDir string
+		if p.cfg.ConduitArgs != nil {
+			dataDir = p.cfg.ConduitArgs.ConduitDataDir
+		}
+		var err error
+		if checker, err = makePayloadChecker(name, *cfg.OutputSchema, dataDir, p.logger); err != nil {
+			return fmt.Errorf("setPayloadChecker(): %w", err)
+		}
+		if p.payloadCheckers == nil {
+			p.payloadCheckers = make(map[int]*payloadChecker)
+		}
+		p.payloadCheckers[idx] = checker
+	}
+	e.SetPayloadChecker(checker)
+	return nil
+}
@@ -0,0 +1,110 @@
+package pipeline
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+const roundSchema = `{"type": "object", "required": ["round"], "properties": {"round": {"type": "integer"}}}`
+
+func TestOutputSchemaValid(t *testing.T) {
+	assert.NoError(t, OutputSchema{File: "schema.json"}.Valid())
+	assert.NoError(t, OutputSchema{File: "schema.json", SampleRate: 0.1, OnViolation: violationFail}.Valid())
+	assert.EqualError(t, OutputSchema{}.Valid(), "file is required")
+	assert.EqualError(t, OutputSchema{File: "schema.json", SampleRate: 2}.Valid(), "sample-rate must be between 0 and 1, found 2")
+	assert.EqualError(t, OutputSchema{File: "schema.json", OnViolation: "drop"}.Valid(), "on-violation must be 'dead-letter', 'warn' or 'fail', found 'drop'")
+}
+
+func TestPayloadChecker(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	tests := []struct {
+		onViolation string
+		ok          bool
+		err         string
+		deadLetter  bool
+	}{
+		{onViolation: "", deadLetter: true},
+		{onViolation: violationWarn, ok: true},
+		{onViolation: violationFail, err: "payload of round 7 violates the output schema: /round: expected integer, found string"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.onViolation, func(t *testing.T) {
+			dataDir := t.TempDir()
+			require.NoError(t, os.WriteFile(path.Join(dataDir, "schema.json"), []byte(roundSchema), 0644))
+			checker, err := makePayloadChecker("file_writer", OutputSchema{File: "schema.json", OnViolation: tc.onViolation}, dataDir, l)
+			require.NoError(t, err)
+			ok, err := checker.Check(7, []byte(`{"round": 7}`))
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = checker.Check(7, []byte(`{"round": "7"}`))
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.ok, ok)
+			file := path.Join(dataDir, deadLetterDir, "payload_file_writer_round_7_1.json")
+			if tc.deadLetter {
+				assert.FileExists(t, file)
+			} else {
+				assert.NoFileExists(t, file)
+			}
+		})
+	}
+
+	_, err := makePayloadChecker("file_writer", OutputSchema{File: "missing.json"}, t.TempDir(), l)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestPayloadCheckerSampling(t *testing.T) {
+	checker := &payloadChecker{sampleRate: 0.25}
+	sampled := 0
+	for round := uint64(0); round < 1000; round++ {
+		if checker.sampled(round) {
+			sampled++
+		}
+		// every payload of a round is sampled alike.
+		assert.Equal(t, checker.sampled(round), checker.sampled(round))
+	}
+	assert.InDelta(t, 250, sampled, 50)
+	assert.True(t, (&payloadChecker{}).sampled(1))
+}
+
+// checkedExporter implements conduit.PayloadValidator.
+type checkedExporter struct {
+	mockExporter
+	checker conduit.PayloadChecker
+}
+
+func (m *checkedExporter) SetPayloadChecker(checker conduit.PayloadChecker) {
+	m.checker = checker
+}
+
+func TestSetPayloadChecker(t *testing.T) {
+	dataDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dataDir, "schema.json"), []byte(roundSchema), 0644))
+	l, _ := test.NewNullLogger()
+	p := pipelineImpl{cfg: &Config{ConduitArgs: &conduit.Args{ConduitDataDir: dataDir}}, logger: l}
+	cfg := NameConfigPair{Name: "mockExporter", OutputSchema: &OutputSchema{File: "schema.json"}}
+
+	require.NoError(t, p.setPayloadChecker(0, NameConfigPair{Name: "mockExporter"}, &mockExporter{}))
+	err := p.setPayloadChecker(0, cfg, &mockExporter{})
+	assert.EqualError(t, err, "setPayloadChecker(): exporter (mockExporter) does not support an output-schema")
+
+	exp := &checkedExporter{}
+	require.NoError(t, p.setPayloadChecker(0, cfg, exp))
+	require.NotNil(t, exp.checker)
+	// a re-initialized exporter gets the same checker.
+	checker := exp.checker
+	require.NoError(t, p.setPayloadChecker(0, cfg, exp))
+	assert.Same(t, checker, exp.checker)
+}
@@ -1,15 +1,18 @@
 package pipeline
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path"
 	"runtime/pprof"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,18 +25,51 @@ import (
 	"github.com/algorand/indexer/util"
 
 	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/coordinator"
 	"github.com/algorand/conduit/conduit/data"
 	"github.com/algorand/conduit/conduit/metrics"
 	"github.com/algorand/conduit/conduit/plugins"
 	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/external"
 	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/observers"
 	"github.com/algorand/conduit/conduit/plugins/processors"
+	"github.com/algorand/conduit/conduit/statestore"
+	"github.com/algorand/conduit/conduit/tracing"
 )
 
 // NameConfigPair is a generic structure used across plugin configuration ser/de
 type NameConfigPair struct {
 	Name   string                 `yaml:"name"`
 	Config map[string]interface{} `yaml:"config"`
+	// Alias identifies the exporter instead of its name, in the data
+	// directory, metrics, logs and the settings which refer to exporters. It
+	// tells apart exporters with the same name. Only supported by exporters.
+	Alias string `yaml:"alias"`
+	// LogLevel optionally overrides the pipeline log level for this plugin.
+	LogLevel string `yaml:"log-level"`
+	// Executable runs the plugin as an external process instead of looking
+	// up a built-in plugin by name.
+	Executable string `yaml:"executable"`
+	// Args are passed to the Executable.
+	Args []string `yaml:"args"`
+	// Sandbox optionally restricts the environment, working directory, umask
+	// and user of the Executable.
+	Sandbox *external.Sandbox `yaml:"sandbox"`
+	// BestEffort exporters do not hold back the pipeline, a failed round is
+	// logged and skipped. Only supported by exporters.
+	BestEffort bool `yaml:"best-effort"`
+	// When is an optional condition, the processor or exporter is skipped for
+	// blocks which do not match it.
+	When string `yaml:"when"`
+	// RetryPolicy optionally overrides the retry policy of the plugin's stage.
+	RetryPolicy *RetryPolicy `yaml:"retry-policy"`
+	// Processors are run after the shared processors on a copy of the block
+	// which is only sent to this exporter. Only supported by exporters.
+	Processors []NameConfigPair `yaml:"processors"`
+	// OutputSchema validates the JSON payloads written by the exporter. Only
+	// supported by exporters.
+	OutputSchema *OutputSchema `yaml:"output-schema"`
 }
 
 // Metrics configs for turning on Prometheus endpoint /metrics
@@ -41,6 +77,25 @@ type Metrics struct {
 	Mode   string `yaml:"mode"`
 	Addr   string `yaml:"addr"`
 	Prefix string `yaml:"prefix"`
+	// TxnTypeLabels controls the txn_type label dimension: "full" (default), "bucket" or "off".
+	TxnTypeLabels metrics.LabelMode `yaml:"txn-type-labels"`
+	// ProcessorLabels controls the processor_name label dimension: "full" (default) or "off".
+	ProcessorLabels metrics.LabelMode `yaml:"processor-labels"`
+	// ResetStaleLabels clears labeled gauges each round so values from earlier rounds are not retained.
+	ResetStaleLabels bool `yaml:"reset-stale-labels"`
+	// Latency is the metric type of the stage latencies: "summary" (default) or "histogram".
+	// Histograms carry the trace ID of the round as an exemplar when tracing is enabled.
+	Latency metrics.LatencyType `yaml:"latency"`
+}
+
+// API configs for the /health, /ready and /status endpoints. They are also
+// served on the metrics address when metrics are enabled.
+type API struct {
+	Addr string `yaml:"addr"`
+	// DebugRounds is the number of recently imported blocks which are kept in
+	// memory for the /debug/round endpoint. Other rounds are fetched again from
+	// the importer, which is not possible while rounds are prefetched.
+	DebugRounds int `yaml:"debug-rounds"`
 }
 
 // Config stores configuration specific to the conduit pipeline
@@ -51,18 +106,126 @@ type Config struct {
 	CPUProfile  string `yaml:"cpu-profile"`
 	PIDFilePath string `yaml:"pid-filepath"`
 	HideBanner  bool   `yaml:"hide-banner"`
+	// Profiling periodically captures profiles for continuous profiling.
+	Profiling Profiling `yaml:"profiling"`
 
 	LogFile          string `yaml:"log-file"`
 	PipelineLogLevel string `yaml:"log-level"`
+	// LogFormat is the format of the log lines, "json" (default) or "text".
+	LogFormat string `yaml:"log-format"`
 	// Store a local copy to access parent variables
 	Importer   NameConfigPair   `yaml:"importer"`
 	Processors []NameConfigPair `yaml:"processors"`
 	Exporter   NameConfigPair   `yaml:"exporter"`
-	Metrics    Metrics          `yaml:"metrics"`
+	// Exporters fan each round out to several exporters, it replaces Exporter.
+	Exporters []NameConfigPair `yaml:"exporters"`
+	// Observers receive a copy of each exported round without holding back
+	// the pipeline.
+	Observers []NameConfigPair `yaml:"observers"`
+	// Migration swaps one exporter for another once they are verified to agree.
+	Migration Migration `yaml:"migration"`
+	// Standby keeps an exporter initialized without rounds until it is promoted.
+	Standby Standby `yaml:"standby"`
+	// Verification compares a sample of the rounds written by several exporters.
+	Verification Verification `yaml:"verification"`
+	Metrics      Metrics      `yaml:"metrics"`
+	API          API          `yaml:"api"`
+	// Telemetry exports OpenTelemetry traces of the rounds.
+	Telemetry Telemetry `yaml:"telemetry"`
+	// StateStore is where the pipeline metadata, such as the next round, is saved.
+	StateStore statestore.Config `yaml:"state-store"`
+	// Coordination lets several instances run the pipeline while each round is
+	// exported by only one of them.
+	Coordination coordinator.Config `yaml:"coordination"`
 	// RetryCount is the number of retries to perform for an error in the pipeline
 	RetryCount uint64 `yaml:"retry-count"`
 	// RetryDelay is a duration amount interpreted from a string
 	RetryDelay time.Duration `yaml:"retry-delay"`
+	// RetryPolicies configures the retries of each stage, they default to
+	// RetryCount and RetryDelay.
+	RetryPolicies RetryPolicies `yaml:"retry-policies"`
+	// OnFailure is what happens when a round exceeds RetryCount: "halt"
+	// (default) stops the pipeline, "skip" and "dead-letter" advance past it.
+	OnFailure string `yaml:"on-failure"`
+	// SupportBundleOnFailure writes a support bundle into the data directory
+	// when the pipeline stops because of an error.
+	SupportBundleOnFailure bool `yaml:"support-bundle-on-failure"`
+	// ShutdownGracePeriod is how long Stop waits for the in-flight round to
+	// finish before cancelling it. Zero cancels immediately.
+	ShutdownGracePeriod time.Duration `yaml:"shutdown-grace-period"`
+	// Watchdog restarts the pipeline loop when it stalls.
+	Watchdog Watchdog `yaml:"watchdog"`
+	// SchemaCheckInterval is how often exporters which implement
+	// conduit.SchemaVerifier verify their schema. Zero disables the periodic
+	// check, drift reported by Receive still pauses the pipeline.
+	SchemaCheckInterval time.Duration `yaml:"schema-check-interval"`
+	// PrefetchRounds is the number of rounds the importer may fetch ahead of the
+	// processors and exporter. Zero disables prefetching.
+	PrefetchRounds uint64 `yaml:"prefetch-rounds"`
+	// DeterminismCheck replays the processors to verify that their output is repeatable.
+	DeterminismCheck DeterminismCheck `yaml:"determinism-check"`
+	// BlockValidation verifies that imported blocks are linked by their hashes.
+	BlockValidation BlockValidation `yaml:"block-validation"`
+	// UnknownProtocol is what happens to a block built with a consensus
+	// protocol which conduit does not know: "warn" (default) processes it,
+	// "halt" stops the pipeline and "skip" advances past it.
+	UnknownProtocol string `yaml:"unknown-protocol"`
+	// KnownProtocols are consensus protocols to accept in addition to the
+	// ones built in, for example after a network upgrade.
+	KnownProtocols []string `yaml:"known-protocols"`
+	// UnknownTxnTypes is what happens to transactions of a type which conduit
+	// does not know: "pass" (default) processes them, "tag" lists them in the
+	// block, "drop" removes them and "halt" stops the pipeline.
+	UnknownTxnTypes string `yaml:"unknown-txn-types"`
+	// ClockSkew detects blocks whose timestamp is far from the wall clock.
+	ClockSkew ClockSkew `yaml:"clock-skew"`
+	// AuthFailure is what happens when a service rejects the credentials of
+	// a plugin: "retry" (default) retries the round like other errors and
+	// "halt" stops the pipeline.
+	AuthFailure string `yaml:"auth-failure"`
+	// VersionCheck is what happens when an exporter has data written by a
+	// newer Conduit or by an incompatible plugin version: "warn" (default)
+	// logs it, "halt" stops the pipeline before the first round and "off"
+	// neither checks nor records the versions.
+	VersionCheck string `yaml:"version-check"`
+	// Batch accumulates rounds for exporters which implement exporters.BatchExporter.
+	Batch Batch `yaml:"batch"`
+	// Signing signs the artifacts written by exporters which support it.
+	Signing Signing `yaml:"signing"`
+	// BinaryEncoding is how exporters which write text formats represent
+	// binary fields, the default is base64.
+	BinaryEncoding conduit.BinaryEncoding `yaml:"binary-encoding"`
+	// Amounts is how exporters which write text formats represent amounts.
+	Amounts Amounts `yaml:"amounts"`
+	// ReuseBlockData releases the block of each round once its OnComplete
+	// callbacks have finished, so that importers reuse its memory.
+	ReuseBlockData bool `yaml:"reuse-block-data"`
+	// Warmup bounds the warm-up of plugins which implement conduit.Warmer.
+	Warmup Warmup `yaml:"warmup"`
+	// Simulation paces fixture blocks as if they arrived in realtime.
+	Simulation Simulation `yaml:"simulation"`
+	// Throttle limits the rate of rounds and optionally trails the latest round.
+	Throttle Throttle `yaml:"throttle"`
+	// Rounds limits the pipeline to a range of rounds and optionally fetches them concurrently.
+	Rounds Rounds `yaml:"rounds"`
+	// Preset is the name of a built-in set of tuned settings, which the rest
+	// of the config overrides.
+	Preset string `yaml:"preset"`
+	// SLO tracks how many rounds are exported within a latency of their block time.
+	SLO SLO `yaml:"slo"`
+	// ConfigDrift periodically compares the config file with the running config.
+	ConfigDrift ConfigDrift `yaml:"config-drift"`
+	// BlockCache keeps the recently imported blocks for the processors.
+	BlockCache BlockCache `yaml:"block-cache"`
+	// PriorityLanes deliver the matching transactions to their exporters
+	// ahead of the other exporters and without batching.
+	PriorityLanes PriorityLanes `yaml:"priority-lanes"`
+	// Bandwidth logs the network traffic of the plugins with its estimated cost.
+	Bandwidth Bandwidth `yaml:"bandwidth"`
+
+	// fileHash is the hash of the config file the config was loaded from,
+	// empty if it was not loaded from a file.
+	fileHash string
 }
 
 // Valid validates pipeline config
@@ -75,12 +238,331 @@ func (cfg *Config) Valid() error {
 			return fmt.Errorf("Args.Valid(): pipeline log level (%s) was invalid: %w", cfg.PipelineLogLevel, err)
 		}
 	}
+	switch cfg.LogFormat {
+	case "", LogFormatJSON, LogFormatText:
+	default:
+		return fmt.Errorf("Args.Valid(): log format (%s) was invalid, expected %s or %s", cfg.LogFormat, LogFormatJSON, LogFormatText)
+	}
+
+	if cfg.Exporter.Name != "" && len(cfg.Exporters) > 0 {
+		return fmt.Errorf("Args.Valid(): exporter and exporters cannot both be configured")
+	}
+
+	exporterNames := make(map[string]bool)
+	for _, id := range cfg.exporterIDs() {
+		if exporterNames[id] {
+			return fmt.Errorf("Args.Valid(): exporter (%s) was configured more than once, give the exporters different aliases", id)
+		}
+		exporterNames[id] = true
+	}
+	for _, pair := range cfg.exporterConfigs() {
+		if strings.ContainsAny(pair.Alias, `/\`) || pair.Alias == "." || pair.Alias == ".." {
+			return fmt.Errorf("Args.Valid(): exporter (%s) alias (%s) was invalid, it names the data directory of the exporter", pair.Name, pair.Alias)
+		}
+	}
+	if err := cfg.Migration.Valid(exporterNames); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid migration: %w", err)
+	}
+	if err := cfg.Standby.Valid(exporterNames, cfg.Migration); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid standby: %w", err)
+	}
+	if err := cfg.Verification.Valid(exporterNames); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid verification: %w", err)
+	}
+
+	if cfg.Importer.When != "" {
+		return fmt.Errorf("Args.Valid(): importer (%s) cannot have a when condition", cfg.Importer.Name)
+	}
+
+	observerNames := make(map[string]bool)
+	for _, pair := range cfg.Observers {
+		if observerNames[pair.Name] {
+			return fmt.Errorf("Args.Valid(): observer (%s) was configured more than once", pair.Name)
+		}
+		observerNames[pair.Name] = true
+		if pair.Executable != "" {
+			return fmt.Errorf("Args.Valid(): observer (%s) cannot be an executable, only built-in observers are supported", pair.Name)
+		}
+		if pair.When != "" {
+			return fmt.Errorf("Args.Valid(): observer (%s) cannot have a when condition", pair.Name)
+		}
+		if pair.RetryPolicy != nil {
+			return fmt.Errorf("Args.Valid(): observer (%s) cannot have a retry policy, observers are not retried", pair.Name)
+		}
+	}
+
+	pairs := append([]NameConfigPair{cfg.Importer}, cfg.Processors...)
+	pairs = append(pairs, cfg.Observers...)
+	for _, pair := range pairs {
+		if pair.Alias != "" {
+			return fmt.Errorf("Args.Valid(): plugin (%s) cannot have an alias, only exporters are supported", pair.Name)
+		}
+		if pair.BestEffort {
+			return fmt.Errorf("Args.Valid(): plugin (%s) cannot be best-effort, only exporters are supported", pair.Name)
+		}
+		if len(pair.Processors) > 0 {
+			return fmt.Errorf("Args.Valid(): plugin (%s) cannot have processors, only exporters are supported", pair.Name)
+		}
+		if pair.OutputSchema != nil {
+			return fmt.Errorf("Args.Valid(): plugin (%s) cannot have an output-schema, only exporters are supported", pair.Name)
+		}
+	}
+	if err := cfg.PriorityLanes.Valid(cfg.exporterConfigs(), cfg.exporterIDs()); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid priority-lanes: %w", err)
+	}
+	for _, pair := range cfg.exporterConfigs() {
+		if err := validExporterProcessors(pair); err != nil {
+			return fmt.Errorf("Args.Valid(): %w", err)
+		}
+		if pair.OutputSchema != nil {
+			if err := pair.OutputSchema.Valid(); err != nil {
+				return fmt.Errorf("Args.Valid(): exporter (%s) output-schema was invalid: %w", pair.Name, err)
+			}
+		}
+	}
+	pairs = append(pairs, cfg.exporterConfigs()...)
+	for _, pair := range pairs {
+		if pair.LogLevel != "" {
+			if _, err := log.ParseLevel(pair.LogLevel); err != nil {
+				return fmt.Errorf("Args.Valid(): plugin (%s) log level (%s) was invalid: %w", pair.Name, pair.LogLevel, err)
+			}
+		}
+		if pair.When != "" {
+			if _, err := compileCondition(pair.When); err != nil {
+				return fmt.Errorf("Args.Valid(): plugin (%s) when condition was invalid: %w", pair.Name, err)
+			}
+		}
+		if err := validSandbox(pair); err != nil {
+			return fmt.Errorf("Args.Valid(): %w", err)
+		}
+	}
+
+	if err := cfg.validPluginConfigs(); err != nil {
+		return fmt.Errorf("Args.Valid(): %w", err)
+	}
 
 	// If it is a negative time, it is an error
 	if cfg.RetryDelay < 0 {
 		return fmt.Errorf("Args.Valid(): invalid retry delay - time duration was negative (%s)", cfg.RetryDelay.String())
 	}
 
+	if cfg.ShutdownGracePeriod < 0 {
+		return fmt.Errorf("Args.Valid(): invalid shutdown grace period - time duration was negative (%s)", cfg.ShutdownGracePeriod.String())
+	}
+	if err := cfg.Watchdog.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid watchdog: %w", err)
+	}
+
+	if err := cfg.RetryPolicies.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid retry-policies: %w", err)
+	}
+	for _, pair := range pairs {
+		if err := pair.RetryPolicy.Valid(); err != nil {
+			return fmt.Errorf("Args.Valid(): plugin (%s) retry-policy was invalid: %w", pair.Name, err)
+		}
+	}
+
+	if cfg.SchemaCheckInterval < 0 {
+		return fmt.Errorf("Args.Valid(): invalid schema check interval - time duration was negative (%s)", cfg.SchemaCheckInterval.String())
+	}
+
+	if err := validOnFailure(cfg.OnFailure); err != nil {
+		return fmt.Errorf("Args.Valid(): %w", err)
+	}
+
+	if err := cfg.BlockValidation.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid block-validation: %w", err)
+	}
+	if err := validUnknownProtocol(cfg.UnknownProtocol); err != nil {
+		return fmt.Errorf("Args.Valid(): %w", err)
+	}
+	if err := validUnknownTxnTypes(cfg.UnknownTxnTypes); err != nil {
+		return fmt.Errorf("Args.Valid(): %w", err)
+	}
+	if err := cfg.ClockSkew.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid clock-skew: %w", err)
+	}
+	if err := cfg.SLO.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid slo: %w", err)
+	}
+	if err := cfg.ConfigDrift.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid config-drift: %w", err)
+	}
+	if err := cfg.BlockCache.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid block-cache: %w", err)
+	}
+	if err := cfg.Bandwidth.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid bandwidth: %w", err)
+	}
+	if err := validAuthFailure(cfg.AuthFailure); err != nil {
+		return fmt.Errorf("Args.Valid(): %w", err)
+	}
+	if err := validVersionCheck(cfg.VersionCheck); err != nil {
+		return fmt.Errorf("Args.Valid(): %w", err)
+	}
+	if err := cfg.Profiling.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid profiling: %w", err)
+	}
+	if cfg.CPUProfile != "" && cfg.Profiling.Mode != "" && cfg.Profiling.captures("cpu") {
+		return fmt.Errorf("Args.Valid(): cpu-profile cannot be used with profiling of the cpu profile")
+	}
+	if err := cfg.DeterminismCheck.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid determinism-check: %w", err)
+	}
+
+	if err := cfg.Rounds.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid rounds: %w", err)
+	}
+
+	if err := validPreset(cfg.Preset); err != nil {
+		return fmt.Errorf("Args.Valid(): %w", err)
+	}
+
+	if err := cfg.Telemetry.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid telemetry: %w", err)
+	}
+
+	if err := cfg.StateStore.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid state-store: %w", err)
+	}
+
+	if err := cfg.Coordination.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid coordination: %w", err)
+	}
+	if err := cfg.Simulation.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid simulation: %w", err)
+	}
+	if err := cfg.Throttle.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid throttle: %w", err)
+	}
+	if err := cfg.Warmup.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid warmup: %w", err)
+	}
+	if err := cfg.Batch.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid batch: %w", err)
+	}
+	if err := cfg.BinaryEncoding.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): %w", err)
+	}
+	if err := cfg.Amounts.Valid(); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid amounts: %w", err)
+	}
+	if cfg.Batch.enabled() && cfg.Coordination.Enabled() {
+		return fmt.Errorf("Args.Valid(): batch cannot be used with coordination")
+	}
+	if cfg.Batch.enabled() && cfg.Verification.enabled() {
+		// The batched rounds are not written when they are verified.
+		return fmt.Errorf("Args.Valid(): batch cannot be used with verification")
+	}
+	if cfg.Batch.enabled() && cfg.ReuseBlockData {
+		// Batches keep the blocks of several rounds.
+		return fmt.Errorf("Args.Valid(): batch cannot be used with reuse-block-data")
+	}
+
+	if err := metrics.ValidateLabelMode(cfg.Metrics.TxnTypeLabels, true); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid metrics txn-type-labels: %w", err)
+	}
+	if err := metrics.ValidateLabelMode(cfg.Metrics.ProcessorLabels, false); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid metrics processor-labels: %w", err)
+	}
+	if err := metrics.ValidateLatencyType(cfg.Metrics.Latency); err != nil {
+		return fmt.Errorf("Args.Valid(): invalid metrics latency: %w", err)
+	}
+	if cfg.API.DebugRounds < 0 {
+		return fmt.Errorf("Args.Valid(): invalid api debug-rounds: must not be negative (%d)", cfg.API.DebugRounds)
+	}
+
+	return nil
+}
+
+// exporterConfigs returns the configured exporters, whether they were
+// configured with exporter or exporters.
+func (cfg *Config) exporterConfigs() []NameConfigPair {
+	if len(cfg.Exporters) > 0 {
+		return cfg.Exporters
+	}
+	return []NameConfigPair{cfg.Exporter}
+}
+
+// exporterIDs returns the IDs of the exporters, which identify them in the
+// data directory, metrics, logs and settings. The ID is the alias of the
+// exporter, its name if no other exporter without an alias has the same
+// name, or else its name and index.
+func (cfg *Config) exporterIDs() []string {
+	exporterCfgs := cfg.exporterConfigs()
+	count := make(map[string]int, len(exporterCfgs))
+	for _, pair := range exporterCfgs {
+		if pair.Alias == "" {
+			count[pair.Name]++
+		}
+	}
+	ids := make([]string, len(exporterCfgs))
+	for idx, pair := range exporterCfgs {
+		switch {
+		case pair.Alias != "":
+			ids[idx] = pair.Alias
+		case count[pair.Name] > 1:
+			ids[idx] = fmt.Sprintf("%s_%d", pair.Name, idx)
+		default:
+			ids[idx] = pair.Name
+		}
+	}
+	return ids
+}
+
+// validSandbox checks the sandbox of a plugin, only external plugins can have
+// one.
+func validSandbox(pair NameConfigPair) error {
+	if pair.Sandbox == nil {
+		return nil
+	}
+	if pair.Executable == "" {
+		return fmt.Errorf("plugin (%s) cannot have a sandbox, only external plugins are supported", pair.Name)
+	}
+	if err := pair.Sandbox.Valid(); err != nil {
+		return fmt.Errorf("plugin (%s) sandbox was invalid: %w", pair.Name, err)
+	}
+	return nil
+}
+
+// validPluginConfigs checks the plugin configs against the config schemas
+// registered by the plugins, external plugins are not checked.
+func (cfg *Config) validPluginConfigs() error {
+	check := func(pluginType plugins.PluginType, path string, pair NameConfigPair) error {
+		if pair.Executable != "" {
+			return nil
+		}
+		if err := plugins.ValidateConfigSchema(pluginType, pair.Name, path+".config", pair.Config); err != nil {
+			return fmt.Errorf("plugin (%s) config was invalid: %w", pair.Name, err)
+		}
+		return nil
+	}
+	if err := check(plugins.Importer, "importer", cfg.Importer); err != nil {
+		return err
+	}
+	for idx, pair := range cfg.Processors {
+		if err := check(plugins.Processor, fmt.Sprintf("processors[%d]", idx), pair); err != nil {
+			return err
+		}
+	}
+	for idx, pair := range cfg.Observers {
+		if err := check(plugins.Observer, fmt.Sprintf("observers[%d]", idx), pair); err != nil {
+			return err
+		}
+	}
+	if len(cfg.Exporters) == 0 {
+		return check(plugins.Exporter, "exporter", cfg.Exporter)
+	}
+	for idx, pair := range cfg.Exporters {
+		if err := check(plugins.Exporter, fmt.Sprintf("exporters[%d]", idx), pair); err != nil {
+			return err
+		}
+		for procIdx, proc := range pair.Processors {
+			if err := check(plugins.Processor, fmt.Sprintf("exporters[%d].processors[%d]", idx, procIdx), proc); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -100,12 +582,55 @@ func MakePipelineConfig(args *conduit.Args) (*Config, error) {
 		return nil, fmt.Errorf("MakePipelineConfig(): could not find %s in data directory (%s)", conduit.DefaultConfigName, args.ConduitDataDir)
 	}
 
-	file, err := os.Open(autoloadParamConfigPath)
+	pCfg, err := loadPipelineConfig(args, autoloadParamConfigPath)
 	if err != nil {
-		return nil, fmt.Errorf("MakePipelineConfig(): reading config error: %w", err)
+		return nil, fmt.Errorf("MakePipelineConfig(): %w", err)
 	}
+	return pCfg, nil
+}
 
-	pCfgDecoder := yaml.NewDecoder(file)
+// MakePipelineConfigFromFile creates a pipeline configuration from a config
+// file outside of the data directory, for example a candidate config which is
+// compared with the running one.
+func MakePipelineConfigFromFile(args *conduit.Args, configFile string) (*Config, error) {
+	if args == nil {
+		return nil, fmt.Errorf("MakePipelineConfigFromFile(): empty conduit config")
+	}
+	if !util.IsDir(args.ConduitDataDir) {
+		return nil, fmt.Errorf("MakePipelineConfigFromFile(): invalid data dir '%s'", args.ConduitDataDir)
+	}
+	pCfg, err := loadPipelineConfig(args, configFile)
+	if err != nil {
+		return nil, fmt.Errorf("MakePipelineConfigFromFile(): %w", err)
+	}
+	return pCfg, nil
+}
+
+// loadPipelineConfig reads and validates a config file.
+func loadPipelineConfig(args *conduit.Args, configFile string) (*Config, error) {
+	configBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading config error: %w", err)
+	}
+	pCfg, err := decodePipelineConfig(configBytes, configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// For convenience, include the command line arguments.
+	pCfg.ConduitArgs = args
+	pCfg.fileHash = hashConfigFile(configBytes)
+
+	if err := pCfg.Valid(); err != nil {
+		return nil, fmt.Errorf("config file (%s) had mal-formed schema: %w", configFile, err)
+	}
+	return pCfg, nil
+}
+
+// decodePipelineConfig decodes a config file with the defaults, the preset
+// and the default log level applied. It is not validated.
+func decodePipelineConfig(configBytes []byte, source string) (*Config, error) {
+	pCfgDecoder := yaml.NewDecoder(bytes.NewReader(configBytes))
 	// Make sure we are strict about only unmarshalling known fields
 	pCfgDecoder.KnownFields(true)
 
@@ -113,23 +638,32 @@ func MakePipelineConfig(args *conduit.Args) (*Config, error) {
 	// Set default value for retry variables
 	pCfg.RetryDelay = 1 * time.Second
 	pCfg.RetryCount = 10
-	err = pCfgDecoder.Decode(&pCfg)
-	if err != nil {
-		return nil, fmt.Errorf("MakePipelineConfig(): config file (%s) was mal-formed yaml: %w", autoloadParamConfigPath, err)
+	// Set default value for shutdown grace period
+	pCfg.ShutdownGracePeriod = 10 * time.Second
+	pCfg.SchemaCheckInterval = defaultSchemaCheckInterval
+	pCfg.Warmup.Timeout = defaultWarmupTimeout
+	// Trace every round by default
+	pCfg.Telemetry.SampleRate = 1
+
+	// The preset is applied first so that the config file overrides it.
+	var presetCfg struct {
+		Preset string `yaml:"preset"`
+	}
+	if err := yaml.Unmarshal(configBytes, &presetCfg); err == nil {
+		if err = pCfg.applyPreset(presetCfg.Preset); err != nil {
+			return nil, fmt.Errorf("config file (%s) had mal-formed schema: %w", source, err)
+		}
 	}
 
-	// For convenience, include the command line arguments.
-	pCfg.ConduitArgs = args
+	if err := pCfgDecoder.Decode(&pCfg); err != nil {
+		return nil, fmt.Errorf("config file (%s) was mal-formed yaml: %w", source, err)
+	}
+	pCfg.applyPresetPluginOptions()
 
 	// Default log level.
 	if pCfg.PipelineLogLevel == "" {
 		pCfg.PipelineLogLevel = conduit.DefaultLogLevel.String()
 	}
-
-	if err := pCfg.Valid(); err != nil {
-		return nil, fmt.Errorf("MakePipelineConfig(): config file (%s) had mal-formed schema: %w", autoloadParamConfigPath, err)
-	}
-
 	return &pCfg, nil
 }
 
@@ -142,6 +676,17 @@ type Pipeline interface {
 	Stop()
 	Error() error
 	Wait()
+	Status() Status
+	Reload(cfg *Config) error
+	Checkpoint() (uint64, error)
+	Validate() (string, error)
+	Pause()
+	Resume()
+	Step(n uint64) error
+	CutOver() error
+	Promote() error
+	RollbackRound(round uint64) error
+	Events() *EventSubscription
 }
 
 type pipelineImpl struct {
@@ -151,24 +696,162 @@ type pipelineImpl struct {
 	cfg      *Config
 	logger   *log.Logger
 	profFile *os.File
-	err      error
-	mu       sync.RWMutex
+	// profilerDone is closed once the profiler stopped, it is nil when
+	// profiling is disabled.
+	profilerDone chan struct{}
+	err          error
+	// authFailure is the plugin whose credentials were rejected by err.
+	authFailure string
+	mu          sync.RWMutex
+
+	// stopCh is closed to request that the pipeline stop after the in-flight round.
+	stopCh      chan struct{}
+	stopReqOnce sync.Once
+	stopOnce    sync.Once
+	// prefetch is the current prefetcher, it is guarded by mu.
+	prefetch   *prefetcher
+	recentLogs *logRing
+	// reloadCh passes new configs to the pipeline loop, loopDone is closed when the loop exits.
+	reloadCh chan reloadRequest
+	loopDone chan struct{}
+	// checkpointCh passes checkpoint requests to the pipeline loop.
+	checkpointCh chan chan checkpointResult
+	// rollbackCh passes RollbackRound requests to the pipeline loop.
+	rollbackCh chan rollbackRequest
+	// promoteCh passes Promote requests to the pipeline loop.
+	promoteCh chan chan error
+	// debugCh passes DebugRound requests to the pipeline loop.
+	debugCh chan debugRequest
+	// debugCache keeps the recently imported blocks for DebugRound.
+	debugCache *debugCache
+	// roundTag tags the processor and exporter logs with the current round,
+	// importerRoundTag tags the importer logs while it fetches the round
+	// without a prefetcher.
+	roundTag         *RoundTag
+	importerRoundTag *RoundTag
+	// control pauses and steps the pipeline loop, see Pause.
+	control controlState
+	// migration is the configured exporter migration, nil if there is none.
+	migration *migrationRun
+	// standby is the configured standby exporter, nil if there is none.
+	standby *standbyRun
+	// watchdog is the state of the pipeline loop checked by the watchdog.
+	watchdog watchdogState
+	// verification is the configured exporter verification, nil if there is none.
+	verification *verificationRun
+	// dryRun initializes the plugins without side effects on the pipeline
+	// state, see Validate.
+	dryRun bool
 
 	initProvider *data.InitProvider
 
 	importer         *importers.Importer
 	processors       []*processors.Processor
-	exporter         *exporters.Exporter
+	exporters        []*exporters.Exporter
+	observers        []*observers.Observer
 	completeCallback []conduit.OnCompleteFunc
+	// observerRuns feed the observers, they are started once the pipeline
+	// is initialized.
+	observerRuns []*observerRun
+	// activity records the plugin calls in progress for /activity.
+	activity activityTracker
+	// events publishes the pipeline events to the Events subscriptions.
+	events eventHub
+	// slo tracks the latency SLO, nil if it is not configured.
+	slo *sloTracker
+	// blockCache keeps the recently imported blocks for the processors, nil
+	// if it is not configured.
+	blockCache *blockCache
+	// windows keeps the blocks of the windows of the WindowProcessors.
+	windows map[*processors.Processor]*roundWindow
+
+	// processorConditions and exporterConditions hold the compiled when
+	// conditions, nil entries always match.
+	processorConditions []*condition
+	exporterConditions  []*condition
+	// lanes are the priority lanes by exporter index, nil entries are not in
+	// a lane.
+	lanes []*priorityLane
+	// traffic sums the network traffic of the plugins between the bandwidth
+	// reports, nil if they are disabled.
+	traffic *trafficAccount
+	// exporterChains are the processor sub-chains of the exporters, nil
+	// entries have none.
+	exporterChains []*exporterChain
+	// names are the plugin names looked up during Init.
+	names pluginNames
+
+	// The plugin loggers are kept so that their level can be reloaded.
+	importerLogger   *log.Logger
+	processorLoggers []*log.Logger
+	exporterLoggers  []*log.Logger
+	observerLoggers  []*log.Logger
+
+	// telemetry attributes resource usage to the plugins, it is nil unless metrics are enabled.
+	telemetry *stageTelemetry
+	// tracer exports a trace of each round, it is nil unless tracing is enabled.
+	tracer *tracing.Tracer
+	// stateStore saves the pipeline metadata, it is nil for the default file store.
+	stateStore statestore.StateStore
+	// lastSchemaCheck is when the exporter schemas were last verified.
+	lastSchemaCheck time.Time
+	// coordinator claims rounds before they are exported, it is nil unless
+	// coordination is configured.
+	coordinator coordinator.Coordinator
+	// lastHeader is the header of the last imported block which was validated.
+	lastHeader *sdk.BlockHeader
+	// upgradeWarned are the unknown protocols of approved upgrades which were
+	// already logged.
+	upgradeWarned map[string]bool
+	// batch holds the rounds waiting for the batch exporters, it is nil unless
+	// batching is configured.
+	batch *batchState
+	// signer signs the exporter output, it is nil unless a signing key is configured.
+	signer conduit.Signer
+	// amountFormat is shared by the exporters, it is nil unless an amount
+	// encoding is configured.
+	amountFormat *conduit.AmountFormat
+	// payloadCheckers validate the payloads of the exporters with an
+	// output-schema, by exporter index.
+	payloadCheckers map[int]*payloadChecker
+	// simClock paces the rounds, it is nil unless simulation is configured.
+	simClock *simClock
+	// throttle is the state of the configured throttle.
+	throttle throttleState
+	// metadataCreated is set when there was no metadata to load.
+	metadataCreated bool
+	// recovery is the report of the state the pipeline resumed from, it is
+	// completed during Init.
+	recovery *RecoveryReport
 
 	pipelineMetadata state
+	status           Status
 }
 
 // state contains the pipeline state.
 type state struct {
+	// GenesisHash is empty for importers which implement
+	// importers.NetworkIdentifier.
 	GenesisHash string `json:"genesis-hash"`
 	Network     string `json:"network"`
-	NextRound   uint64 `json:"next-round"`
+	// NetworkID is the opaque ID of the network, it is the genesis hash
+	// unless the importer declares its own. Metadata saved by older versions
+	// has none.
+	NetworkID string `json:"network-id,omitempty"`
+	NextRound uint64 `json:"next-round"`
+	// FailedRounds were skipped after exceeding the retry count.
+	FailedRounds []failedRound `json:"failed-rounds,omitempty"`
+	// FailedTxns were left out of their exported round by a processor.
+	FailedTxns []failedTxn `json:"failed-txns,omitempty"`
+	// CommitIntents are the rounds prepared by transactional exporters which
+	// have not been exported by every exporter yet.
+	CommitIntents []commitIntent `json:"commit-intents,omitempty"`
+	// Migration is the progress of the exporter migration.
+	Migration *MigrationStatus `json:"migration,omitempty"`
+	// Standby is the role of the standby exporters.
+	Standby *StandbyStatus `json:"standby,omitempty"`
+	// LastExportTime is when the last round was exported.
+	LastExportTime *time.Time `json:"last-export-time,omitempty"`
 }
 
 func (p *pipelineImpl) Error() error {
@@ -179,21 +862,29 @@ func (p *pipelineImpl) Error() error {
 
 func (p *pipelineImpl) setError(err error) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
 	p.err = err
+	// onAuthFailure sets it again if err is an authentication failure.
+	p.authFailure = ""
+	ev := ErrorOccurred{Round: p.status.NextRound, Err: err, RetryCount: p.status.RetryCount, Time: time.Now()}
+	p.mu.Unlock()
+	if err != nil {
+		p.events.publishErrorOccurred(ev)
+	}
 }
 
 func (p *pipelineImpl) registerLifecycleCallbacks() {
 	if v, ok := (*p.importer).(conduit.Completed); ok {
 		p.completeCallback = append(p.completeCallback, v.OnComplete)
 	}
-	for _, processor := range p.processors {
+	for _, processor := range p.allProcessors() {
 		if v, ok := (*processor).(conduit.Completed); ok {
 			p.completeCallback = append(p.completeCallback, v.OnComplete)
 		}
 	}
-	if v, ok := (*p.exporter).(conduit.Completed); ok {
-		p.completeCallback = append(p.completeCallback, v.OnComplete)
+	for _, exporter := range p.exporters {
+		if v, ok := (*exporter).(conduit.Completed); ok {
+			p.completeCallback = append(p.completeCallback, v.OnComplete)
+		}
 	}
 }
 
@@ -202,13 +893,20 @@ func (p *pipelineImpl) registerPluginMetricsCallbacks() {
 	if v, ok := (*p.importer).(conduit.PluginMetrics); ok {
 		collectors = append(collectors, v.ProvideMetrics(p.cfg.Metrics.Prefix)...)
 	}
-	for _, processor := range p.processors {
+	for _, processor := range p.allProcessors() {
 		if v, ok := (*processor).(conduit.PluginMetrics); ok {
 			collectors = append(collectors, v.ProvideMetrics(p.cfg.Metrics.Prefix)...)
 		}
 	}
-	if v, ok := (*p.exporter).(conduit.PluginMetrics); ok {
-		collectors = append(collectors, v.ProvideMetrics(p.cfg.Metrics.Prefix)...)
+	for _, exporter := range p.exporters {
+		if v, ok := (*exporter).(conduit.PluginMetrics); ok {
+			collectors = append(collectors, v.ProvideMetrics(p.cfg.Metrics.Prefix)...)
+		}
+	}
+	for _, observer := range p.observers {
+		if v, ok := (*observer).(conduit.PluginMetrics); ok {
+			collectors = append(collectors, v.ProvideMetrics(p.cfg.Metrics.Prefix)...)
+		}
 	}
 	for _, c := range collectors {
 		_ = prometheus.Register(c)
@@ -228,6 +926,46 @@ func (p *pipelineImpl) makeConfig(pluginType, pluginName string, cfg []byte) (co
 	return
 }
 
+// makePluginLogger creates a logger which shares the pipeline output. The level
+// defaults to the pipeline level unless the plugin overrides it.
+func (p *pipelineImpl) makePluginLogger(pluginType, pluginName, levelOverride string) *log.Logger {
+	pluginLogger := log.New()
+	// Make sure we are thread-safe
+	pluginLogger.SetOutput(p.logger.Out)
+	// Observers run behind the pipeline, so their entries are not tagged.
+	var roundTag *RoundTag
+	switch pluginType {
+	case plugins.Importer:
+		roundTag = p.importerRoundTag
+	case plugins.Processor, plugins.Exporter:
+		roundTag = p.roundTag
+	}
+	if p.cfg != nil && p.cfg.ConduitArgs != nil && p.cfg.ConduitArgs.Pretty {
+		formatter := makePrettyLogFormatter(pluginType, pluginName)
+		formatter.Round = roundTag
+		pluginLogger.SetFormatter(formatter)
+	} else {
+		format := ""
+		if p.cfg != nil {
+			format = p.cfg.LogFormat
+		}
+		pluginLogger.SetFormatter(makeRoundLogFormatter(format, pluginType, pluginName, roundTag))
+	}
+	pluginLogger.SetLevel(p.logger.GetLevel())
+	if p.recentLogs != nil {
+		pluginLogger.AddHook(p.recentLogs)
+	}
+	if levelOverride != "" {
+		level, err := log.ParseLevel(levelOverride)
+		if err != nil {
+			p.logger.Warnf("Invalid log level (%s) for %s %s, using pipeline level: %v", levelOverride, pluginType, pluginName, err)
+		} else {
+			pluginLogger.SetLevel(level)
+		}
+	}
+	return pluginLogger
+}
+
 // Init prepares the pipeline for processing block data
 func (p *pipelineImpl) Init() error {
 	p.logger.Infof("Starting Pipeline Initialization")
@@ -235,9 +973,20 @@ func (p *pipelineImpl) Init() error {
 	if p.cfg.Metrics.Prefix == "" {
 		p.cfg.Metrics.Prefix = conduit.DefaultMetricsPrefix
 	}
+	metrics.SetLatencyType(p.cfg.Metrics.Latency)
 	metrics.RegisterPrometheusMetrics(p.cfg.Metrics.Prefix)
+	p.addPipelineInfoMetric()
+	p.telemetry = nil
+	if p.cfg.Metrics.Mode == "ON" {
+		p.telemetry = &stageTelemetry{}
+	}
+	p.tracer = p.makeTracer()
+	p.traffic = nil
+	if p.cfg.Bandwidth.LogInterval > 0 {
+		p.traffic = makeTrafficAccount()
+	}
 
-	if p.cfg.CPUProfile != "" {
+	if p.cfg.CPUProfile != "" && !p.dryRun {
 		p.logger.Infof("Creating CPU Profile file at %s", p.cfg.CPUProfile)
 		var err error
 		profFile, err := os.Create(p.cfg.CPUProfile)
@@ -253,106 +1002,338 @@ func (p *pipelineImpl) Init() error {
 		}
 	}
 
-	if p.cfg.PIDFilePath != "" {
+	if p.cfg.PIDFilePath != "" && !p.dryRun {
 		err := util.CreateIndexerPidFile(p.logger, p.cfg.PIDFilePath)
 		if err != nil {
 			return err
 		}
 	}
 
+	if p.signer == nil {
+		signer, err := makeSigner(p.cfg.Signing)
+		if err != nil {
+			return fmt.Errorf("Pipeline.Start(): %w", err)
+		}
+		p.signer = signer
+	}
+
 	// TODO Need to change interfaces to accept config of map[string]interface{}
 
 	// Initialize Importer
-	importerLogger := log.New()
-	// Make sure we are thread-safe
-	importerLogger.SetOutput(p.logger.Out)
 	importerName := (*p.importer).Metadata().Name
-	importerLogger.SetFormatter(makePluginLogFormatter(plugins.Importer, importerName))
+	p.names.importer = importerName
+	importerLogger := p.makePluginLogger(plugins.Importer, importerName, p.cfg.Importer.LogLevel)
+	p.importerLogger = importerLogger
 
 	configs, err := yaml.Marshal(p.cfg.Importer.Config)
 	if err != nil {
 		return fmt.Errorf("Pipeline.Start(): could not serialize Importer.Args: %w", err)
 	}
-	genesis, err := (*p.importer).Init(p.ctx, p.makeConfig("importer", importerName, configs), importerLogger)
+	var genesis *sdk.Genesis
+	p.setTrafficMeter(importerType, importerName, *p.importer)
+	p.telemetry.label(importerType, importerName, func() {
+		genesis, err = (*p.importer).Init(p.ctx, p.makeConfig("importer", importerName, configs), importerLogger)
+	})
 	if err != nil {
 		return fmt.Errorf("Pipeline.Start(): could not initialize importer (%s): %w", importerName, err)
 	}
+	if err = p.checkThrottle(p.cfg.Throttle); err != nil {
+		return fmt.Errorf("Pipeline.Start(): %w", err)
+	}
 
 	// initialize or load pipeline metadata
-	gh := genesis.Hash()
-	ghbase64 := base64.StdEncoding.EncodeToString(gh[:])
-	p.pipelineMetadata.GenesisHash = ghbase64
+	if p.stateStore == nil && p.cfg.StateStore.Type != "" && p.cfg.StateStore.Type != statestore.FileType {
+		p.stateStore, err = statestore.New(p.ctx, p.cfg.StateStore, p.cfg.ConduitArgs.ConduitDataDir)
+		if err != nil {
+			return fmt.Errorf("Pipeline.Start(): could not open state store: %w", err)
+		}
+	}
+	if p.coordinator == nil && p.cfg.Coordination.Enabled() && !p.dryRun {
+		p.coordinator, err = coordinator.New(p.ctx, p.cfg.Coordination)
+		if err != nil {
+			return fmt.Errorf("Pipeline.Start(): could not start coordination: %w", err)
+		}
+	}
+	networkID, genesisHash, err := networkIdentity(*p.importer, genesis)
+	if err != nil {
+		return fmt.Errorf("Pipeline.Start(): %w", err)
+	}
+	p.pipelineMetadata.GenesisHash = genesisHash
 	p.pipelineMetadata.Network = genesis.Network
+	p.pipelineMetadata.NetworkID = networkID
 	p.pipelineMetadata, err = p.initializeOrLoadBlockMetadata()
 	if err != nil {
 		return fmt.Errorf("Pipeline.Start(): could not read metadata: %w", err)
 	}
-	if p.pipelineMetadata.GenesisHash != ghbase64 {
-		return fmt.Errorf("Pipeline.Start(): genesis hash in metadata does not match expected value: actual %s, expected %s", gh, p.pipelineMetadata.GenesisHash)
+	if saved := p.pipelineMetadata.savedNetworkID(); saved != networkID {
+		if genesisHash != "" {
+			return fmt.Errorf("Pipeline.Start(): genesis hash in metadata does not match expected value: actual %s, expected %s", genesisHash, saved)
+		}
+		return fmt.Errorf("Pipeline.Start(): network ID in metadata does not match expected value: actual %s, expected %s", networkID, saved)
 	}
+	p.pipelineMetadata.NetworkID = networkID
+	p.pipelineMetadata.GenesisHash = genesisHash
+	p.startRecovery(time.Now())
 	// overriding NextRound if NextRoundOverride is set
 	if p.cfg.ConduitArgs.NextRoundOverride > 0 {
 		p.logger.Infof("Overriding default next round from %d to %d.", p.pipelineMetadata.NextRound, p.cfg.ConduitArgs.NextRoundOverride)
+		p.recoveryAction("next round %d was overridden with %d", p.pipelineMetadata.NextRound, p.cfg.ConduitArgs.NextRoundOverride)
 		p.pipelineMetadata.NextRound = p.cfg.ConduitArgs.NextRoundOverride
+	} else if p.cfg.Rounds.Start > p.pipelineMetadata.NextRound {
+		p.logger.Infof("Starting rounds at %d instead of next round %d.", p.cfg.Rounds.Start, p.pipelineMetadata.NextRound)
+		p.recoveryAction("next round %d was moved to the rounds start %d", p.pipelineMetadata.NextRound, p.cfg.Rounds.Start)
+		p.pipelineMetadata.NextRound = p.cfg.Rounds.Start
+	}
+	if err = p.initMigration(); err != nil {
+		return fmt.Errorf("Pipeline.Start(): %w", err)
+	}
+	if err = p.initStandby(); err != nil {
+		return fmt.Errorf("Pipeline.Start(): %w", err)
+	}
+	if err = p.initVerification(); err != nil {
+		return fmt.Errorf("Pipeline.Start(): %w", err)
 	}
 
 	p.logger.Infof("Initialized Importer: %s", importerName)
+	if p.telemetry != nil {
+		p.telemetry.importerName = importerName
+	}
 
 	// InitProvider
 	round := sdk.Round(p.pipelineMetadata.NextRound)
 	var initProvider data.InitProvider = conduit.MakePipelineInitProvider(&round, genesis)
 	p.initProvider = &initProvider
 
+	// Exporters which provide the next round are initialized first, so that
+	// the other plugins are initialized at their round.
+	exporterCfgs := p.cfg.exporterConfigs()
+	p.exporterLoggers = make([]*log.Logger, len(p.exporters))
+	p.names.exporters = make([]string, len(p.exporters))
+	if p.telemetry != nil {
+		p.telemetry.exporterNames = make([]string, len(p.exporters))
+	}
+	for idx, exporter := range p.exporters {
+		if _, ok := (*exporter).(conduit.RoundProvider); ok {
+			if err = p.initExporter(idx, exporterCfgs[idx]); err != nil {
+				return err
+			}
+		}
+	}
+	if err = p.applyProvidedRound(&round); err != nil {
+		return fmt.Errorf("Pipeline.Init(): %w", err)
+	}
+	p.finishRecovery()
+
 	// Initialize Processors
+	p.blockCache = makeBlockCache(p.cfg.BlockCache)
+	p.processorLoggers = make([]*log.Logger, len(p.processors))
+	p.names.processors = make([]string, len(p.processors))
 	for idx, processor := range p.processors {
-		processorLogger := log.New()
-		// Make sure we are thread-safe
-		processorLogger.SetOutput(p.logger.Out)
-		processorLogger.SetFormatter(makePluginLogFormatter(plugins.Processor, (*processor).Metadata().Name))
+		processorName := (*processor).Metadata().Name
+		p.names.processors[idx] = processorName
+		processorLogger := p.makePluginLogger(plugins.Processor, processorName, p.cfg.Processors[idx].LogLevel)
+		p.processorLoggers[idx] = processorLogger
 		configs, err = yaml.Marshal(p.cfg.Processors[idx].Config)
 		if err != nil {
 			return fmt.Errorf("Pipeline.Start(): could not serialize Processors[%d].Args : %w", idx, err)
 		}
-		processorName := (*processor).Metadata().Name
-		err := (*processor).Init(p.ctx, *p.initProvider, p.makeConfig("processor", processorName, configs), processorLogger)
+		processorLabel := metrics.ProcessorLabel(p.cfg.Metrics.ProcessorLabels, processorName)
+		p.setTrafficMeter(processorType, processorName, *processor)
+		p.telemetry.label(processorType, processorLabel, func() {
+			err = (*processor).Init(p.ctx, *p.initProvider, p.makeConfig("processor", processorName, configs), processorLogger)
+		})
 		if err != nil {
 			return fmt.Errorf("Pipeline.Init(): could not initialize processor (%s): %w", processorName, err)
 		}
+		p.setBlockCache(*processor)
+		if err = p.initWindow(processor, fmt.Sprintf("processor_%d_%s", idx, processorName)); err != nil {
+			return fmt.Errorf("Pipeline.Init(): %w", err)
+		}
 		p.logger.Infof("Initialized Processor: %s", processorName)
+		if p.telemetry != nil {
+			p.telemetry.processorNames = append(p.telemetry.processorNames, processorLabel)
+		}
 	}
 
-	// Initialize Exporter
-	exporterLogger := log.New()
-	// Make sure we are thread-safe
-	exporterLogger.SetOutput(p.logger.Out)
-	exporterLogger.SetFormatter(makePluginLogFormatter(plugins.Exporter, (*p.exporter).Metadata().Name))
+	if err = p.initExporterChains(); err != nil {
+		return err
+	}
 
-	configs, err = yaml.Marshal(p.cfg.Exporter.Config)
-	if err != nil {
-		return fmt.Errorf("Pipeline.Start(): could not serialize Exporter.Args : %w", err)
+	// Initialize Exporters
+	for idx, exporter := range p.exporters {
+		if _, ok := (*exporter).(conduit.RoundProvider); !ok {
+			if err = p.initExporter(idx, exporterCfgs[idx]); err != nil {
+				return err
+			}
+		}
 	}
-	exporterName := (*p.exporter).Metadata().Name
-	err = (*p.exporter).Init(p.ctx, *p.initProvider, p.makeConfig("exporter", exporterName, configs), exporterLogger)
-	if err != nil {
-		return fmt.Errorf("Pipeline.Start(): could not initialize Exporter (%s): %w", exporterName, err)
+	if err = p.checkWriterVersions(); err != nil {
+		return fmt.Errorf("Pipeline.Init(): %w", err)
+	}
+
+	// Initialize Observers
+	p.observerLoggers = make([]*log.Logger, len(p.observers))
+	for idx, observer := range p.observers {
+		observerName := (*observer).Metadata().Name
+		observerLogger := p.makePluginLogger(plugins.Observer, observerName, p.cfg.Observers[idx].LogLevel)
+		p.observerLoggers[idx] = observerLogger
+		configs, err = yaml.Marshal(p.cfg.Observers[idx].Config)
+		if err != nil {
+			return fmt.Errorf("Pipeline.Start(): could not serialize Observers[%d].Args : %w", idx, err)
+		}
+		err = (*observer).Init(p.ctx, *p.initProvider, p.makeConfig("observer", observerName, configs), observerLogger)
+		if err != nil {
+			return fmt.Errorf("Pipeline.Init(): could not initialize observer (%s): %w", observerName, err)
+		}
+		p.logger.Infof("Initialized Observer: %s", observerName)
 	}
-	p.logger.Infof("Initialized Exporter: %s", exporterName)
 
 	// Register callbacks.
 	p.registerLifecycleCallbacks()
 
+	// A dry run stops after the plugins are initialized.
+	if p.dryRun {
+		return nil
+	}
+
+	p.startObservers()
+
+	if err = p.startProfiling(); err != nil {
+		return fmt.Errorf("Pipeline.Init(): %w", err)
+	}
+
 	// start metrics server
 	if p.cfg.Metrics.Mode == "ON" {
 		p.registerPluginMetricsCallbacks()
 		go p.startMetricsServer()
 	}
 
+	// start status API server
+	p.debugCache = makeDebugCache(p.cfg.API.DebugRounds)
+	p.slo = makeSLOTracker(p.cfg.SLO)
+	if p.cfg.API.Addr != "" {
+		go p.startAPIServer()
+	}
+
+	// Warm up the plugins before the first round, /ready is false meanwhile.
+	if err = p.warmup(); err != nil {
+		return fmt.Errorf("Pipeline.Init(): %w", err)
+	}
+
 	return err
 }
 
+// initExporter initializes the exporter at idx with the current InitProvider.
+func (p *pipelineImpl) initExporter(idx int, cfg NameConfigPair) error {
+	exporter := p.exporters[idx]
+	exporterName := p.exporterID(idx)
+	p.names.exporters[idx] = exporterName
+	exporterLogger := p.makePluginLogger(plugins.Exporter, exporterName, cfg.LogLevel)
+	p.exporterLoggers[idx] = exporterLogger
+	configs, err := yaml.Marshal(cfg.Config)
+	if err != nil {
+		return fmt.Errorf("Pipeline.Start(): could not serialize Exporters[%d].Args : %w", idx, err)
+	}
+	p.setTrafficMeter(exporterType, exporterName, *exporter)
+	p.telemetry.label(exporterType, exporterName, func() {
+		err = (*exporter).Init(p.ctx, *p.initProvider, p.makeConfig("exporter", exporterName, configs), exporterLogger)
+	})
+	if err != nil {
+		return fmt.Errorf("Pipeline.Start(): could not initialize Exporter (%s): %w", exporterName, err)
+	}
+	p.setSigner(*exporter)
+	p.setBinaryEncoding(*exporter)
+	p.setAmountFormat(*exporter)
+	if err = p.setPayloadChecker(idx, cfg, *exporter); err != nil {
+		return fmt.Errorf("Pipeline.Start(): %w", err)
+	}
+	p.logger.Infof("Initialized Exporter: %s", exporterName)
+	if p.telemetry != nil {
+		p.telemetry.exporterNames[idx] = exporterName
+	}
+	return nil
+}
+
+// setBinaryEncoding passes the configured binary encoding to the exporter if
+// it writes a text format.
+func (p *pipelineImpl) setBinaryEncoding(exporter exporters.Exporter) {
+	if p.cfg.BinaryEncoding == "" {
+		return
+	}
+	if e, ok := exporter.(conduit.BinaryEncoder); ok {
+		e.SetBinaryEncoding(p.cfg.BinaryEncoding)
+	}
+}
+
+// Stop finishes the in-flight round, then closes all plugins. If the round does
+// not finish within ShutdownGracePeriod the pipeline context is cancelled.
+// It is safe to call Stop more than once.
 func (p *pipelineImpl) Stop() {
+	p.stopOnce.Do(p.stop)
+}
+
+// requestStop asks the pipeline loop to exit once the in-flight round is complete.
+func (p *pipelineImpl) requestStop() {
+	p.stopReqOnce.Do(func() {
+		if p.stopCh != nil {
+			close(p.stopCh)
+		}
+	})
+}
+
+// waitForRound waits for the pipeline loop to exit, returning false if the grace period elapses first.
+func (p *pipelineImpl) waitForRound(grace time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(grace):
+		return false
+	}
+}
+
+func (p *pipelineImpl) stop() {
+	p.requestStop()
+	if p.stopCh != nil && p.cfg.ShutdownGracePeriod > 0 {
+		if !p.waitForRound(p.cfg.ShutdownGracePeriod) {
+			p.logger.Warnf("Pipeline.Stop(): in-flight round did not finish within %s, cancelling", p.cfg.ShutdownGracePeriod)
+		}
+	}
 	p.cf()
 	p.wg.Wait()
+	p.stopObservers()
+	if p.profilerDone != nil {
+		<-p.profilerDone
+	}
+	if prefetch := p.currentPrefetch(); prefetch != nil {
+		prefetch.stop()
+	}
+
+	if p.tracer != nil {
+		ctx, cf := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := p.tracer.Shutdown(ctx); err != nil {
+			p.logger.Warnf("Pipeline.Stop(): could not export traces: %v", err)
+		}
+		cf()
+	}
+
+	if p.coordinator != nil {
+		if err := p.coordinator.Close(); err != nil {
+			p.logger.Warnf("Pipeline.Stop(): could not close coordinator: %v", err)
+		}
+		p.coordinator = nil
+	}
+
+	if p.stateStore != nil {
+		if err := p.stateStore.Close(); err != nil {
+			p.logger.Warnf("Pipeline.Stop(): could not close state store: %v", err)
+		}
+		p.stateStore = nil
+	}
 
 	if p.profFile != nil {
 		if err := p.profFile.Close(); err != nil {
@@ -361,7 +1342,7 @@ func (p *pipelineImpl) Stop() {
 		pprof.StopCPUProfile()
 	}
 
-	if p.cfg.PIDFilePath != "" {
+	if p.cfg.PIDFilePath != "" && !p.dryRun {
 		if err := os.Remove(p.cfg.PIDFilePath); err != nil {
 			p.logger.WithError(err).Errorf("%s: could not remove pid file", p.cfg.PIDFilePath)
 		}
@@ -369,195 +1350,329 @@ func (p *pipelineImpl) Stop() {
 
 	if err := (*p.importer).Close(); err != nil {
 		// Log and continue on closing the rest of the pipeline
-		p.logger.Errorf("Pipeline.Stop(): Importer (%s) error on close: %v", (*p.importer).Metadata().Name, err)
+		p.logger.Errorf("Pipeline.Stop(): Importer (%s) error on close: %v", *p.importer, err)
 	}
 
-	for _, processor := range p.processors {
+	for _, processor := range p.allProcessors() {
 		if err := (*processor).Close(); err != nil {
 			// Log and continue on closing the rest of the pipeline
 			p.logger.Errorf("Pipeline.Stop(): Processor (%s) error on close: %v", (*processor).Metadata().Name, err)
 		}
 	}
 
-	if err := (*p.exporter).Close(); err != nil {
-		p.logger.Errorf("Pipeline.Stop(): Exporter (%s) error on close: %v", (*p.exporter).Metadata().Name, err)
+	for idx, exporter := range p.exporters {
+		if p.migration != nil && p.migration.closed && idx == p.migration.from {
+			continue
+		}
+		if err := (*exporter).Close(); err != nil {
+			// Log and continue on closing the rest of the pipeline
+			p.logger.Errorf("Pipeline.Stop(): Exporter (%s) error on close: %v", *exporter, err)
+		}
+	}
+
+	for _, observer := range p.observers {
+		if err := (*observer).Close(); err != nil {
+			// Log and continue on closing the rest of the pipeline
+			p.logger.Errorf("Pipeline.Stop(): Observer (%s) error on close: %v", (*observer).Metadata().Name, err)
+		}
 	}
 }
 
-func (p *pipelineImpl) addMetrics(block data.BlockData, importTime time.Duration) {
-	metrics.BlockImportTimeSeconds.Observe(importTime.Seconds())
+func (p *pipelineImpl) addMetrics(block data.BlockData, importTime time.Duration, traceID string) {
+	metrics.ObserveLatency(metrics.BlockImportTimeSeconds, importTime.Seconds(), traceID)
 	metrics.ImportedTxnsPerBlock.Observe(float64(len(block.Payset)))
 	metrics.ImportedRoundGauge.Set(float64(block.Round()))
+	if p.cfg.Metrics.ResetStaleLabels {
+		metrics.ImportedTxns.Reset()
+	}
 	txnCountByType := make(map[string]int)
 	for _, txn := range block.Payset {
-		txnCountByType[string(txn.Txn.Type)]++
+		txnCountByType[metrics.TxnTypeLabel(p.cfg.Metrics.TxnTypeLabels, string(txn.Txn.Type))]++
 	}
 	for k, v := range txnCountByType {
 		metrics.ImportedTxns.WithLabelValues(k).Set(float64(v))
 	}
 }
 
+// fingerprint returns a short, stable hash of the input.
+func fingerprint(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// processorsHash fingerprints the ordered list of processor names.
+func (cfg *Config) processorsHash() string {
+	names := make([]string, 0, len(cfg.Processors))
+	for _, proc := range cfg.Processors {
+		names = append(names, proc.Name)
+	}
+	return fingerprint([]byte(strings.Join(names, ",")))
+}
+
+// exporterNames joins the exporter IDs in the order they are configured.
+func (cfg *Config) exporterNames() string {
+	return strings.Join(cfg.exporterIDs(), ",")
+}
+
+// configHash fingerprints the serialized pipeline configuration.
+func (cfg *Config) configHash() (string, error) {
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("configHash(): could not serialize config: %w", err)
+	}
+	return fingerprint(b), nil
+}
+
+// addPipelineInfoMetric publishes the pipeline composition so that dashboards
+// can annotate when it changes.
+func (p *pipelineImpl) addPipelineInfoMetric() {
+	configHash, err := p.cfg.configHash()
+	if err != nil {
+		p.logger.Warnf("unable to compute pipeline config hash: %v", err)
+	}
+	metrics.PipelineInfo.WithLabelValues(
+		p.cfg.Importer.Name,
+		p.cfg.processorsHash(),
+		p.cfg.exporterNames(),
+		configHash).Set(1)
+}
+
 // Start pushes block data through the pipeline
 func (p *pipelineImpl) Start() {
 	p.wg.Add(1)
-	retry := uint64(0)
-	go func() {
-		defer p.wg.Done()
+	var prefetch *prefetcher
+	if size := p.cfg.prefetchSize(); p.subscribing() {
+		p.logger.Infof("Subscribing to the importer from round %d", p.pipelineMetadata.NextRound)
+		prefetch = p.startPrefetch()
+	} else if size > 0 {
+		if p.cfg.Rounds.Workers > 1 {
+			p.logger.Infof("Prefetching up to %d rounds ahead of the exporter with %d workers", size, p.cfg.Rounds.Workers)
+		} else {
+			p.logger.Infof("Prefetching up to %d rounds ahead of the exporter", size)
+		}
+		prefetch = p.startPrefetch()
+	}
+	p.batch = p.makeBatch()
+	if n := len(p.pipelineMetadata.CommitIntents); n > 0 {
+		p.logger.Infof("Found %d prepared rounds from a previous run, they are committed instead of exported again", n)
+	}
+	p.simClock = makeSimClock(p.cfg.Simulation, p.pipelineMetadata.NextRound, time.Now())
+	if p.simClock != nil {
+		p.logger.Warnf("Simulating a round every %s, this is meant for development only", p.cfg.Simulation.RoundTime)
+	}
+	loopDone := make(chan struct{})
+	p.mu.Lock()
+	p.status.Running = true
+	p.status.NextRound = p.pipelineMetadata.NextRound
+	p.loopDone = loopDone
+	p.mu.Unlock()
+	if p.cfg.ConduitArgs != nil && p.cfg.ConduitArgs.Pretty {
+		go p.reportProgress(progressInterval)
+	}
+	if p.cfg.ConfigDrift.Interval > 0 && p.cfg.fileHash != "" {
+		go p.watchConfigDrift(p.cfg.ConfigDrift.Interval, loopDone)
+	}
+	if p.traffic != nil {
+		go p.reportTraffic(p.cfg.Bandwidth.LogInterval, loopDone)
+	}
+	// endLoop closes loopDone once the current loop exits, or is given up by
+	// the watchdog. It returns false for an abandoned loop.
+	endLoop := func(gen uint64) bool {
+		if !p.watchdog.end(gen) {
+			return false
+		}
+		close(loopDone)
+		p.setRunning(false)
+		return true
+	}
+	// loop runs the pipeline from the next round. The watchdog runs a new
+	// generation when the loop stalls, the abandoned loop returns once its
+	// plugin call does.
+	loop := func(gen uint64, prefetch *prefetcher) {
+		defer p.watchdog.exit(gen)
+		ended := false
+		defer func() {
+			if ended {
+				p.wg.Done()
+			}
+		}()
 		// We need to add a separate recover function here since it launches its own go-routine
 		defer HandlePanic(p.logger)
-		for {
-		pipelineRun:
-			metrics.PipelineRetryCount.Observe(float64(retry))
-			if retry > p.cfg.RetryCount {
-				p.logger.Errorf("Pipeline has exceeded maximum retry count (%d) - stopping...", p.cfg.RetryCount)
-				return
+		defer func() {
+			if r := recover(); r != nil {
+				p.writeSupportBundle(fmt.Sprintf("panic: %v", r))
+				panic(r)
 			}
-
-			if retry > 0 {
-				time.Sleep(p.cfg.RetryDelay)
-			}
-
-			select {
-			case <-p.ctx.Done():
-				return
-			default:
-				{
-					p.logger.Infof("Pipeline round: %v", p.pipelineMetadata.NextRound)
-					// fetch block
-					importStart := time.Now()
-					blkData, err := (*p.importer).GetBlock(p.pipelineMetadata.NextRound)
-					if err != nil {
-						p.logger.Errorf("%v", err)
-						p.setError(err)
-						retry++
-						goto pipelineRun
-					}
-					metrics.ImporterTimeSeconds.Observe(time.Since(importStart).Seconds())
-
-					// TODO: Verify that the block was build with a known protocol version.
-
-					// Start time currently measures operations after block fetching is complete.
-					// This is for backwards compatibility w/ Indexer's metrics
-					// run through processors
-					start := time.Now()
-					for _, proc := range p.processors {
-						processorStart := time.Now()
-						blkData, err = (*proc).Process(blkData)
-						if err != nil {
-							p.logger.Errorf("%v", err)
-							p.setError(err)
-							retry++
-							goto pipelineRun
-						}
-						metrics.ProcessorTimeSeconds.WithLabelValues((*proc).Metadata().Name).Observe(time.Since(processorStart).Seconds())
-					}
-					// run through exporter
-					exporterStart := time.Now()
-					err = (*p.exporter).Receive(blkData)
-					if err != nil {
-						p.logger.Errorf("%v", err)
-						p.setError(err)
-						retry++
-						goto pipelineRun
-					}
-					p.logger.Infof("round r=%d (%d txn) exported in %s", p.pipelineMetadata.NextRound, len(blkData.Payset), time.Since(start))
-
-					// Increment Round, update metadata
-					p.pipelineMetadata.NextRound++
-					err = p.encodeMetadataToFile()
-					if err != nil {
-						p.logger.Errorf("%v", err)
-					}
-
-					// Callback Processors
-					for _, cb := range p.completeCallback {
-						err = cb(blkData)
-						if err != nil {
-							p.logger.Errorf("%v", err)
-							p.setError(err)
-							retry++
-							goto pipelineRun
-						}
-					}
-					metrics.ExporterTimeSeconds.Observe(time.Since(exporterStart).Seconds())
-					// Ignore round 0 (which is empty).
-					if p.pipelineMetadata.NextRound > 1 {
-						p.addMetrics(blkData, time.Since(start))
-					}
-					p.setError(nil)
-					retry = 0
-				}
+		}()
+		defer func() {
+			ended = endLoop(gen)
+		}()
+		r := p.makeRoundLoop(gen, prefetch)
+		// The prefetcher may be blocked in GetBlock, Stop waits for it to exit.
+		defer func() {
+			if r.prefetch != nil {
+				r.prefetch.cancel()
 			}
+		}()
+		r.run()
+	}
+	gen := p.watchdog.start(time.Now())
+	if p.cfg.Watchdog.enabled() {
+		p.wg.Add(1)
+		go p.runWatchdog(loopDone, loop, endLoop)
+	}
+	go loop(gen, prefetch)
+}
 
-		}
-	}()
+// isBestEffort reports whether the exporter at idx may skip rounds which it fails to receive.
+func (p *pipelineImpl) isBestEffort(idx int) bool {
+	exporterCfgs := p.cfg.exporterConfigs()
+	return idx < len(exporterCfgs) && exporterCfgs[idx].BestEffort
 }
 
 func (p *pipelineImpl) Wait() {
 	p.wg.Wait()
 }
 
-func metadataPath(dataDir string) string {
-	return path.Join(dataDir, "metadata.json")
+// store returns the state store. When no other backend is configured the
+// state is saved in metadata.json in the data directory.
+func (p *pipelineImpl) store() statestore.StateStore {
+	if p.stateStore != nil {
+		return p.stateStore
+	}
+	return statestore.MakeFileStore(statestore.MetadataPath(p.cfg.ConduitArgs.ConduitDataDir))
 }
 
-func (p *pipelineImpl) encodeMetadataToFile() error {
-	pipelineMetadataFilePath := metadataPath(p.cfg.ConduitArgs.ConduitDataDir)
-	tempFilename := fmt.Sprintf("%s.temp", pipelineMetadataFilePath)
-	file, err := os.Create(tempFilename)
-	if err != nil {
-		return fmt.Errorf("encodeMetadataToFile(): failed to create temp metadata file: %w", err)
+// saveMetadata saves the pipeline state. While rounds are waiting for the
+// batch exporters the first of them is saved as the next round.
+func (p *pipelineImpl) saveMetadata() error {
+	md := p.pipelineMetadata
+	if p.batch.pending() {
+		md.NextRound = p.batch.first
 	}
-	defer file.Close()
-	err = json.NewEncoder(file).Encode(p.pipelineMetadata)
-	if err != nil {
-		return fmt.Errorf("encodeMetadataToFile(): failed to write temp metadata: %w", err)
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(md); err != nil {
+		return fmt.Errorf("saveMetadata(): failed to encode metadata: %w", err)
 	}
-
-	err = os.Rename(tempFilename, pipelineMetadataFilePath)
-	if err != nil {
-		return fmt.Errorf("encodeMetadataToFile(): failed to replace metadata file: %w", err)
+	if err := p.store().Save(p.ctx, buf.Bytes()); err != nil {
+		return fmt.Errorf("saveMetadata(): %w", err)
 	}
 	return nil
 }
 
 func (p *pipelineImpl) initializeOrLoadBlockMetadata() (state, error) {
-	pipelineMetadataFilePath := metadataPath(p.cfg.ConduitArgs.ConduitDataDir)
-	if stat, err := os.Stat(pipelineMetadataFilePath); errors.Is(err, os.ErrNotExist) || (stat != nil && stat.Size() == 0) {
-		if stat != nil && stat.Size() == 0 {
-			err = os.Remove(pipelineMetadataFilePath)
-			if err != nil {
-				return p.pipelineMetadata, fmt.Errorf("Init(): error creating file: %w", err)
-			}
+	data, err := p.store().Load(p.ctx)
+	if err != nil {
+		return p.pipelineMetadata, fmt.Errorf("error reading metadata: %w", err)
+	}
+	if data == nil {
+		p.metadataCreated = true
+		if p.dryRun {
+			return p.pipelineMetadata, nil
 		}
-		err = p.encodeMetadataToFile()
+		err = p.saveMetadata()
 		if err != nil {
 			return p.pipelineMetadata, fmt.Errorf("Init(): error creating file: %w", err)
 		}
-	} else {
+		return p.pipelineMetadata, nil
+	}
+	err = json.Unmarshal(data, &p.pipelineMetadata)
+	if err != nil {
+		return p.pipelineMetadata, fmt.Errorf("error reading metadata: %w", err)
+	}
+	return p.pipelineMetadata, nil
+}
+
+// networkIdentity returns the network ID of the importer and, unless the
+// importer implements importers.NetworkIdentifier, the genesis hash which
+// is also the network ID.
+func networkIdentity(importer importers.Importer, genesis *sdk.Genesis) (networkID string, genesisHash string, err error) {
+	if identifier, ok := importer.(importers.NetworkIdentifier); ok {
+		networkID, err = identifier.NetworkID(genesis)
 		if err != nil {
-			return p.pipelineMetadata, fmt.Errorf("error opening file: %w", err)
+			return "", "", fmt.Errorf("networkIdentity(): importer (%s) could not provide the network ID: %w", importer.Metadata().Name, err)
 		}
-		var data []byte
-		data, err = os.ReadFile(pipelineMetadataFilePath)
-		if err != nil {
-			return p.pipelineMetadata, fmt.Errorf("error reading metadata: %w", err)
+		if networkID == "" {
+			return "", "", fmt.Errorf("networkIdentity(): importer (%s) provided an empty network ID", importer.Metadata().Name)
 		}
-		err = json.Unmarshal(data, &p.pipelineMetadata)
+		return networkID, "", nil
+	}
+	gh := genesis.Hash()
+	genesisHash = base64.StdEncoding.EncodeToString(gh[:])
+	return genesisHash, genesisHash, nil
+}
+
+// savedNetworkID returns the network ID of the metadata, which is the genesis
+// hash for metadata saved before network IDs.
+func (s state) savedNetworkID() string {
+	if s.NetworkID != "" {
+		return s.NetworkID
+	}
+	return s.GenesisHash
+}
+
+// applyProvidedRound replaces the next round with the round of the exporters
+// which implement conduit.RoundProvider, they are authoritative over the
+// state store. The standby exporter is not consulted since it receives no
+// rounds.
+func (p *pipelineImpl) applyProvidedRound(round *sdk.Round) error {
+	var provider string
+	var next uint64
+	for idx, exporter := range p.exporters {
+		roundProvider, ok := (*exporter).(conduit.RoundProvider)
+		if !ok || p.isStandby(idx) {
+			continue
+		}
+		name := p.exporterName(idx)
+		rnd, err := roundProvider.NextRound()
 		if err != nil {
-			return p.pipelineMetadata, fmt.Errorf("error reading metadata: %w", err)
+			return fmt.Errorf("applyProvidedRound(): exporter (%s) could not provide the next round: %w", name, err)
+		}
+		p.exporterRound(name, rnd)
+		if provider != "" && rnd != next {
+			return fmt.Errorf("applyProvidedRound(): exporters (%s) and (%s) provided different next rounds %d and %d", provider, name, next, rnd)
 		}
+		provider, next = name, rnd
 	}
-	return p.pipelineMetadata, nil
+	if provider == "" || next == p.pipelineMetadata.NextRound {
+		return nil
+	}
+	if p.cfg.ConduitArgs.NextRoundOverride > 0 {
+		return fmt.Errorf("applyProvidedRound(): next round override %d does not match exporter (%s) next round %d", p.cfg.ConduitArgs.NextRoundOverride, provider, next)
+	}
+	p.logger.Warnf("Exporter (%s) provided next round %d, replacing next round %d.", provider, next, p.pipelineMetadata.NextRound)
+	p.recoveryAction("next round %d was replaced with the next round %d of exporter (%s)", p.pipelineMetadata.NextRound, next, provider)
+	p.pipelineMetadata.NextRound = next
+	*round = sdk.Round(next)
+	return p.saveMetadata()
 }
 
-// start a http server serving /metrics
+// start a http server serving /metrics and the status API
 func (p *pipelineImpl) startMetricsServer() {
-	http.Handle("/metrics", promhttp.Handler())
-	_ = http.ListenAndServe(p.cfg.Metrics.Addr, nil)
+	mux := http.NewServeMux()
+	// Exemplars are only exposed in the OpenMetrics format.
+	handler := promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+		EnableOpenMetrics: p.cfg.Metrics.Latency == metrics.LatencyHistogram,
+	}))
+	mux.Handle("/metrics", handler)
+	p.registerAPIHandlers(mux)
+	_ = http.ListenAndServe(p.cfg.Metrics.Addr, mux)
 	p.logger.Infof("conduit metrics serving on %s", p.cfg.Metrics.Addr)
 }
 
+// makeProcessor builds the processor of a config, either an external process
+// or a built-in processor looked up by name.
+func makeProcessor(processorConfig NameConfigPair) (processors.Processor, error) {
+	if processorConfig.Executable != "" {
+		return external.MakeProcessor(processorConfig.Name, processorConfig.Executable, processorConfig.Args, processorConfig.Sandbox), nil
+	}
+	processorBuilder, err := processors.ProcessorBuilderByName(processorConfig.Name)
+	if err != nil {
+		return nil, fmt.Errorf("MakePipeline(): could not build processor '%s': %w", processorConfig.Name, err)
+	}
+	return processorBuilder.New(), nil
+}
+
 // MakePipeline creates a Pipeline
 func MakePipeline(ctx context.Context, cfg *Config, logger *log.Logger) (Pipeline, error) {
 
@@ -576,24 +1691,40 @@ func MakePipeline(ctx context.Context, cfg *Config, logger *log.Logger) (Pipelin
 	cancelContext, cancelFunc := context.WithCancel(ctx)
 
 	pipeline := &pipelineImpl{
-		ctx:          cancelContext,
-		cf:           cancelFunc,
-		cfg:          cfg,
-		logger:       logger,
-		initProvider: nil,
-		importer:     nil,
-		processors:   []*processors.Processor{},
-		exporter:     nil,
+		ctx:              cancelContext,
+		cf:               cancelFunc,
+		stopCh:           make(chan struct{}),
+		reloadCh:         make(chan reloadRequest),
+		checkpointCh:     make(chan chan checkpointResult),
+		rollbackCh:       make(chan rollbackRequest),
+		promoteCh:        make(chan chan error),
+		debugCh:          make(chan debugRequest),
+		roundTag:         &RoundTag{},
+		importerRoundTag: &RoundTag{},
+		recentLogs:       makeLogRing(recentLogLines),
+		cfg:              cfg,
+		logger:           logger,
+		initProvider:     nil,
+		importer:         nil,
+		processors:       []*processors.Processor{},
+		exporters:        []*exporters.Exporter{},
+		observers:        []*observers.Observer{},
 	}
 
+	logger.AddHook(pipeline.recentLogs)
+
 	importerName := cfg.Importer.Name
 
-	importerBuilder, err := importers.ImporterBuilderByName(importerName)
-	if err != nil {
-		return nil, fmt.Errorf("MakePipeline(): could not build importer '%s': %w", importerName, err)
+	var importer importers.Importer
+	if cfg.Importer.Executable != "" {
+		importer = external.MakeImporter(importerName, cfg.Importer.Executable, cfg.Importer.Args, cfg.Importer.Sandbox)
+	} else {
+		importerBuilder, err := importers.ImporterBuilderByName(importerName)
+		if err != nil {
+			return nil, fmt.Errorf("MakePipeline(): could not build importer '%s': %w", importerName, err)
+		}
+		importer = importerBuilder.New()
 	}
-
-	importer := importerBuilder.New()
 	pipeline.importer = &importer
 	logger.Infof("Found Importer: %s", importerName)
 
@@ -602,28 +1733,66 @@ func MakePipeline(ctx context.Context, cfg *Config, logger *log.Logger) (Pipelin
 	for _, processorConfig := range cfg.Processors {
 		processorName := processorConfig.Name
 
-		processorBuilder, err := processors.ProcessorBuilderByName(processorName)
+		cond, err := makeCondition(processorConfig)
 		if err != nil {
-			return nil, fmt.Errorf("MakePipeline(): could not build processor '%s': %w", processorName, err)
+			return nil, fmt.Errorf("MakePipeline(): %w", err)
 		}
 
-		processor := processorBuilder.New()
+		processor, err := makeProcessor(processorConfig)
+		if err != nil {
+			return nil, err
+		}
 		pipeline.processors = append(pipeline.processors, &processor)
+		pipeline.processorConditions = append(pipeline.processorConditions, cond)
 		logger.Infof("Found Processor: %s", processorName)
 	}
 
 	// ---
 
-	exporterName := cfg.Exporter.Name
+	for _, exporterConfig := range cfg.exporterConfigs() {
+		exporterName := exporterConfig.Name
+
+		cond, err := makeCondition(exporterConfig)
+		if err != nil {
+			return nil, fmt.Errorf("MakePipeline(): %w", err)
+		}
 
-	exporterBuilder, err := exporters.ExporterBuilderByName(exporterName)
+		var exporter exporters.Exporter
+		if exporterConfig.Executable != "" {
+			exporter = external.MakeExporter(exporterName, exporterConfig.Executable, exporterConfig.Args, exporterConfig.Sandbox)
+		} else {
+			exporterBuilder, err := exporters.ExporterBuilderByName(exporterName)
+			if err != nil {
+				return nil, fmt.Errorf("MakePipeline(): could not build exporter '%s': %w", exporterName, err)
+			}
+			exporter = exporterBuilder.New()
+		}
+		pipeline.exporters = append(pipeline.exporters, &exporter)
+		pipeline.exporterConditions = append(pipeline.exporterConditions, cond)
+		logger.Infof("Found Exporter: %s", exporterName)
+	}
+	exporterChains, err := makeExporterChains(cfg, logger)
 	if err != nil {
-		return nil, fmt.Errorf("MakePipeline(): could not build exporter '%s': %w", exporterName, err)
+		return nil, err
+	}
+	pipeline.exporterChains = exporterChains
+	if pipeline.lanes, err = makePriorityLanes(cfg); err != nil {
+		return nil, fmt.Errorf("MakePipeline(): %w", err)
 	}
 
-	exporter := exporterBuilder.New()
-	pipeline.exporter = &exporter
-	logger.Infof("Found Exporter: %s", exporterName)
+	// ---
+
+	for _, observerConfig := range cfg.Observers {
+		observerName := observerConfig.Name
+
+		observerBuilder, err := observers.ObserverBuilderByName(observerName)
+		if err != nil {
+			return nil, fmt.Errorf("MakePipeline(): could not build observer '%s': %w", observerName, err)
+		}
+		observer := observerBuilder.New()
+		pipeline.observers = append(pipeline.observers, &observer)
+		logger.Infof("Found Observer: %s", observerName)
+	}
 
 	return pipeline, nil
 }
@@ -10,6 +10,7 @@ import (
 	"path"
 	"path/filepath"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -19,12 +20,14 @@ import (
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/algorand/conduit/conduit"
 	"github.com/algorand/conduit/conduit/data"
 	_ "github.com/algorand/conduit/conduit/metrics"
 	"github.com/algorand/conduit/conduit/plugins"
 	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/external"
 	"github.com/algorand/conduit/conduit/plugins/importers"
 	"github.com/algorand/conduit/conduit/plugins/processors"
 )
@@ -39,21 +42,67 @@ func TestPipelineConfigValidity(t *testing.T) {
 		{"valid", Config{
 			ConduitArgs:      &conduit.Args{ConduitDataDir: ""},
 			PipelineLogLevel: "info",
-			Importer:         NameConfigPair{"test", map[string]interface{}{"a": "a"}},
+			Importer:         NameConfigPair{Name: "test", Config: map[string]interface{}{"a": "a"}},
 			Processors:       nil,
-			Exporter:         NameConfigPair{"test", map[string]interface{}{"a": "a"}},
+			Exporter:         NameConfigPair{Name: "test", Config: map[string]interface{}{"a": "a"}},
 		}, ""},
 
 		{"valid 2", Config{
 			ConduitArgs:      &conduit.Args{ConduitDataDir: ""},
 			PipelineLogLevel: "info",
-			Importer:         NameConfigPair{"test", map[string]interface{}{"a": "a"}},
-			Processors:       []NameConfigPair{{"test", map[string]interface{}{"a": "a"}}},
-			Exporter:         NameConfigPair{"test", map[string]interface{}{"a": "a"}},
+			Importer:         NameConfigPair{Name: "test", Config: map[string]interface{}{"a": "a"}},
+			Processors:       []NameConfigPair{{Name: "test", Config: map[string]interface{}{"a": "a"}}},
+			Exporter:         NameConfigPair{Name: "test", Config: map[string]interface{}{"a": "a"}},
 		}, ""},
 
 		{"empty config", Config{ConduitArgs: nil}, "Args.Valid(): conduit args were nil"},
 		{"invalid log level", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, PipelineLogLevel: "asdf"}, "Args.Valid(): pipeline log level (asdf) was invalid:"},
+		{"plugin log level", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporter: NameConfigPair{Name: "test", LogLevel: "debug"}}, ""},
+		{"invalid plugin log level", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Processors: []NameConfigPair{{Name: "test", LogLevel: "asdf"}}}, "Args.Valid(): plugin (test) log level (asdf) was invalid:"},
+		{"bucketed txn type labels", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Metrics: Metrics{TxnTypeLabels: "bucket", ProcessorLabels: "off"}}, ""},
+		{"invalid txn type labels", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Metrics: Metrics{TxnTypeLabels: "asdf"}}, "Args.Valid(): invalid metrics txn-type-labels"},
+		{"invalid processor labels", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Metrics: Metrics{ProcessorLabels: "bucket"}}, "Args.Valid(): invalid metrics processor-labels"},
+		{"histogram latency", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Metrics: Metrics{Latency: "histogram"}}, ""},
+		{"invalid latency", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Metrics: Metrics{Latency: "gauge"}}, "Args.Valid(): invalid metrics latency"},
+		{"invalid api debug-rounds", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, API: API{DebugRounds: -1}}, "Args.Valid(): invalid api debug-rounds"},
+		{"invalid clock-skew", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, ClockSkew: ClockSkew{OnSkew: "skip"}}, "Args.Valid(): invalid clock-skew"},
+		{"invalid verification", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Verification: Verification{Exporters: []string{"noop"}}}, "Args.Valid(): invalid verification"},
+		{"invalid version-check", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, VersionCheck: "fail"}, "Args.Valid(): version-check must be"},
+		{"exporter processors", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporters: []NameConfigPair{{Name: "a", Processors: []NameConfigPair{{Name: "b", When: "block.round > 5"}}}}}, "Args.Valid(): processor (b) of exporter (a) cannot have a when condition"},
+		{"processor processors", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Processors: []NameConfigPair{{Name: "a", Processors: []NameConfigPair{{Name: "b"}}}}}, "Args.Valid(): plugin (a) cannot have processors, only exporters are supported"},
+		{"processor output-schema", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Processors: []NameConfigPair{{Name: "a", OutputSchema: &OutputSchema{File: "schema.json"}}}}, "Args.Valid(): plugin (a) cannot have an output-schema, only exporters are supported"},
+		{"invalid output-schema", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporter: NameConfigPair{Name: "a", OutputSchema: &OutputSchema{}}}, "Args.Valid(): exporter (a) output-schema was invalid: file is required"},
+		{"invalid priority-lanes", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporter: NameConfigPair{Name: "a"}, PriorityLanes: PriorityLanes{{Name: "l", When: "txn.type == 'pay'", Exporters: []string{"b"}}}}, "Args.Valid(): invalid priority-lanes: lane (l) exporter (b) is not configured"},
+		{"builtin sandbox", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Processors: []NameConfigPair{{Name: "a", Sandbox: &external.Sandbox{Dir: "/tmp"}}}}, "Args.Valid(): plugin (a) cannot have a sandbox, only external plugins are supported"},
+		{"invalid sandbox", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporter: NameConfigPair{Name: "a", Executable: "/bin/exporter", Sandbox: &external.Sandbox{Umask: "9"}}}, "Args.Valid(): plugin (a) sandbox was invalid: umask (9) must be an octal mode up to 777"},
+		{"exporter processor sandbox", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporters: []NameConfigPair{{Name: "a", Processors: []NameConfigPair{{Name: "b", Executable: "/bin/processor", Sandbox: &external.Sandbox{User: "nobody"}}}}}}, ""},
+		{"invalid bandwidth", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Bandwidth: Bandwidth{CostPerGB: -1}}, "Args.Valid(): invalid bandwidth: cost-per-gb must not be negative (-1)"},
+		{"multiple exporters", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporters: []NameConfigPair{{Name: "a"}, {Name: "b", BestEffort: true}}}, ""},
+		{"exporter and exporters", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporter: NameConfigPair{Name: "a"}, Exporters: []NameConfigPair{{Name: "b"}}}, "Args.Valid(): exporter and exporters cannot both be configured"},
+		{"duplicate exporters", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporters: []NameConfigPair{{Name: "a", Alias: "b"}, {Name: "b"}}}, "Args.Valid(): exporter (b) was configured more than once, give the exporters different aliases"},
+		{"duplicate exporter names", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporters: []NameConfigPair{{Name: "a"}, {Name: "a"}, {Name: "a", Alias: "archive"}}}, ""},
+		{"duplicate aliases", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporters: []NameConfigPair{{Name: "a", Alias: "archive"}, {Name: "b", Alias: "archive"}}}, "Args.Valid(): exporter (archive) was configured more than once, give the exporters different aliases"},
+		{"alias of another exporter ID", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporters: []NameConfigPair{{Name: "a"}, {Name: "a"}, {Name: "b", Alias: "a_1"}}}, "Args.Valid(): exporter (a_1) was configured more than once, give the exporters different aliases"},
+		{"alias of another exporter name", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporters: []NameConfigPair{{Name: "a", Alias: "a"}, {Name: "a"}}}, "Args.Valid(): exporter (a) was configured more than once, give the exporters different aliases"},
+		{"invalid alias", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporters: []NameConfigPair{{Name: "a", Alias: "../a"}}}, "Args.Valid(): exporter (a) alias (../a) was invalid"},
+		{"processor alias", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Processors: []NameConfigPair{{Name: "a", Alias: "b"}}}, "Args.Valid(): plugin (a) cannot have an alias, only exporters are supported"},
+		{"invalid exporter log level", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporters: []NameConfigPair{{Name: "a", LogLevel: "asdf"}}}, "Args.Valid(): plugin (a) log level (asdf) was invalid:"},
+		{"exporter when", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Processors: []NameConfigPair{{Name: "test", When: "txn.type == 'appl'"}}, Exporter: NameConfigPair{Name: "test", When: "block.round > 5"}}, ""},
+		{"invalid when", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Exporter: NameConfigPair{Name: "test", When: "txn.type == 1"}}, "Args.Valid(): plugin (test) when condition was invalid:"},
+		{"importer when", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Importer: NameConfigPair{Name: "test", When: "block.round > 5"}}, "Args.Valid(): importer (test) cannot have a when condition"},
+		{"best-effort processor", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Processors: []NameConfigPair{{Name: "test", BestEffort: true}}}, "Args.Valid(): plugin (test) cannot be best-effort"},
+		{"observers", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Observers: []NameConfigPair{{Name: "a", LogLevel: "debug"}, {Name: "b"}}}, ""},
+		{"duplicate observers", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Observers: []NameConfigPair{{Name: "a"}, {Name: "a"}}}, "Args.Valid(): observer (a) was configured more than once"},
+		{"observer when", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Observers: []NameConfigPair{{Name: "a", When: "block.round > 5"}}}, "Args.Valid(): observer (a) cannot have a when condition"},
+		{"observer retry policy", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Observers: []NameConfigPair{{Name: "a", RetryPolicy: &RetryPolicy{}}}}, "Args.Valid(): observer (a) cannot have a retry policy"},
+		{"external observer", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Observers: []NameConfigPair{{Name: "a", Executable: "/bin/true"}}}, "Args.Valid(): observer (a) cannot be an executable"},
+		{"best-effort observer", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Observers: []NameConfigPair{{Name: "a", BestEffort: true}}}, "Args.Valid(): plugin (a) cannot be best-effort"},
+		{"rounds", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Rounds: Rounds{Start: 10, End: 10, Workers: 4}}, ""},
+		{"invalid rounds", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, Rounds: Rounds{Start: 11, End: 10}}, "Args.Valid(): invalid rounds: start (11) must not be after end (10)"},
+		{"text log format", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, LogFormat: "text"}, ""},
+		{"invalid log format", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, LogFormat: "xml"}, "Args.Valid(): log format (xml) was invalid, expected json or text"},
+		{"reuse block data", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, ReuseBlockData: true}, ""},
+		{"reuse block data with batch", Config{ConduitArgs: &conduit.Args{ConduitDataDir: ""}, ReuseBlockData: true, Batch: Batch{Size: 10}}, "Args.Valid(): batch cannot be used with reuse-block-data"},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -69,6 +118,44 @@ func TestPipelineConfigValidity(t *testing.T) {
 	}
 }
 
+// TestPipelineConfigPluginSchema tests that plugin configs are checked against
+// the registered config schemas.
+func TestPipelineConfigPluginSchema(t *testing.T) {
+	type schemaConfig struct {
+		Setting int `yaml:"setting"`
+	}
+	plugins.RegisterConfigSchema(plugins.Exporter, "schema_exporter", schemaConfig{})
+	plugins.RegisterConfigSchema(plugins.Processor, "schema_processor", schemaConfig{})
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		errContains string
+	}{
+		{"valid", Config{Processors: []NameConfigPair{{Name: "schema_processor", Config: map[string]interface{}{"setting": 1}}}}, ""},
+		{"unregistered", Config{Exporter: NameConfigPair{Name: "test", Config: map[string]interface{}{"settings": 1}}}, ""},
+		{"external", Config{Exporter: NameConfigPair{Name: "schema_exporter", Executable: "/bin/exporter", Config: map[string]interface{}{"settings": 1}}}, ""},
+		{"processor", Config{Processors: []NameConfigPair{{Name: "test"}, {Name: "schema_processor", Config: map[string]interface{}{"settings": 1}}}},
+			"Args.Valid(): plugin (schema_processor) config was invalid: processors[1].config.settings: unknown key"},
+		{"exporter", Config{Exporter: NameConfigPair{Name: "schema_exporter", Config: map[string]interface{}{"settings": 1}}},
+			"Args.Valid(): plugin (schema_exporter) config was invalid: exporter.config.settings: unknown key"},
+		{"exporters", Config{Exporters: []NameConfigPair{{Name: "test"}, {Name: "schema_exporter", Config: map[string]interface{}{"settings": 1}}}},
+			"Args.Valid(): plugin (schema_exporter) config was invalid: exporters[1].config.settings: unknown key"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cfg.ConduitArgs = &conduit.Args{}
+			err := tc.cfg.Valid()
+			if tc.errContains == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errContains)
+		})
+	}
+}
+
 // TestMakePipelineConfigError tests that making the pipeline configuration with unknown fields causes an error
 func TestMakePipelineConfigErrors(t *testing.T) {
 	tests := []struct {
@@ -91,7 +178,7 @@ exporter:
   name: "noop"
   config:
     connectionstring: ""`, "field processor not found"},
-		{"exporter not exporters", `---
+		{"exporters is a list", `---
 log-level: info
 importer:
   name: "algod"
@@ -105,7 +192,7 @@ processors:
 exporters:
   name: "noop"
   config:
-    connectionstring: ""`, "field exporters not found"},
+    connectionstring: ""`, "cannot unmarshal !!map into []pipeline.NameConfigPair"},
 
 		{"config not configs", `---
 log-level: info
@@ -160,8 +247,7 @@ processors:
       catchpoint: "7560000#3OUX3TLXZNOK6YJXGETKRRV2MHMILF5CCIVZUOJCT6SLY5H2WWTQ"
 exporter:
   name: "noop"
-  config:
-    connectionstring: ""`
+  config: {}`
 
 	err = os.WriteFile(filepath.Join(dataDir, conduit.DefaultConfigName), []byte(validConfigFile), 0777)
 	assert.Nil(t, err)
@@ -169,7 +255,7 @@ exporter:
 	cfg := &conduit.Args{ConduitDataDir: dataDir}
 
 	pCfg, err := MakePipelineConfig(cfg)
-	assert.Nil(t, err)
+	require.NoError(t, err)
 	assert.Equal(t, pCfg.PipelineLogLevel, "info")
 	assert.Equal(t, pCfg.Valid(), nil)
 	assert.Equal(t, pCfg.Importer.Name, "algod")
@@ -177,7 +263,7 @@ exporter:
 	assert.Equal(t, pCfg.Processors[0].Name, "noop")
 	assert.Equal(t, pCfg.Processors[0].Config["catchpoint"], "7560000#3OUX3TLXZNOK6YJXGETKRRV2MHMILF5CCIVZUOJCT6SLY5H2WWTQ")
 	assert.Equal(t, pCfg.Exporter.Name, "noop")
-	assert.Equal(t, pCfg.Exporter.Config["connectionstring"], "")
+	assert.Empty(t, pCfg.Exporter.Config)
 
 	// invalidDataDir has no auto load file
 	invalidDataDir := t.TempDir()
@@ -368,7 +454,7 @@ func TestPipelineRun(t *testing.T) {
 		initProvider:     nil,
 		importer:         &pImporter,
 		processors:       []*processors.Processor{&pProcessor},
-		exporter:         &pExporter,
+		exporters:        []*exporters.Exporter{&pExporter},
 		completeCallback: []conduit.OnCompleteFunc{cbComplete.OnComplete},
 		pipelineMetadata: state{
 			NextRound:   0,
@@ -398,6 +484,88 @@ func TestPipelineRun(t *testing.T) {
 
 }
 
+// TestPipelineExporterFanOut tests that each exporter receives every round exactly once,
+// even when another exporter needs to retry, and that best-effort exporters skip failed rounds.
+func TestPipelineExporterFanOut(t *testing.T) {
+	var pImporter importers.Importer = &roundImporter{}
+	first := &roundExporter{name: "first"}
+	retried := &roundExporter{name: "retried", failRound: 2, failCount: 2}
+	bestEffort := &roundExporter{name: "best-effort", failRound: 3, failCount: math.MaxInt}
+	var pFirst exporters.Exporter = first
+	var pRetried exporters.Exporter = retried
+	var pBestEffort exporters.Exporter = bestEffort
+
+	ctx, cf := context.WithCancel(context.Background())
+	l, _ := test.NewNullLogger()
+	pImpl := pipelineImpl{
+		ctx:        ctx,
+		cf:         cf,
+		logger:     l,
+		importer:   &pImporter,
+		processors: []*processors.Processor{},
+		exporters:  []*exporters.Exporter{&pFirst, &pRetried, &pBestEffort},
+		cfg: &Config{
+			RetryDelay: 0,
+			RetryCount: math.MaxUint64,
+			ConduitArgs: &conduit.Args{
+				ConduitDataDir: t.TempDir(),
+			},
+			Exporters: []NameConfigPair{{Name: "first"}, {Name: "retried"}, {Name: "best-effort", BestEffort: true}},
+		},
+	}
+
+	pImpl.Start()
+	require.Eventually(t, func() bool {
+		return len(bestEffort.received()) >= 5
+	}, 5*time.Second, time.Millisecond)
+	cf()
+	pImpl.Wait()
+
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5}, first.received()[:6])
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5}, retried.received()[:6])
+	assert.Equal(t, []uint64{0, 1, 2, 4, 5}, bestEffort.received()[:5])
+}
+
+// TestPipelineConditions tests that exporters only receive the blocks matching their when condition.
+func TestPipelineConditions(t *testing.T) {
+	var pImporter importers.Importer = &roundImporter{}
+	all := &roundExporter{name: "all"}
+	some := &roundExporter{name: "some"}
+	var pAll exporters.Exporter = all
+	var pSome exporters.Exporter = some
+
+	cond, err := compileCondition("block.round >= 3 && block.round != 4")
+	require.NoError(t, err)
+
+	ctx, cf := context.WithCancel(context.Background())
+	l, _ := test.NewNullLogger()
+	pImpl := pipelineImpl{
+		ctx:                ctx,
+		cf:                 cf,
+		logger:             l,
+		importer:           &pImporter,
+		processors:         []*processors.Processor{},
+		exporters:          []*exporters.Exporter{&pAll, &pSome},
+		exporterConditions: []*condition{nil, cond},
+		cfg: &Config{
+			RetryCount: math.MaxUint64,
+			ConduitArgs: &conduit.Args{
+				ConduitDataDir: t.TempDir(),
+			},
+		},
+	}
+
+	pImpl.Start()
+	require.Eventually(t, func() bool {
+		return len(some.received()) >= 3
+	}, 5*time.Second, time.Millisecond)
+	cf()
+	pImpl.Wait()
+
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5, 6}, all.received()[:7])
+	assert.Equal(t, []uint64{3, 5, 6}, some.received()[:3])
+}
+
 // TestPipelineCpuPidFiles tests that cpu and pid files are created when specified
 func TestPipelineCpuPidFiles(t *testing.T) {
 
@@ -434,7 +602,7 @@ func TestPipelineCpuPidFiles(t *testing.T) {
 		initProvider: nil,
 		importer:     &pImporter,
 		processors:   []*processors.Processor{&pProcessor},
-		exporter:     &pExporter,
+		exporters:    []*exporters.Exporter{&pExporter},
 		pipelineMetadata: state{
 			GenesisHash: "",
 			Network:     "",
@@ -503,7 +671,7 @@ func TestPipelineErrors(t *testing.T) {
 		initProvider:     nil,
 		importer:         &pImporter,
 		processors:       []*processors.Processor{&pProcessor},
-		exporter:         &pExporter,
+		exporters:        []*exporters.Exporter{&pExporter},
 		completeCallback: []conduit.OnCompleteFunc{cbComplete.OnComplete},
 		pipelineMetadata: state{},
 	}
@@ -572,7 +740,7 @@ func Test_pipelineImpl_registerLifecycleCallbacks(t *testing.T) {
 		initProvider: nil,
 		importer:     &pImporter,
 		processors:   []*processors.Processor{&pProcessor, &pProcessor},
-		exporter:     &pExporter,
+		exporters:    []*exporters.Exporter{&pExporter},
 	}
 
 	// Each plugin implements the Completed interface, so there should be 4
@@ -618,7 +786,7 @@ func TestPluginConfigDataDir(t *testing.T) {
 		initProvider: nil,
 		importer:     &pImporter,
 		processors:   []*processors.Processor{&pProcessor},
-		exporter:     &pExporter,
+		exporters:    []*exporters.Exporter{&pExporter},
 		pipelineMetadata: state{
 			NextRound: 3,
 		},
@@ -635,6 +803,47 @@ func TestPluginConfigDataDir(t *testing.T) {
 	assert.DirExists(t, mExporter.cfg.DataDir)
 }
 
+func TestExporterIDs(t *testing.T) {
+	cfg := Config{Exporters: []NameConfigPair{
+		{Name: "file_writer"},
+		{Name: "postgresql"},
+		{Name: "file_writer"},
+		{Name: "postgresql", Alias: "archive"},
+	}}
+	assert.Equal(t, []string{"file_writer_0", "postgresql", "file_writer_2", "archive"}, cfg.exporterIDs())
+
+	cfg = Config{Exporter: NameConfigPair{Name: "noop"}}
+	assert.Equal(t, []string{"noop"}, cfg.exporterIDs())
+}
+
+// TestDuplicateExporterDataDir tests that exporters with the same name have
+// their own data directory.
+func TestDuplicateExporterDataDir(t *testing.T) {
+	first, second := mockExporter{}, mockExporter{}
+	var pImporter importers.Importer = &mockImporter{}
+	var pFirst exporters.Exporter = &first
+	var pSecond exporters.Exporter = &second
+
+	datadir := t.TempDir()
+	l, _ := test.NewNullLogger()
+	pImpl := pipelineImpl{
+		cfg: &Config{
+			ConduitArgs: &conduit.Args{ConduitDataDir: datadir},
+			Importer:    NameConfigPair{Name: "mockImporter"},
+			Exporters:   []NameConfigPair{{Name: "mockExporter"}, {Name: "mockExporter"}},
+		},
+		logger:           l,
+		importer:         &pImporter,
+		exporters:        []*exporters.Exporter{&pFirst, &pSecond},
+		pipelineMetadata: state{NextRound: 3},
+	}
+
+	require.NoError(t, pImpl.Init())
+	assert.Equal(t, path.Join(datadir, "exporter_mockExporter_0"), first.cfg.DataDir)
+	assert.Equal(t, path.Join(datadir, "exporter_mockExporter_1"), second.cfg.DataDir)
+	assert.Equal(t, []string{"mockExporter_0", "mockExporter_1"}, pImpl.names.exporters)
+}
+
 // TestBlockMetaDataFile tests that metadata.json file is created as expected
 func TestBlockMetaDataFile(t *testing.T) {
 
@@ -668,7 +877,7 @@ func TestBlockMetaDataFile(t *testing.T) {
 		initProvider: nil,
 		importer:     &pImporter,
 		processors:   []*processors.Processor{&pProcessor},
-		exporter:     &pExporter,
+		exporters:    []*exporters.Exporter{&pExporter},
 		pipelineMetadata: state{
 			NextRound: 3,
 		},
@@ -692,7 +901,7 @@ func TestBlockMetaDataFile(t *testing.T) {
 	// Test that file encodes correctly
 	pImpl.pipelineMetadata.GenesisHash = "HASH"
 	pImpl.pipelineMetadata.NextRound = 7
-	err = pImpl.encodeMetadataToFile()
+	err = pImpl.saveMetadata()
 	assert.NoError(t, err)
 	metaData, err = pImpl.initializeOrLoadBlockMetadata()
 	assert.NoError(t, err)
@@ -704,8 +913,8 @@ func TestBlockMetaDataFile(t *testing.T) {
 	pImpl.cfg.ConduitArgs.ConduitDataDir = "datadir"
 	metaData, err = pImpl.initializeOrLoadBlockMetadata()
 	assert.Contains(t, err.Error(), "Init(): error creating file")
-	err = pImpl.encodeMetadataToFile()
-	assert.Contains(t, err.Error(), "encodeMetadataToFile(): failed to create temp metadata file")
+	err = pImpl.saveMetadata()
+	assert.Contains(t, err.Error(), "saveMetadata(): Save(): failed to write temp metadata")
 }
 
 func TestGenesisHash(t *testing.T) {
@@ -738,7 +947,7 @@ func TestGenesisHash(t *testing.T) {
 		initProvider: nil,
 		importer:     &pImporter,
 		processors:   []*processors.Processor{&pProcessor},
-		exporter:     &pExporter,
+		exporters:    []*exporters.Exporter{&pExporter},
 		pipelineMetadata: state{
 			GenesisHash: "",
 			Network:     "",
@@ -765,6 +974,184 @@ func TestGenesisHash(t *testing.T) {
 	assert.Contains(t, err.Error(), "genesis hash in metadata does not match")
 }
 
+// networkIDImporter is an importer which declares its network ID.
+type networkIDImporter struct {
+	mockImporter
+	networkID string
+}
+
+func (m *networkIDImporter) NetworkID(genesis *sdk.Genesis) (string, error) {
+	return m.networkID + "-" + genesis.Network, nil
+}
+
+// TestNetworkID tests that the network ID declared by the importer is saved
+// and checked instead of the genesis hash.
+func TestNetworkID(t *testing.T) {
+	datadir := t.TempDir()
+	l, _ := test.NewNullLogger()
+	makePipeline := func(importer importers.Importer) *pipelineImpl {
+		var pProcessor processors.Processor = &mockProcessor{}
+		var pExporter exporters.Exporter = &mockExporter{}
+		return &pipelineImpl{
+			cfg: &Config{
+				ConduitArgs: &conduit.Args{ConduitDataDir: datadir},
+				Importer:    NameConfigPair{Config: map[string]interface{}{}},
+				Processors:  []NameConfigPair{{Config: map[string]interface{}{}}},
+				Exporter:    NameConfigPair{Config: map[string]interface{}{}},
+			},
+			logger:     l,
+			importer:   &importer,
+			processors: []*processors.Processor{&pProcessor},
+			exporters:  []*exporters.Exporter{&pExporter},
+		}
+	}
+
+	var pImporter importers.Importer = &networkIDImporter{mockImporter: mockImporter{genesis: sdk.Genesis{Network: "fork"}}, networkID: "avm"}
+	pImpl := makePipeline(pImporter)
+	require.NoError(t, pImpl.Init())
+	md, err := pImpl.initializeOrLoadBlockMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, "avm-fork", md.NetworkID)
+	assert.Equal(t, "", md.GenesisHash)
+	assert.Equal(t, "fork", md.Network)
+
+	// an importer of another network is rejected.
+	pImporter = &networkIDImporter{mockImporter: mockImporter{genesis: sdk.Genesis{Network: "fork"}}, networkID: "other"}
+	err = makePipeline(pImporter).Init()
+	assert.EqualError(t, err, "Pipeline.Start(): network ID in metadata does not match expected value: actual other-fork, expected avm-fork")
+
+	// an Algorand importer is rejected too.
+	pImporter = &mockImporter{genesis: sdk.Genesis{Network: "fork"}}
+	err = makePipeline(pImporter).Init()
+	assert.ErrorContains(t, err, "Pipeline.Start(): genesis hash in metadata does not match expected value")
+}
+
+// TestNetworkIDLegacyMetadata tests that metadata saved without a network ID
+// is checked with its genesis hash, and the network ID is added.
+func TestNetworkIDLegacyMetadata(t *testing.T) {
+	datadir := t.TempDir()
+	genesis := sdk.Genesis{Network: "test"}
+	gh := genesis.Hash()
+	ghbase64 := base64.StdEncoding.EncodeToString(gh[:])
+	legacy := fmt.Sprintf(`{"genesis-hash": "%s", "network": "test", "next-round": 5}`, ghbase64)
+	require.NoError(t, os.WriteFile(path.Join(datadir, "metadata.json"), []byte(legacy), 0644))
+
+	l, _ := test.NewNullLogger()
+	var pImporter importers.Importer = &mockImporter{genesis: genesis}
+	var pProcessor processors.Processor = &mockProcessor{}
+	var pExporter exporters.Exporter = &mockExporter{}
+	pImpl := pipelineImpl{
+		cfg: &Config{
+			ConduitArgs: &conduit.Args{ConduitDataDir: datadir},
+			Importer:    NameConfigPair{Config: map[string]interface{}{}},
+			Processors:  []NameConfigPair{{Config: map[string]interface{}{}}},
+			Exporter:    NameConfigPair{Config: map[string]interface{}{}},
+		},
+		logger:     l,
+		importer:   &pImporter,
+		processors: []*processors.Processor{&pProcessor},
+		exporters:  []*exporters.Exporter{&pExporter},
+	}
+	require.NoError(t, pImpl.Init())
+	assert.Equal(t, ghbase64, pImpl.pipelineMetadata.NetworkID)
+	assert.Equal(t, ghbase64, pImpl.pipelineMetadata.GenesisHash)
+	assert.Equal(t, uint64(5), pImpl.pipelineMetadata.NextRound)
+}
+
+// roundProviderExporter is an exporter which provides the next round.
+type roundProviderExporter struct {
+	mockExporter
+	nextRound uint64
+	err       error
+}
+
+func (m *roundProviderExporter) NextRound() (uint64, error) {
+	return m.nextRound, m.err
+}
+
+// initRoundProcessor records the round it was initialized at.
+type initRoundProcessor struct {
+	mockProcessor
+	round sdk.Round
+}
+
+func (m *initRoundProcessor) Init(_ context.Context, initProvider data.InitProvider, _ plugins.PluginConfig, _ *log.Logger) error {
+	m.round = initProvider.NextDBRound()
+	return nil
+}
+
+func TestPipelineRoundProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		providers   []uint64
+		providerErr error
+		override    uint64
+		expected    uint64
+		errMsg      string
+	}{
+		{name: "no provider", expected: 3},
+		{name: "same round", providers: []uint64{3}, expected: 3},
+		{name: "adopt round", providers: []uint64{10}, expected: 10},
+		{name: "providers agree", providers: []uint64{10, 10}, expected: 10},
+		{name: "providers disagree", providers: []uint64{10, 11}, errMsg: "exporters (mockExporter_1) and (mockExporter_2) provided different next rounds 10 and 11"},
+		{name: "provider error", providers: []uint64{10}, providerErr: fmt.Errorf("no database"), errMsg: "exporter (mockExporter_1) could not provide the next round: no database"},
+		{name: "override matches", providers: []uint64{10}, override: 10, expected: 10},
+		{name: "override conflict", providers: []uint64{10}, override: 5, errMsg: "next round override 5 does not match exporter (mockExporter_1) next round 10"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var pImporter importers.Importer = &mockImporter{genesis: sdk.Genesis{Network: "test"}}
+			proc := &initRoundProcessor{}
+			var pProcessor processors.Processor = proc
+			var pExporter exporters.Exporter = &mockExporter{}
+			exps := []*exporters.Exporter{&pExporter}
+			exporterCfgs := []NameConfigPair{{Name: "mockExporter"}}
+			for _, rnd := range tc.providers {
+				var exp exporters.Exporter = &roundProviderExporter{nextRound: rnd, err: tc.providerErr}
+				exps = append(exps, &exp)
+				exporterCfgs = append(exporterCfgs, NameConfigPair{Name: "mockExporter"})
+			}
+			datadir := t.TempDir()
+			l, _ := test.NewNullLogger()
+			pImpl := pipelineImpl{
+				cfg: &Config{
+					ConduitArgs: &conduit.Args{
+						ConduitDataDir:    datadir,
+						NextRoundOverride: tc.override,
+					},
+					Processors: []NameConfigPair{{}},
+					Exporters:  exporterCfgs,
+				},
+				logger:           l,
+				importer:         &pImporter,
+				processors:       []*processors.Processor{&pProcessor},
+				exporters:        exps,
+				pipelineMetadata: state{NextRound: 3},
+			}
+
+			err := pImpl.Init()
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, pImpl.pipelineMetadata.NextRound)
+			assert.Equal(t, sdk.Round(tc.expected), proc.round)
+
+			if tc.override > 0 {
+				// an override is saved once the first round completes
+				return
+			}
+			// the adopted round is saved
+			metadata, err := pImpl.initializeOrLoadBlockMetadata()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, metadata.NextRound)
+		})
+	}
+}
+
 func TestPipelineMetricsConfigs(t *testing.T) {
 	var pImporter importers.Importer = &mockImporter{}
 	var pProcessor processors.Processor = &mockProcessor{}
@@ -796,7 +1183,7 @@ func TestPipelineMetricsConfigs(t *testing.T) {
 		initProvider: nil,
 		importer:     &pImporter,
 		processors:   []*processors.Processor{&pProcessor},
-		exporter:     &pExporter,
+		exporters:    []*exporters.Exporter{&pExporter},
 		cf:           cf,
 		ctx:          ctx,
 		pipelineMetadata: state{
@@ -874,7 +1261,7 @@ func TestRoundOverwrite(t *testing.T) {
 		initProvider: nil,
 		importer:     &pImporter,
 		processors:   []*processors.Processor{&pProcessor},
-		exporter:     &pExporter,
+		exporters:    []*exporters.Exporter{&pExporter},
 		pipelineMetadata: state{
 			GenesisHash: "",
 			Network:     "",
@@ -979,7 +1366,7 @@ func TestPipelineRetryVariables(t *testing.T) {
 				initProvider: nil,
 				importer:     &pImporter,
 				processors:   []*processors.Processor{&pProcessor},
-				exporter:     &pExporter,
+				exporters:    []*exporters.Exporter{&pExporter},
 				pipelineMetadata: state{
 					GenesisHash: "",
 					Network:     "",
@@ -1029,9 +1416,234 @@ func TestMetricPrefixApplied(t *testing.T) {
 		logger:       l,
 		initProvider: nil,
 		importer:     &pImporter,
-		exporter:     &pExporter,
+		exporters:    []*exporters.Exporter{&pExporter},
 	}
 
 	pImpl.registerPluginMetricsCallbacks()
 	assert.Equal(t, prefix, mImporter.subsystem)
 }
+
+// TestPipelineInfoHashes tests that the pipeline fingerprints track the composition.
+func TestPipelineInfoHashes(t *testing.T) {
+	cfg := Config{
+		Importer:   NameConfigPair{Name: "algod", Config: map[string]interface{}{"a": "a"}},
+		Processors: []NameConfigPair{{Name: "noop"}, {Name: "filter_processor"}},
+		Exporter:   NameConfigPair{Name: "file_writer", Config: nil},
+	}
+	reordered := cfg
+	reordered.Processors = []NameConfigPair{{Name: "filter_processor"}, {Name: "noop"}}
+
+	assert.Len(t, cfg.processorsHash(), 16)
+	assert.Equal(t, cfg.processorsHash(), cfg.processorsHash())
+	assert.NotEqual(t, cfg.processorsHash(), reordered.processorsHash())
+
+	hash, err := cfg.configHash()
+	assert.NoError(t, err)
+	assert.Len(t, hash, 16)
+}
+
+// blockingExporter blocks in Receive until released.
+type blockingExporter struct {
+	exporters.Exporter
+	entered  chan struct{}
+	release  chan struct{}
+	received chan uint64
+}
+
+func (b *blockingExporter) Metadata() conduit.Metadata {
+	return conduit.Metadata{Name: "blockingExporter"}
+}
+
+func (b *blockingExporter) Close() error {
+	return nil
+}
+
+func (b *blockingExporter) Receive(exportData data.BlockData) error {
+	select {
+	case b.entered <- struct{}{}:
+	default:
+	}
+	<-b.release
+	select {
+	case b.received <- exportData.Round():
+	default:
+	}
+	return nil
+}
+
+func makeBlockingPipeline(t *testing.T, grace time.Duration) (*pipelineImpl, *blockingExporter, *mockImporter) {
+	mImporter := &mockImporter{}
+	mImporter.On("GetBlock", mock.Anything).Return(uniqueBlockData, nil)
+	bExporter := &blockingExporter{
+		entered:  make(chan struct{}, 1),
+		release:  make(chan struct{}),
+		received: make(chan uint64, 10),
+	}
+	var pImporter importers.Importer = mImporter
+	var pExporter exporters.Exporter = bExporter
+
+	ctx, cf := context.WithCancel(context.Background())
+	l, _ := test.NewNullLogger()
+	return &pipelineImpl{
+		ctx:        ctx,
+		cf:         cf,
+		stopCh:     make(chan struct{}),
+		logger:     l,
+		importer:   &pImporter,
+		processors: []*processors.Processor{},
+		exporters:  []*exporters.Exporter{&pExporter},
+		cfg: &Config{
+			RetryCount:          10,
+			ShutdownGracePeriod: grace,
+			ConduitArgs: &conduit.Args{
+				ConduitDataDir: t.TempDir(),
+			},
+		},
+	}, bExporter, mImporter
+}
+
+// TestPipelineStopFinishesRound tests that Stop waits for the in-flight round before cancelling.
+func TestPipelineStopFinishesRound(t *testing.T) {
+	pImpl, bExporter, _ := makeBlockingPipeline(t, 5*time.Second)
+
+	pImpl.Start()
+	<-bExporter.entered
+
+	stopped := make(chan struct{})
+	go func() {
+		pImpl.Stop()
+		close(stopped)
+	}()
+
+	// The round is still in flight, the context must not be cancelled yet.
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, pImpl.ctx.Err())
+	close(bExporter.release)
+
+	<-stopped
+	assert.Equal(t, uniqueBlockData.Round(), <-bExporter.received)
+	assert.Len(t, bExporter.received, 0)
+	assert.Equal(t, uint64(1), pImpl.pipelineMetadata.NextRound)
+
+	// Stop is idempotent.
+	pImpl.Stop()
+}
+
+// TestPipelineStopGracePeriod tests that Stop cancels the pipeline once the grace period elapses.
+func TestPipelineStopGracePeriod(t *testing.T) {
+	pImpl, bExporter, _ := makeBlockingPipeline(t, 10*time.Millisecond)
+
+	pImpl.Start()
+	<-bExporter.entered
+
+	stopped := make(chan struct{})
+	go func() {
+		pImpl.Stop()
+		close(stopped)
+	}()
+	require.Eventually(t, func() bool { return pImpl.ctx.Err() != nil }, 5*time.Second, time.Millisecond)
+
+	close(bExporter.release)
+	<-stopped
+}
+
+// TestStopOnSignal tests that a signal triggers a graceful stop.
+func TestStopOnSignal(t *testing.T) {
+	pImpl, bExporter, _ := makeBlockingPipeline(t, 5*time.Second)
+
+	cancel := StopOnSignal(pImpl, pImpl.logger, syscall.SIGUSR1)
+	defer cancel()
+
+	pImpl.Start()
+	<-bExporter.entered
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	require.Eventually(t, func() bool {
+		select {
+		case <-pImpl.stopCh:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, time.Millisecond)
+
+	// The in-flight round finishes before the pipeline exits.
+	close(bExporter.release)
+	pImpl.Wait()
+	assert.Equal(t, uniqueBlockData.Round(), <-bExporter.received)
+	assert.Equal(t, uint64(1), pImpl.pipelineMetadata.NextRound)
+}
+
+// TestMakePluginLogger tests that plugin loggers default to the pipeline level unless overridden.
+func TestMakePluginLogger(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	l.SetLevel(log.WarnLevel)
+	pImpl := pipelineImpl{logger: l}
+
+	assert.Equal(t, log.WarnLevel, pImpl.makePluginLogger(plugins.Importer, "algod", "").GetLevel())
+	assert.Equal(t, log.DebugLevel, pImpl.makePluginLogger(plugins.Exporter, "postgresql", "debug").GetLevel())
+	assert.Equal(t, log.WarnLevel, pImpl.makePluginLogger(plugins.Processor, "noop", "asdf").GetLevel())
+
+	pImpl.cfg = &Config{LogFormat: LogFormatText}
+	formatter := pImpl.makePluginLogger(plugins.Exporter, "noop", "").Formatter.(PluginLogFormatter)
+	assert.IsType(t, &log.TextFormatter{}, formatter.Formatter)
+
+	// The importer logs are tagged separately and observers are not tagged.
+	pImpl.roundTag, pImpl.importerRoundTag = &RoundTag{}, &RoundTag{}
+	assert.Same(t, pImpl.roundTag, pImpl.makePluginLogger(plugins.Processor, "noop", "").Formatter.(PluginLogFormatter).Round)
+	assert.Same(t, pImpl.importerRoundTag, pImpl.makePluginLogger(plugins.Importer, "algod", "").Formatter.(PluginLogFormatter).Round)
+	assert.Nil(t, pImpl.makePluginLogger(plugins.Observer, "noop", "").Formatter.(PluginLogFormatter).Round)
+}
+
+// paysetImporter returns blocks with a pooled payset of one transaction, it
+// keeps the paysets to check that they are released.
+type paysetImporter struct {
+	roundImporter
+	paysets [][]sdk.SignedTxnInBlock
+}
+
+func (r *paysetImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	blk, err := r.roundImporter.GetBlock(rnd)
+	blk.Payset = append(data.AcquirePayset(1), sdk.SignedTxnInBlock{})
+	blk.Payset[0].Txn.Note = []byte(fmt.Sprintf("round %d", rnd))
+	r.paysets = append(r.paysets, blk.Payset)
+	return blk, err
+}
+
+// noteExporter records the note of the first transaction of each round.
+type noteExporter struct {
+	roundExporter
+	notes []string
+}
+
+func (e *noteExporter) Receive(exportData data.BlockData) error {
+	e.notes = append(e.notes, string(exportData.Payset[0].Txn.Note))
+	return nil
+}
+
+// TestPipelineReuseBlockData tests that blocks are released after their round
+// completes, and not before the exporters receive them.
+func TestPipelineReuseBlockData(t *testing.T) {
+	for _, reuse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("reuse_%t", reuse), func(t *testing.T) {
+			exp := &noteExporter{roundExporter: roundExporter{name: "notes"}}
+			pImpl := makeReloadPipeline(t, exp)
+			imp := &paysetImporter{roundImporter: roundImporter{failRound: math.MaxUint64}}
+			var pImporter importers.Importer = imp
+			pImpl.importer = &pImporter
+			pImpl.cfg.ReuseBlockData = reuse
+			pImpl.cfg.Rounds.End = 2
+
+			pImpl.Start()
+			pImpl.Wait()
+			require.NoError(t, pImpl.Error())
+
+			assert.Equal(t, []string{"round 0", "round 1", "round 2"}, exp.notes)
+			last := imp.paysets[len(imp.paysets)-1]
+			if reuse {
+				assert.Empty(t, last[0].Txn.Note, "the last block is released")
+			} else {
+				assert.Equal(t, "round 2", string(last[0].Txn.Note))
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// fetchResult is the outcome of a single importer GetBlock call.
+type fetchResult struct {
+	round      uint64
+	blk        data.BlockData
+	start      time.Time
+	importTime time.Duration
+	err        error
+}
+
+// prefetcher runs the importer ahead of the rest of the pipeline. Results are
+// delivered in round order on a bounded channel, so at most `size` rounds are
+// held in memory at any time. When the channel is full the importer blocks
+// until the processors and exporter catch up.
+type prefetcher struct {
+	importer     *importers.Importer
+	importerName string
+	telemetry    *stageTelemetry
+	activity     *activityTracker
+	retryDelay   time.Duration
+	// end is the last round to fetch, 0 means there is no last round.
+	end     uint64
+	results chan fetchResult
+	cf      context.CancelFunc
+	// cancelled is closed by cancel and stop.
+	cancelled <-chan struct{}
+	done      chan struct{}
+}
+
+// fetchJob is a round assigned to a worker. The worker sends its results on
+// out, which the reorder loop reads in round order.
+type fetchJob struct {
+	round uint64
+	out   chan fetchResult
+}
+
+// startPrefetcher launches goroutines fetching rounds beginning at nextRound
+// and ending at end, or indefinitely if end is 0. Up to `workers` rounds are
+// fetched concurrently and reordered before they are delivered. A failed
+// fetch is reported to the consumer and then retried for the same round
+// after retryDelay.
+func startPrefetcher(ctx context.Context, importer *importers.Importer, importerName string, telemetry *stageTelemetry, activity *activityTracker, nextRound, end, size, workers uint64, retryDelay time.Duration) *prefetcher {
+	ctx, cf := context.WithCancel(ctx)
+	if workers == 0 {
+		workers = 1
+	}
+	p := &prefetcher{
+		importer:     importer,
+		importerName: importerName,
+		telemetry:    telemetry,
+		activity:     activity,
+		retryDelay:   retryDelay,
+		end:          end,
+		results:      make(chan fetchResult, size),
+		cf:           cf,
+		cancelled:    ctx.Done(),
+		done:         make(chan struct{}),
+	}
+	go p.run(ctx, nextRound, workers)
+	return p
+}
+
+func (p *prefetcher) run(ctx context.Context, rnd uint64, workers uint64) {
+	var wg sync.WaitGroup
+	defer close(p.done)
+	defer wg.Wait()
+
+	jobs := make(chan fetchJob)
+	// ordered holds the jobs in round order. Together with the job being
+	// reordered there are at most `workers` rounds in flight.
+	ordered := make(chan fetchJob, workers-1)
+	for i := uint64(0); i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx, jobs)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		defer close(ordered)
+		for ; p.end == 0 || rnd <= p.end; rnd++ {
+			job := fetchJob{round: rnd, out: make(chan fetchResult, 1)}
+			select {
+			case <-ctx.Done():
+				return
+			case ordered <- job:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- job:
+			}
+		}
+	}()
+
+	for job := range ordered {
+		for {
+			var result fetchResult
+			select {
+			case <-ctx.Done():
+				return
+			case result = <-job.out:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case p.results <- result:
+			}
+			if result.err == nil {
+				break
+			}
+		}
+	}
+}
+
+// work fetches rounds until the jobs channel is closed. Each round is retried
+// until it succeeds or the context is cancelled.
+func (p *prefetcher) work(ctx context.Context, jobs <-chan fetchJob) {
+	for job := range jobs {
+		for {
+			importStart := time.Now()
+			var blk data.BlockData
+			var err error
+			op := p.activity.start(importerType, p.importerName, job.round)
+			p.telemetry.importer(func() {
+				blk, err = (*p.importer).GetBlock(job.round)
+			})
+			p.activity.done(op)
+			result := fetchResult{
+				round:      job.round,
+				blk:        blk,
+				start:      importStart,
+				importTime: time.Since(importStart),
+				err:        err,
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case job.out <- result:
+			}
+			if err == nil {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.retryDelay):
+			}
+		}
+	}
+}
+
+// next returns the next fetched round, or false if the context was cancelled
+// or a stop was requested.
+func (p *prefetcher) next(ctx context.Context, stop <-chan struct{}) (fetchResult, bool) {
+	select {
+	case <-ctx.Done():
+		return fetchResult{}, false
+	case <-p.cancelled:
+		return fetchResult{}, false
+	case <-stop:
+		return fetchResult{}, false
+	case result := <-p.results:
+		return result, true
+	}
+}
+
+// cancel signals the prefetch goroutines to exit without waiting for it. An
+// in-flight GetBlock call is not interrupted.
+func (p *prefetcher) cancel() {
+	p.cf()
+}
+
+// stop cancels the prefetch goroutines and waits for it to exit.
+func (p *prefetcher) stop() {
+	p.cf()
+	<-p.done
+}
@@ -0,0 +1,210 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// roundImporter returns a block for the requested round, failing once on failRound.
+// If alwaysFail is set every request for failRound fails.
+type roundImporter struct {
+	importers.Importer
+	mu         sync.Mutex
+	calls      int
+	failRound  uint64
+	failed     bool
+	alwaysFail bool
+}
+
+func (r *roundImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if rnd == r.failRound && (!r.failed || r.alwaysFail) {
+		r.failed = true
+		return data.BlockData{}, fmt.Errorf("importer")
+	}
+	return data.BlockData{BlockHeader: sdk.BlockHeader{Round: sdk.Round(rnd)}}, nil
+}
+
+func (r *roundImporter) Close() error {
+	return nil
+}
+
+// roundExporter records the rounds it receives, failing failCount times on failRound.
+type roundExporter struct {
+	exporters.Exporter
+	name      string
+	mu        sync.Mutex
+	rounds    []uint64
+	failRound uint64
+	failCount int
+}
+
+func (r *roundExporter) Metadata() conduit.Metadata {
+	return conduit.Metadata{Name: r.name}
+}
+
+func (r *roundExporter) Receive(exportData data.BlockData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if exportData.Round() == r.failRound && r.failCount > 0 {
+		r.failCount--
+		return fmt.Errorf("receive")
+	}
+	r.rounds = append(r.rounds, exportData.Round())
+	return nil
+}
+
+func (r *roundExporter) Close() error {
+	return nil
+}
+
+func (r *roundExporter) received() []uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]uint64(nil), r.rounds...)
+}
+
+// TestPrefetcherOrderAndRetry tests that the prefetcher delivers rounds in order and retries failures.
+func TestPrefetcherOrderAndRetry(t *testing.T) {
+	var imp importers.Importer = &roundImporter{failRound: 3}
+	p := startPrefetcher(context.Background(), &imp, "", nil, nil, 1, 0, 2, 1, 0)
+	defer p.stop()
+
+	var got []uint64
+	var errs int
+	for len(got) < 5 {
+		result, ok := p.next(context.Background(), nil)
+		require.True(t, ok)
+		if result.err != nil {
+			assert.Equal(t, uint64(3), result.round)
+			errs++
+			continue
+		}
+		got = append(got, result.blk.Round())
+	}
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, got)
+	assert.Equal(t, 1, errs)
+}
+
+// TestPrefetcherBounded tests that the importer does not run further ahead than the buffer allows.
+func TestPrefetcherBounded(t *testing.T) {
+	ri := &roundImporter{}
+	var imp importers.Importer = ri
+	p := startPrefetcher(context.Background(), &imp, "", nil, nil, 0, 0, 3, 1, 0)
+
+	time.Sleep(100 * time.Millisecond)
+	ri.mu.Lock()
+	// 3 results buffered plus one blocked on send.
+	assert.LessOrEqual(t, ri.calls, 4)
+	ri.mu.Unlock()
+
+	p.stop()
+}
+
+// TestPipelineRunPrefetch tests that a prefetching pipeline exports every round in order.
+func TestPipelineRunPrefetch(t *testing.T) {
+	var pImporter importers.Importer = &roundImporter{failRound: 2}
+	exp := &roundExporter{}
+	var pExporter exporters.Exporter = exp
+
+	ctx, cf := context.WithCancel(context.Background())
+	l, _ := test.NewNullLogger()
+	pImpl := pipelineImpl{
+		ctx:          ctx,
+		cf:           cf,
+		logger:       l,
+		initProvider: nil,
+		importer:     &pImporter,
+		processors:   []*processors.Processor{},
+		exporters:    []*exporters.Exporter{&pExporter},
+		cfg: &Config{
+			RetryDelay:     0,
+			RetryCount:     10,
+			PrefetchRounds: 4,
+			ConduitArgs: &conduit.Args{
+				ConduitDataDir: t.TempDir(),
+			},
+		},
+	}
+
+	pImpl.Start()
+	require.Eventually(t, func() bool { return len(exp.received()) >= 10 }, 5*time.Second, 10*time.Millisecond)
+	cf()
+	pImpl.Wait()
+
+	for i, rnd := range exp.received() {
+		assert.Equal(t, uint64(i), rnd)
+	}
+}
+
+// slowImporter takes longer to fetch some rounds so that concurrent fetches
+// complete out of order. It records the maximum number of concurrent calls.
+type slowImporter struct {
+	importers.Importer
+	mu      sync.Mutex
+	active  int
+	maxSeen int
+}
+
+func (s *slowImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	s.mu.Lock()
+	s.active++
+	if s.active > s.maxSeen {
+		s.maxSeen = s.active
+	}
+	s.mu.Unlock()
+
+	time.Sleep(time.Duration(3-rnd%3) * time.Millisecond)
+
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+	return data.BlockData{BlockHeader: sdk.BlockHeader{Round: sdk.Round(rnd)}}, nil
+}
+
+// TestPrefetcherWorkers tests that concurrently fetched rounds are delivered in order and stop at the end round.
+func TestPrefetcherWorkers(t *testing.T) {
+	si := &slowImporter{}
+	var imp importers.Importer = si
+	p := startPrefetcher(context.Background(), &imp, "", nil, nil, 5, 24, 4, 4, 0)
+
+	var got []uint64
+	for len(got) < 20 {
+		result, ok := p.next(context.Background(), nil)
+		require.True(t, ok)
+		require.NoError(t, result.err)
+		got = append(got, result.blk.Round())
+	}
+	for i, rnd := range got {
+		assert.Equal(t, uint64(i+5), rnd)
+	}
+
+	// Nothing is fetched after the end round.
+	select {
+	case result := <-p.results:
+		t.Fatalf("unexpected round %d after the end round", result.round)
+	case <-time.After(50 * time.Millisecond):
+	}
+	p.stop()
+
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	assert.Greater(t, si.maxSeen, 1)
+	assert.LessOrEqual(t, si.maxSeen, 4)
+}
@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// preset is a named set of tuned pipeline settings. A preset is applied
+// before the config file is decoded, so anything in the config file
+// overrides it.
+type preset struct {
+	// apply sets the pipeline settings.
+	apply func(cfg *Config)
+	// pluginOptions are recommended config options by plugin name. They are
+	// only added when the plugin is configured and the option is not set.
+	pluginOptions map[string]map[string]interface{}
+}
+
+var presets = map[string]preset{
+	// archive favors throughput and completeness, for example for a full
+	// history backfill into files.
+	"archive": {
+		apply: func(cfg *Config) {
+			cfg.PrefetchRounds = 64
+			cfg.Rounds.Workers = 8
+			cfg.RetryCount = 100
+			cfg.RetryDelay = 5 * time.Second
+			cfg.OnFailure = onFailureHalt
+			cfg.ShutdownGracePeriod = 30 * time.Second
+		},
+		pluginOptions: map[string]map[string]interface{}{
+			"file_writer": {
+				"rounds-per-file": 1000,
+			},
+		},
+	},
+	// realtime-alerts favors latency, rounds are processed as soon as they
+	// are available and a failed round does not hold back the next one.
+	"realtime-alerts": {
+		apply: func(cfg *Config) {
+			cfg.PrefetchRounds = 0
+			cfg.Rounds.Workers = 0
+			cfg.RetryCount = 3
+			cfg.RetryDelay = 100 * time.Millisecond
+			cfg.OnFailure = onFailureDeadLetter
+			cfg.ShutdownGracePeriod = 2 * time.Second
+		},
+	},
+	// analytics balances throughput with keeping up with the network.
+	"analytics": {
+		apply: func(cfg *Config) {
+			cfg.PrefetchRounds = 16
+			cfg.Rounds.Workers = 4
+			cfg.RetryCount = 20
+			cfg.RetryDelay = 1 * time.Second
+			cfg.OnFailure = onFailureDeadLetter
+			cfg.ShutdownGracePeriod = 10 * time.Second
+		},
+		pluginOptions: map[string]map[string]interface{}{
+			"file_writer": {
+				"drop-certificate": true,
+			},
+			"postgresql": {
+				"max-conn": 40,
+			},
+		},
+	},
+}
+
+// presetNames returns the names of the built-in presets.
+func presetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func validPreset(name string) error {
+	if _, ok := presets[name]; name != "" && !ok {
+		return fmt.Errorf("preset must be one of %s, found '%s'", strings.Join(presetNames(), ", "), name)
+	}
+	return nil
+}
+
+// applyPreset sets the pipeline settings of the named preset.
+func (cfg *Config) applyPreset(name string) error {
+	if err := validPreset(name); err != nil {
+		return err
+	}
+	if name == "" {
+		return nil
+	}
+	presets[name].apply(cfg)
+	return nil
+}
+
+// applyPresetPluginOptions adds the recommended plugin options of the
+// configured preset to the plugins which do not set them.
+func (cfg *Config) applyPresetPluginOptions() {
+	p, ok := presets[cfg.Preset]
+	if !ok {
+		return
+	}
+	addOptions := func(pair *NameConfigPair) {
+		options, ok := p.pluginOptions[pair.Name]
+		if !ok || pair.Executable != "" {
+			return
+		}
+		if pair.Config == nil {
+			pair.Config = make(map[string]interface{})
+		}
+		for key, value := range options {
+			if _, ok := pair.Config[key]; !ok {
+				pair.Config[key] = value
+			}
+		}
+	}
+	addOptions(&cfg.Importer)
+	for i := range cfg.Processors {
+		addOptions(&cfg.Processors[i])
+	}
+	addOptions(&cfg.Exporter)
+	for i := range cfg.Exporters {
+		addOptions(&cfg.Exporters[i])
+	}
+}
@@ -0,0 +1,88 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+func TestValidPreset(t *testing.T) {
+	for _, name := range []string{"", "archive", "realtime-alerts", "analytics"} {
+		assert.NoError(t, validPreset(name))
+	}
+	assert.EqualError(t, validPreset("fast"), "preset must be one of analytics, archive, realtime-alerts, found 'fast'")
+}
+
+func TestApplyPreset(t *testing.T) {
+	for name := range presets {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			cfg := Config{ConduitArgs: &conduit.Args{}, Preset: name}
+			require.NoError(t, cfg.applyPreset(name))
+			assert.NoError(t, cfg.Valid())
+		})
+	}
+
+	var cfg Config
+	assert.Error(t, cfg.applyPreset("fast"))
+	require.NoError(t, cfg.applyPreset(""))
+	assert.Equal(t, Config{}, cfg)
+}
+
+func TestApplyPresetPluginOptions(t *testing.T) {
+	cfg := Config{
+		Preset:   "analytics",
+		Importer: NameConfigPair{Name: "algod"},
+		Exporters: []NameConfigPair{
+			{Name: "file_writer"},
+			{Name: "postgresql", Config: map[string]interface{}{"max-conn": 5}},
+		},
+	}
+	cfg.applyPresetPluginOptions()
+
+	assert.Nil(t, cfg.Importer.Config)
+	assert.Equal(t, map[string]interface{}{"drop-certificate": true}, cfg.Exporters[0].Config)
+	// options in the config file take precedence
+	assert.Equal(t, map[string]interface{}{"max-conn": 5}, cfg.Exporters[1].Config)
+
+	// plugins run as an executable are left alone
+	cfg = Config{Preset: "archive", Exporter: NameConfigPair{Name: "file_writer", Executable: "/bin/file_writer"}}
+	cfg.applyPresetPluginOptions()
+	assert.Nil(t, cfg.Exporter.Config)
+}
+
+func TestMakePipelineConfigPreset(t *testing.T) {
+	dataDir := t.TempDir()
+	configFile := `---
+preset: archive
+retry-count: 5
+importer:
+  name: "algod"
+exporter:
+  name: "file_writer"
+  config:
+    drop-certificate: true`
+	err := os.WriteFile(filepath.Join(dataDir, conduit.DefaultConfigName), []byte(configFile), 0777)
+	require.NoError(t, err)
+
+	pCfg, err := MakePipelineConfig(&conduit.Args{ConduitDataDir: dataDir})
+	require.NoError(t, err)
+	assert.Equal(t, "archive", pCfg.Preset)
+	assert.Equal(t, uint64(64), pCfg.PrefetchRounds)
+	assert.Equal(t, 5*time.Second, pCfg.RetryDelay)
+	// overridden by the config file
+	assert.Equal(t, uint64(5), pCfg.RetryCount)
+	assert.Equal(t, 1000, pCfg.Exporter.Config["rounds-per-file"])
+	assert.Equal(t, true, pCfg.Exporter.Config["drop-certificate"])
+
+	err = os.WriteFile(filepath.Join(dataDir, conduit.DefaultConfigName), []byte("preset: fast"), 0777)
+	require.NoError(t, err)
+	_, err = MakePipelineConfig(&conduit.Args{ConduitDataDir: dataDir})
+	assert.ErrorContains(t, err, "preset must be one of analytics, archive, realtime-alerts, found 'fast'")
+}
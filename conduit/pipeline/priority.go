@@ -0,0 +1,169 @@
+package pipeline
+
+import (
+	"fmt"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+// PriorityLane delivers the transactions which match a condition, such as
+// the ones which trigger alerts, to its exporters ahead of the other
+// exporters and without batching.
+type PriorityLane struct {
+	Name string `yaml:"name"`
+	// When selects the transactions of the lane, with the syntax of the
+	// plugin when conditions.
+	When string `yaml:"when"`
+	// Exporters are the IDs of the exporters which receive the lane. They
+	// only receive the matching transactions.
+	Exporters []string `yaml:"exporters"`
+}
+
+// PriorityLanes are the priority lanes of the pipeline.
+type PriorityLanes []PriorityLane
+
+// Valid validates the priority lanes against the configured exporters.
+func (lanes PriorityLanes) Valid(exporterCfgs []NameConfigPair, exporterIDs []string) error {
+	exporterWhen := make(map[string]string, len(exporterCfgs))
+	for idx, id := range exporterIDs {
+		exporterWhen[id] = exporterCfgs[idx].When
+	}
+	names := make(map[string]bool)
+	laneOf := make(map[string]string)
+	for _, lane := range lanes {
+		if lane.Name == "" {
+			return fmt.Errorf("name is required")
+		}
+		if names[lane.Name] {
+			return fmt.Errorf("lane (%s) was configured more than once", lane.Name)
+		}
+		names[lane.Name] = true
+		if lane.When == "" {
+			return fmt.Errorf("lane (%s) when is required", lane.Name)
+		}
+		if _, err := compileCondition(lane.When); err != nil {
+			return fmt.Errorf("lane (%s) when condition was invalid: %w", lane.Name, err)
+		}
+		if len(lane.Exporters) == 0 {
+			return fmt.Errorf("lane (%s) has no exporters", lane.Name)
+		}
+		for _, name := range lane.Exporters {
+			when, ok := exporterWhen[name]
+			if !ok {
+				return fmt.Errorf("lane (%s) exporter (%s) is not configured", lane.Name, name)
+			}
+			if other, ok := laneOf[name]; ok {
+				return fmt.Errorf("exporter (%s) is in lanes (%s) and (%s), it can only be in one lane", name, other, lane.Name)
+			}
+			if when != "" {
+				return fmt.Errorf("lane (%s) exporter (%s) cannot have a when condition, the lane condition applies", lane.Name, name)
+			}
+			laneOf[name] = lane.Name
+		}
+	}
+	return nil
+}
+
+// priorityLane is a compiled PriorityLane.
+type priorityLane struct {
+	name string
+	cond *condition
+}
+
+// makePriorityLanes compiles the lanes, by exporter index. The entries of the
+// exporters which are not in a lane are nil, the result is nil if there are
+// no lanes.
+func makePriorityLanes(cfg *Config) ([]*priorityLane, error) {
+	if len(cfg.PriorityLanes) == 0 {
+		return nil, nil
+	}
+	exporterIDs := cfg.exporterIDs()
+	lanes := make([]*priorityLane, len(exporterIDs))
+	for _, laneCfg := range cfg.PriorityLanes {
+		cond, err := compileCondition(laneCfg.When)
+		if err != nil {
+			return nil, fmt.Errorf("makePriorityLanes(): lane (%s): %w", laneCfg.Name, err)
+		}
+		lane := &priorityLane{name: laneCfg.Name, cond: cond}
+		for _, name := range laneCfg.Exporters {
+			for idx, id := range exporterIDs {
+				if id == name {
+					lanes[idx] = lane
+				}
+			}
+		}
+	}
+	return lanes, nil
+}
+
+// filter returns a copy of the block with the transactions of the lane, it
+// returns false if none match.
+func (l *priorityLane) filter(blk data.BlockData) (data.BlockData, bool, error) {
+	var payset []sdk.SignedTxnInBlock
+	if !l.cond.usesTxn {
+		match, err := l.cond.match(&blk)
+		if err != nil || !match {
+			return blk, false, err
+		}
+		payset = blk.Payset
+	} else {
+		for i := range blk.Payset {
+			match, err := l.cond.root.eval(conditionEnv{block: &blk, txn: &blk.Payset[i].SignedTxnWithAD})
+			if err != nil {
+				return blk, false, err
+			}
+			if match {
+				payset = append(payset, blk.Payset[i])
+			}
+		}
+	}
+	if len(payset) == 0 {
+		return blk, false, nil
+	}
+	blk.Payset = payset
+	return blk, true, nil
+}
+
+// exportOrder returns the exporter indexes in the order they receive a round:
+// the exporters of priority lanes first, then the others.
+func (p *pipelineImpl) exportOrder() []int {
+	order := make([]int, 0, len(p.exporters))
+	for idx := range p.exporters {
+		if p.isPriority(idx) {
+			order = append(order, idx)
+		}
+	}
+	for idx := range p.exporters {
+		if !p.isPriority(idx) {
+			order = append(order, idx)
+		}
+	}
+	return order
+}
+
+// laneBlock returns the block for the exporter at idx. An exporter of a
+// priority lane only receives the matching transactions, and does not receive
+// the round when none match.
+func (p *pipelineImpl) laneBlock(idx int, blk data.BlockData) (data.BlockData, bool, error) {
+	if !p.isPriority(idx) {
+		return blk, true, nil
+	}
+	lane := p.lanes[idx]
+	laneBlk, ok, err := lane.filter(blk)
+	if err != nil {
+		return blk, false, fmt.Errorf("priority lane (%s): %w", lane.name, err)
+	}
+	if ok {
+		metrics.PriorityLaneTxns.WithLabelValues(lane.name).Add(float64(len(laneBlk.Payset)))
+	}
+	return laneBlk, ok, nil
+}
+
+// isPriority reports whether the exporter at idx is in a priority lane, it
+// receives each round as it is processed instead of in batches.
+func (p *pipelineImpl) isPriority(idx int) bool {
+	return idx < len(p.lanes) && p.lanes[idx] != nil
+}
@@ -0,0 +1,107 @@
+package pipeline
+
+import (
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+func TestPriorityLanesValid(t *testing.T) {
+	exporterCfgs := []NameConfigPair{{Name: "postgresql"}, {Name: "alerts"}, {Name: "pager"}, {Name: "filtered", When: "block.round > 5"}}
+	tests := []struct {
+		name  string
+		lanes PriorityLanes
+		err   string
+	}{
+		{name: "valid", lanes: PriorityLanes{{Name: "whales", When: "txn.type == 'pay' && txn.amt > 1000", Exporters: []string{"alerts", "pager"}}}},
+		{name: "no name", lanes: PriorityLanes{{When: "txn.type == 'pay'", Exporters: []string{"alerts"}}}, err: "name is required"},
+		{name: "duplicate", lanes: PriorityLanes{{Name: "a", When: "txn.type == 'pay'", Exporters: []string{"alerts"}}, {Name: "a", When: "txn.type == 'pay'", Exporters: []string{"pager"}}}, err: "lane (a) was configured more than once"},
+		{name: "no when", lanes: PriorityLanes{{Name: "a", Exporters: []string{"alerts"}}}, err: "lane (a) when is required"},
+		{name: "invalid when", lanes: PriorityLanes{{Name: "a", When: "txn.type ==", Exporters: []string{"alerts"}}}, err: "lane (a) when condition was invalid:"},
+		{name: "no exporters", lanes: PriorityLanes{{Name: "a", When: "txn.type == 'pay'"}}, err: "lane (a) has no exporters"},
+		{name: "unknown exporter", lanes: PriorityLanes{{Name: "a", When: "txn.type == 'pay'", Exporters: []string{"chat"}}}, err: "lane (a) exporter (chat) is not configured"},
+		{name: "two lanes", lanes: PriorityLanes{{Name: "a", When: "txn.type == 'pay'", Exporters: []string{"alerts"}}, {Name: "b", When: "txn.type == 'appl'", Exporters: []string{"alerts"}}}, err: "exporter (alerts) is in lanes (a) and (b), it can only be in one lane"},
+		{name: "exporter when", lanes: PriorityLanes{{Name: "a", When: "txn.type == 'pay'", Exporters: []string{"filtered"}}}, err: "lane (a) exporter (filtered) cannot have a when condition, the lane condition applies"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.lanes.Valid(exporterCfgs, (&Config{Exporters: exporterCfgs}).exporterIDs())
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
+
+func TestPriorityLaneFilter(t *testing.T) {
+	cond, err := compileCondition("txn.type == 'pay'")
+	require.NoError(t, err)
+	lane := &priorityLane{name: "payments", cond: cond}
+	blk := data.BlockData{
+		BlockHeader: sdk.BlockHeader{Round: 10},
+		Payset: []sdk.SignedTxnInBlock{
+			{SignedTxnWithAD: sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{Type: sdk.ApplicationCallTx}}}},
+			{SignedTxnWithAD: sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{Type: sdk.PaymentTx}}}},
+		},
+	}
+	laneBlk, ok, err := lane.filter(blk)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, sdk.Round(10), laneBlk.BlockHeader.Round)
+	require.Len(t, laneBlk.Payset, 1)
+	assert.Equal(t, sdk.PaymentTx, laneBlk.Payset[0].Txn.Type)
+	// the block of the other exporters is unchanged.
+	assert.Len(t, blk.Payset, 2)
+
+	blk.Payset = blk.Payset[:1]
+	_, ok, err = lane.filter(blk)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+// txnImporter adds a payment to the even rounds.
+type txnImporter struct {
+	roundImporter
+}
+
+func (r *txnImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	blk, err := r.roundImporter.GetBlock(rnd)
+	if err == nil && rnd%2 == 0 {
+		blk.Payset = []sdk.SignedTxnInBlock{{SignedTxnWithAD: sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{Type: sdk.PaymentTx}}}}}
+	}
+	return blk, err
+}
+
+// TestPipelinePriorityLane tests that the lane exporter receives the matching
+// rounds first and without batching, while the batch exporter is unaffected.
+func TestPipelinePriorityLane(t *testing.T) {
+	batchExp := &batchExporter{roundExporter: roundExporter{name: "batch"}}
+	alerts := &batchExporter{roundExporter: roundExporter{name: "alerts"}}
+	pImpl := makeReloadPipeline(t, batchExp, alerts)
+	var pImporter importers.Importer = &txnImporter{}
+	pImpl.importer = &pImporter
+	pImpl.cfg.Batch = Batch{Size: 4}
+	pImpl.cfg.Rounds.End = 7
+	pImpl.cfg.PriorityLanes = PriorityLanes{{Name: "payments", When: "txn.type == 'pay'", Exporters: []string{"alerts"}}}
+	require.NoError(t, pImpl.cfg.PriorityLanes.Valid(pImpl.cfg.exporterConfigs(), pImpl.cfg.exporterIDs()))
+	var err error
+	pImpl.lanes, err = makePriorityLanes(pImpl.cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 0}, pImpl.exportOrder())
+
+	pImpl.Start()
+	pImpl.Wait()
+	require.NoError(t, pImpl.Error())
+
+	assert.Equal(t, [][]uint64{{0, 1, 2, 3}, {4, 5, 6, 7}}, batchExp.received())
+	assert.Empty(t, alerts.received())
+	assert.Equal(t, []uint64{0, 2, 4, 6}, alerts.roundExporter.received())
+}
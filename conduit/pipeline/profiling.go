@@ -0,0 +1,348 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	httppprof "net/http/pprof"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// profilingSnapshots writes the profiles to the data directory.
+	profilingSnapshots = "snapshots"
+	// profilingPush sends the profiles to a Pyroscope compatible server.
+	profilingPush = "push"
+
+	defaultSnapshotInterval = 10 * time.Minute
+	defaultPushInterval     = 15 * time.Second
+	defaultCPUDuration      = 10 * time.Second
+	defaultProfilingRetain  = 24
+	defaultProfilingDir     = "profiles"
+	defaultProfilingApp     = "conduit"
+
+	// mutexProfileFraction and blockProfileRate are set when the mutex and
+	// block profiles are captured.
+	mutexProfileFraction = 100
+	blockProfileRate     = 10000
+)
+
+// profileTypes are the profiles which can be captured, cpu is sampled for
+// cpu-duration and the others are read from runtime/pprof.
+var profileTypes = []string{"cpu", "heap", "allocs", "goroutine", "mutex", "block"}
+
+var defaultProfiles = []string{"cpu", "heap", "goroutine"}
+
+// Profiling periodically captures profiles, so that performance regressions
+// of a long-running pipeline can be investigated after the fact.
+type Profiling struct {
+	// Mode is "snapshots", which writes the profiles to dir, or "push", which
+	// sends them to url. Profiles are not captured when it is empty.
+	Mode string `yaml:"mode"`
+	// Interval is the time between captures, 10m for snapshots and 15s for
+	// push by default.
+	Interval time.Duration `yaml:"interval"`
+	// CPUDuration is how long the cpu profile is sampled, 10s by default. It
+	// must be shorter than the interval.
+	CPUDuration time.Duration `yaml:"cpu-duration"`
+	// Profiles are the captured profiles, by default cpu, heap and goroutine.
+	Profiles []string `yaml:"profiles"`
+	// Dir is the snapshots directory, relative to the data directory.
+	Dir string `yaml:"dir"`
+	// Retain is the number of snapshots kept of each profile, 24 by default.
+	Retain int `yaml:"retain"`
+	// URL is the address of the Pyroscope compatible server for push.
+	URL string `yaml:"url"`
+	// AppName is the application name of the pushed profiles.
+	AppName string `yaml:"app-name"`
+	// Labels are added to the pushed profiles, along with goos and goarch.
+	Labels map[string]string `yaml:"labels"`
+	// ServePprof serves the net/http/pprof endpoints under /debug/pprof/ on
+	// the API and metrics addresses, for servers which pull profiles.
+	ServePprof bool `yaml:"serve-pprof"`
+}
+
+// Valid validates the profiling config.
+func (pr Profiling) Valid() error {
+	switch pr.Mode {
+	case "", profilingSnapshots:
+	case profilingPush:
+		if pr.URL == "" {
+			return fmt.Errorf("url is required with mode '%s'", profilingPush)
+		}
+		if _, err := url.ParseRequestURI(pr.URL); err != nil {
+			return fmt.Errorf("invalid url: %w", err)
+		}
+	default:
+		return fmt.Errorf("mode must be '%s' or '%s', found '%s'", profilingSnapshots, profilingPush, pr.Mode)
+	}
+	if pr.Interval < 0 || pr.CPUDuration < 0 || pr.Retain < 0 {
+		return fmt.Errorf("interval, cpu-duration and retain must not be negative")
+	}
+	if pr.Mode != "" && pr.captures("cpu") && pr.cpuDuration() >= pr.interval() {
+		return fmt.Errorf("cpu-duration (%s) must be shorter than the interval (%s)", pr.cpuDuration(), pr.interval())
+	}
+	for _, name := range pr.Profiles {
+		known := false
+		for _, typ := range profileTypes {
+			known = known || name == typ
+		}
+		if !known {
+			return fmt.Errorf("unknown profile '%s', the profiles are %s", name, strings.Join(profileTypes, ", "))
+		}
+	}
+	return nil
+}
+
+func (pr Profiling) interval() time.Duration {
+	switch {
+	case pr.Interval > 0:
+		return pr.Interval
+	case pr.Mode == profilingPush:
+		return defaultPushInterval
+	default:
+		return defaultSnapshotInterval
+	}
+}
+
+func (pr Profiling) cpuDuration() time.Duration {
+	if pr.CPUDuration > 0 {
+		return pr.CPUDuration
+	}
+	return defaultCPUDuration
+}
+
+func (pr Profiling) profiles() []string {
+	if len(pr.Profiles) > 0 {
+		return pr.Profiles
+	}
+	return defaultProfiles
+}
+
+// captures reports whether the profile is captured.
+func (pr Profiling) captures(name string) bool {
+	for _, profile := range pr.profiles() {
+		if profile == name {
+			return true
+		}
+	}
+	return false
+}
+
+// profiler captures the profiles every interval.
+type profiler struct {
+	cfg    Profiling
+	dir    string
+	logger *logrus.Logger
+	client *http.Client
+}
+
+// startProfiling starts capturing profiles until the pipeline context is
+// cancelled, profilerDone is closed once the last capture is done.
+func (p *pipelineImpl) startProfiling() error {
+	cfg := p.cfg.Profiling
+	if cfg.Mode == "" {
+		return nil
+	}
+	pr := &profiler{cfg: cfg, logger: p.logger, client: &http.Client{Timeout: 30 * time.Second}}
+	if cfg.Mode == profilingSnapshots {
+		dir := cfg.Dir
+		if dir == "" {
+			dir = defaultProfilingDir
+		}
+		if !filepath.IsAbs(dir) && p.cfg.ConduitArgs != nil {
+			dir = filepath.Join(p.cfg.ConduitArgs.ConduitDataDir, dir)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("startProfiling(): %w", err)
+		}
+		pr.dir = dir
+		p.logger.Infof("Writing %s profiles to %s every %s", strings.Join(cfg.profiles(), ", "), dir, cfg.interval())
+	} else {
+		p.logger.Infof("Pushing %s profiles to %s every %s", strings.Join(cfg.profiles(), ", "), cfg.URL, cfg.interval())
+	}
+	if cfg.captures("mutex") {
+		runtime.SetMutexProfileFraction(mutexProfileFraction)
+	}
+	if cfg.captures("block") {
+		runtime.SetBlockProfileRate(blockProfileRate)
+	}
+	done := make(chan struct{})
+	p.profilerDone = done
+	go func() {
+		defer close(done)
+		pr.run(p.ctx)
+	}()
+	return nil
+}
+
+func (pr *profiler) run(ctx context.Context) {
+	ticker := time.NewTicker(pr.cfg.interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pr.capture(ctx)
+		}
+	}
+}
+
+// capture captures each profile, errors are logged so that a failing capture
+// does not affect the pipeline.
+func (pr *profiler) capture(ctx context.Context) {
+	for _, name := range pr.cfg.profiles() {
+		start := time.Now()
+		var buf bytes.Buffer
+		if err := pr.profile(ctx, name, &buf); err != nil {
+			pr.logger.Warnf("could not capture the %s profile: %v", name, err)
+			continue
+		}
+		var err error
+		if pr.cfg.Mode == profilingPush {
+			err = pr.push(ctx, name, start, time.Now(), buf.Bytes())
+		} else {
+			err = pr.writeSnapshot(name, start, buf.Bytes())
+		}
+		if err != nil {
+			pr.logger.Warnf("could not save the %s profile: %v", name, err)
+		}
+	}
+}
+
+// profile writes the profile in the pprof format. The cpu profile is sampled
+// for cpu-duration, or until ctx is cancelled.
+func (pr *profiler) profile(ctx context.Context, name string, w *bytes.Buffer) error {
+	if name != "cpu" {
+		return pprof.Lookup(name).WriteTo(w, 0)
+	}
+	if err := pprof.StartCPUProfile(w); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(pr.cfg.cpuDuration()):
+	}
+	pprof.StopCPUProfile()
+	return nil
+}
+
+// snapshotName is the file name of a snapshot. Profiles are only symbolized
+// with a binary of the same platform, so the name includes it.
+func snapshotName(name string, start time.Time) string {
+	return fmt.Sprintf("%s-%s-%s-%s.pprof", name, start.UTC().Format("20060102T150405Z"), runtime.GOOS, runtime.GOARCH)
+}
+
+// writeSnapshot writes the profile to the snapshots directory, then removes
+// the oldest snapshots of the profile beyond retain.
+func (pr *profiler) writeSnapshot(name string, start time.Time, profile []byte) error {
+	file := filepath.Join(pr.dir, snapshotName(name, start))
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, profile, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		return err
+	}
+	return pr.rotate(name)
+}
+
+func (pr *profiler) rotate(name string) error {
+	retain := pr.cfg.Retain
+	if retain == 0 {
+		retain = defaultProfilingRetain
+	}
+	entries, err := os.ReadDir(pr.dir)
+	if err != nil {
+		return err
+	}
+	var snapshots []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), name+"-") && strings.HasSuffix(entry.Name(), ".pprof") {
+			snapshots = append(snapshots, entry.Name())
+		}
+	}
+	// The names sort by time.
+	sort.Strings(snapshots)
+	for len(snapshots) > retain {
+		if err := os.Remove(filepath.Join(pr.dir, snapshots[0])); err != nil {
+			return err
+		}
+		snapshots = snapshots[1:]
+	}
+	return nil
+}
+
+// pushName returns the Pyroscope application name of the profile, with its
+// labels in braces.
+func (pr *profiler) pushName(name string) string {
+	app := pr.cfg.AppName
+	if app == "" {
+		app = defaultProfilingApp
+	}
+	labels := map[string]string{"goos": runtime.GOOS, "goarch": runtime.GOARCH}
+	for k, v := range pr.cfg.Labels {
+		labels[k] = v
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + labels[k]
+	}
+	return fmt.Sprintf("%s.%s{%s}", app, name, strings.Join(pairs, ","))
+}
+
+// push sends the profile to the ingest endpoint of the server.
+func (pr *profiler) push(ctx context.Context, name string, start, end time.Time, profile []byte) error {
+	u, err := url.Parse(pr.cfg.URL)
+	if err != nil {
+		return err
+	}
+	u.Path = path.Join(u.Path, "ingest")
+	q := url.Values{}
+	q.Set("name", pr.pushName(name))
+	q.Set("from", fmt.Sprintf("%d", start.Unix()))
+	q.Set("until", fmt.Sprintf("%d", end.Unix()))
+	q.Set("format", "pprof")
+	q.Set("spyName", "gospy")
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(profile))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := pr.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s responded %s", pr.cfg.URL, resp.Status)
+	}
+	return nil
+}
+
+// registerPprofHandlers adds the net/http/pprof endpoints to mux.
+func registerPprofHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", httppprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", httppprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", httppprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", httppprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", httppprof.Trace)
+}
@@ -0,0 +1,152 @@
+package pipeline
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+func TestProfilingValid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Profiling
+		err  string
+	}{
+		{"disabled", Profiling{}, ""},
+		{"snapshots", Profiling{Mode: profilingSnapshots}, ""},
+		{"push", Profiling{Mode: profilingPush, URL: "http://pyroscope:4040"}, ""},
+		{"push without url", Profiling{Mode: profilingPush}, "url is required with mode 'push'"},
+		{"invalid url", Profiling{Mode: profilingPush, URL: "pyroscope"}, `invalid url: parse "pyroscope": invalid URI for request`},
+		{"mode", Profiling{Mode: "pull"}, "mode must be 'snapshots' or 'push', found 'pull'"},
+		{"negative", Profiling{Mode: profilingSnapshots, Retain: -1}, "interval, cpu-duration and retain must not be negative"},
+		{"cpu duration", Profiling{Mode: profilingSnapshots, Interval: 5 * time.Second}, "cpu-duration (10s) must be shorter than the interval (5s)"},
+		{"no cpu", Profiling{Mode: profilingSnapshots, Interval: 5 * time.Second, Profiles: []string{"heap"}}, ""},
+		{"profile", Profiling{Profiles: []string{"heap", "threads"}}, "unknown profile 'threads', the profiles are cpu, heap, allocs, goroutine, mutex, block"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Valid()
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestProfilingDefaults(t *testing.T) {
+	assert.Equal(t, defaultSnapshotInterval, Profiling{Mode: profilingSnapshots}.interval())
+	assert.Equal(t, defaultPushInterval, Profiling{Mode: profilingPush}.interval())
+	assert.Equal(t, time.Minute, Profiling{Interval: time.Minute}.interval())
+	assert.Equal(t, defaultCPUDuration, Profiling{}.cpuDuration())
+	assert.Equal(t, defaultProfiles, Profiling{}.profiles())
+	assert.True(t, Profiling{}.captures("cpu"))
+	assert.False(t, Profiling{Profiles: []string{"heap"}}.captures("cpu"))
+}
+
+func TestProfilingCPUProfileConflict(t *testing.T) {
+	cfg := Config{
+		ConduitArgs: &conduit.Args{ConduitDataDir: t.TempDir()},
+		Importer:    NameConfigPair{Name: "importer"},
+		Exporter:    NameConfigPair{Name: "exporter"},
+		CPUProfile:  "cpu.pprof",
+		Profiling:   Profiling{Mode: profilingSnapshots},
+	}
+	assert.EqualError(t, cfg.Valid(), "Args.Valid(): cpu-profile cannot be used with profiling of the cpu profile")
+	cfg.Profiling.Profiles = []string{"heap"}
+	assert.NoError(t, cfg.Valid())
+}
+
+func TestProfilerSnapshots(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	dir := t.TempDir()
+	pr := &profiler{cfg: Profiling{Mode: profilingSnapshots, Profiles: []string{"heap", "goroutine"}, Retain: 2}, dir: dir, logger: logger}
+
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, pr.writeSnapshot("heap", start.Add(time.Duration(i)*time.Minute), []byte{byte(i)}))
+	}
+	require.NoError(t, pr.writeSnapshot("goroutine", start, []byte{9}))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	platform := runtime.GOOS + "-" + runtime.GOARCH
+	assert.Equal(t, []string{
+		"goroutine-20261016T120000Z-" + platform + ".pprof",
+		"heap-20261016T120100Z-" + platform + ".pprof",
+		"heap-20261016T120200Z-" + platform + ".pprof",
+	}, names)
+
+	// A capture writes a snapshot of each profile.
+	dir = t.TempDir()
+	pr.dir = dir
+	pr.capture(context.Background())
+	assert.Empty(t, hook.AllEntries())
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	info, err := os.Stat(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+	assert.NotZero(t, info.Size())
+}
+
+func TestProfilerPush(t *testing.T) {
+	type ingest struct {
+		path, name, format string
+		size               int
+	}
+	received := make(chan ingest, 1)
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- ingest{path: r.URL.Path, name: r.URL.Query().Get("name"), format: r.URL.Query().Get("format"), size: len(body)}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	logger, _ := test.NewNullLogger()
+	cfg := Profiling{Mode: profilingPush, URL: srv.URL + "/pyroscope", AppName: "indexer-conduit", Labels: map[string]string{"env": "test"}}
+	pr := &profiler{cfg: cfg, logger: logger, client: srv.Client()}
+	now := time.Now()
+	require.NoError(t, pr.push(context.Background(), "heap", now, now, []byte("profile")))
+	got := <-received
+	assert.Equal(t, ingest{
+		path:   "/pyroscope/ingest",
+		name:   "indexer-conduit.heap{env=test,goarch=" + runtime.GOARCH + ",goos=" + runtime.GOOS + "}",
+		format: "pprof",
+		size:   len("profile"),
+	}, got)
+
+	status = http.StatusBadRequest
+	err := pr.push(context.Background(), "heap", now, now, []byte("profile"))
+	<-received
+	assert.EqualError(t, err, srv.URL+"/pyroscope responded 400 Bad Request")
+}
+
+func TestServePprof(t *testing.T) {
+	get := func(p *pipelineImpl) int {
+		mux := http.NewServeMux()
+		p.registerAPIHandlers(mux)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusNotFound, get(&pipelineImpl{cfg: &Config{}}))
+	assert.Equal(t, http.StatusOK, get(&pipelineImpl{cfg: &Config{Profiling: Profiling{ServePprof: true}}}))
+}
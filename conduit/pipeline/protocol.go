@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/algorand/indexer/protocol"
+	"github.com/algorand/indexer/protocol/config"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+const (
+	// unknownProtocolHalt stops the pipeline before the block is processed.
+	unknownProtocolHalt = "halt"
+	// unknownProtocolWarn logs a warning and processes the block.
+	unknownProtocolWarn = "warn"
+	// unknownProtocolSkip records the round in the metadata without
+	// processing or exporting it.
+	unknownProtocolSkip = "skip"
+)
+
+func validUnknownProtocol(policy string) error {
+	switch policy {
+	case "", unknownProtocolHalt, unknownProtocolWarn, unknownProtocolSkip:
+		return nil
+	default:
+		return fmt.Errorf("unknown-protocol must be '%s', '%s' or '%s', found '%s'", unknownProtocolHalt, unknownProtocolWarn, unknownProtocolSkip, policy)
+	}
+}
+
+// unknownProtocolError is returned for a block built with a consensus
+// protocol which this version of conduit does not know.
+type unknownProtocolError struct {
+	round    uint64
+	protocol string
+}
+
+func (e unknownProtocolError) Error() string {
+	return fmt.Sprintf("block %d was built with the unknown consensus protocol %s, conduit may need to be upgraded or the protocol added to known-protocols", e.round, e.protocol)
+}
+
+// knownProtocol reports whether conduit supports the consensus protocol,
+// either built in or added by known-protocols.
+func (cfg *Config) knownProtocol(version string) bool {
+	if _, ok := config.Consensus[protocol.ConsensusVersion(version)]; ok {
+		return true
+	}
+	for _, known := range cfg.KnownProtocols {
+		if known == version {
+			return true
+		}
+	}
+	return false
+}
+
+// checkProtocol returns an unknownProtocolError if the block was built with an
+// unknown protocol. An approved upgrade to an unknown protocol is logged once,
+// before the network switches to it. Blocks without a protocol, such as test
+// fixtures, are not checked.
+func (p *pipelineImpl) checkProtocol(blk *data.BlockData) error {
+	header := &blk.BlockHeader
+	if next := header.NextProtocol; next != "" && !p.cfg.knownProtocol(next) && !p.upgradeWarned[next] {
+		if p.upgradeWarned == nil {
+			p.upgradeWarned = make(map[string]bool)
+		}
+		p.upgradeWarned[next] = true
+		p.logger.WithField("alert", "unknown-protocol").Warnf("The network is upgrading to the unknown consensus protocol %s at round %d, upgrade conduit before then", next, header.NextProtocolSwitchOn)
+	}
+	current := header.CurrentProtocol
+	if current == "" || p.cfg.knownProtocol(current) {
+		return nil
+	}
+	metrics.UnknownProtocolBlocks.WithLabelValues(current).Inc()
+	return unknownProtocolError{round: uint64(header.Round), protocol: current}
+}
+
+// onUnknownProtocol applies the unknown-protocol policy to the current round,
+// it returns true if the pipeline stops. With skip the round is recorded as
+// failed, and dead-lettered in dead-letter mode.
+func (p *pipelineImpl) onUnknownProtocol(err error, encodedBlk []byte) bool {
+	logger := p.logger.WithField("alert", "unknown-protocol")
+	switch p.cfg.UnknownProtocol {
+	case unknownProtocolHalt:
+		logger.Errorf("%v - stopping...", err)
+		p.setError(err)
+		p.writeSupportBundle(err.Error())
+		return true
+	case unknownProtocolSkip:
+		p.setError(err)
+		p.skipFailedRound(encodedBlk, "was built with an unknown consensus protocol")
+	default:
+		logger.Warnf("%v", err)
+	}
+	return false
+}
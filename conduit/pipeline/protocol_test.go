@@ -0,0 +1,108 @@
+package pipeline
+
+import (
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/algorand/indexer/protocol"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+const testUnknownProtocol = "https://example.com/unknown-protocol"
+
+// protocolImporter builds round 2 with an unknown protocol.
+type protocolImporter struct {
+	namedImporter
+}
+
+func (r *protocolImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	blk, err := r.namedImporter.GetBlock(rnd)
+	blk.BlockHeader.CurrentProtocol = string(protocol.ConsensusCurrentVersion)
+	if rnd == 2 {
+		blk.BlockHeader.CurrentProtocol = testUnknownProtocol
+	}
+	return blk, err
+}
+
+func TestValidUnknownProtocol(t *testing.T) {
+	for _, policy := range []string{"", unknownProtocolHalt, unknownProtocolWarn, unknownProtocolSkip} {
+		assert.NoError(t, validUnknownProtocol(policy))
+	}
+	assert.EqualError(t, validUnknownProtocol("retry"), "unknown-protocol must be 'halt', 'warn' or 'skip', found 'retry'")
+}
+
+func TestCheckProtocol(t *testing.T) {
+	metrics.RegisterPrometheusMetrics("protocol_test")
+	logger, hook := test.NewNullLogger()
+	p := &pipelineImpl{cfg: &Config{}, logger: logger}
+	block := func(current, next string) *data.BlockData {
+		return &data.BlockData{BlockHeader: sdk.BlockHeader{Round: 7, UpgradeState: sdk.UpgradeState{
+			CurrentProtocol: current,
+			NextProtocol:    next,
+		}}}
+	}
+
+	assert.NoError(t, p.checkProtocol(block(string(protocol.ConsensusCurrentVersion), "")))
+	assert.NoError(t, p.checkProtocol(block("", "")), "blocks without a protocol are not checked")
+	err := p.checkProtocol(block(testUnknownProtocol, ""))
+	assert.EqualError(t, err, "block 7 was built with the unknown consensus protocol "+testUnknownProtocol+", conduit may need to be upgraded or the protocol added to known-protocols")
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.UnknownProtocolBlocks.WithLabelValues(testUnknownProtocol)))
+
+	p.cfg.KnownProtocols = []string{testUnknownProtocol}
+	assert.NoError(t, p.checkProtocol(block(testUnknownProtocol, "")))
+
+	// An approved upgrade to an unknown protocol is logged once.
+	hook.Reset()
+	assert.NoError(t, p.checkProtocol(block(string(protocol.ConsensusCurrentVersion), "https://example.com/next")))
+	assert.NoError(t, p.checkProtocol(block(string(protocol.ConsensusCurrentVersion), "https://example.com/next")))
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, "unknown-protocol", hook.LastEntry().Data["alert"])
+	assert.Contains(t, hook.LastEntry().Message, "upgrading to the unknown consensus protocol https://example.com/next")
+}
+
+func TestPipelineUnknownProtocol(t *testing.T) {
+	tests := []struct {
+		policy   string
+		received []uint64
+		// next is the round the pipeline stopped at.
+		next   uint64
+		failed []uint64
+	}{
+		{policy: "", received: []uint64{0, 1, 2, 3, 4}, next: 5},
+		{policy: unknownProtocolWarn, received: []uint64{0, 1, 2, 3, 4}, next: 5},
+		{policy: unknownProtocolSkip, received: []uint64{0, 1, 3, 4}, next: 5, failed: []uint64{2}},
+		{policy: unknownProtocolHalt, received: []uint64{0, 1}, next: 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.policy, func(t *testing.T) {
+			exp := &roundExporter{name: "exporter"}
+			pImpl := makeCheckpointPipeline(t, exp)
+			var pImporter importers.Importer = &protocolImporter{namedImporter{roundImporter{failRound: 1000}}}
+			pImpl.importer = &pImporter
+			pImpl.cfg.UnknownProtocol = tc.policy
+			pImpl.cfg.Rounds.End = 4
+
+			pImpl.Start()
+			pImpl.Wait()
+			assert.Equal(t, tc.received, exp.received())
+			md := readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir)
+			assert.Equal(t, tc.next, md.NextRound)
+			var failed []uint64
+			for _, f := range md.FailedRounds {
+				failed = append(failed, f.Round)
+			}
+			assert.Equal(t, tc.failed, failed)
+			if tc.policy == unknownProtocolHalt {
+				var unknown unknownProtocolError
+				assert.ErrorAs(t, pImpl.Error(), &unknown)
+			}
+		})
+	}
+}
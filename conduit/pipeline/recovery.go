@@ -0,0 +1,140 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RecoveryReport describes the state the pipeline resumed from at startup, so
+// that the recovery after a crash can be audited.
+type RecoveryReport struct {
+	Time time.Time `json:"time"`
+	// FreshStart is set when there was no metadata to resume from.
+	FreshStart bool `json:"fresh-start"`
+	// MetadataNextRound is the next round of the saved metadata.
+	MetadataNextRound uint64 `json:"metadata-next-round"`
+	// LastCommittedRound is the last round exported before the restart.
+	LastCommittedRound *uint64 `json:"last-committed-round,omitempty"`
+	// LastExportTime is when the last round was exported, metadata saved by
+	// older versions has none.
+	LastExportTime *time.Time `json:"last-export-time,omitempty"`
+	// SinceLastExport is the time between the last export and the restart.
+	SinceLastExport string `json:"since-last-export,omitempty"`
+	// CommitIntents are the rounds prepared by transactional exporters
+	// which are committed instead of exported again.
+	CommitIntents []uint64 `json:"commit-intents,omitempty"`
+	// FailedRounds is the number of skipped rounds recorded in the metadata.
+	FailedRounds int `json:"failed-rounds"`
+	// DeadLetters is the number of files in the dead letter directory.
+	DeadLetters int `json:"dead-letters"`
+	// ExporterNextRounds are the next rounds of the exporters which implement
+	// conduit.RoundProvider, by exporter name.
+	ExporterNextRounds map[string]uint64 `json:"exporter-next-rounds,omitempty"`
+	// StateMatched is false if an exporter was not at the metadata round.
+	StateMatched bool `json:"state-matched"`
+	// NextRound is the round the pipeline resumes at.
+	NextRound uint64 `json:"next-round"`
+	// Actions are the reconciliation actions which were taken, none if the
+	// pipeline resumed at the metadata round.
+	Actions []string `json:"actions,omitempty"`
+}
+
+// startRecovery starts the recovery report from the loaded metadata.
+func (p *pipelineImpl) startRecovery(now time.Time) {
+	md := p.pipelineMetadata
+	report := &RecoveryReport{
+		Time:              now,
+		FreshStart:        p.metadataCreated,
+		MetadataNextRound: md.NextRound,
+		FailedRounds:      len(md.FailedRounds),
+		StateMatched:      true,
+		NextRound:         md.NextRound,
+	}
+	if !report.FreshStart && md.NextRound > 0 {
+		last := md.NextRound - 1
+		report.LastCommittedRound = &last
+	}
+	if md.LastExportTime != nil {
+		report.LastExportTime = md.LastExportTime
+		report.SinceLastExport = now.Sub(*md.LastExportTime).Round(time.Second).String()
+	}
+	for _, intent := range md.CommitIntents {
+		report.CommitIntents = append(report.CommitIntents, intent.Round)
+	}
+	if entries, err := os.ReadDir(path.Join(p.cfg.ConduitArgs.ConduitDataDir, deadLetterDir)); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				report.DeadLetters++
+			}
+		}
+	}
+	p.recovery = report
+}
+
+// recoveryAction records a reconciliation action in the recovery report.
+func (p *pipelineImpl) recoveryAction(format string, args ...interface{}) {
+	if p.recovery == nil {
+		return
+	}
+	p.recovery.Actions = append(p.recovery.Actions, fmt.Sprintf(format, args...))
+}
+
+// exporterRound records the next round provided by an exporter.
+func (p *pipelineImpl) exporterRound(name string, next uint64) {
+	if p.recovery == nil {
+		return
+	}
+	if p.recovery.ExporterNextRounds == nil {
+		p.recovery.ExporterNextRounds = make(map[string]uint64)
+	}
+	p.recovery.ExporterNextRounds[name] = next
+	if next != p.recovery.MetadataNextRound {
+		p.recovery.StateMatched = false
+	}
+}
+
+// finishRecovery completes the recovery report with the round the pipeline
+// resumes at, and logs it.
+func (p *pipelineImpl) finishRecovery() {
+	report := p.recovery
+	if report == nil {
+		return
+	}
+	report.NextRound = p.pipelineMetadata.NextRound
+	if n := len(report.CommitIntents); n > 0 {
+		p.recoveryAction("%d prepared rounds are committed instead of exported again", n)
+	}
+	p.mu.Lock()
+	p.status.Recovery = report
+	p.mu.Unlock()
+
+	if report.FreshStart {
+		p.logger.Infof("Recovery report: no metadata was found, starting at round %d", report.NextRound)
+		return
+	}
+	details := []string{fmt.Sprintf("metadata next round %d", report.MetadataNextRound)}
+	if report.SinceLastExport != "" {
+		details = append(details, fmt.Sprintf("last export %s before the restart", report.SinceLastExport))
+	}
+	if len(report.ExporterNextRounds) > 0 {
+		names := make([]string, 0, len(report.ExporterNextRounds))
+		for name := range report.ExporterNextRounds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		rounds := make([]string, 0, len(names))
+		for _, name := range names {
+			rounds = append(rounds, fmt.Sprintf("%s at %d", name, report.ExporterNextRounds[name]))
+		}
+		details = append(details, fmt.Sprintf("exporters %s", strings.Join(rounds, ", ")))
+	}
+	details = append(details, fmt.Sprintf("%d commit intents, %d failed rounds, %d dead letters", len(report.CommitIntents), report.FailedRounds, report.DeadLetters))
+	p.logger.Infof("Recovery report: resuming at round %d, %s", report.NextRound, strings.Join(details, ", "))
+	for _, action := range report.Actions {
+		p.logger.Warnf("Recovery action: %s", action)
+	}
+}
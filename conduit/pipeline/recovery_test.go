@@ -0,0 +1,79 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/statestore"
+)
+
+func makeRecoveryPipeline(t *testing.T, dataDir string, exps ...exporters.Exporter) *pipelineImpl {
+	var pImporter importers.Importer = &mockImporter{genesis: sdk.Genesis{Network: "test"}}
+	pImpl := &pipelineImpl{
+		cfg: &Config{
+			ConduitArgs: &conduit.Args{
+				ConduitDataDir: dataDir,
+			},
+		},
+		importer: &pImporter,
+	}
+	pImpl.logger, _ = test.NewNullLogger()
+	for i := range exps {
+		pImpl.exporters = append(pImpl.exporters, &exps[i])
+		pImpl.cfg.Exporters = append(pImpl.cfg.Exporters, NameConfigPair{Name: "mockExporter"})
+	}
+	return pImpl
+}
+
+// TestRecoveryReport tests the report of a fresh start and of a restart where
+// the exporter state does not match the metadata.
+func TestRecoveryReport(t *testing.T) {
+	dataDir := t.TempDir()
+	pImpl := makeRecoveryPipeline(t, dataDir, &mockExporter{})
+	require.NoError(t, pImpl.Init())
+	report := pImpl.Status().Recovery
+	require.NotNil(t, report)
+	assert.True(t, report.FreshStart)
+	assert.Nil(t, report.LastCommittedRound)
+	assert.True(t, report.StateMatched)
+	assert.Empty(t, report.Actions)
+
+	// restart from round 5, with a failed round in the dead letter directory.
+	md := readState(t, dataDir)
+	md.NextRound = 5
+	exported := time.Now().Add(-time.Hour)
+	md.LastExportTime = &exported
+	md.FailedRounds = []failedRound{{Round: 3, File: "3.json"}}
+	b, err := json.Marshal(md)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(statestore.MetadataPath(dataDir), b, 0644))
+	require.NoError(t, os.MkdirAll(path.Join(dataDir, deadLetterDir), 0755))
+	require.NoError(t, os.WriteFile(path.Join(dataDir, deadLetterDir, "3.json"), []byte("{}"), 0644))
+
+	pImpl = makeRecoveryPipeline(t, dataDir, &roundProviderExporter{nextRound: 7})
+	require.NoError(t, pImpl.Init())
+	report = pImpl.Status().Recovery
+	require.NotNil(t, report)
+	assert.False(t, report.FreshStart)
+	assert.Equal(t, uint64(5), report.MetadataNextRound)
+	require.NotNil(t, report.LastCommittedRound)
+	assert.Equal(t, uint64(4), *report.LastCommittedRound)
+	assert.Equal(t, "1h0m0s", report.SinceLastExport)
+	assert.Equal(t, 1, report.FailedRounds)
+	assert.Equal(t, 1, report.DeadLetters)
+	assert.Equal(t, map[string]uint64{"mockExporter": 7}, report.ExporterNextRounds)
+	assert.False(t, report.StateMatched)
+	assert.Equal(t, uint64(7), report.NextRound)
+	assert.Equal(t, []string{"next round 5 was replaced with the next round 7 of exporter (mockExporter)"}, report.Actions)
+}
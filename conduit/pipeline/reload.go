@@ -0,0 +1,316 @@
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+	"github.com/algorand/conduit/conduit/plugins"
+)
+
+// reloadRequest asks the pipeline loop to apply a new config between rounds.
+type reloadRequest struct {
+	cfg    *Config
+	result chan error
+}
+
+// samePlugin reports whether two plugin configs refer to the same plugin, the
+// plugin config itself may differ.
+func samePlugin(a, b NameConfigPair) bool {
+	return a.Name == b.Name && a.Alias == b.Alias && a.Executable == b.Executable && reflect.DeepEqual(a.Args, b.Args) && reflect.DeepEqual(a.Sandbox, b.Sandbox)
+}
+
+func samePlugins(a, b []NameConfigPair) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if !samePlugin(a[idx], b[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameExporterProcessors reports whether the processor sub-chains of the
+// exporters are unchanged, including their config.
+func sameExporterProcessors(a, b []NameConfigPair) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if !reflect.DeepEqual(a[idx].Processors, b[idx].Processors) {
+			return false
+		}
+	}
+	return true
+}
+
+// restartRequired returns an error describing the first change which cannot
+// be applied while conduit is running.
+func (cfg *Config) restartRequired(newCfg *Config) error {
+	checks := []struct {
+		name    string
+		changed bool
+	}{
+		{"importer", !samePlugin(cfg.Importer, newCfg.Importer)},
+		{"processors", !samePlugins(cfg.Processors, newCfg.Processors)},
+		{"exporters", !samePlugins(cfg.exporterConfigs(), newCfg.exporterConfigs())},
+		{"exporter processors", !sameExporterProcessors(cfg.exporterConfigs(), newCfg.exporterConfigs())},
+		{"exporter output-schema", !sameOutputSchemas(cfg.exporterConfigs(), newCfg.exporterConfigs())},
+		{"observers", !reflect.DeepEqual(cfg.Observers, newCfg.Observers)},
+		{"migration", cfg.Migration != newCfg.Migration},
+		{"standby", cfg.Standby != newCfg.Standby},
+		{"watchdog", cfg.Watchdog != newCfg.Watchdog},
+		{"verification", !reflect.DeepEqual(cfg.Verification, newCfg.Verification)},
+		{"version-check", cfg.VersionCheck != newCfg.VersionCheck},
+		{"log-file", cfg.LogFile != newCfg.LogFile},
+		{"log-format", cfg.LogFormat != newCfg.LogFormat},
+		{"cpu-profile", cfg.CPUProfile != newCfg.CPUProfile},
+		{"profiling", !reflect.DeepEqual(cfg.Profiling, newCfg.Profiling)},
+		{"pid-filepath", cfg.PIDFilePath != newCfg.PIDFilePath},
+		{"metrics mode", cfg.Metrics.Mode != newCfg.Metrics.Mode},
+		{"metrics addr", cfg.Metrics.Addr != newCfg.Metrics.Addr},
+		{"metrics latency", cfg.Metrics.Latency != newCfg.Metrics.Latency},
+		{"api addr", cfg.API.Addr != newCfg.API.Addr},
+		{"api debug-rounds", cfg.API.DebugRounds != newCfg.API.DebugRounds},
+		{"telemetry", !reflect.DeepEqual(cfg.Telemetry, newCfg.Telemetry)},
+		{"state-store", !reflect.DeepEqual(cfg.StateStore, newCfg.StateStore)},
+		{"coordination", !reflect.DeepEqual(cfg.Coordination, newCfg.Coordination)},
+		{"prefetch-rounds", cfg.PrefetchRounds != newCfg.PrefetchRounds},
+		{"rounds", cfg.Rounds != newCfg.Rounds},
+		{"signing", cfg.Signing != newCfg.Signing},
+		{"binary-encoding", cfg.BinaryEncoding != newCfg.BinaryEncoding},
+		{"amounts", !reflect.DeepEqual(cfg.Amounts, newCfg.Amounts)},
+		{"reuse-block-data", cfg.ReuseBlockData != newCfg.ReuseBlockData},
+		{"simulation", cfg.Simulation != newCfg.Simulation},
+		{"slo", cfg.SLO != newCfg.SLO},
+		{"config-drift", cfg.ConfigDrift != newCfg.ConfigDrift},
+		{"block-cache", cfg.BlockCache != newCfg.BlockCache},
+		{"priority-lanes", !reflect.DeepEqual(cfg.PriorityLanes, newCfg.PriorityLanes)},
+		{"bandwidth", cfg.Bandwidth != newCfg.Bandwidth},
+	}
+	for _, check := range checks {
+		if check.changed {
+			return fmt.Errorf("%s changed, restart conduit to apply it", check.name)
+		}
+	}
+	return nil
+}
+
+// Reload applies a new config. Settings such as the log level and retry
+// settings are changed between rounds, plugins whose config changed are
+// reconfigured or re-initialized. Changes which require a restart are
+// rejected and the running config is left unchanged. If a plugin cannot be
+// reloaded the pipeline stops with the error.
+func (p *pipelineImpl) Reload(cfg *Config) error {
+	if err := cfg.Valid(); err != nil {
+		return fmt.Errorf("Reload(): %w", err)
+	}
+	p.mu.RLock()
+	err := p.cfg.restartRequired(cfg)
+	if err == nil {
+		err = p.checkThrottle(cfg.Throttle)
+	}
+	loopDone := p.loopDone
+	p.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("Reload(): %w", err)
+	}
+	if loopDone == nil {
+		return fmt.Errorf("Reload(): pipeline is not running")
+	}
+
+	req := reloadRequest{cfg: cfg, result: make(chan error, 1)}
+	select {
+	case p.reloadCh <- req:
+	case <-loopDone:
+		return fmt.Errorf("Reload(): pipeline is not running")
+	}
+	return <-req.result
+}
+
+// setPluginLevel sets the plugin log level, which follows the pipeline level
+// unless the plugin overrides it.
+func setPluginLevel(logger *log.Logger, pipelineLevel log.Level, levelOverride string) {
+	if logger == nil {
+		return
+	}
+	level := pipelineLevel
+	if levelOverride != "" {
+		if override, err := log.ParseLevel(levelOverride); err == nil {
+			level = override
+		}
+	}
+	logger.SetLevel(level)
+}
+
+// reloadPlugin applies a changed plugin config. Plugins which implement
+// conduit.ConfigReloader are updated in place, others are closed and
+// initialized again by reinit.
+func (p *pipelineImpl) reloadPlugin(pluginType, name string, plugin interface{}, config map[string]interface{}, reinit func(plugins.PluginConfig) error) error {
+	configs, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("could not serialize %s (%s) config: %w", pluginType, name, err)
+	}
+	pluginCfg := p.makeConfig(pluginType, name, configs)
+	if v, ok := plugin.(conduit.ConfigReloader); ok {
+		if err = v.OnConfigReload(pluginCfg); err != nil {
+			return fmt.Errorf("%s (%s) could not reload config: %w", pluginType, name, err)
+		}
+		p.logger.Infof("Reloaded %s config: %s", pluginType, name)
+		return nil
+	}
+	if err = reinit(pluginCfg); err != nil {
+		return fmt.Errorf("could not re-initialize %s (%s): %w", pluginType, name, err)
+	}
+	p.logger.Infof("Re-initialized %s: %s", pluginType, name)
+	return nil
+}
+
+// applyReload is called by the pipeline loop when no plugin calls are in
+// flight. Exporters which are re-initialized are sent the current round again.
+func (p *pipelineImpl) applyReload(cfg *Config, exported []bool) error {
+	if cfg.PipelineLogLevel == "" {
+		cfg.PipelineLogLevel = conduit.DefaultLogLevel.String()
+	}
+	level, err := log.ParseLevel(cfg.PipelineLogLevel)
+	if err != nil {
+		return fmt.Errorf("applyReload(): %w", err)
+	}
+	p.logger.SetLevel(level)
+	setPluginLevel(p.importerLogger, level, cfg.Importer.LogLevel)
+	for idx, logger := range p.processorLoggers {
+		setPluginLevel(logger, level, cfg.Processors[idx].LogLevel)
+	}
+	exporterCfgs := cfg.exporterConfigs()
+	for idx, logger := range p.exporterLoggers {
+		setPluginLevel(logger, level, exporterCfgs[idx].LogLevel)
+	}
+	for idx, chain := range p.exporterChains {
+		if chain == nil {
+			continue
+		}
+		for procIdx, logger := range chain.loggers {
+			setPluginLevel(logger, level, exporterCfgs[idx].Processors[procIdx].LogLevel)
+		}
+	}
+
+	if !reflect.DeepEqual(p.cfg.Importer.Config, cfg.Importer.Config) {
+		importer := *p.importer
+		err = p.reloadPlugin("importer", importer.Metadata().Name, importer, cfg.Importer.Config, func(pluginCfg plugins.PluginConfig) error {
+			if err := importer.Close(); err != nil {
+				p.logger.Warnf("Importer (%s) error on close: %v", importer.Metadata().Name, err)
+			}
+			genesis, err := importer.Init(p.ctx, pluginCfg, p.importerLogger)
+			if err != nil {
+				return err
+			}
+			networkID, _, err := networkIdentity(importer, genesis)
+			if err != nil {
+				return err
+			}
+			if networkID != p.pipelineMetadata.NetworkID {
+				return fmt.Errorf("network ID does not match: actual %s, expected %s", networkID, p.pipelineMetadata.NetworkID)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("applyReload(): %w", err)
+		}
+	}
+
+	// Processors and exporters are initialized at the current round.
+	var initProvider data.InitProvider
+	if p.initProvider != nil {
+		round := sdk.Round(p.pipelineMetadata.NextRound)
+		initProvider = conduit.MakePipelineInitProvider(&round, (*p.initProvider).GetGenesis())
+	}
+
+	for idx, proc := range p.processors {
+		if reflect.DeepEqual(p.cfg.Processors[idx].Config, cfg.Processors[idx].Config) {
+			continue
+		}
+		processor := *proc
+		err = p.reloadPlugin("processor", processor.Metadata().Name, processor, cfg.Processors[idx].Config, func(pluginCfg plugins.PluginConfig) error {
+			if err := processor.Close(); err != nil {
+				p.logger.Warnf("Processor (%s) error on close: %v", processor.Metadata().Name, err)
+			}
+			if err := processor.Init(p.ctx, initProvider, pluginCfg, p.processorLoggers[idx]); err != nil {
+				return err
+			}
+			p.setBlockCache(processor)
+			return p.initWindow(proc, fmt.Sprintf("processor_%d_%s", idx, processor.Metadata().Name))
+		})
+		if err != nil {
+			return fmt.Errorf("applyReload(): %w", err)
+		}
+	}
+
+	oldExporterCfgs := p.cfg.exporterConfigs()
+	for idx, exp := range p.exporters {
+		if reflect.DeepEqual(oldExporterCfgs[idx].Config, exporterCfgs[idx].Config) || p.isRetired(idx) {
+			continue
+		}
+		exporter := *exp
+		reinitialized := false
+		err = p.reloadPlugin("exporter", p.exporterName(idx), exporter, exporterCfgs[idx].Config, func(pluginCfg plugins.PluginConfig) error {
+			if err := exporter.Close(); err != nil {
+				p.logger.Warnf("Exporter (%s) error on close: %v", p.exporterName(idx), err)
+			}
+			reinitialized = true
+			if err := exporter.Init(p.ctx, initProvider, pluginCfg, p.exporterLoggers[idx]); err != nil {
+				return err
+			}
+			p.setSigner(exporter)
+			return p.setPayloadChecker(idx, exporterCfgs[idx], exporter)
+		})
+		if err != nil {
+			return fmt.Errorf("applyReload(): %w", err)
+		}
+		if reinitialized {
+			exported[idx] = false
+		}
+	}
+
+	processorConditions := make([]*condition, 0, len(cfg.Processors))
+	for _, pair := range cfg.Processors {
+		cond, err := makeCondition(pair)
+		if err != nil {
+			return fmt.Errorf("applyReload(): %w", err)
+		}
+		processorConditions = append(processorConditions, cond)
+	}
+	exporterConditions := make([]*condition, 0, len(exporterCfgs))
+	for _, pair := range exporterCfgs {
+		cond, err := makeCondition(pair)
+		if err != nil {
+			return fmt.Errorf("applyReload(): %w", err)
+		}
+		exporterConditions = append(exporterConditions, cond)
+	}
+	p.processorConditions = processorConditions
+	p.exporterConditions = exporterConditions
+
+	if cfg.Metrics.Prefix == "" {
+		cfg.Metrics.Prefix = conduit.DefaultMetricsPrefix
+	}
+	if cfg.Metrics.Prefix != p.cfg.Metrics.Prefix {
+		metrics.RegisterPrometheusMetrics(cfg.Metrics.Prefix)
+	}
+
+	p.mu.Lock()
+	p.cfg = cfg
+	p.mu.Unlock()
+	metrics.PipelineInfo.Reset()
+	p.addPipelineInfoMetric()
+	p.logger.Infof("Pipeline configuration reloaded")
+	return nil
+}
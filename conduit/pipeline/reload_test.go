@@ -0,0 +1,247 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+func TestRestartRequired(t *testing.T) {
+	base := Config{
+		Importer:   NameConfigPair{Name: "algod", Config: map[string]interface{}{"netaddr": "a"}},
+		Processors: []NameConfigPair{{Name: "filter_processor"}},
+		Exporter:   NameConfigPair{Name: "postgresql"},
+		RetryCount: 10,
+	}
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		errMsg string
+	}{
+		{"no change", func(cfg *Config) {}, ""},
+		{"safe changes", func(cfg *Config) {
+			cfg.RetryCount = 5
+			cfg.PipelineLogLevel = "debug"
+			cfg.Metrics.Prefix = "other"
+			cfg.Importer.Config = map[string]interface{}{"netaddr": "b"}
+			cfg.Processors[0].When = "block.round > 5"
+		}, ""},
+		{"importer", func(cfg *Config) { cfg.Importer.Name = "file_reader" }, "importer changed"},
+		{"processor added", func(cfg *Config) { cfg.Processors = append(cfg.Processors, NameConfigPair{Name: "noop"}) }, "processors changed"},
+		{"exporter executable", func(cfg *Config) { cfg.Exporter.Executable = "/bin/exporter" }, "exporters changed"},
+		{"exporter list", func(cfg *Config) {
+			cfg.Exporters = []NameConfigPair{cfg.Exporter}
+			cfg.Exporter = NameConfigPair{}
+		}, ""},
+		{"api", func(cfg *Config) { cfg.API.Addr = ":8981" }, "api addr changed"},
+		{"rounds", func(cfg *Config) { cfg.Rounds.End = 100 }, "rounds changed"},
+		{"telemetry", func(cfg *Config) { cfg.Telemetry.SampleRate = 0.5 }, "telemetry changed"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			newCfg := base
+			newCfg.Processors = append([]NameConfigPair(nil), base.Processors...)
+			tc.modify(&newCfg)
+			err := base.restartRequired(&newCfg)
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.errMsg+", restart conduit to apply it")
+		})
+	}
+}
+
+// initExporter records its initializations in addition to the received rounds.
+type initExporter struct {
+	roundExporter
+	inits []uint64
+}
+
+func (e *initExporter) Init(_ context.Context, initProvider data.InitProvider, cfg plugins.PluginConfig, logger *log.Logger) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.inits = append(e.inits, uint64(initProvider.NextDBRound()))
+	return nil
+}
+
+// reloadExporter implements conduit.ConfigReloader.
+type reloadExporter struct {
+	initExporter
+	reloads int
+}
+
+func (e *reloadExporter) OnConfigReload(cfg plugins.PluginConfig) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.reloads++
+	return nil
+}
+
+func makeReloadPipeline(t *testing.T, exps ...exporters.Exporter) *pipelineImpl {
+	var pImporter importers.Importer = &namedImporter{}
+	ctx, cf := context.WithCancel(context.Background())
+	l, _ := test.NewNullLogger()
+	l.SetLevel(log.InfoLevel)
+	rnd := sdk.Round(0)
+	var initProvider data.InitProvider = conduit.MakePipelineInitProvider(&rnd, &sdk.Genesis{})
+
+	pImpl := &pipelineImpl{
+		ctx:          ctx,
+		cf:           cf,
+		logger:       l,
+		stopCh:       make(chan struct{}),
+		reloadCh:     make(chan reloadRequest),
+		checkpointCh: make(chan chan checkpointResult),
+		rollbackCh:   make(chan rollbackRequest),
+		promoteCh:    make(chan chan error),
+		debugCh:      make(chan debugRequest),
+		initProvider: &initProvider,
+		importer:     &pImporter,
+		processors:   []*processors.Processor{},
+		cfg: &Config{
+			PipelineLogLevel: "info",
+			RetryCount:       10,
+			Metrics:          Metrics{Prefix: conduit.DefaultMetricsPrefix},
+			ConduitArgs: &conduit.Args{
+				ConduitDataDir: t.TempDir(),
+			},
+		},
+	}
+	for idx := range exps {
+		exp := exps[idx]
+		pImpl.exporters = append(pImpl.exporters, &exp)
+		pImpl.exporterLoggers = append(pImpl.exporterLoggers, pImpl.makePluginLogger(plugins.Exporter, exp.Metadata().Name, ""))
+		pImpl.cfg.Exporters = append(pImpl.cfg.Exporters, NameConfigPair{Name: exp.Metadata().Name, Config: map[string]interface{}{"setting": 1}})
+	}
+	return pImpl
+}
+
+// reloadedConfig copies the pipeline config with new exporter settings.
+func reloadedConfig(cfg *Config) *Config {
+	newCfg := *cfg
+	newCfg.Exporters = nil
+	for _, pair := range cfg.Exporters {
+		pair.Config = map[string]interface{}{"setting": 2}
+		newCfg.Exporters = append(newCfg.Exporters, pair)
+	}
+	newCfg.RetryCount = 3
+	newCfg.PipelineLogLevel = "debug"
+	return &newCfg
+}
+
+// TestPipelineReload tests that a reload applies safe settings, reconfigures
+// ConfigReloader plugins and re-initializes other plugins at the current round.
+func TestPipelineReload(t *testing.T) {
+	plain := &initExporter{roundExporter: roundExporter{name: "plain"}}
+	reloader := &reloadExporter{initExporter: initExporter{roundExporter: roundExporter{name: "reloader"}}}
+	pImpl := makeReloadPipeline(t, plain, reloader)
+
+	assert.EqualError(t, pImpl.Reload(reloadedConfig(pImpl.cfg)), "Reload(): pipeline is not running")
+
+	pImpl.Start()
+	require.Eventually(t, func() bool { return len(plain.received()) >= 3 }, 5*time.Second, time.Millisecond)
+	require.NoError(t, pImpl.Reload(reloadedConfig(pImpl.cfg)))
+
+	plain.mu.Lock()
+	require.Len(t, plain.inits, 1)
+	reinitRound := plain.inits[0]
+	plain.mu.Unlock()
+	reloader.mu.Lock()
+	assert.Equal(t, 1, reloader.reloads)
+	assert.Empty(t, reloader.inits)
+	reloader.mu.Unlock()
+	assert.Equal(t, log.DebugLevel, pImpl.logger.GetLevel())
+	assert.Equal(t, log.DebugLevel, pImpl.exporterLoggers[0].GetLevel())
+
+	// Changes which require a restart are rejected.
+	restartCfg := reloadedConfig(pImpl.cfg)
+	restartCfg.Exporters = restartCfg.Exporters[:1]
+	assert.EqualError(t, pImpl.Reload(restartCfg), "Reload(): exporters changed, restart conduit to apply it")
+
+	require.Eventually(t, func() bool { return len(plain.received()) >= int(reinitRound)+3 }, 5*time.Second, time.Millisecond)
+	pImpl.cf()
+	pImpl.Wait()
+
+	pImpl.mu.RLock()
+	assert.Equal(t, uint64(3), pImpl.cfg.RetryCount)
+	pImpl.mu.RUnlock()
+	// No rounds were skipped or repeated.
+	for _, exp := range []*roundExporter{&plain.roundExporter, &reloader.roundExporter} {
+		for i, rnd := range exp.received() {
+			assert.Equal(t, uint64(i), rnd)
+		}
+	}
+	assert.EqualError(t, pImpl.Reload(reloadedConfig(pImpl.cfg)), "Reload(): pipeline is not running")
+}
+
+// failingInitExporter fails to initialize again.
+type failingInitExporter struct {
+	roundExporter
+}
+
+func (e *failingInitExporter) Init(context.Context, data.InitProvider, plugins.PluginConfig, *log.Logger) error {
+	return errUnavailable
+}
+
+var errUnavailable = errors.New("unavailable")
+
+// TestPipelineReloadFailure tests that the pipeline stops when a plugin cannot be re-initialized.
+func TestPipelineReloadFailure(t *testing.T) {
+	exp := &failingInitExporter{roundExporter: roundExporter{name: "failing"}}
+	pImpl := makeReloadPipeline(t, exp)
+
+	pImpl.Start()
+	require.Eventually(t, func() bool { return len(exp.received()) >= 1 }, 5*time.Second, time.Millisecond)
+	err := pImpl.Reload(reloadedConfig(pImpl.cfg))
+	assert.EqualError(t, err, "applyReload(): could not re-initialize exporter (failing): unavailable")
+	pImpl.Wait()
+	assert.ErrorIs(t, pImpl.Error(), errUnavailable)
+}
+
+// TestReloadOnSignal tests that a signal reloads the configuration.
+func TestReloadOnSignal(t *testing.T) {
+	plain := &initExporter{roundExporter: roundExporter{name: "plain"}}
+	pImpl := makeReloadPipeline(t, plain)
+
+	var mu sync.Mutex
+	loads := 0
+	cancel := ReloadOnSignal(pImpl, pImpl.logger, func() (*Config, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		loads++
+		return reloadedConfig(pImpl.cfg), nil
+	}, syscall.SIGUSR2)
+	defer cancel()
+
+	pImpl.Start()
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+	require.Eventually(t, func() bool {
+		plain.mu.Lock()
+		defer plain.mu.Unlock()
+		return len(plain.inits) == 1
+	}, 5*time.Second, time.Millisecond)
+	pImpl.cf()
+	pImpl.Wait()
+
+	mu.Lock()
+	assert.Equal(t, 1, loads)
+	mu.Unlock()
+}
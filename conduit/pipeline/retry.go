@@ -0,0 +1,375 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+const (
+	importerStage  = "importer"
+	processorStage = "processors"
+	exporterStage  = "exporters"
+)
+
+// The error classes of the failures, see ErrorClass.
+const (
+	// ErrorClassAuth are credentials which a service rejected, a *conduit.AuthError.
+	ErrorClassAuth = "auth"
+	// ErrorClassTimeout are timeouts, such as a context.DeadlineExceeded or a
+	// network timeout.
+	ErrorClassTimeout = "timeout"
+	// ErrorClassRetryable are other errors marked as a *conduit.RetryableError.
+	ErrorClassRetryable = "retryable"
+	// ErrorClassOther are all other errors.
+	ErrorClassOther = "other"
+)
+
+var errorClasses = []string{ErrorClassAuth, ErrorClassTimeout, ErrorClassRetryable, ErrorClassOther}
+
+// ErrorClass returns the class of a failure, the first of ErrorClassAuth,
+// ErrorClassTimeout and ErrorClassRetryable which applies, or ErrorClassOther.
+func ErrorClass(err error) string {
+	var authErr *conduit.AuthError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &authErr):
+		return ErrorClassAuth
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case isRetryable(err):
+		return ErrorClassRetryable
+	}
+	return ErrorClassOther
+}
+
+// RetryPolicy configures how a failed round is retried. Unset fields are
+// inherited from the stage policy, then from retry-count and retry-delay.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries before on-failure applies.
+	MaxRetries *uint64 `yaml:"max-retries"`
+	// InitialDelay is the delay before the first retry.
+	InitialDelay time.Duration `yaml:"initial-delay"`
+	// MaxDelay caps the delay between retries, zero does not cap it.
+	MaxDelay time.Duration `yaml:"max-delay"`
+	// Multiplier grows the delay after each retry, 1 (default) keeps it constant.
+	Multiplier float64 `yaml:"multiplier"`
+	// Jitter randomizes each delay by up to this fraction of it, between 0 and 1.
+	Jitter float64 `yaml:"jitter"`
+	// MaxElapsedTime stops retrying once this much time passed since the
+	// first failure of the round, zero does not limit it.
+	MaxElapsedTime time.Duration `yaml:"max-elapsed-time"`
+	// RetryableOnly treats errors which are not a conduit.RetryableError as
+	// fatal, on-failure applies without retrying.
+	RetryableOnly bool `yaml:"retryable-only"`
+	// Strategy is the name of the registered RetryStrategy which decides
+	// whether and when to retry, the default applies the policy.
+	Strategy string `yaml:"strategy"`
+}
+
+// RetryPolicies configures the retries of each stage. A plugin's retry-policy
+// overrides the policy of its stage, and the policy of the error class
+// overrides both.
+type RetryPolicies struct {
+	Importer   *RetryPolicy `yaml:"importer"`
+	Processors *RetryPolicy `yaml:"processors"`
+	Exporters  *RetryPolicy `yaml:"exporters"`
+	// ErrorClasses are the policies of the error classes: auth, timeout,
+	// retryable and other.
+	ErrorClasses map[string]*RetryPolicy `yaml:"error-classes"`
+}
+
+// Valid validates the retry policy.
+func (rp *RetryPolicy) Valid() error {
+	if rp == nil {
+		return nil
+	}
+	if rp.InitialDelay < 0 || rp.MaxDelay < 0 || rp.MaxElapsedTime < 0 {
+		return fmt.Errorf("initial-delay, max-delay and max-elapsed-time must not be negative")
+	}
+	if rp.Multiplier != 0 && rp.Multiplier < 1 {
+		return fmt.Errorf("multiplier (%v) must be at least 1", rp.Multiplier)
+	}
+	if rp.Jitter < 0 || rp.Jitter > 1 {
+		return fmt.Errorf("jitter (%v) must be between 0 and 1", rp.Jitter)
+	}
+	if rp.Strategy != "" {
+		if _, ok := lookupRetryStrategy(rp.Strategy); !ok {
+			return fmt.Errorf("unknown strategy (%s)", rp.Strategy)
+		}
+	}
+	return nil
+}
+
+// Valid validates the retry policy of each stage.
+func (rps RetryPolicies) Valid() error {
+	if err := rps.Importer.Valid(); err != nil {
+		return fmt.Errorf("%s: %w", importerStage, err)
+	}
+	if err := rps.Processors.Valid(); err != nil {
+		return fmt.Errorf("%s: %w", processorStage, err)
+	}
+	if err := rps.Exporters.Valid(); err != nil {
+		return fmt.Errorf("%s: %w", exporterStage, err)
+	}
+	classes := make([]string, 0, len(rps.ErrorClasses))
+	for class := range rps.ErrorClasses {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		known := false
+		for _, c := range errorClasses {
+			known = known || c == class
+		}
+		if !known {
+			return fmt.Errorf("unknown error class (%s), expected one of %v", class, errorClasses)
+		}
+		if err := rps.ErrorClasses[class].Valid(); err != nil {
+			return fmt.Errorf("error class %s: %w", class, err)
+		}
+	}
+	return nil
+}
+
+// merge returns the policy with the fields which are set in override replaced.
+func (rp RetryPolicy) merge(override *RetryPolicy) RetryPolicy {
+	if override == nil {
+		return rp
+	}
+	if override.MaxRetries != nil {
+		rp.MaxRetries = override.MaxRetries
+	}
+	if override.InitialDelay != 0 {
+		rp.InitialDelay = override.InitialDelay
+	}
+	if override.MaxDelay != 0 {
+		rp.MaxDelay = override.MaxDelay
+	}
+	if override.Multiplier != 0 {
+		rp.Multiplier = override.Multiplier
+	}
+	if override.Jitter != 0 {
+		rp.Jitter = override.Jitter
+	}
+	if override.MaxElapsedTime != 0 {
+		rp.MaxElapsedTime = override.MaxElapsedTime
+	}
+	if override.RetryableOnly {
+		rp.RetryableOnly = true
+	}
+	if override.Strategy != "" {
+		rp.Strategy = override.Strategy
+	}
+	return rp
+}
+
+// delay returns the delay before the given retry, starting at 1.
+func (rp RetryPolicy) delay(retry uint64, random func() float64) time.Duration {
+	multiplier := rp.Multiplier
+	if multiplier == 0 {
+		multiplier = 1
+	}
+	delay := float64(rp.InitialDelay)
+	if retry > 1 {
+		delay *= math.Pow(multiplier, float64(retry-1))
+	}
+	if rp.MaxDelay > 0 && delay > float64(rp.MaxDelay) {
+		delay = float64(rp.MaxDelay)
+	}
+	if rp.Jitter > 0 {
+		delay *= 1 + rp.Jitter*(2*random()-1)
+	}
+	if delay > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+// retryPolicy returns the policy for a failure of the plugin at idx in the
+// stage. Failures outside of a plugin use retry-count and retry-delay.
+func (cfg *Config) retryPolicy(stage string, idx int) RetryPolicy {
+	retryCount := cfg.RetryCount
+	policy := RetryPolicy{MaxRetries: &retryCount, InitialDelay: cfg.RetryDelay, Multiplier: 1}
+	switch stage {
+	case importerStage:
+		policy = policy.merge(cfg.RetryPolicies.Importer).merge(cfg.Importer.RetryPolicy)
+	case processorStage:
+		policy = policy.merge(cfg.RetryPolicies.Processors)
+		if idx < len(cfg.Processors) {
+			policy = policy.merge(cfg.Processors[idx].RetryPolicy)
+		}
+	case exporterStage:
+		policy = policy.merge(cfg.RetryPolicies.Exporters)
+		if exporterCfgs := cfg.exporterConfigs(); idx < len(exporterCfgs) {
+			policy = policy.merge(exporterCfgs[idx].RetryPolicy)
+		}
+	}
+	return policy
+}
+
+// classPolicy returns the policy with the policy of the error class of err
+// applied.
+func (cfg *Config) classPolicy(policy RetryPolicy, err error) RetryPolicy {
+	return policy.merge(cfg.RetryPolicies.ErrorClasses[ErrorClass(err)])
+}
+
+// pluginName returns the name of the plugin at idx in the stage, or an empty
+// string for failures outside of a plugin.
+func (cfg *Config) pluginName(stage string, idx int) string {
+	switch stage {
+	case importerStage:
+		return cfg.Importer.Name
+	case processorStage:
+		if idx < len(cfg.Processors) {
+			return cfg.Processors[idx].Name
+		}
+	case exporterStage:
+		if ids := cfg.exporterIDs(); idx < len(ids) {
+			return ids[idx]
+		}
+	}
+	return ""
+}
+
+// RetryDefault is the name of the default RetryStrategy, which applies the
+// retry policy.
+const RetryDefault = "default"
+
+// RetryFailure is a failure of a round, for a RetryStrategy to decide on.
+type RetryFailure struct {
+	Round uint64
+	// Stage is "importer", "processors" or "exporters", it is empty for
+	// failures outside of a plugin.
+	Stage string
+	// Plugin is the name of the plugin which failed.
+	Plugin string
+	Err    error
+	// Class is the ErrorClass of Err.
+	Class string
+	// Retry is the number of failures of the round so far, starting at 1.
+	Retry uint64
+	// Elapsed is the time since the first failure of the round.
+	Elapsed time.Duration
+	// Policy is the retry policy of the failure, with its error class applied.
+	Policy RetryPolicy
+}
+
+// RetryDecision is the decision of a RetryStrategy.
+type RetryDecision struct {
+	// GiveUp stops retrying the round, on-failure applies.
+	GiveUp bool
+	// Reason describes why the round is no longer retried.
+	Reason string
+	// Delay is the wait before the next retry.
+	Delay time.Duration
+}
+
+// DefaultDecision applies the retry policy of the failure, custom strategies
+// can use it for the failures they do not handle.
+func (f RetryFailure) DefaultDecision() RetryDecision {
+	policy := f.Policy
+	if policy.RetryableOnly && !isRetryable(f.Err) {
+		return RetryDecision{GiveUp: true, Reason: "failed with a non-retryable error"}
+	}
+	var maxRetries uint64
+	if policy.MaxRetries != nil {
+		maxRetries = *policy.MaxRetries
+	}
+	if f.Retry > maxRetries || (policy.MaxElapsedTime > 0 && f.Elapsed >= policy.MaxElapsedTime) {
+		return RetryDecision{GiveUp: true, Reason: fmt.Sprintf("exceeded the maximum retry count (%d) or elapsed time", maxRetries)}
+	}
+	return RetryDecision{Delay: policy.delay(f.Retry, rand.Float64)}
+}
+
+// RetryStrategy decides whether and when a failed round is retried, for
+// example to page someone about an auth failure before retrying. It is
+// called from the pipeline loop, which waits for it.
+type RetryStrategy interface {
+	Decide(failure RetryFailure) RetryDecision
+}
+
+// RetryStrategyFunc is RetryStrategy implemented by a function.
+type RetryStrategyFunc func(failure RetryFailure) RetryDecision
+
+// Decide calls f.
+func (f RetryStrategyFunc) Decide(failure RetryFailure) RetryDecision {
+	return f(failure)
+}
+
+var (
+	retryStrategiesMu sync.RWMutex
+	retryStrategies   = map[string]RetryStrategy{
+		RetryDefault: RetryStrategyFunc(RetryFailure.DefaultDecision),
+	}
+)
+
+// RegisterRetryStrategy makes a RetryStrategy available to the strategy of
+// retry policies. It is typically called from an init function.
+func RegisterRetryStrategy(name string, strategy RetryStrategy) {
+	retryStrategiesMu.Lock()
+	defer retryStrategiesMu.Unlock()
+	retryStrategies[name] = strategy
+}
+
+func lookupRetryStrategy(name string) (RetryStrategy, bool) {
+	if name == "" {
+		name = RetryDefault
+	}
+	retryStrategiesMu.RLock()
+	defer retryStrategiesMu.RUnlock()
+	s, ok := retryStrategies[name]
+	return s, ok
+}
+
+// isRetryable reports whether err is, or wraps, a *conduit.RetryableError.
+func isRetryable(err error) bool {
+	var retryable *conduit.RetryableError
+	return errors.As(err, &retryable)
+}
+
+// retryState tracks the retries of the current round.
+type retryState struct {
+	// firstFailure is when the round first failed.
+	firstFailure time.Time
+	// exhausted is set once the round should not be retried again.
+	exhausted bool
+	// why describes why the round is no longer retried.
+	why string
+	// delay is the wait before the next retry.
+	delay time.Duration
+}
+
+// failed records a failure of the round, its strategy decides whether it is
+// retried.
+func (s *retryState) failed(failure RetryFailure, now time.Time) {
+	if s.firstFailure.IsZero() {
+		s.firstFailure = now
+	}
+	failure.Elapsed = now.Sub(s.firstFailure)
+	strategy, ok := lookupRetryStrategy(failure.Policy.Strategy)
+	if !ok {
+		// The strategy was validated with the config.
+		strategy, _ = lookupRetryStrategy(RetryDefault)
+	}
+	decision := strategy.Decide(failure)
+	s.exhausted = decision.GiveUp
+	s.why = decision.Reason
+	if s.exhausted && s.why == "" {
+		s.why = fmt.Sprintf("was given up by the %s retry strategy", failure.Policy.Strategy)
+	}
+	s.delay = decision.Delay
+}
+
+// reason describes why the round is no longer retried.
+func (s *retryState) reason() string {
+	return s.why
+}
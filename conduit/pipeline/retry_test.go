@@ -0,0 +1,248 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+)
+
+func uint64Ptr(v uint64) *uint64 {
+	return &v
+}
+
+func TestRetryPolicyValid(t *testing.T) {
+	var nilPolicy *RetryPolicy
+	assert.NoError(t, nilPolicy.Valid())
+	assert.NoError(t, (&RetryPolicy{Multiplier: 2, Jitter: 0.5}).Valid())
+	assert.EqualError(t, (&RetryPolicy{Multiplier: 0.5}).Valid(), "multiplier (0.5) must be at least 1")
+	assert.EqualError(t, (&RetryPolicy{Jitter: 2}).Valid(), "jitter (2) must be between 0 and 1")
+	assert.EqualError(t, (&RetryPolicy{MaxDelay: -time.Second}).Valid(), "initial-delay, max-delay and max-elapsed-time must not be negative")
+	assert.EqualError(t, RetryPolicies{Exporters: &RetryPolicy{Jitter: -1}}.Valid(), "exporters: jitter (-1) must be between 0 and 1")
+}
+
+func TestConfigRetryPolicy(t *testing.T) {
+	cfg := Config{
+		RetryCount: 10,
+		RetryDelay: time.Second,
+		Importer:   NameConfigPair{Name: "algod"},
+		Processors: []NameConfigPair{{Name: "noop"}},
+		Exporters: []NameConfigPair{
+			{Name: "postgresql"},
+			{Name: "file_writer", RetryPolicy: &RetryPolicy{MaxRetries: uint64Ptr(0), RetryableOnly: true}},
+		},
+		RetryPolicies: RetryPolicies{
+			Importer:  &RetryPolicy{Multiplier: 2, MaxDelay: time.Minute},
+			Exporters: &RetryPolicy{MaxRetries: uint64Ptr(3), InitialDelay: 5 * time.Second},
+		},
+	}
+
+	defaultPolicy := RetryPolicy{MaxRetries: uint64Ptr(10), InitialDelay: time.Second, Multiplier: 1}
+	assert.Equal(t, defaultPolicy, cfg.retryPolicy("", 0))
+	assert.Equal(t, defaultPolicy, cfg.retryPolicy(processorStage, 0))
+	assert.Equal(t, RetryPolicy{MaxRetries: uint64Ptr(10), InitialDelay: time.Second, Multiplier: 2, MaxDelay: time.Minute}, cfg.retryPolicy(importerStage, 0))
+	assert.Equal(t, RetryPolicy{MaxRetries: uint64Ptr(3), InitialDelay: 5 * time.Second, Multiplier: 1}, cfg.retryPolicy(exporterStage, 0))
+	assert.Equal(t, RetryPolicy{MaxRetries: uint64Ptr(0), InitialDelay: 5 * time.Second, Multiplier: 1, RetryableOnly: true}, cfg.retryPolicy(exporterStage, 1))
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	half := func() float64 { return 0.5 }
+	policy := RetryPolicy{InitialDelay: time.Second, Multiplier: 2, MaxDelay: 10 * time.Second}
+	var delays []time.Duration
+	for retry := uint64(1); retry <= 6; retry++ {
+		delays = append(delays, policy.delay(retry, half))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}, delays)
+
+	// the delay is constant without a multiplier
+	assert.Equal(t, time.Second, RetryPolicy{InitialDelay: time.Second}.delay(5, half))
+
+	// jitter randomizes the delay by up to the fraction in both directions
+	policy = RetryPolicy{InitialDelay: time.Second, Jitter: 0.5}
+	assert.Equal(t, 500*time.Millisecond, policy.delay(1, func() float64 { return 0 }))
+	assert.Equal(t, 1500*time.Millisecond, policy.delay(1, func() float64 { return 1 }))
+
+	// large exponents do not overflow
+	policy = RetryPolicy{InitialDelay: time.Second, Multiplier: 10}
+	assert.Equal(t, time.Duration(math.MaxInt64), policy.delay(100, half))
+}
+
+func TestRetryStateFailed(t *testing.T) {
+	now := time.Now()
+	policy := RetryPolicy{MaxRetries: uint64Ptr(2), InitialDelay: time.Second, Multiplier: 2}
+
+	var state retryState
+	state.failed(RetryFailure{Retry: 1, Err: fmt.Errorf("timeout"), Policy: policy}, now)
+	assert.False(t, state.exhausted)
+	assert.Equal(t, time.Second, state.delay)
+	state.failed(RetryFailure{Retry: 2, Err: fmt.Errorf("timeout"), Policy: policy}, now)
+	assert.False(t, state.exhausted)
+	assert.Equal(t, 2*time.Second, state.delay)
+	state.failed(RetryFailure{Retry: 3, Err: fmt.Errorf("timeout"), Policy: policy}, now)
+	assert.True(t, state.exhausted)
+	assert.Equal(t, "exceeded the maximum retry count (2) or elapsed time", state.reason())
+
+	// max elapsed time since the first failure
+	policy.MaxElapsedTime = time.Minute
+	state = retryState{}
+	state.failed(RetryFailure{Retry: 1, Err: fmt.Errorf("timeout"), Policy: policy}, now)
+	assert.False(t, state.exhausted)
+	state.failed(RetryFailure{Retry: 2, Err: fmt.Errorf("timeout"), Policy: policy}, now.Add(time.Minute))
+	assert.True(t, state.exhausted)
+
+	// only retryable errors are retried
+	policy = RetryPolicy{MaxRetries: uint64Ptr(5), RetryableOnly: true}
+	state = retryState{}
+	state.failed(RetryFailure{Retry: 1, Err: fmt.Errorf("wrapped: %w", conduit.MakeRetryableError(fmt.Errorf("timeout"))), Policy: policy}, now)
+	assert.False(t, state.exhausted)
+	state.failed(RetryFailure{Retry: 2, Err: fmt.Errorf("constraint violation"), Policy: policy}, now)
+	assert.True(t, state.exhausted)
+	assert.Equal(t, "failed with a non-retryable error", state.reason())
+}
+
+func TestRetryableError(t *testing.T) {
+	assert.Nil(t, conduit.MakeRetryableError(nil))
+	inner := fmt.Errorf("timeout")
+	err := conduit.MakeRetryableError(inner)
+	assert.EqualError(t, err, "timeout")
+	assert.ErrorIs(t, err, inner)
+	assert.True(t, isRetryable(fmt.Errorf("receive: %w", err)))
+	assert.False(t, isRetryable(inner))
+}
+
+// retryableExporter fails the first failCount receives with a retryable error.
+type retryableExporter struct {
+	roundExporter
+	retryableFailures int
+}
+
+func (r *retryableExporter) Receive(exportData data.BlockData) error {
+	r.mu.Lock()
+	if r.retryableFailures > 0 {
+		r.retryableFailures--
+		r.mu.Unlock()
+		return conduit.MakeRetryableError(fmt.Errorf("timeout"))
+	}
+	r.mu.Unlock()
+	return r.roundExporter.Receive(exportData)
+}
+
+// TestPipelineRetryableOnly tests that a non-retryable exporter error skips the
+// round without retrying, while retryable errors are retried.
+func TestPipelineRetryableOnly(t *testing.T) {
+	exp := &retryableExporter{roundExporter: roundExporter{name: "exporter", failRound: 2, failCount: math.MaxInt}, retryableFailures: 2}
+	pImpl := makeFailurePipeline(t, &roundImporter{failRound: 1000}, &exp.roundExporter, onFailureSkip)
+	var pExporter exporters.Exporter = exp
+	pImpl.exporters[0] = &pExporter
+	pImpl.cfg.RetryCount = 100
+	pImpl.cfg.RetryDelay = time.Hour
+	pImpl.cfg.Exporters = []NameConfigPair{{Name: "exporter", RetryPolicy: &RetryPolicy{RetryableOnly: true, InitialDelay: time.Millisecond}}}
+
+	pImpl.Start()
+	require.Eventually(t, func() bool {
+		return len(exp.received()) >= 4
+	}, 5*time.Second, time.Millisecond)
+	pImpl.cf()
+	pImpl.Wait()
+
+	assert.Equal(t, []uint64{0, 1, 3, 4}, exp.received()[:4])
+	assert.Equal(t, []failedRound{{Round: 2, Error: "receive"}}, readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir).FailedRounds)
+}
+
+// timeoutError is a network timeout, like a net.Error of a dial.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestErrorClass(t *testing.T) {
+	assert.Equal(t, ErrorClassAuth, ErrorClass(fmt.Errorf("receive: %w", conduit.MakeAuthError(fmt.Errorf("HTTP 401")))))
+	assert.Equal(t, ErrorClassTimeout, ErrorClass(fmt.Errorf("receive: %w", context.DeadlineExceeded)))
+	assert.Equal(t, ErrorClassTimeout, ErrorClass(conduit.MakeRetryableError(timeoutError{})))
+	assert.Equal(t, ErrorClassRetryable, ErrorClass(conduit.MakeRetryableError(fmt.Errorf("busy"))))
+	assert.Equal(t, ErrorClassOther, ErrorClass(fmt.Errorf("constraint violation")))
+}
+
+func TestRetryPoliciesErrorClasses(t *testing.T) {
+	assert.EqualError(t, RetryPolicies{ErrorClasses: map[string]*RetryPolicy{"fatal": {}}}.Valid(), "unknown error class (fatal), expected one of [auth timeout retryable other]")
+	assert.EqualError(t, RetryPolicies{ErrorClasses: map[string]*RetryPolicy{ErrorClassAuth: {Jitter: 2}}}.Valid(), "error class auth: jitter (2) must be between 0 and 1")
+	assert.EqualError(t, (&RetryPolicy{Strategy: "missing"}).Valid(), "unknown strategy (missing)")
+	assert.NoError(t, (&RetryPolicy{Strategy: RetryDefault}).Valid())
+
+	cfg := Config{
+		RetryCount: 10,
+		RetryDelay: time.Second,
+		RetryPolicies: RetryPolicies{
+			Importer:     &RetryPolicy{MaxRetries: uint64Ptr(100)},
+			ErrorClasses: map[string]*RetryPolicy{ErrorClassAuth: {MaxRetries: uint64Ptr(0), Strategy: RetryDefault}},
+		},
+	}
+	policy := cfg.retryPolicy(importerStage, 0)
+	assert.Equal(t, policy, cfg.classPolicy(policy, fmt.Errorf("timeout")))
+	assert.Equal(t, RetryPolicy{MaxRetries: uint64Ptr(0), InitialDelay: time.Second, Multiplier: 1, Strategy: RetryDefault}, cfg.classPolicy(policy, conduit.MakeAuthError(fmt.Errorf("HTTP 403"))))
+}
+
+// recordingStrategy records the failures, it gives up on the second retry.
+type recordingStrategy struct {
+	mu       sync.Mutex
+	failures []RetryFailure
+}
+
+func (s *recordingStrategy) Decide(failure RetryFailure) RetryDecision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failure.Elapsed = 0
+	s.failures = append(s.failures, failure)
+	if failure.Retry >= 2 {
+		return RetryDecision{GiveUp: true, Reason: "was escalated"}
+	}
+	return failure.DefaultDecision()
+}
+
+func (s *recordingStrategy) recorded() []RetryFailure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RetryFailure(nil), s.failures...)
+}
+
+// TestPipelineRetryStrategy tests that the strategy of the error class decides
+// the retries of the round.
+func TestPipelineRetryStrategy(t *testing.T) {
+	strategy := &recordingStrategy{}
+	RegisterRetryStrategy("test-escalate", strategy)
+	exp := &roundExporter{name: "exporter", failRound: 2, failCount: math.MaxInt}
+	pImpl := makeFailurePipeline(t, &roundImporter{failRound: 1000}, exp, onFailureSkip)
+	pImpl.cfg.RetryCount = 100
+	pImpl.cfg.Exporters = []NameConfigPair{{Name: "exporter"}}
+	policy := &RetryPolicy{InitialDelay: time.Millisecond, Strategy: "test-escalate"}
+	pImpl.cfg.RetryPolicies.ErrorClasses = map[string]*RetryPolicy{ErrorClassOther: policy}
+	require.NoError(t, pImpl.cfg.RetryPolicies.Valid())
+
+	pImpl.Start()
+	require.Eventually(t, func() bool {
+		return len(exp.received()) >= 4
+	}, 5*time.Second, time.Millisecond)
+	pImpl.cf()
+	pImpl.Wait()
+
+	assert.Equal(t, []uint64{0, 1, 3, 4}, exp.received()[:4])
+	merged := RetryPolicy{MaxRetries: uint64Ptr(100), InitialDelay: time.Millisecond, Multiplier: 1, Strategy: "test-escalate"}
+	failures := strategy.recorded()
+	require.Len(t, failures, 2)
+	for i, failure := range failures {
+		assert.EqualError(t, failure.Err, "receive")
+		failure.Err = nil
+		assert.Equal(t, RetryFailure{Round: 2, Stage: exporterStage, Plugin: "exporter", Class: ErrorClassOther, Retry: uint64(i + 1), Policy: merged}, failure)
+	}
+	assert.Equal(t, []failedRound{{Round: 2, Error: "receive"}}, readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir).FailedRounds)
+}
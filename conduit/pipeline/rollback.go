@@ -0,0 +1,119 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+// rollbackRequest is a RollbackRound request passed to the pipeline loop.
+type rollbackRequest struct {
+	round  uint64
+	result chan error
+}
+
+// RollbackRound waits for the in-flight round, then rewinds the processors and
+// exporters which implement conduit.RoundRewinder to the round, saves it as
+// the next round and resumes the pipeline from it. The round must not be
+// after the next round.
+func (p *pipelineImpl) RollbackRound(round uint64) error {
+	p.mu.RLock()
+	loopDone := p.loopDone
+	p.mu.RUnlock()
+	if loopDone == nil {
+		return fmt.Errorf("RollbackRound(): pipeline is not running")
+	}
+
+	req := rollbackRequest{round: round, result: make(chan error, 1)}
+	select {
+	case p.rollbackCh <- req:
+	case <-loopDone:
+		return fmt.Errorf("RollbackRound(): pipeline is not running")
+	}
+	return <-req.result
+}
+
+// rollback is called by the pipeline loop between rounds, the loop restarts
+// the prefetcher and forgets the in-flight round once it succeeds.
+func (p *pipelineImpl) rollback(round uint64) error {
+	if round > p.pipelineMetadata.NextRound {
+		return fmt.Errorf("RollbackRound(): round %d is after the next round %d", round, p.pipelineMetadata.NextRound)
+	}
+	// The rewinders remove the rounds which are waiting in the batch, so they
+	// must have received them.
+	if err := p.flushBatch(); err != nil {
+		return fmt.Errorf("RollbackRound(): %w", err)
+	}
+	rewind := func(plugin interface{}, pluginType, name string) error {
+		rewinder, ok := plugin.(conduit.RoundRewinder)
+		if !ok {
+			p.logger.Warnf("%s (%s) does not implement conduit.RoundRewinder, its data from round %d is kept", pluginType, name, round)
+			return nil
+		}
+		if err := rewinder.RewindRound(round); err != nil {
+			return fmt.Errorf("RollbackRound(): %s (%s) could not rewind to round %d: %w", pluginType, name, round, err)
+		}
+		return nil
+	}
+	for _, proc := range p.processors {
+		if err := rewind(*proc, "processor", (*proc).Metadata().Name); err != nil {
+			return err
+		}
+	}
+	for idx, exporter := range p.exporters {
+		// The standby did not receive the rounds.
+		if p.isStandby(idx) {
+			continue
+		}
+		if err := rewind(*exporter, "exporter", p.exporterName(idx)); err != nil {
+			return err
+		}
+	}
+	if err := p.rewindWindows(round); err != nil {
+		return fmt.Errorf("RollbackRound(): %w", err)
+	}
+
+	// Prepared rounds are not committed, and the failures of the rewound
+	// rounds are recorded again if they fail again.
+	p.rollbackIntents(p.pipelineMetadata.NextRound)
+	p.pipelineMetadata.CommitIntents = nil
+	p.pipelineMetadata.FailedRounds = keepFailedRounds(p.pipelineMetadata.FailedRounds, round)
+	p.pipelineMetadata.FailedTxns = keepFailedTxns(p.pipelineMetadata.FailedTxns, round)
+	p.pipelineMetadata.NextRound = round
+	p.lastHeader = nil
+	p.simClock = makeSimClock(p.cfg.Simulation, round, time.Now())
+	if err := p.saveMetadata(); err != nil {
+		return fmt.Errorf("RollbackRound(): could not save metadata: %w", err)
+	}
+
+	p.mu.Lock()
+	p.status.NextRound = round
+	p.control.paused = false
+	p.mu.Unlock()
+	p.setError(nil)
+	p.logger.Infof("Pipeline rolled back, resuming from round %d", round)
+	return nil
+}
+
+// keepFailedRounds returns the failed rounds before the round.
+func keepFailedRounds(failed []failedRound, round uint64) []failedRound {
+	var kept []failedRound
+	for _, f := range failed {
+		if f.Round < round {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// keepFailedTxns returns the failed transactions of the rounds before the round.
+func keepFailedTxns(failed []failedTxn, round uint64) []failedTxn {
+	var kept []failedTxn
+	for _, f := range failed {
+		if f.Round < round {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
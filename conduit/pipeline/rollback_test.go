@@ -0,0 +1,138 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rewindExporter is a roundExporter which forgets the rewound rounds.
+type rewindExporter struct {
+	roundExporter
+	rewinds []uint64
+	err     error
+}
+
+func (r *rewindExporter) RewindRound(round uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.rewinds = append(r.rewinds, round)
+	for i, rnd := range r.rounds {
+		if rnd >= round {
+			r.rounds = r.rounds[:i]
+			break
+		}
+	}
+	return nil
+}
+
+// TestPipelineRollbackRound tests that the rewinders are rewound, the round is
+// saved as the next round and the pipeline resumes from it.
+func TestPipelineRollbackRound(t *testing.T) {
+	exp := &rewindExporter{roundExporter: roundExporter{name: "rewinder"}}
+	plain := &roundExporter{name: "plain"}
+	pImpl := makeCheckpointPipeline(t, exp, plain)
+	assert.EqualError(t, pImpl.RollbackRound(1), "RollbackRound(): pipeline is not running")
+
+	pImpl.Start()
+	defer func() {
+		pImpl.cf()
+		pImpl.Wait()
+	}()
+	require.Eventually(t, func() bool { return len(exp.received()) >= 5 }, 5*time.Second, time.Millisecond)
+
+	pImpl.Pause()
+	held := waitHeld(t, &exp.roundExporter)
+	err := pImpl.RollbackRound(uint64(held + 1))
+	assert.EqualError(t, err, fmt.Sprintf("RollbackRound(): round %d is after the next round %d", held+1, held))
+
+	require.NoError(t, pImpl.RollbackRound(2))
+	assert.Equal(t, []uint64{2}, exp.rewinds)
+	assert.False(t, pImpl.Status().Paused)
+
+	// The rewound rounds are exported again, in order.
+	require.Eventually(t, func() bool { return len(exp.received()) >= held }, 5*time.Second, time.Millisecond)
+	received := exp.received()
+	for i, round := range received {
+		assert.Equal(t, uint64(i), round)
+	}
+	// Exporters without RewindRound receive the rounds twice.
+	require.Eventually(t, func() bool { return len(plain.received()) > held }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, uint64(2), plain.received()[held])
+}
+
+// TestRollbackState tests the saved state of a rollback.
+func TestRollbackState(t *testing.T) {
+	pImpl := makeCheckpointPipeline(t, &rewindExporter{roundExporter: roundExporter{name: "rewinder"}})
+	pImpl.pipelineMetadata.NextRound = 5
+	pImpl.pipelineMetadata.FailedRounds = []failedRound{{Round: 1}, {Round: 3}}
+	pImpl.pipelineMetadata.FailedTxns = []failedTxn{{Round: 2, Intra: 1}, {Round: 4}}
+	pImpl.status.NextRound = 5
+	pImpl.control.paused = true
+
+	require.NoError(t, pImpl.rollback(2))
+	saved := readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir)
+	assert.Equal(t, uint64(2), saved.NextRound)
+	assert.Equal(t, []failedRound{{Round: 1}}, saved.FailedRounds)
+	assert.Empty(t, saved.FailedTxns)
+	assert.Equal(t, uint64(2), pImpl.Status().NextRound)
+	assert.False(t, pImpl.Status().Paused)
+}
+
+// TestPipelineRollbackFailure tests that a rewinder failure is returned and the
+// next round is not changed.
+func TestPipelineRollbackFailure(t *testing.T) {
+	exp := &rewindExporter{roundExporter: roundExporter{name: "rewinder"}, err: errors.New("read only")}
+	pImpl := makeCheckpointPipeline(t, exp)
+	pImpl.Pause()
+	pImpl.Start()
+	defer func() {
+		pImpl.cf()
+		pImpl.Wait()
+	}()
+	require.NoError(t, pImpl.Step(3))
+	held := waitHeld(t, &exp.roundExporter)
+	require.Equal(t, 3, held)
+
+	err := pImpl.RollbackRound(1)
+	assert.EqualError(t, err, "RollbackRound(): exporter (rewinder) could not rewind to round 1: read only")
+	assert.Equal(t, uint64(3), pImpl.Status().NextRound)
+	assert.True(t, pImpl.Status().Paused)
+}
+
+func TestRollbackAPI(t *testing.T) {
+	exp := &rewindExporter{roundExporter: roundExporter{name: "rewinder"}}
+	pImpl := makeCheckpointPipeline(t, exp)
+	mux := http.NewServeMux()
+	pImpl.registerAPIHandlers(mux)
+	request := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusMethodNotAllowed, request(http.MethodGet, "/rollback?round=1").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/rollback").Code)
+	assert.Equal(t, http.StatusConflict, request(http.MethodPost, "/rollback?round=1").Code)
+
+	pImpl.Pause()
+	pImpl.Start()
+	defer func() {
+		pImpl.cf()
+		pImpl.Wait()
+	}()
+	require.NoError(t, pImpl.Step(2))
+	waitHeld(t, &exp.roundExporter)
+	rec := request(http.MethodPost, "/rollback?round=1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"next-round": 1}`, rec.Body.String())
+}
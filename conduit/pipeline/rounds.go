@@ -0,0 +1,69 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// Rounds configs for importing a bounded range of rounds, for example for a
+// historical backfill.
+type Rounds struct {
+	// Start is the first round to import. It is ignored when next-round in
+	// the metadata is already past it, so an interrupted backfill resumes
+	// where it stopped. 0 continues from the metadata.
+	Start uint64 `yaml:"start"`
+	// End is the last round to import, the pipeline exits once it has been
+	// exported. 0 means there is no last round.
+	End uint64 `yaml:"end"`
+	// Workers is the number of concurrent importer GetBlock calls. Blocks are
+	// reordered before they are processed. 0 and 1 fetch one round at a time.
+	Workers uint64 `yaml:"workers"`
+}
+
+// Valid validates the rounds config.
+func (r Rounds) Valid() error {
+	if r.End > 0 && r.Start > r.End {
+		return fmt.Errorf("start (%d) must not be after end (%d)", r.Start, r.End)
+	}
+	return nil
+}
+
+// finished reports whether the last round in the range has been exported.
+func (r Rounds) finished(nextRound uint64) bool {
+	return r.End > 0 && nextRound > r.End
+}
+
+// prefetchSize is the number of rounds which may be fetched ahead of the
+// processors. Concurrent workers need at least one buffered round each.
+func (cfg *Config) prefetchSize() uint64 {
+	if cfg.Rounds.Workers > 1 && cfg.Rounds.Workers > cfg.PrefetchRounds {
+		return cfg.Rounds.Workers
+	}
+	return cfg.PrefetchRounds
+}
+
+// startPrefetch starts a prefetcher at the next round, which becomes the
+// current prefetcher. Importers which implement importers.SubscribingImporter
+// push their blocks to it.
+func (p *pipelineImpl) startPrefetch() *prefetcher {
+	var prefetch *prefetcher
+	if sub, ok := (*p.importer).(importers.SubscribingImporter); ok {
+		prefetch = startSubscriber(p.ctx, sub, p.pipelineMetadata.NextRound, p.cfg.Rounds.End, p.cfg.prefetchSize(), p.cfg.RetryDelay)
+	} else {
+		prefetch = startPrefetcher(p.ctx, p.importer, p.importerName(), p.telemetry, &p.activity, p.pipelineMetadata.NextRound, p.cfg.Rounds.End, p.cfg.prefetchSize(), p.cfg.Rounds.Workers, p.cfg.RetryDelay)
+	}
+	p.activity.setPrefetcher(prefetch)
+	p.mu.Lock()
+	p.prefetch = prefetch
+	p.mu.Unlock()
+	return prefetch
+}
+
+// currentPrefetch returns the prefetcher which was started last, nil if the
+// rounds are not prefetched.
+func (p *pipelineImpl) currentPrefetch() *prefetcher {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.prefetch
+}
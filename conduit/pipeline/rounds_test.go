@@ -0,0 +1,127 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+func TestRoundsFinished(t *testing.T) {
+	assert.False(t, Rounds{}.finished(100))
+	assert.False(t, Rounds{End: 10}.finished(10))
+	assert.True(t, Rounds{End: 10}.finished(11))
+}
+
+func TestPrefetchSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefetch uint64
+		workers  uint64
+		expected uint64
+	}{
+		{"disabled", 0, 0, 0},
+		{"one worker", 0, 1, 0},
+		{"prefetch", 8, 0, 8},
+		{"workers", 0, 4, 4},
+		{"prefetch more than workers", 8, 4, 8},
+		{"workers more than prefetch", 2, 4, 4},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Config{PrefetchRounds: tc.prefetch, Rounds: Rounds{Workers: tc.workers}}
+			assert.Equal(t, tc.expected, cfg.prefetchSize())
+		})
+	}
+}
+
+// TestRoundsStart tests that the start round is only used when the metadata is behind it.
+func TestRoundsStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		next     uint64
+		override uint64
+		expected uint64
+	}{
+		{"metadata behind start", 3, 0, 10},
+		{"metadata past start", 12, 0, 12},
+		{"next round override", 3, 5, 5},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var pImporter importers.Importer = &mockImporter{genesis: sdk.Genesis{Network: "test"}}
+			l, _ := test.NewNullLogger()
+			pImpl := pipelineImpl{
+				cfg: &Config{
+					ConduitArgs: &conduit.Args{
+						ConduitDataDir:    t.TempDir(),
+						NextRoundOverride: tc.override,
+					},
+					Rounds: Rounds{Start: 10},
+				},
+				logger:           l,
+				importer:         &pImporter,
+				pipelineMetadata: state{NextRound: tc.next},
+			}
+			require.NoError(t, pImpl.Init())
+			assert.Equal(t, tc.expected, pImpl.pipelineMetadata.NextRound)
+		})
+	}
+}
+
+// TestPipelineRounds tests that a bounded range is exported in order and the pipeline exits without an error.
+func TestPipelineRounds(t *testing.T) {
+	var pImporter importers.Importer = &slowImporter{}
+	exp := &roundExporter{name: "exporter"}
+	var pExporter exporters.Exporter = exp
+
+	ctx, cf := context.WithCancel(context.Background())
+	defer cf()
+	l, _ := test.NewNullLogger()
+	pImpl := pipelineImpl{
+		ctx:        ctx,
+		cf:         cf,
+		logger:     l,
+		importer:   &pImporter,
+		processors: []*processors.Processor{},
+		exporters:  []*exporters.Exporter{&pExporter},
+		cfg: &Config{
+			RetryCount: 1,
+			Rounds:     Rounds{End: 20, Workers: 4},
+			ConduitArgs: &conduit.Args{
+				ConduitDataDir: t.TempDir(),
+			},
+		},
+		pipelineMetadata: state{NextRound: 3},
+	}
+
+	pImpl.Start()
+	done := make(chan struct{})
+	go func() {
+		pImpl.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeline did not finish")
+	}
+
+	var expected []uint64
+	for rnd := uint64(3); rnd <= 20; rnd++ {
+		expected = append(expected, rnd)
+	}
+	assert.Equal(t, expected, exp.received())
+	assert.NoError(t, pImpl.Error())
+	assert.Equal(t, uint64(21), pImpl.pipelineMetadata.NextRound)
+	assert.False(t, pImpl.status.Running)
+}
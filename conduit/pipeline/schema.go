@@ -0,0 +1,115 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+// defaultSchemaCheckInterval is how often exporter schemas are verified when
+// schema-check-interval is not configured.
+const defaultSchemaCheckInterval = time.Minute
+
+// schemaDrift is a *conduit.SchemaDriftError attributed to an exporter.
+type schemaDrift struct {
+	exporter string
+	err      error
+}
+
+func (d schemaDrift) Error() string {
+	return fmt.Sprintf("exporter (%s): %v", d.exporter, d.err)
+}
+
+func (d schemaDrift) Unwrap() error {
+	return d.err
+}
+
+// isSchemaDrift reports whether err is, or wraps, a *conduit.SchemaDriftError.
+func isSchemaDrift(err error) bool {
+	var drift *conduit.SchemaDriftError
+	return errors.As(err, &drift)
+}
+
+// recheckInterval is how often the schema is verified while the pipeline is paused.
+func (cfg *Config) recheckInterval() time.Duration {
+	if cfg.SchemaCheckInterval > 0 {
+		return cfg.SchemaCheckInterval
+	}
+	if cfg.RetryDelay > 0 {
+		return cfg.RetryDelay
+	}
+	return time.Second
+}
+
+// verifySchemas calls VerifySchema on the exporters which implement
+// conduit.SchemaVerifier. Only drift is returned, other errors are logged
+// since they also fail the exporter's next Receive. The standby exporter is
+// verified by its health check instead.
+func (p *pipelineImpl) verifySchemas() *schemaDrift {
+	for idx, exporter := range p.exporters {
+		verifier, ok := (*exporter).(conduit.SchemaVerifier)
+		if !ok || p.isStandby(idx) {
+			continue
+		}
+		name := p.exporterName(idx)
+		err := verifier.VerifySchema()
+		if isSchemaDrift(err) {
+			return &schemaDrift{exporter: name, err: err}
+		}
+		if err != nil {
+			p.logger.Warnf("exporter (%s) could not verify its schema: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// checkSchemas verifies the exporter schemas if schema-check-interval has
+// elapsed since the last check.
+func (p *pipelineImpl) checkSchemas() *schemaDrift {
+	if p.cfg.SchemaCheckInterval <= 0 || time.Since(p.lastSchemaCheck) < p.cfg.SchemaCheckInterval {
+		return nil
+	}
+	p.lastSchemaCheck = time.Now()
+	return p.verifySchemas()
+}
+
+// pauseForSchemaDrift blocks until the exporter schemas match again. The round
+// is neither retried nor dead-lettered while paused. It returns false if the
+// pipeline is stopped while paused.
+func (p *pipelineImpl) pauseForSchemaDrift(drift schemaDrift) bool {
+	p.logger.Errorf("%v - pausing until the schema is restored", drift)
+	p.setError(drift)
+	p.setPaused(drift.Error())
+	metrics.ExporterSchemaDrift.WithLabelValues(drift.exporter).Set(1)
+	defer func() {
+		metrics.ExporterSchemaDrift.WithLabelValues(drift.exporter).Set(0)
+		p.setPaused("")
+	}()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return false
+		case <-p.stopCh:
+			p.logger.Infof("Pipeline stopped while paused for schema drift")
+			return false
+		case <-time.After(p.cfg.recheckInterval()):
+		}
+		p.lastSchemaCheck = time.Now()
+		current := p.verifySchemas()
+		if current == nil {
+			p.logger.Infof("exporter (%s) schema restored, resuming", drift.exporter)
+			return true
+		}
+		if current.exporter != drift.exporter {
+			metrics.ExporterSchemaDrift.WithLabelValues(drift.exporter).Set(0)
+			drift = *current
+			metrics.ExporterSchemaDrift.WithLabelValues(drift.exporter).Set(1)
+		}
+		p.setError(drift)
+		p.setPaused(drift.Error())
+	}
+}
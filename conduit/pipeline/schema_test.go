@@ -0,0 +1,94 @@
+package pipeline
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// driftExporter reports schema drift while drifted is set.
+type driftExporter struct {
+	roundExporter
+	driftMu  sync.Mutex
+	drifted  bool
+	verified int
+}
+
+func (d *driftExporter) setDrifted(drifted bool) {
+	d.driftMu.Lock()
+	defer d.driftMu.Unlock()
+	d.drifted = drifted
+}
+
+func (d *driftExporter) VerifySchema() error {
+	d.driftMu.Lock()
+	defer d.driftMu.Unlock()
+	d.verified++
+	if d.drifted {
+		return &conduit.SchemaDriftError{Differences: []string{"column txn.txn is missing"}}
+	}
+	return nil
+}
+
+func (d *driftExporter) Receive(exportData data.BlockData) error {
+	if err := d.VerifySchema(); err != nil {
+		return err
+	}
+	return d.roundExporter.Receive(exportData)
+}
+
+func TestSchemaDriftError(t *testing.T) {
+	err := &conduit.SchemaDriftError{Differences: []string{"table txn is missing", "column block_header.round is integer, expected bigint"}}
+	assert.EqualError(t, err, "schema drift detected: table txn is missing; column block_header.round is integer, expected bigint")
+	drift := schemaDrift{exporter: "postgresql", err: err}
+	assert.True(t, isSchemaDrift(drift))
+	assert.False(t, isSchemaDrift(nil))
+	assert.EqualError(t, drift, "exporter (postgresql): schema drift detected: table txn is missing; column block_header.round is integer, expected bigint")
+}
+
+// TestPipelineSchemaDrift tests that the pipeline pauses on schema drift
+// without consuming retries or dead-lettering the round, and resumes once the
+// schema is restored.
+func TestPipelineSchemaDrift(t *testing.T) {
+	exp := &driftExporter{roundExporter: roundExporter{name: "drift_exporter"}}
+	pImpl := makeReloadPipeline(t, exp)
+	var imp importers.Importer = &namedImporter{roundImporter{failRound: 1000}}
+	pImpl.importer = &imp
+	pImpl.cfg.RetryCount = 0
+	pImpl.cfg.OnFailure = onFailureDeadLetter
+	pImpl.cfg.SchemaCheckInterval = 10 * time.Millisecond
+
+	exp.setDrifted(true)
+	pImpl.Start()
+	defer func() {
+		pImpl.cf()
+		pImpl.Wait()
+		pImpl.stop()
+	}()
+
+	require.Eventually(t, func() bool { return pImpl.Status().Paused }, 5*time.Second, time.Millisecond)
+	status := pImpl.Status()
+	assert.Contains(t, status.PauseReason, "exporter (drift_exporter): schema drift detected: column txn.txn is missing")
+	assert.Contains(t, status.LastError, "schema drift detected")
+	assert.Equal(t, uint64(0), status.RetryCount)
+	assert.Empty(t, exp.received())
+
+	exp.setDrifted(false)
+	require.Eventually(t, func() bool { return len(exp.received()) >= 3 }, 5*time.Second, time.Millisecond)
+	assert.False(t, pImpl.Status().Paused)
+	// no round was skipped
+	assert.Equal(t, []uint64{0, 1, 2}, exp.received()[:3])
+}
+
+func TestRecheckInterval(t *testing.T) {
+	assert.Equal(t, 5*time.Second, (&Config{SchemaCheckInterval: 5 * time.Second, RetryDelay: time.Millisecond}).recheckInterval())
+	assert.Equal(t, time.Millisecond, (&Config{RetryDelay: time.Millisecond}).recheckInterval())
+	assert.Equal(t, time.Second, (&Config{}).recheckInterval())
+}
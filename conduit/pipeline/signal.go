@@ -0,0 +1,77 @@
+package pipeline
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StopOnSignal gracefully stops the pipeline when one of the signals is
+// received. SIGINT and SIGTERM are used when no signals are provided.
+// The returned function stops listening for signals.
+func StopOnSignal(p Pipeline, logger *log.Logger, sigs ...os.Signal) func() {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-ch:
+			logger.Infof("Received %s, stopping pipeline after the current round", sig)
+			p.Stop()
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// ReloadOnSignal reloads the pipeline config returned by load when one of the
+// signals is received. SIGHUP is used when no signals are provided. The
+// returned function stops listening for signals.
+func ReloadOnSignal(p Pipeline, logger *log.Logger, load func() (*Config, error), sigs ...os.Signal) func() {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				logger.Infof("Received %s, reloading the configuration after the current round", sig)
+				cfg, err := load()
+				if err == nil {
+					err = p.Reload(cfg)
+				}
+				if err != nil {
+					logger.Errorf("Configuration was not reloaded: %v", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
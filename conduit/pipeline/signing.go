@@ -0,0 +1,78 @@
+package pipeline
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+)
+
+// Signing configs for signing the artifacts written by exporters.
+type Signing struct {
+	// KeyFile is a PEM encoded PKCS #8 ed25519 private key, as generated by
+	// "openssl genpkey -algorithm ed25519". Signing is disabled when it is empty.
+	KeyFile string `yaml:"key-file"`
+}
+
+// keySigner signs with an ed25519 private key.
+type keySigner struct {
+	key ed25519.PrivateKey
+}
+
+func (s keySigner) Sign(msg []byte) []byte {
+	return ed25519.Sign(s.key, msg)
+}
+
+func (s keySigner) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// makeSigner loads the signing key, the signer is nil if signing is disabled.
+func makeSigner(cfg Signing) (conduit.Signer, error) {
+	if cfg.KeyFile == "" {
+		return nil, nil
+	}
+	pemBytes, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("makeSigner(): unable to read key-file: %w", err)
+	}
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("makeSigner(): key-file %s is not PEM encoded", cfg.KeyFile)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("makeSigner(): unable to parse key-file %s: %w", cfg.KeyFile, err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("makeSigner(): key-file %s is not an ed25519 key", cfg.KeyFile)
+	}
+	return keySigner{key: edKey}, nil
+}
+
+// setSigner passes the signer to the exporter if it signs its output.
+func (p *pipelineImpl) setSigner(exporter exporters.Exporter) {
+	if p.signer == nil {
+		return
+	}
+	if s, ok := exporter.(conduit.OutputSigner); ok {
+		s.SetSigner(p.signer)
+	} else {
+		p.logger.Warnf("Exporter (%s) does not sign its output", exporter.Metadata().Name)
+	}
+}
+
+// signingKey returns the base64 encoded public key, or an empty string if
+// signing is disabled.
+func (p *pipelineImpl) signingKey() string {
+	if p.signer == nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(p.signer.PublicKey())
+}
@@ -0,0 +1,117 @@
+package pipeline
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path"
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// writeKeyFile writes a PKCS #8 PEM encoded key to a temporary file.
+func writeKeyFile(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyFile := path.Join(t.TempDir(), "signing.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return keyFile
+}
+
+func TestMakeSigner(t *testing.T) {
+	signer, err := makeSigner(Signing{})
+	require.NoError(t, err)
+	assert.Nil(t, signer)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err = makeSigner(Signing{KeyFile: writeKeyFile(t, priv)})
+	require.NoError(t, err)
+	assert.Equal(t, pub, signer.PublicKey())
+	assert.True(t, ed25519.Verify(pub, []byte("block"), signer.Sign([]byte("block"))))
+}
+
+func TestMakeSignerErrors(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	notPEM := path.Join(t.TempDir(), "key.txt")
+	require.NoError(t, os.WriteFile(notPEM, []byte("key"), 0600))
+
+	tests := []struct {
+		name    string
+		keyFile string
+		errMsg  string
+	}{
+		{name: "missing", keyFile: path.Join(t.TempDir(), "missing.pem"), errMsg: "makeSigner(): unable to read key-file"},
+		{name: "not pem", keyFile: notPEM, errMsg: "is not PEM encoded"},
+		{name: "not ed25519", keyFile: writeKeyFile(t, ecKey), errMsg: "is not an ed25519 key"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := makeSigner(Signing{KeyFile: tc.keyFile})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
+
+// signingExporter implements conduit.OutputSigner.
+type signingExporter struct {
+	mockExporter
+	signer conduit.Signer
+}
+
+func (e *signingExporter) SetSigner(signer conduit.Signer) {
+	e.signer = signer
+}
+
+// TestPipelineSigning tests that the signer is passed to the exporters which
+// support it and that the public key is reported in the status.
+func TestPipelineSigning(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	var pImporter importers.Importer = &mockImporter{genesis: sdk.Genesis{Network: "test"}}
+	var pProcessor processors.Processor = &mockProcessor{}
+	signing := &signingExporter{}
+	var pSigning exporters.Exporter = signing
+	var pPlain exporters.Exporter = &mockExporter{}
+	l, _ := test.NewNullLogger()
+	pImpl := pipelineImpl{
+		cfg: &Config{
+			ConduitArgs: &conduit.Args{ConduitDataDir: t.TempDir()},
+			Processors:  []NameConfigPair{{}},
+			Exporters:   []NameConfigPair{{Name: "mockExporter"}, {Name: "mockExporter"}},
+			Signing:     Signing{KeyFile: writeKeyFile(t, priv)},
+		},
+		logger:     l,
+		importer:   &pImporter,
+		processors: []*processors.Processor{&pProcessor},
+		exporters:  []*exporters.Exporter{&pSigning, &pPlain},
+	}
+	require.NoError(t, pImpl.Init())
+
+	require.NotNil(t, signing.signer)
+	assert.Equal(t, pub, signing.signer.PublicKey())
+	assert.Equal(t, base64.StdEncoding.EncodeToString(pub), pImpl.Status().SigningKey)
+
+	pImpl.cfg.Signing.KeyFile = path.Join(t.TempDir(), "missing.pem")
+	pImpl.signer = nil
+	err = pImpl.Init()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Pipeline.Start(): makeSigner(): unable to read key-file")
+}
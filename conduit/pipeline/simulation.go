@@ -0,0 +1,85 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/algorand/conduit/conduit/data"
+)
+
+// Simulation configs for replaying fixtures, for example with the file_reader
+// importer, as if the blocks arrived in realtime. It is meant for developing
+// and testing realtime exporters and alerting, not for production pipelines.
+type Simulation struct {
+	// RoundTime is the simulated time between blocks, each round is released
+	// RoundTime after the previous one. Zero disables the simulation.
+	RoundTime time.Duration `yaml:"round-time"`
+	// Timestamps replaces the block timestamps with the simulated arrival
+	// time of the round, so that the blocks look recent to the exporters.
+	Timestamps bool `yaml:"timestamps"`
+}
+
+// Valid validates the simulation config.
+func (s Simulation) Valid() error {
+	if s.RoundTime < 0 {
+		return fmt.Errorf("round-time must not be negative (%s)", s.RoundTime)
+	}
+	if s.Timestamps && s.RoundTime == 0 {
+		return fmt.Errorf("timestamps requires a round-time")
+	}
+	return nil
+}
+
+func (s Simulation) enabled() bool {
+	return s.RoundTime > 0
+}
+
+// simClock is the simulated chain clock, the first round arrives when the
+// clock starts and every following round RoundTime later.
+type simClock struct {
+	cfg   Simulation
+	start time.Time
+	first uint64
+}
+
+func makeSimClock(cfg Simulation, first uint64, now time.Time) *simClock {
+	if !cfg.enabled() {
+		return nil
+	}
+	return &simClock{cfg: cfg, start: now, first: first}
+}
+
+// arrival is the simulated time at which the round is available.
+func (c *simClock) arrival(round uint64) time.Time {
+	if round <= c.first {
+		return c.start
+	}
+	return c.start.Add(time.Duration(round-c.first) * c.cfg.RoundTime)
+}
+
+// pace waits until the block arrives on the simulated clock and rewrites its
+// timestamp if configured. Rounds which are behind the clock are released
+// immediately, like blocks which a node already has. It returns false if the
+// pipeline is stopped while waiting.
+func (p *pipelineImpl) pace(blk *data.BlockData) bool {
+	c := p.simClock
+	if c == nil {
+		return true
+	}
+	arrival := c.arrival(blk.Round())
+	if wait := time.Until(arrival); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-p.ctx.Done():
+			return false
+		case <-p.stopCh:
+			return false
+		}
+	}
+	if c.cfg.Timestamps {
+		blk.BlockHeader.TimeStamp = arrival.Unix()
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/data"
+)
+
+func TestSimulationValid(t *testing.T) {
+	assert.NoError(t, Simulation{}.Valid())
+	assert.NoError(t, Simulation{RoundTime: 2800 * time.Millisecond, Timestamps: true}.Valid())
+	assert.EqualError(t, Simulation{RoundTime: -time.Second}.Valid(), "round-time must not be negative (-1s)")
+	assert.EqualError(t, Simulation{Timestamps: true}.Valid(), "timestamps requires a round-time")
+}
+
+func TestSimClockArrival(t *testing.T) {
+	assert.Nil(t, makeSimClock(Simulation{}, 10, time.Now()))
+
+	start := time.Unix(1000, 0)
+	c := makeSimClock(Simulation{RoundTime: 2800 * time.Millisecond}, 10, start)
+	assert.Equal(t, start, c.arrival(5))
+	assert.Equal(t, start, c.arrival(10))
+	assert.Equal(t, start.Add(2800*time.Millisecond), c.arrival(11))
+	assert.Equal(t, start.Add(28*time.Second), c.arrival(20))
+}
+
+func TestPace(t *testing.T) {
+	ctx, cf := context.WithCancel(context.Background())
+	defer cf()
+	start := time.Now().Add(-time.Hour)
+	p := &pipelineImpl{
+		ctx:      ctx,
+		stopCh:   make(chan struct{}),
+		simClock: makeSimClock(Simulation{RoundTime: time.Minute, Timestamps: true}, 100, start),
+	}
+
+	// Rounds behind the simulated clock are released immediately.
+	blk := data.BlockData{BlockHeader: sdk.BlockHeader{Round: 130, TimeStamp: 1}}
+	require.True(t, p.pace(&blk))
+	assert.Equal(t, start.Add(30*time.Minute).Unix(), blk.BlockHeader.TimeStamp)
+
+	// Rounds ahead of it wait until the pipeline stops.
+	blk = data.BlockData{BlockHeader: sdk.BlockHeader{Round: 200}}
+	time.AfterFunc(10*time.Millisecond, func() { close(p.stopCh) })
+	assert.False(t, p.pace(&blk))
+}
+
+// TestPipelineSimulation tests that the rounds are paced by the round time.
+func TestPipelineSimulation(t *testing.T) {
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeReloadPipeline(t, exp)
+	pImpl.cfg.Simulation = Simulation{RoundTime: 20 * time.Millisecond}
+	pImpl.cfg.Rounds.End = 5
+
+	start := time.Now()
+	pImpl.Start()
+	pImpl.Wait()
+	require.NoError(t, pImpl.Error())
+
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5}, exp.received())
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
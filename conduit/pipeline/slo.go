@@ -0,0 +1,156 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+const (
+	defaultSLOTarget = 0.99
+	defaultSLOWindow = time.Hour
+	// sloBuckets is the number of buckets of the sliding window, rounds
+	// leave the window one bucket at a time.
+	sloBuckets = 60
+)
+
+// SLO configs for tracking how many rounds are exported within a latency of
+// their block time, for example 99% of the rounds within 5s.
+type SLO struct {
+	// Latency is the time after the block timestamp by which a round should
+	// be exported, zero disables the tracking.
+	Latency time.Duration `yaml:"latency"`
+	// Target is the fraction of rounds which should be exported in time,
+	// 0.99 by default.
+	Target float64 `yaml:"target"`
+	// Window is the period over which the fraction is measured, 1h by default.
+	Window time.Duration `yaml:"window"`
+}
+
+// Valid validates the SLO config.
+func (s SLO) Valid() error {
+	if s.Latency < 0 {
+		return fmt.Errorf("latency must not be negative (%s)", s.Latency)
+	}
+	if s.Target < 0 || s.Target >= 1 {
+		return fmt.Errorf("target must be at least 0 and less than 1, found %v", s.Target)
+	}
+	if s.Window < 0 {
+		return fmt.Errorf("window must not be negative (%s)", s.Window)
+	}
+	return nil
+}
+
+// SLOStatus is the state of the latency SLO over its window.
+type SLOStatus struct {
+	Target  float64       `json:"target"`
+	Latency time.Duration `json:"latency"`
+	Window  time.Duration `json:"window"`
+	// Rounds and Late are the exported rounds in the window, and how many of
+	// them were exported after the latency.
+	Rounds uint64 `json:"rounds"`
+	Late   uint64 `json:"late"`
+	// BurnRate is how fast the error budget is spent, 1 spends exactly the
+	// budget over the window and above 1 the target is missed.
+	BurnRate float64 `json:"burn-rate"`
+	// BudgetRemaining is the fraction of the error budget which is left, it
+	// is negative once the target is missed.
+	BudgetRemaining float64 `json:"budget-remaining"`
+}
+
+// sloBucket counts the rounds exported in a slice of the window.
+type sloBucket struct {
+	start  time.Time
+	rounds uint64
+	late   uint64
+}
+
+// sloTracker measures the latency SLO over a sliding window.
+type sloTracker struct {
+	cfg     SLO
+	bucket  time.Duration
+	mu      sync.Mutex
+	buckets []sloBucket
+}
+
+// makeSLOTracker returns a tracker for the config, or nil if it is disabled.
+func makeSLOTracker(cfg SLO) *sloTracker {
+	if cfg.Latency == 0 {
+		return nil
+	}
+	if cfg.Target == 0 {
+		cfg.Target = defaultSLOTarget
+	}
+	if cfg.Window == 0 {
+		cfg.Window = defaultSLOWindow
+	}
+	return &sloTracker{cfg: cfg, bucket: cfg.Window / sloBuckets}
+}
+
+// expire drops the buckets which left the window.
+func (t *sloTracker) expire(now time.Time) {
+	cutoff := now.Add(-t.cfg.Window)
+	idx := 0
+	for idx < len(t.buckets) && !t.buckets[idx].start.After(cutoff) {
+		idx++
+	}
+	t.buckets = t.buckets[idx:]
+}
+
+// record counts a round exported at now, whose block has roundTime.
+func (t *sloTracker) record(roundTime, now time.Time) {
+	late := now.Sub(roundTime) > t.cfg.Latency
+	if late {
+		metrics.SLORounds.WithLabelValues("late").Inc()
+	} else {
+		metrics.SLORounds.WithLabelValues("in_time").Inc()
+	}
+
+	t.mu.Lock()
+	t.expire(now)
+	start := now.Truncate(t.bucket)
+	if n := len(t.buckets); n == 0 || t.buckets[n-1].start != start {
+		t.buckets = append(t.buckets, sloBucket{start: start})
+	}
+	current := &t.buckets[len(t.buckets)-1]
+	current.rounds++
+	if late {
+		current.late++
+	}
+	status := t.statusLocked()
+	t.mu.Unlock()
+
+	metrics.SLOBurnRate.Set(status.BurnRate)
+	metrics.SLOBudgetRemaining.Set(status.BudgetRemaining)
+}
+
+// status returns the state of the SLO at now.
+func (t *sloTracker) status(now time.Time) SLOStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expire(now)
+	return t.statusLocked()
+}
+
+func (t *sloTracker) statusLocked() SLOStatus {
+	status := SLOStatus{
+		Target:          t.cfg.Target,
+		Latency:         t.cfg.Latency,
+		Window:          t.cfg.Window,
+		BudgetRemaining: 1,
+	}
+	for _, b := range t.buckets {
+		status.Rounds += b.rounds
+		status.Late += b.late
+	}
+	if status.Rounds == 0 {
+		return status
+	}
+	lateRatio := float64(status.Late) / float64(status.Rounds)
+	budget := 1 - t.cfg.Target
+	status.BurnRate = lateRatio / budget
+	status.BudgetRemaining = 1 - status.BurnRate
+	return status
+}
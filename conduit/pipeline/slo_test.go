@@ -0,0 +1,64 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+func TestSLOValid(t *testing.T) {
+	assert.NoError(t, SLO{}.Valid())
+	assert.NoError(t, SLO{Latency: 5 * time.Second, Target: 0.999, Window: time.Hour}.Valid())
+	assert.EqualError(t, SLO{Latency: -time.Second}.Valid(), "latency must not be negative (-1s)")
+	assert.EqualError(t, SLO{Target: 1}.Valid(), "target must be at least 0 and less than 1, found 1")
+	assert.EqualError(t, SLO{Window: -time.Second}.Valid(), "window must not be negative (-1s)")
+}
+
+// TestSLOTracker tests the burn rate and remaining budget as late rounds enter
+// and leave the window.
+func TestSLOTracker(t *testing.T) {
+	metrics.RegisterPrometheusMetrics("slo_test")
+	assert.Nil(t, makeSLOTracker(SLO{}))
+	tracker := makeSLOTracker(SLO{Latency: 5 * time.Second, Target: 0.9})
+	require.NotNil(t, tracker)
+	assert.Equal(t, defaultSLOWindow, tracker.cfg.Window)
+
+	start := time.Unix(1000000, 0)
+	assert.Equal(t, SLOStatus{Target: 0.9, Latency: 5 * time.Second, Window: time.Hour, BudgetRemaining: 1}, tracker.status(start))
+
+	// 18 rounds in time and 2 late rounds spend the whole budget.
+	for i := 0; i < 18; i++ {
+		now := start.Add(time.Duration(i) * time.Second)
+		tracker.record(now.Add(-5*time.Second), now)
+	}
+	tracker.record(start, start.Add(6*time.Second))
+	status := tracker.status(start.Add(20 * time.Second))
+	assert.Equal(t, uint64(19), status.Rounds)
+	assert.Equal(t, uint64(1), status.Late)
+	assert.InDelta(t, 0.526, status.BurnRate, 0.001)
+	assert.InDelta(t, 0.474, status.BudgetRemaining, 0.001)
+
+	later := start.Add(30 * time.Minute)
+	tracker.record(later.Add(-time.Minute), later)
+	status = tracker.status(later)
+	assert.InDelta(t, 1, status.BurnRate, 0.001)
+	assert.InDelta(t, 0, status.BudgetRemaining, 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(metrics.SLOBurnRate), 0.001)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.SLORounds.WithLabelValues("late")))
+	assert.Equal(t, float64(18), testutil.ToFloat64(metrics.SLORounds.WithLabelValues("in_time")))
+
+	// the first rounds left the window, only the last late round is left.
+	status = tracker.status(start.Add(time.Hour + time.Minute))
+	assert.Equal(t, uint64(1), status.Rounds)
+	assert.InDelta(t, 10, status.BurnRate, 0.001)
+	assert.InDelta(t, -9, status.BudgetRemaining, 0.001)
+
+	status = tracker.status(later.Add(2 * time.Hour))
+	assert.Equal(t, uint64(0), status.Rounds)
+	assert.Equal(t, float64(1), status.BudgetRemaining)
+}
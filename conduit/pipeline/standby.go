@@ -0,0 +1,248 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+// defaultStandbyHealthCheckInterval is how often the standby exporter is
+// health checked when health-check-interval is not set.
+const defaultStandbyHealthCheckInterval = time.Minute
+
+// Standby configs a hot standby exporter: it is initialized and health checked
+// but receives no rounds until it is promoted, which demotes the primary.
+type Standby struct {
+	// Primary is the name of the exporter which receives the rounds.
+	Primary string `yaml:"primary"`
+	// Standby is the name of the exporter which is promoted on request.
+	Standby string `yaml:"standby"`
+	// HealthCheckInterval is how often the standby is health checked, the
+	// default is one minute.
+	HealthCheckInterval time.Duration `yaml:"health-check-interval"`
+}
+
+// Valid validates the standby config.
+func (s Standby) Valid(exporterNames map[string]bool, migration Migration) error {
+	if !s.enabled() {
+		return nil
+	}
+	if !exporterNames[s.Primary] {
+		return fmt.Errorf("primary exporter (%s) is not configured", s.Primary)
+	}
+	if !exporterNames[s.Standby] {
+		return fmt.Errorf("standby exporter (%s) is not configured", s.Standby)
+	}
+	if s.Primary == s.Standby {
+		return fmt.Errorf("primary and standby must be different exporters")
+	}
+	if s.HealthCheckInterval < 0 {
+		return fmt.Errorf("health-check-interval must not be negative")
+	}
+	for _, name := range []string{s.Primary, s.Standby} {
+		if migration.enabled() && (name == migration.From || name == migration.To) {
+			return fmt.Errorf("exporter (%s) cannot be both in the standby and the migration", name)
+		}
+	}
+	return nil
+}
+
+func (s Standby) enabled() bool {
+	return s.Primary != "" || s.Standby != ""
+}
+
+func (s Standby) healthCheckInterval() time.Duration {
+	if s.HealthCheckInterval == 0 {
+		return defaultStandbyHealthCheckInterval
+	}
+	return s.HealthCheckInterval
+}
+
+// StandbyStatus is the role of the standby exporters, it is saved with the
+// pipeline metadata so that a promotion survives restarts, and reported by
+// the /status endpoint.
+type StandbyStatus struct {
+	// Primary is the exporter which receives the rounds, and Standby the one
+	// which is promoted next. They are swapped by each promotion.
+	Primary string `json:"primary"`
+	Standby string `json:"standby"`
+	// Healthy is the result of the last health check of the standby, there
+	// was no check since the last promotion when LastCheck is not set.
+	Healthy     bool      `json:"healthy"`
+	LastCheck   time.Time `json:"last-check,omitempty"`
+	HealthError string    `json:"health-error,omitempty"`
+	// Promotions is the number of promotions, PromotedRound is the first
+	// round exported by the primary after the last one.
+	Promotions    uint64 `json:"promotions,omitempty"`
+	PromotedRound uint64 `json:"promoted-round,omitempty"`
+}
+
+// standbyRun is the runtime state of the standby, it is guarded by p.mu.
+type standbyRun struct {
+	// primary and standby are the exporter indexes.
+	primary, standby int
+	lastCheck        time.Time
+}
+
+// initStandby resolves the configured standby, the roles saved with the
+// pipeline metadata are kept if they are the configured exporters. It is
+// called once the pipeline metadata is loaded.
+func (p *pipelineImpl) initStandby() error {
+	p.standby = nil
+	s := p.cfg.Standby
+	if !s.enabled() {
+		if p.pipelineMetadata.Standby != nil {
+			p.logger.Infof("Standby exporter %s is no longer configured", p.pipelineMetadata.Standby.Standby)
+			p.pipelineMetadata.Standby = nil
+		}
+		return nil
+	}
+	indexes := make(map[string]int)
+	for idx, id := range p.cfg.exporterIDs() {
+		indexes[id] = idx
+	}
+	primary, okPrimary := indexes[s.Primary]
+	standby, okStandby := indexes[s.Standby]
+	if !okPrimary || !okStandby {
+		return fmt.Errorf("initStandby(): exporters %s and %s must both be configured", s.Primary, s.Standby)
+	}
+
+	status := p.pipelineMetadata.Standby
+	switch {
+	case status != nil && status.Primary == s.Primary && status.Standby == s.Standby:
+	case status != nil && status.Primary == s.Standby && status.Standby == s.Primary:
+		p.logger.Infof("Exporter (%s) was promoted at round %d, it stays the primary", status.Primary, status.PromotedRound)
+		primary, standby = standby, primary
+	default:
+		status = &StandbyStatus{Primary: s.Primary, Standby: s.Standby}
+	}
+	p.pipelineMetadata.Standby = status
+	p.standby = &standbyRun{primary: primary, standby: standby}
+	return nil
+}
+
+// isStandby reports whether the exporter at idx is the standby, it receives
+// no rounds.
+func (p *pipelineImpl) isStandby(idx int) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.standby != nil && p.standby.standby == idx
+}
+
+// healthCheck calls the conduit.HealthChecker and conduit.SchemaVerifier
+// hooks of the exporter at idx.
+func (p *pipelineImpl) healthCheck(idx int) error {
+	exporter := *p.exporters[idx]
+	if checker, ok := exporter.(conduit.HealthChecker); ok {
+		if err := checker.HealthCheck(); err != nil {
+			return err
+		}
+	}
+	if verifier, ok := exporter.(conduit.SchemaVerifier); ok {
+		if err := verifier.VerifySchema(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkStandby health checks the standby if health-check-interval has elapsed
+// since the last check. A failed check is logged and reported by /status, it
+// does not stop the pipeline.
+func (p *pipelineImpl) checkStandby(now time.Time) {
+	if p.standby == nil || now.Sub(p.standby.lastCheck) < p.cfg.Standby.healthCheckInterval() {
+		return
+	}
+	p.recordStandbyHealth(p.healthCheck(p.standby.standby), now)
+}
+
+func (p *pipelineImpl) recordStandbyHealth(err error, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := p.pipelineMetadata.Standby
+	p.standby.lastCheck = now
+	status.LastCheck = now
+	status.Healthy = err == nil
+	status.HealthError = ""
+	if err != nil {
+		status.HealthError = err.Error()
+		p.logger.Warnf("Standby exporter (%s) failed its health check: %v", status.Standby, err)
+	}
+}
+
+// Promote waits for the in-flight round, then makes the standby exporter the
+// primary and demotes the primary to the standby. The primary is committed
+// first, and the standby must pass its health check.
+func (p *pipelineImpl) Promote() error {
+	p.mu.RLock()
+	loopDone := p.loopDone
+	standby := p.standby
+	p.mu.RUnlock()
+	if standby == nil {
+		return fmt.Errorf("Promote(): no standby is configured")
+	}
+	if loopDone == nil {
+		return fmt.Errorf("Promote(): pipeline is not running")
+	}
+
+	result := make(chan error, 1)
+	select {
+	case p.promoteCh <- result:
+	case <-loopDone:
+		return fmt.Errorf("Promote(): pipeline is not running")
+	}
+	return <-result
+}
+
+// promote is called by the pipeline loop between rounds.
+func (p *pipelineImpl) promote() error {
+	// The rounds received by the primary are made durable before it is
+	// demoted, and saved as exported.
+	if result := p.checkpoint(); result.err != nil {
+		return fmt.Errorf("Promote(): %w", result.err)
+	}
+	round := p.pipelineMetadata.NextRound
+	err := p.healthCheck(p.standby.standby)
+	p.recordStandbyHealth(err, time.Now())
+	status := p.pipelineMetadata.Standby
+	if err != nil {
+		return fmt.Errorf("Promote(): standby exporter (%s) is not healthy: %w", status.Standby, err)
+	}
+	if provider, ok := (*p.exporters[p.standby.standby]).(conduit.RoundProvider); ok {
+		next, err := provider.NextRound()
+		if err != nil {
+			return fmt.Errorf("Promote(): standby exporter (%s) could not provide the next round: %w", status.Standby, err)
+		}
+		if next != round {
+			return fmt.Errorf("Promote(): standby exporter (%s) next round %d does not match next round %d", status.Standby, next, round)
+		}
+	}
+
+	p.mu.Lock()
+	p.standby.primary, p.standby.standby = p.standby.standby, p.standby.primary
+	status.Primary, status.Standby = status.Standby, status.Primary
+	status.Promotions++
+	status.PromotedRound = round
+	// The demoted primary is health checked before the next round.
+	p.standby.lastCheck = time.Time{}
+	status.LastCheck = time.Time{}
+	status.Healthy = false
+	status.HealthError = ""
+	p.mu.Unlock()
+	p.logger.Infof("Promoted standby exporter (%s) at round %d, exporter (%s) is now the standby", status.Primary, round, status.Standby)
+	if err := p.saveMetadata(); err != nil {
+		return fmt.Errorf("Promote(): %w", err)
+	}
+	return nil
+}
+
+// standbyStatus returns a copy of the standby roles, it must be called with
+// p.mu held.
+func (p *pipelineImpl) standbyStatus() *StandbyStatus {
+	if p.standby == nil || p.pipelineMetadata.Standby == nil {
+		return nil
+	}
+	status := *p.pipelineMetadata.Standby
+	return &status
+}
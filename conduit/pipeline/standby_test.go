@@ -0,0 +1,129 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// healthExporter implements conduit.HealthChecker, it is healthy unless err is set.
+type healthExporter struct {
+	roundExporter
+	healthMu sync.Mutex
+	err      error
+	checks   int
+}
+
+func (e *healthExporter) HealthCheck() error {
+	e.healthMu.Lock()
+	defer e.healthMu.Unlock()
+	e.checks++
+	return e.err
+}
+
+func TestStandbyValid(t *testing.T) {
+	names := map[string]bool{"primary": true, "standby": true, "new": true}
+	tests := []struct {
+		name      string
+		standby   Standby
+		migration Migration
+		err       string
+	}{
+		{"disabled", Standby{}, Migration{}, ""},
+		{"valid", Standby{Primary: "primary", Standby: "standby", HealthCheckInterval: time.Second}, Migration{}, ""},
+		{"unknown primary", Standby{Primary: "other", Standby: "standby"}, Migration{}, "primary exporter (other) is not configured"},
+		{"unknown standby", Standby{Primary: "primary", Standby: "other"}, Migration{}, "standby exporter (other) is not configured"},
+		{"same exporter", Standby{Primary: "primary", Standby: "primary"}, Migration{}, "primary and standby must be different exporters"},
+		{"negative interval", Standby{Primary: "primary", Standby: "standby", HealthCheckInterval: -time.Second}, Migration{}, "health-check-interval must not be negative"},
+		{"migration", Standby{Primary: "primary", Standby: "standby"}, Migration{From: "standby", To: "new", Window: 10}, "exporter (standby) cannot be both in the standby and the migration"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.standby.Valid(names, tc.migration)
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+// TestPipelinePromote tests that a promotion swaps the exporters at a round
+// boundary, and that the roles are kept after a restart.
+func TestPipelinePromote(t *testing.T) {
+	pImpl := makeCheckpointPipeline(t, &roundExporter{name: "primary"})
+	assert.EqualError(t, pImpl.Promote(), "Promote(): no standby is configured")
+
+	primary := &healthExporter{roundExporter: roundExporter{name: "primary"}}
+	standby := &healthExporter{roundExporter: roundExporter{name: "standby"}}
+	pImpl = makeCheckpointPipeline(t, primary, standby)
+	pImpl.cfg.Standby = Standby{Primary: "primary", Standby: "standby", HealthCheckInterval: time.Hour}
+	require.NoError(t, pImpl.initStandby())
+	assert.EqualError(t, pImpl.Promote(), "Promote(): pipeline is not running")
+
+	pImpl.Start()
+	require.Eventually(t, func() bool { return len(primary.received()) >= 3 }, 5*time.Second, time.Millisecond)
+	require.NoError(t, pImpl.Promote())
+	status := pImpl.Status().Standby
+	require.NotNil(t, status)
+	assert.Equal(t, "standby", status.Primary)
+	assert.Equal(t, "primary", status.Standby)
+	assert.Equal(t, uint64(1), status.Promotions)
+	require.Eventually(t, func() bool { return len(standby.received()) >= 3 }, 5*time.Second, time.Millisecond)
+	assert.True(t, pImpl.Status().Standby.Healthy, "the demoted primary is checked")
+	pImpl.cf()
+	pImpl.Wait()
+
+	// Each round is exported by exactly one of the exporters.
+	promoted := status.PromotedRound
+	var before, after []uint64
+	for round := uint64(0); round < promoted; round++ {
+		before = append(before, round)
+	}
+	for round := promoted; round < promoted+uint64(len(standby.received())); round++ {
+		after = append(after, round)
+	}
+	assert.Equal(t, before, primary.received())
+	assert.Equal(t, after, standby.received())
+	assert.Equal(t, 2, standby.checks, "the standby is checked at the first round and before the promotion")
+	assert.Equal(t, 1, primary.checks)
+
+	saved := readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir).Standby
+	require.NotNil(t, saved)
+	assert.Equal(t, "standby", saved.Primary)
+	require.NoError(t, pImpl.initStandby())
+	assert.True(t, pImpl.isStandby(0), "the promotion is kept after a restart")
+}
+
+// TestPipelinePromoteUnhealthy tests that an unhealthy standby is not promoted.
+func TestPipelinePromoteUnhealthy(t *testing.T) {
+	primary := &roundExporter{name: "primary"}
+	standby := &healthExporter{roundExporter: roundExporter{name: "standby"}, err: fmt.Errorf("unreachable")}
+	pImpl := makeCheckpointPipeline(t, primary, standby)
+	pImpl.cfg.Standby = Standby{Primary: "primary", Standby: "standby"}
+	require.NoError(t, pImpl.initStandby())
+
+	pImpl.Start()
+	defer func() {
+		pImpl.cf()
+		pImpl.Wait()
+	}()
+	require.Eventually(t, func() bool {
+		status := pImpl.Status().Standby
+		return status != nil && !status.LastCheck.IsZero()
+	}, 5*time.Second, time.Millisecond)
+	status := pImpl.Status().Standby
+	assert.False(t, status.Healthy)
+	assert.Equal(t, "unreachable", status.HealthError)
+
+	assert.EqualError(t, pImpl.Promote(), "Promote(): standby exporter (standby) is not healthy: unreachable")
+	assert.Equal(t, "primary", pImpl.Status().Standby.Primary)
+	received := len(primary.received())
+	require.Eventually(t, func() bool { return len(primary.received()) > received }, 5*time.Second, time.Millisecond)
+	assert.Empty(t, standby.received())
+}
@@ -0,0 +1,316 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Status is a point in time snapshot of the pipeline state.
+type Status struct {
+	Running           bool      `json:"running"`
+	NextRound         uint64    `json:"next-round"`
+	LastExportedRound *uint64   `json:"last-exported-round,omitempty"`
+	LastExportTime    time.Time `json:"last-export-time,omitempty"`
+	LastRoundTime     time.Time `json:"last-round-time,omitempty"`
+	LastError         string    `json:"last-error,omitempty"`
+	RetryCount        uint64    `json:"retry-count"`
+	Paused            bool      `json:"paused"`
+	PauseReason       string    `json:"pause-reason,omitempty"`
+	LastCheckpoint    time.Time `json:"last-checkpoint,omitempty"`
+	// LatestRound is the latest round reported by the importer, it is only
+	// requested for the throttle lag-rounds.
+	LatestRound uint64 `json:"latest-round,omitempty"`
+	// ConfigDrift is set while the config file differs from the running
+	// config, it is only checked with config-drift.
+	ConfigDrift bool `json:"config-drift,omitempty"`
+	// WarmingUp is set while plugins warm up before the first round.
+	WarmingUp bool `json:"warming-up,omitempty"`
+	// WatchdogRestarts is the number of times the watchdog restarted a
+	// stalled pipeline loop.
+	WatchdogRestarts int `json:"watchdog-restarts,omitempty"`
+	// AuthFailure is the plugin whose credentials were rejected by the last
+	// error, such as "importer (algod)".
+	AuthFailure string `json:"auth-failure,omitempty"`
+	// SigningKey is the base64 ed25519 public key which verifies the exporter output.
+	SigningKey string   `json:"signing-key,omitempty"`
+	Importer   string   `json:"importer"`
+	Processors []string `json:"processors"`
+	Exporters  []string `json:"exporters"`
+	// Migration is the progress of the exporter migration, if one is configured.
+	Migration *MigrationStatus `json:"migration,omitempty"`
+	// Standby is the role and health of the standby exporters, if one is configured.
+	Standby *StandbyStatus `json:"standby,omitempty"`
+	// SLO is the state of the latency SLO, if one is configured.
+	SLO *SLOStatus `json:"slo,omitempty"`
+	// Recovery is the report of the state the pipeline resumed from at startup.
+	Recovery *RecoveryReport `json:"recovery,omitempty"`
+}
+
+// Ready reports whether the pipeline is running, warm and the last round succeeded.
+func (s Status) Ready() bool {
+	return s.Running && !s.WarmingUp && s.LastError == ""
+}
+
+// Status returns a snapshot of the pipeline state.
+func (p *pipelineImpl) Status() Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := p.status
+	if p.err != nil {
+		status.LastError = p.err.Error()
+	}
+	status.AuthFailure = p.authFailure
+	if p.control.paused && !status.Paused {
+		status.Paused = true
+		status.PauseReason = operatorPauseReason
+	}
+	status.SigningKey = p.signingKey()
+	status.Migration = p.migrationStatus()
+	status.Standby = p.standbyStatus()
+	status.WatchdogRestarts = p.watchdog.restartCount()
+	if p.slo != nil {
+		slo := p.slo.status(time.Now())
+		status.SLO = &slo
+	}
+	if p.importer != nil {
+		status.Importer = p.importerName()
+	}
+	status.Processors = make([]string, 0, len(p.processors))
+	for idx := range p.processors {
+		status.Processors = append(status.Processors, p.processorName(idx))
+	}
+	status.Exporters = make([]string, 0, len(p.exporters))
+	for idx := range p.exporters {
+		status.Exporters = append(status.Exporters, p.exporterName(idx))
+	}
+	return status
+}
+
+func (p *pipelineImpl) setRunning(running bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.Running = running
+}
+
+func (p *pipelineImpl) setRetryCount(retry uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.RetryCount = retry
+}
+
+// setPaused records why the pipeline is paused, an empty reason resumes it.
+func (p *pipelineImpl) setPaused(reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.Paused = reason != ""
+	p.status.PauseReason = reason
+}
+
+// setRoundExported records a successful export. nextRound is the round which will be fetched next.
+func (p *pipelineImpl) setRoundExported(round, nextRound uint64, roundTime time.Time) {
+	now := time.Now()
+	p.mu.Lock()
+	p.status.LastExportedRound = &round
+	p.status.LastExportTime = now
+	p.status.LastRoundTime = roundTime
+	p.status.NextRound = nextRound
+	p.mu.Unlock()
+	if p.slo != nil && roundTime.Unix() > 0 {
+		p.slo.record(roundTime, now)
+	}
+	p.events.publishRoundExported(RoundExported{Round: round, RoundTime: roundTime, ExportTime: now})
+	p.publishLag()
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// registerAPIHandlers adds the /health, /ready, /status, /activity,
+// /checkpoint, /pause, /resume, /step, /cutover, /promote, /rollback and
+// /debug/round endpoints to mux, and the pprof endpoints with profiling serve-pprof.
+func (p *pipelineImpl) registerAPIHandlers(mux *http.ServeMux) {
+	if p.cfg.Profiling.ServePprof {
+		registerPprofHandlers(mux)
+	}
+	// health: the pipeline goroutine is alive.
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		status := p.Status()
+		code := http.StatusOK
+		if !status.Running {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, map[string]bool{"running": status.Running})
+	})
+	// ready: the pipeline is alive and the most recent round was successful.
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		status := p.Status()
+		code := http.StatusOK
+		if !status.Ready() {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, map[string]bool{"ready": status.Ready()})
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, p.Status())
+	})
+	// checkpoint: flush the exporters and save the pipeline metadata.
+	mux.HandleFunc("/checkpoint", func(w http.ResponseWriter, r *http.Request) {
+		if !requirePost(w, r) {
+			return
+		}
+		round, err := p.Checkpoint()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]uint64{"next-round": round})
+	})
+	// pause, resume and step: operator control of the pipeline loop.
+	mux.HandleFunc("/pause", func(w http.ResponseWriter, r *http.Request) {
+		if !requirePost(w, r) {
+			return
+		}
+		p.Pause()
+		writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+	})
+	mux.HandleFunc("/resume", func(w http.ResponseWriter, r *http.Request) {
+		if !requirePost(w, r) {
+			return
+		}
+		p.Resume()
+		writeJSON(w, http.StatusOK, map[string]bool{"paused": false})
+	})
+	mux.HandleFunc("/step", func(w http.ResponseWriter, r *http.Request) {
+		if !requirePost(w, r) {
+			return
+		}
+		rounds := uint64(1)
+		if v := r.URL.Query().Get("rounds"); v != "" {
+			var err error
+			if rounds, err = strconv.ParseUint(v, 10, 64); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid rounds: %s", v)})
+				return
+			}
+		}
+		if err := p.Step(rounds); err != nil {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]uint64{"rounds": rounds})
+	})
+	// cutover: retire the from exporter of a verified migration.
+	mux.HandleFunc("/cutover", func(w http.ResponseWriter, r *http.Request) {
+		if !requirePost(w, r) {
+			return
+		}
+		if err := p.CutOver(); err != nil {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"cutover": true})
+	})
+	// promote: swap the standby and primary exporters.
+	mux.HandleFunc("/promote", func(w http.ResponseWriter, r *http.Request) {
+		if !requirePost(w, r) {
+			return
+		}
+		if err := p.Promote(); err != nil {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, p.Status().Standby)
+	})
+	// rollback: rewind the plugins and resume from an earlier round.
+	mux.HandleFunc("/rollback", func(w http.ResponseWriter, r *http.Request) {
+		if !requirePost(w, r) {
+			return
+		}
+		v := r.URL.Query().Get("round")
+		round, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid round: %s", v)})
+			return
+		}
+		if err := p.RollbackRound(round); err != nil {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]uint64{"next-round": round})
+	})
+	// activity: the plugin calls in progress and the buffer occupancy.
+	mux.HandleFunc("/activity", p.handleActivity)
+	// debug/round: run the processors again on a past round.
+	mux.HandleFunc("/debug/round", p.handleDebugRound)
+}
+
+// requirePost rejects requests which are not POST, it returns false if the
+// request was rejected.
+func requirePost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodPost {
+		return true
+	}
+	w.Header().Set("Allow", http.MethodPost)
+	writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "use POST"})
+	return false
+}
+
+// startAPIServer serves the status API on its own address until the pipeline context is cancelled.
+func (p *pipelineImpl) startAPIServer() {
+	mux := http.NewServeMux()
+	p.registerAPIHandlers(mux)
+	srv := &http.Server{Addr: p.cfg.API.Addr, Handler: mux}
+	go func() {
+		<-p.ctx.Done()
+		ctx, cf := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cf()
+		_ = srv.Shutdown(ctx)
+	}()
+	p.logger.Infof("conduit status API serving on %s", p.cfg.API.Addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		p.logger.Errorf("conduit status API server error: %v", err)
+	}
+}
+
+// progressInterval is how often the console progress line is logged.
+const progressInterval = 10 * time.Second
+
+// progressLine summarizes throughput and lag between two status snapshots.
+func progressLine(prev, cur Status, elapsed time.Duration, now time.Time) string {
+	rounds := cur.NextRound - prev.NextRound
+	line := fmt.Sprintf("progress: next round %d, %.2f rounds/s", cur.NextRound, float64(rounds)/elapsed.Seconds())
+	if !cur.LastRoundTime.IsZero() {
+		line += fmt.Sprintf(", lag %s", now.Sub(cur.LastRoundTime).Round(time.Second))
+	}
+	return line
+}
+
+// reportProgress periodically logs throughput and lag until the pipeline stops.
+func (p *pipelineImpl) reportProgress(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	prev := p.Status()
+	last := time.Now()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-p.stopCh:
+			return
+		case now := <-ticker.C:
+			cur := p.Status()
+			if !cur.Running {
+				return
+			}
+			p.logger.Info(progressLine(prev, cur, now.Sub(last), now))
+			prev, last = cur, now
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// TestStatusAPI tests the /health, /ready and /status endpoints.
+func TestStatusAPI(t *testing.T) {
+	mImporter := mockImporter{}
+	mImporter.On("GetBlock", mock.Anything).Return(uniqueBlockData, nil)
+	mProcessor := mockProcessor{}
+	mProcessor.On("Process", mock.Anything).Return(uniqueBlockData)
+	mExporter := mockExporter{}
+	mExporter.On("Receive", mock.Anything).Return(nil)
+
+	var pImporter importers.Importer = &mImporter
+	var pProcessor processors.Processor = &mProcessor
+	var pExporter exporters.Exporter = &mExporter
+
+	ctx, cf := context.WithCancel(context.Background())
+	l, _ := test.NewNullLogger()
+	pImpl := pipelineImpl{
+		ctx:        ctx,
+		cf:         cf,
+		logger:     l,
+		importer:   &pImporter,
+		processors: []*processors.Processor{&pProcessor},
+		exporters:  []*exporters.Exporter{&pExporter},
+		cfg: &Config{
+			RetryDelay: 0,
+			RetryCount: 10,
+			ConduitArgs: &conduit.Args{
+				ConduitDataDir: t.TempDir(),
+			},
+			Importer:   NameConfigPair{Name: "mockImporter"},
+			Processors: []NameConfigPair{{Name: "mockProcessor"}},
+			Exporter:   NameConfigPair{Name: "mockExporter"},
+		},
+	}
+
+	mux := http.NewServeMux()
+	pImpl.registerAPIHandlers(mux)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// Not started yet.
+	assert.Equal(t, http.StatusServiceUnavailable, get("/health").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/ready").Code)
+
+	pImpl.Start()
+	require.Eventually(t, func() bool { return pImpl.Status().LastExportedRound != nil }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusOK, get("/health").Code)
+	assert.Equal(t, http.StatusOK, get("/ready").Code)
+
+	rec := get("/status")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var status Status
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.True(t, status.Running)
+	assert.Equal(t, "mockImporter", status.Importer)
+	assert.Equal(t, []string{"mockProcessor"}, status.Processors)
+	assert.Equal(t, []string{"mockExporter"}, status.Exporters)
+
+	cf()
+	pImpl.Wait()
+	assert.False(t, pImpl.Status().Running)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/health").Code)
+
+	pImpl.setError(fmt.Errorf("exporter"))
+	assert.Equal(t, "exporter", pImpl.Status().LastError)
+}
+
+// TestProgressLine tests the throughput and lag summary.
+func TestProgressLine(t *testing.T) {
+	now := time.Now()
+	prev := Status{NextRound: 100}
+	cur := Status{NextRound: 120}
+	assert.Equal(t, "progress: next round 120, 2.00 rounds/s", progressLine(prev, cur, 10*time.Second, now))
+
+	cur.LastRoundTime = now.Add(-90 * time.Second)
+	assert.Equal(t, "progress: next round 120, 2.00 rounds/s, lag 1m30s", progressLine(prev, cur, 10*time.Second, now))
+}
@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// startSubscriber returns a prefetcher which delivers the blocks pushed by a
+// subscribing importer, beginning at nextRound and ending at end, or
+// indefinitely if end is 0. When the subscription fails the failure is
+// reported to the consumer, and after retryDelay the importer is subscribed
+// again from the first round which was not delivered.
+func startSubscriber(ctx context.Context, importer importers.SubscribingImporter, nextRound, end, size uint64, retryDelay time.Duration) *prefetcher {
+	ctx, cf := context.WithCancel(ctx)
+	if size == 0 {
+		size = 1
+	}
+	p := &prefetcher{
+		retryDelay: retryDelay,
+		end:        end,
+		results:    make(chan fetchResult, size),
+		cf:         cf,
+		cancelled:  ctx.Done(),
+		done:       make(chan struct{}),
+	}
+	go p.subscribe(ctx, importer, nextRound)
+	return p
+}
+
+// subscribing reports whether the importer pushes its blocks.
+func (p *pipelineImpl) subscribing() bool {
+	_, ok := (*p.importer).(importers.SubscribingImporter)
+	return ok
+}
+
+func (p *prefetcher) subscribe(ctx context.Context, importer importers.SubscribingImporter, rnd uint64) {
+	defer close(p.done)
+	for {
+		err := p.receive(ctx, importer, &rnd)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case p.results <- fetchResult{round: rnd, start: time.Now(), err: err}:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.retryDelay):
+		}
+	}
+}
+
+// receive delivers the blocks of a single subscription starting at rnd, which
+// is advanced past each delivered block. It returns nil once the end round was
+// delivered, otherwise the reason the subscription ended.
+func (p *prefetcher) receive(ctx context.Context, importer importers.SubscribingImporter, rnd *uint64) error {
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	blocks, errs := importer.Subscribe(subCtx, *rnd)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err, ok := <-errs:
+			if !ok {
+				// Keep receiving blocks, the block channel reports the end.
+				errs = nil
+				continue
+			}
+			if err == nil {
+				continue
+			}
+			return fmt.Errorf("subscription at round %d failed: %w", *rnd, err)
+		case blk, ok := <-blocks:
+			if !ok {
+				select {
+				case err := <-errs:
+					if err != nil {
+						return fmt.Errorf("subscription at round %d failed: %w", *rnd, err)
+					}
+				default:
+				}
+				return fmt.Errorf("subscription at round %d was closed", *rnd)
+			}
+			if blk.Round() < *rnd {
+				// The round was already delivered.
+				continue
+			}
+			if blk.Round() > *rnd {
+				return fmt.Errorf("subscription skipped from round %d to round %d", *rnd, blk.Round())
+			}
+			result := fetchResult{round: *rnd, blk: blk, start: start, importTime: time.Since(start)}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case p.results <- result:
+			}
+			*rnd++
+			if p.end != 0 && *rnd > p.end {
+				return nil
+			}
+			start = time.Now()
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// subscribingImporter implements importers.SubscribingImporter, it pushes
+// consecutive rounds from the start round, repeating the round before it, and
+// fails once at failRound.
+type subscribingImporter struct {
+	namedImporter
+	mu        sync.Mutex
+	starts    []uint64
+	failRound uint64
+	failed    bool
+}
+
+func (s *subscribingImporter) Subscribe(ctx context.Context, startRound uint64) (<-chan data.BlockData, <-chan error) {
+	s.mu.Lock()
+	s.starts = append(s.starts, startRound)
+	s.mu.Unlock()
+
+	blocks := make(chan data.BlockData)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(blocks)
+		rnd := startRound
+		if rnd > 0 {
+			rnd--
+		}
+		for ; ; rnd++ {
+			s.mu.Lock()
+			fail := rnd == s.failRound && !s.failed
+			s.failed = s.failed || fail
+			s.mu.Unlock()
+			if fail {
+				errs <- fmt.Errorf("subscription")
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case blocks <- data.BlockData{BlockHeader: sdk.BlockHeader{Round: sdk.Round(rnd)}}:
+			}
+		}
+	}()
+	return blocks, errs
+}
+
+func (s *subscribingImporter) subscriptions() []uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]uint64(nil), s.starts...)
+}
+
+func TestSubscriber(t *testing.T) {
+	imp := &subscribingImporter{failRound: 3}
+	p := startSubscriber(context.Background(), imp, 1, 5, 2, time.Millisecond)
+	defer p.stop()
+
+	var rounds []uint64
+	var errs int
+	for len(rounds) < 5 {
+		result := <-p.results
+		if result.err != nil {
+			assert.Equal(t, uint64(3), result.round)
+			assert.Contains(t, result.err.Error(), "subscription at round 3 failed: subscription")
+			errs++
+			continue
+		}
+		assert.Equal(t, result.round, result.blk.Round())
+		rounds = append(rounds, result.round)
+	}
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, rounds)
+	assert.Equal(t, 1, errs)
+	assert.Equal(t, []uint64{1, 3}, imp.subscriptions())
+
+	// Nothing is delivered after the end round.
+	select {
+	case <-p.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber did not stop after the end round")
+	}
+	assert.Len(t, p.results, 0)
+}
+
+// TestPipelineSubscription tests that the pipeline receives the rounds from a
+// subscribing importer instead of calling GetBlock, and resubscribes after a
+// failure.
+func TestPipelineSubscription(t *testing.T) {
+	imp := &subscribingImporter{failRound: 4}
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeReloadPipeline(t, exp)
+	var pImporter importers.Importer = imp
+	pImpl.importer = &pImporter
+	pImpl.cfg.Rounds.End = 7
+
+	pImpl.Start()
+	pImpl.Wait()
+	require.NoError(t, pImpl.Error())
+
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5, 6, 7}, exp.received())
+	assert.Equal(t, []uint64{0, 4}, imp.subscriptions())
+	assert.Zero(t, imp.calls)
+	assert.Equal(t, uint64(8), readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir).NextRound)
+}
@@ -0,0 +1,343 @@
+package pipeline
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/indexer/version"
+
+	"github.com/algorand/conduit/conduit/statestore"
+)
+
+// recentLogLines is the number of log lines kept in memory for support bundles.
+const recentLogLines = 500
+
+// maxLogFileTail is the amount of the log file included in a support bundle.
+const maxLogFileTail = 1024 * 1024
+
+// redactedValue replaces the configuration values which are not known to be
+// safe in support bundles.
+const redactedValue = "REDACTED"
+
+// bundleSafeSettings are the settings whose values are kept in support
+// bundles, as the keys of their path in the config file. The values of all
+// other strings are redacted.
+var bundleSafeSettings = map[string]bool{
+	"cpu-profile":              true,
+	"pid-filepath":             true,
+	"log-file":                 true,
+	"log-level":                true,
+	"metrics.mode":             true,
+	"metrics.addr":             true,
+	"metrics.prefix":           true,
+	"metrics.txn-type-labels":  true,
+	"metrics.processor-labels": true,
+	"api.addr":                 true,
+	"retry-delay":              true,
+	"shutdown-grace-period":    true,
+	"coordination.type":        true,
+	"coordination.key":         true,
+	"coordination.instance-id": true,
+	"coordination.lease":       true,
+	"coordination.retention":   true,
+	"coordination.table":       true,
+	"state-store.type":         true,
+	"state-store.key":          true,
+	"state-store.table":        true,
+	"state-store.bucket":       true,
+	"state-store.region":       true,
+}
+
+// bundlePluginSections are the config sections which list plugins.
+var bundlePluginSections = map[string]bool{
+	"importer":   true,
+	"processors": true,
+	"exporter":   true,
+	"exporters":  true,
+	"observers":  true,
+}
+
+// bundlePluginSettings are the plugin settings whose values are kept in
+// support bundles, the plugin config is always redacted.
+var bundlePluginSettings = map[string]bool{
+	"name":       true,
+	"log-level":  true,
+	"executable": true,
+}
+
+// logRing is a logrus hook which remembers the most recent log lines.
+type logRing struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func makeLogRing(size int) *logRing {
+	return &logRing{lines: make([]string, size)}
+}
+
+// Levels implements log.Hook
+func (r *logRing) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire implements log.Hook
+func (r *logRing) Fire(entry *log.Entry) error {
+	line, err := entry.String()
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.next] = strings.TrimRight(line, "\n")
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+	return nil
+}
+
+// Lines returns the remembered log lines, oldest first.
+func (r *logRing) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]string(nil), r.lines[:r.next]...)
+	}
+	return append(append([]string(nil), r.lines[r.next:]...), r.lines[:r.next]...)
+}
+
+// isSafeSetting reports whether the value of the setting at path is kept in
+// support bundles.
+func isSafeSetting(path []string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	if bundleSafeSettings[strings.Join(path, ".")] {
+		return true
+	}
+	for _, section := range path[:len(path)-1] {
+		if !bundlePluginSections[section] {
+			return false
+		}
+	}
+	return len(path) > 1 && bundlePluginSettings[path[len(path)-1]]
+}
+
+// isPluginArgs reports whether path is the args of a plugin executable.
+func isPluginArgs(path []string) bool {
+	return len(path) > 1 && path[len(path)-1] == "args" && bundlePluginSections[path[len(path)-2]]
+}
+
+// redactArg redacts an argument of a plugin executable, only the names of
+// flags are kept, as "--token" or "--token=REDACTED".
+func redactArg(arg string) string {
+	if !strings.HasPrefix(arg, "-") {
+		return redactedValue
+	}
+	if idx := strings.Index(arg, "="); idx >= 0 {
+		return arg[:idx+1] + redactedValue
+	}
+	return arg
+}
+
+// redactNode redacts the strings of node which are not safe settings, path
+// is the keys of node in the config file.
+func redactNode(node *yaml.Node, path []string) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			redactNode(child, path)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			redactNode(node.Content[i+1], append(path[:len(path):len(path)], node.Content[i].Value))
+		}
+	case yaml.ScalarNode:
+		// Numbers and booleans are kept, they do not hold credentials.
+		if node.Tag != "!!str" || node.Value == "" || isSafeSetting(path) {
+			return
+		}
+		if isPluginArgs(path) {
+			node.Value = redactArg(node.Value)
+		} else {
+			node.Value = redactedValue
+		}
+		node.Style = 0
+	}
+}
+
+// RedactedConfig returns the configuration for support bundles. Every string
+// is redacted unless it is a safe setting, so that credentials in plugin
+// configs and new settings are never included.
+func RedactedConfig(cfg Config) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := node.Encode(cfg); err != nil {
+		return nil, fmt.Errorf("RedactedConfig(): unable to encode the config: %w", err)
+	}
+	redactNode(&node, nil)
+	return &node, nil
+}
+
+// SupportBundlePath returns the default location of a support bundle in the data directory.
+func SupportBundlePath(dataDir string, now time.Time) string {
+	return path.Join(dataDir, fmt.Sprintf("support-bundle-%s.zip", now.UTC().Format("20060102T150405Z")))
+}
+
+// SupportBundle describes the inputs to a support bundle.
+type SupportBundle struct {
+	// DataDir is the conduit data directory.
+	DataDir string
+	// Config is the pipeline configuration, it may be nil if the config could not be loaded.
+	Config *Config
+	// ConfigErr is included when the configuration could not be loaded.
+	ConfigErr error
+	// Reason describes why the bundle was created.
+	Reason string
+	// RecentLogs are included if there is no log file.
+	RecentLogs []string
+}
+
+// Write creates a zip archive with the information needed to debug a failure:
+// redacted configuration, pipeline metadata, recent logs and runtime profiles.
+func (b SupportBundle) Write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	add := func(name string, write func(io.Writer) error) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return fmt.Errorf("SupportBundle.Write(): unable to add %s: %w", name, err)
+		}
+		if err = write(f); err != nil {
+			return fmt.Errorf("SupportBundle.Write(): unable to write %s: %w", name, err)
+		}
+		return nil
+	}
+
+	if err := add("info.txt", b.writeInfo); err != nil {
+		return err
+	}
+	if b.Config != nil {
+		if err := add("conduit.yml", func(w io.Writer) error {
+			redacted, err := RedactedConfig(*b.Config)
+			if err != nil {
+				return err
+			}
+			return yaml.NewEncoder(w).Encode(redacted)
+		}); err != nil {
+			return err
+		}
+	}
+	if metadata, err := os.ReadFile(statestore.MetadataPath(b.DataDir)); err == nil {
+		if err := add("metadata.json", func(w io.Writer) error {
+			_, err := w.Write(metadata)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	if err := add("logs.txt", b.writeLogs); err != nil {
+		return err
+	}
+	if err := add("goroutines.txt", func(w io.Writer) error {
+		return pprof.Lookup("goroutine").WriteTo(w, 2)
+	}); err != nil {
+		return err
+	}
+	if err := add("heap.pprof", func(w io.Writer) error {
+		return pprof.Lookup("heap").WriteTo(w, 0)
+	}); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (b SupportBundle) writeInfo(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "created: %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "reason: %s\n", b.Reason)
+	fmt.Fprintf(&sb, "version: %s\n", version.LongVersion())
+	fmt.Fprintf(&sb, "go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if b.ConfigErr != nil {
+		fmt.Fprintf(&sb, "config error: %v\n", b.ConfigErr)
+	}
+	if b.Config != nil {
+		describe := func(pluginType string, pair NameConfigPair) {
+			source := "built-in"
+			if pair.Executable != "" {
+				source = fmt.Sprintf("external (%s)", pair.Executable)
+			}
+			fmt.Fprintf(&sb, "%s: %s, %s\n", pluginType, pair.Name, source)
+		}
+		describe("importer", b.Config.Importer)
+		for _, proc := range b.Config.Processors {
+			describe("processor", proc)
+		}
+		for _, exporter := range b.Config.exporterConfigs() {
+			describe("exporter", exporter)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (b SupportBundle) writeLogs(w io.Writer) error {
+	if b.Config != nil && b.Config.LogFile != "" {
+		f, err := os.Open(b.Config.LogFile)
+		if err == nil {
+			defer f.Close()
+			if stat, err := f.Stat(); err == nil && stat.Size() > maxLogFileTail {
+				if _, err = f.Seek(-maxLogFileTail, io.SeekEnd); err != nil {
+					return err
+				}
+			}
+			_, err = io.Copy(w, f)
+			return err
+		}
+	}
+	for _, line := range b.RecentLogs {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSupportBundle writes a support bundle into the data directory after a fatal error.
+func (p *pipelineImpl) writeSupportBundle(reason string) {
+	if !p.cfg.SupportBundleOnFailure || p.cfg.ConduitArgs == nil {
+		return
+	}
+	bundle := SupportBundle{
+		DataDir: p.cfg.ConduitArgs.ConduitDataDir,
+		Config:  p.cfg,
+		Reason:  reason,
+	}
+	if p.recentLogs != nil {
+		bundle.RecentLogs = p.recentLogs.Lines()
+	}
+	bundlePath := SupportBundlePath(bundle.DataDir, time.Now())
+	f, err := os.Create(bundlePath)
+	if err != nil {
+		p.logger.Errorf("unable to create support bundle: %v", err)
+		return
+	}
+	defer f.Close()
+	if err = bundle.Write(f); err != nil {
+		p.logger.Errorf("unable to write support bundle: %v", err)
+		return
+	}
+	p.logger.Infof("Support bundle written to %s", bundlePath)
+}
@@ -0,0 +1,235 @@
+package pipeline
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/coordinator"
+	"github.com/algorand/conduit/conduit/plugins/external"
+	"github.com/algorand/conduit/conduit/statestore"
+)
+
+// redactedConfig returns the redacted config as a map.
+func redactedConfig(t *testing.T, cfg Config) map[string]interface{} {
+	node, err := RedactedConfig(cfg)
+	require.NoError(t, err)
+	var redacted map[string]interface{}
+	require.NoError(t, node.Decode(&redacted))
+	return redacted
+}
+
+func TestRedactedConfig(t *testing.T) {
+	cfg := Config{
+		PipelineLogLevel: "info",
+		RetryCount:       5,
+		Importer: NameConfigPair{Name: "algod", Config: map[string]interface{}{
+			"netaddr": "http://127.0.0.1:8080",
+			"token":   "secret-token",
+		}},
+		Processors: []NameConfigPair{{Name: "noop", LogLevel: "debug"}},
+		Exporter: NameConfigPair{Name: "postgresql", Config: map[string]interface{}{
+			"connection-string": "host=localhost password=hunter2",
+			"max-conn":          20,
+			"nested":            map[string]interface{}{"api-key": "abc", "delete-task": true},
+		}},
+		Telemetry: Telemetry{Headers: map[string]string{"Authorization": "Bearer abc"}},
+		Coordination: coordinator.Config{
+			Type:     "redis",
+			Key:      "mainnet",
+			Address:  "redis.internal:6379",
+			Password: "hunter2",
+		},
+		StateStore: statestore.Config{
+			Type:             statestore.S3Type,
+			Key:              "mainnet",
+			Bucket:           "conduit-state",
+			Region:           "us-east-1",
+			Endpoint:         "https://minio.internal:9000",
+			AccessKeyID:      "AKID",
+			SecretAccessKey:  "hunter2",
+			ConnectionString: "host=localhost password=hunter2",
+			Endpoints:        []string{"http://etcd.internal:2379"},
+			Password:         "hunter2",
+		},
+	}
+
+	redacted := redactedConfig(t, cfg)
+	assert.Equal(t, "info", redacted["log-level"])
+	assert.Equal(t, 5, redacted["retry-count"])
+	importer := redacted["importer"].(map[string]interface{})
+	assert.Equal(t, "algod", importer["name"])
+	assert.Equal(t, map[string]interface{}{"netaddr": redactedValue, "token": redactedValue}, importer["config"])
+	processor := redacted["processors"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "noop", processor["name"])
+	assert.Equal(t, "debug", processor["log-level"])
+	exporter := redacted["exporter"].(map[string]interface{})
+	assert.Equal(t, "postgresql", exporter["name"])
+	assert.Equal(t, map[string]interface{}{
+		"connection-string": redactedValue,
+		"max-conn":          20,
+		"nested":            map[string]interface{}{"api-key": redactedValue, "delete-task": true},
+	}, exporter["config"])
+	telemetry := redacted["telemetry"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"Authorization": redactedValue}, telemetry["headers"])
+	coordination := redacted["coordination"].(map[string]interface{})
+	assert.Equal(t, "redis", coordination["type"])
+	assert.Equal(t, "mainnet", coordination["key"])
+	assert.Equal(t, redactedValue, coordination["address"])
+	assert.Equal(t, redactedValue, coordination["password"])
+	stateStore := redacted["state-store"].(map[string]interface{})
+	assert.Equal(t, "s3", stateStore["type"])
+	assert.Equal(t, "mainnet", stateStore["key"])
+	assert.Equal(t, "conduit-state", stateStore["bucket"])
+	assert.Equal(t, "us-east-1", stateStore["region"])
+	for _, setting := range []string{"endpoint", "access-key-id", "secret-access-key", "connection-string", "password"} {
+		assert.Equal(t, redactedValue, stateStore[setting], setting)
+	}
+	assert.Equal(t, []interface{}{redactedValue}, stateStore["endpoints"])
+
+	// The original config is not modified.
+	assert.Equal(t, "secret-token", cfg.Importer.Config["token"])
+	assert.Equal(t, "Bearer abc", cfg.Telemetry.Headers["Authorization"])
+}
+
+func TestRedactedConfigArgs(t *testing.T) {
+	cfg := Config{
+		Importer: NameConfigPair{Name: "custom", Executable: "/bin/custom", Args: []string{
+			"--verbose", "--api-token=abc", "--password", "hunter2", "--dir", "/tmp",
+		}},
+	}
+
+	importer := redactedConfig(t, cfg)["importer"].(map[string]interface{})
+	assert.Equal(t, "/bin/custom", importer["executable"])
+	assert.Equal(t, []interface{}{
+		"--verbose", "--api-token=" + redactedValue, "--password", redactedValue, "--dir", redactedValue,
+	}, importer["args"])
+	assert.Equal(t, "hunter2", cfg.Importer.Args[3])
+}
+
+func TestRedactedConfigObservers(t *testing.T) {
+	cfg := Config{
+		Observers: []NameConfigPair{{Name: "webhook", Config: map[string]interface{}{
+			"url":    "https://example.com/hook",
+			"secret": "hunter2",
+		}}},
+	}
+
+	observer := redactedConfig(t, cfg)["observers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "webhook", observer["name"])
+	assert.Equal(t, map[string]interface{}{"url": redactedValue, "secret": redactedValue}, observer["config"])
+	assert.Equal(t, "hunter2", cfg.Observers[0].Config["secret"])
+}
+
+func TestRedactedConfigExporterProcessors(t *testing.T) {
+	cfg := Config{
+		Exporters: []NameConfigPair{{Name: "file_writer", Processors: []NameConfigPair{
+			{Name: "enrich", Config: map[string]interface{}{"endpoint": "http://localhost", "api-token": "abc"}},
+		}}},
+	}
+
+	exporter := redactedConfig(t, cfg)["exporters"].([]interface{})[0].(map[string]interface{})
+	processor := exporter["processors"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "enrich", processor["name"])
+	assert.Equal(t, map[string]interface{}{"endpoint": redactedValue, "api-token": redactedValue}, processor["config"])
+	assert.Equal(t, "abc", cfg.Exporters[0].Processors[0].Config["api-token"])
+}
+
+func TestRedactedConfigSandbox(t *testing.T) {
+	cfg := Config{
+		Exporter: NameConfigPair{Name: "custom", Executable: "/bin/custom", Sandbox: &external.Sandbox{
+			Env:    []string{"PATH"},
+			SetEnv: map[string]string{"CUSTOM_TOKEN": "abc"},
+		}},
+	}
+
+	exporter := redactedConfig(t, cfg)["exporter"].(map[string]interface{})
+	sandbox := exporter["sandbox"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"CUSTOM_TOKEN": redactedValue}, sandbox["set-env"])
+	assert.Equal(t, "abc", cfg.Exporter.Sandbox.SetEnv["CUSTOM_TOKEN"])
+}
+
+func TestLogRing(t *testing.T) {
+	ring := makeLogRing(3)
+	l := log.New()
+	l.SetOutput(io.Discard)
+	l.SetFormatter(&log.TextFormatter{DisableTimestamp: true})
+	l.AddHook(ring)
+
+	assert.Empty(t, ring.Lines())
+	for i := 0; i < 5; i++ {
+		l.Infof("line %d", i)
+	}
+	assert.Equal(t, []string{
+		`level=info msg="line 2"`,
+		`level=info msg="line 3"`,
+		`level=info msg="line 4"`,
+	}, ring.Lines())
+}
+
+func TestSupportBundleWrite(t *testing.T) {
+	dataDir := t.TempDir()
+	require.NoError(t, os.WriteFile(statestore.MetadataPath(dataDir), []byte(`{"next-round":5}`), 0644))
+
+	bundle := SupportBundle{
+		DataDir: dataDir,
+		Config: &Config{
+			ConduitArgs: &conduit.Args{ConduitDataDir: dataDir},
+			Importer:    NameConfigPair{Name: "algod", Config: map[string]interface{}{"token": "abc"}},
+			Exporter:    NameConfigPair{Name: "custom", Executable: "/bin/custom"},
+		},
+		ConfigErr:  fmt.Errorf("bad config"),
+		Reason:     "testing",
+		RecentLogs: []string{"first", "second"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, bundle.Write(&buf))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		b, err := io.ReadAll(r)
+		require.NoError(t, err)
+		files[f.Name] = string(b)
+	}
+
+	assert.Contains(t, files["conduit.yml"], "name: algod")
+	assert.NotContains(t, files["conduit.yml"], "abc")
+	assert.Contains(t, files, "goroutines.txt")
+	assert.Contains(t, files, "heap.pprof")
+	assert.Equal(t, `{"next-round":5}`, files["metadata.json"])
+	assert.Equal(t, "first\nsecond\n", files["logs.txt"])
+	assert.Contains(t, files["info.txt"], "reason: testing")
+	assert.Contains(t, files["info.txt"], "config error: bad config")
+	assert.Contains(t, files["info.txt"], "importer: algod, built-in")
+	assert.Contains(t, files["info.txt"], "exporter: custom, external (/bin/custom)")
+}
+
+func TestSupportBundleLogFile(t *testing.T) {
+	logFile := path.Join(t.TempDir(), "conduit.log")
+	require.NoError(t, os.WriteFile(logFile, []byte("from the log file\n"), 0644))
+
+	var buf bytes.Buffer
+	bundle := SupportBundle{Config: &Config{LogFile: logFile}, RecentLogs: []string{"ignored"}}
+	require.NoError(t, bundle.writeLogs(&buf))
+	assert.Equal(t, "from the log file\n", buf.String())
+}
+
+func TestSupportBundlePath(t *testing.T) {
+	now := time.Date(2023, 3, 4, 5, 6, 7, 0, time.UTC)
+	assert.Equal(t, "/data/support-bundle-20230304T050607Z.zip", SupportBundlePath("/data", now))
+}
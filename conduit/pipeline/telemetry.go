@@ -0,0 +1,188 @@
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	rtmetrics "runtime/metrics"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+const (
+	// The pprof labels set while a plugin is called. Goroutines started by the
+	// plugin inherit them, and CPU profiles can be filtered by them.
+	pluginTypeLabel = "conduit_plugin_type"
+	pluginNameLabel = "conduit_plugin_name"
+
+	allocBytesMetric   = "/gc/heap/allocs:bytes"
+	allocObjectsMetric = "/gc/heap/allocs:objects"
+
+	importerType  = "importer"
+	processorType = "processor"
+	exporterType  = "exporter"
+
+	// goroutineSampleInterval limits how often the goroutine profile is read.
+	goroutineSampleInterval = 10 * time.Second
+)
+
+// resourceUsage is a snapshot of the process-wide resource counters.
+type resourceUsage struct {
+	cpu          time.Duration
+	allocBytes   uint64
+	allocObjects uint64
+}
+
+func readResourceUsage() resourceUsage {
+	samples := []rtmetrics.Sample{{Name: allocBytesMetric}, {Name: allocObjectsMetric}}
+	rtmetrics.Read(samples)
+	usage := resourceUsage{cpu: processCPUTime()}
+	if samples[0].Value.Kind() == rtmetrics.KindUint64 {
+		usage.allocBytes = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == rtmetrics.KindUint64 {
+		usage.allocObjects = samples[1].Value.Uint64()
+	}
+	return usage
+}
+
+// pluginKey identifies a plugin in the telemetry metrics.
+type pluginKey struct {
+	pluginType string
+	name       string
+}
+
+// stageTelemetry attributes CPU time, allocations and goroutines to plugins.
+// The counters are process-wide, so when stages run concurrently, for example
+// with prefetch-rounds, their usage is attributed to every plugin being called
+// at the time. A nil *stageTelemetry only calls the plugins.
+type stageTelemetry struct {
+	// The plugin names are set as the plugins are initialized.
+	importerName   string
+	processorNames []string
+	exporterNames  []string
+
+	mu         sync.Mutex
+	lastSample time.Time
+}
+
+// label calls fn with the pprof labels of the plugin.
+func (t *stageTelemetry) label(pluginType string, name string, fn func()) {
+	if t == nil {
+		fn()
+		return
+	}
+	labels := pprof.Labels(pluginTypeLabel, pluginType, pluginNameLabel, name)
+	pprof.Do(context.Background(), labels, func(context.Context) {
+		fn()
+	})
+}
+
+// observe calls fn with the pprof labels of the plugin and records the
+// resources used while it ran.
+func (t *stageTelemetry) observe(pluginType string, name string, fn func()) {
+	before := readResourceUsage()
+	t.label(pluginType, name, fn)
+	after := readResourceUsage()
+
+	metrics.PluginCPUSeconds.WithLabelValues(pluginType, name).Add((after.cpu - before.cpu).Seconds())
+	metrics.PluginAllocBytes.WithLabelValues(pluginType, name).Add(float64(after.allocBytes - before.allocBytes))
+	metrics.PluginAllocObjects.WithLabelValues(pluginType, name).Add(float64(after.allocObjects - before.allocObjects))
+}
+
+// importer observes a call to the importer.
+func (t *stageTelemetry) importer(fn func()) {
+	if t == nil {
+		fn()
+		return
+	}
+	t.observe(importerType, t.importerName, fn)
+}
+
+// processor observes a call to the processor at idx.
+func (t *stageTelemetry) processor(idx int, fn func()) {
+	if t == nil {
+		fn()
+		return
+	}
+	t.observe(processorType, t.processorNames[idx], fn)
+}
+
+// exporter observes a call to the exporter at idx.
+func (t *stageTelemetry) exporter(idx int, fn func()) {
+	if t == nil {
+		fn()
+		return
+	}
+	t.observe(exporterType, t.exporterNames[idx], fn)
+}
+
+// sampleGoroutines updates the plugin goroutine gauge, at most once every
+// goroutineSampleInterval.
+func (t *stageTelemetry) sampleGoroutines() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	if time.Since(t.lastSample) < goroutineSampleInterval {
+		t.mu.Unlock()
+		return nil
+	}
+	t.lastSample = time.Now()
+	t.mu.Unlock()
+
+	counts, err := pluginGoroutines()
+	if err != nil {
+		return err
+	}
+	metrics.PluginGoroutines.Reset()
+	for key, count := range counts {
+		metrics.PluginGoroutines.WithLabelValues(key.pluginType, key.name).Set(float64(count))
+	}
+	return nil
+}
+
+// pluginGoroutines counts the running goroutines by the plugin labels they
+// carry. This includes goroutines which are currently calling a plugin.
+func pluginGoroutines() (map[pluginKey]int, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil, err
+	}
+	return parseGoroutineProfile(&buf)
+}
+
+// parseGoroutineProfile reads a goroutine profile in the debug=1 text format,
+// where each stack is preceded by "<count> @ <pcs>" and optionally followed by
+// "# labels: {...}".
+func parseGoroutineProfile(r io.Reader) (map[pluginKey]int, error) {
+	counts := make(map[pluginKey]int)
+	var count int
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if fields := strings.Fields(line); len(fields) > 1 && fields[1] == "@" {
+			count, _ = strconv.Atoi(fields[0])
+			continue
+		}
+		if !strings.HasPrefix(line, "# labels: ") {
+			continue
+		}
+		var labels map[string]string
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "# labels: ")), &labels); err != nil {
+			continue
+		}
+		if name, ok := labels[pluginNameLabel]; ok {
+			counts[pluginKey{labels[pluginTypeLabel], name}] += count
+		}
+	}
+	return counts, scanner.Err()
+}
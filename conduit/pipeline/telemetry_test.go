@@ -0,0 +1,66 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGoroutineProfile(t *testing.T) {
+	profile := `goroutine profile: total 6
+3 @ 0x43a0d6 0x4071ac 0x406d98
+# labels: {"conduit_plugin_name":"algod", "conduit_plugin_type":"importer"}
+#	0x4be3c4	github.com/algorand/conduit/conduit/plugins/importers/algod.(*algodImporter).monitorCatchpoint+0x84
+
+2 @ 0x43a0d6 0x44a2f2
+#	0x44a2f1	time.Sleep+0x131
+
+1 @ 0x43a0d6 0x4071ac
+# labels: {"conduit_plugin_name":"postgresql", "conduit_plugin_type":"exporter", "other":"x"}
+
+4 @ 0x43a0d6
+# labels: {"unrelated":"label"}
+`
+	counts, err := parseGoroutineProfile(strings.NewReader(profile))
+	require.NoError(t, err)
+	assert.Equal(t, map[pluginKey]int{
+		{importerType, "algod"}:      3,
+		{exporterType, "postgresql"}: 1,
+	}, counts)
+}
+
+func TestPluginGoroutines(t *testing.T) {
+	telemetry := &stageTelemetry{exporterNames: []string{"test_exporter"}}
+	started := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+
+	// goroutines started by the plugin inherit its labels
+	telemetry.exporter(0, func() {
+		for i := 0; i < 2; i++ {
+			go func() {
+				started <- struct{}{}
+				<-done
+			}()
+		}
+	})
+	<-started
+	<-started
+
+	counts, err := pluginGoroutines()
+	require.NoError(t, err)
+	assert.Equal(t, 2, counts[pluginKey{exporterType, "test_exporter"}])
+}
+
+func TestStageTelemetryDisabled(t *testing.T) {
+	var telemetry *stageTelemetry
+	calls := 0
+	telemetry.importer(func() { calls++ })
+	telemetry.processor(3, func() { calls++ })
+	telemetry.exporter(3, func() { calls++ })
+	telemetry.label(importerType, "name", func() { calls++ })
+	assert.Equal(t, 4, calls)
+	assert.NoError(t, telemetry.sampleGoroutines())
+}
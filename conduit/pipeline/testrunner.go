@@ -0,0 +1,693 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/coordinator"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/statestore"
+)
+
+// TestFileSuffixes are the suffixes of the pipeline test files.
+var TestFileSuffixes = []string{".test.yml", ".test.yaml"}
+
+// DefaultTestTimeout is the timeout of a pipeline test which does not set one.
+const DefaultTestTimeout = time.Minute
+
+// PipelineTest declares an end-to-end test of a pipeline config: the blocks
+// it imports and what its exporters must receive.
+type PipelineTest struct {
+	// Name describes the test, it defaults to the file name.
+	Name string `yaml:"name"`
+	// Config is the conduit.yml under test, relative to the test file.
+	Config string `yaml:"config"`
+	// Pipeline is an inline conduit.yml, instead of Config.
+	Pipeline map[string]interface{} `yaml:"pipeline"`
+	// Input replaces the importer of the pipeline.
+	Input TestInput `yaml:"input"`
+	// RunExporters are the IDs of the exporters which run as configured.
+	// The other exporters are replaced by recorders.
+	RunExporters []string `yaml:"run-exporters"`
+	// Expect are the assertions checked once the pipeline stopped.
+	Expect TestExpect `yaml:"expect"`
+	// Timeout fails the test if the pipeline has not exported the last round
+	// in time, DefaultTestTimeout by default.
+	Timeout time.Duration `yaml:"timeout"`
+
+	file string
+}
+
+// TestInput are the blocks imported by a pipeline test, either fixtures or
+// the block files written by file_writer.
+type TestInput struct {
+	// Fixtures are the names of the fixture blocks, round 0 is the first.
+	Fixtures []string `yaml:"fixtures"`
+	// Network is the network of the fixtures, "mainnet" by default.
+	Network string `yaml:"network"`
+	// BlockDir is a directory of block files and their genesis.json, relative
+	// to the test file.
+	BlockDir string `yaml:"block-dir"`
+	// FirstRound and LastRound are the rounds read from BlockDir.
+	FirstRound uint64 `yaml:"first-round"`
+	LastRound  uint64 `yaml:"last-round"`
+}
+
+// TestExpect are the assertions of a pipeline test.
+type TestExpect struct {
+	// Error is part of the error the pipeline must stop with, by default the
+	// pipeline must not fail.
+	Error string `yaml:"error"`
+	// Exporters are the assertions on what the recorded exporters received.
+	Exporters []ExporterExpectation `yaml:"exporters"`
+	// Metrics are the assertions on the metrics once the pipeline stopped.
+	Metrics []MetricExpectation `yaml:"metrics"`
+}
+
+// ExporterExpectation asserts what a recorded exporter received. The
+// conditions have the syntax of the plugin when conditions, a condition with
+// transaction fields is checked for each transaction, other conditions for
+// each block.
+type ExporterExpectation struct {
+	Name string `yaml:"name"`
+	// Rounds are the rounds the exporter must receive, in order.
+	Rounds *[]uint64 `yaml:"rounds"`
+	// Txns is the number of transactions the exporter must receive.
+	Txns *int `yaml:"txns"`
+	// All must match every transaction or block, Any at least one and None
+	// none.
+	All  string `yaml:"all"`
+	Any  string `yaml:"any"`
+	None string `yaml:"none"`
+}
+
+// MetricExpectation asserts the value of a metric, the sum of its series
+// which have the labels. The value of a summary or histogram is its sample
+// count.
+type MetricExpectation struct {
+	// Name is the full name of the metric, with the metrics prefix.
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels"`
+	Value  *float64          `yaml:"value"`
+	Min    *float64          `yaml:"min"`
+	Max    *float64          `yaml:"max"`
+}
+
+// TestResult is the outcome of a pipeline test.
+type TestResult struct {
+	Name     string
+	File     string
+	Duration time.Duration
+	// Failures are the assertions which failed, the test passed if it has none.
+	Failures []string
+}
+
+// Passed reports whether every assertion of the test passed.
+func (r TestResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// TestReport is the outcome of a test run.
+type TestReport struct {
+	Results []TestResult
+}
+
+// Passed reports whether every test passed.
+func (r TestReport) Passed() bool {
+	for _, result := range r.Results {
+		if !result.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Write prints the result of each test and a summary.
+func (r TestReport) Write(w io.Writer) error {
+	failed := 0
+	for _, result := range r.Results {
+		status := "PASS"
+		if !result.Passed() {
+			status = "FAIL"
+			failed++
+		}
+		if _, err := fmt.Fprintf(w, "--- %s: %s (%s) (%.2fs)\n", status, result.Name, result.File, result.Duration.Seconds()); err != nil {
+			return err
+		}
+		for _, failure := range result.Failures {
+			if _, err := fmt.Fprintf(w, "    %s\n", failure); err != nil {
+				return err
+			}
+		}
+	}
+	status := "PASS"
+	if failed > 0 {
+		status = "FAIL"
+	}
+	_, err := fmt.Fprintf(w, "%s: %d tests, %d failed\n", status, len(r.Results), failed)
+	return err
+}
+
+// FindTestFiles returns the test files of the paths, directories are searched
+// recursively.
+func FindTestFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, fmt.Errorf("FindTestFiles(): %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && isTestFile(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("FindTestFiles(): %w", err)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func isTestFile(path string) bool {
+	for _, suffix := range TestFileSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadPipelineTest reads a test file.
+func LoadPipelineTest(file string) (*PipelineTest, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("LoadPipelineTest(): %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	var test PipelineTest
+	if err = decoder.Decode(&test); err != nil {
+		return nil, fmt.Errorf("LoadPipelineTest(): test file (%s) was mal-formed yaml: %w", file, err)
+	}
+	test.file = file
+	if test.Name == "" {
+		test.Name = filepath.Base(file)
+	}
+	if err = test.Valid(); err != nil {
+		return nil, fmt.Errorf("LoadPipelineTest(): test file (%s) was invalid: %w", file, err)
+	}
+	return &test, nil
+}
+
+// Valid validates the test, the conditions are compiled.
+func (t PipelineTest) Valid() error {
+	if (t.Config == "") == (t.Pipeline == nil) {
+		return fmt.Errorf("either config or pipeline is required")
+	}
+	if t.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative (%s)", t.Timeout)
+	}
+	if (len(t.Input.Fixtures) == 0) == (t.Input.BlockDir == "") {
+		return fmt.Errorf("input requires either fixtures or block-dir")
+	}
+	if t.Input.BlockDir != "" && t.Input.FirstRound > t.Input.LastRound {
+		return fmt.Errorf("input first-round (%d) must not be after last-round (%d)", t.Input.FirstRound, t.Input.LastRound)
+	}
+	for _, e := range t.Expect.Exporters {
+		if e.Name == "" {
+			return fmt.Errorf("expect exporters: name is required")
+		}
+		for _, when := range []string{e.All, e.Any, e.None} {
+			if when == "" {
+				continue
+			}
+			if _, err := compileCondition(when); err != nil {
+				return fmt.Errorf("expect exporter (%s): condition was invalid: %w", e.Name, err)
+			}
+		}
+	}
+	for _, m := range t.Expect.Metrics {
+		if m.Name == "" {
+			return fmt.Errorf("expect metrics: name is required")
+		}
+		if m.Value == nil && m.Min == nil && m.Max == nil {
+			return fmt.Errorf("expect metric (%s): value, min or max is required", m.Name)
+		}
+	}
+	return nil
+}
+
+// rounds returns the first and last round of the input.
+func (in TestInput) rounds() (uint64, uint64) {
+	if len(in.Fixtures) > 0 {
+		return 0, uint64(len(in.Fixtures) - 1)
+	}
+	return in.FirstRound, in.LastRound
+}
+
+// pipelineConfig decodes the pipeline under test and prepares it to run in
+// dataDir: the importer is replaced with the input, and the settings which
+// need an environment, such as servers, are cleared.
+func (t PipelineTest) pipelineConfig(dataDir string) (*Config, error) {
+	dir := filepath.Dir(t.file)
+	var configBytes []byte
+	source := t.file
+	var err error
+	if t.Config != "" {
+		source = filepath.Join(dir, t.Config)
+		if configBytes, err = os.ReadFile(source); err != nil {
+			return nil, err
+		}
+	} else if configBytes, err = yaml.Marshal(t.Pipeline); err != nil {
+		return nil, err
+	}
+	cfg, err := decodePipelineConfig(configBytes, source)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ConduitArgs = &conduit.Args{ConduitDataDir: dataDir}
+
+	if len(t.Input.Fixtures) > 0 {
+		network := t.Input.Network
+		if network == "" {
+			network = "mainnet"
+		}
+		cfg.Importer = NameConfigPair{Name: "fixtures", Config: map[string]interface{}{
+			"network":  network,
+			"fixtures": t.Input.Fixtures,
+		}}
+	} else {
+		cfg.Importer = NameConfigPair{Name: "file_reader", Config: map[string]interface{}{
+			"block-dir":   filepath.Join(dir, t.Input.BlockDir),
+			"retry-count": 1,
+		}}
+	}
+	cfg.Rounds.Start, cfg.Rounds.End = t.Input.rounds()
+	cfg.Rounds.Workers = 0
+
+	cfg.Metrics.Mode = ""
+	cfg.API = API{}
+	cfg.PIDFilePath = ""
+	cfg.LogFile = ""
+	cfg.CPUProfile = ""
+	cfg.Profiling = Profiling{}
+	cfg.Telemetry = Telemetry{}
+	cfg.StateStore = statestore.Config{}
+	cfg.Coordination = coordinator.Config{}
+	cfg.ConfigDrift = ConfigDrift{}
+	cfg.Throttle = Throttle{}
+	cfg.Simulation = Simulation{}
+	cfg.Observers = nil
+	cfg.Migration = Migration{}
+	cfg.Standby = Standby{}
+	cfg.Watchdog = Watchdog{}
+	cfg.Verification = Verification{}
+	cfg.ReuseBlockData = false
+	// The recorders do not write payloads.
+	exporterIDs := cfg.exporterIDs()
+	for idx := range cfg.Exporters {
+		if !t.runs(exporterIDs[idx]) {
+			cfg.Exporters[idx].OutputSchema = nil
+		}
+	}
+	if len(cfg.Exporters) == 0 && !t.runs(exporterIDs[0]) {
+		cfg.Exporter.OutputSchema = nil
+	}
+	return cfg, cfg.Valid()
+}
+
+// runs reports whether the exporter runs as configured instead of being
+// recorded.
+func (t PipelineTest) runs(exporter string) bool {
+	for _, name := range t.RunExporters {
+		if name == exporter {
+			return true
+		}
+	}
+	return false
+}
+
+// recordingExporter replaces an exporter in a pipeline test, it keeps the
+// blocks it receives.
+type recordingExporter struct {
+	name   string
+	mu     sync.Mutex
+	blocks []data.BlockData
+}
+
+func (r *recordingExporter) Metadata() conduit.Metadata {
+	return conduit.Metadata{Name: r.name}
+}
+
+func (r *recordingExporter) Init(context.Context, data.InitProvider, plugins.PluginConfig, *log.Logger) error {
+	return nil
+}
+
+func (r *recordingExporter) Config() string {
+	return ""
+}
+
+func (r *recordingExporter) Close() error {
+	return nil
+}
+
+func (r *recordingExporter) Receive(exportData data.BlockData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// A round which is exported again replaces the previous one.
+	for idx := range r.blocks {
+		if r.blocks[idx].Round() == exportData.Round() {
+			r.blocks[idx] = exportData
+			return nil
+		}
+	}
+	r.blocks = append(r.blocks, exportData)
+	return nil
+}
+
+func (r *recordingExporter) received() []data.BlockData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]data.BlockData(nil), r.blocks...)
+}
+
+// RunPipelineTest runs the pipeline of the test in a temporary data
+// directory and checks its assertions. The pipeline logs with logger, at the
+// log-level of the pipeline config.
+func RunPipelineTest(ctx context.Context, test *PipelineTest, logger *log.Logger) TestResult {
+	start := time.Now()
+	result := TestResult{Name: test.Name, File: test.file}
+	result.Failures = test.run(ctx, logger)
+	result.Duration = time.Since(start)
+	return result
+}
+
+func (t *PipelineTest) run(ctx context.Context, logger *log.Logger) []string {
+	dataDir, err := os.MkdirTemp("", "conduit-test-")
+	if err != nil {
+		return []string{fmt.Sprintf("unable to create the data directory: %v", err)}
+	}
+	defer os.RemoveAll(dataDir)
+
+	cfg, err := t.pipelineConfig(dataDir)
+	if err != nil {
+		return t.checkError(fmt.Errorf("invalid pipeline config: %w", err))
+	}
+	level, err := log.ParseLevel(cfg.PipelineLogLevel)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid log level: %v", err)}
+	}
+	logger.SetLevel(level)
+
+	p, err := MakePipeline(ctx, cfg, logger)
+	if err != nil {
+		return t.checkError(err)
+	}
+	pImpl := p.(*pipelineImpl)
+	recorders := make(map[string]*recordingExporter)
+	exporterIDs := cfg.exporterIDs()
+	for idx := range pImpl.exporters {
+		name := exporterIDs[idx]
+		if t.runs(name) {
+			continue
+		}
+		recorders[name] = &recordingExporter{name: name}
+		var exp exporters.Exporter = recorders[name]
+		pImpl.exporters[idx] = &exp
+	}
+
+	if err = p.Init(); err != nil {
+		p.Stop()
+		return t.checkError(err)
+	}
+	p.Start()
+	// A pipeline which ends at round 0 has no last round, it is stopped once
+	// the round is exported.
+	timeout := t.Timeout
+	if timeout == 0 {
+		timeout = DefaultTestTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	done := make(chan struct{})
+	timedOut := make(chan bool, 1)
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				timedOut <- false
+				return
+			case <-timer.C:
+				p.Stop()
+				timedOut <- true
+				return
+			case <-ticker.C:
+				if p.Status().NextRound > cfg.Rounds.End {
+					p.Stop()
+					timedOut <- false
+					return
+				}
+			}
+		}
+	}()
+	p.Wait()
+	close(done)
+	p.Stop()
+	if <-timedOut {
+		return []string{fmt.Sprintf("the pipeline did not export round %d within %s", cfg.Rounds.End, timeout)}
+	}
+
+	err = p.Error()
+	if err != nil && p.Status().NextRound > cfg.Rounds.End {
+		// Every input round was exported, the importer failed past the last
+		// one before the pipeline was stopped.
+		err = nil
+	}
+	failures := t.checkError(err)
+	for _, e := range t.Expect.Exporters {
+		recorder, ok := recorders[e.Name]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("exporter (%s) is not a recorded exporter of the pipeline", e.Name))
+			continue
+		}
+		failures = append(failures, e.check(recorder.received())...)
+	}
+	for _, m := range t.Expect.Metrics {
+		if failure := m.check(); failure != "" {
+			failures = append(failures, failure)
+		}
+	}
+	return failures
+}
+
+// checkError compares the error the pipeline stopped with to the expected
+// error.
+func (t *PipelineTest) checkError(err error) []string {
+	switch {
+	case t.Expect.Error == "" && err != nil:
+		return []string{fmt.Sprintf("the pipeline failed: %v", err)}
+	case t.Expect.Error != "" && err == nil:
+		return []string{fmt.Sprintf("the pipeline did not fail, expected an error with %q", t.Expect.Error)}
+	case t.Expect.Error != "" && !strings.Contains(err.Error(), t.Expect.Error):
+		return []string{fmt.Sprintf("the pipeline failed with %q, expected an error with %q", err.Error(), t.Expect.Error)}
+	}
+	return nil
+}
+
+// check returns the failed assertions on the received blocks.
+func (e ExporterExpectation) check(blocks []data.BlockData) []string {
+	var failures []string
+	if e.Rounds != nil {
+		rounds := make([]uint64, 0, len(blocks))
+		for _, blk := range blocks {
+			rounds = append(rounds, blk.Round())
+		}
+		if !reflect.DeepEqual(rounds, *e.Rounds) && (len(rounds) > 0 || len(*e.Rounds) > 0) {
+			failures = append(failures, fmt.Sprintf("exporter (%s): expected rounds %v, received %v", e.Name, *e.Rounds, rounds))
+		}
+	}
+	if e.Txns != nil {
+		txns := 0
+		for _, blk := range blocks {
+			txns += len(blk.Payset)
+		}
+		if txns != *e.Txns {
+			failures = append(failures, fmt.Sprintf("exporter (%s): expected %d transactions, received %d", e.Name, *e.Txns, txns))
+		}
+	}
+	for _, assertion := range []struct {
+		kind string
+		when string
+		pass func(matched, total int) bool
+	}{
+		{"all", e.All, func(matched, total int) bool { return matched == total }},
+		{"any", e.Any, func(matched, total int) bool { return matched > 0 }},
+		{"none", e.None, func(matched, total int) bool { return matched == 0 }},
+	} {
+		if assertion.when == "" {
+			continue
+		}
+		matched, total, err := countMatches(assertion.when, blocks)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("exporter (%s): %s %q: %v", e.Name, assertion.kind, assertion.when, err))
+		} else if !assertion.pass(matched, total) {
+			failures = append(failures, fmt.Sprintf("exporter (%s): %s %q: %d of %d matched", e.Name, assertion.kind, assertion.when, matched, total))
+		}
+	}
+	return failures
+}
+
+// countMatches counts the transactions which match a condition with
+// transaction fields, or the blocks which match another condition.
+func countMatches(when string, blocks []data.BlockData) (matched int, total int, err error) {
+	cond, err := compileCondition(when)
+	if err != nil {
+		return 0, 0, err
+	}
+	for idx := range blocks {
+		blk := &blocks[idx]
+		if !cond.usesTxn {
+			total++
+			match, err := cond.match(blk)
+			if err != nil {
+				return 0, 0, err
+			}
+			if match {
+				matched++
+			}
+			continue
+		}
+		for i := range blk.Payset {
+			total++
+			match, err := cond.root.eval(conditionEnv{block: blk, txn: &blk.Payset[i].SignedTxnWithAD})
+			if err != nil {
+				return 0, 0, err
+			}
+			if match {
+				matched++
+			}
+		}
+	}
+	return matched, total, nil
+}
+
+// check returns the failed assertion on the metric, or an empty string.
+func (m MetricExpectation) check() string {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return fmt.Sprintf("metric (%s): unable to gather the metrics: %v", m.Name, err)
+	}
+	var value float64
+	found := false
+	for _, family := range families {
+		if family.GetName() != m.Name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			matches := true
+			for name, expected := range m.Labels {
+				if labels[name] != expected {
+					matches = false
+				}
+			}
+			if !matches {
+				continue
+			}
+			found = true
+			switch {
+			case metric.GetCounter() != nil:
+				value += metric.GetCounter().GetValue()
+			case metric.GetGauge() != nil:
+				value += metric.GetGauge().GetValue()
+			case metric.GetUntyped() != nil:
+				value += metric.GetUntyped().GetValue()
+			case metric.GetSummary() != nil:
+				value += float64(metric.GetSummary().GetSampleCount())
+			case metric.GetHistogram() != nil:
+				value += float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	// A metric without series, such as a counter which was never incremented,
+	// is zero.
+	if !found {
+		value = 0
+	}
+	switch {
+	case m.Value != nil && value != *m.Value:
+		return fmt.Sprintf("metric %s: expected %v, found %v", m.describe(), *m.Value, value)
+	case m.Min != nil && value < *m.Min:
+		return fmt.Sprintf("metric %s: expected at least %v, found %v", m.describe(), *m.Min, value)
+	case m.Max != nil && value > *m.Max:
+		return fmt.Sprintf("metric %s: expected at most %v, found %v", m.describe(), *m.Max, value)
+	}
+	return ""
+}
+
+// describe returns the metric name with its labels, like the Prometheus
+// exposition format.
+func (m MetricExpectation) describe() string {
+	if len(m.Labels) == 0 {
+		return m.Name
+	}
+	names := make([]string, 0, len(m.Labels))
+	for name := range m.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, m.Labels[name]))
+	}
+	return m.Name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// RunPipelineTests runs the tests of the files whose name matches the
+// pattern, in order.
+func RunPipelineTests(ctx context.Context, files []string, pattern *regexp.Regexp, logger *log.Logger) (TestReport, error) {
+	var report TestReport
+	for _, file := range files {
+		test, err := LoadPipelineTest(file)
+		if err != nil {
+			return report, err
+		}
+		if pattern != nil && !pattern.MatchString(test.Name) {
+			continue
+		}
+		report.Results = append(report.Results, RunPipelineTest(ctx, test, logger))
+	}
+	return report, nil
+}
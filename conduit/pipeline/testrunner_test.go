@@ -0,0 +1,168 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"regexp"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+const testRunnerConfig = `
+log-level: info
+importer:
+  name: algod
+  config:
+    netaddr: "http://127.0.0.1:8080"
+exporter:
+  name: noop
+`
+
+func writeTestFile(t *testing.T, dir, name, contents string) string {
+	file := path.Join(dir, name)
+	require.NoError(t, os.WriteFile(file, []byte(contents), 0644))
+	return file
+}
+
+func TestLoadPipelineTestInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		err      string
+	}{
+		{"no config", "input: {fixtures: [empty]}", "either config or pipeline is required"},
+		{"no input", "config: conduit.yml", "input requires either fixtures or block-dir"},
+		{"both inputs", "config: conduit.yml\ninput: {fixtures: [empty], block-dir: blocks}", "input requires either fixtures or block-dir"},
+		{"rounds", "config: conduit.yml\ninput: {block-dir: blocks, first-round: 5, last-round: 4}", "input first-round (5) must not be after last-round (4)"},
+		{"condition", "config: conduit.yml\ninput: {fixtures: [empty]}\nexpect: {exporters: [{name: noop, any: \"txn.type ==\"}]}", "expect exporter (noop): condition was invalid:"},
+		{"metric", "config: conduit.yml\ninput: {fixtures: [empty]}\nexpect: {metrics: [{name: conduit_imported_round}]}", "expect metric (conduit_imported_round): value, min or max is required"},
+		{"unknown field", "config: conduit.yml\ninputs: {fixtures: [empty]}", "was mal-formed yaml"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file := writeTestFile(t, t.TempDir(), "invalid.test.yml", tc.contents)
+			_, err := LoadPipelineTest(file)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestFindTestFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(path.Join(dir, "nested"), 0755))
+	a := writeTestFile(t, dir, "a.test.yml", "")
+	b := writeTestFile(t, path.Join(dir, "nested"), "b.test.yaml", "")
+	writeTestFile(t, dir, conduit.DefaultConfigName, "")
+
+	files, err := FindTestFiles([]string{dir})
+	require.NoError(t, err)
+	assert.Equal(t, []string{a, b}, files)
+
+	files, err = FindTestFiles([]string{a})
+	require.NoError(t, err)
+	assert.Equal(t, []string{a}, files)
+
+	_, err = FindTestFiles([]string{path.Join(dir, "missing")})
+	assert.Error(t, err)
+}
+
+// TestRunPipelineTests runs the fixtures through a config file and an inline
+// pipeline, with passing and failing assertions.
+func TestRunPipelineTests(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, conduit.DefaultConfigName, testRunnerConfig)
+	writeTestFile(t, dir, "pass.test.yml", `
+name: keyreg
+config: conduit.yml
+input:
+  fixtures: [empty, keyreg]
+expect:
+  exporters:
+    - name: noop
+      rounds: [0, 1]
+      txns: 3
+      any: "txn.type == 'keyreg'"
+      none: "txn.type == 'pay'"
+      all: "block.round < 2"
+  metrics:
+    - name: conduit_imported_round
+      value: 1
+    - name: conduit_imported_tx_per_block
+      min: 1
+`)
+	writeTestFile(t, dir, "fail.test.yml", `
+name: wrong expectations
+pipeline:
+  importer:
+    name: algod
+  exporter:
+    name: noop
+input:
+  fixtures: [keyreg]
+expect:
+  exporters:
+    - name: noop
+      rounds: [1]
+      all: "txn.type == 'pay'"
+    - name: postgresql
+  metrics:
+    - name: conduit_imported_round
+      max: -1
+`)
+	writeTestFile(t, dir, "error.test.yml", `
+name: unknown exporter
+pipeline:
+  importer:
+    name: algod
+  exporter:
+    name: missing
+input:
+  fixtures: [empty]
+expect:
+  error: "could not build exporter 'missing'"
+`)
+
+	files, err := FindTestFiles([]string{dir})
+	require.NoError(t, err)
+	var logs bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&logs)
+	report, err := RunPipelineTests(context.Background(), files, nil, logger)
+	require.NoError(t, err)
+	require.Len(t, report.Results, 3)
+	assert.False(t, report.Passed())
+
+	results := make(map[string]TestResult)
+	for _, result := range report.Results {
+		results[result.Name] = result
+	}
+	assert.Empty(t, results["keyreg"].Failures)
+	assert.Empty(t, results["unknown exporter"].Failures)
+	assert.Equal(t, []string{
+		"exporter (noop): expected rounds [1], received [0]",
+		"exporter (noop): all \"txn.type == 'pay'\": 0 of 3 matched",
+		"exporter (postgresql) is not a recorded exporter of the pipeline",
+		"metric conduit_imported_round: expected at most -1, found 0",
+	}, results["wrong expectations"].Failures)
+	assert.NotEmpty(t, logs.String())
+
+	var out bytes.Buffer
+	require.NoError(t, report.Write(&out))
+	assert.Contains(t, out.String(), "--- PASS: keyreg")
+	assert.Contains(t, out.String(), "--- FAIL: wrong expectations")
+	assert.Contains(t, out.String(), "FAIL: 3 tests, 1 failed\n")
+
+	// the pattern selects the tests by name.
+	report, err = RunPipelineTests(context.Background(), files, regexp.MustCompile("^key"), logger)
+	require.NoError(t, err)
+	require.Len(t, report.Results, 1)
+	assert.True(t, report.Passed())
+}
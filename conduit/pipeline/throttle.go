@@ -0,0 +1,111 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+// defaultTipInterval is how often the latest round is requested while a round
+// is held by lag-rounds.
+const defaultTipInterval = time.Second
+
+// Throttle configs limit how fast the pipeline processes rounds, for example
+// so that a database is not overwhelmed while catching up, or to trail the
+// latest round by a number of rounds.
+type Throttle struct {
+	// MaxRoundsPerSecond limits the rate at which rounds are processed. Zero
+	// disables the limit.
+	MaxRoundsPerSecond float64 `yaml:"max-rounds-per-second"`
+	// LagRounds holds each round until the latest round is at least LagRounds
+	// later. The importer must implement conduit.TipProvider. Zero disables
+	// the lag target.
+	LagRounds uint64 `yaml:"lag-rounds"`
+	// TipInterval is how often the latest round is requested while a round
+	// is held by LagRounds. The default is 1s.
+	TipInterval time.Duration `yaml:"tip-interval"`
+}
+
+// Valid validates the throttle config.
+func (t Throttle) Valid() error {
+	if t.MaxRoundsPerSecond < 0 {
+		return fmt.Errorf("max-rounds-per-second must not be negative (%g)", t.MaxRoundsPerSecond)
+	}
+	if t.TipInterval < 0 {
+		return fmt.Errorf("tip-interval must not be negative (%s)", t.TipInterval)
+	}
+	return nil
+}
+
+func (t Throttle) tipInterval() time.Duration {
+	if t.TipInterval == 0 {
+		return defaultTipInterval
+	}
+	return t.TipInterval
+}
+
+// throttleState is used by the pipeline loop only.
+type throttleState struct {
+	// next is when the next round may start under max-rounds-per-second.
+	next time.Time
+	// tip is the latest round reported by the importer, tipChecked is when
+	// it was requested.
+	tip        uint64
+	tipChecked time.Time
+}
+
+// checkThrottle returns an error if the throttle cannot be applied with the
+// importer.
+func (p *pipelineImpl) checkThrottle(t Throttle) error {
+	if t.LagRounds == 0 {
+		return nil
+	}
+	if _, ok := (*p.importer).(conduit.TipProvider); !ok {
+		return fmt.Errorf("throttle lag-rounds requires an importer which reports the latest round, %s does not", (*p.importer).Metadata().Name)
+	}
+	return nil
+}
+
+// throttleDelay returns how long the round is held by the throttle, 0 if it
+// is processed now.
+func (p *pipelineImpl) throttleDelay(round uint64, now time.Time) time.Duration {
+	t := p.cfg.Throttle
+	state := &p.throttle
+	if t.LagRounds > 0 && state.tip < round+t.LagRounds {
+		interval := t.tipInterval()
+		if state.tipChecked.IsZero() || now.Sub(state.tipChecked) >= interval {
+			p.refreshTip(now)
+		}
+		if state.tip < round+t.LagRounds {
+			p.logger.Debugf("Holding round %d until round %d, the latest round is %d", round, round+t.LagRounds, state.tip)
+			return state.tipChecked.Add(interval).Sub(now)
+		}
+	}
+	if t.MaxRoundsPerSecond > 0 {
+		if now.Before(state.next) {
+			return state.next.Sub(now)
+		}
+		state.next = now.Add(time.Duration(float64(time.Second) / t.MaxRoundsPerSecond))
+	}
+	return 0
+}
+
+// refreshTip requests the latest round from the importer.
+func (p *pipelineImpl) refreshTip(now time.Time) {
+	p.throttle.tipChecked = now
+	provider, ok := (*p.importer).(conduit.TipProvider)
+	if !ok {
+		return
+	}
+	tip, err := provider.LatestRound()
+	if err != nil {
+		p.logger.Warnf("Could not get the latest round from the importer: %v", err)
+		return
+	}
+	p.throttle.tip = tip
+	p.mu.Lock()
+	p.status.LatestRound = tip
+	p.mu.Unlock()
+	p.publishLag()
+}
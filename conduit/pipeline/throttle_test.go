@@ -0,0 +1,111 @@
+package pipeline
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// tipImporter is a namedImporter which reports a latest round.
+type tipImporter struct {
+	namedImporter
+	tipMu    sync.Mutex
+	tip      uint64
+	tipErr   error
+	tipCalls int
+}
+
+func (r *tipImporter) LatestRound() (uint64, error) {
+	r.tipMu.Lock()
+	defer r.tipMu.Unlock()
+	r.tipCalls++
+	return r.tip, r.tipErr
+}
+
+func (r *tipImporter) setTip(tip uint64) {
+	r.tipMu.Lock()
+	defer r.tipMu.Unlock()
+	r.tip = tip
+}
+
+func TestThrottleValid(t *testing.T) {
+	assert.NoError(t, Throttle{}.Valid())
+	assert.NoError(t, Throttle{MaxRoundsPerSecond: 0.5, LagRounds: 10, TipInterval: time.Second}.Valid())
+	assert.EqualError(t, Throttle{MaxRoundsPerSecond: -1}.Valid(), "max-rounds-per-second must not be negative (-1)")
+	assert.EqualError(t, Throttle{TipInterval: -time.Second}.Valid(), "tip-interval must not be negative (-1s)")
+}
+
+func TestThrottleRate(t *testing.T) {
+	pImpl := makeCheckpointPipeline(t)
+	pImpl.cfg.Throttle = Throttle{MaxRoundsPerSecond: 10}
+	start := time.Now()
+	assert.Zero(t, pImpl.throttleDelay(0, start))
+	assert.Equal(t, 50*time.Millisecond, pImpl.throttleDelay(1, start.Add(50*time.Millisecond)))
+	assert.Zero(t, pImpl.throttleDelay(1, start.Add(100*time.Millisecond)))
+	assert.Equal(t, 100*time.Millisecond, pImpl.throttleDelay(2, start.Add(100*time.Millisecond)))
+}
+
+func TestThrottleLag(t *testing.T) {
+	pImpl := makeCheckpointPipeline(t)
+	imp := &tipImporter{namedImporter: namedImporter{roundImporter{failRound: 1000}}, tip: 12}
+	var pImporter importers.Importer = imp
+	pImpl.importer = &pImporter
+	pImpl.cfg.Throttle = Throttle{LagRounds: 5, TipInterval: time.Second}
+	start := time.Now()
+
+	assert.Zero(t, pImpl.throttleDelay(7, start))
+	// The tip is only requested again when it is too low.
+	assert.Zero(t, pImpl.throttleDelay(7, start))
+	assert.Equal(t, 1, imp.tipCalls)
+	assert.Equal(t, uint64(12), pImpl.Status().LatestRound)
+
+	assert.Equal(t, time.Second, pImpl.throttleDelay(8, start.Add(time.Second)))
+	assert.Equal(t, 2, imp.tipCalls)
+	assert.Equal(t, 500*time.Millisecond, pImpl.throttleDelay(8, start.Add(1500*time.Millisecond)))
+	assert.Equal(t, 2, imp.tipCalls)
+
+	imp.setTip(13)
+	assert.Zero(t, pImpl.throttleDelay(8, start.Add(2*time.Second)))
+
+	// A failure keeps the last known tip.
+	imp.tipErr = errors.New("node unavailable")
+	assert.Equal(t, time.Second, pImpl.throttleDelay(9, start.Add(3*time.Second)))
+	assert.Equal(t, uint64(13), pImpl.Status().LatestRound)
+}
+
+func TestCheckThrottle(t *testing.T) {
+	pImpl := makeCheckpointPipeline(t)
+	assert.NoError(t, pImpl.checkThrottle(Throttle{MaxRoundsPerSecond: 1}))
+	assert.EqualError(t, pImpl.checkThrottle(Throttle{LagRounds: 1}), "throttle lag-rounds requires an importer which reports the latest round, test_importer does not")
+
+	var pImporter importers.Importer = &tipImporter{}
+	pImpl.importer = &pImporter
+	assert.NoError(t, pImpl.checkThrottle(Throttle{LagRounds: 1}))
+}
+
+// TestPipelineThrottleLag tests that the pipeline trails the latest round.
+func TestPipelineThrottleLag(t *testing.T) {
+	exp := &roundExporter{name: "exporter"}
+	pImpl := makeCheckpointPipeline(t, exp)
+	imp := &tipImporter{namedImporter: namedImporter{roundImporter{failRound: 1000}}, tip: 3}
+	var pImporter importers.Importer = imp
+	pImpl.importer = &pImporter
+	pImpl.cfg.Throttle = Throttle{LagRounds: 2, TipInterval: 10 * time.Millisecond}
+
+	pImpl.Start()
+	defer func() {
+		pImpl.cf()
+		pImpl.Wait()
+	}()
+	assert.Equal(t, 2, waitHeld(t, exp))
+
+	imp.setTip(10)
+	require.Eventually(t, func() bool { return len(exp.received()) == 9 }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, 9, waitHeld(t, exp))
+}
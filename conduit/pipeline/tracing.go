@@ -0,0 +1,52 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/algorand/conduit/conduit/tracing"
+)
+
+// Telemetry configs for exporting OpenTelemetry traces, with one trace per
+// round and a span for each plugin call.
+type Telemetry struct {
+	// Endpoint is the OTLP/HTTP traces URL, for example
+	// http://localhost:4318/v1/traces. Tracing is disabled when it is empty.
+	Endpoint string `yaml:"endpoint"`
+	// SampleRate is the fraction of rounds which are traced, between 0 and 1.
+	SampleRate float64 `yaml:"sample-rate"`
+	// Headers are added to the export requests, for example for authentication.
+	Headers map[string]string `yaml:"headers"`
+	// ServiceName is the service.name of the traces, it defaults to "conduit".
+	ServiceName string `yaml:"service-name"`
+}
+
+// Valid validates the telemetry config.
+func (t Telemetry) Valid() error {
+	if t.SampleRate < 0 || t.SampleRate > 1 {
+		return fmt.Errorf("sample-rate (%v) must be between 0 and 1", t.SampleRate)
+	}
+	return nil
+}
+
+// makeTracer returns a tracer for the config, or nil if tracing is disabled.
+func (p *pipelineImpl) makeTracer() *tracing.Tracer {
+	if p.cfg.Telemetry.Endpoint == "" {
+		return nil
+	}
+	p.logger.Infof("Exporting traces of %v of the rounds to %s", p.cfg.Telemetry.SampleRate, p.cfg.Telemetry.Endpoint)
+	return tracing.NewTracer(tracing.Config{
+		Endpoint:    p.cfg.Telemetry.Endpoint,
+		Headers:     p.cfg.Telemetry.Headers,
+		ServiceName: p.cfg.Telemetry.ServiceName,
+		SampleRate:  p.cfg.Telemetry.SampleRate,
+	}, p.logger)
+}
+
+// pluginSpan starts a span for a plugin call within the round span.
+func pluginSpan(round *tracing.Span, name string, pluginName string, start time.Time) *tracing.Span {
+	if round == nil {
+		return nil
+	}
+	return round.Child(name, start, tracing.String("conduit.plugin.name", pluginName))
+}
@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+func TestTelemetryValid(t *testing.T) {
+	assert.NoError(t, Telemetry{}.Valid())
+	assert.NoError(t, Telemetry{SampleRate: 0.5}.Valid())
+	assert.EqualError(t, Telemetry{SampleRate: 1.5}.Valid(), "sample-rate (1.5) must be between 0 and 1")
+	assert.EqualError(t, Telemetry{SampleRate: -1}.Valid(), "sample-rate (-1) must be between 0 and 1")
+}
+
+// namedImporter adds metadata to the roundImporter.
+type namedImporter struct {
+	roundImporter
+}
+
+func (r *namedImporter) Metadata() conduit.Metadata {
+	return conduit.Metadata{Name: "test_importer"}
+}
+
+// TestPipelineTracing tests that each round attempt is traced with spans for
+// the importer and exporter calls.
+func TestPipelineTracing(t *testing.T) {
+	var mu sync.Mutex
+	var spans []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []map[string]interface{} `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}))
+	defer server.Close()
+
+	exp := &roundExporter{name: "test_exporter", failRound: 1, failCount: 1}
+	pImpl := makeReloadPipeline(t, exp)
+	var imp importers.Importer = &namedImporter{roundImporter{failRound: 1000}}
+	pImpl.importer = &imp
+	pImpl.cfg.Telemetry = Telemetry{Endpoint: server.URL, SampleRate: 1}
+	pImpl.tracer = pImpl.makeTracer()
+
+	pImpl.Start()
+	require.Eventually(t, func() bool { return len(exp.received()) >= 3 }, 5*time.Second, time.Millisecond)
+	pImpl.cf()
+	pImpl.Wait()
+	pImpl.stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	names := make(map[string]int)
+	var failed []string
+	for _, span := range spans {
+		names[span["name"].(string)]++
+		if status, ok := span["status"].(map[string]interface{}); ok && status["message"] != nil {
+			failed = append(failed, span["name"].(string))
+		}
+	}
+	assert.GreaterOrEqual(t, names["round"], 4)
+	assert.Equal(t, names["round"], names["importer.GetBlock"])
+	// the last round may have been cancelled before it was exported
+	assert.GreaterOrEqual(t, names["exporter.Receive"], names["round"]-1)
+	// the exporter failed on the first attempt of round 1
+	assert.ElementsMatch(t, []string{"round", "exporter.Receive"}, failed)
+}
@@ -0,0 +1,132 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+)
+
+// commitIntent is the write-ahead record of a round prepared by an
+// exporters.TransactionalExporter. It is saved before the round is committed,
+// so that a round which was prepared before the pipeline stopped is committed
+// instead of being exported again.
+type commitIntent struct {
+	Round    uint64                `json:"round"`
+	Exporter int                   `json:"exporter"`
+	Name     string                `json:"name"`
+	Token    exporters.CommitToken `json:"token"`
+	// committed is set once Commit succeeds, committed rounds are not rolled
+	// back if the round is skipped.
+	committed bool
+}
+
+// intent returns the commit intent of the exporter at idx for the round, nil
+// if the round has not been prepared. An intent of the round which was saved by
+// the exporter at another index, or by another exporter at idx, is an error:
+// the exporters were reordered or renamed since the round was prepared, and
+// its token must not be committed by the wrong exporter.
+func (p *pipelineImpl) intent(round uint64, idx int) (*commitIntent, error) {
+	name := p.exporterName(idx)
+	for i := range p.pipelineMetadata.CommitIntents {
+		intent := &p.pipelineMetadata.CommitIntents[i]
+		if intent.Round != round || (intent.Exporter != idx && intent.Name != name) {
+			continue
+		}
+		if intent.Exporter != idx || intent.Name != name {
+			return nil, fmt.Errorf("intent(): round %d was prepared by exporter %d (%s) but exporter %d is %s, restore the exporters of the previous run to commit the round", round, intent.Exporter, intent.Name, idx, name)
+		}
+		return intent, nil
+	}
+	return nil, nil
+}
+
+// receive sends the block to the exporter at idx. Transactional exporters
+// prepare the round, the commit intent is saved and then the round is
+// committed.
+func (p *pipelineImpl) receive(idx int, exporter exporters.Exporter, blk data.BlockData) error {
+	te, ok := exporter.(exporters.TransactionalExporter)
+	if !ok {
+		return exporter.Receive(blk)
+	}
+	name := p.exporterName(idx)
+	round := blk.Round()
+	intent, err := p.intent(round, idx)
+	if err != nil {
+		return fmt.Errorf("receive(): exporter (%s): %w", name, err)
+	}
+	if intent == nil {
+		token, err := te.Prepare(blk)
+		if err != nil {
+			return fmt.Errorf("receive(): exporter (%s) could not prepare round %d: %w", name, round, err)
+		}
+		if token == "" {
+			// The exporter committed the round.
+			return nil
+		}
+		p.pipelineMetadata.CommitIntents = append(p.pipelineMetadata.CommitIntents, commitIntent{Round: round, Exporter: idx, Name: name, Token: token})
+		if err = p.saveMetadata(); err != nil {
+			p.pipelineMetadata.CommitIntents = p.pipelineMetadata.CommitIntents[:len(p.pipelineMetadata.CommitIntents)-1]
+			if rbErr := te.Rollback(token); rbErr != nil {
+				p.logger.Errorf("receive(): exporter (%s) could not roll back round %d: %v", name, round, rbErr)
+			}
+			return fmt.Errorf("receive(): unable to save the commit intent of round %d: %w", round, err)
+		}
+		intent = &p.pipelineMetadata.CommitIntents[len(p.pipelineMetadata.CommitIntents)-1]
+	} else {
+		// The round was prepared by a previous attempt, possibly before a
+		// restart, and the decision to commit it was already saved.
+		p.logger.Infof("exporter (%s) committing round %d which was prepared by a previous attempt", name, round)
+	}
+	if err := te.Commit(intent.Token); err != nil {
+		return fmt.Errorf("receive(): exporter (%s) could not commit round %d: %w", name, round, err)
+	}
+	intent.committed = true
+	return nil
+}
+
+// rollbackIntents rolls back the rounds which were prepared but not committed
+// in the round, which is about to be skipped.
+func (p *pipelineImpl) rollbackIntents(round uint64) {
+	for _, intent := range p.pipelineMetadata.CommitIntents {
+		if intent.Round != round || intent.committed || intent.Exporter >= len(p.exporters) {
+			continue
+		}
+		if name := p.exporterName(intent.Exporter); name != intent.Name {
+			p.logger.Errorf("rollbackIntents(): round %d was prepared by exporter %s but exporter %d is %s, it is not rolled back", round, intent.Name, intent.Exporter, name)
+			continue
+		}
+		te, ok := (*p.exporters[intent.Exporter]).(exporters.TransactionalExporter)
+		if !ok {
+			continue
+		}
+		if err := te.Rollback(intent.Token); err != nil {
+			p.logger.Errorf("rollbackIntents(): exporter (%s) could not roll back round %d: %v", intent.Name, round, err)
+		}
+	}
+	p.dropIntents(round + 1)
+}
+
+// dropIntents removes the commit intents of the rounds before nextRound, they
+// are saved with the next metadata update.
+func (p *pipelineImpl) dropIntents(nextRound uint64) {
+	var intents []commitIntent
+	for _, intent := range p.pipelineMetadata.CommitIntents {
+		if intent.Round >= nextRound {
+			intents = append(intents, intent)
+		}
+	}
+	p.pipelineMetadata.CommitIntents = intents
+}
+
+// dropRoundIntents removes the commit intents of a replayed round, the intents
+// of the other rounds are kept.
+func (p *pipelineImpl) dropRoundIntents(round uint64) {
+	var intents []commitIntent
+	for _, intent := range p.pipelineMetadata.CommitIntents {
+		if intent.Round != round {
+			intents = append(intents, intent)
+		}
+	}
+	p.pipelineMetadata.CommitIntents = intents
+}
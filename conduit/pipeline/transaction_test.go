@@ -0,0 +1,181 @@
+package pipeline
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+)
+
+// txnExporter implements exporters.TransactionalExporter.
+type txnExporter struct {
+	roundExporter
+	prepared   []uint64
+	committed  []exporters.CommitToken
+	rolledBack []exporters.CommitToken
+	// commitFailRound fails commitFailCount commits of the round.
+	commitFailRound uint64
+	commitFailCount int
+	// unprepared commits the rounds in Prepare.
+	unprepared bool
+}
+
+func (e *txnExporter) Prepare(exportData data.BlockData) (exporters.CommitToken, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.prepared = append(e.prepared, exportData.Round())
+	if e.unprepared {
+		return "", nil
+	}
+	return exporters.CommitToken(fmt.Sprintf("txn-%d", exportData.Round())), nil
+}
+
+func (e *txnExporter) Commit(token exporters.CommitToken) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if token == exporters.CommitToken(fmt.Sprintf("txn-%d", e.commitFailRound)) && e.commitFailCount > 0 {
+		e.commitFailCount--
+		return fmt.Errorf("commit")
+	}
+	e.committed = append(e.committed, token)
+	return nil
+}
+
+func (e *txnExporter) Rollback(token exporters.CommitToken) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rolledBack = append(e.rolledBack, token)
+	return nil
+}
+
+// TestPipelineTransactionalExporter tests that a failed commit is retried
+// without preparing the round again.
+func TestPipelineTransactionalExporter(t *testing.T) {
+	exp := &txnExporter{roundExporter: roundExporter{name: "exporter"}, commitFailRound: 2, commitFailCount: 1}
+	pImpl := makeReloadPipeline(t, exp)
+	pImpl.cfg.Rounds.End = 3
+
+	pImpl.Start()
+	pImpl.Wait()
+	require.NoError(t, pImpl.Error())
+
+	assert.Equal(t, []uint64{0, 1, 2, 3}, exp.prepared)
+	assert.Equal(t, []exporters.CommitToken{"txn-0", "txn-1", "txn-2", "txn-3"}, exp.committed)
+	assert.Empty(t, exp.received())
+	metadata := readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir)
+	assert.Equal(t, uint64(4), metadata.NextRound)
+	assert.Empty(t, metadata.CommitIntents)
+}
+
+// TestPipelineTransactionalExporterRecovery tests that a round prepared before
+// a restart is committed instead of exported again.
+func TestPipelineTransactionalExporterRecovery(t *testing.T) {
+	exp := &txnExporter{roundExporter: roundExporter{name: "exporter"}}
+	pImpl := makeReloadPipeline(t, exp)
+	pImpl.cfg.Rounds.End = 3
+	pImpl.pipelineMetadata = state{
+		NextRound:     2,
+		CommitIntents: []commitIntent{{Round: 2, Exporter: 0, Name: "exporter", Token: "prepared-2"}},
+	}
+
+	pImpl.Start()
+	pImpl.Wait()
+	require.NoError(t, pImpl.Error())
+
+	assert.Equal(t, []uint64{3}, exp.prepared)
+	assert.Equal(t, []exporters.CommitToken{"prepared-2", "txn-3"}, exp.committed)
+	assert.Empty(t, readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir).CommitIntents)
+}
+
+// TestPipelineTransactionalExporterUnprepared tests that no commit intent is
+// saved for the rounds which were committed by Prepare.
+func TestPipelineTransactionalExporterUnprepared(t *testing.T) {
+	exp := &txnExporter{roundExporter: roundExporter{name: "exporter"}, unprepared: true}
+	pImpl := makeReloadPipeline(t, exp)
+	pImpl.cfg.Rounds.End = 3
+
+	pImpl.Start()
+	pImpl.Wait()
+	require.NoError(t, pImpl.Error())
+
+	assert.Equal(t, []uint64{0, 1, 2, 3}, exp.prepared)
+	assert.Empty(t, exp.committed)
+	assert.Empty(t, readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir).CommitIntents)
+}
+
+// TestPipelineTransactionalExporterSkip tests that a prepared round is rolled
+// back when it is skipped.
+func TestPipelineTransactionalExporterSkip(t *testing.T) {
+	exp := &txnExporter{roundExporter: roundExporter{name: "exporter"}, commitFailRound: 2, commitFailCount: math.MaxInt}
+	pImpl := makeFailurePipeline(t, &roundImporter{failRound: math.MaxUint64}, &exp.roundExporter, onFailureSkip)
+	var pExporter exporters.Exporter = exp
+	pImpl.exporters = []*exporters.Exporter{&pExporter}
+	pImpl.cfg.Rounds.End = 3
+
+	pImpl.Start()
+	pImpl.Wait()
+
+	assert.Equal(t, []uint64{0, 1, 2, 3}, exp.prepared)
+	assert.Equal(t, []exporters.CommitToken{"txn-0", "txn-1", "txn-3"}, exp.committed)
+	assert.Equal(t, []exporters.CommitToken{"txn-2"}, exp.rolledBack)
+	metadata := readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir)
+	require.Len(t, metadata.FailedRounds, 1)
+	assert.Equal(t, uint64(2), metadata.FailedRounds[0].Round)
+	assert.Empty(t, metadata.CommitIntents)
+}
+
+func TestIntent(t *testing.T) {
+	first := &txnExporter{roundExporter: roundExporter{name: "first"}}
+	second := &txnExporter{roundExporter: roundExporter{name: "second"}}
+	tests := []struct {
+		name   string
+		intent commitIntent
+		idx    int
+		found  bool
+		err    string
+	}{
+		{"match", commitIntent{Round: 2, Exporter: 1, Name: "second"}, 1, true, ""},
+		{"other exporter", commitIntent{Round: 2, Exporter: 0, Name: "first"}, 1, false, ""},
+		{"other round", commitIntent{Round: 3, Exporter: 1, Name: "second"}, 1, false, ""},
+		{"renamed", commitIntent{Round: 2, Exporter: 1, Name: "archive"}, 1, false, "intent(): round 2 was prepared by exporter 1 (archive) but exporter 1 is second, restore the exporters of the previous run to commit the round"},
+		{"reordered", commitIntent{Round: 2, Exporter: 0, Name: "second"}, 1, false, "intent(): round 2 was prepared by exporter 0 (second) but exporter 1 is second, restore the exporters of the previous run to commit the round"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pImpl := makeReloadPipeline(t, first, second)
+			pImpl.pipelineMetadata.CommitIntents = []commitIntent{tc.intent}
+			intent, err := pImpl.intent(2, tc.idx)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.found, intent != nil)
+		})
+	}
+}
+
+// TestPipelineTransactionalExporterMismatch tests that a round prepared by
+// another exporter is not committed.
+func TestPipelineTransactionalExporterMismatch(t *testing.T) {
+	exp := &txnExporter{roundExporter: roundExporter{name: "exporter"}}
+	pImpl := makeReloadPipeline(t, exp)
+	pImpl.cfg.RetryCount = 1
+	pImpl.cfg.Rounds.End = 3
+	pImpl.pipelineMetadata = state{
+		NextRound:     2,
+		CommitIntents: []commitIntent{{Round: 2, Exporter: 0, Name: "archive", Token: "prepared-2"}},
+	}
+
+	pImpl.Start()
+	pImpl.Wait()
+	assert.ErrorContains(t, pImpl.Error(), "round 2 was prepared by exporter 0 (archive) but exporter 0 is exporter")
+	assert.Empty(t, exp.prepared)
+	assert.Empty(t, exp.committed)
+	assert.Empty(t, exp.rolledBack)
+}
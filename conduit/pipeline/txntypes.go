@@ -0,0 +1,111 @@
+package pipeline
+
+import (
+	"fmt"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+const (
+	// unknownTxnTypesPass logs a warning and processes the transactions.
+	unknownTxnTypesPass = "pass"
+	// unknownTxnTypesTag processes the transactions and lists them in
+	// data.BlockData.UnknownTxns.
+	unknownTxnTypesTag = "tag"
+	// unknownTxnTypesDrop removes the transactions from the payset.
+	unknownTxnTypesDrop = "drop"
+	// unknownTxnTypesHalt stops the pipeline before the block is processed.
+	unknownTxnTypesHalt = "halt"
+)
+
+// knownTxnTypes are the transaction types conduit supports, an empty type is
+// known so that test fixtures are not flagged.
+var knownTxnTypes = map[sdk.TxType]bool{
+	"":                    true,
+	sdk.PaymentTx:         true,
+	sdk.KeyRegistrationTx: true,
+	sdk.AssetConfigTx:     true,
+	sdk.AssetTransferTx:   true,
+	sdk.AssetFreezeTx:     true,
+	sdk.ApplicationCallTx: true,
+	sdk.StateProofTx:      true,
+}
+
+func validUnknownTxnTypes(policy string) error {
+	switch policy {
+	case "", unknownTxnTypesPass, unknownTxnTypesTag, unknownTxnTypesDrop, unknownTxnTypesHalt:
+		return nil
+	default:
+		return fmt.Errorf("unknown-txn-types must be '%s', '%s', '%s' or '%s', found '%s'", unknownTxnTypesPass, unknownTxnTypesTag, unknownTxnTypesDrop, unknownTxnTypesHalt, policy)
+	}
+}
+
+// unknownTxnTypeError is returned with the halt policy for the first
+// transaction of an unknown type.
+type unknownTxnTypeError struct {
+	round uint64
+	intra uint64
+	typ   string
+}
+
+func (e unknownTxnTypeError) Error() string {
+	return fmt.Sprintf("transaction %d of block %d has the unknown type %s, conduit may need to be upgraded", e.intra, e.round, e.typ)
+}
+
+// unknownTxnType returns the type of the transaction if it is unknown,
+// otherwise the first unknown type of its inner transactions, or "".
+func unknownTxnType(stxn *sdk.SignedTxnWithAD) string {
+	if !knownTxnTypes[stxn.Txn.Type] {
+		return string(stxn.Txn.Type)
+	}
+	for i := range stxn.EvalDelta.InnerTxns {
+		if typ := unknownTxnType(&stxn.EvalDelta.InnerTxns[i]); typ != "" {
+			return typ
+		}
+	}
+	return ""
+}
+
+// checkTxnTypes applies the unknown-txn-types policy to the top level
+// transactions of the block which are, or contain, transactions of an
+// unknown type. With halt an unknownTxnTypeError is returned and the block is
+// unchanged.
+func (p *pipelineImpl) checkTxnTypes(blk *data.BlockData) error {
+	var unknown []data.UnknownTxn
+	for idx := range blk.Payset {
+		if typ := unknownTxnType(&blk.Payset[idx].SignedTxnWithAD); typ != "" {
+			unknown = append(unknown, data.UnknownTxn{Intra: uint64(idx), Type: typ})
+			metrics.UnknownTxnTypes.WithLabelValues(typ).Inc()
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	round := uint64(blk.BlockHeader.Round)
+	logger := p.logger.WithField("alert", "unknown-txn-type")
+	switch p.cfg.UnknownTxnTypes {
+	case unknownTxnTypesHalt:
+		return unknownTxnTypeError{round: round, intra: unknown[0].Intra, typ: unknown[0].Type}
+	case unknownTxnTypesTag:
+		blk.UnknownTxns = unknown
+		logger.Warnf("block %d has %d transactions of an unknown type, starting with %s, they are tagged", round, len(unknown), unknown[0].Type)
+	case unknownTxnTypesDrop:
+		payset := blk.Payset[:0]
+		next := 0
+		for idx := range blk.Payset {
+			if next < len(unknown) && unknown[next].Intra == uint64(idx) {
+				next++
+				continue
+			}
+			payset = append(payset, blk.Payset[idx])
+		}
+		blk.Payset = payset
+		logger.Warnf("block %d has %d transactions of an unknown type, starting with %s, they are dropped", round, len(unknown), unknown[0].Type)
+	default:
+		logger.Warnf("block %d has %d transactions of an unknown type, starting with %s", round, len(unknown), unknown[0].Type)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// txnTypeImporter adds a transaction of an unknown type to round 2.
+type txnTypeImporter struct {
+	namedImporter
+}
+
+func (r *txnTypeImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	blk, err := r.namedImporter.GetBlock(rnd)
+	if rnd == 2 {
+		blk.Payset = []sdk.SignedTxnInBlock{makeTypedTxn("hb")}
+	}
+	return blk, err
+}
+
+func makeTypedTxn(typ sdk.TxType, inner ...sdk.TxType) sdk.SignedTxnInBlock {
+	var stxn sdk.SignedTxnInBlock
+	stxn.Txn.Type = typ
+	for _, t := range inner {
+		var itxn sdk.SignedTxnWithAD
+		itxn.Txn.Type = t
+		stxn.EvalDelta.InnerTxns = append(stxn.EvalDelta.InnerTxns, itxn)
+	}
+	return stxn
+}
+
+func TestValidUnknownTxnTypes(t *testing.T) {
+	for _, policy := range []string{"", unknownTxnTypesPass, unknownTxnTypesTag, unknownTxnTypesDrop, unknownTxnTypesHalt} {
+		assert.NoError(t, validUnknownTxnTypes(policy))
+	}
+	assert.EqualError(t, validUnknownTxnTypes("skip"), "unknown-txn-types must be 'pass', 'tag', 'drop' or 'halt', found 'skip'")
+}
+
+func TestCheckTxnTypes(t *testing.T) {
+	metrics.RegisterPrometheusMetrics("txntypes_test")
+	block := func() *data.BlockData {
+		return &data.BlockData{
+			BlockHeader: sdk.BlockHeader{Round: 7},
+			Payset: []sdk.SignedTxnInBlock{
+				makeTypedTxn(sdk.PaymentTx),
+				makeTypedTxn("hb"),
+				makeTypedTxn(sdk.ApplicationCallTx, sdk.PaymentTx, "xyz"),
+				makeTypedTxn(sdk.AssetTransferTx),
+			},
+		}
+	}
+	types := func(blk *data.BlockData) []sdk.TxType {
+		var ret []sdk.TxType
+		for _, stxn := range blk.Payset {
+			ret = append(ret, stxn.Txn.Type)
+		}
+		return ret
+	}
+	unknown := []data.UnknownTxn{{Intra: 1, Type: "hb"}, {Intra: 2, Type: "xyz"}}
+
+	tests := []struct {
+		policy string
+		types  []sdk.TxType
+		tagged []data.UnknownTxn
+		err    string
+	}{
+		{policy: "", types: types(block())},
+		{policy: unknownTxnTypesPass, types: types(block())},
+		{policy: unknownTxnTypesTag, types: types(block()), tagged: unknown},
+		{policy: unknownTxnTypesDrop, types: []sdk.TxType{sdk.PaymentTx, sdk.AssetTransferTx}},
+		{policy: unknownTxnTypesHalt, types: types(block()), err: "transaction 1 of block 7 has the unknown type hb, conduit may need to be upgraded"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.policy, func(t *testing.T) {
+			logger, hook := test.NewNullLogger()
+			p := &pipelineImpl{cfg: &Config{UnknownTxnTypes: tc.policy}, logger: logger}
+			blk := block()
+			err := p.checkTxnTypes(blk)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, hook.AllEntries(), 1)
+				assert.Equal(t, "unknown-txn-type", hook.LastEntry().Data["alert"])
+			}
+			assert.Equal(t, tc.types, types(blk))
+			assert.Equal(t, tc.tagged, blk.UnknownTxns)
+		})
+	}
+	assert.Equal(t, float64(len(tests)), testutil.ToFloat64(metrics.UnknownTxnTypes.WithLabelValues("hb")))
+
+	// Blocks without unknown types are unchanged.
+	logger, hook := test.NewNullLogger()
+	p := &pipelineImpl{cfg: &Config{UnknownTxnTypes: unknownTxnTypesHalt}, logger: logger}
+	blk := &data.BlockData{Payset: []sdk.SignedTxnInBlock{makeTypedTxn(sdk.KeyRegistrationTx), makeTypedTxn("")}}
+	assert.NoError(t, p.checkTxnTypes(blk))
+	assert.Empty(t, hook.AllEntries())
+}
+
+func TestPipelineUnknownTxnTypes(t *testing.T) {
+	tests := []struct {
+		policy   string
+		received []uint64
+		next     uint64
+	}{
+		{policy: unknownTxnTypesPass, received: []uint64{0, 1, 2, 3}, next: 4},
+		{policy: unknownTxnTypesHalt, received: []uint64{0, 1}, next: 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.policy, func(t *testing.T) {
+			exp := &roundExporter{name: "exporter"}
+			pImpl := makeCheckpointPipeline(t, exp)
+			var pImporter importers.Importer = &txnTypeImporter{namedImporter{roundImporter{failRound: 1000}}}
+			pImpl.importer = &pImporter
+			pImpl.cfg.UnknownTxnTypes = tc.policy
+			pImpl.cfg.Rounds.End = 3
+
+			pImpl.Start()
+			pImpl.Wait()
+			assert.Equal(t, tc.received, exp.received())
+			assert.Equal(t, tc.next, readState(t, pImpl.cfg.ConduitArgs.ConduitDataDir).NextRound)
+			if tc.policy == unknownTxnTypesHalt {
+				var unknown unknownTxnTypeError
+				assert.ErrorAs(t, pImpl.Error(), &unknown)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package pipeline
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+const (
+	// invalidRetry fetches the round again, as if the importer failed.
+	invalidRetry = "retry"
+	// invalidWarn logs a warning and exports the block.
+	invalidWarn = "warn"
+)
+
+// The checks which an imported block can fail, used as the metric label.
+const (
+	checkRound        = "round"
+	checkGenesisHash  = "genesis-hash"
+	checkPreviousHash = "previous-hash"
+	checkBlockHash    = "block-hash"
+)
+
+// blockHashPrefix is the domain separation prefix of block header hashes.
+var blockHashPrefix = []byte("BH")
+
+// BlockValidation configs for verifying that imported blocks form a chain.
+type BlockValidation struct {
+	// Enabled verifies the round, genesis hash, previous block hash and, when
+	// the block has a certificate, the block hash of each imported block.
+	Enabled bool `yaml:"enabled"`
+	// OnInvalid is either "retry" (default) or "warn".
+	OnInvalid string `yaml:"on-invalid"`
+}
+
+// Valid validates the block validation config.
+func (bv BlockValidation) Valid() error {
+	switch bv.OnInvalid {
+	case "", invalidRetry, invalidWarn:
+		return nil
+	default:
+		return fmt.Errorf("on-invalid must be '%s' or '%s', found '%s'", invalidRetry, invalidWarn, bv.OnInvalid)
+	}
+}
+
+// invalidBlockError is returned when an imported block fails a check.
+type invalidBlockError struct {
+	check string
+	round uint64
+	msg   string
+}
+
+func (e invalidBlockError) Error() string {
+	return fmt.Sprintf("imported block %d is invalid, %s", e.round, e.msg)
+}
+
+// blockHash returns the hash of a block header, which the next block
+// references as its previous block hash.
+func blockHash(header *sdk.BlockHeader) sdk.BlockHash {
+	encoded := msgpack.Encode(header)
+	return sha512.Sum512_256(append(append([]byte{}, blockHashPrefix...), encoded...))
+}
+
+// encodeHash encodes a block hash the way it is written in JSON.
+func encodeHash(hash sdk.BlockHash) string {
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// certificateDigest returns the block hash which the certificate votes for, if
+// it can be found. Certificates are decoded without a schema, so the digest
+// may be raw bytes or a base64 string.
+func certificateDigest(cert *map[string]interface{}) ([]byte, bool) {
+	if cert == nil {
+		return nil, false
+	}
+	var digest interface{}
+	switch prop := (*cert)["prop"].(type) {
+	case map[string]interface{}:
+		digest = prop["dig"]
+	case map[interface{}]interface{}:
+		digest = prop["dig"]
+	}
+	switch dig := digest.(type) {
+	case []byte:
+		return dig, true
+	case string:
+		b, err := base64.StdEncoding.DecodeString(dig)
+		return b, err == nil
+	}
+	return nil, false
+}
+
+// validateBlock checks that the block is the expected round of the expected
+// network, and that it follows prev. prev is nil, or is not the previous
+// round, after a restart or when a round was skipped.
+func validateBlock(blk *data.BlockData, round uint64, genesisHash string, prev *sdk.BlockHeader) error {
+	header := &blk.BlockHeader
+	if uint64(header.Round) != round {
+		return invalidBlockError{checkRound, round, fmt.Sprintf("the importer returned round %d", header.Round)}
+	}
+	if gh := base64.StdEncoding.EncodeToString(header.GenesisHash[:]); genesisHash != "" && gh != genesisHash {
+		return invalidBlockError{checkGenesisHash, round, fmt.Sprintf("genesis hash %s does not match %s", gh, genesisHash)}
+	}
+	hash := blockHash(header)
+	if prev != nil && uint64(prev.Round)+1 == round {
+		if expected := blockHash(prev); header.Branch != expected {
+			return invalidBlockError{checkPreviousHash, round, fmt.Sprintf("previous block hash %s does not match the hash of block %d, %s", encodeHash(header.Branch), prev.Round, encodeHash(expected))}
+		}
+	}
+	if digest, ok := certificateDigest(blk.Certificate); ok && !bytes.Equal(digest, hash[:]) {
+		return invalidBlockError{checkBlockHash, round, fmt.Sprintf("block hash %s does not match the certificate", encodeHash(hash))}
+	}
+	return nil
+}
+
+// validateImported validates the block if block-validation is enabled. It
+// returns an error if the block should be fetched again.
+func (p *pipelineImpl) validateImported(blk *data.BlockData) error {
+	if !p.cfg.BlockValidation.Enabled {
+		return nil
+	}
+	err := validateBlock(blk, p.pipelineMetadata.NextRound, p.pipelineMetadata.GenesisHash, p.lastHeader)
+	if invalid, ok := err.(invalidBlockError); ok {
+		metrics.InvalidBlocks.WithLabelValues(invalid.check).Inc()
+		if p.cfg.BlockValidation.OnInvalid == invalidWarn {
+			p.logger.Warnf("%v", err)
+			err = nil
+		}
+	}
+	if err == nil {
+		header := blk.BlockHeader
+		p.lastHeader = &header
+	}
+	return err
+}
@@ -0,0 +1,152 @@
+package pipeline
+
+import (
+	"encoding/base64"
+	"sync"
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// chainBlocks returns blocks 0 to n-1 linked by their previous block hash.
+func chainBlocks(n int) []data.BlockData {
+	blocks := make([]data.BlockData, n)
+	for i := range blocks {
+		blocks[i].BlockHeader = sdk.BlockHeader{Round: sdk.Round(i), GenesisHash: sdk.Digest{7}, TimeStamp: int64(i)}
+		if i > 0 {
+			blocks[i].BlockHeader.Branch = blockHash(&blocks[i-1].BlockHeader)
+		}
+	}
+	return blocks
+}
+
+func TestBlockValidationValid(t *testing.T) {
+	assert.NoError(t, BlockValidation{}.Valid())
+	assert.NoError(t, BlockValidation{Enabled: true, OnInvalid: invalidWarn}.Valid())
+	assert.EqualError(t, BlockValidation{OnInvalid: "stop"}.Valid(), "on-invalid must be 'retry' or 'warn', found 'stop'")
+}
+
+func TestValidateBlock(t *testing.T) {
+	blocks := chainBlocks(3)
+	genesisHash := base64.StdEncoding.EncodeToString(blocks[0].BlockHeader.GenesisHash[:])
+	hash := blockHash(&blocks[2].BlockHeader)
+	otherPrev := blocks[0].BlockHeader
+	otherPrev.TimeStamp = 100
+	otherPrev.Round = 1
+
+	tests := []struct {
+		name  string
+		blk   data.BlockData
+		round uint64
+		prev  *sdk.BlockHeader
+		check string
+	}{
+		{name: "linked", blk: blocks[2], round: 2, prev: &blocks[1].BlockHeader},
+		{name: "no previous block", blk: blocks[2], round: 2},
+		{name: "previous block is not the previous round", blk: blocks[2], round: 2, prev: &blocks[0].BlockHeader},
+		{name: "wrong round", blk: blocks[1], round: 2, check: checkRound},
+		{name: "fork", blk: blocks[2], round: 2, prev: &otherPrev, check: checkPreviousHash},
+		{
+			name:  "other network",
+			blk:   data.BlockData{BlockHeader: sdk.BlockHeader{Round: 2, GenesisHash: sdk.Digest{8}}},
+			round: 2,
+			check: checkGenesisHash,
+		},
+		{
+			name:  "certificate matches",
+			blk:   data.BlockData{BlockHeader: blocks[2].BlockHeader, Certificate: &map[string]interface{}{"prop": map[string]interface{}{"dig": hash[:]}}},
+			round: 2,
+		},
+		{
+			name:  "certificate matches base64",
+			blk:   data.BlockData{BlockHeader: blocks[2].BlockHeader, Certificate: &map[string]interface{}{"prop": map[interface{}]interface{}{"dig": encodeHash(hash)}}},
+			round: 2,
+		},
+		{
+			name:  "certificate does not match",
+			blk:   data.BlockData{BlockHeader: blocks[2].BlockHeader, Certificate: &map[string]interface{}{"prop": map[string]interface{}{"dig": []byte{1}}}},
+			round: 2,
+			check: checkBlockHash,
+		},
+		{
+			name:  "certificate without digest",
+			blk:   data.BlockData{BlockHeader: blocks[2].BlockHeader, Certificate: &map[string]interface{}{"rnd": 2}},
+			round: 2,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateBlock(&tc.blk, tc.round, genesisHash, tc.prev)
+			if tc.check == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tc.check, err.(invalidBlockError).check)
+		})
+	}
+}
+
+// chainImporter returns linked blocks, the block for corruptRound is returned
+// with a wrong previous block hash corruptCount times.
+type chainImporter struct {
+	namedImporter
+	blocks       []data.BlockData
+	corruptRound uint64
+	corruptCount int
+	fetches      map[uint64]int
+	mu           sync.Mutex
+}
+
+func (c *chainImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetches[rnd]++
+	blk := c.blocks[rnd]
+	if rnd == c.corruptRound && c.corruptCount > 0 {
+		c.corruptCount--
+		blk.BlockHeader.Branch = sdk.BlockHash{1}
+	}
+	return blk, nil
+}
+
+// TestPipelineBlockValidation tests that an invalid block is fetched again,
+// or exported with a warning.
+func TestPipelineBlockValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		onInvalid string
+		prefetch  uint64
+		fetches   int
+	}{
+		{name: "retry", fetches: 2},
+		{name: "retry with prefetch", prefetch: 3, fetches: 2},
+		{name: "warn", onInvalid: invalidWarn, fetches: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			exp := &roundExporter{name: "exporter"}
+			pImpl := makeReloadPipeline(t, exp)
+			imp := &chainImporter{blocks: chainBlocks(20), corruptRound: 3, corruptCount: 1, fetches: map[uint64]int{}}
+			var pImporter importers.Importer = imp
+			pImpl.importer = &pImporter
+			pImpl.cfg.BlockValidation = BlockValidation{Enabled: true, OnInvalid: tc.onInvalid}
+			pImpl.cfg.PrefetchRounds = tc.prefetch
+			pImpl.cfg.Rounds.End = 10
+
+			pImpl.Start()
+			pImpl.Wait()
+			require.NoError(t, pImpl.Error())
+
+			assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, exp.received())
+			imp.mu.Lock()
+			assert.Equal(t, tc.fetches, imp.fetches[3])
+			imp.mu.Unlock()
+		})
+	}
+}
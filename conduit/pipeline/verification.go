@@ -0,0 +1,141 @@
+package pipeline
+
+import (
+	"fmt"
+	mrand "math/rand"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+// Results of a verified round, used as the metric label.
+const (
+	verificationMatch    = "match"
+	verificationDiverged = "diverged"
+)
+
+// Verification configs for dual-write setups: a sample of the rounds is read
+// back from the exporters and compared, so that exporters which write the same
+// data to different destinations are known to agree, for example before an
+// exporter migration.
+type Verification struct {
+	// Exporters are the names of the compared exporters. The first one is the
+	// reference which the others are compared with.
+	Exporters []string `yaml:"exporters"`
+	// SampleRate is the fraction of rounds which are verified, greater than
+	// 0 and at most 1.
+	SampleRate float64 `yaml:"sample-rate"`
+	// Comparator is the name of the registered Comparator which verifies the
+	// rounds, the default is ComparatorSummary.
+	Comparator string `yaml:"comparator"`
+}
+
+// Valid validates the verification config.
+func (v Verification) Valid(exporterNames map[string]bool) error {
+	if !v.enabled() {
+		return nil
+	}
+	if len(v.Exporters) < 2 {
+		return fmt.Errorf("at least two exporters must be compared")
+	}
+	seen := make(map[string]bool)
+	for _, name := range v.Exporters {
+		if !exporterNames[name] {
+			return fmt.Errorf("exporter (%s) is not configured", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("exporter (%s) is listed more than once", name)
+		}
+		seen[name] = true
+	}
+	if v.SampleRate <= 0 || v.SampleRate > 1 {
+		return fmt.Errorf("sample-rate (%v) must be greater than 0 and at most 1", v.SampleRate)
+	}
+	if _, ok := lookupComparator(v.comparator()); !ok {
+		return fmt.Errorf("unknown comparator (%s)", v.comparator())
+	}
+	return nil
+}
+
+func (v Verification) enabled() bool {
+	return len(v.Exporters) > 0
+}
+
+func (v Verification) comparator() string {
+	if v.Comparator == "" {
+		return ComparatorSummary
+	}
+	return v.Comparator
+}
+
+// verificationRun is the runtime state of the verification.
+type verificationRun struct {
+	// exporters are the indexes of the compared exporters, the first one is
+	// the reference.
+	exporters  []int
+	comparator Comparator
+	// sample reports whether a round is verified.
+	sample func() bool
+}
+
+// initVerification resolves the exporters of the configured verification.
+func (p *pipelineImpl) initVerification() error {
+	p.verification = nil
+	v := p.cfg.Verification
+	if !v.enabled() {
+		return nil
+	}
+	comparator, ok := lookupComparator(v.comparator())
+	if !ok {
+		return fmt.Errorf("initVerification(): unknown comparator (%s)", v.comparator())
+	}
+	indexes := make(map[string]int)
+	for idx, id := range p.cfg.exporterIDs() {
+		indexes[id] = idx
+	}
+	run := &verificationRun{comparator: comparator, sample: func() bool { return mrand.Float64() < v.SampleRate }}
+	for _, name := range v.Exporters {
+		idx, ok := indexes[name]
+		if !ok {
+			return fmt.Errorf("initVerification(): exporter (%s) must be configured", name)
+		}
+		run.exporters = append(run.exporters, idx)
+	}
+	p.logger.Infof("Verifying %v of the rounds exported by %v", v.SampleRate, v.Exporters)
+	p.verification = run
+	return nil
+}
+
+// verifyRound compares a sampled round of each exporter with the reference.
+// Exporters whose when condition did not match the block, the exporter
+// retired by a migration and the standby exporter are not compared. It is called by the pipeline loop
+// once the round was exported.
+func (p *pipelineImpl) verifyRound(blk *data.BlockData) {
+	run := p.verification
+	if run == nil || !run.sample() {
+		return
+	}
+	skipped := func(idx int) bool {
+		match, err := matchCondition(p.exporterConditions, idx, blk)
+		return err != nil || !match || p.isRetired(idx) || p.isStandby(idx)
+	}
+	ref := run.exporters[0]
+	if skipped(ref) {
+		return
+	}
+	reference := *p.exporters[ref]
+	round := blk.Round()
+	for _, idx := range run.exporters[1:] {
+		if skipped(idx) {
+			continue
+		}
+		exporter := *p.exporters[idx]
+		name := p.exporterName(idx)
+		if err := run.comparator.Compare(round, reference, exporter); err != nil {
+			metrics.ExporterVerifications.WithLabelValues(name, verificationDiverged).Inc()
+			p.logger.WithField("alert", "exporter-divergence").Warnf("exporter (%s) diverged from %s at round %d: %v", name, p.exporterName(ref), round, err)
+			continue
+		}
+		metrics.ExporterVerifications.WithLabelValues(name, verificationMatch).Inc()
+	}
+}
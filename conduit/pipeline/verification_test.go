@@ -0,0 +1,84 @@
+package pipeline
+
+import (
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+func TestVerificationValid(t *testing.T) {
+	names := map[string]bool{"postgresql": true, "kafka": true, "file_writer": true}
+	tests := []struct {
+		name         string
+		verification Verification
+		err          string
+	}{
+		{"disabled", Verification{}, ""},
+		{"valid", Verification{Exporters: []string{"postgresql", "kafka", "file_writer"}, SampleRate: 0.1}, ""},
+		{"received", Verification{Exporters: []string{"postgresql", "kafka"}, SampleRate: 1, Comparator: ComparatorReceived}, ""},
+		{"one exporter", Verification{Exporters: []string{"postgresql"}, SampleRate: 1}, "at least two exporters must be compared"},
+		{"unknown exporter", Verification{Exporters: []string{"postgresql", "s3"}, SampleRate: 1}, "exporter (s3) is not configured"},
+		{"duplicate exporter", Verification{Exporters: []string{"kafka", "kafka"}, SampleRate: 1}, "exporter (kafka) is listed more than once"},
+		{"no sample rate", Verification{Exporters: []string{"postgresql", "kafka"}}, "sample-rate (0) must be greater than 0 and at most 1"},
+		{"large sample rate", Verification{Exporters: []string{"postgresql", "kafka"}, SampleRate: 2}, "sample-rate (2) must be greater than 0 and at most 1"},
+		{"unknown comparator", Verification{Exporters: []string{"postgresql", "kafka"}, SampleRate: 1, Comparator: "rows"}, "unknown comparator (rows)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.verification.Valid(names)
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+// TestPipelineVerification tests that the sampled rounds of each exporter are
+// compared with the reference and that divergences are counted.
+func TestPipelineVerification(t *testing.T) {
+	metrics.RegisterPrometheusMetrics("verification_test")
+	reference := &parityExporter{roundExporter: roundExporter{name: "reference"}}
+	same := &parityExporter{roundExporter: roundExporter{name: "same"}}
+	diverging := &parityExporter{roundExporter: roundExporter{name: "diverging"}, differ: map[uint64]bool{2: true, 5: true}}
+	unverified := &parityExporter{roundExporter: roundExporter{name: "unverified"}, differ: map[uint64]bool{1: true}}
+	pImpl := makeCheckpointPipeline(t, reference, same, diverging, unverified)
+	logger, hook := test.NewNullLogger()
+	pImpl.logger = logger
+	pImpl.cfg.Exporters = []NameConfigPair{{Name: "reference"}, {Name: "same"}, {Name: "diverging"}, {Name: "unverified"}}
+	pImpl.cfg.Verification = Verification{Exporters: []string{"reference", "same", "diverging"}, SampleRate: 1}
+	pImpl.cfg.Rounds.End = 7
+	require.NoError(t, pImpl.initVerification())
+
+	pImpl.Start()
+	pImpl.Wait()
+	require.NoError(t, pImpl.Error())
+
+	assert.Equal(t, 8.0, testutil.ToFloat64(metrics.ExporterVerifications.WithLabelValues("same", verificationMatch)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.ExporterVerifications.WithLabelValues("same", verificationDiverged)))
+	assert.Equal(t, 6.0, testutil.ToFloat64(metrics.ExporterVerifications.WithLabelValues("diverging", verificationMatch)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.ExporterVerifications.WithLabelValues("diverging", verificationDiverged)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.ExporterVerifications.WithLabelValues("unverified", verificationDiverged)))
+
+	var alerts []string
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["alert"] == "exporter-divergence" {
+			alerts = append(alerts, entry.Message)
+		}
+	}
+	require.Len(t, alerts, 2)
+	assert.Equal(t, `exporter (diverging) diverged from reference at round 2: summaries differ: reference has "round 2", diverging has "different"`, alerts[0])
+
+	// Rounds which are not sampled are not compared.
+	pImpl.verification.sample = func() bool { return false }
+	pImpl.verifyRound(&data.BlockData{BlockHeader: sdk.BlockHeader{Round: 5}})
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.ExporterVerifications.WithLabelValues("diverging", verificationDiverged)))
+}
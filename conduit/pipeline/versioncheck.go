@@ -0,0 +1,191 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/algorand/indexer/version"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins"
+)
+
+const (
+	// versionCheckWarn logs a warning when the exported data was written by a
+	// newer or incompatible pipeline.
+	versionCheckWarn = "warn"
+	// versionCheckHalt stops the pipeline before the first round instead.
+	versionCheckHalt = "halt"
+	// versionCheckOff neither checks nor records the versions.
+	versionCheckOff = "off"
+)
+
+// conduitVersion returns the version of the running binary, it is replaced by tests.
+var conduitVersion = version.Version
+
+func validVersionCheck(policy string) error {
+	switch policy {
+	case "", versionCheckWarn, versionCheckHalt, versionCheckOff:
+		return nil
+	default:
+		return fmt.Errorf("version-check must be '%s', '%s' or '%s', found '%s'", versionCheckWarn, versionCheckHalt, versionCheckOff, policy)
+	}
+}
+
+// versionMismatchError is returned with the halt policy when an exporter has
+// data written by a newer or incompatible pipeline.
+type versionMismatchError struct {
+	exporter string
+	problems []string
+}
+
+func (e versionMismatchError) Error() string {
+	return fmt.Sprintf("exporter (%s) has data written by a newer or incompatible pipeline: %s", e.exporter, strings.Join(e.problems, "; "))
+}
+
+// semver is the major, minor and patch of a version, pre-release and build
+// suffixes are ignored.
+type semver [3]uint64
+
+// parseSemver parses versions such as "1.2.3" or "v1.2.3-beta.1", ok is false
+// for other versions such as development builds.
+func parseSemver(v string) (semver, bool) {
+	var result semver
+	v = strings.TrimPrefix(v, "v")
+	if idx := strings.IndexAny(v, "-+"); idx >= 0 {
+		v = v[:idx]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != len(result) {
+		return result, false
+	}
+	for idx, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return result, false
+		}
+		result[idx] = n
+	}
+	return result, true
+}
+
+// less reports whether v is an older version than other.
+func (v semver) less(other semver) bool {
+	for idx := range v {
+		if v[idx] != other[idx] {
+			return v[idx] < other[idx]
+		}
+	}
+	return false
+}
+
+// compatible reports whether data written by other can be extended by v. The
+// major versions must be equal, and the minor versions as well before 1.0.0.
+func (v semver) compatible(other semver) bool {
+	if v[0] != other[0] {
+		return false
+	}
+	return v[0] != 0 || v[1] == other[1]
+}
+
+// compareVersions returns why the current versions must not write after the
+// recorded versions. Versions which are not semantic versions, such as
+// development builds, are not compared.
+func compareVersions(recorded, current conduit.WriterVersions) []string {
+	var problems []string
+	if old, ok := parseSemver(recorded.Conduit); ok {
+		if cur, ok := parseSemver(current.Conduit); ok && cur.less(old) {
+			problems = append(problems, fmt.Sprintf("conduit %s is older than %s", current.Conduit, recorded.Conduit))
+		}
+	}
+	keys := make([]string, 0, len(current.Plugins))
+	for key := range current.Plugins {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		old, ok := parseSemver(recorded.Plugins[key])
+		if !ok {
+			continue
+		}
+		cur, ok := parseSemver(current.Plugins[key])
+		if !ok {
+			continue
+		}
+		switch {
+		case !cur.compatible(old):
+			problems = append(problems, fmt.Sprintf("%s %s is not compatible with %s", key, current.Plugins[key], recorded.Plugins[key]))
+		case cur.less(old):
+			problems = append(problems, fmt.Sprintf("%s %s is older than %s", key, current.Plugins[key], recorded.Plugins[key]))
+		}
+	}
+	return problems
+}
+
+// pluginVersion returns the version reported by the plugin, or the Conduit
+// version for plugins which are released with Conduit.
+func pluginVersion(plugin interface{}) string {
+	if reporter, ok := plugin.(conduit.VersionReporter); ok {
+		return reporter.PluginVersion()
+	}
+	return conduitVersion()
+}
+
+// writerVersions returns the versions of the running pipeline.
+func (p *pipelineImpl) writerVersions() conduit.WriterVersions {
+	versions := conduit.WriterVersions{
+		Conduit: conduitVersion(),
+		Plugins: make(map[string]string),
+	}
+	versions.Plugins[fmt.Sprintf("%s/%s", plugins.Importer, (*p.importer).Metadata().Name)] = pluginVersion(*p.importer)
+	for _, proc := range p.processors {
+		versions.Plugins[fmt.Sprintf("%s/%s", plugins.Processor, (*proc).Metadata().Name)] = pluginVersion(*proc)
+	}
+	for idx, exp := range p.exporters {
+		versions.Plugins[fmt.Sprintf("%s/%s", plugins.Exporter, p.exporterName(idx))] = pluginVersion(*exp)
+	}
+	return versions
+}
+
+// checkWriterVersions compares the running pipeline with the versions
+// recorded by each exporter which implements conduit.VersionRecorder, then
+// records the running versions. With halt a versionMismatchError is returned
+// and nothing is recorded. A dry run only checks.
+func (p *pipelineImpl) checkWriterVersions() error {
+	if p.cfg.VersionCheck == versionCheckOff {
+		return nil
+	}
+	current := p.writerVersions()
+	if _, ok := parseSemver(current.Conduit); !ok {
+		p.logger.Infof("Conduit version %s is not a release, it is not checked against the exported data.", current.Conduit)
+	}
+	for _, exp := range p.exporters {
+		recorder, ok := (*exp).(conduit.VersionRecorder)
+		if !ok {
+			continue
+		}
+		name := (*exp).Metadata().Name
+		recorded, err := recorder.RecordedVersions()
+		if err != nil {
+			return fmt.Errorf("exporter (%s) could not read the recorded versions: %w", name, err)
+		}
+		if recorded != nil {
+			if problems := compareVersions(*recorded, current); len(problems) > 0 {
+				mismatch := versionMismatchError{exporter: name, problems: problems}
+				if p.cfg.VersionCheck == versionCheckHalt {
+					return mismatch
+				}
+				p.logger.WithField("alert", "version-downgrade").Warnf("%v", mismatch)
+			}
+		}
+		if p.dryRun {
+			continue
+		}
+		if err = recorder.RecordVersions(current); err != nil {
+			return fmt.Errorf("exporter (%s) could not record the versions: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+// versionExporter records the versions in memory.
+type versionExporter struct {
+	roundExporter
+	recorded *conduit.WriterVersions
+	records  int
+}
+
+func (v *versionExporter) RecordedVersions() (*conduit.WriterVersions, error) {
+	return v.recorded, nil
+}
+
+func (v *versionExporter) RecordVersions(versions conduit.WriterVersions) error {
+	v.recorded = &versions
+	v.records++
+	return nil
+}
+
+// externalExporter reports its own version.
+type externalExporter struct {
+	roundExporter
+}
+
+func (e *externalExporter) PluginVersion() string {
+	return "0.3.1"
+}
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		version string
+		want    semver
+		ok      bool
+	}{
+		{"1.2.3", semver{1, 2, 3}, true},
+		{"v1.2.3", semver{1, 2, 3}, true},
+		{"1.2.3-beta.1", semver{1, 2, 3}, true},
+		{"1.2.3+build", semver{1, 2, 3}, true},
+		{"1.2", semver{}, false},
+		{"(unknown version)", semver{}, false},
+		{"", semver{}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.version, func(t *testing.T) {
+			got, ok := parseSemver(tc.version)
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	recorded := conduit.WriterVersions{Conduit: "1.4.0", Plugins: map[string]string{"exporter/ext": "2.1.0", "processor/pre": "0.3.0"}}
+	tests := []struct {
+		name     string
+		current  conduit.WriterVersions
+		problems []string
+	}{
+		{"same", recorded, nil},
+		{"upgrade", conduit.WriterVersions{Conduit: "1.5.0", Plugins: map[string]string{"exporter/ext": "2.2.0", "processor/pre": "0.3.4"}}, nil},
+		{"conduit downgrade", conduit.WriterVersions{Conduit: "1.3.9", Plugins: recorded.Plugins}, []string{"conduit 1.3.9 is older than 1.4.0"}},
+		{"development build", conduit.WriterVersions{Conduit: "(unknown version)", Plugins: recorded.Plugins}, nil},
+		{"plugin downgrade", conduit.WriterVersions{Conduit: "1.4.0", Plugins: map[string]string{"exporter/ext": "2.0.5"}}, []string{"exporter/ext 2.0.5 is older than 2.1.0"}},
+		{"major version", conduit.WriterVersions{Conduit: "1.4.0", Plugins: map[string]string{"exporter/ext": "3.0.0"}}, []string{"exporter/ext 3.0.0 is not compatible with 2.1.0"}},
+		{"minor version before 1.0.0", conduit.WriterVersions{Conduit: "1.4.0", Plugins: map[string]string{"processor/pre": "0.4.0"}}, []string{"processor/pre 0.4.0 is not compatible with 0.3.0"}},
+		{"new plugin", conduit.WriterVersions{Conduit: "1.4.0", Plugins: map[string]string{"exporter/other": "0.1.0"}}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.problems, compareVersions(recorded, tc.current))
+		})
+	}
+}
+
+// TestCheckWriterVersions tests that the recorded versions are checked with
+// each policy and replaced by the running versions.
+func TestCheckWriterVersions(t *testing.T) {
+	defer func(f func() string) { conduitVersion = f }(conduitVersion)
+	conduitVersion = func() string { return "1.2.0" }
+
+	newer := conduit.WriterVersions{Conduit: "1.3.0"}
+	tests := []struct {
+		name    string
+		policy  string
+		dryRun  bool
+		err     string
+		warned  bool
+		records int
+	}{
+		{"warn", "", false, "", true, 1},
+		{"halt", versionCheckHalt, false, "exporter (recorder) has data written by a newer or incompatible pipeline: conduit 1.2.0 is older than 1.3.0", false, 0},
+		{"off", versionCheckOff, false, "", false, 0},
+		{"dry run", "", true, "", true, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := &versionExporter{roundExporter: roundExporter{name: "recorder"}, recorded: &newer}
+			external := &externalExporter{roundExporter: roundExporter{name: "external"}}
+			pImpl := makeCheckpointPipeline(t, recorder, external)
+			logger, hook := test.NewNullLogger()
+			pImpl.logger = logger
+			pImpl.cfg.VersionCheck = tc.policy
+			pImpl.dryRun = tc.dryRun
+
+			err := pImpl.checkWriterVersions()
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+			warned := false
+			for _, entry := range hook.AllEntries() {
+				warned = warned || entry.Data["alert"] == "version-downgrade"
+			}
+			assert.Equal(t, tc.warned, warned)
+			require.Equal(t, tc.records, recorder.records)
+			if tc.records > 0 {
+				assert.Equal(t, "1.2.0", recorder.recorded.Conduit)
+				assert.Equal(t, "0.3.1", recorder.recorded.Plugins["exporter/external"])
+				assert.Equal(t, "1.2.0", recorder.recorded.Plugins["exporter/recorder"])
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+// defaultWarmupTimeout is how long plugins may warm up when warmup.timeout is
+// not configured.
+const defaultWarmupTimeout = 5 * time.Minute
+
+// Warmup configs for plugins which implement conduit.Warmer.
+type Warmup struct {
+	// Timeout cancels the warm-up after this long. Zero waits until every
+	// plugin is warm.
+	Timeout time.Duration `yaml:"timeout"`
+	// Required fails the pipeline when a plugin cannot warm up in time,
+	// otherwise the failure is logged and the pipeline starts cold.
+	Required bool `yaml:"required"`
+}
+
+// Valid validates the warmup config.
+func (w Warmup) Valid() error {
+	if w.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative (%s)", w.Timeout)
+	}
+	return nil
+}
+
+// warmer is a plugin which warms up, with its name for logging.
+type warmer struct {
+	name   string
+	warmer conduit.Warmer
+}
+
+// warmers returns the plugins which implement conduit.Warmer.
+func (p *pipelineImpl) warmers() []warmer {
+	var warmers []warmer
+	add := func(pluginType string, plugin conduit.PluginMetadata) {
+		if w, ok := plugin.(conduit.Warmer); ok {
+			warmers = append(warmers, warmer{name: fmt.Sprintf("%s (%s)", pluginType, plugin.Metadata().Name), warmer: w})
+		}
+	}
+	if p.importer != nil {
+		add("importer", *p.importer)
+	}
+	for _, processor := range p.allProcessors() {
+		add("processor", *processor)
+	}
+	for _, exporter := range p.exporters {
+		add("exporter", *exporter)
+	}
+	return warmers
+}
+
+// warmup calls Warmup on every plugin which implements conduit.Warmer
+// concurrently and waits for them, the pipeline is not ready meanwhile.
+// Failures are only returned when the warm-up is required.
+func (p *pipelineImpl) warmup() error {
+	warmers := p.warmers()
+	if len(warmers) == 0 {
+		return nil
+	}
+	p.setWarmingUp(true)
+	defer p.setWarmingUp(false)
+
+	ctx, cancel := p.ctx, context.CancelFunc(func() {})
+	if p.cfg.Warmup.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(p.ctx, p.cfg.Warmup.Timeout)
+	}
+	defer cancel()
+
+	start := time.Now()
+	p.logger.Infof("Warming up %d plugins", len(warmers))
+	errs := make([]error, len(warmers))
+	var wg sync.WaitGroup
+	for idx := range warmers {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			errs[idx] = warmers[idx].warmer.Warmup(ctx)
+		}(idx)
+	}
+	wg.Wait()
+
+	var failed error
+	for idx, err := range errs {
+		if err == nil {
+			continue
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("did not finish within %s: %w", p.cfg.Warmup.Timeout, err)
+		}
+		err = fmt.Errorf("warmup(): %s could not warm up: %w", warmers[idx].name, err)
+		if !p.cfg.Warmup.Required {
+			p.logger.Warnf("%v, starting cold", err)
+		} else if failed == nil {
+			failed = err
+		}
+	}
+	if failed != nil {
+		return failed
+	}
+	p.logger.Infof("Warm-up finished in %s", time.Since(start))
+	return nil
+}
+
+func (p *pipelineImpl) setWarmingUp(warmingUp bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.WarmingUp = warmingUp
+}
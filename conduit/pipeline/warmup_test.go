@@ -0,0 +1,116 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// warmingExporter implements conduit.Warmer, it waits for release or ctx and
+// records the status of the pipeline meanwhile.
+type warmingExporter struct {
+	mockExporter
+	pipeline *pipelineImpl
+	release  chan struct{}
+	status   Status
+	err      error
+}
+
+func (e *warmingExporter) Warmup(ctx context.Context) error {
+	e.status = e.pipeline.Status()
+	select {
+	case <-e.release:
+		return e.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func makeWarmupPipeline(t *testing.T, exp exporters.Exporter, warmup Warmup) *pipelineImpl {
+	var pImporter importers.Importer = &mockImporter{genesis: sdk.Genesis{Network: "test"}}
+	var pProcessor processors.Processor = &mockProcessor{}
+	l, _ := test.NewNullLogger()
+	ctx, cf := context.WithCancel(context.Background())
+	t.Cleanup(cf)
+	return &pipelineImpl{
+		ctx: ctx,
+		cf:  cf,
+		cfg: &Config{
+			ConduitArgs: &conduit.Args{ConduitDataDir: t.TempDir()},
+			Processors:  []NameConfigPair{{}},
+			Exporters:   []NameConfigPair{{Name: "mockExporter"}},
+			Warmup:      warmup,
+		},
+		logger:     l,
+		importer:   &pImporter,
+		processors: []*processors.Processor{&pProcessor},
+		exporters:  []*exporters.Exporter{&exp},
+	}
+}
+
+func TestWarmupValid(t *testing.T) {
+	assert.NoError(t, Warmup{Timeout: time.Second}.Valid())
+	assert.EqualError(t, Warmup{Timeout: -time.Second}.Valid(), "timeout must not be negative (-1s)")
+}
+
+func TestPipelineWarmup(t *testing.T) {
+	tests := []struct {
+		name    string
+		warmup  Warmup
+		release bool
+		err     error
+		errMsg  string
+	}{
+		{name: "warm", release: true},
+		{name: "failure is logged", release: true, err: fmt.Errorf("cache")},
+		{name: "timeout is logged", warmup: Warmup{Timeout: time.Millisecond}},
+		{
+			name:    "required failure",
+			warmup:  Warmup{Required: true},
+			release: true,
+			err:     fmt.Errorf("cache"),
+			errMsg:  "Pipeline.Init(): warmup(): exporter (mockExporter) could not warm up: cache",
+		},
+		{
+			name:   "required timeout",
+			warmup: Warmup{Timeout: time.Millisecond, Required: true},
+			errMsg: "could not warm up: did not finish within 1ms: context deadline exceeded",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			exp := &warmingExporter{release: make(chan struct{}), err: tc.err}
+			pImpl := makeWarmupPipeline(t, exp, tc.warmup)
+			exp.pipeline = pImpl
+			if tc.release {
+				close(exp.release)
+			}
+
+			err := pImpl.Init()
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.True(t, exp.status.WarmingUp)
+			assert.False(t, pImpl.Status().WarmingUp)
+		})
+	}
+}
+
+func TestStatusReadyWarmingUp(t *testing.T) {
+	assert.True(t, Status{Running: true}.Ready())
+	assert.False(t, Status{Running: true, WarmingUp: true}.Ready())
+}
@@ -0,0 +1,296 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/algorand/conduit/conduit/metrics"
+)
+
+// defaultWatchdogMaxRestarts is how many times a stalled loop is restarted
+// when max-restarts is not set.
+const defaultWatchdogMaxRestarts = 3
+
+// Watchdog configs the restart of a stalled pipeline loop.
+type Watchdog struct {
+	// StallTimeout is how long the pipeline loop may go without starting a
+	// round, while it is not paused or retrying, before it is restarted. The
+	// watchdog is disabled when it is not set.
+	StallTimeout time.Duration `yaml:"stall-timeout"`
+	// MaxRestarts is how many times the loop is restarted before the
+	// pipeline stops, the default is 3.
+	MaxRestarts int `yaml:"max-restarts"`
+}
+
+// Valid validates the watchdog config.
+func (w Watchdog) Valid() error {
+	if w.StallTimeout < 0 {
+		return fmt.Errorf("stall-timeout must not be negative")
+	}
+	if w.MaxRestarts < 0 {
+		return fmt.Errorf("max-restarts must not be negative")
+	}
+	return nil
+}
+
+func (w Watchdog) enabled() bool {
+	return w.StallTimeout > 0
+}
+
+func (w Watchdog) maxRestarts() int {
+	if w.MaxRestarts == 0 {
+		return defaultWatchdogMaxRestarts
+	}
+	return w.MaxRestarts
+}
+
+// watchdogState is the generation and the progress of the pipeline loop. A
+// loop abandoned by the watchdog exits without cleaning up once its plugin
+// call returns, the next generation only runs after it exited so that the
+// plugins are not called concurrently. The zero value is ready to use.
+type watchdogState struct {
+	mu sync.Mutex
+	// gen is the generation of the current loop.
+	gen uint64
+	// ended is set once the current loop exited, or was given up by the
+	// watchdog.
+	ended bool
+	// beat is when the current loop last started a round.
+	beat     time.Time
+	restarts int
+	// running is the generation of the loop which was run last, exited is
+	// closed once it returns.
+	running uint64
+	exited  chan struct{}
+}
+
+// start begins a new generation for the loop run by Start, the loop of a
+// previous run has ended.
+func (w *watchdogState) start(now time.Time) uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.gen++
+	w.ended = false
+	w.beat = now
+	w.running = w.gen
+	w.exited = make(chan struct{})
+	return w.gen
+}
+
+// run records that the loop of generation gen runs, it returns false if gen
+// was replaced in the meantime.
+func (w *watchdogState) run(gen uint64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if gen != w.gen {
+		return false
+	}
+	w.running = gen
+	w.exited = make(chan struct{})
+	return true
+}
+
+// exit records that the loop of generation gen returned.
+func (w *watchdogState) exit(gen uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if gen == w.running {
+		close(w.exited)
+		w.running = 0
+	}
+}
+
+// lastExited returns a channel which is closed once the loop which was run
+// last returned.
+func (w *watchdogState) lastExited() <-chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running == 0 {
+		exited := make(chan struct{})
+		close(exited)
+		return exited
+	}
+	return w.exited
+}
+
+// refresh restarts the stall timeout and returns the current generation.
+func (w *watchdogState) refresh(now time.Time) uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.beat = now
+	return w.gen
+}
+
+// alive records that the loop of generation gen starts a round, it returns
+// false if the loop was abandoned.
+func (w *watchdogState) alive(gen uint64, now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if gen != w.gen {
+		return false
+	}
+	w.beat = now
+	return true
+}
+
+// abandoned reports whether the loop of generation gen was replaced.
+func (w *watchdogState) abandoned(gen uint64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return gen != w.gen
+}
+
+// end marks the loop of generation gen as ended, it returns false if the loop
+// was abandoned or already ended.
+func (w *watchdogState) end(gen uint64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if gen != w.gen || w.ended {
+		return false
+	}
+	w.ended = true
+	return true
+}
+
+// stalled returns how long the current loop has not started a round, if it
+// is longer than timeout.
+func (w *watchdogState) stalled(now time.Time, timeout time.Duration) (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	since := now.Sub(w.beat)
+	return since, !w.ended && since >= timeout
+}
+
+// abandon replaces the current loop, it returns the new generation and
+// whether the restart budget allows running it, or false if the loop ended in
+// the meantime.
+func (w *watchdogState) abandon(now time.Time, budget int) (gen uint64, restart bool, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ended {
+		return 0, false, false
+	}
+	w.gen++
+	w.beat = now
+	if w.restarts >= budget {
+		return w.gen, false, true
+	}
+	w.restarts++
+	return w.gen, true, true
+}
+
+func (w *watchdogState) restartCount() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.restarts
+}
+
+// runWatchdog checks the pipeline loop every quarter of the stall-timeout
+// until it exits. A stalled loop is abandoned and loop runs a new generation
+// from the next round once the abandoned loop returned. Once the restart
+// budget is exhausted the pipeline stops with an error, endLoop cleans up for
+// the abandoned loop.
+func (p *pipelineImpl) runWatchdog(loopDone <-chan struct{}, loop func(gen uint64, prefetch *prefetcher), endLoop func(gen uint64) bool) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.cfg.Watchdog.StallTimeout / 4)
+	defer ticker.Stop()
+	metrics.WatchdogRestartBudget.Set(float64(p.cfg.Watchdog.maxRestarts()))
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-loopDone:
+			return
+		case <-ticker.C:
+			if !p.checkStall(time.Now(), loop, endLoop) {
+				return
+			}
+		}
+	}
+}
+
+// checkStall restarts the pipeline loop if it stalled, it returns false once
+// the watchdog gave up on the loop.
+func (p *pipelineImpl) checkStall(now time.Time, loop func(gen uint64, prefetch *prefetcher), endLoop func(gen uint64) bool) bool {
+	cfg := p.cfg.Watchdog
+	since, stalled := p.watchdog.stalled(now, cfg.StallTimeout)
+	if !stalled {
+		return true
+	}
+	status := p.Status()
+	if status.Paused || status.RetryCount > 0 || status.WarmingUp {
+		// Waiting is expected, the timeout starts once the loop resumes.
+		p.watchdog.refresh(now)
+		return true
+	}
+
+	inFlight := "no plugin call"
+	if slowest := p.Activity().Slowest; slowest != nil {
+		inFlight = fmt.Sprintf("%s (%s) at round %d for %s", slowest.Stage, slowest.Plugin, slowest.Round, slowest.Elapsed.Round(time.Second))
+	}
+	reason := fmt.Sprintf("pipeline loop stalled for %s at round %d, in flight: %s", since.Round(time.Second), status.NextRound, inFlight)
+	p.logger.WithField("alert", "pipeline-stall").Error(reason)
+	p.writeSupportBundle(reason)
+
+	gen, restart, ok := p.watchdog.abandon(now, cfg.maxRestarts())
+	if !ok {
+		return false
+	}
+	if !restart {
+		metrics.WatchdogStalls.WithLabelValues("stopped").Inc()
+		err := fmt.Errorf("watchdog: %s, the restart budget (%d) is exhausted", reason, cfg.maxRestarts())
+		p.logger.Errorf("%v - stopping...", err)
+		p.setError(err)
+		if endLoop(gen) {
+			p.wg.Done()
+		}
+		return false
+	}
+	metrics.WatchdogStalls.WithLabelValues("restarted").Inc()
+	restarts := p.watchdog.restartCount()
+	metrics.WatchdogRestartBudget.Set(float64(cfg.maxRestarts() - restarts))
+	p.logger.Warnf("Watchdog restarting the pipeline loop at round %d (restart %d of %d)", status.NextRound, restarts, cfg.maxRestarts())
+	// A loop waiting for the prefetcher returns once it is cancelled.
+	if prefetch := p.currentPrefetch(); prefetch != nil {
+		prefetch.cancel()
+	}
+	go p.restartLoop(gen, loop, endLoop)
+	return true
+}
+
+// restartLoop runs the loop of generation gen once the abandoned loop and its
+// prefetcher returned from their plugin calls. A stalled call which does not
+// return stalls the new generation too, until the restart budget is exhausted.
+func (p *pipelineImpl) restartLoop(gen uint64, loop func(gen uint64, prefetch *prefetcher), endLoop func(gen uint64) bool) {
+	waitFor := func(done <-chan struct{}) bool {
+		select {
+		case <-done:
+			return true
+		case <-p.ctx.Done():
+			// The pipeline stops before the generation runs.
+			if endLoop(gen) {
+				p.wg.Done()
+			}
+			return false
+		}
+	}
+	if !waitFor(p.watchdog.lastExited()) {
+		return
+	}
+	prefetch := p.currentPrefetch()
+	if prefetch != nil {
+		prefetch.cancel()
+		if !waitFor(prefetch.done) {
+			return
+		}
+	}
+	if !p.watchdog.run(gen) {
+		// Replaced by a newer generation, which waits for the same calls.
+		return
+	}
+	if prefetch != nil {
+		prefetch = p.startPrefetch()
+	}
+	loop(gen, prefetch)
+}
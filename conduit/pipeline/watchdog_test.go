@@ -0,0 +1,173 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// hangExporter blocks hangs times in Receive of hangRound until release is
+// closed, the released calls fail.
+type hangExporter struct {
+	roundExporter
+	hangMu    sync.Mutex
+	hangRound uint64
+	hangs     int
+	release   chan struct{}
+}
+
+func (e *hangExporter) Receive(exportData data.BlockData) error {
+	e.hangMu.Lock()
+	hang := exportData.Round() == e.hangRound && e.hangs > 0
+	if hang {
+		e.hangs--
+	}
+	e.hangMu.Unlock()
+	if hang {
+		<-e.release
+		return fmt.Errorf("released")
+	}
+	return e.roundExporter.Receive(exportData)
+}
+
+// hangImporter blocks in GetBlock of hangRound until release is closed.
+type hangImporter struct {
+	namedImporter
+	hangRound uint64
+	release   chan struct{}
+}
+
+func (i *hangImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	if rnd == i.hangRound {
+		<-i.release
+	}
+	return data.BlockData{BlockHeader: sdk.BlockHeader{Round: sdk.Round(rnd)}}, nil
+}
+
+func TestWatchdogValid(t *testing.T) {
+	tests := []struct {
+		name     string
+		watchdog Watchdog
+		err      string
+	}{
+		{"disabled", Watchdog{}, ""},
+		{"valid", Watchdog{StallTimeout: time.Minute, MaxRestarts: 5}, ""},
+		{"negative timeout", Watchdog{StallTimeout: -time.Minute}, "stall-timeout must not be negative"},
+		{"negative restarts", Watchdog{StallTimeout: time.Minute, MaxRestarts: -1}, "max-restarts must not be negative"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.watchdog.Valid()
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+// TestWatchdogRestart tests that a stalled loop is restarted from the next
+// round once its plugin call returns, and that the abandoned loop does not
+// export once it is released.
+func TestWatchdogRestart(t *testing.T) {
+	exp := &hangExporter{roundExporter: roundExporter{name: "hang"}, hangRound: 5, hangs: 1, release: make(chan struct{})}
+	pImpl := makeCheckpointPipeline(t, exp)
+	pImpl.cfg.Watchdog = Watchdog{StallTimeout: 100 * time.Millisecond}
+
+	pImpl.Start()
+	defer pImpl.Stop()
+	require.Eventually(t, func() bool { return pImpl.Status().WatchdogRestarts == 1 }, 5*time.Second, time.Millisecond)
+	// The new generation waits for the stalled call.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4}, exp.received())
+	close(exp.release)
+	require.Eventually(t, func() bool { return len(exp.received()) >= 10 }, 5*time.Second, time.Millisecond)
+	status := pImpl.Status()
+	assert.True(t, status.Running)
+	assert.Equal(t, 1, status.WatchdogRestarts)
+	pImpl.Stop()
+
+	received := exp.received()
+	for idx, round := range received {
+		require.Equal(t, uint64(idx), round, "each round is exported once, in order")
+	}
+}
+
+// TestWatchdogRestartPrefetch tests that a loop waiting for a stalled
+// prefetcher is restarted with a new prefetcher once the importer returns.
+func TestWatchdogRestartPrefetch(t *testing.T) {
+	imp := &hangImporter{hangRound: 5, release: make(chan struct{})}
+	exp := &roundExporter{name: "exp"}
+	pImpl := makeCheckpointPipeline(t, exp)
+	var pImporter importers.Importer = imp
+	pImpl.importer = &pImporter
+	pImpl.cfg.PrefetchRounds = 2
+	pImpl.cfg.Watchdog = Watchdog{StallTimeout: 100 * time.Millisecond}
+
+	pImpl.Start()
+	defer pImpl.Stop()
+	require.Eventually(t, func() bool { return pImpl.Status().WatchdogRestarts == 1 }, 5*time.Second, time.Millisecond)
+	close(imp.release)
+	require.Eventually(t, func() bool { return len(exp.received()) >= 10 }, 5*time.Second, time.Millisecond)
+	pImpl.Stop()
+
+	for idx, round := range exp.received() {
+		require.Equal(t, uint64(idx), round, "each round is exported once, in order")
+	}
+}
+
+// TestWatchdogBudget tests that the pipeline stops once the restart budget is
+// exhausted.
+func TestWatchdogBudget(t *testing.T) {
+	exp := &hangExporter{roundExporter: roundExporter{name: "hang"}, hangRound: 3, hangs: 10, release: make(chan struct{})}
+	pImpl := makeCheckpointPipeline(t, exp)
+	pImpl.cfg.Watchdog = Watchdog{StallTimeout: 50 * time.Millisecond, MaxRestarts: 1}
+
+	pImpl.Start()
+	require.Eventually(t, func() bool { return !pImpl.Status().Running }, 5*time.Second, time.Millisecond)
+	pImpl.Wait()
+	status := pImpl.Status()
+	assert.Equal(t, 1, status.WatchdogRestarts)
+	assert.Contains(t, status.LastError, "the restart budget (1) is exhausted")
+	assert.Equal(t, []uint64{0, 1, 2}, exp.received())
+	close(exp.release)
+	pImpl.Stop()
+	<-pImpl.watchdog.lastExited()
+	assert.Equal(t, []uint64{0, 1, 2}, exp.received())
+}
+
+// TestWatchdogStartAgain tests that the pipeline runs again once it stopped,
+// a new loop is not mistaken for the one which ended.
+func TestWatchdogStartAgain(t *testing.T) {
+	exp := &roundExporter{name: "exp"}
+	pImpl := makeCheckpointPipeline(t, exp)
+	pImpl.cfg.Watchdog = Watchdog{StallTimeout: time.Minute}
+
+	for _, end := range []uint64{2, 4} {
+		pImpl.cfg.Rounds.End = end
+		pImpl.Start()
+		done := make(chan struct{})
+		go func() {
+			pImpl.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("pipeline did not stop after round %d", end)
+		}
+		require.NoError(t, pImpl.Error())
+		assert.False(t, pImpl.Status().Running)
+	}
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4}, exp.received())
+}
@@ -0,0 +1,228 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+const (
+	// windowDirName is the directory of the data directory where the windows
+	// of the WindowProcessors are saved.
+	windowDirName = "windows"
+	windowFileExt = ".msgp"
+)
+
+// roundWindow keeps the blocks which a processors.WindowProcessor received for
+// the rounds before the current round of its window.
+type roundWindow struct {
+	size uint64
+	// dir is where the blocks are saved, they are only kept in memory when
+	// it is empty.
+	dir    string
+	rounds []uint64
+	blocks map[uint64]data.BlockData
+}
+
+// loadRoundWindow reads the blocks saved in dir.
+func loadRoundWindow(dir string, size uint64) (*roundWindow, error) {
+	w := &roundWindow{size: size, dir: dir, blocks: make(map[uint64]data.BlockData)}
+	if dir == "" {
+		return w, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loadRoundWindow(): %w", err)
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), windowFileExt) {
+			continue
+		}
+		round, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), windowFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		b, err := os.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("loadRoundWindow(): %w", err)
+		}
+		var blk data.BlockData
+		if err = msgpack.Decode(b, &blk); err != nil {
+			return nil, fmt.Errorf("loadRoundWindow(): unable to decode round %d: %w", round, err)
+		}
+		w.rounds = append(w.rounds, round)
+		w.blocks[round] = blk
+	}
+	sort.Slice(w.rounds, func(i, j int) bool { return w.rounds[i] < w.rounds[j] })
+	if n := len(w.rounds); n > 0 {
+		if err = w.prune(w.rounds[n-1]); err != nil {
+			return nil, fmt.Errorf("loadRoundWindow(): %w", err)
+		}
+	}
+	return w, nil
+}
+
+// window returns the blocks of the window ending with blk.
+func (w *roundWindow) window(blk data.BlockData) []data.BlockData {
+	round := blk.Round()
+	var first uint64
+	if round+1 > w.size {
+		first = round + 1 - w.size
+	}
+	var window []data.BlockData
+	for _, rnd := range w.rounds {
+		if rnd >= first && rnd < round {
+			window = append(window, w.blocks[rnd])
+		}
+	}
+	return append(window, blk)
+}
+
+// record keeps the encoded block which the processor received for the round,
+// and drops the rounds which are no longer needed for the window of the next
+// round.
+func (w *roundWindow) record(round uint64, encoded []byte) error {
+	var blk data.BlockData
+	if err := msgpack.Decode(encoded, &blk); err != nil {
+		return fmt.Errorf("record(): unable to copy round %d: %w", round, err)
+	}
+	if w.dir != "" {
+		if err := os.MkdirAll(w.dir, os.ModePerm); err != nil {
+			return fmt.Errorf("record(): %w", err)
+		}
+		file := path.Join(w.dir, fmt.Sprintf("%d%s", round, windowFileExt))
+		if err := os.WriteFile(file+".tmp", encoded, 0644); err != nil {
+			return fmt.Errorf("record(): %w", err)
+		}
+		if err := os.Rename(file+".tmp", file); err != nil {
+			return fmt.Errorf("record(): %w", err)
+		}
+	}
+	if _, ok := w.blocks[round]; !ok {
+		w.rounds = append(w.rounds, round)
+		sort.Slice(w.rounds, func(i, j int) bool { return w.rounds[i] < w.rounds[j] })
+	}
+	w.blocks[round] = blk
+	return w.prune(round)
+}
+
+// prune keeps the rounds up to round which are in the window of the next
+// round.
+func (w *roundWindow) prune(round uint64) error {
+	var keep []uint64
+	for _, rnd := range w.rounds {
+		if rnd <= round && rnd+w.size > round+1 {
+			keep = append(keep, rnd)
+			continue
+		}
+		if err := w.remove(rnd); err != nil {
+			return err
+		}
+	}
+	w.rounds = keep
+	return nil
+}
+
+// rewind drops the blocks of round and later rounds.
+func (w *roundWindow) rewind(round uint64) error {
+	var keep []uint64
+	for _, rnd := range w.rounds {
+		if rnd < round {
+			keep = append(keep, rnd)
+			continue
+		}
+		if err := w.remove(rnd); err != nil {
+			return err
+		}
+	}
+	w.rounds = keep
+	return nil
+}
+
+func (w *roundWindow) remove(round uint64) error {
+	delete(w.blocks, round)
+	if w.dir == "" {
+		return nil
+	}
+	err := os.Remove(path.Join(w.dir, fmt.Sprintf("%d%s", round, windowFileExt)))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove(): %w", err)
+	}
+	return nil
+}
+
+// initWindow prepares the window of the processor if it is a
+// processors.WindowProcessor. key identifies the processor in the pipeline, it
+// names the directory where its window is saved.
+func (p *pipelineImpl) initWindow(proc *processors.Processor, key string) error {
+	wp, ok := (*proc).(processors.WindowProcessor)
+	if !ok {
+		return nil
+	}
+	size := wp.WindowSize()
+	if size == 0 {
+		return fmt.Errorf("initWindow(): processor (%s) has an empty window", wp.Metadata().Name)
+	}
+	if w, ok := p.windows[proc]; ok {
+		w.size = size
+		return nil
+	}
+	var dir string
+	if p.cfg != nil && p.cfg.ConduitArgs != nil && p.cfg.ConduitArgs.ConduitDataDir != "" {
+		dir = path.Join(p.cfg.ConduitArgs.ConduitDataDir, windowDirName, key)
+	}
+	w, err := loadRoundWindow(dir, size)
+	if err != nil {
+		return fmt.Errorf("initWindow(): processor (%s): %w", wp.Metadata().Name, err)
+	}
+	if p.windows == nil {
+		p.windows = make(map[*processors.Processor]*roundWindow)
+	}
+	p.windows[proc] = w
+	return nil
+}
+
+// rewindWindows drops the blocks of round and later rounds from the windows.
+func (p *pipelineImpl) rewindWindows(round uint64) error {
+	for _, w := range p.windows {
+		if err := w.rewind(round); err != nil {
+			return fmt.Errorf("rewindWindows(): %w", err)
+		}
+	}
+	return nil
+}
+
+// runProcessor calls Process, or ProcessWindow with the window of the block's
+// round if w is not nil. The window only advances when record is true, it is
+// false when a past round is processed again.
+func runProcessor(proc processors.Processor, w *roundWindow, blk data.BlockData, record bool) (data.BlockData, error) {
+	wp, ok := proc.(processors.WindowProcessor)
+	if !ok || w == nil {
+		return proc.Process(blk)
+	}
+	var input []byte
+	if record {
+		// The processor may modify the block it is processing.
+		input = msgpack.Encode(blk)
+	}
+	out, err := wp.ProcessWindow(w.window(blk))
+	if err != nil || !record {
+		return out, err
+	}
+	if err = w.record(blk.Round(), input); err != nil {
+		return out, fmt.Errorf("processor (%s) window could not be saved: %w", proc.Metadata().Name, err)
+	}
+	return out, nil
+}
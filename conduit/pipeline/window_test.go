@@ -0,0 +1,147 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// windowProcessor implements processors.WindowProcessor, it records the
+// rounds of each window and sets the block's txn counter to the window size.
+type windowProcessor struct {
+	mockProcessor
+	size    uint64
+	windows [][]uint64
+	err     error
+}
+
+func (m *windowProcessor) WindowSize() uint64 {
+	return m.size
+}
+
+func (m *windowProcessor) ProcessWindow(window []data.BlockData) (data.BlockData, error) {
+	var rounds []uint64
+	for _, blk := range window {
+		rounds = append(rounds, blk.Round())
+	}
+	m.windows = append(m.windows, rounds)
+	out := window[len(window)-1]
+	out.BlockHeader.TxnCounter = uint64(len(window))
+	return out, m.err
+}
+
+func windowBlock(round uint64) data.BlockData {
+	return data.BlockData{BlockHeader: sdk.BlockHeader{Round: sdk.Round(round)}}
+}
+
+func TestRunProcessorWindow(t *testing.T) {
+	dir := path.Join(t.TempDir(), "window")
+	w, err := loadRoundWindow(dir, 3)
+	require.NoError(t, err)
+	proc := &windowProcessor{size: 3}
+
+	for rnd := uint64(5); rnd <= 8; rnd++ {
+		out, err := runProcessor(proc, w, windowBlock(rnd), true)
+		require.NoError(t, err)
+		assert.Equal(t, sdk.Round(rnd), out.BlockHeader.Round)
+	}
+	assert.Equal(t, [][]uint64{{5}, {5, 6}, {5, 6, 7}, {6, 7, 8}}, proc.windows)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// a past round does not advance the window, its earlier rounds were
+	// already dropped.
+	_, err = runProcessor(proc, w, windowBlock(7), false)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{7}, proc.windows[4])
+	assert.Equal(t, []uint64{7, 8}, w.rounds)
+
+	// a failed round does not advance the window.
+	proc.err = fmt.Errorf("window failure")
+	_, err = runProcessor(proc, w, windowBlock(9), true)
+	require.EqualError(t, err, "window failure")
+	assert.Equal(t, []uint64{7, 8}, w.rounds)
+
+	// the window is restored after a restart.
+	w, err = loadRoundWindow(dir, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{7, 8}, w.rounds)
+	proc.err = nil
+	_, err = runProcessor(proc, w, windowBlock(9), true)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{7, 8, 9}, proc.windows[len(proc.windows)-1])
+
+	// other processors are not given a window.
+	plain := &mockProcessor{}
+	plain.On("Process", windowBlock(10))
+	out, err := runProcessor(plain, nil, windowBlock(10), true)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.Round(11), out.BlockHeader.Round)
+}
+
+func TestRoundWindowRewind(t *testing.T) {
+	dir := t.TempDir()
+	w, err := loadRoundWindow(dir, 4)
+	require.NoError(t, err)
+	proc := &windowProcessor{size: 4}
+	for rnd := uint64(0); rnd < 4; rnd++ {
+		_, err = runProcessor(proc, w, windowBlock(rnd), true)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []uint64{1, 2, 3}, w.rounds)
+	require.NoError(t, w.rewind(2))
+	assert.Equal(t, []uint64{1}, w.rounds)
+	_, err = os.Stat(path.Join(dir, "3.msgp"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// a smaller window drops the older rounds when it is loaded.
+	w, err = loadRoundWindow(dir, 1)
+	require.NoError(t, err)
+	assert.Empty(t, w.rounds)
+}
+
+// TestPipelineWindowInit tests that the windows are prepared for the
+// WindowProcessors when the pipeline is initialized.
+func TestPipelineWindowInit(t *testing.T) {
+	var pImporter importers.Importer = &mockImporter{genesis: sdk.Genesis{Network: "test"}}
+	var pWindow processors.Processor = &windowProcessor{size: 10}
+	var pPlain processors.Processor = &mockProcessor{}
+	var pExporter exporters.Exporter = &mockExporter{}
+	dataDir := t.TempDir()
+	l, _ := test.NewNullLogger()
+	pImpl := pipelineImpl{
+		cfg: &Config{
+			ConduitArgs: &conduit.Args{ConduitDataDir: dataDir},
+			Processors:  []NameConfigPair{{}, {}},
+			Exporter:    NameConfigPair{Name: "mockExporter"},
+		},
+		logger:     l,
+		importer:   &pImporter,
+		processors: []*processors.Processor{&pPlain, &pWindow},
+		exporters:  []*exporters.Exporter{&pExporter},
+	}
+	require.NoError(t, pImpl.Init())
+	require.Len(t, pImpl.windows, 1)
+	w := pImpl.windows[&pWindow]
+	require.NotNil(t, w)
+	assert.Equal(t, uint64(10), w.size)
+	assert.Equal(t, path.Join(dataDir, windowDirName, "processor_1_mockProcessor"), w.dir)
+
+	pWindow = &windowProcessor{}
+	pImpl.windows = nil
+	err := pImpl.Init()
+	assert.EqualError(t, err, "Pipeline.Init(): initWindow(): processor (mockProcessor) has an empty window")
+}
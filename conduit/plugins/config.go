@@ -1,6 +1,12 @@
 package plugins
 
-import "gopkg.in/yaml.v3"
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
 
 // PluginConfig is a generic string which can be deserialized by each individual Plugin
 type PluginConfig struct {
@@ -15,6 +21,19 @@ func (pc PluginConfig) UnmarshalConfig(config interface{}) error {
 	return yaml.Unmarshal([]byte(pc.Config), config)
 }
 
+// Decode strictly unmarshals the plugin config into config, unlike
+// UnmarshalConfig unknown keys are an error.
+func (pc PluginConfig) Decode(config interface{}) error {
+	decoder := yaml.NewDecoder(strings.NewReader(pc.Config))
+	decoder.KnownFields(true)
+	err := decoder.Decode(config)
+	if errors.Is(err, io.EOF) {
+		// An empty config leaves the defaults.
+		return nil
+	}
+	return err
+}
+
 // MakePluginConfig is a helper to create the struct.
 func MakePluginConfig(config string) PluginConfig {
 	return PluginConfig{Config: config}
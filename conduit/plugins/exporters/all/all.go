@@ -3,6 +3,12 @@ package all
 import (
 	// Call package wide init function
 	_ "github.com/algorand/conduit/conduit/plugins/exporters/filewriter"
+	_ "github.com/algorand/conduit/conduit/plugins/exporters/flight"
+	_ "github.com/algorand/conduit/conduit/plugins/exporters/golden"
+	_ "github.com/algorand/conduit/conduit/plugins/exporters/ipfsarchive"
+	_ "github.com/algorand/conduit/conduit/plugins/exporters/kafka"
 	_ "github.com/algorand/conduit/conduit/plugins/exporters/noop"
+	_ "github.com/algorand/conduit/conduit/plugins/exporters/pgstaging"
 	_ "github.com/algorand/conduit/conduit/plugins/exporters/postgresql"
+	_ "github.com/algorand/conduit/conduit/plugins/exporters/stream"
 )
@@ -34,3 +34,43 @@ type Exporter interface {
 	// Should return an error on failure--retries are configurable.
 	Receive(exportData data.BlockData) error
 }
+
+// BatchExporter is for exporters which write more efficiently in bulk, such as
+// warehouses or object storage. When the pipeline batch size is configured
+// ReceiveBatch is called instead of Receive with consecutive rounds.
+type BatchExporter interface {
+	Exporter
+
+	// ReceiveBatch is called with the blocks accumulated since the previous
+	// batch, in round order. The pipeline only saves its next round once the
+	// batch succeeds, a failed batch is retried with the same blocks.
+	ReceiveBatch(exportData []data.BlockData) error
+}
+
+// CommitToken identifies a round prepared by a TransactionalExporter. It is
+// saved in the pipeline metadata, so it should be small and must remain valid
+// across restarts, such as a transaction or batch ID.
+type CommitToken string
+
+// TransactionalExporter is for exporters which write rounds in two phases to
+// achieve exactly-once delivery, such as Kafka transactions or Postgres
+// prepared transactions. The pipeline calls Prepare instead of Receive, saves
+// the token as a commit intent and then calls Commit. If the pipeline stops
+// after the intent is saved, Commit is called with the same token when the
+// round is retried instead of exporting the round again.
+type TransactionalExporter interface {
+	Exporter
+
+	// Prepare writes the block without making it visible and returns the token
+	// used to commit it. Nothing should remain prepared if it fails. An empty
+	// token means that the round was committed, for example when the sink does
+	// not allow prepared writes, Commit is not called.
+	Prepare(exportData data.BlockData) (CommitToken, error)
+
+	// Commit makes the prepared round visible. It must succeed without
+	// writing the round again if the token was already committed.
+	Commit(token CommitToken) error
+
+	// Rollback discards a prepared round which is not going to be committed.
+	Rollback(token CommitToken) error
+}
@@ -0,0 +1,221 @@
+package filewriter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// ChunkFilePattern is used to name chunk files, it is formatted with the first round in the chunk.
+const ChunkFilePattern = "%[1]d_blocks.chunk"
+
+// A chunk file holds up to rounds-per-file consecutive rounds, starting at a
+// multiple of rounds-per-file. The layout is:
+//
+//	magic | record... | index entry... | index offset | entry count | index magic
+//
+// Each record is the round (uint64), the data length (uint32) and the encoded
+// block. The index is only written once the chunk is complete or the exporter
+// is closed, until then readers scan the records.
+var (
+	chunkMagic      = []byte("CNDTCHNK")
+	chunkIndexMagic = []byte("CIDX")
+)
+
+const (
+	recordHeaderSize = 8 + 4
+	indexEntrySize   = 8 + 8 + 4
+	indexFooterSize  = 8 + 4 + 4
+)
+
+// chunkEntry is the location of a record in a chunk file.
+type chunkEntry struct {
+	round  uint64
+	offset int64
+	length uint32
+}
+
+// ChunkStart returns the first round of the chunk containing round.
+func ChunkStart(round, roundsPerFile uint64) uint64 {
+	return round - round%roundsPerFile
+}
+
+// readChunkIndex returns the records in a chunk file and the offset after the
+// last complete record. The index is used if present, otherwise the records
+// are scanned and a partially written record at the end is ignored.
+func readChunkIndex(file *os.File) ([]chunkEntry, int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := info.Size()
+
+	magic := make([]byte, len(chunkMagic))
+	if _, err = file.ReadAt(magic, 0); err != nil || string(magic) != string(chunkMagic) {
+		return nil, 0, fmt.Errorf("%s is not a chunk file", file.Name())
+	}
+
+	if size >= int64(len(chunkMagic)+indexFooterSize) {
+		footer := make([]byte, indexFooterSize)
+		if _, err = file.ReadAt(footer, size-indexFooterSize); err != nil {
+			return nil, 0, err
+		}
+		indexOffset := int64(binary.BigEndian.Uint64(footer[0:8]))
+		count := int64(binary.BigEndian.Uint32(footer[8:12]))
+		if string(footer[12:]) == string(chunkIndexMagic) && indexOffset+count*indexEntrySize+indexFooterSize == size {
+			buf := make([]byte, count*indexEntrySize)
+			if _, err = file.ReadAt(buf, indexOffset); err != nil {
+				return nil, 0, err
+			}
+			entries := make([]chunkEntry, 0, count)
+			for i := int64(0); i < count; i++ {
+				e := buf[i*indexEntrySize:]
+				entries = append(entries, chunkEntry{
+					round:  binary.BigEndian.Uint64(e[0:8]),
+					offset: int64(binary.BigEndian.Uint64(e[8:16])),
+					length: binary.BigEndian.Uint32(e[16:20]),
+				})
+			}
+			return entries, indexOffset, nil
+		}
+	}
+
+	var entries []chunkEntry
+	offset := int64(len(chunkMagic))
+	header := make([]byte, recordHeaderSize)
+	for offset+recordHeaderSize <= size {
+		if _, err = file.ReadAt(header, offset); err != nil {
+			return nil, 0, err
+		}
+		length := binary.BigEndian.Uint32(header[8:12])
+		if offset+recordHeaderSize+int64(length) > size {
+			break
+		}
+		entries = append(entries, chunkEntry{
+			round:  binary.BigEndian.Uint64(header[0:8]),
+			offset: offset + recordHeaderSize,
+			length: length,
+		})
+		offset += recordHeaderSize + int64(length)
+	}
+	return entries, offset, nil
+}
+
+// ReadChunkRecord reads the data for a round from a chunk file. If the file or
+// round does not exist yet the error wraps fs.ErrNotExist.
+func ReadChunkRecord(filename string, round uint64) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("ReadChunkRecord(): failed to open %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	entries, _, err := readChunkIndex(file)
+	if err != nil {
+		return nil, fmt.Errorf("ReadChunkRecord(): failed to read index: %w", err)
+	}
+	for _, e := range entries {
+		if e.round == round {
+			buf := make([]byte, e.length)
+			if _, err = file.ReadAt(buf, e.offset); err != nil {
+				return nil, fmt.Errorf("ReadChunkRecord(): failed to read round %d from %s: %w", round, filename, err)
+			}
+			return buf, nil
+		}
+	}
+	return nil, fmt.Errorf("ReadChunkRecord(): round %d not found in %s: %w", round, filename, fs.ErrNotExist)
+}
+
+// chunkWriter appends records to a chunk file.
+type chunkWriter struct {
+	file    *os.File
+	first   uint64
+	entries []chunkEntry
+	offset  int64
+}
+
+// openChunkWriter opens the chunk file starting at first so that nextRound can
+// be appended. Records for nextRound and later rounds, a partially written
+// record and the index are removed from an existing file.
+func openChunkWriter(filename string, first, nextRound uint64) (*chunkWriter, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("openChunkWriter(): failed to open %s: %w", filename, err)
+	}
+	w := &chunkWriter{file: file, first: first, offset: int64(len(chunkMagic))}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("openChunkWriter(): %w", err)
+	}
+	if info.Size() == 0 {
+		if _, err = file.Write(chunkMagic); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("openChunkWriter(): failed to write %s: %w", filename, err)
+		}
+		return w, nil
+	}
+
+	entries, _, err := readChunkIndex(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("openChunkWriter(): failed to read index: %w", err)
+	}
+	for _, e := range entries {
+		if e.round >= nextRound {
+			break
+		}
+		w.entries = append(w.entries, e)
+		w.offset = e.offset + int64(e.length)
+	}
+	if err = file.Truncate(w.offset); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("openChunkWriter(): failed to truncate %s: %w", filename, err)
+	}
+	if _, err = file.Seek(w.offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("openChunkWriter(): %w", err)
+	}
+	return w, nil
+}
+
+// append writes a record for round.
+func (w *chunkWriter) append(round uint64, b []byte) error {
+	record := make([]byte, recordHeaderSize, recordHeaderSize+len(b))
+	binary.BigEndian.PutUint64(record[0:8], round)
+	binary.BigEndian.PutUint32(record[8:12], uint32(len(b)))
+	record = append(record, b...)
+	if _, err := w.file.Write(record); err != nil {
+		return fmt.Errorf("append(): failed to write round %d: %w", round, err)
+	}
+	w.entries = append(w.entries, chunkEntry{round: round, offset: w.offset + recordHeaderSize, length: uint32(len(b))})
+	w.offset += int64(len(record))
+	return nil
+}
+
+// close writes the index and closes the file.
+func (w *chunkWriter) close() error {
+	buf := make([]byte, len(w.entries)*indexEntrySize+indexFooterSize)
+	for i, e := range w.entries {
+		entry := buf[i*indexEntrySize:]
+		binary.BigEndian.PutUint64(entry[0:8], e.round)
+		binary.BigEndian.PutUint64(entry[8:16], uint64(e.offset))
+		binary.BigEndian.PutUint32(entry[16:20], e.length)
+	}
+	footer := buf[len(w.entries)*indexEntrySize:]
+	binary.BigEndian.PutUint64(footer[0:8], uint64(w.offset))
+	binary.BigEndian.PutUint32(footer[8:12], uint32(len(w.entries)))
+	copy(footer[12:], chunkIndexMagic)
+
+	_, err := w.file.Write(buf)
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("close(): failed to write index: %w", err)
+	}
+	return nil
+}
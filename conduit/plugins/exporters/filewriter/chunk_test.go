@@ -0,0 +1,97 @@
+package filewriter
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkStart(t *testing.T) {
+	assert.Equal(t, uint64(0), ChunkStart(0, 10))
+	assert.Equal(t, uint64(0), ChunkStart(9, 10))
+	assert.Equal(t, uint64(10), ChunkStart(10, 10))
+	assert.Equal(t, uint64(1000), ChunkStart(1234, 1000))
+}
+
+func writeChunk(t *testing.T, filename string, first, next, last uint64, closeIndex bool) {
+	w, err := openChunkWriter(filename, first, next)
+	require.NoError(t, err)
+	for rnd := next; rnd <= last; rnd++ {
+		require.NoError(t, w.append(rnd, []byte{byte(rnd), byte(rnd)}))
+	}
+	if closeIndex {
+		require.NoError(t, w.close())
+	} else {
+		require.NoError(t, w.file.Close())
+	}
+}
+
+func requireRecords(t *testing.T, filename string, rounds ...uint64) {
+	for _, rnd := range rounds {
+		b, err := ReadChunkRecord(filename, rnd)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{byte(rnd), byte(rnd)}, b)
+	}
+}
+
+func TestChunkReadWrite(t *testing.T) {
+	tests := []struct {
+		name       string
+		closeIndex bool
+	}{
+		{"with index", true},
+		{"without index", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := path.Join(t.TempDir(), "0_blocks.chunk")
+			writeChunk(t, filename, 0, 0, 4, tc.closeIndex)
+			requireRecords(t, filename, 0, 1, 2, 3, 4)
+
+			_, err := ReadChunkRecord(filename, 5)
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+		})
+	}
+
+	_, err := ReadChunkRecord(path.Join(t.TempDir(), "missing.chunk"), 0)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+// TestChunkResume tests that an existing chunk is truncated to the next round
+// and that a partially written record is discarded.
+func TestChunkResume(t *testing.T) {
+	filename := path.Join(t.TempDir(), "10_blocks.chunk")
+	writeChunk(t, filename, 10, 10, 15, true)
+
+	// Resume at round 13, rounds 13 to 15 are rewritten and the index is replaced.
+	writeChunk(t, filename, 10, 13, 19, true)
+	requireRecords(t, filename, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19)
+
+	// A crash while writing a record leaves a partial record without an index.
+	writeChunk(t, filename, 10, 12, 13, false)
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.Write([]byte{0, 0, 0, 0, 0, 0, 0, 14, 0, 0, 0, 2, 14})
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	_, err = ReadChunkRecord(filename, 14)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	writeChunk(t, filename, 10, 14, 14, true)
+	requireRecords(t, filename, 10, 11, 12, 13, 14)
+	_, err = ReadChunkRecord(filename, 15)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestChunkInvalidFile(t *testing.T) {
+	filename := path.Join(t.TempDir(), "0_blocks.chunk")
+	require.NoError(t, os.WriteFile(filename, []byte("not a chunk file"), 0644))
+	_, err := ReadChunkRecord(filename, 0)
+	assert.ErrorContains(t, err, "is not a chunk file")
+	_, err = openChunkWriter(filename, 0, 0)
+	assert.ErrorContains(t, err, "is not a chunk file")
+}
@@ -1,17 +1,24 @@
 package filewriter
 
 import (
+	"bytes"
 	"context"
 	_ "embed" // used to embed config
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path"
 
+	"github.com/algorand/go-codec/codec"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 
 	"github.com/algorand/conduit/conduit"
+	conduitcodec "github.com/algorand/conduit/conduit/codec"
 	"github.com/algorand/conduit/conduit/data"
 	"github.com/algorand/conduit/conduit/plugins"
 	"github.com/algorand/conduit/conduit/plugins/exporters"
@@ -22,14 +29,37 @@ const (
 	PluginName = "file_writer"
 	// FilePattern is used to name the output files.
 	FilePattern = "%[1]d_block.json"
+	// SignatureSuffix is appended to the name of a file for its signature.
+	SignatureSuffix = ".sig"
+	// VersionsFilename is the file in the blocks directory which records the
+	// versions of the pipeline which wrote the blocks.
+	VersionsFilename = "conduit_versions.json"
 )
 
 type fileExporter struct {
 	round  uint64
 	cfg    Config
 	logger *logrus.Logger
+	// chunk is the open chunk file when rounds-per-file is used.
+	chunk *chunkWriter
+	// signer signs the files once they are complete, it is nil if signing is disabled.
+	signer conduit.Signer
+	// prettyHandle and chunkHandle write binary fields with the pipeline
+	// binary-encoding, they are nil for the default base64.
+	prettyHandle *codec.JsonHandle
+	chunkHandle  *codec.JsonHandle
+	// amountFormat rewrites the amounts, it is nil for the default integer
+	// encoding.
+	amountFormat *conduit.AmountFormat
+	// checker validates each block file or chunk record, it is nil unless an
+	// output-schema is configured.
+	checker conduit.PayloadChecker
 }
 
+// errPayloadRejected is returned by the encoder when the payload checker
+// rejected a block, the block is not written.
+var errPayloadRejected = errors.New("payload rejected")
+
 //go:embed sample.yaml
 var sampleFile string
 
@@ -53,6 +83,15 @@ func (exp *fileExporter) Init(_ context.Context, initProvider data.InitProvider,
 	if exp.cfg.FilenamePattern == "" {
 		exp.cfg.FilenamePattern = FilePattern
 	}
+	if exp.cfg.ChunkFilenamePattern == "" {
+		exp.cfg.ChunkFilenamePattern = ChunkFilePattern
+	}
+	if exp.cfg.FilenamePattern, err = conduitcodec.WithCompression(exp.cfg.FilenamePattern, exp.cfg.Compression); err != nil {
+		return fmt.Errorf("Init(): filename-pattern: %w", err)
+	}
+	if exp.cfg.ChunkFilenamePattern, err = conduitcodec.WithCompression(exp.cfg.ChunkFilenamePattern, exp.cfg.Compression); err != nil {
+		return fmt.Errorf("Init(): chunk-filename-pattern: %w", err)
+	}
 	// default to the data directory if no override provided.
 	if exp.cfg.BlocksDir == "" {
 		exp.cfg.BlocksDir = cfg.DataDir
@@ -76,6 +115,149 @@ func (exp *fileExporter) Config() string {
 
 func (exp *fileExporter) Close() error {
 	exp.logger.Infof("latest round on file: %d", exp.round)
+	if exp.chunk != nil {
+		return exp.closeChunk()
+	}
+	return nil
+}
+
+// SetSigner signs each file once it is complete.
+func (exp *fileExporter) SetSigner(signer conduit.Signer) {
+	exp.signer = signer
+}
+
+// SetBinaryEncoding writes binary fields with the encoding. Files which do not
+// use base64 cannot be read by the file_reader importer.
+func (exp *fileExporter) SetBinaryEncoding(encoding conduit.BinaryEncoding) {
+	exp.prettyHandle = encoding.JSONHandle(2)
+	exp.chunkHandle = encoding.JSONHandle(0)
+}
+
+// SetAmountFormat rewrites the amounts of the blocks with the format. Files
+// which do not use the integer encoding cannot be read by the file_reader
+// importer.
+func (exp *fileExporter) SetAmountFormat(format *conduit.AmountFormat) {
+	exp.amountFormat = format
+}
+
+// SetPayloadChecker checks each block file, or chunk record, before it is
+// written. Rejected rounds are not written.
+func (exp *fileExporter) SetPayloadChecker(checker conduit.PayloadChecker) {
+	exp.checker = checker
+}
+
+// RecordedVersions reads the versions file of the blocks directory.
+func (exp *fileExporter) RecordedVersions() (*conduit.WriterVersions, error) {
+	encoded, err := os.ReadFile(path.Join(exp.cfg.BlocksDir, VersionsFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("RecordedVersions(): %w", err)
+	}
+	var versions conduit.WriterVersions
+	if err = json.Unmarshal(encoded, &versions); err != nil {
+		return nil, fmt.Errorf("RecordedVersions(): unable to decode %s: %w", VersionsFilename, err)
+	}
+	return &versions, nil
+}
+
+// RecordVersions writes the versions file of the blocks directory.
+func (exp *fileExporter) RecordVersions(versions conduit.WriterVersions) error {
+	encoded, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("RecordVersions(): unable to encode versions: %w", err)
+	}
+	if err = os.WriteFile(path.Join(exp.cfg.BlocksDir, VersionsFilename), encoded, 0644); err != nil {
+		return fmt.Errorf("RecordVersions(): %w", err)
+	}
+	return nil
+}
+
+// encoder returns the function which encodes a block file or chunk record of
+// the current round.
+func (exp *fileExporter) encoder(pretty bool) encodeFunc {
+	encode := handleEncoder(exp.handle(pretty))
+	if exp.amountFormat == nil && exp.checker == nil {
+		return encode
+	}
+	indent := ""
+	if pretty {
+		indent = "  "
+	}
+	return func(w io.Writer, v interface{}) error {
+		var buf bytes.Buffer
+		if err := encode(&buf, v); err != nil {
+			return err
+		}
+		b := buf.Bytes()
+		if exp.amountFormat != nil {
+			var err error
+			if b, err = exp.amountFormat.Rewrite(b, indent); err != nil {
+				return err
+			}
+		}
+		if exp.checker != nil {
+			ok, err := exp.checker.Check(exp.round, b)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return errPayloadRejected
+			}
+		}
+		_, err := w.Write(b)
+		return err
+	}
+}
+
+// handle returns the handle to encode a block file or chunk record.
+func (exp *fileExporter) handle(pretty bool) *codec.JsonHandle {
+	if pretty && exp.prettyHandle != nil {
+		return exp.prettyHandle
+	}
+	if !pretty && exp.chunkHandle != nil {
+		return exp.chunkHandle
+	}
+	return encodeHandle(pretty)
+}
+
+// signFile writes the base64 signature of a file to the file name with
+// SignatureSuffix appended.
+func (exp *fileExporter) signFile(filename string) error {
+	if exp.signer == nil {
+		return nil
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("signFile(): %w", err)
+	}
+	signature := base64.StdEncoding.EncodeToString(exp.signer.Sign(b)) + "\n"
+	if err = os.WriteFile(filename+SignatureSuffix, []byte(signature), 0644); err != nil {
+		return fmt.Errorf("signFile(): failed to write signature: %w", err)
+	}
+	return nil
+}
+
+// closeChunk writes the index of the open chunk file and signs it.
+func (exp *fileExporter) closeChunk() error {
+	filename := exp.chunk.file.Name()
+	err := exp.chunk.close()
+	exp.chunk = nil
+	if err != nil {
+		return err
+	}
+	return exp.signFile(filename)
+}
+
+// ForceCommit syncs the open chunk file, other files are complete once written.
+func (exp *fileExporter) ForceCommit() error {
+	if exp.chunk == nil {
+		return nil
+	}
+	if err := exp.chunk.file.Sync(); err != nil {
+		return fmt.Errorf("ForceCommit(): failed to sync chunk: %w", err)
+	}
 	return nil
 }
 
@@ -84,7 +266,11 @@ func (exp *fileExporter) Receive(exportData data.BlockData) error {
 		return fmt.Errorf("exporter not initialized")
 	}
 	if exportData.Round() != exp.round {
-		return fmt.Errorf("Receive(): wrong block: received round %d, expected round %d", exportData.Round(), exp.round)
+		err := fmt.Errorf("Receive(): wrong block: received round %d, expected round %d", exportData.Round(), exp.round)
+		if exportData.Round() < exp.round {
+			return conduit.MakeDuplicateRoundError(exportData.Round(), err)
+		}
+		return err
 	}
 
 	// write block to file
@@ -93,20 +279,107 @@ func (exp *fileExporter) Receive(exportData data.BlockData) error {
 			exportData.Certificate = nil
 		}
 
-		blockFile := path.Join(exp.cfg.BlocksDir, fmt.Sprintf(exp.cfg.FilenamePattern, exportData.Round()))
-		err := EncodeJSONToFile(blockFile, exportData, true)
-		if err != nil {
-			return fmt.Errorf("Receive(): failed to write file %s: %w", blockFile, err)
+		if exp.cfg.RoundsPerFile > 1 {
+			if err := exp.writeChunk(exportData); err != nil {
+				return fmt.Errorf("Receive(): %w", err)
+			}
+		} else {
+			blockFile := path.Join(exp.cfg.BlocksDir, fmt.Sprintf(exp.cfg.FilenamePattern, exportData.Round()))
+			err := encodeJSONToFile(blockFile, exportData, exp.encoder(true))
+			if errors.Is(err, errPayloadRejected) {
+				exp.logger.Warnf("Block %d was rejected by the payload checker and was not written", exportData.Round())
+				if err = os.Remove(blockFile); err != nil {
+					return fmt.Errorf("Receive(): failed to remove file %s: %w", blockFile, err)
+				}
+				exp.round++
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("Receive(): failed to write file %s: %w", blockFile, err)
+			}
+			if err = exp.signFile(blockFile); err != nil {
+				return fmt.Errorf("Receive(): %w", err)
+			}
+			exp.logger.Infof("Wrote block %d to %s", exportData.Round(), blockFile)
 		}
-		exp.logger.Infof("Wrote block %d to %s", exportData.Round(), blockFile)
 	}
 
 	exp.round++
 	return nil
 }
 
+// ReadRound reads the block of a round back from its file or chunk. Blocks
+// written with a binary-encoding or amount encoding other than the default
+// cannot be read.
+func (exp *fileExporter) ReadRound(round uint64) (data.BlockData, bool, error) {
+	var blk data.BlockData
+	if exp.prettyHandle != nil || exp.amountFormat != nil {
+		return blk, false, fmt.Errorf("ReadRound(): blocks written with a binary-encoding or amount encoding cannot be read")
+	}
+	if exp.cfg.RoundsPerFile > 1 {
+		chunkFile := path.Join(exp.cfg.BlocksDir, fmt.Sprintf(exp.cfg.ChunkFilenamePattern, ChunkStart(round, exp.cfg.RoundsPerFile)))
+		b, err := ReadChunkRecord(chunkFile, round)
+		if errors.Is(err, fs.ErrNotExist) {
+			return blk, false, nil
+		}
+		if err != nil {
+			return blk, false, fmt.Errorf("ReadRound(): %w", err)
+		}
+		if err = DecodeJSONFromBytes(chunkFile, b, &blk, false); err != nil {
+			return blk, false, fmt.Errorf("ReadRound(): %w", err)
+		}
+		return blk, true, nil
+	}
+	blockFile := path.Join(exp.cfg.BlocksDir, fmt.Sprintf(exp.cfg.FilenamePattern, round))
+	err := DecodeJSONFromFile(blockFile, &blk, false)
+	if errors.Is(err, fs.ErrNotExist) {
+		return blk, false, nil
+	}
+	if err != nil {
+		return blk, false, fmt.Errorf("ReadRound(): %w", err)
+	}
+	return blk, true, nil
+}
+
+// writeChunk appends the block to its chunk file. The index is written once the
+// last round of the chunk has been added.
+func (exp *fileExporter) writeChunk(exportData data.BlockData) error {
+	round := exportData.Round()
+	first := ChunkStart(round, exp.cfg.RoundsPerFile)
+	chunkFile := path.Join(exp.cfg.BlocksDir, fmt.Sprintf(exp.cfg.ChunkFilenamePattern, first))
+	if exp.chunk == nil {
+		var err error
+		exp.chunk, err = openChunkWriter(chunkFile, first, round)
+		if err != nil {
+			return err
+		}
+	}
+
+	b, err := encodeJSONToBytes(chunkFile, exportData, exp.encoder(false))
+	switch {
+	case errors.Is(err, errPayloadRejected):
+		exp.logger.Warnf("Block %d was rejected by the payload checker and was not written to %s", round, chunkFile)
+	case err != nil:
+		return err
+	default:
+		if err = exp.chunk.append(round, b); err != nil {
+			// Reopening the chunk removes a partially written record.
+			exp.chunk.file.Close()
+			exp.chunk = nil
+			return err
+		}
+		exp.logger.Infof("Wrote block %d to %s", round, chunkFile)
+	}
+
+	if round == first+exp.cfg.RoundsPerFile-1 {
+		err = exp.closeChunk()
+	}
+	return err
+}
+
 func init() {
 	exporters.Register(PluginName, exporters.ExporterConstructorFunc(func() exporters.Exporter {
 		return &fileExporter{}
 	}))
+	plugins.RegisterConfigSchema(plugins.Exporter, PluginName, Config{})
 }
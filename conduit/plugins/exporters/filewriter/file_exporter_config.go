@@ -22,10 +22,23 @@ type Config struct {
 	FilenamePattern string `yaml:"filename-pattern"`
 	// <code>drop-certificate</code> is used to remove the vote certificate from the block data before writing files.
 	DropCertificate bool `yaml:"drop-certificate"`
+	/* <code>rounds-per-file</code> writes several rounds to each file, reducing the number of files for long archives.<br/>
+	Each chunk file starts at a multiple of rounds-per-file and contains length-prefixed records followed by an index.<br/>
+	A value of 0 or 1 writes one file per round using filename-pattern.
+	*/
+	RoundsPerFile uint64 `yaml:"rounds-per-file"`
+	/* <code>chunk-filename-pattern</code> is the format used to write chunk files. It uses go
+	string formatting and should accept one number for the first round in the chunk.<br/>
+	If the file has a '.gz' extension, each record will be gzipped.
+	Default:
 
-	// TODO: compression level - Default, Fastest, Best compression, etc
+		"%[1]d_blocks.chunk"
+	*/
+	ChunkFilenamePattern string `yaml:"chunk-filename-pattern"`
+	/* <code>compression</code> is the name of the compression of the files: none, gzip, zstd, lz4 or snappy.<br/>
+	Its extension is added to the filename patterns which do not have it. By default the compression is chosen by the extension of the patterns.
+	*/
+	Compression string `yaml:"compression"`
 
-	// TODO: How to avoid having millions of files in a directory?
-	//       Write batches of blocks to a single file?
-	//       Tree of directories
+	// TODO: compression level - Default, Fastest, Best compression, etc
 }
@@ -2,9 +2,12 @@ package filewriter
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -13,6 +16,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 
+	"github.com/algorand/conduit/conduit"
 	"github.com/algorand/conduit/conduit/data"
 	"github.com/algorand/conduit/conduit/plugins"
 	"github.com/algorand/conduit/conduit/plugins/exporters"
@@ -96,7 +100,8 @@ func TestExporterInit(t *testing.T) {
 	// creates a new output file
 	err := fileExp.Init(context.Background(), testutil.MockedInitProvider(&round), plugins.MakePluginConfig(config), logger)
 	pluginConfig := fileExp.Config()
-	configWithDefault := config + "filename-pattern: '%[1]d_block.json'\n" + "drop-certificate: false\n"
+	configWithDefault := config + "filename-pattern: '%[1]d_block.json'\n" + "drop-certificate: false\n" +
+		"rounds-per-file: 0\n" + "chunk-filename-pattern: '%[1]d_blocks.chunk'\n" + "compression: \"\"\n"
 	assert.Equal(t, configWithDefault, string(pluginConfig))
 	fileExp.Close()
 
@@ -238,3 +243,230 @@ func TestDropCertificate(t *testing.T) {
 		assert.Nil(t, blockData.Certificate)
 	}
 }
+
+func TestRoundsPerFile(t *testing.T) {
+	tempdir := t.TempDir()
+	cfg := Config{
+		BlocksDir:     tempdir,
+		RoundsPerFile: 10,
+	}
+	config, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+
+	numRounds := 25
+	sendData(t, fileCons.New(), string(config), numRounds)
+
+	for _, first := range []uint64{0, 10, 20} {
+		assert.FileExists(t, path.Join(tempdir, fmt.Sprintf(ChunkFilePattern, first)))
+	}
+	assert.NoFileExists(t, path.Join(tempdir, fmt.Sprintf(FilePattern, 0)))
+
+	// block data is valid
+	for i := 0; i < numRounds; i++ {
+		chunkFile := path.Join(tempdir, fmt.Sprintf(ChunkFilePattern, ChunkStart(uint64(i), 10)))
+		b, err := ReadChunkRecord(chunkFile, uint64(i))
+		require.NoError(t, err)
+		var blockData data.BlockData
+		err = DecodeJSONFromBytes(chunkFile, b, &blockData, true)
+		require.NoError(t, err)
+		require.Equal(t, sdk.Round(i), blockData.BlockHeader.Round)
+		require.NotNil(t, blockData.Certificate)
+	}
+}
+
+func TestReadRound(t *testing.T) {
+	for _, roundsPerFile := range []uint64{0, 10} {
+		config, err := yaml.Marshal(Config{BlocksDir: t.TempDir(), RoundsPerFile: roundsPerFile})
+		require.NoError(t, err)
+		fileExp := fileCons.New()
+		sendData(t, fileExp, string(config), 5)
+
+		reader := fileExp.(conduit.RoundReader)
+		blk, ok, err := reader.ReadRound(3)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, sdk.Round(3), blk.BlockHeader.Round)
+		require.NotNil(t, blk.Delta)
+		assert.Equal(t, int64(1234), blk.Delta.PrevTimestamp)
+
+		_, ok, err = reader.ReadRound(5)
+		require.NoError(t, err)
+		assert.False(t, ok, "round 5 was not written")
+		_, ok, err = reader.ReadRound(15)
+		require.NoError(t, err)
+		assert.False(t, ok, "the chunk of round 15 was not written")
+	}
+}
+
+func TestForceCommit(t *testing.T) {
+	tempdir := t.TempDir()
+	config, err := yaml.Marshal(Config{BlocksDir: tempdir, RoundsPerFile: 10})
+	require.NoError(t, err)
+
+	fileExp := fileCons.New()
+	committer, ok := fileExp.(conduit.ForceCommitter)
+	require.True(t, ok)
+	rnd := sdk.Round(0)
+	err = fileExp.Init(context.Background(), testutil.MockedInitProvider(&rnd), plugins.MakePluginConfig(string(config)), logger)
+	require.NoError(t, err)
+	require.NoError(t, committer.ForceCommit())
+
+	for i := sdk.Round(0); i < 3; i++ {
+		require.NoError(t, fileExp.Receive(data.BlockData{BlockHeader: sdk.BlockHeader{Round: i}}))
+	}
+	require.NoError(t, committer.ForceCommit())
+
+	// The committed rounds are readable before the chunk index is written.
+	chunkFile := path.Join(tempdir, fmt.Sprintf(ChunkFilePattern, 0))
+	for i := uint64(0); i < 3; i++ {
+		_, err = ReadChunkRecord(chunkFile, i)
+		require.NoError(t, err)
+	}
+	require.NoError(t, fileExp.Close())
+}
+
+func TestRecordVersions(t *testing.T) {
+	tempdir := t.TempDir()
+	config, err := yaml.Marshal(Config{BlocksDir: tempdir})
+	require.NoError(t, err)
+
+	fileExp := fileCons.New()
+	recorder, ok := fileExp.(conduit.VersionRecorder)
+	require.True(t, ok)
+	rnd := sdk.Round(0)
+	err = fileExp.Init(context.Background(), testutil.MockedInitProvider(&rnd), plugins.MakePluginConfig(string(config)), logger)
+	require.NoError(t, err)
+
+	recorded, err := recorder.RecordedVersions()
+	require.NoError(t, err)
+	assert.Nil(t, recorded)
+
+	versions := conduit.WriterVersions{Conduit: "1.2.0", Plugins: map[string]string{"exporter/file_writer": "1.2.0"}}
+	require.NoError(t, recorder.RecordVersions(versions))
+	recorded, err = recorder.RecordedVersions()
+	require.NoError(t, err)
+	assert.Equal(t, &versions, recorded)
+	assert.FileExists(t, path.Join(tempdir, VersionsFilename))
+	require.NoError(t, fileExp.Close())
+}
+
+// testSigner signs with a generated ed25519 key.
+type testSigner struct {
+	key ed25519.PrivateKey
+}
+
+func (s testSigner) Sign(msg []byte) []byte {
+	return ed25519.Sign(s.key, msg)
+}
+
+func (s testSigner) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+func TestSignFiles(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	signer := testSigner{key: key}
+
+	tests := []struct {
+		name          string
+		roundsPerFile uint64
+		files         []string
+	}{
+		{name: "file per round", files: []string{"0_block.json", "1_block.json", "2_block.json"}},
+		{name: "chunk", roundsPerFile: 2, files: []string{"0_blocks.chunk", "2_blocks.chunk"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tempdir := t.TempDir()
+			config, err := yaml.Marshal(Config{BlocksDir: tempdir, RoundsPerFile: tc.roundsPerFile})
+			require.NoError(t, err)
+
+			fileExp := fileCons.New()
+			outputSigner, ok := fileExp.(conduit.OutputSigner)
+			require.True(t, ok)
+			rnd := sdk.Round(0)
+			err = fileExp.Init(context.Background(), testutil.MockedInitProvider(&rnd), plugins.MakePluginConfig(string(config)), logger)
+			require.NoError(t, err)
+			outputSigner.SetSigner(signer)
+			for i := sdk.Round(0); i < 3; i++ {
+				require.NoError(t, fileExp.Receive(data.BlockData{BlockHeader: sdk.BlockHeader{Round: i}}))
+			}
+			require.NoError(t, fileExp.Close())
+
+			for _, name := range tc.files {
+				b, err := os.ReadFile(path.Join(tempdir, name))
+				require.NoError(t, err)
+				sigFile, err := os.ReadFile(path.Join(tempdir, name+SignatureSuffix))
+				require.NoError(t, err)
+				signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigFile)))
+				require.NoError(t, err)
+				assert.True(t, ed25519.Verify(signer.PublicKey(), b, signature), name)
+			}
+		})
+	}
+}
+
+// rejectChecker rejects the payloads of odd rounds.
+type rejectChecker struct {
+	payloads map[uint64][]byte
+}
+
+func (c *rejectChecker) Check(round uint64, payload []byte) (bool, error) {
+	c.payloads[round] = payload
+	return round%2 == 0, nil
+}
+
+func TestPayloadChecker(t *testing.T) {
+	tests := []struct {
+		name          string
+		roundsPerFile uint64
+	}{
+		{name: "file per round"},
+		{name: "chunk", roundsPerFile: 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tempdir := t.TempDir()
+			config, err := yaml.Marshal(Config{BlocksDir: tempdir, RoundsPerFile: tc.roundsPerFile})
+			require.NoError(t, err)
+
+			fileExp := fileCons.New()
+			validator, ok := fileExp.(conduit.PayloadValidator)
+			require.True(t, ok)
+			rnd := sdk.Round(0)
+			err = fileExp.Init(context.Background(), testutil.MockedInitProvider(&rnd), plugins.MakePluginConfig(string(config)), logger)
+			require.NoError(t, err)
+			checker := &rejectChecker{payloads: make(map[uint64][]byte)}
+			validator.SetPayloadChecker(checker)
+			for i := sdk.Round(0); i < 4; i++ {
+				require.NoError(t, fileExp.Receive(data.BlockData{BlockHeader: sdk.BlockHeader{Round: i}}))
+			}
+			require.NoError(t, fileExp.Close())
+			require.Len(t, checker.payloads, 4)
+
+			for i := uint64(0); i < 4; i++ {
+				var blk data.BlockData
+				require.NoError(t, DecodeJSONFromBytes("", checker.payloads[i], &blk, true))
+				assert.Equal(t, sdk.Round(i), blk.BlockHeader.Round)
+
+				if tc.roundsPerFile == 0 {
+					blockFile := path.Join(tempdir, fmt.Sprintf(FilePattern, i))
+					if i%2 == 0 {
+						assert.FileExists(t, blockFile)
+					} else {
+						assert.NoFileExists(t, blockFile)
+					}
+					continue
+				}
+				chunkFile := path.Join(tempdir, fmt.Sprintf(ChunkFilePattern, ChunkStart(i, tc.roundsPerFile)))
+				_, err = ReadChunkRecord(chunkFile, i)
+				if i%2 == 0 {
+					assert.NoError(t, err)
+				} else {
+					assert.ErrorIs(t, err, os.ErrNotExist)
+				}
+			}
+		})
+	}
+}
@@ -2,15 +2,15 @@ package filewriter
 
 import (
 	"bytes"
-	"compress/gzip"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
-	"strings"
 
 	"github.com/algorand/go-algorand-sdk/v2/encoding/json"
 	"github.com/algorand/go-codec/codec"
+
+	conduitcodec "github.com/algorand/conduit/conduit/codec"
 )
 
 var prettyHandle *codec.JsonHandle
@@ -37,33 +37,71 @@ func init() {
 	jsonStrictHandle.MapKeyAsString = true
 }
 
-// EncodeJSONToFile is used to encode an object to a file. If the file ends in .gz it will be gzipped.
+// encodeHandle returns the default handle to encode files.
+func encodeHandle(pretty bool) *codec.JsonHandle {
+	if pretty {
+		return prettyHandle
+	}
+	return jsonStrictHandle
+}
+
+// encodeFunc writes the JSON encoding of v.
+type encodeFunc func(w io.Writer, v interface{}) error
+
+// handleEncoder returns an encodeFunc which encodes with the handle.
+func handleEncoder(handle *codec.JsonHandle) encodeFunc {
+	return func(w io.Writer, v interface{}) error {
+		return codec.NewEncoder(w, handle).Encode(v)
+	}
+}
+
+// EncodeJSONToFile is used to encode an object to a file. The file is compressed with the compression of its
+// extension, for example it is gzipped if it ends in .gz.
 func EncodeJSONToFile(filename string, v interface{}, pretty bool) error {
-	var writer io.Writer
+	return encodeJSONToFile(filename, v, handleEncoder(encodeHandle(pretty)))
+}
 
+func encodeJSONToFile(filename string, v interface{}, encode encodeFunc) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("EncodeJSONToFile(): failed to create %s: %w", filename, err)
 	}
 	defer file.Close()
 
-	if strings.HasSuffix(filename, ".gz") {
-		gz := gzip.NewWriter(file)
-		gz.Name = filename
-		defer gz.Close()
-		writer = gz
-	} else {
-		writer = file
+	writer, err := conduitcodec.CompressionOf(filename).NewWriter(file)
+	if err != nil {
+		return fmt.Errorf("EncodeJSONToFile(): %w", err)
+	}
+	if err = encode(writer, v); err != nil {
+		writer.Close()
+		return err
 	}
+	if err = writer.Close(); err != nil {
+		return fmt.Errorf("EncodeJSONToFile(): failed to compress %s: %w", filename, err)
+	}
+	return nil
+}
 
-	var handle *codec.JsonHandle
-	if pretty {
-		handle = prettyHandle
-	} else {
-		handle = jsonStrictHandle
+// EncodeJSONToBytes is used to encode an object for a file. The result is compressed with the compression of the
+// filename extension, for example it is gzipped if the filename ends in .gz.
+func EncodeJSONToBytes(filename string, v interface{}, pretty bool) ([]byte, error) {
+	return encodeJSONToBytes(filename, v, handleEncoder(encodeHandle(pretty)))
+}
+
+func encodeJSONToBytes(filename string, v interface{}, encode encodeFunc) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := conduitcodec.CompressionOf(filename).NewWriter(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("EncodeJSONToBytes(): %w", err)
+	}
+	if err = encode(writer, v); err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("EncodeJSONToBytes(): failed to encode: %w", err)
+	}
+	if err = writer.Close(); err != nil {
+		return nil, fmt.Errorf("EncodeJSONToBytes(): failed to compress: %w", err)
 	}
-	enc := codec.NewEncoder(writer, handle)
-	return enc.Encode(v)
+	return buf.Bytes(), nil
 }
 
 // DecodeJSONFromFile is used to decode a file to an object.
@@ -73,17 +111,17 @@ func DecodeJSONFromFile(filename string, v interface{}, strict bool) error {
 	if err != nil {
 		return fmt.Errorf("DecodeJSONFromFile(): failed to read %s: %w", filename, err)
 	}
+	return DecodeJSONFromBytes(filename, fileBytes, v, strict)
+}
 
-	var reader io.Reader = bytes.NewReader(fileBytes)
-
-	if strings.HasSuffix(filename, ".gz") {
-		gz, err := gzip.NewReader(reader)
-		if err != nil {
-			return fmt.Errorf("DecodeJSONFromFile(): failed to make gzip reader: %w", err)
-		}
-		defer gz.Close()
-		reader = gz
+// DecodeJSONFromBytes is used to decode the contents of a file to an object. The contents are decompressed with the
+// compression of the filename extension, for example they are gunzipped if the filename ends in .gz.
+func DecodeJSONFromBytes(filename string, fileBytes []byte, v interface{}, strict bool) error {
+	reader, err := conduitcodec.CompressionOf(filename).NewReader(bytes.NewReader(fileBytes))
+	if err != nil {
+		return fmt.Errorf("DecodeJSONFromBytes(): %w", err)
 	}
+	defer reader.Close()
 	var handle *codec.JsonHandle
 	if strict {
 		handle = json.CodecHandle
@@ -2,10 +2,13 @@ package filewriter
 
 import (
 	"io/ioutil"
+	"os/exec"
 	"path"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/codec"
 )
 
 func TestEncodeToAndFromFile(t *testing.T) {
@@ -60,4 +63,20 @@ func TestEncodeToAndFromFile(t *testing.T) {
 		err = DecodeJSONFromFile(small, &testDecode, false)
 		require.Equal(t, data, testDecode)
 	}
+
+	// compressions which run a command
+	for _, command := range []string{"zstd", "lz4"} {
+		if _, err := exec.LookPath(command); err != nil {
+			continue
+		}
+		compression, err := codec.CompressionByName(command)
+		require.NoError(t, err)
+		small := path.Join(tempdir, "small.json"+compression.Extension())
+		require.NoError(t, EncodeJSONToFile(small, data, false))
+		b, err := ioutil.ReadFile(small)
+		require.NoError(t, err)
+		var testDecode test
+		require.NoError(t, DecodeJSONFromBytes(small, b, &testDecode, false))
+		require.Equal(t, data, testDecode)
+	}
 }
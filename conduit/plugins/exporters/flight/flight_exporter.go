@@ -0,0 +1,378 @@
+package flight
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed" // used to embed config
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+)
+
+// PluginName to use when configuring.
+const PluginName = "arrow_flight"
+
+const (
+	defaultListenAddr   = "127.0.0.1:8815"
+	defaultRetainRounds = 1000
+	// maxBatchRows is the number of rows after which a record batch is sent.
+	maxBatchRows = 64 * 1024
+	// shutdownTimeout is how long Close waits for the running calls.
+	shutdownTimeout = 5 * time.Second
+)
+
+type flightExporter struct {
+	round  uint64
+	cfg    Config
+	logger *logrus.Logger
+
+	listener net.Listener
+	server   *grpc.Server
+	// meter counts the traffic with the clients, it is nil unless the
+	// pipeline accounts for the bandwidth.
+	meter conduit.TrafficMeter
+
+	// mu protects rounds, the retained rounds in order.
+	mu     sync.RWMutex
+	rounds []*roundData
+}
+
+//go:embed sample.yaml
+var sampleFile string
+
+var metadata = conduit.Metadata{
+	Name:         PluginName,
+	Description:  "Exporter serving recent transactions as Arrow record batches over Arrow Flight.",
+	Deprecated:   false,
+	SampleConfig: sampleFile,
+}
+
+func (exp *flightExporter) Metadata() conduit.Metadata {
+	return metadata
+}
+
+// ValidateConfig checks the config without listening.
+func (exp *flightExporter) ValidateConfig(cfg plugins.PluginConfig) error {
+	var fcfg Config
+	if err := cfg.UnmarshalConfig(&fcfg); err != nil {
+		return fmt.Errorf("ValidateConfig(): %w", err)
+	}
+	if err := fcfg.setDefaults(); err != nil {
+		return fmt.Errorf("ValidateConfig(): %w", err)
+	}
+	return nil
+}
+
+func (exp *flightExporter) Init(_ context.Context, initProvider data.InitProvider, cfg plugins.PluginConfig, logger *logrus.Logger) error {
+	exp.logger = logger
+	if err := cfg.UnmarshalConfig(&exp.cfg); err != nil {
+		return fmt.Errorf("connect failure in unmarshalConfig: %w", err)
+	}
+	if err := exp.cfg.setDefaults(); err != nil {
+		return fmt.Errorf("Init(): %w", err)
+	}
+	exp.round = uint64(initProvider.NextDBRound())
+
+	var err error
+	if exp.listener, err = net.Listen("tcp", exp.cfg.ListenAddr); err != nil {
+		return fmt.Errorf("Init(): %w", err)
+	}
+	exp.listener = conduit.MeteredListener(exp.listener, exp.meter)
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(exp.authorizeUnary),
+		grpc.ChainStreamInterceptor(exp.authorizeStream),
+	}
+	scheme := "grpc"
+	if exp.cfg.TLS.CertFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(exp.cfg.TLS.CertFile, exp.cfg.TLS.KeyFile)
+		if err != nil {
+			exp.listener.Close()
+			return fmt.Errorf("Init(): %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+		scheme = "grpc+tls"
+	}
+	exp.server = grpc.NewServer(opts...)
+	flight.RegisterFlightServiceServer(exp.server, &flightService{exp: exp})
+	go func() {
+		if err := exp.server.Serve(exp.listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			exp.logger.Errorf("arrow flight server error: %v", err)
+		}
+	}()
+	exp.logger.Infof("Serving Arrow Flight on %s://%s", scheme, exp.listener.Addr())
+	return nil
+}
+
+// setDefaults fills in the defaults and validates the configuration.
+func (cfg *Config) setDefaults() error {
+	if cfg.ListenAddr == "" {
+		cfg.ListenAddr = defaultListenAddr
+	}
+	if cfg.RetainRounds == 0 {
+		cfg.RetainRounds = defaultRetainRounds
+	}
+	if cfg.RetainRounds < 0 {
+		return fmt.Errorf("retain-rounds must be positive")
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		return fmt.Errorf("tls cert-file and key-file must both be set")
+	}
+	return nil
+}
+
+func (exp *flightExporter) Config() string {
+	cfg := exp.cfg
+	if cfg.Token != "" {
+		cfg.Token = "********"
+	}
+	ret, _ := yaml.Marshal(cfg)
+	return string(ret)
+}
+
+// Close stops the server, running calls are given shutdownTimeout to finish.
+func (exp *flightExporter) Close() error {
+	if exp.server == nil {
+		return nil
+	}
+	stopped := make(chan struct{})
+	go func() {
+		exp.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		exp.server.Stop()
+	}
+	return nil
+}
+
+// SetTrafficMeter counts the bytes exchanged with the clients, by listen
+// address.
+func (exp *flightExporter) SetTrafficMeter(meter conduit.TrafficMeter) {
+	exp.meter = meter
+}
+
+// Receive retains the rows of the round, dropping the oldest round once
+// retain-rounds are retained.
+func (exp *flightExporter) Receive(exportData data.BlockData) error {
+	if exp.logger == nil {
+		return fmt.Errorf("exporter not initialized")
+	}
+	if exportData.Round() != exp.round {
+		return fmt.Errorf("Receive(): wrong block: received round %d, expected round %d", exportData.Round(), exp.round)
+	}
+	r := makeRoundData(&exportData)
+	exp.mu.Lock()
+	if len(exp.rounds) >= exp.cfg.RetainRounds {
+		exp.rounds = exp.rounds[len(exp.rounds)-exp.cfg.RetainRounds+1:]
+	}
+	exp.rounds = append(exp.rounds, r)
+	exp.mu.Unlock()
+	exp.round++
+	return nil
+}
+
+// RewindRound drops the retained rounds from the round on.
+func (exp *flightExporter) RewindRound(round uint64) error {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+	for i, r := range exp.rounds {
+		if r.round >= round {
+			exp.rounds = exp.rounds[:i:i]
+			break
+		}
+	}
+	exp.round = round
+	return nil
+}
+
+// retained returns the retained rounds from the round on.
+func (exp *flightExporter) retained(from uint64) []*roundData {
+	exp.mu.RLock()
+	defer exp.mu.RUnlock()
+	for i, r := range exp.rounds {
+		if r.round >= from {
+			return exp.rounds[i:len(exp.rounds):len(exp.rounds)]
+		}
+	}
+	return nil
+}
+
+// authorize checks the bearer token of the call.
+func (exp *flightExporter) authorize(ctx context.Context) error {
+	if exp.cfg.Token == "" {
+		return nil
+	}
+	md, _ := grpcmetadata.FromIncomingContext(ctx)
+	for _, auth := range md.Get("authorization") {
+		if strings.HasPrefix(auth, "Bearer ") && subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(exp.cfg.Token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing bearer token")
+}
+
+func (exp *flightExporter) authorizeUnary(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := exp.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (exp *flightExporter) authorizeStream(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := exp.authorize(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// flightService serves the Flight service, the methods which are not
+// implemented return Unimplemented.
+type flightService struct {
+	flight.BaseFlightServer
+	exp *flightExporter
+}
+
+func (s *flightService) ListFlights(_ *flight.Criteria, stream flight.FlightService_ListFlightsServer) error {
+	for i := range tables {
+		d := &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{tables[i].name}}
+		if err := stream.Send(s.exp.flightInfo(&tables[i], d, 0)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *flightService) GetFlightInfo(_ context.Context, d *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	t, from, err := parseDescriptor(d)
+	if err != nil {
+		return nil, err
+	}
+	return s.exp.flightInfo(t, d, from), nil
+}
+
+func (s *flightService) GetSchema(_ context.Context, d *flight.FlightDescriptor) (*flight.SchemaResult, error) {
+	t, _, err := parseDescriptor(d)
+	if err != nil {
+		return nil, err
+	}
+	return &flight.SchemaResult{Schema: flight.SerializeSchema(t.schema, memory.DefaultAllocator)}, nil
+}
+
+func (s *flightService) DoGet(ticket *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	t, from, err := parseFlight(strings.Split(string(ticket.GetTicket()), "/"))
+	if err != nil {
+		return err
+	}
+	return s.exp.doGet(stream.Context(), t, from, stream)
+}
+
+// ListActions returns no actions.
+func (s *flightService) ListActions(*flight.Empty, flight.FlightService_ListActionsServer) error {
+	return nil
+}
+
+// parseDescriptor returns the table of a path flight descriptor and the first
+// round.
+func parseDescriptor(d *flight.FlightDescriptor) (*table, uint64, error) {
+	if d.GetType() != flight.DescriptorPATH {
+		return nil, 0, status.Error(codes.InvalidArgument, "only path flight descriptors are supported")
+	}
+	return parseFlight(d.GetPath())
+}
+
+// parseFlight returns the table of a flight path and the first round, the
+// path is the table name optionally followed by the first round.
+func parseFlight(path []string) (*table, uint64, error) {
+	if len(path) == 0 || len(path) > 2 {
+		return nil, 0, status.Error(codes.InvalidArgument, "the flight must be a table name optionally followed by the first round")
+	}
+	t := findTable(path[0])
+	if t == nil {
+		return nil, 0, status.Errorf(codes.NotFound, "unknown flight '%s'", path[0])
+	}
+	var from uint64
+	if len(path) == 2 {
+		var err error
+		if from, err = strconv.ParseUint(path[1], 10, 64); err != nil {
+			return nil, 0, status.Errorf(codes.InvalidArgument, "invalid first round '%s'", path[1])
+		}
+	}
+	return t, from, nil
+}
+
+// flightInfo describes the flight, its ticket is the path.
+func (exp *flightExporter) flightInfo(t *table, d *flight.FlightDescriptor, from uint64) *flight.FlightInfo {
+	var records int64
+	for _, r := range exp.retained(from) {
+		records += int64(t.rows(r))
+	}
+	return &flight.FlightInfo{
+		Schema:           flight.SerializeSchema(t.schema, memory.DefaultAllocator),
+		FlightDescriptor: d,
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: []byte(strings.Join(d.GetPath(), "/"))}}},
+		TotalRecords:     records,
+		TotalBytes:       -1,
+	}
+}
+
+// doGet streams the retained rounds in record batches of up to maxBatchRows
+// rows, a round is not split across batches.
+func (exp *flightExporter) doGet(ctx context.Context, t *table, from uint64, stream flight.FlightService_DoGetServer) error {
+	w := flight.NewRecordWriter(stream, ipc.WithSchema(t.schema))
+	defer w.Close()
+	b := array.NewRecordBuilder(memory.DefaultAllocator, t.schema)
+	defer b.Release()
+	rows := 0
+	flush := func() error {
+		rec := b.NewRecord()
+		defer rec.Release()
+		rows = 0
+		return w.Write(rec)
+	}
+	for _, r := range exp.retained(from) {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		if n := t.rows(r); n > 0 && rows > 0 && rows+n > maxBatchRows {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		t.append(b, r)
+		rows += t.rows(r)
+	}
+	if rows > 0 {
+		return flush()
+	}
+	return nil
+}
+
+func init() {
+	exporters.Register(PluginName, exporters.ExporterConstructorFunc(func() exporters.Exporter {
+		return &flightExporter{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Exporter, PluginName, Config{})
+}
@@ -0,0 +1,26 @@
+package flight
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+//PluginName: conduit_exporters_arrow_flight
+
+// Config specific to the Arrow Flight exporter
+type Config struct {
+	// <code>listen-addr</code> is the host:port the Flight service is served on. Default: "127.0.0.1:8815".
+	ListenAddr string `yaml:"listen-addr"`
+	// <code>token</code> enables authentication, clients send it in an "authorization: Bearer" header.
+	Token string `yaml:"token"`
+	// <code>retain-rounds</code> is the number of recent rounds kept in memory and served to clients. Default: 1000.
+	RetainRounds int `yaml:"retain-rounds"`
+	/* <code>tls</code> serves the Flight service over TLS, it is served in plaintext when it is not configured.<br/>
+	A self-signed certificate can be used with clients which disable the server verification.
+	*/
+	TLS TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig configures TLS.
+type TLSConfig struct {
+	// <code>cert-file</code> and <code>key-file</code> are the PEM certificate and key of the server.
+	CertFile string `yaml:"cert-file"`
+	KeyFile  string `yaml:"key-file"`
+}
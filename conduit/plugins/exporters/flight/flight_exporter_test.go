@@ -0,0 +1,376 @@
+package flight
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/v2/crypto"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/algorand/indexer/protocol"
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/tools/testutil"
+)
+
+var logger *logrus.Logger
+var flightCons = exporters.ExporterConstructorFunc(func() exporters.Exporter {
+	return &flightExporter{}
+})
+
+func init() {
+	logger, _ = test.NewNullLogger()
+}
+
+// writeCert writes a self-signed certificate for localhost.
+func writeCert(t *testing.T) TLSConfig {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	cfg := TLSConfig{CertFile: path.Join(dir, "cert.pem"), KeyFile: path.Join(dir, "key.pem")}
+	require.NoError(t, os.WriteFile(cfg.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(cfg.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return cfg
+}
+
+// initExporter starts the exporter at round 5 on a random port.
+func initExporter(t *testing.T, cfg Config) *flightExporter {
+	cfg.ListenAddr = "127.0.0.1:0"
+	cfgStr, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	rnd := sdk.Round(5)
+	exp := flightCons.New().(*flightExporter)
+	require.NoError(t, exp.Init(context.Background(), testutil.MockedInitProvider(&rnd), plugins.MakePluginConfig(string(cfgStr)), logger))
+	t.Cleanup(func() { exp.Close() })
+	return exp
+}
+
+// dial connects a Flight client to the exporter, over TLS if tlsConfig is
+// not nil.
+func dial(t *testing.T, exp *flightExporter, tlsConfig *tls.Config) flight.Client {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	client, err := flight.NewClientWithMiddleware(exp.listener.Addr().String(), nil, nil, grpc.WithTransportCredentials(creds))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// doGet returns the records of the ticket.
+func doGet(ctx context.Context, client flight.Client, ticket string) ([]arrow.Record, error) {
+	stream, err := client.DoGet(ctx, &flight.Ticket{Ticket: []byte(ticket)})
+	if err != nil {
+		return nil, err
+	}
+	reader, err := flight.NewRecordReader(stream)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+	var records []arrow.Record
+	for reader.Next() {
+		rec := reader.Record()
+		rec.Retain()
+		records = append(records, rec)
+	}
+	if err = reader.Err(); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return records, nil
+}
+
+// values returns the values of a column, nil for nulls.
+func values(col arrow.Array) []interface{} {
+	result := make([]interface{}, col.Len())
+	for i := range result {
+		if col.IsNull(i) {
+			continue
+		}
+		switch c := col.(type) {
+		case *array.Uint64:
+			result[i] = c.Value(i)
+		case *array.String:
+			result[i] = c.Value(i)
+		case *array.Timestamp:
+			result[i] = int64(c.Value(i))
+		}
+	}
+	return result
+}
+
+func testBlock(round uint64, payset ...sdk.SignedTxnInBlock) data.BlockData {
+	return data.BlockData{
+		BlockHeader: sdk.BlockHeader{
+			Round:       sdk.Round(round),
+			TimeStamp:   int64(1700000000 + round),
+			GenesisID:   "testnet-v1.0",
+			GenesisHash: sdk.Digest{9},
+			UpgradeState: sdk.UpgradeState{
+				CurrentProtocol: string(protocol.ConsensusCurrentVersion),
+			},
+		},
+		Payset: payset,
+	}
+}
+
+func pay(sender, receiver sdk.Address, amount uint64) sdk.SignedTxnInBlock {
+	var stxn sdk.SignedTxnInBlock
+	stxn.Txn.Type = sdk.PaymentTx
+	stxn.Txn.Sender = sender
+	stxn.Txn.Receiver = receiver
+	stxn.Txn.Amount = sdk.MicroAlgos(amount)
+	stxn.Txn.Fee = 1000
+	stxn.HasGenesisID = true
+	return stxn
+}
+
+func TestExporterMetadata(t *testing.T) {
+	exp := flightCons.New()
+	assert.Equal(t, metadata.Name, exp.Metadata().Name)
+	assert.Equal(t, metadata.Description, exp.Metadata().Description)
+	assert.Equal(t, metadata.Deprecated, exp.Metadata().Deprecated)
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		err  string
+	}{
+		{"defaults", Config{}, ""},
+		{"tls", Config{TLS: TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"}}, ""},
+		{"tls key", Config{TLS: TLSConfig{CertFile: "cert.pem"}}, "ValidateConfig(): tls cert-file and key-file must both be set"},
+		{"retain", Config{RetainRounds: -1}, "ValidateConfig(): retain-rounds must be positive"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfgStr, err := yaml.Marshal(tc.cfg)
+			require.NoError(t, err)
+			err = flightCons.New().(conduit.ConfigValidator).ValidateConfig(plugins.MakePluginConfig(string(cfgStr)))
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestReceiveRetainsRounds(t *testing.T) {
+	exp := initExporter(t, Config{RetainRounds: 2})
+	assert.EqualError(t, exp.Receive(testBlock(6)), "Receive(): wrong block: received round 6, expected round 5")
+	for round := uint64(5); round < 8; round++ {
+		require.NoError(t, exp.Receive(testBlock(round)))
+	}
+	require.Len(t, exp.retained(0), 2)
+	assert.Equal(t, uint64(6), exp.retained(0)[0].round)
+	assert.Equal(t, uint64(7), exp.retained(7)[0].round)
+	assert.Empty(t, exp.retained(8))
+}
+
+func TestRewindRound(t *testing.T) {
+	exp := initExporter(t, Config{})
+	for round := uint64(5); round < 9; round++ {
+		require.NoError(t, exp.Receive(testBlock(round)))
+	}
+	require.NoError(t, exp.RewindRound(7))
+	require.Len(t, exp.retained(0), 2)
+	assert.Equal(t, uint64(6), exp.retained(0)[1].round)
+
+	// The rewound round is received again.
+	require.NoError(t, exp.Receive(testBlock(7)))
+	assert.Equal(t, uint64(7), exp.retained(0)[2].round)
+}
+
+func TestTxID(t *testing.T) {
+	blk := testBlock(5, pay(sdk.Address{1}, sdk.Address{2}, 10))
+	txn := blk.Payset[0].Txn
+	txn.GenesisID = "testnet-v1.0"
+	txn.GenesisHash = sdk.Digest{9}
+	assert.Equal(t, crypto.TransactionIDString(txn), txID(&blk.BlockHeader, &blk.Payset[0]))
+}
+
+func TestDoGet(t *testing.T) {
+	exp := initExporter(t, Config{})
+	client := dial(t, exp, nil)
+	axfer := pay(sdk.Address{2}, sdk.Address{}, 0)
+	axfer.Txn.Type = sdk.AssetTransferTx
+	axfer.Txn.AssetReceiver = sdk.Address{1}
+	axfer.Txn.AssetAmount = 3
+	axfer.Txn.XferAsset = 77
+	require.NoError(t, exp.Receive(testBlock(5, pay(sdk.Address{1}, sdk.Address{2}, 10))))
+	require.NoError(t, exp.Receive(testBlock(6)))
+	require.NoError(t, exp.Receive(testBlock(7, axfer)))
+
+	records, err := doGet(context.Background(), client, "transactions")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	rec := records[0]
+	assert.True(t, findTable("transactions").schema.Equal(rec.Schema()))
+	assert.Equal(t, []interface{}{uint64(5), uint64(7)}, values(rec.Column(0)))
+	assert.Equal(t, []interface{}{"pay", "axfer"}, values(rec.Column(3)))
+	assert.Equal(t, []interface{}{sdk.Address{2}.String(), sdk.Address{1}.String()}, values(rec.Column(5)))
+	assert.Equal(t, []interface{}{uint64(10), uint64(3)}, values(rec.Column(6)))
+	assert.Equal(t, []interface{}{nil, uint64(77)}, values(rec.Column(7)))
+
+	// The first round is in the ticket.
+	records, err = doGet(context.Background(), client, "blocks/6")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	rec = records[0]
+	assert.Equal(t, []interface{}{uint64(6), uint64(7)}, values(rec.Column(0)))
+	assert.Equal(t, []interface{}{int64(1700000006), int64(1700000007)}, values(rec.Column(1)))
+	assert.Equal(t, []interface{}{uint64(0), uint64(1)}, values(rec.Column(3)))
+}
+
+func TestDoGetBatches(t *testing.T) {
+	exp := initExporter(t, Config{})
+	for round := uint64(5); round < maxBatchRows+6; round++ {
+		exp.rounds = append(exp.rounds, &roundData{round: round})
+	}
+	records, err := doGet(context.Background(), dial(t, exp, nil), "blocks")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, int64(maxBatchRows), records[0].NumRows())
+	assert.Equal(t, int64(1), records[1].NumRows())
+}
+
+func TestFlightInfo(t *testing.T) {
+	exp := initExporter(t, Config{})
+	client := dial(t, exp, nil)
+	ctx := context.Background()
+	require.NoError(t, exp.Receive(testBlock(5, pay(sdk.Address{1}, sdk.Address{2}, 10), pay(sdk.Address{1}, sdk.Address{3}, 10))))
+
+	info, err := client.GetFlightInfo(ctx, &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{"transactions"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), info.TotalRecords)
+	require.Len(t, info.Endpoint, 1)
+	assert.Equal(t, "transactions", string(info.Endpoint[0].Ticket.Ticket))
+
+	flights, err := client.ListFlights(ctx, &flight.Criteria{})
+	require.NoError(t, err)
+	var names []string
+	for {
+		info, err := flights.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, info.FlightDescriptor.Path[0])
+	}
+	assert.Equal(t, []string{"blocks", "transactions"}, names)
+
+	result, err := client.GetSchema(ctx, &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{"blocks"}})
+	require.NoError(t, err)
+	schema, err := flight.DeserializeSchema(result.Schema, memory.DefaultAllocator)
+	require.NoError(t, err)
+	assert.True(t, findTable("blocks").schema.Equal(schema))
+}
+
+func TestCallErrors(t *testing.T) {
+	exp := initExporter(t, Config{Token: "secret"})
+	client := dial(t, exp, nil)
+	auth := grpcmetadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"no token", func() error {
+			_, err := doGet(context.Background(), client, "blocks")
+			return err
+		}, codes.Unauthenticated},
+		{"wrong token", func() error {
+			_, err := doGet(grpcmetadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong"), client, "blocks")
+			return err
+		}, codes.Unauthenticated},
+		{"unknown flight", func() error {
+			_, err := doGet(auth, client, "accounts")
+			return err
+		}, codes.NotFound},
+		{"invalid round", func() error {
+			_, err := doGet(auth, client, "blocks/x")
+			return err
+		}, codes.InvalidArgument},
+		{"command descriptor", func() error {
+			_, err := client.GetFlightInfo(auth, &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("SELECT")})
+			return err
+		}, codes.InvalidArgument},
+		{"unimplemented", func() error {
+			stream, err := client.DoPut(auth)
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}, codes.Unimplemented},
+		{"ok", func() error {
+			_, err := doGet(auth, client, "blocks")
+			return err
+		}, codes.OK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.code, status.Code(tc.call()))
+		})
+	}
+}
+
+func TestTLS(t *testing.T) {
+	exp := initExporter(t, Config{TLS: writeCert(t)})
+	require.NoError(t, exp.Receive(testBlock(5)))
+
+	records, err := doGet(context.Background(), dial(t, exp, &tls.Config{InsecureSkipVerify: true}), "blocks")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, int64(1), records[0].NumRows())
+
+	// Plaintext clients are rejected.
+	_, err = doGet(context.Background(), dial(t, exp, nil), "blocks")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestConfigHidesToken(t *testing.T) {
+	exp := initExporter(t, Config{Token: "secret"})
+	assert.NotContains(t, exp.Config(), "secret")
+}
@@ -0,0 +1,170 @@
+package flight
+
+import (
+	"github.com/algorand/go-algorand-sdk/v2/crypto"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/algorand/indexer/protocol"
+	"github.com/algorand/indexer/protocol/config"
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+
+	"github.com/algorand/conduit/conduit/data"
+)
+
+// roundData has the rows of a round, it is not modified once retained.
+type roundData struct {
+	round      uint64
+	timestamp  int64
+	txnCounter uint64
+	txns       []txnRow
+}
+
+type txnRow struct {
+	txid   string
+	typ    string
+	sender string
+	// receiver is empty for transactions without a receiver.
+	receiver  string
+	hasAmount bool
+	amount    uint64
+	// assetID is 0 for transactions without an asset.
+	assetID uint64
+	fee     uint64
+}
+
+// table is a flight, the rows of each retained round are appended to a
+// record of its schema.
+type table struct {
+	name   string
+	schema *arrow.Schema
+	rows   func(r *roundData) int
+	append func(b *array.RecordBuilder, r *roundData)
+}
+
+var tables = []table{
+	{
+		name: "blocks",
+		schema: arrow.NewSchema([]arrow.Field{
+			{Name: "round", Type: arrow.PrimitiveTypes.Uint64},
+			{Name: "timestamp", Type: arrow.FixedWidthTypes.Timestamp_s},
+			{Name: "txn_counter", Type: arrow.PrimitiveTypes.Uint64},
+			{Name: "txns", Type: arrow.PrimitiveTypes.Uint64},
+		}, nil),
+		rows: func(*roundData) int { return 1 },
+		append: func(b *array.RecordBuilder, r *roundData) {
+			b.Field(0).(*array.Uint64Builder).Append(r.round)
+			b.Field(1).(*array.TimestampBuilder).Append(arrow.Timestamp(r.timestamp))
+			b.Field(2).(*array.Uint64Builder).Append(r.txnCounter)
+			b.Field(3).(*array.Uint64Builder).Append(uint64(len(r.txns)))
+		},
+	},
+	{
+		name: "transactions",
+		schema: arrow.NewSchema([]arrow.Field{
+			{Name: "round", Type: arrow.PrimitiveTypes.Uint64},
+			{Name: "intra", Type: arrow.PrimitiveTypes.Uint64},
+			{Name: "txid", Type: arrow.BinaryTypes.String},
+			{Name: "type", Type: arrow.BinaryTypes.String},
+			{Name: "sender", Type: arrow.BinaryTypes.String},
+			{Name: "receiver", Type: arrow.BinaryTypes.String, Nullable: true},
+			{Name: "amount", Type: arrow.PrimitiveTypes.Uint64, Nullable: true},
+			{Name: "asset_id", Type: arrow.PrimitiveTypes.Uint64, Nullable: true},
+			{Name: "fee", Type: arrow.PrimitiveTypes.Uint64},
+		}, nil),
+		rows: func(r *roundData) int { return len(r.txns) },
+		append: func(b *array.RecordBuilder, r *roundData) {
+			for intra, txn := range r.txns {
+				b.Field(0).(*array.Uint64Builder).Append(r.round)
+				b.Field(1).(*array.Uint64Builder).Append(uint64(intra))
+				b.Field(2).(*array.StringBuilder).Append(txn.txid)
+				b.Field(3).(*array.StringBuilder).Append(txn.typ)
+				b.Field(4).(*array.StringBuilder).Append(txn.sender)
+				receiver := b.Field(5).(*array.StringBuilder)
+				if txn.receiver != "" {
+					receiver.Append(txn.receiver)
+				} else {
+					receiver.AppendNull()
+				}
+				amount := b.Field(6).(*array.Uint64Builder)
+				if txn.hasAmount {
+					amount.Append(txn.amount)
+				} else {
+					amount.AppendNull()
+				}
+				assetID := b.Field(7).(*array.Uint64Builder)
+				if txn.assetID != 0 {
+					assetID.Append(txn.assetID)
+				} else {
+					assetID.AppendNull()
+				}
+				b.Field(8).(*array.Uint64Builder).Append(txn.fee)
+			}
+		},
+	},
+}
+
+func findTable(name string) *table {
+	for i := range tables {
+		if tables[i].name == name {
+			return &tables[i]
+		}
+	}
+	return nil
+}
+
+// makeRoundData extracts the rows of the top level transactions.
+func makeRoundData(blk *data.BlockData) *roundData {
+	header := &blk.BlockHeader
+	r := &roundData{
+		round:      uint64(header.Round),
+		timestamp:  header.TimeStamp,
+		txnCounter: header.TxnCounter,
+		txns:       make([]txnRow, len(blk.Payset)),
+	}
+	for idx := range blk.Payset {
+		stxn := &blk.Payset[idx]
+		txn := &stxn.Txn
+		row := txnRow{
+			txid:   txID(header, stxn),
+			typ:    string(txn.Type),
+			sender: txn.Sender.String(),
+			fee:    uint64(txn.Fee),
+		}
+		switch txn.Type {
+		case sdk.PaymentTx:
+			row.receiver = txn.Receiver.String()
+			row.hasAmount = true
+			row.amount = uint64(txn.Amount)
+		case sdk.AssetTransferTx:
+			row.receiver = txn.AssetReceiver.String()
+			row.hasAmount = true
+			row.amount = txn.AssetAmount
+			row.assetID = uint64(txn.XferAsset)
+		case sdk.AssetConfigTx:
+			row.assetID = uint64(txn.ConfigAsset)
+			if row.assetID == 0 {
+				// The ID of a created asset is in the apply data.
+				row.assetID = stxn.ConfigAsset
+			}
+		case sdk.AssetFreezeTx:
+			row.assetID = uint64(txn.FreezeAsset)
+		}
+		r.txns[idx] = row
+	}
+	return r
+}
+
+// txID restores the genesis fields which are removed from transactions in
+// blocks, and returns the transaction ID.
+func txID(header *sdk.BlockHeader, stxn *sdk.SignedTxnInBlock) string {
+	txn := stxn.Txn
+	if stxn.HasGenesisID {
+		txn.GenesisID = header.GenesisID
+	}
+	// Unknown protocols are newer ones, which require the genesis hash.
+	params, ok := config.Consensus[protocol.ConsensusVersion(header.CurrentProtocol)]
+	if stxn.HasGenesisHash || !ok || params.RequireGenesisHash {
+		txn.GenesisHash = header.GenesisHash
+	}
+	return crypto.TransactionIDString(txn)
+}
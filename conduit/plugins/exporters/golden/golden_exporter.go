@@ -0,0 +1,155 @@
+package golden
+
+import (
+	"bytes"
+	"context"
+	_ "embed" // used to embed config
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/exporters/filewriter"
+)
+
+const (
+	// PluginName to use when configuring.
+	PluginName = "golden"
+	// FilePattern is used to name the golden files.
+	FilePattern = "%[1]d_golden.json"
+	// ActualSuffix is appended to the name of a golden file for the output of
+	// a round which does not match it.
+	ActualSuffix = ".actual"
+)
+
+// goldenExporter writes the canonical JSON of each round, or compares it with
+// the golden file written by a previous run.
+type goldenExporter struct {
+	cfg    Config
+	logger *logrus.Logger
+	// matched and updated count the rounds for the summary logged on close.
+	matched int
+	updated int
+}
+
+//go:embed sample.yaml
+var sampleConfig string
+
+var metadata = conduit.Metadata{
+	Name:         PluginName,
+	Description:  "Exporter for writing the canonical output of each round to golden files, or comparing it with them.",
+	Deprecated:   false,
+	SampleConfig: sampleConfig,
+}
+
+func (exp *goldenExporter) Metadata() conduit.Metadata {
+	return metadata
+}
+
+func (exp *goldenExporter) Init(_ context.Context, _ data.InitProvider, cfg plugins.PluginConfig, logger *logrus.Logger) error {
+	exp.logger = logger
+	if err := cfg.UnmarshalConfig(&exp.cfg); err != nil {
+		return fmt.Errorf("connect failure in unmarshalConfig: %w", err)
+	}
+	if exp.cfg.GoldenDir == "" {
+		exp.cfg.GoldenDir = cfg.DataDir
+	}
+	if !exp.cfg.Update {
+		if _, err := os.Stat(exp.cfg.GoldenDir); err != nil {
+			return fmt.Errorf("Init(): golden directory not found, run with update to create it: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(exp.cfg.GoldenDir, 0755); err != nil {
+		return fmt.Errorf("Init() error: %w", err)
+	}
+	return nil
+}
+
+func (exp *goldenExporter) Config() string {
+	ret, _ := yaml.Marshal(exp.cfg)
+	return string(ret)
+}
+
+func (exp *goldenExporter) Close() error {
+	if exp.logger != nil {
+		exp.logger.Infof("golden rounds: %d matched, %d updated", exp.matched, exp.updated)
+	}
+	return nil
+}
+
+// Canonical returns the canonical JSON of a block, as written to the golden
+// files. The certificate is not deterministic, so it is left out.
+func Canonical(exportData data.BlockData) ([]byte, error) {
+	exportData.Certificate = nil
+	return filewriter.EncodeJSONToBytes("", exportData, true)
+}
+
+func (exp *goldenExporter) Receive(exportData data.BlockData) error {
+	if exp.logger == nil {
+		return fmt.Errorf("exporter not initialized")
+	}
+	actual, err := Canonical(exportData)
+	if err != nil {
+		return fmt.Errorf("Receive(): %w", err)
+	}
+	goldenFile := path.Join(exp.cfg.GoldenDir, fmt.Sprintf(FilePattern, exportData.Round()))
+
+	if exp.cfg.Update {
+		if err = os.WriteFile(goldenFile, actual, 0644); err != nil {
+			return fmt.Errorf("Receive(): failed to write golden file %s: %w", goldenFile, err)
+		}
+		exp.updated++
+		exp.logger.Infof("Wrote golden file of round %d to %s", exportData.Round(), goldenFile)
+		return nil
+	}
+
+	expected, err := os.ReadFile(goldenFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("Receive(): golden file %s of round %d not found, run with update to create it", goldenFile, exportData.Round())
+	}
+	if err != nil {
+		return fmt.Errorf("Receive(): failed to read golden file %s: %w", goldenFile, err)
+	}
+	if bytes.Equal(expected, actual) {
+		exp.matched++
+		// A previous mismatch was fixed.
+		_ = os.Remove(goldenFile + ActualSuffix)
+		return nil
+	}
+	if err = os.WriteFile(goldenFile+ActualSuffix, actual, 0644); err != nil {
+		return fmt.Errorf("Receive(): failed to write output of round %d: %w", exportData.Round(), err)
+	}
+	return fmt.Errorf("Receive(): round %d does not match golden file %s, %s, the output was written to %s", exportData.Round(), goldenFile, firstDifference(expected, actual), goldenFile+ActualSuffix)
+}
+
+// firstDifference describes the first line which differs between the golden
+// file and the output.
+func firstDifference(expected, actual []byte) string {
+	expectedLines := bytes.Split(expected, []byte("\n"))
+	actualLines := bytes.Split(actual, []byte("\n"))
+	for idx := 0; ; idx++ {
+		switch {
+		case idx >= len(expectedLines):
+			return fmt.Sprintf("line %d was added: %q", idx+1, bytes.TrimSpace(actualLines[idx]))
+		case idx >= len(actualLines):
+			return fmt.Sprintf("line %d was removed: %q", idx+1, bytes.TrimSpace(expectedLines[idx]))
+		case !bytes.Equal(expectedLines[idx], actualLines[idx]):
+			return fmt.Sprintf("line %d is %q instead of %q", idx+1, bytes.TrimSpace(actualLines[idx]), bytes.TrimSpace(expectedLines[idx]))
+		}
+	}
+}
+
+func init() {
+	exporters.Register(PluginName, exporters.ExporterConstructorFunc(func() exporters.Exporter {
+		return &goldenExporter{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Exporter, PluginName, Config{})
+}
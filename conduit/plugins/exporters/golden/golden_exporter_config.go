@@ -0,0 +1,17 @@
+package golden
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+//Name: conduit_exporters_golden
+
+// Config specific to the golden exporter
+type Config struct {
+	/* <code>golden-dir</code> is an optional path to the directory of the golden files.<br/>
+	If no directory is provided the default plugin data directory is used.
+	*/
+	GoldenDir string `yaml:"golden-dir"`
+	/* <code>update</code> writes the output of each round to its golden file, instead of comparing it.<br/>
+	The directory is created if it doesn't exist.
+	*/
+	Update bool `yaml:"update"`
+}
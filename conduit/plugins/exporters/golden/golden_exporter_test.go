@@ -0,0 +1,109 @@
+package golden
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	fixturesimporter "github.com/algorand/conduit/conduit/plugins/importers/fixtures"
+	"github.com/algorand/conduit/conduit/plugins/tools/testutil"
+)
+
+func initExporter(t *testing.T, dir string, update bool) exporters.Exporter {
+	logger, _ := test.NewNullLogger()
+	exp := &goldenExporter{}
+	cfg := plugins.MakePluginConfig(fmt.Sprintf("golden-dir: %s\nupdate: %t\n", dir, update))
+	require.NoError(t, exp.Init(context.Background(), testutil.MockedInitProvider(nil), cfg, logger))
+	t.Cleanup(func() { exp.Close() })
+	return exp
+}
+
+func fixtureBlocks(t *testing.T) []data.BlockData {
+	var blocks []data.BlockData
+	for rnd, name := range fixturesimporter.Names() {
+		blk, err := fixturesimporter.Block("mainnet", uint64(rnd), name)
+		require.NoError(t, err)
+		blocks = append(blocks, blk)
+	}
+	return blocks
+}
+
+func TestExporterMetadata(t *testing.T) {
+	meta := (&goldenExporter{}).Metadata()
+	assert.Equal(t, metadata.Name, meta.Name)
+	assert.Equal(t, metadata.Description, meta.Description)
+}
+
+func TestInitMissingDir(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	cfg := plugins.MakePluginConfig(fmt.Sprintf("golden-dir: %s\n", path.Join(t.TempDir(), "missing")))
+	err := (&goldenExporter{}).Init(context.Background(), testutil.MockedInitProvider(nil), cfg, logger)
+	assert.ErrorContains(t, err, "golden directory not found, run with update to create it")
+}
+
+// TestUpdateAndCompare tests that the golden files written by update match
+// the same blocks on the next run.
+func TestUpdateAndCompare(t *testing.T) {
+	dir := path.Join(t.TempDir(), "golden")
+	blocks := fixtureBlocks(t)
+
+	exp := initExporter(t, dir, true)
+	for _, blk := range blocks {
+		require.NoError(t, exp.Receive(blk))
+	}
+	for rnd := range blocks {
+		assert.FileExists(t, path.Join(dir, fmt.Sprintf(FilePattern, rnd)))
+	}
+
+	exp = initExporter(t, dir, false)
+	for _, blk := range blocks {
+		// The certificate is not part of the canonical output.
+		blk.Certificate = &map[string]interface{}{"step": 2}
+		require.NoError(t, exp.Receive(blk))
+	}
+	assert.Equal(t, len(blocks), exp.(*goldenExporter).matched)
+}
+
+func TestCompareMismatch(t *testing.T) {
+	dir := t.TempDir()
+	blk := data.BlockData{BlockHeader: sdk.BlockHeader{Round: 5}, Payset: []sdk.SignedTxnInBlock{{}}}
+	blk.Payset[0].Txn.Type = sdk.PaymentTx
+	blk.Payset[0].Txn.Amount = 10
+	require.NoError(t, initExporter(t, dir, true).Receive(blk))
+
+	exp := initExporter(t, dir, false)
+	goldenFile := path.Join(dir, "5_golden.json")
+	blk.Payset[0].Txn.Amount = 11
+	err := exp.Receive(blk)
+	assert.EqualError(t, err, fmt.Sprintf(`Receive(): round 5 does not match golden file %s, line 8 is "\"amt\": 11," instead of "\"amt\": 10,", the output was written to %s.actual`, goldenFile, goldenFile))
+	actual, err := os.ReadFile(goldenFile + ActualSuffix)
+	require.NoError(t, err)
+	expected, err := Canonical(blk)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	// The output is removed once the round matches again.
+	blk.Payset[0].Txn.Amount = 10
+	require.NoError(t, exp.Receive(blk))
+	assert.NoFileExists(t, goldenFile+ActualSuffix)
+
+	err = exp.Receive(data.BlockData{BlockHeader: sdk.BlockHeader{Round: 6}})
+	assert.ErrorContains(t, err, "golden file "+path.Join(dir, "6_golden.json")+" of round 6 not found, run with update to create it")
+}
+
+func TestFirstDifference(t *testing.T) {
+	assert.Equal(t, `line 2 is "b" instead of "c"`, firstDifference([]byte("a\n c"), []byte("a\n b")))
+	assert.Equal(t, `line 2 was added: "b"`, firstDifference([]byte("a"), []byte("a\nb")))
+	assert.Equal(t, `line 2 was removed: "b"`, firstDifference([]byte("a\nb"), []byte("a")))
+}
@@ -0,0 +1,143 @@
+package ipfsarchive
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+)
+
+// file is added to IPFS under its name.
+type file struct {
+	name     string
+	contents []byte
+}
+
+// ipfsClient adds files with the RPC API of an IPFS node and pins them with
+// an IPFS Pinning Service API.
+type ipfsClient struct {
+	client      *http.Client
+	apiAddr     string
+	pinEndpoint string
+	pinToken    string
+	publishKey  string
+	ctx         context.Context
+}
+
+// addResult is a line of the response of /api/v0/add.
+type addResult struct {
+	Name string `json:"Name"`
+	Hash string `json:"Hash"`
+}
+
+// add adds the files and returns the CID of the directory wrapping them, or
+// of the file itself when wrap is false.
+func (c *ipfsClient) add(files []file, wrap bool) (string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, f := range files {
+		part, err := mw.CreateFormFile("file", f.name)
+		if err != nil {
+			return "", fmt.Errorf("add(): %w", err)
+		}
+		if _, err = part.Write(f.contents); err != nil {
+			return "", fmt.Errorf("add(): %w", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return "", fmt.Errorf("add(): %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("cid-version", "1")
+	query.Set("pin", "true")
+	if wrap {
+		query.Set("wrap-with-directory", "true")
+	}
+	resp, err := c.post(c.apiAddr+"/api/v0/add?"+query.Encode(), mw.FormDataContentType(), &body, nil)
+	if err != nil {
+		return "", fmt.Errorf("add(): %w", err)
+	}
+	defer resp.Body.Close()
+
+	// One result is returned per file, the wrapping directory is last.
+	var last addResult
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if err = json.Unmarshal(scanner.Bytes(), &last); err != nil {
+			return "", fmt.Errorf("add(): unable to decode response: %w", err)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", fmt.Errorf("add(): %w", err)
+	}
+	if last.Hash == "" || (wrap && last.Name != "") {
+		return "", fmt.Errorf("add(): the IPFS node did not return the CID")
+	}
+	return last.Hash, nil
+}
+
+// publish points the IPNS name of the key at the CID.
+func (c *ipfsClient) publish(cid string) error {
+	query := url.Values{}
+	query.Set("arg", "/ipfs/"+cid)
+	query.Set("key", c.publishKey)
+	query.Set("allow-offline", "true")
+	resp, err := c.post(c.apiAddr+"/api/v0/name/publish?"+query.Encode(), "", nil, nil)
+	if err != nil {
+		return fmt.Errorf("publish(): %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// pin requests the pinning service to pin the CID, it does not wait for the
+// pin to complete.
+func (c *ipfsClient) pin(cid, name string) error {
+	if c.pinEndpoint == "" {
+		return nil
+	}
+	b, err := json.Marshal(map[string]string{"cid": cid, "name": name})
+	if err != nil {
+		return fmt.Errorf("pin(): %w", err)
+	}
+	headers := map[string]string{"Authorization": "Bearer " + c.pinToken}
+	resp, err := c.post(c.pinEndpoint+"/pins", "application/json", bytes.NewReader(b), headers)
+	if err != nil {
+		return fmt.Errorf("pin(): %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// post sends a request and returns the response if it succeeded.
+func (c *ipfsClient) post(url, contentType string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned %s: %s", req.URL.Path, resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
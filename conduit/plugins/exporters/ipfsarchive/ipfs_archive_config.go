@@ -0,0 +1,37 @@
+package ipfsarchive
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+import "time"
+
+//Name: conduit_exporters_ipfs_archive
+
+// Config specific to the IPFS archive exporter
+type Config struct {
+	/* <code>api-addr</code> is the address of the RPC API of the IPFS node which adds the files, such as Kubo.<br/>
+	The default is "http://127.0.0.1:5001".
+	*/
+	APIAddr string `yaml:"api-addr"`
+	// <code>rounds-per-chunk</code> is the number of rounds in each published directory, the default is 1000.
+	RoundsPerChunk uint64 `yaml:"rounds-per-chunk"`
+	/* <code>filename-pattern</code> is the format used to name the block files. It uses go string formatting and should accept one number for the round.
+	Files ending in .gz are compressed. The default pattern is
+
+	"%[1]d_block.json.gz"
+	*/
+	FilenamePattern string `yaml:"filename-pattern"`
+	// <code>ipns-key</code> is the name of the key of the IPFS node which the manifest is published with, the manifest is only added to IPFS when it is empty.
+	IPNSKey string `yaml:"ipns-key"`
+	// <code>timeout</code> is how long a request to the IPFS node or the pinning service may take, the default is 5m.
+	Timeout time.Duration `yaml:"timeout"`
+	// <code>pinning</code> pins the published files with a remote pinning service.
+	Pinning PinningConfig `yaml:"pinning"`
+}
+
+// PinningConfig configures a service which implements the IPFS Pinning Service API.
+type PinningConfig struct {
+	// <code>endpoint</code> is the URL of the pinning service API, pinning is disabled when it is empty.
+	Endpoint string `yaml:"endpoint"`
+	// <code>token</code> is the access token of the pinning service.
+	Token string `yaml:"token"`
+}
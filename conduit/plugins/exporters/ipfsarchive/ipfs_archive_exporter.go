@@ -0,0 +1,333 @@
+package ipfsarchive
+
+import (
+	"context"
+	_ "embed" // used to embed config
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/exporters/filewriter"
+)
+
+const (
+	// PluginName to use when configuring.
+	PluginName = "ipfs_archive"
+	// FilePattern is the default name of the block files.
+	FilePattern = "%[1]d_block.json.gz"
+
+	defaultAPIAddr        = "http://127.0.0.1:5001"
+	defaultRoundsPerChunk = 1000
+	defaultTimeout        = 5 * time.Minute
+	// stateFile keeps the manifest and the first round of the staged chunk.
+	stateFile = "state.json"
+	// stagingDir holds the block files of the chunk which is not complete.
+	stagingDir  = "staging"
+	genesisFile = "genesis.json"
+)
+
+// RoundRange is a published directory of block files for the rounds
+// [First, Last].
+type RoundRange struct {
+	First uint64 `json:"first"`
+	Last  uint64 `json:"last"`
+	CID   string `json:"cid"`
+}
+
+// Manifest lists the files of an archive, it is read by the ipfs importer.
+type Manifest struct {
+	Genesis string       `json:"genesis"`
+	Ranges  []RoundRange `json:"ranges"`
+}
+
+// archiveState is saved in the plugin data directory after each publication.
+type archiveState struct {
+	Manifest Manifest `json:"manifest"`
+	// ManifestCID is the CID of the last published manifest.
+	ManifestCID string `json:"manifest-cid"`
+	// ChunkFirst is the first round of the staged chunk, nil if none is staged.
+	ChunkFirst *uint64 `json:"chunk-first,omitempty"`
+}
+
+// archiveExporter stages the block files of each chunk in the plugin data
+// directory and adds them to IPFS once the chunk is complete.
+type archiveExporter struct {
+	cfg     Config
+	logger  *logrus.Logger
+	dataDir string
+	client  *ipfsClient
+	cancel  context.CancelFunc
+	state   archiveState
+	// meter counts the traffic with the IPFS API and the pinning service, it
+	// is nil unless the pipeline accounts for the bandwidth.
+	meter conduit.TrafficMeter
+}
+
+//go:embed sample.yaml
+var sampleConfig string
+
+var metadata = conduit.Metadata{
+	Name:         PluginName,
+	Description:  "Exporter for publishing chunked block archives and their manifest to IPFS.",
+	Deprecated:   false,
+	SampleConfig: sampleConfig,
+}
+
+func (exp *archiveExporter) Metadata() conduit.Metadata {
+	return metadata
+}
+
+func (exp *archiveExporter) Init(ctx context.Context, initProvider data.InitProvider, cfg plugins.PluginConfig, logger *logrus.Logger) error {
+	exp.logger = logger
+	if err := cfg.UnmarshalConfig(&exp.cfg); err != nil {
+		return fmt.Errorf("connect failure in unmarshalConfig: %w", err)
+	}
+	if cfg.DataDir == "" {
+		return fmt.Errorf("Init(): a plugin data directory is required to stage the chunks")
+	}
+	exp.dataDir = cfg.DataDir
+	if exp.cfg.APIAddr == "" {
+		exp.cfg.APIAddr = defaultAPIAddr
+	}
+	if exp.cfg.RoundsPerChunk == 0 {
+		exp.cfg.RoundsPerChunk = defaultRoundsPerChunk
+	}
+	if exp.cfg.FilenamePattern == "" {
+		exp.cfg.FilenamePattern = FilePattern
+	}
+	if exp.cfg.Timeout == 0 {
+		exp.cfg.Timeout = defaultTimeout
+	}
+	if err := os.MkdirAll(path.Join(exp.dataDir, stagingDir), 0755); err != nil {
+		return fmt.Errorf("Init() error: %w", err)
+	}
+
+	var clientCtx context.Context
+	clientCtx, exp.cancel = context.WithCancel(ctx)
+	exp.client = &ipfsClient{
+		client:      &http.Client{Timeout: exp.cfg.Timeout, Transport: conduit.MeteredTransport(exp.meter)},
+		apiAddr:     strings.TrimSuffix(exp.cfg.APIAddr, "/"),
+		pinEndpoint: strings.TrimSuffix(exp.cfg.Pinning.Endpoint, "/"),
+		pinToken:    exp.cfg.Pinning.Token,
+		publishKey:  exp.cfg.IPNSKey,
+		ctx:         clientCtx,
+	}
+
+	if err := exp.readState(); err != nil {
+		return fmt.Errorf("Init(): %w", err)
+	}
+	if exp.state.Manifest.Genesis == "" {
+		genesis, err := filewriter.EncodeJSONToBytes(genesisFile, initProvider.GetGenesis(), true)
+		if err != nil {
+			return fmt.Errorf("Init(): failed to encode genesis: %w", err)
+		}
+		cid, err := exp.client.add([]file{{name: genesisFile, contents: genesis}}, false)
+		if err != nil {
+			return fmt.Errorf("Init(): failed to add genesis: %w", err)
+		}
+		if err = exp.client.pin(cid, genesisFile); err != nil {
+			return fmt.Errorf("Init(): failed to pin genesis: %w", err)
+		}
+		exp.state.Manifest.Genesis = cid
+		if err = exp.writeState(); err != nil {
+			return fmt.Errorf("Init(): %w", err)
+		}
+	}
+	return nil
+}
+
+func (exp *archiveExporter) readState() error {
+	b, err := os.ReadFile(path.Join(exp.dataDir, stateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("readState(): %w", err)
+	}
+	if err = json.Unmarshal(b, &exp.state); err != nil {
+		return fmt.Errorf("readState(): unable to decode %s: %w", stateFile, err)
+	}
+	return nil
+}
+
+func (exp *archiveExporter) writeState() error {
+	b, err := json.MarshalIndent(exp.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("writeState(): %w", err)
+	}
+	tmp := path.Join(exp.dataDir, stateFile+".tmp")
+	if err = os.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("writeState(): %w", err)
+	}
+	if err = os.Rename(tmp, path.Join(exp.dataDir, stateFile)); err != nil {
+		return fmt.Errorf("writeState(): %w", err)
+	}
+	return nil
+}
+
+func (exp *archiveExporter) Config() string {
+	ret, _ := yaml.Marshal(exp.cfg)
+	return string(ret)
+}
+
+// Close publishes the staged rounds of an incomplete chunk, so that a
+// pipeline which stops at rounds.end leaves a complete archive. The chunk is
+// published again once it is complete.
+func (exp *archiveExporter) Close() error {
+	if exp.client == nil {
+		return nil
+	}
+	defer exp.cancel()
+	if exp.state.ChunkFirst == nil {
+		return nil
+	}
+	if err := exp.publishChunk(false); err != nil {
+		return fmt.Errorf("Close(): %w", err)
+	}
+	return nil
+}
+
+// SetTrafficMeter counts the bytes exchanged with the IPFS API and the
+// pinning service.
+func (exp *archiveExporter) SetTrafficMeter(meter conduit.TrafficMeter) {
+	exp.meter = meter
+}
+
+func (exp *archiveExporter) Receive(exportData data.BlockData) error {
+	if exp.client == nil {
+		return fmt.Errorf("exporter not initialized")
+	}
+	round := exportData.Round()
+	// A chunk whose last round was skipped is published once a later chunk starts.
+	if exp.state.ChunkFirst != nil && round > exp.chunkLast(*exp.state.ChunkFirst) {
+		if err := exp.publishChunk(true); err != nil {
+			return fmt.Errorf("Receive(): %w", err)
+		}
+	}
+
+	name := fmt.Sprintf(exp.cfg.FilenamePattern, round)
+	b, err := filewriter.EncodeJSONToBytes(name, exportData, false)
+	if err != nil {
+		return fmt.Errorf("Receive(): failed to encode round %d: %w", round, err)
+	}
+	if err = os.WriteFile(path.Join(exp.dataDir, stagingDir, name), b, 0644); err != nil {
+		return fmt.Errorf("Receive(): failed to stage round %d: %w", round, err)
+	}
+	if exp.state.ChunkFirst == nil {
+		exp.state.ChunkFirst = &round
+		if err = exp.writeState(); err != nil {
+			return fmt.Errorf("Receive(): %w", err)
+		}
+	}
+
+	if round == exp.chunkLast(*exp.state.ChunkFirst) {
+		if err = exp.publishChunk(true); err != nil {
+			return fmt.Errorf("Receive(): %w", err)
+		}
+	}
+	return nil
+}
+
+// chunkLast returns the last round of the chunk containing rnd, chunks are
+// aligned to rounds-per-chunk.
+func (exp *archiveExporter) chunkLast(rnd uint64) uint64 {
+	return rnd - rnd%exp.cfg.RoundsPerChunk + exp.cfg.RoundsPerChunk - 1
+}
+
+// publishChunk adds the staged block files to IPFS and publishes the updated
+// manifest. The staging directory is emptied once the chunk is complete.
+func (exp *archiveExporter) publishChunk(complete bool) error {
+	dir := path.Join(exp.dataDir, stagingDir)
+	first := *exp.state.ChunkFirst
+	last := first
+	var files []file
+	for rnd := first; rnd <= exp.chunkLast(first); rnd++ {
+		name := fmt.Sprintf(exp.cfg.FilenamePattern, rnd)
+		b, err := os.ReadFile(path.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("publishChunk(): %w", err)
+		}
+		files = append(files, file{name: name, contents: b})
+		last = rnd
+	}
+	if len(files) == 0 {
+		exp.state.ChunkFirst = nil
+		return exp.writeState()
+	}
+
+	name := fmt.Sprintf("rounds %d-%d", first, last)
+	cid, err := exp.client.add(files, true)
+	if err != nil {
+		return fmt.Errorf("publishChunk(): failed to add %s: %w", name, err)
+	}
+	if err = exp.client.pin(cid, name); err != nil {
+		return fmt.Errorf("publishChunk(): failed to pin %s: %w", name, err)
+	}
+	exp.setRange(RoundRange{First: first, Last: last, CID: cid})
+
+	manifest, err := json.MarshalIndent(exp.state.Manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("publishChunk(): %w", err)
+	}
+	manifestCID, err := exp.client.add([]file{{name: "manifest.json", contents: manifest}}, false)
+	if err != nil {
+		return fmt.Errorf("publishChunk(): failed to add manifest: %w", err)
+	}
+	if err = exp.client.pin(manifestCID, "manifest"); err != nil {
+		return fmt.Errorf("publishChunk(): failed to pin manifest: %w", err)
+	}
+	if exp.cfg.IPNSKey != "" {
+		if err = exp.client.publish(manifestCID); err != nil {
+			return fmt.Errorf("publishChunk(): failed to publish manifest: %w", err)
+		}
+	}
+	exp.state.ManifestCID = manifestCID
+
+	if complete {
+		for _, f := range files {
+			if err = os.Remove(path.Join(dir, f.name)); err != nil {
+				return fmt.Errorf("publishChunk(): %w", err)
+			}
+		}
+		exp.state.ChunkFirst = nil
+	}
+	if err = exp.writeState(); err != nil {
+		return fmt.Errorf("publishChunk(): %w", err)
+	}
+	exp.logger.Infof("Published %s as /ipfs/%s, manifest /ipfs/%s", name, cid, manifestCID)
+	return nil
+}
+
+// setRange adds the range to the manifest, replacing the range previously
+// published for an incomplete chunk with the same first round.
+func (exp *archiveExporter) setRange(rr RoundRange) {
+	for idx, existing := range exp.state.Manifest.Ranges {
+		if existing.First == rr.First {
+			exp.state.Manifest.Ranges[idx] = rr
+			return
+		}
+	}
+	exp.state.Manifest.Ranges = append(exp.state.Manifest.Ranges, rr)
+}
+
+func init() {
+	exporters.Register(PluginName, exporters.ExporterConstructorFunc(func() exporters.Exporter {
+		return &archiveExporter{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Exporter, PluginName, Config{})
+}
@@ -0,0 +1,223 @@
+package ipfsarchive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters/filewriter"
+)
+
+// ipfsNode fakes the RPC API of an IPFS node and a pinning service. The CID
+// of the added content is a hash of the file names and contents.
+type ipfsNode struct {
+	*httptest.Server
+	mu sync.Mutex
+	// dirs are the file names of the added directories.
+	dirs      map[string][]string
+	files     map[string][]byte
+	pins      []string
+	published []string
+	fail      bool
+}
+
+func makeIPFSNode(t *testing.T) *ipfsNode {
+	node := &ipfsNode{dirs: make(map[string][]string), files: make(map[string][]byte)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v0/add", func(w http.ResponseWriter, r *http.Request) {
+		node.mu.Lock()
+		defer node.mu.Unlock()
+		if node.fail {
+			http.Error(w, "node is offline", http.StatusInternalServerError)
+			return
+		}
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		hash := sha256.New()
+		var names []string
+		for _, header := range r.MultipartForm.File["file"] {
+			f, err := header.Open()
+			require.NoError(t, err)
+			b, err := io.ReadAll(f)
+			require.NoError(t, err)
+			cid := fmt.Sprintf("file%x", sha256.Sum256(b))[:16]
+			node.files[cid] = b
+			names = append(names, header.Filename)
+			hash.Write([]byte(header.Filename))
+			hash.Write(b)
+			fmt.Fprintf(w, `{"Name":"%s","Hash":"%s"}`+"\n", header.Filename, cid)
+		}
+		if r.URL.Query().Get("wrap-with-directory") == "true" {
+			cid := "dir" + hex.EncodeToString(hash.Sum(nil))[:13]
+			node.dirs[cid] = names
+			fmt.Fprintf(w, `{"Name":"","Hash":"%s"}`+"\n", cid)
+		}
+	})
+	mux.HandleFunc("/api/v0/name/publish", func(w http.ResponseWriter, r *http.Request) {
+		node.mu.Lock()
+		defer node.mu.Unlock()
+		assert.Equal(t, "archive", r.URL.Query().Get("key"))
+		node.published = append(node.published, r.URL.Query().Get("arg"))
+	})
+	mux.HandleFunc("/pins", func(w http.ResponseWriter, r *http.Request) {
+		node.mu.Lock()
+		defer node.mu.Unlock()
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var pin map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&pin))
+		node.pins = append(node.pins, pin["name"])
+		w.WriteHeader(http.StatusAccepted)
+	})
+	node.Server = httptest.NewServer(mux)
+	t.Cleanup(node.Close)
+	return node
+}
+
+func (node *ipfsNode) setFail(fail bool) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	node.fail = fail
+}
+
+func (node *ipfsNode) manifest(t *testing.T, cid string) Manifest {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	var m Manifest
+	require.NoError(t, json.Unmarshal(node.files[cid], &m))
+	return m
+}
+
+func (node *ipfsNode) dir(cid string) []string {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	names := append([]string(nil), node.dirs[cid]...)
+	sort.Strings(names)
+	return names
+}
+
+func initExporter(t *testing.T, dataDir string, cfg Config) *archiveExporter {
+	cfgStr, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	logger, _ := test.NewNullLogger()
+	rnd := sdk.Round(0)
+	initProvider := conduit.MakePipelineInitProvider(&rnd, &sdk.Genesis{Network: "test"})
+	exp := &archiveExporter{}
+	require.NoError(t, exp.Init(context.Background(), initProvider, plugins.PluginConfig{DataDir: dataDir, Config: string(cfgStr)}, logger))
+	return exp
+}
+
+func sendRounds(t *testing.T, exp *archiveExporter, first, last uint64) {
+	for rnd := first; rnd <= last; rnd++ {
+		require.NoError(t, exp.Receive(data.BlockData{BlockHeader: sdk.BlockHeader{Round: sdk.Round(rnd)}}))
+	}
+}
+
+func TestExporterMetadata(t *testing.T) {
+	exp := &archiveExporter{}
+	meta := exp.Metadata()
+	assert.Equal(t, metadata.Name, meta.Name)
+	assert.Equal(t, metadata.Description, meta.Description)
+	assert.Equal(t, metadata.Deprecated, meta.Deprecated)
+}
+
+// TestPublishChunks tests that complete chunks are published with the
+// manifest, and that an incomplete chunk is published on close and replaced
+// once it is complete.
+func TestPublishChunks(t *testing.T) {
+	node := makeIPFSNode(t)
+	dataDir := t.TempDir()
+	cfg := Config{
+		APIAddr:        node.URL + "/",
+		RoundsPerChunk: 4,
+		IPNSKey:        "archive",
+		Pinning:        PinningConfig{Endpoint: node.URL, Token: "secret"},
+	}
+	exp := initExporter(t, dataDir, cfg)
+	genesisCID := exp.state.Manifest.Genesis
+	var genesis sdk.Genesis
+	require.NoError(t, filewriter.DecodeJSONFromBytes(genesisFile, node.files[genesisCID], &genesis, false))
+	assert.Equal(t, "test", genesis.Network)
+
+	// round 2 starts in the middle of the first chunk.
+	sendRounds(t, exp, 2, 9)
+	m := node.manifest(t, exp.state.ManifestCID)
+	require.Len(t, m.Ranges, 2)
+	assert.Equal(t, genesisCID, m.Genesis)
+	assert.Equal(t, RoundRange{First: 2, Last: 3, CID: m.Ranges[0].CID}, m.Ranges[0])
+	assert.Equal(t, []string{"2_block.json.gz", "3_block.json.gz"}, node.dir(m.Ranges[0].CID))
+	assert.Equal(t, RoundRange{First: 4, Last: 7, CID: m.Ranges[1].CID}, m.Ranges[1])
+	entries, err := os.ReadDir(path.Join(dataDir, stagingDir))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	require.NoError(t, exp.Close())
+	m = node.manifest(t, exp.state.ManifestCID)
+	require.Len(t, m.Ranges, 3)
+	assert.Equal(t, uint64(9), m.Ranges[2].Last)
+	partial := m.Ranges[2].CID
+
+	// the chunk is completed after a restart.
+	exp = initExporter(t, dataDir, cfg)
+	assert.Equal(t, genesisCID, exp.state.Manifest.Genesis)
+	sendRounds(t, exp, 10, 11)
+	m = node.manifest(t, exp.state.ManifestCID)
+	require.Len(t, m.Ranges, 3)
+	assert.Equal(t, uint64(8), m.Ranges[2].First)
+	assert.Equal(t, uint64(11), m.Ranges[2].Last)
+	assert.NotEqual(t, partial, m.Ranges[2].CID)
+	assert.Len(t, node.dir(m.Ranges[2].CID), 4)
+	require.NoError(t, exp.Close())
+
+	assert.Equal(t, []string{"genesis.json", "rounds 2-3", "manifest", "rounds 4-7", "manifest", "rounds 8-9", "manifest", "rounds 8-11", "manifest"}, node.pins)
+	require.Len(t, node.published, 4)
+	assert.Equal(t, "/ipfs/"+exp.state.ManifestCID, node.published[3])
+}
+
+// TestSkippedChunkEnd tests that a chunk whose last round is never received
+// is published when the next chunk starts.
+func TestSkippedChunkEnd(t *testing.T) {
+	node := makeIPFSNode(t)
+	exp := initExporter(t, t.TempDir(), Config{APIAddr: node.URL, RoundsPerChunk: 4})
+	sendRounds(t, exp, 0, 2)
+	sendRounds(t, exp, 5, 5)
+	m := node.manifest(t, exp.state.ManifestCID)
+	require.Len(t, m.Ranges, 1)
+	assert.Equal(t, RoundRange{First: 0, Last: 2, CID: m.Ranges[0].CID}, m.Ranges[0])
+	assert.Equal(t, uint64(5), *exp.state.ChunkFirst)
+}
+
+// TestPublishFailure tests that a failed publication fails the round, which
+// publishes the chunk when it is retried.
+func TestPublishFailure(t *testing.T) {
+	node := makeIPFSNode(t)
+	exp := initExporter(t, t.TempDir(), Config{APIAddr: node.URL, RoundsPerChunk: 2})
+	node.setFail(true)
+	require.NoError(t, exp.Receive(data.BlockData{BlockHeader: sdk.BlockHeader{Round: 0}}))
+	err := exp.Receive(data.BlockData{BlockHeader: sdk.BlockHeader{Round: 1}})
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "Receive(): publishChunk(): failed to add rounds 0-1: add(): /api/v0/add returned 500 Internal Server Error: node is offline"), err.Error())
+
+	node.setFail(false)
+	require.NoError(t, exp.Receive(data.BlockData{BlockHeader: sdk.BlockHeader{Round: 1}}))
+	assert.Len(t, node.manifest(t, exp.state.ManifestCID).Ranges, 1)
+}
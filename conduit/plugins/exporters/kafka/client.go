@@ -0,0 +1,127 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+const (
+	saslPlain       = "PLAIN"
+	saslScramSHA256 = "SCRAM-SHA-256"
+	saslScramSHA512 = "SCRAM-SHA-512"
+)
+
+// makeClient creates the producer. Keyed messages are assigned to partitions
+// with the murmur2 hash of the Java client, so that keys are written to the
+// same partitions as with other producers.
+func makeClient(cfg Config, meter conduit.TrafficMeter) (*kgo.Client, error) {
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.ClientID(cfg.ClientID),
+		kgo.RecordPartitioner(kgo.StickyKeyPartitioner(nil)),
+		kgo.ProduceRequestTimeout(cfg.Timeout),
+		// The messages which are not delivered in time fail the round, which
+		// the pipeline retries.
+		kgo.RecordDeliveryTimeout(cfg.Timeout),
+	}
+	if cfg.RequiredAcks == acksLeader {
+		// Idempotent writes require the acknowledgement of every replica.
+		opts = append(opts, kgo.RequiredAcks(kgo.LeaderAck()), kgo.DisableIdempotentWrite())
+	} else {
+		opts = append(opts, kgo.RequiredAcks(kgo.AllISRAcks()))
+	}
+
+	var tlsConfig *tls.Config
+	if cfg.TLS.Enabled {
+		var err error
+		if tlsConfig, err = makeTLSConfig(cfg.TLS); err != nil {
+			return nil, fmt.Errorf("makeClient(): %w", err)
+		}
+	}
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	opts = append(opts, kgo.Dialer(func(ctx context.Context, network, host string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, host)
+		if err != nil {
+			return nil, err
+		}
+		conn = conduit.MeteredConn(conn, host, meter)
+		if tlsConfig == nil {
+			return conn, nil
+		}
+		brokerConfig := tlsConfig.Clone()
+		if brokerConfig.ServerName == "" {
+			brokerConfig.ServerName, _, _ = net.SplitHostPort(host)
+		}
+		return tls.Client(conn, brokerConfig), nil
+	}))
+
+	switch cfg.SASL.Mechanism {
+	case saslPlain:
+		opts = append(opts, kgo.SASL(plain.Auth{User: cfg.SASL.Username, Pass: cfg.SASL.Password}.AsMechanism()))
+	case saslScramSHA256:
+		opts = append(opts, kgo.SASL(scram.Auth{User: cfg.SASL.Username, Pass: cfg.SASL.Password}.AsSha256Mechanism()))
+	case saslScramSHA512:
+		opts = append(opts, kgo.SASL(scram.Auth{User: cfg.SASL.Username, Pass: cfg.SASL.Password}.AsSha512Mechanism()))
+	}
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("makeClient(): %w", err)
+	}
+	return client, nil
+}
+
+func makeTLSConfig(cfg TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read ca-file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca-file %s has no certificates", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// isAuthError reports whether the brokers rejected the credentials or denied
+// access to the topics.
+func isAuthError(err error) bool {
+	return errors.Is(err, kerr.SaslAuthenticationFailed) ||
+		errors.Is(err, kerr.TopicAuthorizationFailed) ||
+		errors.Is(err, kerr.ClusterAuthorizationFailed)
+}
+
+// isPermanentError reports whether a broker rejected the messages, such as a
+// message which is too large. Other errors are transient: the brokers are
+// unavailable or the partition leaders moved.
+func isPermanentError(err error) bool {
+	var kafkaErr *kerr.Error
+	return errors.As(err, &kafkaErr) && !kafkaErr.Retriable
+}
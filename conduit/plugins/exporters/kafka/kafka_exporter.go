@@ -0,0 +1,453 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	_ "embed" // used to embed config
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit"
+	conduitcodec "github.com/algorand/conduit/conduit/codec"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+)
+
+// PluginName to use when configuring.
+const PluginName = "kafka"
+
+const (
+	modeBlocks       = "blocks"
+	modeTransactions = "transactions"
+	modeBoth         = "both"
+
+	formatJSON    = conduitcodec.JSON
+	formatMsgpack = conduitcodec.Msgpack
+
+	keyRound  = "round"
+	keySender = "sender"
+	keyAppID  = "app-id"
+	keyNone   = "none"
+
+	acksAll    = "all"
+	acksLeader = "leader"
+
+	defaultClientID         = "conduit"
+	defaultBlockTopic       = "algorand-blocks"
+	defaultTransactionTopic = "algorand-transactions"
+	defaultTimeout          = 30 * time.Second
+)
+
+// txnMessage is the value of a transaction message.
+type txnMessage struct {
+	Round     uint64               `codec:"round"`
+	Intra     int                  `codec:"intra"`
+	Timestamp int64                `codec:"timestamp"`
+	Txn       sdk.SignedTxnInBlock `codec:"txn"`
+}
+
+// topicData is available to the topic templates.
+type topicData struct {
+	Network string
+	Round   uint64
+	TxType  string
+}
+
+type kafkaExporter struct {
+	round   uint64
+	cfg     Config
+	logger  *logrus.Logger
+	network string
+	client  *kgo.Client
+
+	blockTopic       *template.Template
+	transactionTopic *template.Template
+	// signer adds a signature header to the messages, it is nil if signing is disabled.
+	signer conduit.Signer
+	// jsonEncoding writes binary fields with the pipeline binary-encoding, it
+	// is nil for the default base64.
+	jsonEncoding conduitcodec.Encoding
+	// amountFormat rewrites the amounts of JSON messages, it is nil for the
+	// default integer encoding.
+	amountFormat *conduit.AmountFormat
+	// checker validates each JSON message before it is published, it is nil
+	// unless an output-schema is configured.
+	checker conduit.PayloadChecker
+	// meter counts the traffic with the brokers, it is nil unless the
+	// pipeline accounts for the bandwidth.
+	meter conduit.TrafficMeter
+}
+
+//go:embed sample.yaml
+var sampleFile string
+
+var metadata = conduit.Metadata{
+	Name:         PluginName,
+	Description:  "Exporter for publishing blocks and transactions to Kafka topics.",
+	Deprecated:   false,
+	SampleConfig: sampleFile,
+}
+
+func (exp *kafkaExporter) Metadata() conduit.Metadata {
+	return metadata
+}
+
+// ValidateConfig checks the config and topic templates without connecting.
+func (exp *kafkaExporter) ValidateConfig(cfg plugins.PluginConfig) error {
+	var kcfg Config
+	if err := cfg.UnmarshalConfig(&kcfg); err != nil {
+		return fmt.Errorf("ValidateConfig(): %w", err)
+	}
+	if err := kcfg.setDefaults(); err != nil {
+		return fmt.Errorf("ValidateConfig(): %w", err)
+	}
+	if _, _, err := kcfg.parseTopics(); err != nil {
+		return fmt.Errorf("ValidateConfig(): %w", err)
+	}
+	return nil
+}
+
+func (exp *kafkaExporter) Init(_ context.Context, initProvider data.InitProvider, cfg plugins.PluginConfig, logger *logrus.Logger) error {
+	exp.logger = logger
+	if err := cfg.UnmarshalConfig(&exp.cfg); err != nil {
+		return fmt.Errorf("connect failure in unmarshalConfig: %w", err)
+	}
+	if err := exp.cfg.setDefaults(); err != nil {
+		return fmt.Errorf("Init(): %w", err)
+	}
+
+	var err error
+	if exp.blockTopic, exp.transactionTopic, err = exp.cfg.parseTopics(); err != nil {
+		return fmt.Errorf("Init(): %w", err)
+	}
+
+	if exp.client, err = makeClient(exp.cfg, exp.meter); err != nil {
+		return fmt.Errorf("Init(): %w", err)
+	}
+	exp.network = initProvider.GetGenesis().Network
+	exp.round = uint64(initProvider.NextDBRound())
+	return nil
+}
+
+// setDefaults fills in the defaults and validates the configuration.
+func (cfg *Config) setDefaults() error {
+	if len(cfg.Brokers) == 0 {
+		return fmt.Errorf("at least one broker is required")
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = defaultClientID
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = modeBlocks
+	}
+	if cfg.BlockTopic == "" {
+		cfg.BlockTopic = defaultBlockTopic
+	}
+	if cfg.TransactionTopic == "" {
+		cfg.TransactionTopic = defaultTransactionTopic
+	}
+	if cfg.Format == "" {
+		cfg.Format = formatJSON
+	}
+	if cfg.PartitionKey == "" {
+		cfg.PartitionKey = keyRound
+	}
+	if cfg.RequiredAcks == "" {
+		cfg.RequiredAcks = acksAll
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	switch cfg.Mode {
+	case modeBlocks, modeTransactions, modeBoth:
+	default:
+		return fmt.Errorf("mode must be '%s', '%s' or '%s', found '%s'", modeBlocks, modeTransactions, modeBoth, cfg.Mode)
+	}
+	if _, err := conduitcodec.EncodingByName(cfg.Format); err != nil {
+		return fmt.Errorf("format: %w", err)
+	}
+	switch cfg.PartitionKey {
+	case keyRound, keySender, keyAppID, keyNone:
+	default:
+		return fmt.Errorf("partition-key must be '%s', '%s', '%s' or '%s', found '%s'", keyRound, keySender, keyAppID, keyNone, cfg.PartitionKey)
+	}
+	switch cfg.RequiredAcks {
+	case acksAll, acksLeader:
+	default:
+		return fmt.Errorf("required-acks must be '%s' or '%s', found '%s'", acksAll, acksLeader, cfg.RequiredAcks)
+	}
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	switch cfg.SASL.Mechanism {
+	case "":
+	case saslPlain, saslScramSHA256, saslScramSHA512:
+		if cfg.SASL.Username == "" {
+			return fmt.Errorf("sasl username is required for %s", cfg.SASL.Mechanism)
+		}
+	default:
+		return fmt.Errorf("sasl mechanism must be '%s', '%s' or '%s', found '%s'", saslPlain, saslScramSHA256, saslScramSHA512, cfg.SASL.Mechanism)
+	}
+	return nil
+}
+
+// parseTopics parses the block and transaction topic templates.
+func (cfg *Config) parseTopics() (blockTopic, transactionTopic *template.Template, err error) {
+	if blockTopic, err = template.New("block-topic").Option("missingkey=error").Parse(cfg.BlockTopic); err != nil {
+		return nil, nil, fmt.Errorf("invalid block-topic: %w", err)
+	}
+	if transactionTopic, err = template.New("transaction-topic").Option("missingkey=error").Parse(cfg.TransactionTopic); err != nil {
+		return nil, nil, fmt.Errorf("invalid transaction-topic: %w", err)
+	}
+	return blockTopic, transactionTopic, nil
+}
+
+func (exp *kafkaExporter) Config() string {
+	cfg := exp.cfg
+	if cfg.SASL.Password != "" {
+		cfg.SASL.Password = "********"
+	}
+	ret, _ := yaml.Marshal(cfg)
+	return string(ret)
+}
+
+func (exp *kafkaExporter) Close() error {
+	if exp.client != nil {
+		exp.client.Close()
+	}
+	return nil
+}
+
+// SetSigner adds a "signature" header to each message, the ed25519
+// signature of the message value.
+func (exp *kafkaExporter) SetSigner(signer conduit.Signer) {
+	exp.signer = signer
+}
+
+// SetBinaryEncoding writes binary fields of JSON messages with the encoding,
+// msgpack messages are not affected.
+func (exp *kafkaExporter) SetBinaryEncoding(encoding conduit.BinaryEncoding) {
+	exp.jsonEncoding = conduitcodec.NewJSONEncoding(encoding.JSONHandle(0))
+}
+
+// SetAmountFormat rewrites the amounts of JSON messages with the format,
+// msgpack messages are not affected.
+func (exp *kafkaExporter) SetAmountFormat(format *conduit.AmountFormat) {
+	exp.amountFormat = format
+}
+
+// SetPayloadChecker checks each JSON message before it is published, rejected
+// messages are not published. Messages of other formats are not checked.
+func (exp *kafkaExporter) SetPayloadChecker(checker conduit.PayloadChecker) {
+	if exp.cfg.Format != formatJSON {
+		exp.logger.Warnf("The output-schema is ignored for the %s format", exp.cfg.Format)
+		return
+	}
+	exp.checker = checker
+}
+
+// SetTrafficMeter counts the bytes exchanged with each broker.
+func (exp *kafkaExporter) SetTrafficMeter(meter conduit.TrafficMeter) {
+	exp.meter = meter
+}
+
+// check reports whether the message of the round may be published.
+func (exp *kafkaExporter) check(round uint64, value []byte) (bool, error) {
+	if exp.checker == nil {
+		return true, nil
+	}
+	ok, err := exp.checker.Check(round, value)
+	if err == nil && !ok {
+		exp.logger.Warnf("A message of round %d was rejected by the payload checker and was not published", round)
+	}
+	return ok, err
+}
+
+func (exp *kafkaExporter) Receive(exportData data.BlockData) error {
+	if exp.logger == nil {
+		return fmt.Errorf("exporter not initialized")
+	}
+	if exportData.Round() != exp.round {
+		err := fmt.Errorf("Receive(): wrong block: received round %d, expected round %d", exportData.Round(), exp.round)
+		if exportData.Round() < exp.round {
+			return conduit.MakeDuplicateRoundError(exportData.Round(), err)
+		}
+		return err
+	}
+
+	msgs, err := exp.messages(exportData)
+	if err != nil {
+		return fmt.Errorf("Receive(): %w", err)
+	}
+	if err = exp.publish(msgs); err != nil {
+		switch {
+		case isAuthError(err):
+			err = conduit.MakeAuthError(err)
+		case !isPermanentError(err):
+			err = conduit.MakeRetryableError(err)
+		}
+		return fmt.Errorf("Receive(): failed to publish round %d: %w", exp.round, err)
+	}
+	exp.logger.Infof("Published %d messages for round %d", len(msgs), exp.round)
+
+	exp.round++
+	return nil
+}
+
+// messages returns the messages of a block for the configured mode.
+func (exp *kafkaExporter) messages(exportData data.BlockData) ([]*kgo.Record, error) {
+	round := exportData.Round()
+	roundBytes := []byte(strconv.FormatUint(round, 10))
+	var msgs []*kgo.Record
+
+	if exp.cfg.Mode != modeTransactions {
+		topic, err := exp.topic(exp.blockTopic, topicData{Network: exp.network, Round: round})
+		if err != nil {
+			return nil, err
+		}
+		value, err := exp.encode(exportData)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := exp.check(round, value)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			msg := &kgo.Record{Topic: topic, Value: value, Headers: []kgo.RecordHeader{{Key: "round", Value: roundBytes}}}
+			if exp.cfg.PartitionKey != keyNone {
+				msg.Key = roundBytes
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+
+	if exp.cfg.Mode != modeBlocks {
+		for intra, stxn := range exportData.Payset {
+			txType := string(stxn.Txn.Type)
+			topic, err := exp.topic(exp.transactionTopic, topicData{Network: exp.network, Round: round, TxType: txType})
+			if err != nil {
+				return nil, err
+			}
+			value, err := exp.encode(txnMessage{
+				Round:     round,
+				Intra:     intra,
+				Timestamp: exportData.BlockHeader.TimeStamp,
+				Txn:       stxn,
+			})
+			if err != nil {
+				return nil, err
+			}
+			ok, err := exp.check(round, value)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			msgs = append(msgs, &kgo.Record{
+				Topic: topic,
+				Key:   exp.transactionKey(&stxn, roundBytes),
+				Value: value,
+				Headers: []kgo.RecordHeader{
+					{Key: "round", Value: roundBytes},
+					{Key: "intra", Value: []byte(strconv.Itoa(intra))},
+					{Key: "type", Value: []byte(txType)},
+				},
+			})
+		}
+	}
+	if exp.signer != nil {
+		for _, msg := range msgs {
+			msg.Headers = append(msg.Headers, kgo.RecordHeader{Key: "signature", Value: exp.signer.Sign(msg.Value)})
+		}
+	}
+	return msgs, nil
+}
+
+func (exp *kafkaExporter) topic(tmpl *template.Template, td topicData) (string, error) {
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, td); err != nil {
+		return "", fmt.Errorf("unable to render topic: %w", err)
+	}
+	if buf.Len() == 0 {
+		return "", fmt.Errorf("%s renders an empty topic", tmpl.Name())
+	}
+	return buf.String(), nil
+}
+
+// encode encodes a message with the codec registry, like the other plugins
+// which write the format.
+func (exp *kafkaExporter) encode(v interface{}) ([]byte, error) {
+	encoding, err := conduitcodec.EncodingByName(exp.cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+	if exp.cfg.Format == formatJSON && exp.jsonEncoding != nil {
+		encoding = exp.jsonEncoding
+	}
+	var buf bytes.Buffer
+	if err = encoding.Encode(&buf, v); err != nil {
+		return nil, fmt.Errorf("unable to encode message: %w", err)
+	}
+	if exp.cfg.Format == formatJSON && exp.amountFormat != nil {
+		return exp.amountFormat.Rewrite(buf.Bytes(), "")
+	}
+	return buf.Bytes(), nil
+}
+
+// transactionKey returns the message key of a transaction.
+func (exp *kafkaExporter) transactionKey(stxn *sdk.SignedTxnInBlock, roundBytes []byte) []byte {
+	switch exp.cfg.PartitionKey {
+	case keyRound:
+		return roundBytes
+	case keyAppID:
+		appID := uint64(stxn.Txn.ApplicationID)
+		if appID == 0 {
+			appID = uint64(stxn.ApplyData.ApplicationID)
+		}
+		if appID != 0 {
+			return []byte(strconv.FormatUint(appID, 10))
+		}
+		return []byte(stxn.Txn.Sender.String())
+	case keySender:
+		return []byte(stxn.Txn.Sender.String())
+	}
+	return nil
+}
+
+// publish produces the messages and waits until the brokers acknowledged
+// them. Messages with the same key are written to the same partition, in
+// order, messages without a key are spread over the partitions.
+func (exp *kafkaExporter) publish(msgs []*kgo.Record) error {
+	ctx := context.Background()
+	err := exp.client.ProduceSync(ctx, msgs...).FirstErr()
+	if errors.Is(err, kgo.ErrRecordTimeout) {
+		// The client retries until the timeout when the brokers reject the
+		// credentials, a ping returns the cause.
+		pingCtx, cancel := context.WithTimeout(ctx, exp.cfg.Timeout)
+		defer cancel()
+		if pingErr := exp.client.Ping(pingCtx); isAuthError(pingErr) {
+			return pingErr
+		}
+	}
+	return err
+}
+
+func init() {
+	exporters.Register(PluginName, exporters.ExporterConstructorFunc(func() exporters.Exporter {
+		return &kafkaExporter{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Exporter, PluginName, Config{})
+}
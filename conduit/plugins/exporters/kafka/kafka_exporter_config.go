@@ -0,0 +1,78 @@
+package kafka
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+import "time"
+
+//PluginName: conduit_exporters_kafka
+
+// Config specific to the kafka exporter
+type Config struct {
+	// <code>brokers</code> are the host:port addresses used to discover the cluster.
+	Brokers []string `yaml:"brokers"`
+	// <code>client-id</code> identifies conduit in the broker logs and quotas. Default: "conduit".
+	ClientID string `yaml:"client-id"`
+	/* <code>mode</code> selects what is published:<br/>
+	<ul>
+		<li>blocks: one message per block (default).</li>
+		<li>transactions: one message per top level transaction, including its inner transactions.</li>
+		<li>both: blocks and transactions, to their own topics.</li>
+	</ul>
+	*/
+	Mode string `yaml:"mode"`
+	/* <code>block-topic</code> is the topic of block messages. It is a go template which may use .Network and .Round.
+	Default:
+
+		"algorand-blocks"
+	*/
+	BlockTopic string `yaml:"block-topic"`
+	/* <code>transaction-topic</code> is the topic of transaction messages. It is a go template which may use .Network, .Round and .TxType.
+	Default:
+
+		"algorand-transactions"
+	*/
+	TransactionTopic string `yaml:"transaction-topic"`
+	// <code>format</code> is the name of the encoding of the messages, "json" (default), "msgpack", "protobuf" or "avro".
+	Format string `yaml:"format"`
+	/* <code>partition-key</code> selects the message key, which determines the partition:<br/>
+	<ul>
+		<li>round: the round (default).</li>
+		<li>sender: the transaction sender, block messages use the round.</li>
+		<li>app-id: the called or created application, other transactions use the sender and block messages use the round.</li>
+		<li>none: no key, messages are distributed over the partitions.</li>
+	</ul>
+	*/
+	PartitionKey string `yaml:"partition-key"`
+	// <code>required-acks</code> is either "all" (default), to wait for every in-sync replica, or "leader".
+	RequiredAcks string `yaml:"required-acks"`
+	// <code>timeout</code> limits connecting to a broker and delivering the messages of a round. Default: 30s.
+	Timeout time.Duration `yaml:"timeout"`
+	// <code>tls</code> configures encrypted connections to the brokers.
+	TLS TLSConfig `yaml:"tls"`
+	// <code>sasl</code> configures authentication with the brokers.
+	SASL SASLConfig `yaml:"sasl"`
+}
+
+// TLSConfig configures encrypted connections.
+type TLSConfig struct {
+	// <code>enabled</code> connects to the brokers with TLS.
+	Enabled bool `yaml:"enabled"`
+	// <code>ca-file</code> is a PEM file of the certificate authorities to trust instead of the system roots.
+	CAFile string `yaml:"ca-file"`
+	// <code>cert-file</code> and <code>key-file</code> are the PEM client certificate and key, for mutual TLS.
+	CertFile string `yaml:"cert-file"`
+	KeyFile  string `yaml:"key-file"`
+	// <code>server-name</code> overrides the host name which the broker certificates are verified against.
+	ServerName string `yaml:"server-name"`
+	// <code>insecure-skip-verify</code> disables the verification of the broker certificates.
+	InsecureSkipVerify bool `yaml:"insecure-skip-verify"`
+}
+
+// SASLConfig configures authentication.
+type SASLConfig struct {
+	// <code>mechanism</code> is PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512. Authentication is disabled if it is empty.
+	Mechanism string `yaml:"mechanism"`
+	// <code>username</code> and <code>password</code> are the credentials.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
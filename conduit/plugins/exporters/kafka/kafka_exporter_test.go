@@ -0,0 +1,446 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit"
+	conduitcodec "github.com/algorand/conduit/conduit/codec"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/tools/testutil"
+)
+
+var logger *logrus.Logger
+var kafkaCons = exporters.ExporterConstructorFunc(func() exporters.Exporter {
+	return &kafkaExporter{}
+})
+
+func init() {
+	logger, _ = test.NewNullLogger()
+}
+
+// startCluster starts a single broker cluster, the topics are seeded with
+// kfake.SeedTopics since the exporter does not create them.
+func startCluster(t *testing.T, opts ...kfake.Opt) *kfake.Cluster {
+	cluster, err := kfake.NewCluster(append([]kfake.Opt{kfake.NumBrokers(1)}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(cluster.Close)
+	return cluster
+}
+
+// failProduce makes the cluster reply to produce requests with the error code,
+// until it is set back to zero.
+func failProduce(cluster *kfake.Cluster) *atomic.Int32 {
+	var code atomic.Int32
+	cluster.ControlKey(int16(kmsg.Produce), func(kreq kmsg.Request) (kmsg.Response, error, bool) {
+		cluster.KeepControl()
+		errorCode := int16(code.Load())
+		if errorCode == 0 {
+			return nil, nil, false
+		}
+		req := kreq.(*kmsg.ProduceRequest)
+		resp := req.ResponseKind().(*kmsg.ProduceResponse)
+		for _, topic := range req.Topics {
+			respTopic := kmsg.NewProduceResponseTopic()
+			respTopic.Topic = topic.Topic
+			for _, partition := range topic.Partitions {
+				respPartition := kmsg.NewProduceResponseTopicPartition()
+				respPartition.Partition = partition.Partition
+				respPartition.ErrorCode = errorCode
+				respTopic.Partitions = append(respTopic.Partitions, respPartition)
+			}
+			resp.Topics = append(resp.Topics, respTopic)
+		}
+		return resp, nil, true
+	})
+	return &code
+}
+
+// messages consumes the messages published to a topic, by partition.
+func messages(t *testing.T, cluster *kfake.Cluster, topic string) map[int32][]*kgo.Record {
+	var total int64
+	for _, info := range cluster.PartitionInfos(topic) {
+		total += info.HighWatermark
+	}
+	result := make(map[int32][]*kgo.Record)
+	if total == 0 {
+		return result
+	}
+	consumer, err := kgo.NewClient(
+		kgo.SeedBrokers(cluster.ListenAddrs()...),
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	)
+	require.NoError(t, err)
+	defer consumer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for consumed := int64(0); consumed < total; {
+		fetches := consumer.PollFetches(ctx)
+		require.NoError(t, fetches.Err())
+		fetches.EachRecord(func(rec *kgo.Record) {
+			result[rec.Partition] = append(result[rec.Partition], rec)
+			consumed++
+		})
+	}
+	return result
+}
+
+// keyPartition is the partition of a key with the murmur2 hash of the Java
+// client.
+func keyPartition(topic string, key []byte, partitions int) int32 {
+	partitioner := kgo.StickyKeyPartitioner(nil).ForTopic(topic)
+	return int32(partitioner.Partition(&kgo.Record{Key: key}, partitions))
+}
+
+func initExporter(t *testing.T, cfg Config) exporters.Exporter {
+	cfgStr, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	rnd := sdk.Round(1)
+	initProvider := testutil.MockedInitProvider(&rnd)
+	initProvider.Genesis = &sdk.Genesis{Network: "testnet"}
+	exp := kafkaCons.New()
+	require.NoError(t, exp.Init(context.Background(), initProvider, plugins.MakePluginConfig(string(cfgStr)), logger))
+	t.Cleanup(func() { exp.Close() })
+	return exp
+}
+
+func testBlock(round uint64) data.BlockData {
+	sender := sdk.Address{1}
+	return data.BlockData{
+		BlockHeader: sdk.BlockHeader{Round: sdk.Round(round), TimeStamp: 1000},
+		Payset: []sdk.SignedTxnInBlock{
+			{SignedTxnWithAD: sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{
+				Type:   sdk.PaymentTx,
+				Header: sdk.Header{Sender: sender},
+			}}}},
+			{SignedTxnWithAD: sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{
+				Type:              sdk.ApplicationCallTx,
+				Header:            sdk.Header{Sender: sender},
+				ApplicationFields: sdk.ApplicationFields{ApplicationCallTxnFields: sdk.ApplicationCallTxnFields{ApplicationID: 42}},
+			}}}},
+		},
+	}
+}
+
+func TestExporterMetadata(t *testing.T) {
+	meta := kafkaCons.New().Metadata()
+	assert.Equal(t, metadata.Name, meta.Name)
+	assert.Equal(t, metadata.Description, meta.Description)
+	assert.Equal(t, metadata.Deprecated, meta.Deprecated)
+}
+
+func TestConfigDefaults(t *testing.T) {
+	cfg := Config{Brokers: []string{"localhost:9092"}}
+	require.NoError(t, cfg.setDefaults())
+	assert.Equal(t, Config{
+		Brokers:          []string{"localhost:9092"},
+		ClientID:         defaultClientID,
+		Mode:             modeBlocks,
+		BlockTopic:       defaultBlockTopic,
+		TransactionTopic: defaultTransactionTopic,
+		Format:           formatJSON,
+		PartitionKey:     keyRound,
+		RequiredAcks:     acksAll,
+		Timeout:          defaultTimeout,
+	}, cfg)
+}
+
+func TestConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		err  string
+	}{
+		{name: "no brokers", err: "at least one broker is required"},
+		{name: "mode", cfg: Config{Mode: "rounds"}, err: "mode must be 'blocks', 'transactions' or 'both', found 'rounds'"},
+		{name: "format", cfg: Config{Format: "xml"}, err: "format: unknown encoding (xml), expected one of: avro, json, msgpack, protobuf"},
+		{name: "partition key", cfg: Config{PartitionKey: "receiver"}, err: "partition-key must be 'round', 'sender', 'app-id' or 'none', found 'receiver'"},
+		{name: "acks", cfg: Config{RequiredAcks: "none"}, err: "required-acks must be 'all' or 'leader', found 'none'"},
+		{name: "sasl mechanism", cfg: Config{SASL: SASLConfig{Mechanism: "GSSAPI"}}, err: "sasl mechanism must be 'PLAIN', 'SCRAM-SHA-256' or 'SCRAM-SHA-512', found 'GSSAPI'"},
+		{name: "sasl username", cfg: Config{SASL: SASLConfig{Mechanism: saslPlain}}, err: "sasl username is required for PLAIN"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.name != "no brokers" {
+				tc.cfg.Brokers = []string{"localhost:9092"}
+			}
+			assert.EqualError(t, tc.cfg.setDefaults(), tc.err)
+		})
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		err  string
+	}{
+		{name: "valid", cfg: Config{Brokers: []string{"localhost:9092"}, BlockTopic: "{{.Network}}-blocks"}},
+		{name: "invalid", cfg: Config{Mode: "rounds"}, err: "ValidateConfig(): at least one broker is required"},
+		{name: "topic", cfg: Config{Brokers: []string{"localhost:9092"}, BlockTopic: "{{.Network"}, err: "ValidateConfig(): invalid block-topic"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfgStr, err := yaml.Marshal(tc.cfg)
+			require.NoError(t, err)
+			validator := kafkaCons.New().(conduit.ConfigValidator)
+			err = validator.ValidateConfig(plugins.MakePluginConfig(string(cfgStr)))
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
+
+func TestExporterConfigRedactsPassword(t *testing.T) {
+	exp := initExporter(t, Config{Brokers: []string{"localhost:9092"}, SASL: SASLConfig{Mechanism: saslPlain, Username: "conduit", Password: "secret"}})
+	assert.NotContains(t, exp.Config(), "secret")
+}
+
+func TestReceiveBlocks(t *testing.T) {
+	cluster := startCluster(t, kfake.SeedTopics(3, "testnet-blocks"))
+	exp := initExporter(t, Config{Brokers: cluster.ListenAddrs(), BlockTopic: "{{.Network}}-blocks"})
+
+	for round := uint64(1); round <= 3; round++ {
+		require.NoError(t, exp.Receive(testBlock(round)))
+	}
+	err := exp.Receive(testBlock(5))
+	assert.EqualError(t, err, "Receive(): wrong block: received round 5, expected round 4")
+	// a round which was already published is reported as a duplicate.
+	err = exp.Receive(testBlock(2))
+	var dupErr *conduit.DuplicateRoundError
+	require.True(t, errors.As(err, &dupErr))
+	assert.Equal(t, uint64(2), dupErr.Round)
+
+	var total int
+	for partition, msgs := range messages(t, cluster, "testnet-blocks") {
+		for _, msg := range msgs {
+			total++
+			assert.Equal(t, keyPartition("testnet-blocks", msg.Key, 3), partition)
+			assert.Equal(t, []kgo.RecordHeader{{Key: "round", Value: msg.Key}}, msg.Headers)
+			assert.Contains(t, string(msg.Value), `"block":`)
+		}
+	}
+	assert.Equal(t, 3, total)
+}
+
+func TestReceiveTransactions(t *testing.T) {
+	tests := []struct {
+		name         string
+		partitionKey string
+		keys         []string
+	}{
+		{name: "round", partitionKey: keyRound, keys: []string{"1", "1"}},
+		{name: "sender", partitionKey: keySender, keys: []string{sdk.Address{1}.String(), sdk.Address{1}.String()}},
+		{name: "app-id", partitionKey: keyAppID, keys: []string{sdk.Address{1}.String(), "42"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := startCluster(t, kfake.SeedTopics(1, "txns-pay", "txns-appl", defaultBlockTopic))
+			exp := initExporter(t, Config{
+				Brokers:          cluster.ListenAddrs(),
+				Mode:             modeTransactions,
+				TransactionTopic: "txns-{{.TxType}}",
+				Format:           formatMsgpack,
+				PartitionKey:     tc.partitionKey,
+			})
+			require.NoError(t, exp.Receive(testBlock(1)))
+
+			pay := messages(t, cluster, "txns-pay")[0]
+			appl := messages(t, cluster, "txns-appl")[0]
+			require.Len(t, pay, 1)
+			require.Len(t, appl, 1)
+			assert.Equal(t, tc.keys, []string{string(pay[0].Key), string(appl[0].Key)})
+			assert.Equal(t, []kgo.RecordHeader{
+				{Key: "round", Value: []byte("1")},
+				{Key: "intra", Value: []byte("1")},
+				{Key: "type", Value: []byte("appl")},
+			}, appl[0].Headers)
+
+			var value txnMessage
+			require.NoError(t, msgpack.Decode(appl[0].Value, &value))
+			assert.Equal(t, uint64(1), value.Round)
+			assert.Equal(t, 1, value.Intra)
+			assert.Equal(t, int64(1000), value.Timestamp)
+			assert.Equal(t, sdk.AppIndex(42), value.Txn.Txn.ApplicationID)
+			assert.Empty(t, messages(t, cluster, defaultBlockTopic))
+		})
+	}
+}
+
+// TestReceiveFormats tests that the messages are decoded with the encoding of
+// the codec registry of their format.
+func TestReceiveFormats(t *testing.T) {
+	for _, format := range []string{conduitcodec.Protobuf, conduitcodec.Avro} {
+		t.Run(format, func(t *testing.T) {
+			cluster := startCluster(t, kfake.SeedTopics(1, defaultBlockTopic))
+			exp := initExporter(t, Config{Brokers: cluster.ListenAddrs(), Format: format})
+			require.NoError(t, exp.Receive(testBlock(1)))
+
+			msgs := messages(t, cluster, defaultBlockTopic)[0]
+			require.Len(t, msgs, 1)
+			encoding, err := conduitcodec.EncodingByName(format)
+			require.NoError(t, err)
+			var value data.BlockData
+			require.NoError(t, encoding.Decode(bytes.NewReader(msgs[0].Value), &value))
+			assert.Equal(t, testBlock(1).BlockHeader, value.BlockHeader)
+			assert.Len(t, value.Payset, len(testBlock(1).Payset))
+		})
+	}
+}
+
+// TestReceiveWithoutKey tests that the messages have no key, they are
+// distributed over the partitions by the client.
+func TestReceiveWithoutKey(t *testing.T) {
+	cluster := startCluster(t, kfake.SeedTopics(2, defaultBlockTopic, defaultTransactionTopic))
+	exp := initExporter(t, Config{Brokers: cluster.ListenAddrs(), Mode: modeBoth, PartitionKey: keyNone})
+	require.NoError(t, exp.Receive(testBlock(1)))
+	require.NoError(t, exp.Receive(testBlock(2)))
+
+	counts := make(map[string]int)
+	for _, topic := range []string{defaultBlockTopic, defaultTransactionTopic} {
+		for _, msgs := range messages(t, cluster, topic) {
+			for _, msg := range msgs {
+				assert.Nil(t, msg.Key)
+				counts[topic]++
+			}
+		}
+	}
+	assert.Equal(t, map[string]int{defaultBlockTopic: 2, defaultTransactionTopic: 4}, counts)
+}
+
+func TestReceiveSASLPlain(t *testing.T) {
+	cluster := startCluster(t, kfake.SeedTopics(1, defaultBlockTopic), kfake.EnableSASL(), kfake.Superuser(saslPlain, "conduit", "secret"))
+	exp := initExporter(t, Config{Brokers: cluster.ListenAddrs(), SASL: SASLConfig{Mechanism: saslPlain, Username: "conduit", Password: "secret"}})
+	require.NoError(t, exp.Receive(testBlock(1)))
+
+	// kfake closes the connection on invalid credentials, brokers reply with
+	// SASL_AUTHENTICATION_FAILED.
+	cluster.ControlKey(int16(kmsg.SASLAuthenticate), func(kreq kmsg.Request) (kmsg.Response, error, bool) {
+		cluster.KeepControl()
+		req := kreq.(*kmsg.SASLAuthenticateRequest)
+		if string(req.SASLAuthBytes) == "\x00conduit\x00secret" {
+			return nil, nil, false
+		}
+		resp := req.ResponseKind().(*kmsg.SASLAuthenticateResponse)
+		resp.ErrorCode = kerr.SaslAuthenticationFailed.Code
+		return resp, nil, true
+	})
+	exp = initExporter(t, Config{Brokers: cluster.ListenAddrs(), SASL: SASLConfig{Mechanism: saslPlain, Username: "conduit", Password: "wrong"}, Timeout: time.Second})
+	err := exp.Receive(testBlock(1))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "SASL_AUTHENTICATION_FAILED")
+	var authErr *conduit.AuthError
+	assert.True(t, errors.As(err, &authErr), "rejected credentials are reported as an auth failure")
+}
+
+func TestReceiveSASLScram(t *testing.T) {
+	cluster := startCluster(t, kfake.SeedTopics(1, defaultBlockTopic), kfake.EnableSASL(), kfake.Superuser(saslScramSHA512, "conduit", "secret"))
+	exp := initExporter(t, Config{Brokers: cluster.ListenAddrs(), SASL: SASLConfig{Mechanism: saslScramSHA512, Username: "conduit", Password: "secret"}})
+	require.NoError(t, exp.Receive(testBlock(1)))
+}
+
+func TestReceiveErrors(t *testing.T) {
+	cluster := startCluster(t, kfake.SeedTopics(1, defaultBlockTopic))
+	produceError := failProduce(cluster)
+	produceError.Store(6)
+	exp := initExporter(t, Config{Brokers: cluster.ListenAddrs(), Timeout: time.Second})
+	err := exp.Receive(testBlock(1))
+	require.Error(t, err)
+	var retryable *conduit.RetryableError
+	assert.True(t, errors.As(err, &retryable), "a leader change is retried")
+
+	produceError.Store(10)
+	err = exp.Receive(testBlock(1))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MESSAGE_TOO_LARGE")
+	assert.False(t, errors.As(err, &retryable), "a rejected message is not retried")
+
+	produceError.Store(0)
+	require.NoError(t, exp.Receive(testBlock(1)))
+	assert.Len(t, messages(t, cluster, defaultBlockTopic)[0], 1)
+
+	// The broker is unavailable.
+	addrs := cluster.ListenAddrs()
+	cluster.Close()
+	exp = initExporter(t, Config{Brokers: addrs, Timeout: time.Second})
+	err = exp.Receive(testBlock(1))
+	require.Error(t, err)
+	assert.True(t, errors.As(err, &retryable))
+}
+
+// testSigner signs with a generated ed25519 key.
+type testSigner struct {
+	key ed25519.PrivateKey
+}
+
+func (s testSigner) Sign(msg []byte) []byte {
+	return ed25519.Sign(s.key, msg)
+}
+
+func (s testSigner) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+func TestReceiveSigned(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	signer := testSigner{key: key}
+
+	cluster := startCluster(t, kfake.SeedTopics(1, defaultBlockTopic, defaultTransactionTopic))
+	exp := initExporter(t, Config{Brokers: cluster.ListenAddrs(), Mode: modeBoth})
+	exp.(conduit.OutputSigner).SetSigner(signer)
+	require.NoError(t, exp.Receive(testBlock(1)))
+
+	msgs := append(messages(t, cluster, defaultBlockTopic)[0], messages(t, cluster, defaultTransactionTopic)[0]...)
+	require.Len(t, msgs, 3)
+	for _, msg := range msgs {
+		last := msg.Headers[len(msg.Headers)-1]
+		require.Equal(t, "signature", last.Key)
+		assert.True(t, ed25519.Verify(signer.PublicKey(), msg.Value, last.Value))
+	}
+}
+
+// applChecker rejects the messages with an application call.
+type applChecker struct{}
+
+func (applChecker) Check(round uint64, payload []byte) (bool, error) {
+	return !strings.Contains(string(payload), `"type":"appl"`), nil
+}
+
+func TestReceiveChecked(t *testing.T) {
+	cluster := startCluster(t, kfake.SeedTopics(1, defaultBlockTopic, defaultTransactionTopic))
+	exp := initExporter(t, Config{Brokers: cluster.ListenAddrs(), Mode: modeBoth})
+	exp.(conduit.PayloadValidator).SetPayloadChecker(applChecker{})
+	require.NoError(t, exp.Receive(testBlock(1)))
+
+	// the block and the application call are rejected.
+	assert.Empty(t, messages(t, cluster, defaultBlockTopic)[0])
+	msgs := messages(t, cluster, defaultTransactionTopic)[0]
+	require.Len(t, msgs, 1)
+	assert.Contains(t, string(msgs[0].Value), `"type":"pay"`)
+}
@@ -70,4 +70,5 @@ func init() {
 	exporters.Register(PluginName, exporters.ExporterConstructorFunc(func() exporters.Exporter {
 		return &noopExporter{}
 	}))
+	plugins.RegisterConfigSchema(plugins.Exporter, PluginName, ExporterConfig{})
 }
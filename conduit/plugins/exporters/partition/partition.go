@@ -0,0 +1,137 @@
+// Package partition names the time-partitioned tables, indices or topics of
+// the exporters, derived from the block timestamp, such as txns_2024_05, and
+// selects the partitions which are past their retention.
+package partition
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The periods of the partitions.
+const (
+	Day   = "day"
+	Month = "month"
+	Year  = "year"
+)
+
+// layouts are the time layouts of the partition suffixes by period, they sort
+// in time order.
+var layouts = map[string]string{
+	Day:   "2006_01_02",
+	Month: "2006_01",
+	Year:  "2006",
+}
+
+// Scheme partitions the rounds by the period of their block timestamp, in UTC.
+type Scheme struct {
+	// Period is day, month or year, the rounds are not partitioned when it is
+	// not set.
+	Period string
+	// Retention is the number of periods which are kept, including the
+	// current one. 0 keeps every partition.
+	Retention int
+}
+
+// Valid validates the scheme.
+func (s Scheme) Valid() error {
+	if s.Period != "" && layouts[s.Period] == "" {
+		return fmt.Errorf("period '%s' must be one of %s, %s or %s", s.Period, Day, Month, Year)
+	}
+	if s.Retention < 0 {
+		return fmt.Errorf("retention must not be negative")
+	}
+	return nil
+}
+
+// Enabled reports whether the rounds are partitioned.
+func (s Scheme) Enabled() bool {
+	return s.Period != ""
+}
+
+// Start returns the start of the period of t.
+func (s Scheme) Start(t time.Time) time.Time {
+	t = t.UTC()
+	switch s.Period {
+	case Day:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case Month:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// Name returns the partition of base at t, base is returned as is when the
+// rounds are not partitioned.
+func (s Scheme) Name(base string, t time.Time) string {
+	if !s.Enabled() {
+		return base
+	}
+	return base + "_" + t.UTC().Format(layouts[s.Period])
+}
+
+// NameOf returns the partition of base for a block timestamp in seconds.
+func (s Scheme) NameOf(base string, timestamp int64) string {
+	return s.Name(base, time.Unix(timestamp, 0))
+}
+
+// Parse returns the start of the period of name, a partition of base, or
+// false if name is not a partition of base with this period.
+func (s Scheme) Parse(base, name string) (time.Time, bool) {
+	layout := layouts[s.Period]
+	if layout == "" || !strings.HasPrefix(name, base+"_") {
+		return time.Time{}, false
+	}
+	suffix := strings.TrimPrefix(name, base+"_")
+	if len(suffix) != len(layout) {
+		return time.Time{}, false
+	}
+	start, err := time.Parse(layout, suffix)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return start, true
+}
+
+// Expired returns the partitions of base among names which are past the
+// retention at t, oldest first. Other names are ignored.
+func (s Scheme) Expired(base string, names []string, t time.Time) []string {
+	if !s.Enabled() || s.Retention == 0 {
+		return nil
+	}
+	cutoff := s.add(s.Start(t), 1-s.Retention)
+	var expired []string
+	for _, name := range names {
+		if start, ok := s.Parse(base, name); ok && start.Before(cutoff) {
+			expired = append(expired, name)
+		}
+	}
+	sort.Strings(expired)
+	return expired
+}
+
+// add returns t moved by n periods.
+func (s Scheme) add(t time.Time, n int) time.Time {
+	switch s.Period {
+	case Day:
+		return t.AddDate(0, 0, n)
+	case Month:
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(n, 0, 0)
+	}
+}
+
+// IsPartition reports whether name is a partition of base with any period,
+// for the consumers which do not know the period of the exporter.
+func IsPartition(base, name string) bool {
+	for period := range layouts {
+		if _, ok := (Scheme{Period: period}).Parse(base, name); ok {
+			return true
+		}
+	}
+	return false
+}
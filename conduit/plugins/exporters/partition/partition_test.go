@@ -0,0 +1,47 @@
+package partition
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	assert.NoError(t, Scheme{}.Valid())
+	assert.NoError(t, Scheme{Period: Month, Retention: 12}.Valid())
+	assert.EqualError(t, Scheme{Period: "week"}.Valid(), "period 'week' must be one of day, month or year")
+	assert.EqualError(t, Scheme{Period: Day, Retention: -1}.Valid(), "retention must not be negative")
+}
+
+func TestName(t *testing.T) {
+	ts := time.Date(2024, 5, 17, 23, 30, 0, 0, time.FixedZone("east", 3600))
+	assert.Equal(t, "txns", Scheme{}.Name("txns", ts))
+	assert.Equal(t, "txns_2024_05_17", Scheme{Period: Day}.Name("txns", ts), "the period is in UTC")
+	assert.Equal(t, "txns_2024_05", Scheme{Period: Month}.Name("txns", ts))
+	assert.Equal(t, "txns_2024", Scheme{Period: Year}.Name("txns", ts))
+	assert.Equal(t, "txns_1970_01", Scheme{Period: Month}.NameOf("txns", 0))
+}
+
+func TestParse(t *testing.T) {
+	s := Scheme{Period: Month}
+	start, ok := s.Parse("txns", "txns_2024_05")
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), start)
+	for _, name := range []string{"txns", "txns_2024", "txns_2024_05_17", "txns_2024_13", "blocks_2024_05", "txns_extra_2024_05"} {
+		_, ok = s.Parse("txns", name)
+		assert.False(t, ok, name)
+	}
+	assert.True(t, IsPartition("txns", "txns_2024_05_17"))
+	assert.True(t, IsPartition("txns", "txns_2024"))
+	assert.False(t, IsPartition("txns", "txns_archive"))
+}
+
+func TestExpired(t *testing.T) {
+	names := []string{"txns", "txns_2024_05", "txns_2024_02", "txns_2024_03", "txns_2024_04", "other_2020_01", "txns_2023_12"}
+	now := time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC)
+	assert.Nil(t, Scheme{Period: Month}.Expired("txns", names, now), "every partition is kept")
+	assert.Equal(t, []string{"txns_2023_12", "txns_2024_02"}, Scheme{Period: Month, Retention: 3}.Expired("txns", names, now))
+	assert.Equal(t, []string{"txns_2023_12", "txns_2024_02", "txns_2024_03", "txns_2024_04"}, Scheme{Period: Month, Retention: 1}.Expired("txns", names, now))
+	assert.Equal(t, []string{"txns_2023"}, Scheme{Period: Year, Retention: 1}.Expired("txns", []string{"txns_2023", "txns_2024"}, now))
+}
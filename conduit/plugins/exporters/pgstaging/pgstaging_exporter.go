@@ -0,0 +1,434 @@
+package pgstaging
+
+//go:generate go run ../../../../cmd/conduit-configgen/main.go config.schema.json pgstaging_exporter_config.go
+
+import (
+	"bytes"
+	"context"
+	_ "embed" // used to embed config
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/exporters/partition"
+)
+
+// PluginName to use when configuring.
+const PluginName = "postgresql_staging"
+
+const (
+	// DefaultSchema is the staging schema when none is configured.
+	DefaultSchema = "conduit_staging"
+	// BlocksTable has a row for each staged round, the block is msgpack
+	// encoded. The columns are in this order: round bigint, block bytea.
+	// With partition, the rounds are staged in the tables of the periods
+	// instead, such as blocks_2024_05, which have the same columns.
+	BlocksTable = "blocks"
+	// GenesisTable has one row with the msgpack encoded genesis.
+	GenesisTable = "genesis"
+)
+
+// ValidSchema returns an error if the schema cannot be used as a staging
+// schema, an empty schema is the default one. The schema names are restricted
+// to the identifiers which are not quoted, so that consumers can match the
+// decoded table names.
+func ValidSchema(schema string) error {
+	if schema != "" && !configSchemaPattern.MatchString(schema) {
+		return fmt.Errorf("schema '%s' must be a lower case identifier", schema)
+	}
+	return nil
+}
+
+type stagingExporter struct {
+	round     uint64
+	cfg       Config
+	ctx       context.Context
+	conn      *pgx.Conn
+	logger    *logrus.Logger
+	partition partition.Scheme
+	// tables are the partition tables which were created since Init.
+	tables map[string]bool
+	// twoPhase is set when the server allows prepared transactions, the
+	// rounds are then staged in two phases by Prepare and Commit.
+	twoPhase bool
+	// prepared are the timestamps of the prepared rounds, by token.
+	prepared map[exporters.CommitToken]int64
+}
+
+//go:embed sample.yaml
+var sampleConfig string
+
+var metadata = conduit.Metadata{
+	Name:         PluginName,
+	Description:  "Exporter staging blocks in Postgres for importers consuming them with logical decoding.",
+	Deprecated:   false,
+	SampleConfig: sampleConfig,
+}
+
+func (exp *stagingExporter) Metadata() conduit.Metadata {
+	return metadata
+}
+
+// ValidateConfig checks the config without connecting.
+func (exp *stagingExporter) ValidateConfig(cfg plugins.PluginConfig) error {
+	var scfg Config
+	if err := cfg.DecodeAndValidate(&scfg); err != nil {
+		return fmt.Errorf("ValidateConfig(): %w", err)
+	}
+	return nil
+}
+
+func (exp *stagingExporter) Init(ctx context.Context, initProvider data.InitProvider, cfg plugins.PluginConfig, logger *logrus.Logger) error {
+	exp.ctx = ctx
+	exp.logger = logger
+	if err := cfg.DecodeAndValidate(&exp.cfg); err != nil {
+		return fmt.Errorf("Init(): %w", err)
+	}
+	exp.round = uint64(initProvider.NextDBRound())
+	exp.partition = partition.Scheme{Period: exp.cfg.Partition.Period, Retention: exp.cfg.Partition.Retention}
+	if exp.partition.Enabled() && exp.cfg.RetainRounds > 0 {
+		return fmt.Errorf("Init(): retain-rounds cannot be used with partition, the tables are dropped after the partition retention")
+	}
+	exp.tables = make(map[string]bool)
+
+	var err error
+	if exp.conn, err = pgx.Connect(ctx, exp.cfg.ConnectionString); err != nil {
+		return fmt.Errorf("Init(): unable to connect: %w", err)
+	}
+	if err = exp.createSchema(initProvider.GetGenesis()); err != nil {
+		exp.conn.Close(ctx)
+		return fmt.Errorf("Init(): %w", err)
+	}
+	var maxPrepared string
+	if err = exp.conn.QueryRow(ctx, "SHOW max_prepared_transactions").Scan(&maxPrepared); err != nil {
+		exp.conn.Close(ctx)
+		return fmt.Errorf("Init(): %w", err)
+	}
+	exp.twoPhase = maxPrepared != "0"
+	if !exp.twoPhase {
+		exp.logger.Warnf("max_prepared_transactions is 0, the rounds are staged by Prepare instead of prepared transactions")
+	}
+	exp.prepared = make(map[exporters.CommitToken]int64)
+	return nil
+}
+
+// createSchema creates the staging tables and saves the genesis, the schema
+// must not have been staged for another network.
+func (exp *stagingExporter) createSchema(genesis *sdk.Genesis) error {
+	queries := []string{
+		fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", exp.cfg.Schema),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (round bigint PRIMARY KEY, block bytea NOT NULL)", exp.table(BlocksTable)),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id int PRIMARY KEY CHECK (id = 1), genesis bytea NOT NULL)", exp.table(GenesisTable)),
+	}
+	for _, query := range queries {
+		if _, err := exp.conn.Exec(exp.ctx, query); err != nil {
+			return fmt.Errorf("unable to create the staging schema %s: %w", exp.cfg.Schema, err)
+		}
+	}
+
+	encoded := msgpack.Encode(genesis)
+	query := fmt.Sprintf("INSERT INTO %s (id, genesis) VALUES (1, $1) ON CONFLICT (id) DO NOTHING", exp.table(GenesisTable))
+	if _, err := exp.conn.Exec(exp.ctx, query, encoded); err != nil {
+		return fmt.Errorf("unable to save the genesis: %w", err)
+	}
+	var saved []byte
+	query = fmt.Sprintf("SELECT genesis FROM %s WHERE id = 1", exp.table(GenesisTable))
+	if err := exp.conn.QueryRow(exp.ctx, query).Scan(&saved); err != nil {
+		return fmt.Errorf("unable to read the genesis: %w", err)
+	}
+	if !bytes.Equal(saved, encoded) {
+		var other sdk.Genesis
+		if err := msgpack.Decode(saved, &other); err != nil {
+			return fmt.Errorf("unable to decode the staged genesis: %w", err)
+		}
+		return fmt.Errorf("the staging schema %s has blocks of network %s, not %s", exp.cfg.Schema, other.Network, genesis.Network)
+	}
+	return nil
+}
+
+// table returns the qualified name of a staging table.
+func (exp *stagingExporter) table(name string) string {
+	return exp.cfg.Schema + "." + name
+}
+
+func (exp *stagingExporter) Config() string {
+	ret, _ := yaml.Marshal(exp.cfg)
+	return string(ret)
+}
+
+func (exp *stagingExporter) Close() error {
+	if exp.conn == nil {
+		return nil
+	}
+	return exp.conn.Close(context.Background())
+}
+
+// Receive stages the round and deletes the rounds which are no longer
+// retained, in one transaction. A round which is already staged, for example
+// after a restart, is not changed. With partition, the round is staged in the
+// table of the period of its timestamp, which is created with its first round.
+func (exp *stagingExporter) Receive(exportData data.BlockData) error {
+	if exp.conn == nil {
+		return fmt.Errorf("exporter not initialized")
+	}
+	if err := exp.checkRound(exportData.Round()); err != nil {
+		return fmt.Errorf("Receive(): %w", err)
+	}
+	tx, err := exp.conn.Begin(exp.ctx)
+	if err != nil {
+		return fmt.Errorf("Receive(): %w", conduit.MakeSQLAuthError(err))
+	}
+	defer tx.Rollback(exp.ctx)
+	name, err := exp.stage(tx, exportData)
+	if err != nil {
+		return fmt.Errorf("Receive(): %w", err)
+	}
+	if err = tx.Commit(exp.ctx); err != nil {
+		return fmt.Errorf("Receive(): unable to commit round %d: %w", exportData.Round(), err)
+	}
+	exp.staged(exportData.Round(), name, exportData.BlockHeader.TimeStamp)
+	return nil
+}
+
+// checkRound returns an error unless the round is the next round to stage.
+func (exp *stagingExporter) checkRound(round uint64) error {
+	if round != exp.round {
+		err := fmt.Errorf("wrong block: received round %d, expected round %d", round, exp.round)
+		if round < exp.round {
+			return conduit.MakeDuplicateRoundError(round, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// stage inserts the round in the transaction and deletes the rounds which are
+// no longer retained. It returns the name of the table of the round.
+func (exp *stagingExporter) stage(tx pgx.Tx, exportData data.BlockData) (string, error) {
+	round := exportData.Round()
+	name := exp.partition.NameOf(BlocksTable, exportData.BlockHeader.TimeStamp)
+	if name != BlocksTable && !exp.tables[name] {
+		query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (round bigint PRIMARY KEY, block bytea NOT NULL)", exp.table(name))
+		if _, err := tx.Exec(exp.ctx, query); err != nil {
+			return "", fmt.Errorf("unable to create table %s: %w", name, conduit.MakeSQLAuthError(err))
+		}
+	}
+	query := fmt.Sprintf("INSERT INTO %s (round, block) VALUES ($1, $2) ON CONFLICT (round) DO NOTHING", exp.table(name))
+	if _, err := tx.Exec(exp.ctx, query, int64(round), msgpack.Encode(exportData)); err != nil {
+		return "", fmt.Errorf("unable to stage round %d: %w", round, conduit.MakeSQLAuthError(err))
+	}
+	if !exp.partition.Enabled() && exp.cfg.RetainRounds > 0 && round >= exp.cfg.RetainRounds {
+		query = fmt.Sprintf("DELETE FROM %s WHERE round <= $1", exp.table(BlocksTable))
+		if _, err := tx.Exec(exp.ctx, query, int64(round-exp.cfg.RetainRounds)); err != nil {
+			return "", fmt.Errorf("unable to delete rounds: %w", err)
+		}
+	}
+	return name, nil
+}
+
+// staged advances the exporter past the round, which was committed to the
+// table. The expired tables are dropped when the table was created.
+func (exp *stagingExporter) staged(round uint64, name string, timestamp int64) {
+	if round >= exp.round {
+		exp.round = round + 1
+	}
+	if name == BlocksTable || exp.tables[name] {
+		return
+	}
+	exp.tables[name] = true
+	if err := exp.dropExpired(time.Unix(timestamp, 0)); err != nil {
+		// The round is staged, the tables are dropped with the next period.
+		exp.logger.Warnf("unable to drop the expired tables: %v", err)
+	}
+}
+
+// tokenPattern matches the tokens of the prepared rounds, which are the
+// transaction IDs: conduit.<schema>.<table>.<round>.
+var tokenPattern = regexp.MustCompile(`^conduit\.([a-z_][a-z0-9_]*)\.([a-z_][a-z0-9_]*)\.([0-9]+)$`)
+
+// makeToken returns the token of the round prepared in the table. The
+// transaction IDs are shared by the databases of the server, so they include
+// the schema.
+func (exp *stagingExporter) makeToken(name string, round uint64) exporters.CommitToken {
+	return exporters.CommitToken(fmt.Sprintf("conduit.%s.%s.%d", exp.cfg.Schema, name, round))
+}
+
+// parseToken returns the table and round of a token from the schema.
+func (exp *stagingExporter) parseToken(token exporters.CommitToken) (string, uint64, error) {
+	match := tokenPattern.FindStringSubmatch(string(token))
+	if match == nil || match[1] != exp.cfg.Schema {
+		return "", 0, fmt.Errorf("'%s' is not a token of schema %s", token, exp.cfg.Schema)
+	}
+	round, err := strconv.ParseUint(match[3], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("'%s' is not a token of schema %s: %w", token, exp.cfg.Schema, err)
+	}
+	return match[2], round, nil
+}
+
+// isUndefinedObject reports whether err is a Postgres undefined_object error,
+// which COMMIT PREPARED and ROLLBACK PREPARED return for transactions which
+// are not prepared.
+func isUndefinedObject(err error) bool {
+	var sqlErr interface{ SQLState() string }
+	return errors.As(err, &sqlErr) && sqlErr.SQLState() == "42704"
+}
+
+// Prepare stages the round in a prepared transaction, which is committed by
+// Commit. Without prepared transactions on the server, the round is staged by
+// Receive and the token is empty.
+func (exp *stagingExporter) Prepare(exportData data.BlockData) (exporters.CommitToken, error) {
+	if exp.conn == nil || !exp.twoPhase {
+		return "", exp.Receive(exportData)
+	}
+	round := exportData.Round()
+	if err := exp.checkRound(round); err != nil {
+		return "", fmt.Errorf("Prepare(): %w", err)
+	}
+	tx, err := exp.conn.Begin(exp.ctx)
+	if err != nil {
+		return "", fmt.Errorf("Prepare(): %w", conduit.MakeSQLAuthError(err))
+	}
+	name, err := exp.stage(tx, exportData)
+	if err != nil {
+		tx.Rollback(exp.ctx)
+		return "", fmt.Errorf("Prepare(): %w", err)
+	}
+	token := exp.makeToken(name, round)
+	// PREPARE TRANSACTION ends the transaction of the session, it is not
+	// committed or rolled back by tx.
+	if _, err = tx.Exec(exp.ctx, fmt.Sprintf("PREPARE TRANSACTION '%s'", token)); err != nil {
+		tx.Rollback(exp.ctx)
+		return "", fmt.Errorf("Prepare(): unable to prepare round %d: %w", round, err)
+	}
+	exp.prepared[token] = exportData.BlockHeader.TimeStamp
+	return token, nil
+}
+
+// Commit commits the prepared transaction of the token. A transaction which is
+// no longer prepared was committed if its round is staged.
+func (exp *stagingExporter) Commit(token exporters.CommitToken) error {
+	if exp.conn == nil {
+		return errors.New("exporter not initialized")
+	}
+	name, round, err := exp.parseToken(token)
+	if err != nil {
+		return fmt.Errorf("Commit(): %w", err)
+	}
+	_, err = exp.conn.Exec(exp.ctx, fmt.Sprintf("COMMIT PREPARED '%s'", token))
+	if isUndefinedObject(err) {
+		var staged bool
+		query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE round = $1)", exp.table(name))
+		if err = exp.conn.QueryRow(exp.ctx, query, int64(round)).Scan(&staged); err == nil && !staged {
+			err = fmt.Errorf("round %d is neither prepared nor staged in %s", round, exp.table(name))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("Commit(): unable to commit round %d: %w", round, conduit.MakeSQLAuthError(err))
+	}
+	timestamp, ok := exp.prepared[token]
+	delete(exp.prepared, token)
+	if !ok {
+		// Prepared before a restart, the expired tables are dropped when the
+		// next table is created.
+		exp.tables[name] = true
+	}
+	exp.staged(round, name, timestamp)
+	return nil
+}
+
+// Rollback rolls back the prepared transaction of the token, if it is still
+// prepared.
+func (exp *stagingExporter) Rollback(token exporters.CommitToken) error {
+	if exp.conn == nil {
+		return errors.New("exporter not initialized")
+	}
+	if _, _, err := exp.parseToken(token); err != nil {
+		return fmt.Errorf("Rollback(): %w", err)
+	}
+	delete(exp.prepared, token)
+	if _, err := exp.conn.Exec(exp.ctx, fmt.Sprintf("ROLLBACK PREPARED '%s'", token)); err != nil && !isUndefinedObject(err) {
+		return fmt.Errorf("Rollback(): %w", err)
+	}
+	return nil
+}
+
+// partitions returns the partition tables of the blocks in the schema, with
+// any period.
+func (exp *stagingExporter) partitions() ([]string, error) {
+	rows, err := exp.conn.Query(exp.ctx, "SELECT table_name FROM information_schema.tables WHERE table_schema = $1", exp.cfg.Schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if partition.IsPartition(BlocksTable, name) {
+			names = append(names, name)
+		}
+	}
+	return names, rows.Err()
+}
+
+// dropExpired drops the partition tables which are past the retention at the
+// block time t. Replication slots keep the dropped rounds until their
+// importers consumed them.
+func (exp *stagingExporter) dropExpired(t time.Time) error {
+	names, err := exp.partitions()
+	if err != nil {
+		return err
+	}
+	for _, name := range exp.partition.Expired(BlocksTable, names, t) {
+		if _, err = exp.conn.Exec(exp.ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", exp.table(name))); err != nil {
+			return err
+		}
+		delete(exp.tables, name)
+		exp.logger.Infof("dropped table %s, it is past the partition retention", exp.table(name))
+	}
+	return nil
+}
+
+// RewindRound deletes the staged rounds from the round on, from the blocks
+// table and the partition tables. Importers which already consumed them are
+// not rewound.
+func (exp *stagingExporter) RewindRound(round uint64) error {
+	if exp.conn == nil {
+		return errors.New("exporter not initialized")
+	}
+	names, err := exp.partitions()
+	if err != nil {
+		return fmt.Errorf("RewindRound(): %w", err)
+	}
+	for _, name := range append([]string{BlocksTable}, names...) {
+		query := fmt.Sprintf("DELETE FROM %s WHERE round >= $1", exp.table(name))
+		if _, err = exp.conn.Exec(exp.ctx, query, int64(round)); err != nil {
+			return fmt.Errorf("RewindRound(): %w", err)
+		}
+	}
+	exp.round = round
+	return nil
+}
+
+func init() {
+	exporters.Register(PluginName, exporters.ExporterConstructorFunc(func() exporters.Exporter {
+		return &stagingExporter{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Exporter, PluginName, Config{})
+}
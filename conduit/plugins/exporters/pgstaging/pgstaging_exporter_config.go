@@ -0,0 +1,80 @@
+// Code generated by conduit-configgen. DO NOT EDIT.
+
+package pgstaging
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+//PluginName: conduit_exporters_postgresql_staging
+
+import (
+	"regexp"
+
+	"github.com/algorand/conduit/conduit/plugins"
+)
+
+var (
+	configSchemaPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+)
+
+// Config specific to the postgresql staging exporter
+type Config struct {
+	/* <code>connection-string</code> is the Postgresql connection string of the staging database.<br/>
+	See https://github.com/jackc/pgconn for more details
+	*/
+	ConnectionString string `yaml:"connection-string"`
+	// <code>schema</code> is the staging schema, it is created if it does not exist. Default: "conduit_staging".
+	Schema string `yaml:"schema"`
+	/* <code>retain-rounds</code> deletes the staged rounds which are older than this number of rounds.<br/>
+	Replication slots keep the deleted rounds until their importers consumed them. A value of 0 keeps every round.
+	*/
+	RetainRounds uint64 `yaml:"retain-rounds"`
+	/* <code>partition</code> writes the rounds into a table per period of their block timestamp, such as blocks_2024_05, instead of the blocks table.<br/>
+	The tables are created when their first round is staged, and dropped once they are past the retention.
+	*/
+	Partition PartitionConfig `yaml:"partition"`
+}
+
+// SetDefaults fills in the defaults of the fields which are not set.
+func (cfg *Config) SetDefaults() {
+	if cfg.Schema == "" {
+		cfg.Schema = "conduit_staging"
+	}
+	cfg.Partition.SetDefaults()
+}
+
+// Validate checks the fields, the errors are plugins.FieldErrors.
+func (cfg *Config) Validate() error {
+	var errs plugins.FieldErrors
+	if cfg.ConnectionString == "" {
+		errs.Addf("connection-string", "is required")
+	}
+	if cfg.Schema != "" && !configSchemaPattern.MatchString(cfg.Schema) {
+		errs.Addf("schema", "must match %s", configSchemaPattern)
+	}
+	errs.Merge("partition", cfg.Partition.Validate())
+	return errs.Err()
+}
+
+// PartitionConfig is the config of <code>partition</code>.
+type PartitionConfig struct {
+	// <code>period</code> is the period of the tables: day, month or year. The rounds are not partitioned when it is not set.
+	Period string `yaml:"period"`
+	// <code>retention</code> is the number of periods which are kept, including the current one. A value of 0 keeps every table.
+	Retention int `yaml:"retention"`
+}
+
+// SetDefaults fills in the defaults of the fields which are not set.
+func (cfg *PartitionConfig) SetDefaults() {
+}
+
+// Validate checks the fields, the errors are plugins.FieldErrors.
+func (cfg *PartitionConfig) Validate() error {
+	var errs plugins.FieldErrors
+	if cfg.Period != "" && cfg.Period != "day" && cfg.Period != "month" && cfg.Period != "year" {
+		errs.Addf("period", "must be one of day, month, year")
+	}
+	if cfg.Retention < 0 {
+		errs.Addf("retention", "must be at least 0")
+	}
+	return errs.Err()
+}
@@ -0,0 +1,134 @@
+package pgstaging
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/tools/testutil"
+)
+
+var stagingCons = exporters.ExporterConstructorFunc(func() exporters.Exporter {
+	return &stagingExporter{}
+})
+
+func TestExporterMetadata(t *testing.T) {
+	exp := stagingCons.New()
+	assert.Equal(t, metadata.Name, exp.Metadata().Name)
+	assert.Equal(t, metadata.Description, exp.Metadata().Description)
+	assert.Equal(t, metadata.Deprecated, exp.Metadata().Deprecated)
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		err  string
+	}{
+		{"defaults", Config{ConnectionString: "host=localhost"}, ""},
+		{"schema", Config{ConnectionString: "host=localhost", Schema: "staging_2"}, ""},
+		{"connection", Config{}, "ValidateConfig(): connection-string: is required"},
+		{"quoted schema", Config{ConnectionString: "host=localhost", Schema: "Staging"}, "ValidateConfig(): schema: must match ^[a-z_][a-z0-9_]*$"},
+		{"all errors", Config{Schema: "Staging"}, "ValidateConfig(): connection-string: is required; schema: must match ^[a-z_][a-z0-9_]*$"},
+		{"qualified schema", Config{ConnectionString: "host=localhost", Schema: "db.staging"}, "ValidateConfig(): schema: must match ^[a-z_][a-z0-9_]*$"},
+		{"partition", Config{ConnectionString: "host=localhost", Partition: PartitionConfig{Period: "month", Retention: 12}}, ""},
+		{"partition period", Config{ConnectionString: "host=localhost", Partition: PartitionConfig{Period: "week"}}, "ValidateConfig(): partition.period: must be one of day, month, year"},
+		{"partition retention", Config{ConnectionString: "host=localhost", Partition: PartitionConfig{Period: "day", Retention: -1}}, "ValidateConfig(): partition.retention: must be at least 0"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfgStr, err := yaml.Marshal(tc.cfg)
+			require.NoError(t, err)
+			err = stagingCons.New().(conduit.ConfigValidator).ValidateConfig(plugins.MakePluginConfig(string(cfgStr)))
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	var cfg Config
+	cfg.SetDefaults()
+	assert.Equal(t, DefaultSchema, cfg.Schema)
+}
+
+func TestReceiveNotInitialized(t *testing.T) {
+	assert.EqualError(t, stagingCons.New().Receive(data.BlockData{}), "exporter not initialized")
+}
+
+func TestToken(t *testing.T) {
+	exp := &stagingExporter{cfg: Config{Schema: DefaultSchema}}
+	token := exp.makeToken("blocks_2024_05", 42)
+	assert.Equal(t, exporters.CommitToken("conduit.conduit_staging.blocks_2024_05.42"), token)
+	name, round, err := exp.parseToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "blocks_2024_05", name)
+	assert.Equal(t, uint64(42), round)
+
+	for _, token := range []exporters.CommitToken{"", "txn-42", "conduit.other.blocks.42", "conduit.conduit_staging.blocks.-1", "conduit.conduit_staging.blocks'.42"} {
+		_, _, err = exp.parseToken(token)
+		assert.EqualError(t, err, fmt.Sprintf("'%s' is not a token of schema conduit_staging", token), token)
+	}
+}
+
+// TestTwoPhase stages rounds with prepared transactions, it needs a server
+// with max_prepared_transactions above 0 in TEST_PG.
+func TestTwoPhase(t *testing.T) {
+	connStr := os.Getenv("TEST_PG")
+	if connStr == "" {
+		t.Skip("TEST_PG is not set")
+	}
+	schema := fmt.Sprintf("conduit_test_%d", time.Now().UnixNano())
+	exp := stagingCons.New().(*stagingExporter)
+	logger, _ := test.NewNullLogger()
+	round := sdk.Round(0)
+	cfg := plugins.MakePluginConfig(fmt.Sprintf("connection-string: '%s'\nschema: %s", connStr, schema))
+	require.NoError(t, exp.Init(context.Background(), testutil.MockedInitProvider(&round), cfg, logger))
+	defer exp.Close()
+	if !exp.twoPhase {
+		t.Skip("max_prepared_transactions is 0")
+	}
+	conn, err := pgx.Connect(context.Background(), connStr)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+	defer conn.Exec(context.Background(), fmt.Sprintf("DROP SCHEMA %s CASCADE", schema))
+	staged := func(round uint64) bool {
+		var found bool
+		query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s.blocks WHERE round = $1)", schema)
+		require.NoError(t, conn.QueryRow(context.Background(), query, int64(round)).Scan(&found))
+		return found
+	}
+
+	token, err := exp.Prepare(data.BlockData{BlockHeader: sdk.BlockHeader{Round: 0}})
+	require.NoError(t, err)
+	assert.Equal(t, exp.makeToken(BlocksTable, 0), token)
+	assert.False(t, staged(0))
+	require.NoError(t, exp.Commit(token))
+	assert.True(t, staged(0))
+	// A committed token is committed again after a restart.
+	require.NoError(t, exp.Commit(token))
+
+	token, err = exp.Prepare(data.BlockData{BlockHeader: sdk.BlockHeader{Round: 1}})
+	require.NoError(t, err)
+	require.NoError(t, exp.Rollback(token))
+	require.NoError(t, exp.Rollback(token))
+	assert.ErrorContains(t, exp.Commit(token), "round 1 is neither prepared nor staged")
+	assert.False(t, staged(1))
+}
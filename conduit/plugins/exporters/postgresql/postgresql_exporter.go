@@ -89,10 +89,12 @@ func (exp *postgresqlExporter) Init(ctx context.Context, initProvider data.InitP
 	if err != nil {
 		return fmt.Errorf("error getting next db round : %v", err)
 	}
+	// The database is authoritative over the next round, the pipeline adopts
+	// it through NextRound.
 	if uint64(initProvider.NextDBRound()) != dbRound {
-		return fmt.Errorf("initializing block round %d but next round to account is %d", initProvider.NextDBRound(), dbRound)
+		exp.logger.Warnf("initializing block round %d but next round to account is %d", initProvider.NextDBRound(), dbRound)
 	}
-	exp.round = uint64(initProvider.NextDBRound())
+	exp.round = dbRound
 
 	// if data pruning is enabled
 	if !exp.cfg.Test && exp.cfg.Delete.Rounds > 0 {
@@ -103,6 +105,11 @@ func (exp *postgresqlExporter) Init(ctx context.Context, initProvider data.InitP
 	return nil
 }
 
+// NextRound returns the next round to account in the database.
+func (exp *postgresqlExporter) NextRound() (uint64, error) {
+	return exp.db.GetNextRoundToAccount()
+}
+
 func (exp *postgresqlExporter) Config() string {
 	ret, _ := yaml.Marshal(exp.cfg)
 	return string(ret)
@@ -119,6 +126,9 @@ func (exp *postgresqlExporter) Close() error {
 }
 
 func (exp *postgresqlExporter) Receive(exportData data.BlockData) error {
+	if next := atomic.LoadUint64(&exp.round); exportData.Round() < next {
+		return conduit.MakeDuplicateRoundError(exportData.Round(), fmt.Errorf("Receive(): the next round to account in the database is %d", next))
+	}
 	if exportData.Delta == nil {
 		if exportData.Round() == 0 {
 			exportData.Delta = &sdk.LedgerStateDelta{}
@@ -131,7 +141,7 @@ func (exp *postgresqlExporter) Receive(exportData data.BlockData) error {
 		Delta: *exportData.Delta,
 	}
 	if err := exp.db.AddBlock(&vb); err != nil {
-		return err
+		return conduit.MakeSQLAuthError(err)
 	}
 	atomic.StoreUint64(&exp.round, exportData.Round()+1)
 	return nil
@@ -141,4 +151,5 @@ func init() {
 	exporters.Register(PluginName, exporters.ExporterConstructorFunc(func() exporters.Exporter {
 		return &postgresqlExporter{}
 	}))
+	plugins.RegisterConfigSchema(plugins.Exporter, PluginName, ExporterConfig{})
 }
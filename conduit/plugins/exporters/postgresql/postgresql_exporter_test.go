@@ -2,6 +2,7 @@ package postgresql
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -13,6 +14,7 @@ import (
 	sdk "github.com/algorand/go-algorand-sdk/v2/types"
 	_ "github.com/algorand/indexer/idb/dummy"
 
+	"github.com/algorand/conduit/conduit"
 	"github.com/algorand/conduit/conduit/data"
 	"github.com/algorand/conduit/conduit/plugins"
 	"github.com/algorand/conduit/conduit/plugins/exporters"
@@ -97,6 +99,24 @@ func TestReceiveAddBlockSuccess(t *testing.T) {
 	assert.NoError(t, pgsqlExp.Receive(block))
 }
 
+func TestReceiveDuplicateRound(t *testing.T) {
+	pgsqlExp := pgsqlConstructor.New()
+	cfg := plugins.MakePluginConfig("test: true")
+	assert.NoError(t, pgsqlExp.Init(context.Background(), testutil.MockedInitProvider(&round), cfg, logger))
+
+	block := data.BlockData{
+		BlockHeader: sdk.BlockHeader{},
+		Payset:      sdk.Payset{},
+		Certificate: &map[string]interface{}{},
+		Delta:       &sdk.LedgerStateDelta{},
+	}
+	require.NoError(t, pgsqlExp.Receive(block))
+	err := pgsqlExp.Receive(block)
+	var dupErr *conduit.DuplicateRoundError
+	require.True(t, errors.As(err, &dupErr))
+	assert.EqualError(t, err, "round 0 already exported: Receive(): the next round to account in the database is 1")
+}
+
 func TestPostgresqlExporterInit(t *testing.T) {
 	pgsqlExp := pgsqlConstructor.New()
 	cfg := plugins.MakePluginConfig("test: true")
@@ -109,10 +129,13 @@ func TestPostgresqlExporterInit(t *testing.T) {
 	err := pgsqlExp.Init(context.Background(), initProvider, cfg, logger)
 	assert.Contains(t, err.Error(), "error importing genesis: genesis hash not matching")
 
-	// incorrect round
+	// the next round to account in the database is adopted
 	round = 1
 	err = pgsqlExp.Init(context.Background(), testutil.MockedInitProvider(&round), cfg, logger)
-	assert.Contains(t, err.Error(), "initializing block round 1 but next round to account is 0")
+	assert.NoError(t, err)
+	next, err := pgsqlExp.(conduit.RoundProvider).NextRound()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), next)
 }
 
 func TestUnmarshalConfigsContainingDeleteTask(t *testing.T) {
@@ -0,0 +1,151 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+// expectedSchema lists the tables and columns written by the exporter, with
+// their information_schema data types. Extra tables and columns are allowed.
+var expectedSchema = map[string]map[string]string{
+	"block_header": {
+		"round":        "bigint",
+		"realtime":     "timestamp without time zone",
+		"rewardslevel": "bigint",
+		"header":       "jsonb",
+	},
+	"txn": {
+		"round":    "bigint",
+		"intra":    "integer",
+		"typeenum": "smallint",
+		"asset":    "bigint",
+		"txid":     "bytea",
+		"txn":      "jsonb",
+		"extra":    "jsonb",
+	},
+	"txn_participation": {
+		"addr":  "bytea",
+		"round": "bigint",
+		"intra": "integer",
+	},
+	"account": {
+		"addr":          "bytea",
+		"microalgos":    "bigint",
+		"rewardsbase":   "bigint",
+		"rewards_total": "bigint",
+		"deleted":       "boolean",
+		"created_at":    "bigint",
+		"closed_at":     "bigint",
+		"account_data":  "jsonb",
+	},
+	"account_asset": {
+		"addr":       "bytea",
+		"assetid":    "bigint",
+		"amount":     "numeric",
+		"frozen":     "boolean",
+		"deleted":    "boolean",
+		"created_at": "bigint",
+		"closed_at":  "bigint",
+	},
+	"asset": {
+		"id":           "bigint",
+		"creator_addr": "bytea",
+		"params":       "jsonb",
+		"deleted":      "boolean",
+		"created_at":   "bigint",
+		"closed_at":    "bigint",
+	},
+	"app": {
+		"id":         "bigint",
+		"creator":    "bytea",
+		"params":     "jsonb",
+		"deleted":    "boolean",
+		"created_at": "bigint",
+		"closed_at":  "bigint",
+	},
+	"account_app": {
+		"addr":       "bytea",
+		"app":        "bigint",
+		"localstate": "jsonb",
+		"deleted":    "boolean",
+		"created_at": "bigint",
+		"closed_at":  "bigint",
+	},
+	"app_box": {
+		"app":   "bigint",
+		"name":  "bytea",
+		"value": "bytea",
+	},
+	"metastate": {
+		"k": "text",
+		"v": "jsonb",
+	},
+}
+
+const schemaQuery = `SELECT table_name, column_name, data_type FROM information_schema.columns WHERE table_schema = current_schema()`
+
+// VerifySchema checks that the tables and columns written by the exporter
+// still exist with the expected types.
+func (exp *postgresqlExporter) VerifySchema() error {
+	if exp.cfg.Test {
+		return nil
+	}
+	conn, err := pgx.Connect(exp.ctx, exp.cfg.ConnectionString)
+	if err != nil {
+		return fmt.Errorf("VerifySchema(): unable to connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	rows, err := conn.Query(exp.ctx, schemaQuery)
+	if err != nil {
+		return fmt.Errorf("VerifySchema(): unable to read schema: %w", err)
+	}
+	defer rows.Close()
+	actual := make(map[string]map[string]string)
+	for rows.Next() {
+		var table, column, dataType string
+		if err = rows.Scan(&table, &column, &dataType); err != nil {
+			return fmt.Errorf("VerifySchema(): unable to read schema: %w", err)
+		}
+		if actual[table] == nil {
+			actual[table] = make(map[string]string)
+		}
+		actual[table][column] = dataType
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("VerifySchema(): unable to read schema: %w", err)
+	}
+
+	if differences := compareSchema(expectedSchema, actual); len(differences) > 0 {
+		return &conduit.SchemaDriftError{Differences: differences}
+	}
+	return nil
+}
+
+// compareSchema returns the missing and mismatched tables and columns of
+// actual, sorted so that the error is stable.
+func compareSchema(expected, actual map[string]map[string]string) []string {
+	var differences []string
+	for table, columns := range expected {
+		actualColumns, ok := actual[table]
+		if !ok {
+			differences = append(differences, fmt.Sprintf("table %s is missing", table))
+			continue
+		}
+		for column, dataType := range columns {
+			actualType, ok := actualColumns[column]
+			if !ok {
+				differences = append(differences, fmt.Sprintf("column %s.%s is missing", table, column))
+			} else if actualType != dataType {
+				differences = append(differences, fmt.Sprintf("column %s.%s is %s, expected %s", table, column, actualType, dataType))
+			}
+		}
+	}
+	sort.Strings(differences)
+	return differences
+}
@@ -0,0 +1,57 @@
+package postgresql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareSchema(t *testing.T) {
+	expected := map[string]map[string]string{
+		"block_header": {"round": "bigint", "header": "jsonb"},
+		"txn":          {"round": "bigint", "txn": "jsonb"},
+	}
+	tests := []struct {
+		name     string
+		actual   map[string]map[string]string
+		expected []string
+	}{
+		{
+			name: "match",
+			actual: map[string]map[string]string{
+				"block_header": {"round": "bigint", "header": "jsonb", "extra": "text"},
+				"txn":          {"round": "bigint", "txn": "jsonb"},
+				"other":        {"id": "bigint"},
+			},
+		},
+		{
+			name: "missing table",
+			actual: map[string]map[string]string{
+				"block_header": {"round": "bigint", "header": "jsonb"},
+			},
+			expected: []string{"table txn is missing"},
+		},
+		{
+			name: "missing and mismatched columns",
+			actual: map[string]map[string]string{
+				"block_header": {"round": "integer"},
+				"txn":          {"round": "bigint", "txn": "jsonb"},
+			},
+			expected: []string{
+				"column block_header.header is missing",
+				"column block_header.round is integer, expected bigint",
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, compareSchema(expected, tc.actual))
+		})
+	}
+}
+
+func TestVerifySchemaTestMode(t *testing.T) {
+	exp := &postgresqlExporter{cfg: ExporterConfig{Test: true}}
+	assert.NoError(t, exp.VerifySchema())
+}
@@ -0,0 +1,64 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+// versionsMetastateKey is the metastate row holding the versions of the
+// pipeline which wrote the database.
+const versionsMetastateKey = "conduit_versions"
+
+// RecordedVersions reads the versions saved in the metastate table.
+func (exp *postgresqlExporter) RecordedVersions() (*conduit.WriterVersions, error) {
+	if exp.cfg.Test {
+		return nil, nil
+	}
+	conn, err := pgx.Connect(exp.ctx, exp.cfg.ConnectionString)
+	if err != nil {
+		return nil, fmt.Errorf("RecordedVersions(): unable to connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	var encoded []byte
+	err = conn.QueryRow(exp.ctx, `SELECT v FROM metastate WHERE k = $1`, versionsMetastateKey).Scan(&encoded)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("RecordedVersions(): unable to read metastate: %w", err)
+	}
+	var versions conduit.WriterVersions
+	if err = json.Unmarshal(encoded, &versions); err != nil {
+		return nil, fmt.Errorf("RecordedVersions(): unable to decode %s: %w", versionsMetastateKey, err)
+	}
+	return &versions, nil
+}
+
+// RecordVersions saves the versions in the metastate table.
+func (exp *postgresqlExporter) RecordVersions(versions conduit.WriterVersions) error {
+	if exp.cfg.Test {
+		return nil
+	}
+	encoded, err := json.Marshal(versions)
+	if err != nil {
+		return fmt.Errorf("RecordVersions(): unable to encode versions: %w", err)
+	}
+	conn, err := pgx.Connect(exp.ctx, exp.cfg.ConnectionString)
+	if err != nil {
+		return fmt.Errorf("RecordVersions(): unable to connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(exp.ctx, `INSERT INTO metastate (k, v) VALUES ($1, $2) ON CONFLICT (k) DO UPDATE SET v = EXCLUDED.v`, versionsMetastateKey, string(encoded))
+	if err != nil {
+		return fmt.Errorf("RecordVersions(): unable to write metastate: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// errClosed is returned to the readers once the exporter is closed.
+var errClosed = errors.New("the stream is closed")
+
+// behindError is returned to a reader whose cursor is older than the buffer.
+type behindError struct {
+	cursor, first uint64
+}
+
+func (e behindError) Error() string {
+	return fmt.Sprintf("round %d is no longer buffered, the oldest buffered round is %d", e.cursor, e.first)
+}
+
+// message is an encoded round.
+type message struct {
+	round uint64
+	data  []byte
+	// skipped is set for a round which is not sent to the clients.
+	skipped bool
+}
+
+// hub is a ring buffer of the last exported rounds. Every connection reads it
+// with its own cursor, so a slow client never holds back the pipeline or the
+// other clients.
+type hub struct {
+	mu   sync.Mutex
+	ring []message
+	// count is the number of buffered rounds, they are the rounds before next.
+	count int
+	next  uint64
+	// notify is closed, and replaced, when a round is published.
+	notify chan struct{}
+	closed bool
+}
+
+func makeHub(size int, next uint64) *hub {
+	return &hub{ring: make([]message, size), next: next, notify: make(chan struct{})}
+}
+
+// publish buffers the round, replacing the oldest one when the buffer is full,
+// and wakes up the readers.
+func (h *hub) publish(round uint64, data []byte) {
+	h.add(message{round: round, data: data})
+}
+
+// skip buffers the round without sending it to the readers, so that the
+// buffered rounds stay consecutive.
+func (h *hub) skip(round uint64) {
+	h.add(message{round: round, skipped: true})
+}
+
+func (h *hub) add(msg message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if msg.round != h.next {
+		// The rounds are consecutive unless the pipeline was rewound.
+		h.count = 0
+	}
+	if h.count < len(h.ring) {
+		h.count++
+	}
+	h.ring[msg.round%uint64(len(h.ring))] = msg
+	h.next = msg.round + 1
+	close(h.notify)
+	h.notify = make(chan struct{})
+}
+
+// read returns the buffered rounds from cursor on, and a channel which is
+// closed once another round is published. A cursor after the last round
+// returns no rounds.
+func (h *hub) read(cursor uint64) ([]message, <-chan struct{}, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, nil, errClosed
+	}
+	first := h.next - uint64(h.count)
+	if cursor < first {
+		return nil, nil, behindError{cursor: cursor, first: first}
+	}
+	var msgs []message
+	for round := cursor; round < h.next; round++ {
+		if msg := h.ring[round%uint64(len(h.ring))]; !msg.skipped {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, h.notify, nil
+}
+
+// nextRound returns the round which will be published next.
+func (h *hub) nextRound() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.next
+}
+
+// close wakes up the readers, which then return errClosed.
+func (h *hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.closed {
+		h.closed = true
+		close(h.notify)
+	}
+}
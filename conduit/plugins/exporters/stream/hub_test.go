@@ -0,0 +1,68 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rounds(msgs []message) (result []uint64) {
+	for _, msg := range msgs {
+		result = append(result, msg.round)
+	}
+	return result
+}
+
+func TestHub(t *testing.T) {
+	h := makeHub(3, 10)
+	msgs, wait, err := h.read(10)
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+
+	h.publish(10, []byte("10"))
+	select {
+	case <-wait:
+	default:
+		t.Fatal("publish did not wake up the reader")
+	}
+	for round := uint64(11); round < 14; round++ {
+		h.publish(round, nil)
+	}
+	assert.Equal(t, uint64(14), h.nextRound())
+
+	// The buffer keeps the last three rounds.
+	msgs, _, err = h.read(11)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{11, 12, 13}, rounds(msgs))
+	msgs, _, err = h.read(13)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{13}, rounds(msgs))
+	msgs, _, err = h.read(20)
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+	_, _, err = h.read(10)
+	assert.EqualError(t, err, "round 10 is no longer buffered, the oldest buffered round is 11")
+
+	// A rewound pipeline restarts the buffer.
+	h.publish(5, nil)
+	_, _, err = h.read(4)
+	assert.Error(t, err)
+	msgs, _, err = h.read(5)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{5}, rounds(msgs))
+
+	// Skipped rounds are not read.
+	h.skip(6)
+	h.publish(7, nil)
+	msgs, _, err = h.read(5)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{5, 7}, rounds(msgs))
+
+	_, wait, err = h.read(8)
+	require.NoError(t, err)
+	h.close()
+	<-wait
+	_, _, err = h.read(8)
+	assert.ErrorIs(t, err, errClosed)
+}
@@ -0,0 +1,476 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	_ "embed" // used to embed config
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit"
+	conduitcodec "github.com/algorand/conduit/conduit/codec"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+)
+
+// PluginName to use when configuring.
+const PluginName = "stream"
+
+const (
+	defaultListenAddr   = "127.0.0.1:8900"
+	defaultPath         = "/blocks"
+	defaultBufferSize   = 100
+	defaultKeepalive    = 30 * time.Second
+	defaultWriteTimeout = 10 * time.Second
+	// maxClientMessage limits the messages read from the WebSocket clients,
+	// which are discarded.
+	maxClientMessage = 1 << 16
+	// closeBehind is the close code sent when the client fell behind the
+	// buffer.
+	closeBehind websocket.StatusCode = 4000
+)
+
+type streamExporter struct {
+	round  uint64
+	cfg    Config
+	logger *logrus.Logger
+	hub    *hub
+
+	listener net.Listener
+	server   *http.Server
+	// wg tracks the handlers, the WebSocket connections are not closed by
+	// the server. closing is set by Close.
+	handlersMu sync.Mutex
+	closing    bool
+	wg         sync.WaitGroup
+	// encoding writes binary fields with the pipeline binary-encoding, it is
+	// nil for the JSON encoding of the codec registry, with base64.
+	encoding conduitcodec.Encoding
+	// amountFormat rewrites the amounts, it is nil for the default integer
+	// encoding.
+	amountFormat *conduit.AmountFormat
+	// checker validates each round before it is published, it is nil unless
+	// an output-schema is configured.
+	checker conduit.PayloadChecker
+	// meter counts the traffic with the clients, it is nil unless the
+	// pipeline accounts for the bandwidth.
+	meter conduit.TrafficMeter
+}
+
+//go:embed sample.yaml
+var sampleFile string
+
+var metadata = conduit.Metadata{
+	Name:         PluginName,
+	Description:  "Exporter for streaming blocks to WebSocket and Server-Sent Events clients.",
+	Deprecated:   false,
+	SampleConfig: sampleFile,
+}
+
+func (exp *streamExporter) Metadata() conduit.Metadata {
+	return metadata
+}
+
+// ValidateConfig checks the config without listening.
+func (exp *streamExporter) ValidateConfig(cfg plugins.PluginConfig) error {
+	var scfg Config
+	if err := cfg.UnmarshalConfig(&scfg); err != nil {
+		return fmt.Errorf("ValidateConfig(): %w", err)
+	}
+	if err := scfg.setDefaults(); err != nil {
+		return fmt.Errorf("ValidateConfig(): %w", err)
+	}
+	return nil
+}
+
+func (exp *streamExporter) Init(_ context.Context, initProvider data.InitProvider, cfg plugins.PluginConfig, logger *logrus.Logger) error {
+	exp.logger = logger
+	if err := cfg.UnmarshalConfig(&exp.cfg); err != nil {
+		return fmt.Errorf("connect failure in unmarshalConfig: %w", err)
+	}
+	if err := exp.cfg.setDefaults(); err != nil {
+		return fmt.Errorf("Init(): %w", err)
+	}
+	exp.round = uint64(initProvider.NextDBRound())
+	exp.hub = makeHub(exp.cfg.BufferSize, exp.round)
+
+	var err error
+	if exp.listener, err = net.Listen("tcp", exp.cfg.ListenAddr); err != nil {
+		return fmt.Errorf("Init(): %w", err)
+	}
+	exp.listener = conduit.MeteredListener(exp.listener, exp.meter)
+	mux := http.NewServeMux()
+	mux.HandleFunc(exp.cfg.Path, exp.serveStream)
+	exp.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		var err error
+		if exp.cfg.TLS.CertFile != "" {
+			err = exp.server.ServeTLS(exp.listener, exp.cfg.TLS.CertFile, exp.cfg.TLS.KeyFile)
+		} else {
+			err = exp.server.Serve(exp.listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			exp.logger.Errorf("stream server error: %v", err)
+		}
+	}()
+	exp.logger.Infof("Streaming blocks on %s%s", exp.listener.Addr(), exp.cfg.Path)
+	return nil
+}
+
+// setDefaults fills in the defaults and validates the configuration.
+func (cfg *Config) setDefaults() error {
+	if cfg.ListenAddr == "" {
+		cfg.ListenAddr = defaultListenAddr
+	}
+	if cfg.Path == "" {
+		cfg.Path = defaultPath
+	}
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = defaultBufferSize
+	}
+	if cfg.Keepalive == 0 {
+		cfg.Keepalive = defaultKeepalive
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = defaultWriteTimeout
+	}
+
+	if !strings.HasPrefix(cfg.Path, "/") {
+		return fmt.Errorf("path must start with '/', found '%s'", cfg.Path)
+	}
+	if cfg.BufferSize < 0 {
+		return fmt.Errorf("buffer-size must be positive")
+	}
+	if cfg.Keepalive < 0 || cfg.WriteTimeout < 0 {
+		return fmt.Errorf("keepalive and write-timeout must not be negative")
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		return fmt.Errorf("tls requires both cert-file and key-file")
+	}
+	return nil
+}
+
+func (exp *streamExporter) Config() string {
+	cfg := exp.cfg
+	if cfg.Token != "" {
+		cfg.Token = "********"
+	}
+	ret, _ := yaml.Marshal(cfg)
+	return string(ret)
+}
+
+// Close disconnects the clients and stops the server.
+func (exp *streamExporter) Close() error {
+	if exp.server == nil {
+		return nil
+	}
+	// The handlers return once the hub is closed, so that the SSE responses
+	// end cleanly.
+	exp.hub.close()
+	ctx, cf := context.WithTimeout(context.Background(), exp.cfg.WriteTimeout)
+	defer cf()
+	err := exp.server.Shutdown(ctx)
+	if err != nil {
+		err = exp.server.Close()
+	}
+	exp.handlersMu.Lock()
+	exp.closing = true
+	exp.handlersMu.Unlock()
+	// The WebSocket handlers close their connections once the hub is closed.
+	exp.wg.Wait()
+	return err
+}
+
+// track registers a handler with Close, it returns false once the exporter is
+// closing.
+func (exp *streamExporter) track() bool {
+	exp.handlersMu.Lock()
+	defer exp.handlersMu.Unlock()
+	if exp.closing {
+		return false
+	}
+	exp.wg.Add(1)
+	return true
+}
+
+// untrack is called when a handler returns.
+func (exp *streamExporter) untrack() {
+	exp.wg.Done()
+}
+
+// SetBinaryEncoding writes binary fields of the blocks with the encoding.
+func (exp *streamExporter) SetBinaryEncoding(encoding conduit.BinaryEncoding) {
+	exp.encoding = conduitcodec.NewJSONEncoding(encoding.JSONHandle(0))
+}
+
+// SetAmountFormat rewrites the amounts of the blocks with the format.
+func (exp *streamExporter) SetAmountFormat(format *conduit.AmountFormat) {
+	exp.amountFormat = format
+}
+
+// SetPayloadChecker checks each round before it is published. Rejected
+// rounds are skipped by the clients.
+func (exp *streamExporter) SetPayloadChecker(checker conduit.PayloadChecker) {
+	exp.checker = checker
+}
+
+// SetTrafficMeter counts the bytes exchanged with the clients, by listen
+// address.
+func (exp *streamExporter) SetTrafficMeter(meter conduit.TrafficMeter) {
+	exp.meter = meter
+}
+
+// Receive buffers the round for the clients, it never waits for them.
+func (exp *streamExporter) Receive(exportData data.BlockData) error {
+	if exp.logger == nil {
+		return fmt.Errorf("exporter not initialized")
+	}
+	if exportData.Round() != exp.round {
+		return fmt.Errorf("Receive(): wrong block: received round %d, expected round %d", exportData.Round(), exp.round)
+	}
+	encoding := exp.encoding
+	if encoding == nil {
+		encoding = conduitcodec.Encodings[conduitcodec.JSON]
+	}
+	var buf bytes.Buffer
+	if err := encoding.Encode(&buf, exportData); err != nil {
+		return fmt.Errorf("Receive(): unable to encode round %d: %w", exp.round, err)
+	}
+	msg := bytes.TrimSpace(buf.Bytes())
+	if exp.amountFormat != nil {
+		var err error
+		if msg, err = exp.amountFormat.Rewrite(msg, ""); err != nil {
+			return fmt.Errorf("Receive(): unable to encode round %d: %w", exp.round, err)
+		}
+	}
+	if exp.checker != nil {
+		ok, err := exp.checker.Check(exp.round, msg)
+		if err != nil {
+			return fmt.Errorf("Receive(): %w", err)
+		}
+		if !ok {
+			exp.logger.Warnf("Round %d was rejected by the payload checker and was not published", exp.round)
+			exp.hub.skip(exp.round)
+			exp.round++
+			return nil
+		}
+	}
+	exp.hub.publish(exp.round, msg)
+	exp.round++
+	return nil
+}
+
+// authorized checks the bearer token of the request.
+func (exp *streamExporter) authorized(r *http.Request) bool {
+	if exp.cfg.Token == "" {
+		return true
+	}
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(exp.cfg.Token)) == 1
+}
+
+// cursor returns the first round to send: the from query parameter, the round
+// after the SSE Last-Event-ID of a reconnecting client, or the next round.
+func (exp *streamExporter) cursor(r *http.Request) (uint64, error) {
+	if from := r.URL.Query().Get("from"); from != "" {
+		round, err := strconv.ParseUint(from, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid from round '%s'", from)
+		}
+		return round, nil
+	}
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		round, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid Last-Event-ID '%s'", id)
+		}
+		return round + 1, nil
+	}
+	return exp.hub.nextRound(), nil
+}
+
+func (exp *streamExporter) serveStream(w http.ResponseWriter, r *http.Request) {
+	if !exp.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="conduit"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	cursor, err := exp.cursor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, _, err = exp.hub.read(cursor); err != nil {
+		status := http.StatusServiceUnavailable
+		var behind behindError
+		if errors.As(err, &behind) {
+			status = http.StatusGone
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if isWebSocket(r) {
+		exp.serveWebSocket(w, r, cursor)
+		return
+	}
+	exp.serveSSE(w, r, cursor)
+}
+
+// stream sends the rounds from cursor on until the context is done, the
+// exporter is closed, the client falls behind the buffer or a send fails.
+func (exp *streamExporter) stream(ctx context.Context, cursor uint64, send func(message) error, keepalive func() error) error {
+	ticker := time.NewTicker(exp.cfg.Keepalive)
+	defer ticker.Stop()
+	for {
+		msgs, wait, err := exp.hub.read(cursor)
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			if err = send(msg); err != nil {
+				return err
+			}
+			cursor = msg.round + 1
+		}
+		select {
+		case <-wait:
+		case <-ticker.C:
+			if err = keepalive(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// serveSSE sends each round as a "block" event whose id is the round, so that
+// EventSource clients resume where they stopped when they reconnect.
+func (exp *streamExporter) serveSSE(w http.ResponseWriter, r *http.Request, cursor uint64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	if !exp.track() {
+		http.Error(w, errClosed.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer exp.untrack()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(msg message) error {
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: block\ndata: %s\n\n", msg.round, msg.data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	keepalive := func() error {
+		if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	err := exp.stream(r.Context(), cursor, send, keepalive)
+	var behind behindError
+	if errors.As(err, &behind) {
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", err)
+		flusher.Flush()
+		exp.logger.Warnf("Disconnected stream client %s: %v", r.RemoteAddr, err)
+	}
+}
+
+// isWebSocket reports whether the request asks for a WebSocket upgrade.
+func isWebSocket(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serveWebSocket sends each round as a text message.
+func (exp *streamExporter) serveWebSocket(w http.ResponseWriter, r *http.Request, cursor uint64) {
+	// The clients are authenticated by the token rather than their origin.
+	c, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: []string{"*"}})
+	if err != nil {
+		exp.logger.Debugf("WebSocket upgrade from %s failed: %v", r.RemoteAddr, err)
+		return
+	}
+	defer c.CloseNow()
+	if !exp.track() {
+		c.Close(websocket.StatusGoingAway, "conduit is stopping")
+		return
+	}
+	defer exp.untrack()
+	c.SetReadLimit(maxClientMessage)
+
+	// The hijacked connection is not cancelled by the server, the read loop
+	// answers pings and cancels the stream when the client goes away. Client
+	// messages are discarded.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := c.Read(ctx); err != nil {
+				return
+			}
+		}
+	}()
+	send := func(msg message) error {
+		writeCtx, cf := context.WithTimeout(ctx, exp.cfg.WriteTimeout)
+		defer cf()
+		return c.Write(writeCtx, websocket.MessageText, msg.data)
+	}
+	keepalive := func() error {
+		pingCtx, cf := context.WithTimeout(ctx, exp.cfg.WriteTimeout)
+		defer cf()
+		return c.Ping(pingCtx)
+	}
+	err = exp.stream(ctx, cursor, send, keepalive)
+	// The client already closed the connection when the context is cancelled.
+	var behind behindError
+	switch {
+	case errors.As(err, &behind):
+		exp.logger.Warnf("Disconnected stream client %s: %v", r.RemoteAddr, err)
+		c.Close(closeBehind, "the client fell behind the buffer")
+	case errors.Is(err, errClosed):
+		c.Close(websocket.StatusGoingAway, "conduit is stopping")
+	}
+}
+
+func init() {
+	exporters.Register(PluginName, exporters.ExporterConstructorFunc(func() exporters.Exporter {
+		return &streamExporter{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Exporter, PluginName, Config{})
+}
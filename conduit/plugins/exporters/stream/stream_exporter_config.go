@@ -0,0 +1,34 @@
+package stream
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+import "time"
+
+//PluginName: conduit_exporters_stream
+
+// Config specific to the stream exporter
+type Config struct {
+	// <code>listen-addr</code> is the host:port the stream is served on. Default: "127.0.0.1:8900".
+	ListenAddr string `yaml:"listen-addr"`
+	// <code>path</code> is the URL path of the stream. Default: "/blocks".
+	Path string `yaml:"path"`
+	/* <code>token</code> enables authentication. Clients send it in an "Authorization: Bearer" header, or in the
+	token query parameter when the client cannot set headers, such as a browser EventSource.
+	*/
+	Token string `yaml:"token"`
+	// <code>buffer-size</code> is the number of recent rounds kept in memory for late joiners and reconnecting clients. Default: 100.
+	BufferSize int `yaml:"buffer-size"`
+	// <code>keepalive</code> is how often idle connections are pinged. Default: 30s.
+	Keepalive time.Duration `yaml:"keepalive"`
+	// <code>write-timeout</code> limits each write to a WebSocket client, a client which does not keep up is disconnected. Default: 10s.
+	WriteTimeout time.Duration `yaml:"write-timeout"`
+	// <code>tls</code> serves the stream over HTTPS.
+	TLS TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig configures HTTPS.
+type TLSConfig struct {
+	// <code>cert-file</code> and <code>key-file</code> are the PEM certificate and key of the server. TLS is disabled if they are empty.
+	CertFile string `yaml:"cert-file"`
+	KeyFile  string `yaml:"key-file"`
+}
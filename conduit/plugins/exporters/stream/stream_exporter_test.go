@@ -0,0 +1,270 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/coder/websocket"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit"
+	conduitcodec "github.com/algorand/conduit/conduit/codec"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/tools/testutil"
+)
+
+var logger *logrus.Logger
+var streamCons = exporters.ExporterConstructorFunc(func() exporters.Exporter {
+	return &streamExporter{}
+})
+
+func init() {
+	logger, _ = test.NewNullLogger()
+}
+
+// initExporter starts the exporter at round 5 on a random port.
+func initExporter(t *testing.T, cfg Config) *streamExporter {
+	cfg.ListenAddr = "127.0.0.1:0"
+	cfgStr, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	rnd := sdk.Round(5)
+	exp := streamCons.New().(*streamExporter)
+	require.NoError(t, exp.Init(context.Background(), testutil.MockedInitProvider(&rnd), plugins.MakePluginConfig(string(cfgStr)), logger))
+	t.Cleanup(func() { exp.Close() })
+	return exp
+}
+
+func (exp *streamExporter) url(query string) string {
+	return fmt.Sprintf("http://%s%s%s", exp.listener.Addr(), exp.cfg.Path, query)
+}
+
+func testBlock(round uint64) data.BlockData {
+	return data.BlockData{BlockHeader: sdk.BlockHeader{Round: sdk.Round(round)}}
+}
+
+// encoded returns the message of the round.
+func encoded(t *testing.T, round uint64) string {
+	var buf bytes.Buffer
+	require.NoError(t, conduitcodec.Encodings[conduitcodec.JSON].Encode(&buf, testBlock(round)))
+	return strings.TrimSpace(buf.String())
+}
+
+func receiveRounds(t *testing.T, exp *streamExporter, first, last uint64) {
+	for round := first; round <= last; round++ {
+		require.NoError(t, exp.Receive(testBlock(round)))
+	}
+}
+
+func TestExporterMetadata(t *testing.T) {
+	exp := streamCons.New()
+	assert.Equal(t, metadata.Name, exp.Metadata().Name)
+	assert.Equal(t, metadata.Description, exp.Metadata().Description)
+	assert.Equal(t, metadata.Deprecated, exp.Metadata().Deprecated)
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		err  string
+	}{
+		{"defaults", Config{}, ""},
+		{"path", Config{Path: "blocks"}, "ValidateConfig(): path must start with '/', found 'blocks'"},
+		{"buffer", Config{BufferSize: -1}, "ValidateConfig(): buffer-size must be positive"},
+		{"keepalive", Config{Keepalive: -time.Second}, "ValidateConfig(): keepalive and write-timeout must not be negative"},
+		{"tls", Config{TLS: TLSConfig{CertFile: "cert.pem"}}, "ValidateConfig(): tls requires both cert-file and key-file"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfgStr, err := yaml.Marshal(tc.cfg)
+			require.NoError(t, err)
+			err = streamCons.New().(conduit.ConfigValidator).ValidateConfig(plugins.MakePluginConfig(string(cfgStr)))
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestReceiveWrongRound(t *testing.T) {
+	exp := initExporter(t, Config{})
+	err := exp.Receive(testBlock(6))
+	assert.EqualError(t, err, "Receive(): wrong block: received round 6, expected round 5")
+}
+
+func TestConfigHidesToken(t *testing.T) {
+	exp := initExporter(t, Config{Token: "secret"})
+	assert.NotContains(t, exp.Config(), "secret")
+}
+
+func TestAuthAndCursors(t *testing.T) {
+	exp := initExporter(t, Config{Token: "secret", BufferSize: 2})
+	receiveRounds(t, exp, 5, 7)
+
+	tests := []struct {
+		name   string
+		query  string
+		header http.Header
+		status int
+	}{
+		{"no token", "?from=6", nil, http.StatusUnauthorized},
+		{"wrong token", "?from=6", http.Header{"Authorization": {"Bearer wrong"}}, http.StatusUnauthorized},
+		{"query token", "?from=6&token=secret", nil, http.StatusOK},
+		{"header token", "?from=6", http.Header{"Authorization": {"Bearer secret"}}, http.StatusOK},
+		{"not buffered", "?from=5&token=secret", nil, http.StatusGone},
+		{"last event id", "?token=secret", http.Header{"Last-Event-ID": {"5"}}, http.StatusOK},
+		{"invalid from", "?from=x&token=secret", nil, http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, exp.url(tc.query), nil)
+			require.NoError(t, err)
+			for key, values := range tc.header {
+				req.Header[key] = values
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.status, resp.StatusCode)
+		})
+	}
+}
+
+// readEvent reads the next SSE event, skipping comments.
+func readEvent(t *testing.T, r *bufio.Reader) (fields map[string]string) {
+	fields = make(map[string]string)
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" && len(fields) > 0 {
+			return fields
+		}
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		parts := strings.SplitN(line, ": ", 2)
+		require.Len(t, parts, 2)
+		fields[parts[0]] = parts[1]
+	}
+}
+
+func TestServerSentEvents(t *testing.T) {
+	exp := initExporter(t, Config{BufferSize: 10})
+	receiveRounds(t, exp, 5, 6)
+
+	resp, err := http.Get(exp.url("?from=6"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// The buffered round is replayed, then live rounds are streamed.
+	r := bufio.NewReader(resp.Body)
+	event := readEvent(t, r)
+	assert.Equal(t, "6", event["id"])
+	assert.Equal(t, "block", event["event"])
+	assert.Equal(t, encoded(t, 6), event["data"])
+	receiveRounds(t, exp, 7, 7)
+	event = readEvent(t, r)
+	assert.Equal(t, "7", event["id"])
+
+	// Close ends the stream.
+	require.NoError(t, exp.Close())
+	_, err = io.ReadAll(r)
+	assert.NoError(t, err)
+}
+
+func dialWebSocket(t *testing.T, exp *streamExporter, query string) *websocket.Conn {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, fmt.Sprintf("ws://%s%s%s", exp.listener.Addr(), exp.cfg.Path, query), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.CloseNow() })
+	return c
+}
+
+// readMessage reads a text message.
+func readMessage(t *testing.T, c *websocket.Conn) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	typ, data, err := c.Read(ctx)
+	if err != nil {
+		return "", err
+	}
+	assert.Equal(t, websocket.MessageText, typ)
+	return string(data), nil
+}
+
+func TestWebSocket(t *testing.T) {
+	exp := initExporter(t, Config{})
+	receiveRounds(t, exp, 5, 5)
+
+	c := dialWebSocket(t, exp, "?from=5")
+	msg, err := readMessage(t, c)
+	require.NoError(t, err)
+	assert.Equal(t, encoded(t, 5), msg)
+
+	// The pong is read by the pending read.
+	read := make(chan string, 1)
+	go func() {
+		msg, err := readMessage(t, c)
+		assert.NoError(t, err)
+		read <- msg
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, c.Ping(ctx))
+
+	receiveRounds(t, exp, 6, 6)
+	assert.Equal(t, encoded(t, 6), <-read)
+
+	// The closing handshake is echoed.
+	assert.NoError(t, c.Close(websocket.StatusNormalClosure, ""))
+}
+
+func TestWebSocketBehind(t *testing.T) {
+	exp := initExporter(t, Config{BufferSize: 1})
+	c := dialWebSocket(t, exp, "")
+	// Without a cursor the client starts at the next round, the rounds
+	// published before it reads them are dropped from the buffer.
+	receiveRounds(t, exp, 5, 20)
+	for {
+		if _, err := readMessage(t, c); err != nil {
+			assert.Equal(t, closeBehind, websocket.CloseStatus(err), err)
+			break
+		}
+	}
+}
+
+func TestWebSocketClose(t *testing.T) {
+	exp := initExporter(t, Config{})
+	c := dialWebSocket(t, exp, "")
+	// The client reads while the exporter closes, to answer the closing
+	// handshake.
+	read := make(chan error, 1)
+	go func() {
+		_, err := readMessage(t, c)
+		read <- err
+	}()
+	require.NoError(t, exp.Close())
+	assert.Equal(t, websocket.StatusGoingAway, websocket.CloseStatus(<-read))
+}
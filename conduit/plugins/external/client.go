@@ -0,0 +1,245 @@
+package external
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins/external/pluginpb"
+)
+
+// handshakeTimeout is how long a plugin has to write the handshake after it
+// is started.
+var handshakeTimeout = 30 * time.Second
+
+// callTimeout is how long a plugin has to answer a request, so that a hung
+// plugin does not stall the pipeline forever.
+var callTimeout = 5 * time.Minute
+
+// closeTimeout is how long a plugin has to exit after the close request.
+var closeTimeout = 5 * time.Second
+
+// client manages an external plugin process.
+type client struct {
+	name       string
+	executable string
+	args       []string
+	sandbox    *Sandbox
+
+	// ctx is the context of Init, the requests are canceled with it.
+	ctx    context.Context
+	logger *logrus.Logger
+	config string
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	conn    *grpc.ClientConn
+	plugin  pluginpb.PluginClient
+	exited  chan struct{}
+	started bool
+}
+
+func makeClient(name, executable string, args []string, sandbox *Sandbox) *client {
+	return &client{
+		name:       name,
+		executable: executable,
+		args:       args,
+		sandbox:    sandbox,
+	}
+}
+
+// Metadata is available before the process is started.
+func (c *client) Metadata() conduit.Metadata {
+	return conduit.Metadata{
+		Name:        c.name,
+		Description: fmt.Sprintf("External plugin (%s).", c.executable),
+	}
+}
+
+// Config returns the configuration used to initialize the plugin.
+func (c *client) Config() string {
+	return c.config
+}
+
+// forwardLines calls line with each line of r until r is drained.
+func forwardLines(r io.Reader, line func(string)) {
+	br := bufio.NewReader(r)
+	for {
+		s, err := br.ReadString('\n')
+		if s = strings.TrimRight(s, "\r\n"); s != "" {
+			line(s)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// start launches the plugin executable and connects to its service.
+func (c *client) start(ctx context.Context, logger *logrus.Logger) error {
+	c.ctx = ctx
+	c.logger = logger
+	cmd, err := c.sandbox.command(ctx, c.executable, c.args)
+	if err != nil {
+		return fmt.Errorf("start(): external plugin (%s): %w", c.name, err)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("start(): %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("start(): %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("start(): %w", err)
+	}
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("start(): unable to start external plugin (%s): %w", c.executable, err)
+	}
+
+	// The pipes are read to the end before cmd.Wait closes them. The first
+	// line of stdout is the handshake, the rest of the output goes to the
+	// plugin logger.
+	handshake := make(chan string, 1)
+	exited := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(2)
+	go func() {
+		defer readers.Done()
+		forwardLines(stderr, func(line string) { logger.Info(line) })
+	}()
+	go func() {
+		defer readers.Done()
+		first := true
+		forwardLines(stdout, func(line string) {
+			if first {
+				first = false
+				handshake <- line
+				return
+			}
+			logger.Info(line)
+		})
+	}()
+	go func() {
+		readers.Wait()
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	kill := func(err error) error {
+		_ = stdin.Close()
+		_ = cmd.Process.Kill()
+		<-exited
+		return err
+	}
+	var line string
+	select {
+	case line = <-handshake:
+	case <-exited:
+		return fmt.Errorf("start(): external plugin (%s) exited before the handshake", c.name)
+	case <-time.After(handshakeTimeout):
+		return kill(fmt.Errorf("start(): no handshake from external plugin (%s) within %s", c.name, handshakeTimeout))
+	case <-ctx.Done():
+		return kill(fmt.Errorf("start(): external plugin (%s): %w", c.name, ctx.Err()))
+	}
+	network, address, err := parseHandshake(line)
+	if err != nil {
+		return kill(fmt.Errorf("start(): external plugin (%s): %w", c.name, err))
+	}
+	conn, err := grpc.NewClient(network+":"+address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMessageSize), grpc.MaxCallSendMsgSize(maxMessageSize)))
+	if err != nil {
+		return kill(fmt.Errorf("start(): unable to connect to external plugin (%s): %w", c.name, err))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cmd = cmd
+	c.stdin = stdin
+	c.conn = conn
+	c.plugin = pluginpb.NewPluginClient(conn)
+	c.exited = exited
+	c.started = true
+	return nil
+}
+
+// call sends a request with rpc and waits for its response, at most for
+// callTimeout. Requests may be sent concurrently.
+func (c *client) call(method string, rpc func(ctx context.Context, plugin pluginpb.PluginClient) error) error {
+	c.mu.Lock()
+	plugin, started := c.plugin, c.started
+	c.mu.Unlock()
+	if !started {
+		return fmt.Errorf("call(): external plugin (%s) is not running", c.name)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, callTimeout)
+	defer cancel()
+	return c.callError(method, rpc(ctx, plugin))
+}
+
+// callError returns the error of the plugin as it is, and describes the
+// errors of the service.
+func (c *client) callError(method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	st, _ := status.FromError(err)
+	switch st.Code() {
+	case codes.Unknown, codes.Unimplemented, codes.InvalidArgument:
+		return errors.New(st.Message())
+	case codes.DeadlineExceeded:
+		return fmt.Errorf("call(): no %s response from external plugin (%s) within %s", method, c.name, callTimeout)
+	}
+	return fmt.Errorf("call(): %s request to external plugin (%s) failed: %w", method, c.name, err)
+}
+
+// close asks the plugin to shut down, killing it if it does not exit in time.
+func (c *client) close() error {
+	c.mu.Lock()
+	started := c.started
+	c.started = false
+	c.mu.Unlock()
+	if !started {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	_, err := c.plugin.Close(ctx, &pluginpb.Empty{})
+	cancel()
+	err = c.callError(methodClose, err)
+	_ = c.conn.Close()
+	_ = c.stdin.Close()
+	select {
+	case <-c.exited:
+	case <-time.After(closeTimeout):
+		_ = c.cmd.Process.Kill()
+		<-c.exited
+	}
+	return err
+}
+
+// onComplete forwards the OnComplete lifecycle hook.
+func (c *client) onComplete(blk []byte) error {
+	return c.call(methodOnComplete, func(ctx context.Context, plugin pluginpb.PluginClient) error {
+		_, err := plugin.OnComplete(ctx, &pluginpb.Block{Block: blk})
+		return err
+	})
+}
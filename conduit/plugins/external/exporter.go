@@ -0,0 +1,66 @@
+package external
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/external/pluginpb"
+)
+
+type exporter struct {
+	*client
+}
+
+// MakeExporter returns an Exporter backed by an external executable, run in the
+// sandbox if it is not nil.
+func MakeExporter(name, executable string, args []string, sandbox *Sandbox) exporters.Exporter {
+	return &exporter{client: makeClient(name, executable, args, sandbox)}
+}
+
+// Init starts the plugin process and initializes it.
+func (e *exporter) Init(ctx context.Context, initProvider data.InitProvider, cfg plugins.PluginConfig, logger *logrus.Logger) error {
+	e.config = cfg.Config
+	if err := e.start(ctx, logger); err != nil {
+		return err
+	}
+	err := e.call(methodInit, func(ctx context.Context, plugin pluginpb.PluginClient) error {
+		_, err := plugin.Init(ctx, &pluginpb.InitRequest{
+			DataDir:   cfg.DataDir,
+			Config:    cfg.Config,
+			Genesis:   encodeGenesis(initProvider.GetGenesis()),
+			NextRound: uint64(initProvider.NextDBRound()),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Init(): external exporter (%s) failed: %w", e.name, err)
+	}
+	return nil
+}
+
+// Receive sends the block to the plugin.
+func (e *exporter) Receive(exportData data.BlockData) error {
+	err := e.call(methodReceive, func(ctx context.Context, plugin pluginpb.PluginClient) error {
+		_, err := plugin.Receive(ctx, &pluginpb.Block{Block: encodeBlock(exportData)})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Receive(): external exporter (%s) failed: %w", e.name, err)
+	}
+	return nil
+}
+
+// OnComplete forwards the lifecycle hook to the plugin.
+func (e *exporter) OnComplete(input data.BlockData) error {
+	return e.onComplete(encodeBlock(input))
+}
+
+// Close stops the plugin process.
+func (e *exporter) Close() error {
+	return e.close()
+}
@@ -0,0 +1,274 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/external/pluginpb"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// pluginEnv selects which test plugin the test binary serves when re-executed.
+const pluginEnv = "CONDUIT_EXTERNAL_TEST_PLUGIN"
+
+type testImporter struct {
+	importers.Importer
+	network string
+}
+
+func (i *testImporter) Init(_ context.Context, cfg plugins.PluginConfig, _ *logrus.Logger) (*sdk.Genesis, error) {
+	i.network = cfg.Config
+	if cfg.Config == "sandbox" {
+		// Report the working directory and environment of the process.
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		_, home := os.LookupEnv("HOME")
+		i.network = fmt.Sprintf("%s %s %t", wd, os.Getenv("SANDBOX_VALUE"), home)
+	}
+	return &sdk.Genesis{Network: i.network}, nil
+}
+
+func (i *testImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	if rnd == 13 {
+		return data.BlockData{}, fmt.Errorf("unlucky round")
+	}
+	return data.BlockData{BlockHeader: sdk.BlockHeader{Round: sdk.Round(rnd), GenesisID: i.network}}, nil
+}
+
+func (i *testImporter) Close() error {
+	return nil
+}
+
+type testProcessor struct {
+	processors.Processor
+}
+
+func (p *testProcessor) Init(_ context.Context, _ data.InitProvider, _ plugins.PluginConfig, _ *logrus.Logger) error {
+	return nil
+}
+
+func (p *testProcessor) Process(input data.BlockData) (data.BlockData, error) {
+	input.BlockHeader.Round++
+	return input, nil
+}
+
+func (p *testProcessor) Close() error {
+	return nil
+}
+
+// hungRound is a round which the test exporter never finishes receiving.
+const hungRound = 99
+
+type testExporter struct {
+	exporters.Exporter
+	nextRound sdk.Round
+	closed    chan struct{}
+}
+
+func (e *testExporter) Init(_ context.Context, initProvider data.InitProvider, _ plugins.PluginConfig, _ *logrus.Logger) error {
+	e.nextRound = initProvider.NextDBRound()
+	e.closed = make(chan struct{})
+	return nil
+}
+
+func (e *testExporter) Receive(exportData data.BlockData) error {
+	if exportData.BlockHeader.Round == hungRound {
+		<-e.closed
+		return nil
+	}
+	if exportData.BlockHeader.Round != e.nextRound {
+		return fmt.Errorf("expected round %d, got %d", e.nextRound, exportData.BlockHeader.Round)
+	}
+	e.nextRound++
+	return nil
+}
+
+func (e *testExporter) OnComplete(_ data.BlockData) error {
+	return nil
+}
+
+func (e *testExporter) Close() error {
+	if e.closed != nil {
+		close(e.closed)
+	}
+	return nil
+}
+
+func TestMain(m *testing.M) {
+	var err error
+	switch os.Getenv(pluginEnv) {
+	case "importer":
+		err = ServeImporter(&testImporter{})
+	case "processor":
+		err = ServeProcessor(&testProcessor{})
+	case "exporter":
+		err = ServeExporter(&testExporter{})
+	case "exit":
+		fmt.Fprintln(os.Stderr, "plugin failed")
+		os.Exit(1)
+	default:
+		os.Exit(m.Run())
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func testLogger() *logrus.Logger {
+	l, _ := test.NewNullLogger()
+	return l
+}
+
+func TestExternalImporter(t *testing.T) {
+	t.Setenv(pluginEnv, "importer")
+	imp := MakeImporter("test_importer", os.Args[0], nil, nil)
+	assert.Equal(t, "test_importer", imp.Metadata().Name)
+
+	genesis, err := imp.Init(context.Background(), plugins.MakePluginConfig("testnet"), testLogger())
+	require.NoError(t, err)
+	assert.Equal(t, "testnet", genesis.Network)
+	assert.Equal(t, "testnet", imp.Config())
+
+	blk, err := imp.GetBlock(5)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), blk.Round())
+	assert.Equal(t, "testnet", blk.BlockHeader.GenesisID)
+
+	_, err = imp.GetBlock(13)
+	assert.ErrorContains(t, err, "unlucky round")
+
+	require.NoError(t, imp.Close())
+	_, err = imp.GetBlock(5)
+	assert.ErrorContains(t, err, "is not running")
+}
+
+func TestExternalProcessorAndExporter(t *testing.T) {
+	round := sdk.Round(10)
+	initProvider := conduit.MakePipelineInitProvider(&round, &sdk.Genesis{})
+
+	t.Setenv(pluginEnv, "processor")
+	proc := MakeProcessor("test_processor", os.Args[0], nil, nil)
+	require.NoError(t, proc.Init(context.Background(), initProvider, plugins.PluginConfig{}, testLogger()))
+
+	t.Setenv(pluginEnv, "exporter")
+	exp := MakeExporter("test_exporter", os.Args[0], nil, nil)
+	require.NoError(t, exp.Init(context.Background(), initProvider, plugins.PluginConfig{}, testLogger()))
+
+	blk, err := proc.Process(data.BlockData{BlockHeader: sdk.BlockHeader{Round: 9}})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), blk.Round())
+	require.NoError(t, exp.Receive(blk))
+	require.NoError(t, exp.(conduit.Completed).OnComplete(blk))
+
+	// The exporter expects round 11 next.
+	assert.ErrorContains(t, exp.Receive(blk), "expected round 11, got 10")
+
+	require.NoError(t, proc.Close())
+	require.NoError(t, exp.Close())
+}
+
+func TestExternalMissingExecutable(t *testing.T) {
+	exp := MakeExporter("missing", "/does/not/exist", nil, nil)
+	err := exp.Init(context.Background(), conduit.MakePipelineInitProvider(new(sdk.Round), &sdk.Genesis{}), plugins.PluginConfig{}, testLogger())
+	assert.ErrorContains(t, err, "unable to start external plugin (/does/not/exist)")
+	assert.NoError(t, exp.Close())
+}
+
+func TestServeProtocolErrors(t *testing.T) {
+	lis, err := net.Listen("unix", filepath.Join(t.TempDir(), "plugin.sock"))
+	require.NoError(t, err)
+
+	stdin, stdinW := io.Pipe()
+	defer stdinW.Close()
+	s := &server{ctx: context.Background(), logger: testLogger(), exporter: &testExporter{}}
+	served := make(chan error, 1)
+	go func() {
+		served <- s.serve(lis, stdin)
+	}()
+
+	conn, err := grpc.NewClient("unix:"+lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	c := &client{name: "test", plugin: pluginpb.NewPluginClient(conn)}
+	ctx := context.Background()
+
+	_, err = c.plugin.GetBlock(ctx, &pluginpb.GetBlockRequest{Round: 1})
+	assert.EqualError(t, c.callError(methodGetBlock, err), "unsupported method 'get_block'")
+	_, err = c.plugin.Receive(ctx, &pluginpb.Block{Block: []byte("not msgpack")})
+	assert.ErrorContains(t, c.callError(methodReceive, err), "invalid block")
+
+	_, err = c.plugin.Close(ctx, &pluginpb.Empty{})
+	require.NoError(t, err)
+	require.NoError(t, <-served)
+}
+
+func TestServeStdinClosed(t *testing.T) {
+	lis, err := net.Listen("unix", filepath.Join(t.TempDir(), "plugin.sock"))
+	require.NoError(t, err)
+	s := &server{ctx: context.Background(), logger: testLogger(), exporter: &testExporter{}}
+	// The service stops when the pipeline closes stdin.
+	assert.NoError(t, s.serve(lis, strings.NewReader("")))
+}
+
+func TestParseHandshake(t *testing.T) {
+	network, address, err := parseHandshake(formatHandshake("unix", "/tmp/plugin.sock") + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, "unix", network)
+	assert.Equal(t, "/tmp/plugin.sock", address)
+
+	_, _, err = parseHandshake("CONDUIT_PLUGIN|1|unix|/tmp/plugin.sock")
+	assert.EqualError(t, err, "parseHandshake(): unsupported protocol version 1, expected 2")
+	_, _, err = parseHandshake("CONDUIT_PLUGIN|2|tcp|127.0.0.1:1234")
+	assert.EqualError(t, err, "parseHandshake(): unsupported network 'tcp'")
+	_, _, err = parseHandshake("hello")
+	assert.EqualError(t, err, "parseHandshake(): malformed handshake 'hello'")
+}
+
+func TestExternalHungPlugin(t *testing.T) {
+	defer func(timeout time.Duration) { callTimeout = timeout }(callTimeout)
+	callTimeout = 500 * time.Millisecond
+
+	t.Setenv(pluginEnv, "exporter")
+	exp := MakeExporter("test_exporter", os.Args[0], nil, nil)
+	round := sdk.Round(hungRound)
+	require.NoError(t, exp.Init(context.Background(), conduit.MakePipelineInitProvider(&round, &sdk.Genesis{}), plugins.PluginConfig{}, testLogger()))
+
+	err := exp.Receive(data.BlockData{BlockHeader: sdk.BlockHeader{Round: hungRound}})
+	assert.EqualError(t, err, "Receive(): external exporter (test_exporter) failed: call(): no receive response from external plugin (test_exporter) within 500ms")
+
+	// The plugin still answers the other requests.
+	require.NoError(t, exp.(conduit.Completed).OnComplete(data.BlockData{}))
+	require.NoError(t, exp.Close())
+}
+
+func TestExternalExitBeforeHandshake(t *testing.T) {
+	t.Setenv(pluginEnv, "exit")
+	exp := MakeExporter("test_exporter", os.Args[0], nil, nil)
+	err := exp.Init(context.Background(), conduit.MakePipelineInitProvider(new(sdk.Round), &sdk.Genesis{}), plugins.PluginConfig{}, testLogger())
+	assert.EqualError(t, err, "start(): external plugin (test_exporter) exited before the handshake")
+	assert.NoError(t, exp.Close())
+}
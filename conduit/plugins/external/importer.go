@@ -0,0 +1,68 @@
+package external
+
+import (
+	"context"
+	"fmt"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/external/pluginpb"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+type importer struct {
+	*client
+}
+
+// MakeImporter returns an Importer backed by an external executable, run in the
+// sandbox if it is not nil.
+func MakeImporter(name, executable string, args []string, sandbox *Sandbox) importers.Importer {
+	return &importer{client: makeClient(name, executable, args, sandbox)}
+}
+
+// Init starts the plugin process and initializes it.
+func (i *importer) Init(ctx context.Context, cfg plugins.PluginConfig, logger *logrus.Logger) (*sdk.Genesis, error) {
+	i.config = cfg.Config
+	if err := i.start(ctx, logger); err != nil {
+		return nil, err
+	}
+	var resp *pluginpb.InitResponse
+	err := i.call(methodInit, func(ctx context.Context, plugin pluginpb.PluginClient) (err error) {
+		resp, err = plugin.Init(ctx, &pluginpb.InitRequest{DataDir: cfg.DataDir, Config: cfg.Config})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Init(): external importer (%s) failed: %w", i.name, err)
+	}
+	genesis, err := decodeGenesis(resp.GetGenesis())
+	if err != nil {
+		return nil, fmt.Errorf("Init(): external importer (%s) returned an invalid genesis: %w", i.name, err)
+	}
+	return genesis, nil
+}
+
+// GetBlock requests a round from the plugin.
+func (i *importer) GetBlock(rnd uint64) (data.BlockData, error) {
+	var resp *pluginpb.Block
+	err := i.call(methodGetBlock, func(ctx context.Context, plugin pluginpb.PluginClient) (err error) {
+		resp, err = plugin.GetBlock(ctx, &pluginpb.GetBlockRequest{Round: rnd})
+		return err
+	})
+	if err != nil {
+		return data.BlockData{}, fmt.Errorf("GetBlock(): external importer (%s) failed: %w", i.name, err)
+	}
+	return decodeBlock(resp.GetBlock())
+}
+
+// OnComplete forwards the lifecycle hook to the plugin.
+func (i *importer) OnComplete(input data.BlockData) error {
+	return i.onComplete(encodeBlock(input))
+}
+
+// Close stops the plugin process.
+func (i *importer) Close() error {
+	return i.close()
+}
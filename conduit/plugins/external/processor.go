@@ -0,0 +1,67 @@
+package external
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/external/pluginpb"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+type processor struct {
+	*client
+}
+
+// MakeProcessor returns a Processor backed by an external executable, run in the
+// sandbox if it is not nil.
+func MakeProcessor(name, executable string, args []string, sandbox *Sandbox) processors.Processor {
+	return &processor{client: makeClient(name, executable, args, sandbox)}
+}
+
+// Init starts the plugin process and initializes it.
+func (p *processor) Init(ctx context.Context, initProvider data.InitProvider, cfg plugins.PluginConfig, logger *logrus.Logger) error {
+	p.config = cfg.Config
+	if err := p.start(ctx, logger); err != nil {
+		return err
+	}
+	err := p.call(methodInit, func(ctx context.Context, plugin pluginpb.PluginClient) error {
+		_, err := plugin.Init(ctx, &pluginpb.InitRequest{
+			DataDir:   cfg.DataDir,
+			Config:    cfg.Config,
+			Genesis:   encodeGenesis(initProvider.GetGenesis()),
+			NextRound: uint64(initProvider.NextDBRound()),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Init(): external processor (%s) failed: %w", p.name, err)
+	}
+	return nil
+}
+
+// Process sends the block to the plugin and returns the modified block.
+func (p *processor) Process(input data.BlockData) (data.BlockData, error) {
+	var resp *pluginpb.Block
+	err := p.call(methodProcess, func(ctx context.Context, plugin pluginpb.PluginClient) (err error) {
+		resp, err = plugin.Process(ctx, &pluginpb.Block{Block: encodeBlock(input)})
+		return err
+	})
+	if err != nil {
+		return input, fmt.Errorf("Process(): external processor (%s) failed: %w", p.name, err)
+	}
+	return decodeBlock(resp.GetBlock())
+}
+
+// OnComplete forwards the lifecycle hook to the plugin.
+func (p *processor) OnComplete(input data.BlockData) error {
+	return p.onComplete(encodeBlock(input))
+}
+
+// Close stops the plugin process.
+func (p *processor) Close() error {
+	return p.close()
+}
@@ -0,0 +1,90 @@
+// Package external runs importer, processor and exporter plugins as separate
+// executables. The pipeline starts the executable, which serves the gRPC
+// Plugin service of pluginpb/plugin.proto on a unix socket and announces the
+// socket with a handshake line on its stdout. The plugin stops when its stdin
+// is closed. Anything the plugin writes to stderr, and to stdout after the
+// handshake, is forwarded to the pipeline logs.
+//
+// Plugin authors implement the regular plugin interface and call
+// ServeImporter, ServeProcessor or ServeExporter from their main function.
+package external
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit/data"
+)
+
+// ProtocolVersion is incremented whenever the plugin service changes.
+const ProtocolVersion = 2
+
+// Methods of the plugin service, as they are named in the errors.
+const (
+	methodInit       = "init"
+	methodGetBlock   = "get_block"
+	methodProcess    = "process"
+	methodReceive    = "receive"
+	methodOnComplete = "on_complete"
+	methodClose      = "close"
+)
+
+// handshakePrefix starts the handshake line, which is followed by the
+// protocol version, the network and the address of the plugin service:
+//
+//	CONDUIT_PLUGIN|2|unix|/tmp/conduit-plugin123/plugin.sock
+const handshakePrefix = "CONDUIT_PLUGIN"
+
+// maxMessageSize bounds a single protocol message. Blocks with large deltas
+// can be tens of megabytes once encoded.
+const maxMessageSize = 512 * 1024 * 1024
+
+func formatHandshake(network, address string) string {
+	return fmt.Sprintf("%s|%d|%s|%s", handshakePrefix, ProtocolVersion, network, address)
+}
+
+// parseHandshake returns the network and the address of the handshake line.
+func parseHandshake(line string) (string, string, error) {
+	parts := strings.SplitN(strings.TrimSpace(line), "|", 4)
+	if len(parts) != 4 || parts[0] != handshakePrefix {
+		return "", "", fmt.Errorf("parseHandshake(): malformed handshake '%s'", strings.TrimSpace(line))
+	}
+	version, err := strconv.Atoi(parts[1])
+	if err != nil || version != ProtocolVersion {
+		return "", "", fmt.Errorf("parseHandshake(): unsupported protocol version %s, expected %d", parts[1], ProtocolVersion)
+	}
+	if parts[2] != "unix" {
+		return "", "", fmt.Errorf("parseHandshake(): unsupported network '%s'", parts[2])
+	}
+	return parts[2], parts[3], nil
+}
+
+func encodeBlock(blk data.BlockData) []byte {
+	return msgpack.Encode(blk)
+}
+
+func decodeBlock(b []byte) (data.BlockData, error) {
+	var blk data.BlockData
+	err := msgpack.Decode(b, &blk)
+	return blk, err
+}
+
+func encodeGenesis(genesis *sdk.Genesis) []byte {
+	if genesis == nil {
+		return nil
+	}
+	return msgpack.Encode(genesis)
+}
+
+func decodeGenesis(b []byte) (*sdk.Genesis, error) {
+	var genesis sdk.Genesis
+	if len(b) == 0 {
+		return &genesis, nil
+	}
+	err := msgpack.Decode(b, &genesis)
+	return &genesis, err
+}
@@ -0,0 +1,133 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sandbox restricts the process of an external plugin, so that untrusted
+// plugins can be run with the least privileges.
+type Sandbox struct {
+	// Env lists the environment variables which the plugin inherits from
+	// conduit, a name ending with "*" matches a prefix. The plugin inherits
+	// the whole environment when Env is not set, and none of it when it is
+	// an empty list.
+	Env []string `yaml:"env"`
+	// SetEnv sets additional environment variables of the plugin.
+	SetEnv map[string]string `yaml:"set-env"`
+	// Dir is the working directory of the plugin, by default the working
+	// directory of conduit.
+	Dir string `yaml:"dir"`
+	// Umask is the octal file mode creation mask of the plugin, e.g. "077".
+	Umask string `yaml:"umask"`
+	// User and Group are the name or numeric ID of the user and group which
+	// the plugin runs as, conduit must be allowed to switch to them. The
+	// group defaults to the primary group of the user.
+	User  string `yaml:"user"`
+	Group string `yaml:"group"`
+}
+
+// Valid checks the sandbox settings which do not depend on the host.
+func (s *Sandbox) Valid() error {
+	for _, name := range s.Env {
+		if name == "" || strings.Contains(name, "=") || strings.Contains(strings.TrimSuffix(name, "*"), "*") {
+			return fmt.Errorf("env name (%s) was invalid", name)
+		}
+	}
+	for name := range s.SetEnv {
+		if name == "" || strings.Contains(name, "=") {
+			return fmt.Errorf("set-env name (%s) was invalid", name)
+		}
+	}
+	if s.Umask != "" {
+		if _, err := s.umask(); err != nil {
+			return err
+		}
+	}
+	if s.User == "" && s.Group != "" {
+		return fmt.Errorf("group (%s) requires a user", s.Group)
+	}
+	return nil
+}
+
+func (s *Sandbox) umask() (uint32, error) {
+	umask, err := strconv.ParseUint(s.Umask, 8, 32)
+	if err != nil || umask > 0777 {
+		return 0, fmt.Errorf("umask (%s) must be an octal mode up to 777", s.Umask)
+	}
+	return uint32(umask), nil
+}
+
+// allowed reports whether an environment variable is inherited.
+func (s *Sandbox) allowed(name string) bool {
+	for _, pattern := range s.Env {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(name, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// environ returns the environment of the plugin from the environment of
+// conduit.
+func (s *Sandbox) environ(parent []string) []string {
+	env := make([]string, 0, len(parent)+len(s.SetEnv))
+	for _, kv := range parent {
+		name := kv
+		if idx := strings.Index(kv, "="); idx >= 0 {
+			name = kv[:idx]
+		}
+		if _, ok := s.SetEnv[name]; ok {
+			continue
+		}
+		if s.Env == nil || s.allowed(name) {
+			env = append(env, kv)
+		}
+	}
+	names := make([]string, 0, len(s.SetEnv))
+	for name := range s.SetEnv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, name+"="+s.SetEnv[name])
+	}
+	return env
+}
+
+// command returns the command which runs the plugin executable in the
+// sandbox, a nil sandbox runs it unrestricted.
+func (s *Sandbox) command(ctx context.Context, executable string, args []string) (*exec.Cmd, error) {
+	if s == nil {
+		return exec.CommandContext(ctx, executable, args...), nil
+	}
+	if err := s.Valid(); err != nil {
+		return nil, fmt.Errorf("command(): sandbox was invalid: %w", err)
+	}
+	name, cmdArgs := executable, args
+	if s.Umask != "" {
+		// The umask of a child process can only be set before it executes
+		// the plugin, conduit's own umask is left unchanged.
+		umask, _ := s.umask()
+		name = "/bin/sh"
+		cmdArgs = append([]string{"-c", fmt.Sprintf(`umask %03o && exec "$0" "$@"`, umask), executable}, args...)
+	}
+	cmd := exec.CommandContext(ctx, name, cmdArgs...)
+	cmd.Dir = s.Dir
+	if s.Env != nil || len(s.SetEnv) > 0 {
+		cmd.Env = s.environ(os.Environ())
+	}
+	if err := s.setCredential(cmd); err != nil {
+		return nil, fmt.Errorf("command(): %w", err)
+	}
+	return cmd, nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package external
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// setCredential is not supported on this platform, neither is the umask.
+func (s *Sandbox) setCredential(*exec.Cmd) error {
+	if s.User != "" || s.Umask != "" {
+		return fmt.Errorf("setCredential(): user and umask are not supported on this platform")
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/algorand/conduit/conduit/plugins"
+)
+
+func TestSandboxValid(t *testing.T) {
+	tests := []struct {
+		sandbox Sandbox
+		err     string
+	}{
+		{Sandbox{Env: []string{"PATH", "PLUGIN_*"}, SetEnv: map[string]string{"MODE": "test"}, Umask: "077", User: "nobody"}, ""},
+		{Sandbox{Env: []string{"A=B"}}, "env name (A=B) was invalid"},
+		{Sandbox{Env: []string{"*_KEY"}}, "env name (*_KEY) was invalid"},
+		{Sandbox{SetEnv: map[string]string{"": "value"}}, "set-env name () was invalid"},
+		{Sandbox{Umask: "999"}, "umask (999) must be an octal mode up to 777"},
+		{Sandbox{Umask: "1777"}, "umask (1777) must be an octal mode up to 777"},
+		{Sandbox{Group: "nogroup"}, "group (nogroup) requires a user"},
+	}
+	for _, tc := range tests {
+		err := tc.sandbox.Valid()
+		if tc.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tc.err)
+		}
+	}
+}
+
+func TestSandboxEnviron(t *testing.T) {
+	parent := []string{"PATH=/bin", "HOME=/root", "PLUGIN_TOKEN=secret", "MODE=prod"}
+
+	s := Sandbox{SetEnv: map[string]string{"MODE": "test"}}
+	assert.Equal(t, []string{"PATH=/bin", "HOME=/root", "PLUGIN_TOKEN=secret", "MODE=test"}, s.environ(parent), "the whole environment is inherited")
+
+	s.Env = []string{"PATH", "PLUGIN_*"}
+	assert.Equal(t, []string{"PATH=/bin", "PLUGIN_TOKEN=secret", "MODE=test"}, s.environ(parent))
+
+	s = Sandbox{Env: []string{}}
+	assert.Empty(t, s.environ(parent), "an empty allowlist inherits nothing")
+}
+
+func TestSandboxCommand(t *testing.T) {
+	cmd, err := (*Sandbox)(nil).command(context.Background(), "plugin", []string{"-v"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plugin", "-v"}, cmd.Args)
+	assert.Nil(t, cmd.Env)
+
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("umask is not supported on this platform")
+	}
+	cmd, err = (&Sandbox{Umask: "27"}).command(context.Background(), "plugin", []string{"-v"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/bin/sh", "-c", `umask 027 && exec "$0" "$@"`, "plugin", "-v"}, cmd.Args)
+
+	_, err = (&Sandbox{Umask: "8"}).command(context.Background(), "plugin", nil)
+	assert.EqualError(t, err, "command(): sandbox was invalid: umask (8) must be an octal mode up to 777")
+}
+
+func TestExternalImporterSandbox(t *testing.T) {
+	t.Setenv(pluginEnv, "importer")
+	t.Setenv("SANDBOX_VALUE", "inherited")
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+
+	sandbox := &Sandbox{Env: []string{pluginEnv, "SANDBOX_*"}, Dir: dir}
+	imp := MakeImporter("test_importer", os.Args[0], nil, sandbox)
+	genesis, err := imp.Init(context.Background(), plugins.MakePluginConfig("sandbox"), testLogger())
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%s inherited false", dir), genesis.Network, "HOME is not inherited")
+	require.NoError(t, imp.Close())
+
+	sandbox.SetEnv = map[string]string{"SANDBOX_VALUE": "set", "HOME": dir}
+	imp = MakeImporter("test_importer", os.Args[0], nil, sandbox)
+	genesis, err = imp.Init(context.Background(), plugins.MakePluginConfig("sandbox"), testLogger())
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%s set true", dir), genesis.Network)
+	require.NoError(t, imp.Close())
+
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("user is not supported on this platform")
+	}
+	imp = MakeImporter("test_importer", os.Args[0], nil, &Sandbox{User: "no-such-user-for-conduit"})
+	_, err = imp.Init(context.Background(), plugins.MakePluginConfig("sandbox"), testLogger())
+	assert.ErrorContains(t, err, "unknown user (no-such-user-for-conduit)")
+}
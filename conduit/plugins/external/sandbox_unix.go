@@ -0,0 +1,52 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package external
+
+import (
+	"fmt"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// setCredential runs the command as the user and group of the sandbox.
+func (s *Sandbox) setCredential(cmd *exec.Cmd) error {
+	if s.User == "" {
+		return nil
+	}
+	u, err := user.Lookup(s.User)
+	if err != nil {
+		if u, err = user.LookupId(s.User); err != nil {
+			return fmt.Errorf("setCredential(): unknown user (%s): %w", s.User, err)
+		}
+	}
+	gid := u.Gid
+	if s.Group != "" {
+		g, err := user.LookupGroup(s.Group)
+		if err != nil {
+			if g, err = user.LookupGroupId(s.Group); err != nil {
+				return fmt.Errorf("setCredential(): unknown group (%s): %w", s.Group, err)
+			}
+		}
+		gid = g.Gid
+	}
+	uidValue, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("setCredential(): user (%s) has no numeric id: %w", s.User, err)
+	}
+	gidValue, err := strconv.ParseUint(gid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("setCredential(): group (%s) has no numeric id: %w", gid, err)
+	}
+	// The supplementary groups of conduit are dropped.
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{
+			Uid:    uint32(uidValue),
+			Gid:    uint32(gidValue),
+			Groups: []uint32{},
+		},
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
+	"github.com/algorand/conduit/conduit/plugins/external/pluginpb"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// server implements the plugin service for exactly one plugin.
+type server struct {
+	pluginpb.UnimplementedPluginServer
+
+	ctx       context.Context
+	logger    *logrus.Logger
+	importer  importers.Importer
+	processor processors.Processor
+	exporter  exporters.Exporter
+	// stop stops the service once the close request is answered.
+	stop func()
+}
+
+// ServeImporter runs the importer as an external plugin until the pipeline closes it.
+func ServeImporter(imp importers.Importer) error {
+	return serveStdio(&server{importer: imp})
+}
+
+// ServeProcessor runs the processor as an external plugin until the pipeline closes it.
+func ServeProcessor(proc processors.Processor) error {
+	return serveStdio(&server{processor: proc})
+}
+
+// ServeExporter runs the exporter as an external plugin until the pipeline closes it.
+func ServeExporter(exp exporters.Exporter) error {
+	return serveStdio(&server{exporter: exp})
+}
+
+func serveStdio(s *server) error {
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	logger.SetFormatter(&logrus.JSONFormatter{DisableHTMLEscape: true})
+	s.logger = logger
+	s.ctx = context.Background()
+
+	dir, err := os.MkdirTemp("", "conduit-plugin")
+	if err != nil {
+		return fmt.Errorf("serveStdio(): unable to create the socket directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	lis, err := net.Listen("unix", filepath.Join(dir, "plugin.sock"))
+	if err != nil {
+		return fmt.Errorf("serveStdio(): unable to listen: %w", err)
+	}
+	if _, err = fmt.Fprintln(os.Stdout, formatHandshake("unix", lis.Addr().String())); err != nil {
+		lis.Close()
+		return fmt.Errorf("serveStdio(): unable to write the handshake: %w", err)
+	}
+	return s.serve(lis, os.Stdin)
+}
+
+// serve handles requests until a close request is answered or stdin is
+// closed.
+func (s *server) serve(lis net.Listener, stdin io.Reader) error {
+	srv := grpc.NewServer(grpc.MaxRecvMsgSize(maxMessageSize), grpc.MaxSendMsgSize(maxMessageSize))
+	pluginpb.RegisterPluginServer(srv, s)
+	s.stop = func() {
+		// GracefulStop waits for the close request, so it cannot be called
+		// from the handler.
+		go srv.GracefulStop()
+	}
+	go func() {
+		// The pipeline is gone when stdin is closed.
+		_, _ = io.Copy(io.Discard, stdin)
+		srv.Stop()
+	}()
+	if err := srv.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("serve(): %w", err)
+	}
+	return nil
+}
+
+func unsupported(method string) error {
+	return status.Errorf(codes.Unimplemented, "unsupported method '%s'", method)
+}
+
+// Init initializes the plugin.
+func (s *server) Init(_ context.Context, req *pluginpb.InitRequest) (*pluginpb.InitResponse, error) {
+	cfg := plugins.PluginConfig{DataDir: req.GetDataDir(), Config: req.GetConfig()}
+	if s.importer != nil {
+		genesis, err := s.importer.Init(s.ctx, cfg, s.logger)
+		if err != nil {
+			return nil, err
+		}
+		return &pluginpb.InitResponse{Genesis: encodeGenesis(genesis)}, nil
+	}
+
+	genesis, err := decodeGenesis(req.GetGenesis())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid genesis: %v", err)
+	}
+	round := sdk.Round(req.GetNextRound())
+	initProvider := conduit.MakePipelineInitProvider(&round, genesis)
+	if s.processor != nil {
+		err = s.processor.Init(s.ctx, initProvider, cfg, s.logger)
+	} else {
+		err = s.exporter.Init(s.ctx, initProvider, cfg, s.logger)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.InitResponse{}, nil
+}
+
+// GetBlock returns a round of the importer.
+func (s *server) GetBlock(_ context.Context, req *pluginpb.GetBlockRequest) (*pluginpb.Block, error) {
+	if s.importer == nil {
+		return nil, unsupported(methodGetBlock)
+	}
+	blk, err := s.importer.GetBlock(req.GetRound())
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.Block{Block: encodeBlock(blk)}, nil
+}
+
+// Process returns the block modified by the processor.
+func (s *server) Process(_ context.Context, req *pluginpb.Block) (*pluginpb.Block, error) {
+	if s.processor == nil {
+		return nil, unsupported(methodProcess)
+	}
+	blk, err := blockOf(req)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.processor.Process(blk)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.Block{Block: encodeBlock(out)}, nil
+}
+
+// Receive passes the block to the exporter.
+func (s *server) Receive(_ context.Context, req *pluginpb.Block) (*pluginpb.Empty, error) {
+	if s.exporter == nil {
+		return nil, unsupported(methodReceive)
+	}
+	blk, err := blockOf(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.exporter.Receive(blk); err != nil {
+		return nil, err
+	}
+	return &pluginpb.Empty{}, nil
+}
+
+// OnComplete forwards the lifecycle hook to plugins which implement it.
+func (s *server) OnComplete(_ context.Context, req *pluginpb.Block) (*pluginpb.Empty, error) {
+	blk, err := blockOf(req)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := s.plugin().(conduit.Completed); ok {
+		if err = v.OnComplete(blk); err != nil {
+			return nil, err
+		}
+	}
+	return &pluginpb.Empty{}, nil
+}
+
+// Close closes the plugin and stops the service.
+func (s *server) Close(context.Context, *pluginpb.Empty) (*pluginpb.Empty, error) {
+	defer s.stop()
+	if err := s.close(); err != nil {
+		return nil, err
+	}
+	return &pluginpb.Empty{}, nil
+}
+
+func blockOf(req *pluginpb.Block) (data.BlockData, error) {
+	blk, err := decodeBlock(req.GetBlock())
+	if err != nil {
+		return blk, status.Errorf(codes.InvalidArgument, "invalid block: %v", err)
+	}
+	return blk, nil
+}
+
+func (s *server) plugin() interface{} {
+	switch {
+	case s.importer != nil:
+		return s.importer
+	case s.processor != nil:
+		return s.processor
+	default:
+		return s.exporter
+	}
+}
+
+func (s *server) close() error {
+	switch {
+	case s.importer != nil:
+		return s.importer.Close()
+	case s.processor != nil:
+		return s.processor.Close()
+	default:
+		return s.exporter.Close()
+	}
+}
@@ -49,6 +49,13 @@ type algodImporter struct {
 	ctx     context.Context
 	cancel  context.CancelFunc
 	mode    int
+	// sources are the clients of the delta-healing sources.
+	sources []*algod.Client
+	// meter counts the size of the blocks and deltas by node, it is nil
+	// unless the pipeline accounts for the bandwidth.
+	meter conduit.TrafficMeter
+	// streamState records whether the block stream of the node can be used.
+	streamState streamState
 }
 
 //go:embed sample.yaml
@@ -78,6 +85,7 @@ func init() {
 	importers.Register(PluginName, importers.ImporterConstructorFunc(func() importers.Importer {
 		return &algodImporter{}
 	}))
+	plugins.RegisterConfigSchema(plugins.Importer, PluginName, Config{})
 }
 
 func (algodImp *algodImporter) Init(ctx context.Context, cfg plugins.PluginConfig, logger *logrus.Logger) (*sdk.Genesis, error) {
@@ -117,6 +125,12 @@ func (algodImp *algodImporter) Init(ctx context.Context, cfg plugins.PluginConfi
 		return nil, err
 	}
 	algodImp.aclient = client
+	if err = algodImp.initHealing(); err != nil {
+		return nil, err
+	}
+	if err = algodImp.cfg.Stream.valid(algodImp.cfg.ConfirmationDepth); err != nil {
+		return nil, err
+	}
 
 	genesisResponse, err := client.GetGenesis().Do(ctx)
 	if err != nil {
@@ -149,15 +163,35 @@ func (algodImp *algodImporter) Close() error {
 	return nil
 }
 
-func (algodImp *algodImporter) getDelta(rnd uint64) (sdk.LedgerStateDelta, error) {
+// SetTrafficMeter counts the size of the blocks and deltas received from each
+// node. The SDK client does not expose its connections, so the requests and
+// the HTTP framing are not counted.
+func (algodImp *algodImporter) SetTrafficMeter(meter conduit.TrafficMeter) {
+	algodImp.meter = meter
+}
+
+// received counts a payload received from the node at netAddr.
+func (algodImp *algodImporter) received(netAddr string, payload []byte) {
+	if algodImp.meter == nil {
+		return
+	}
+	destination := netAddr
+	if u, err := url.Parse(netAddr); err == nil && u.Host != "" {
+		destination = u.Host
+	}
+	algodImp.meter.Add(destination, uint64(len(payload)), 0)
+}
+
+func (algodImp *algodImporter) getDelta(client *algod.Client, netAddr string, rnd uint64) (sdk.LedgerStateDelta, error) {
 	var delta sdk.LedgerStateDelta
 	params := struct {
 		Format string `url:"format,omitempty"`
 	}{Format: "msgp"}
-	bytes, err := (*common.Client)(algodImp.aclient).GetRaw(algodImp.ctx, fmt.Sprintf("/v2/deltas/%d", rnd), params, nil)
+	bytes, err := (*common.Client)(client).GetRaw(algodImp.ctx, fmt.Sprintf("/v2/deltas/%d", rnd), params, nil)
 	if err != nil {
 		return delta, err
 	}
+	algodImp.received(netAddr, bytes)
 
 	err = msgpack.Decode(bytes, &delta)
 	return delta, err
@@ -169,13 +203,19 @@ func (algodImp *algodImporter) GetBlock(rnd uint64) (data.BlockData, error) {
 	var status models.NodeStatus
 	var blk data.BlockData
 
+	waited := false
 	for r := 0; r < retries; r++ {
-		status, err = algodImp.aclient.StatusAfterBlock(rnd - 1).Do(algodImp.ctx)
+		var idle bool
+		status, idle, err = algodImp.waitForRound(algodImp.ctx, rnd)
+		waited = waited || idle
 		if err != nil {
 			// If context has expired.
 			if algodImp.ctx.Err() != nil {
 				return blk, fmt.Errorf("GetBlock ctx error: %w", err)
 			}
+			if authErr := authError(err); authErr != nil {
+				return blk, fmt.Errorf("error getting status for round: %w", authErr)
+			}
 			err = fmt.Errorf("error getting status for round: %w", err)
 			algodImp.logger.Errorf("error getting status for round %d (attempt %d)", rnd, r)
 			continue
@@ -185,48 +225,198 @@ func (algodImp *algodImporter) GetBlock(rnd uint64) (data.BlockData, error) {
 		dt := time.Since(start)
 		getAlgodRawBlockTimeSeconds.Observe(dt.Seconds())
 		if err != nil {
+			if authErr := authError(err); authErr != nil {
+				return blk, fmt.Errorf("error getting block for round %d: %w", rnd, authErr)
+			}
 			algodImp.logger.Errorf("error getting block for round %d (attempt %d)", rnd, r)
 			continue
 		}
-		tmpBlk := new(models.BlockResponse)
-		err = msgpack.Decode(blockbytes, tmpBlk)
-		if err != nil {
-			return blk, err
+		algodImp.received(algodImp.cfg.NetAddr, blockbytes)
+		blk, err = algodImp.decodeBlock(rnd, blockbytes, status.LastRound)
+		if err == nil && waited {
+			observeTipLatency(blk)
 		}
+		return blk, err
+	}
 
-		blk.BlockHeader = tmpBlk.Block.BlockHeader
-		blk.Payset = tmpBlk.Block.Payset
-		blk.Certificate = tmpBlk.Cert
+	err = fmt.Errorf("failed to get block for round %d after %d attempts, check node configuration: %s", rnd, retries, err)
+	algodImp.logger.Errorf(err.Error())
+	return blk, err
+}
+
+// waitForRound long-polls the node status until the node has round rnd, and
+// the confirmation-depth rounds after it. When the node is caught up, a
+// long-poll which times out before the next block is added is polled again
+// instead of fetching a block which does not exist yet, so following the tip
+// of the chain does not spend retries or log errors. idle is set if the
+// pipeline waited at the tip.
+func (algodImp *algodImporter) waitForRound(ctx context.Context, rnd uint64) (status models.NodeStatus, idle bool, err error) {
+	target := rnd + algodImp.cfg.ConfirmationDepth
+	for {
+		status, err = algodImp.aclient.StatusAfterBlock(target - 1).Do(ctx)
+		if err != nil || target == 0 || status.LastRound >= target || (rnd > 0 && status.LastRound < rnd-1) {
+			return status, idle, err
+		}
+		if !idle {
+			algodImp.logger.Debugf("caught up with the node at round %d, waiting for round %d", status.LastRound, target)
+		}
+		idle = true
+	}
+}
+
+// observeTipLatency records how long after its timestamp a block which was
+// waited for at the tip was received. Blocks without a timestamp are skipped.
+func observeTipLatency(blk data.BlockData) {
+	if blk.BlockHeader.TimeStamp == 0 {
+		return
+	}
+	tipLatencySeconds.Observe(time.Since(time.Unix(blk.BlockHeader.TimeStamp, 0)).Seconds())
+}
+
+// authError returns err as a *conduit.AuthError if algod rejected the token,
+// otherwise nil. The SDK errors only carry the status in their message.
+func authError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var code int
+	if _, scanErr := fmt.Sscanf(err.Error(), "HTTP %d:", &code); scanErr == nil && conduit.IsAuthStatus(code) {
+		return conduit.MakeAuthError(err)
+	}
+	return nil
+}
 
-		if algodImp.mode == followerMode {
-			// Round 0 has no delta associated with it
-			if rnd != 0 {
-				var delta sdk.LedgerStateDelta
-				delta, err = algodImp.getDelta(rnd)
+// LatestRound returns the last round of the node which is confirmation-depth
+// rounds behind its tip.
+func (algodImp *algodImporter) LatestRound() (uint64, error) {
+	status, err := algodImp.aclient.Status().Do(algodImp.ctx)
+	if authErr := authError(err); authErr != nil {
+		err = authErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("LatestRound(): %w", err)
+	}
+	if status.LastRound < algodImp.cfg.ConfirmationDepth {
+		return 0, nil
+	}
+	return status.LastRound - algodImp.cfg.ConfirmationDepth, nil
+}
+
+// decodeBlock decodes a raw block and, in follower mode, adds its ledger state
+// delta. nodeRound is the last round of the node, used to explain a missing delta.
+func (algodImp *algodImporter) decodeBlock(rnd uint64, blockbytes []byte, nodeRound uint64) (data.BlockData, error) {
+	var blk data.BlockData
+	tmpBlk := new(models.BlockResponse)
+	// Decode into a released payset to reuse its memory.
+	tmpBlk.Block.Payset = data.AcquirePayset(0)
+	err := msgpack.Decode(blockbytes, tmpBlk)
+	if err != nil {
+		return blk, err
+	}
+
+	blk.BlockHeader = tmpBlk.Block.BlockHeader
+	blk.Payset = tmpBlk.Block.Payset
+	if len(blk.Payset) == 0 {
+		blk.Payset = nil
+	}
+	blk.Certificate = tmpBlk.Cert
+
+	if algodImp.mode == followerMode {
+		// Round 0 has no delta associated with it
+		if rnd != 0 {
+			var delta sdk.LedgerStateDelta
+			delta, err = algodImp.getDelta(algodImp.aclient, algodImp.cfg.NetAddr, rnd)
+			if authErr := authError(err); authErr != nil {
+				return data.BlockData{}, fmt.Errorf("unable to get the ledger state delta of round %d: %w", rnd, authErr)
+			}
+			if err != nil && algodImp.cfg.DeltaHealing.Enabled {
+				algodImp.logger.Warnf("ledger state delta of round %d not found (node round %d), healing: %v", rnd, nodeRound, err)
+				delta, err = algodImp.healDelta(rnd)
 				if err != nil {
-					if status.LastRound < rnd {
-						err = fmt.Errorf("ledger state delta not found: node round (%d) is behind required round (%d), ensure follower node has its sync round set to the required round", status.LastRound, rnd)
-					} else {
-						err = fmt.Errorf("ledger state delta not found: node round (%d), required round (%d): verify follower node configuration and ensure follower node has its sync round set to the required round, re-deploying the follower node may be necessary", status.LastRound, rnd)
-					}
+					err = fmt.Errorf("ledger state delta not found: node round (%d), required round (%d): %w", nodeRound, rnd, err)
 					algodImp.logger.Error(err.Error())
 					return data.BlockData{}, err
 				}
-				blk.Delta = &delta
 			}
+			if err != nil {
+				if nodeRound < rnd {
+					err = fmt.Errorf("ledger state delta not found: node round (%d) is behind required round (%d), ensure follower node has its sync round set to the required round", nodeRound, rnd)
+				} else {
+					err = fmt.Errorf("ledger state delta not found: node round (%d), required round (%d): verify follower node configuration and ensure follower node has its sync round set to the required round, re-deploying the follower node may be necessary", nodeRound, rnd)
+				}
+				algodImp.logger.Error(err.Error())
+				return data.BlockData{}, err
+			}
+			blk.Delta = &delta
 		}
-
-		return blk, err
 	}
 
-	err = fmt.Errorf("failed to get block for round %d after %d attempts, check node configuration: %s", rnd, retries, err)
-	algodImp.logger.Errorf(err.Error())
-	return blk, err
+	return blk, nil
+}
+
+// Subscribe sends the blocks starting at startRound. The node status is only
+// requested once the last known round of the node has been sent, so that
+// catching up does not wait for a status request on every round. Once caught
+// up, the blocks are received from the node stream if it is enabled, and
+// polled again when the stream fails.
+func (algodImp *algodImporter) Subscribe(ctx context.Context, startRound uint64) (<-chan data.BlockData, <-chan error) {
+	blocks := make(chan data.BlockData)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(blocks)
+		var nodeRound uint64
+		statusKnown := false
+		for rnd := startRound; ; rnd++ {
+			if statusKnown && rnd > nodeRound && algodImp.streamReady(time.Now()) {
+				var err error
+				rnd, err = algodImp.stream(ctx, rnd, blocks)
+				if ctx.Err() != nil {
+					return
+				}
+				algodImp.streamFailed(err, time.Now())
+			}
+			waited := false
+			if !statusKnown || rnd+algodImp.cfg.ConfirmationDepth > nodeRound {
+				status, idle, err := algodImp.waitForRound(ctx, rnd)
+				if err != nil {
+					errs <- fmt.Errorf("Subscribe(): error getting status for round %d: %w", rnd, err)
+					return
+				}
+				nodeRound = status.LastRound
+				statusKnown = true
+				waited = idle
+			}
+			start := time.Now()
+			blockbytes, err := algodImp.aclient.BlockRaw(rnd).Do(ctx)
+			getAlgodRawBlockTimeSeconds.Observe(time.Since(start).Seconds())
+			if err != nil {
+				errs <- fmt.Errorf("Subscribe(): error getting block for round %d: %w", rnd, err)
+				return
+			}
+			algodImp.received(algodImp.cfg.NetAddr, blockbytes)
+			blk, err := algodImp.decodeBlock(rnd, blockbytes, nodeRound)
+			if err != nil {
+				errs <- fmt.Errorf("Subscribe(): %w", err)
+				return
+			}
+			if waited {
+				observeTipLatency(blk)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case blocks <- blk:
+			}
+		}
+	}()
+	return blocks, errs
 }
 
 func (algodImp *algodImporter) ProvideMetrics(subsystem string) []prometheus.Collector {
 	getAlgodRawBlockTimeSeconds = initGetAlgodRawBlockTimeSeconds(subsystem)
+	tipLatencySeconds = initTipLatencySeconds(subsystem)
 	return []prometheus.Collector{
 		getAlgodRawBlockTimeSeconds,
+		tipLatencySeconds,
 	}
 }
@@ -4,6 +4,8 @@ package algodimporter
 
 //Name: conduit_importers_algod
 
+import "time"
+
 // Config specific to the algod importer
 type Config struct {
 	// <code>mode</code> is the mode of operation of the algod importer.  It must be either <code>archival</code> or <code>follower</code>.
@@ -12,4 +14,44 @@ type Config struct {
 	NetAddr string `yaml:"netaddr"`
 	// <code>token</code> is the Algod API endpoint token.
 	Token string `yaml:"token"`
+	/* <code>confirmation-depth</code> only imports a round once the node is at least this many rounds past it, to stay clear of the tip of the chain. Default: 0.<br/>
+	The latest round reported for the throttle and the status endpoint is the last round of the node minus the depth, the tip latency includes the time it takes to add these rounds. In follower mode the node only advances a limited number of rounds past its sync round, keep the depth small.
+	*/
+	ConfirmationDepth uint64 `yaml:"confirmation-depth"`
+	// <code>delta-healing</code> recovers missing ledger state deltas in follower mode, instead of failing until the follower node is fixed.
+	DeltaHealing DeltaHealing `yaml:"delta-healing"`
+	// <code>stream</code> receives the blocks pushed over a websocket once the importer caught up with the node, instead of long-polling the node status for each round.
+	Stream Stream `yaml:"stream"`
+}
+
+// DeltaHealing recovers the ledger state deltas which the follower node does not return, for example after it restarted or its sync round was advanced by another client.
+type DeltaHealing struct {
+	// <code>enabled</code> turns on the recovery.
+	Enabled bool `yaml:"enabled"`
+	// <code>attempts</code> is the number of times a missing delta is requested again from the follower node. Before each attempt the sync round is set back to the round if it was reset, and the node is given <code>wait</code> to catch up. Default: 3.
+	Attempts int `yaml:"attempts"`
+	// <code>wait</code> is the delay before each attempt. Default: 5s.
+	Wait time.Duration `yaml:"wait"`
+	/* <code>sources</code> are other algod nodes, such as a second follower node, which are asked for the deltas the follower node no longer has.<br/>
+	A delta expires once the sync round of the node is past it, then it can only be recovered from a node which still has it.
+	*/
+	Sources []DeltaSource `yaml:"sources"`
+}
+
+// DeltaSource is an algod node which serves ledger state deltas.
+type DeltaSource struct {
+	// <code>netaddr</code> is the Algod network address, <code>http</code> is assumed when it has no scheme.
+	NetAddr string `yaml:"netaddr"`
+	// <code>token</code> is the Algod API endpoint token.
+	Token string `yaml:"token"`
+}
+
+// Stream receives the blocks pushed by a proxy in front of the node which serves a websocket block stream, algod itself does not serve one. The importer connects to <code>endpoint</code> with the <code>round</code> query parameter set to the next round, and the stream sends a binary message with the msgpack block of each round from that round on, as returned by <code>/v2/blocks/{round}?format=msgpack</code>. The stream is only used at the tip of the chain, when the endpoint rejects the websocket handshake the importer keeps polling.
+type Stream struct {
+	// <code>enabled</code> turns on the stream.
+	Enabled bool `yaml:"enabled"`
+	// <code>endpoint</code> is the websocket URL of the stream, for example ws://localhost:8081/v2/blocks/stream. Required when the stream is enabled.
+	Endpoint string `yaml:"endpoint"`
+	// <code>idle-timeout</code> is how long the stream may send no block before the importer falls back to polling. Default: 1m.
+	IdleTimeout time.Duration `yaml:"idle-timeout"`
 }
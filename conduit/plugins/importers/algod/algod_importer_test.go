@@ -2,11 +2,14 @@ package algodimporter
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
@@ -18,7 +21,9 @@ import (
 
 	sdk "github.com/algorand/go-algorand-sdk/v2/types"
 
+	"github.com/algorand/conduit/conduit"
 	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/importers"
 )
 
 var (
@@ -218,6 +223,126 @@ netaddr: %s
 	}
 }
 
+// sizeMeter sums the traffic by destination.
+type sizeMeter map[string]uint64
+
+func (m sizeMeter) Add(destination string, in, out uint64) {
+	m[destination] += in + out
+}
+
+func TestGetBlockTrafficMeter(t *testing.T) {
+	server := NewAlgodServer(GenesisResponder, BlockResponder, BlockAfterResponder, LedgerStateDeltaResponder)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	meter := sizeMeter{}
+	testImporter := New()
+	testImporter.SetTrafficMeter(meter)
+	_, err := testImporter.Init(ctx, plugins.MakePluginConfig(fmt.Sprintf("mode: follower\nnetaddr: %s\n", server.URL)), logger)
+	require.NoError(t, err)
+
+	_, err = testImporter.GetBlock(uint64(10))
+	require.NoError(t, err)
+	host := strings.TrimPrefix(server.URL, "http://")
+	require.Len(t, meter, 1)
+	// the block and its delta.
+	assert.Greater(t, meter[host], uint64(0))
+	size := meter[host]
+
+	_, err = testImporter.GetBlock(uint64(10))
+	require.NoError(t, err)
+	assert.Equal(t, 2*size, meter[host])
+}
+
+// TestGetBlockIdleAtTip tests that a long-poll which times out while the node
+// is caught up is polled again, instead of fetching the block and retrying.
+func TestGetBlockIdleAtTip(t *testing.T) {
+	var polls int32
+	waitForBlock := func(reqPath string, w http.ResponseWriter) bool {
+		if !strings.Contains(reqPath, "/wait-for-block-after") {
+			return false
+		}
+		// The first two long-polls time out at round 9.
+		status := models.NodeStatus{LastRound: 9}
+		if atomic.AddInt32(&polls, 1) > 2 {
+			status.LastRound = 10
+		}
+		return MakeBlockAfterResponder(status)(reqPath, w)
+	}
+	algodServer := NewAlgodServer(GenesisResponder, BlockResponder, waitForBlock)
+	testLogger, hook := test.NewNullLogger()
+	testImporter := New()
+	cfgStr, err := yaml.Marshal(Config{Mode: archivalModeStr, NetAddr: algodServer.URL})
+	require.NoError(t, err)
+	_, err = testImporter.Init(context.Background(), plugins.MakePluginConfig(string(cfgStr)), testLogger)
+	require.NoError(t, err)
+
+	blk, err := testImporter.GetBlock(10)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), blk.Round())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&polls))
+	for _, entry := range hook.AllEntries() {
+		assert.NotEqual(t, logrus.ErrorLevel, entry.Level, entry.Message)
+	}
+	require.NoError(t, testImporter.Close())
+}
+
+// TestGetBlockConfirmationDepth tests that a round is only fetched once the
+// node is confirmation-depth rounds past it.
+func TestGetBlockConfirmationDepth(t *testing.T) {
+	var polls int32
+	waitForBlock := func(reqPath string, w http.ResponseWriter) bool {
+		if !strings.Contains(reqPath, "/wait-for-block-after") {
+			return false
+		}
+		assert.Equal(t, "11", path.Base(reqPath))
+		// The node adds a round after each long-poll, starting at round 10.
+		status := models.NodeStatus{LastRound: 9 + uint64(atomic.AddInt32(&polls, 1))}
+		return MakeBlockAfterResponder(status)(reqPath, w)
+	}
+	algodServer := NewAlgodServer(GenesisResponder, BlockResponder, waitForBlock)
+	testImporter := New()
+	cfgStr, err := yaml.Marshal(Config{Mode: archivalModeStr, NetAddr: algodServer.URL, ConfirmationDepth: 2})
+	require.NoError(t, err)
+	_, err = testImporter.Init(context.Background(), plugins.MakePluginConfig(string(cfgStr)), logger)
+	require.NoError(t, err)
+
+	blk, err := testImporter.GetBlock(10)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), blk.Round())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&polls))
+	require.NoError(t, testImporter.Close())
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	algodServer := NewAlgodServer(GenesisResponder, BlockResponder, BlockAfterResponder)
+	var testImporter importers.Importer = New()
+
+	cfgStr := fmt.Sprintf(`---
+mode: %s
+netaddr: %s
+`, "archival", algodServer.URL)
+	_, err := testImporter.Init(ctx, plugins.MakePluginConfig(cfgStr), logger)
+	require.NoError(t, err)
+
+	subCtx, subCancel := context.WithCancel(ctx)
+	blocks, errs := testImporter.(importers.SubscribingImporter).Subscribe(subCtx, 10)
+	for rnd := uint64(10); rnd < 13; rnd++ {
+		select {
+		case blk := <-blocks:
+			assert.Equal(t, rnd, blk.Round())
+		case err := <-errs:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// The block channel is closed once the subscription is cancelled.
+	subCancel()
+	for range blocks {
+	}
+}
+
 func TestGetBlockContextCancelled(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -282,7 +407,7 @@ netaddr: %s
 
 func TestAlgodImporter_ProvideMetrics(t *testing.T) {
 	testImporter := &algodImporter{}
-	assert.Len(t, testImporter.ProvideMetrics("blah"), 1)
+	assert.Len(t, testImporter.ProvideMetrics("blah"), 2)
 }
 
 func TestGetBlockErrors(t *testing.T) {
@@ -328,6 +453,20 @@ func TestGetBlockErrors(t *testing.T) {
 			err:                 fmt.Sprintf("ledger state delta not found: node round (200), required round (200)"),
 			logs:                []string{"ledger state delta not found: node round (200), required round (200)"},
 		},
+		{
+			name:                "Token rejected",
+			rnd:                 123,
+			blockAfterResponder: MakeStatusResponder("/wait-for-block-after", http.StatusUnauthorized, ""),
+			err:                 "error getting status for round: credentials rejected: HTTP 401",
+		},
+		{
+			name:                "Delta forbidden",
+			rnd:                 200,
+			blockAfterResponder: MakeBlockAfterResponder(models.NodeStatus{LastRound: 200}),
+			blockResponder:      BlockResponder,
+			deltaResponder:      MakeStatusResponder("/v2/deltas/", http.StatusForbidden, ""),
+			err:                 "unable to get the ledger state delta of round 200: credentials rejected: HTTP 403",
+		},
 	}
 
 	for _, tc := range testcases {
@@ -382,3 +521,38 @@ func TestGetBlockErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestLatestRound(t *testing.T) {
+	aclient, err := MockAClient(NewAlgodHandler(MakeJsonResponder("/v2/status", models.NodeStatus{LastRound: 42})))
+	require.NoError(t, err)
+	imp := &algodImporter{aclient: aclient, ctx: context.Background()}
+	round, err := imp.LatestRound()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), round)
+
+	// The latest round is confirmation-depth rounds behind the node.
+	imp.cfg.ConfirmationDepth = 2
+	round, err = imp.LatestRound()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(40), round)
+	imp.cfg.ConfirmationDepth = 50
+	round, err = imp.LatestRound()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), round)
+
+	aclient, err = MockAClient(NewAlgodHandler())
+	require.NoError(t, err)
+	imp.aclient = aclient
+	_, err = imp.LatestRound()
+	assert.Error(t, err)
+}
+
+func TestAuthError(t *testing.T) {
+	assert.NoError(t, authError(nil))
+	assert.NoError(t, authError(fmt.Errorf("HTTP 404: not found")))
+	assert.NoError(t, authError(fmt.Errorf("connection refused")))
+	for _, msg := range []string{"HTTP 401: Invalid API Token", "HTTP 403: forbidden"} {
+		var authErr *conduit.AuthError
+		assert.ErrorAs(t, authError(errors.New(msg)), &authErr)
+	}
+}
@@ -0,0 +1,127 @@
+package algodimporter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+)
+
+const (
+	defaultHealingAttempts = 3
+	defaultHealingWait     = 5 * time.Second
+)
+
+func (h DeltaHealing) valid() error {
+	if h.Attempts < 0 || h.Wait < 0 {
+		return fmt.Errorf("delta-healing attempts and wait must not be negative")
+	}
+	for i, src := range h.Sources {
+		if src.NetAddr == "" {
+			return fmt.Errorf("delta-healing sources[%d] netaddr is required", i)
+		}
+	}
+	return nil
+}
+
+func (h DeltaHealing) attempts() int {
+	if h.Attempts > 0 {
+		return h.Attempts
+	}
+	return defaultHealingAttempts
+}
+
+func (h DeltaHealing) wait() time.Duration {
+	if h.Wait > 0 {
+		return h.Wait
+	}
+	return defaultHealingWait
+}
+
+// makeClient returns an algod client, adding the http scheme when the address
+// has none.
+func makeClient(netAddr, token string) (*algod.Client, error) {
+	if !strings.HasPrefix(netAddr, "http://") && !strings.HasPrefix(netAddr, "https://") {
+		netAddr = "http://" + netAddr
+	}
+	if _, err := url.Parse(netAddr); err != nil {
+		return nil, err
+	}
+	return algod.MakeClient(netAddr, token)
+}
+
+// initHealing creates the clients of the delta sources.
+func (algodImp *algodImporter) initHealing() error {
+	h := algodImp.cfg.DeltaHealing
+	if err := h.valid(); err != nil {
+		return err
+	}
+	algodImp.sources = nil
+	for i, src := range h.Sources {
+		client, err := makeClient(src.NetAddr, src.Token)
+		if err != nil {
+			return fmt.Errorf("delta-healing sources[%d]: %w", i, err)
+		}
+		algodImp.sources = append(algodImp.sources, client)
+	}
+	return nil
+}
+
+// sleep waits for d, it returns false if the importer is closed meanwhile.
+func (algodImp *algodImporter) sleep(d time.Duration) bool {
+	select {
+	case <-algodImp.ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// healDelta recovers the delta of the round after the follower node did not
+// return it. The follower node keeps the deltas from its sync round on, so the
+// delta is requested again as long as the sync round is not past the round,
+// setting it back when it was reset by a restart. Otherwise the delta expired
+// on the follower node and it is requested from the sources.
+func (algodImp *algodImporter) healDelta(rnd uint64) (sdk.LedgerStateDelta, error) {
+	h := algodImp.cfg.DeltaHealing
+	var err error
+	for attempt := 1; attempt <= h.attempts(); attempt++ {
+		syncResp, syncErr := algodImp.aclient.GetSyncRound().Do(algodImp.ctx)
+		if syncErr == nil && syncResp.Round > rnd {
+			err = fmt.Errorf("the sync round of the follower node (%d) is past the round, the delta expired", syncResp.Round)
+			break
+		}
+		if syncErr != nil {
+			// The sync round is not set after the node restarted.
+			algodImp.logger.Warnf("delta-healing: the sync round of the follower node is not set (%v), setting it to %d", syncErr, rnd)
+			if _, err = algodImp.aclient.SetSyncRound(rnd).Do(algodImp.ctx); err != nil {
+				err = fmt.Errorf("unable to set the sync round of the follower node: %w", err)
+				break
+			}
+		}
+		if !algodImp.sleep(h.wait()) {
+			return sdk.LedgerStateDelta{}, fmt.Errorf("delta-healing: %w", algodImp.ctx.Err())
+		}
+		var delta sdk.LedgerStateDelta
+		if delta, err = algodImp.getDelta(algodImp.aclient, algodImp.cfg.NetAddr, rnd); err == nil {
+			algodImp.logger.Infof("delta-healing: recovered the delta of round %d from the follower node after %d attempts", rnd, attempt)
+			return delta, nil
+		}
+		algodImp.logger.Warnf("delta-healing: the follower node did not return the delta of round %d (attempt %d): %v", rnd, attempt, err)
+	}
+	for i, source := range algodImp.sources {
+		delta, srcErr := algodImp.getDelta(source, algodImp.cfg.DeltaHealing.Sources[i].NetAddr, rnd)
+		if srcErr == nil {
+			algodImp.logger.Infof("delta-healing: recovered the delta of round %d from sources[%d]", rnd, i)
+			return delta, nil
+		}
+		algodImp.logger.Warnf("delta-healing: sources[%d] did not return the delta of round %d: %v", i, rnd, srcErr)
+	}
+	if len(algodImp.sources) > 0 {
+		return sdk.LedgerStateDelta{}, fmt.Errorf("delta-healing failed, no source has the delta: %w", err)
+	}
+	return sdk.LedgerStateDelta{}, fmt.Errorf("delta-healing failed: %w", err)
+}
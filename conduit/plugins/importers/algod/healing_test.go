@@ -0,0 +1,158 @@
+package algodimporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/v2/encoding/json"
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// followerNode is a mock follower node whose delta is missing for the first
+// missing requests, with a sync round which is unset when syncRound is 0.
+type followerNode struct {
+	mu        sync.Mutex
+	syncRound uint64
+	missing   int
+	requests  []string
+}
+
+func (n *followerNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.requests = append(n.requests, r.Method+" "+r.URL.Path)
+	switch {
+	case r.URL.Path == "/v2/ledger/sync" && r.Method == http.MethodGet:
+		if n.syncRound == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(json.Encode(models.GetSyncRoundResponse{Round: n.syncRound}))
+	case strings.HasPrefix(r.URL.Path, "/v2/ledger/sync/") && r.Method == http.MethodPost:
+		n.syncRound = 200
+		w.WriteHeader(http.StatusOK)
+	case strings.HasPrefix(r.URL.Path, "/v2/deltas/"):
+		if n.missing > 0 {
+			n.missing--
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(msgpack.Encode(sdk.LedgerStateDelta{PrevTimestamp: 1234}))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func makeHealingImporter(t *testing.T, h DeltaHealing, node http.Handler, sources ...http.Handler) *algodImporter {
+	srv := httptest.NewServer(node)
+	t.Cleanup(srv.Close)
+	client, err := algod.MakeClient(srv.URL, "")
+	require.NoError(t, err)
+	testLogger, _ := test.NewNullLogger()
+	imp := &algodImporter{aclient: client, ctx: context.Background(), logger: testLogger, mode: followerMode}
+	imp.cfg.DeltaHealing = h
+	for _, source := range sources {
+		srv := httptest.NewServer(source)
+		t.Cleanup(srv.Close)
+		imp.cfg.DeltaHealing.Sources = append(imp.cfg.DeltaHealing.Sources, DeltaSource{NetAddr: strings.TrimPrefix(srv.URL, "http://")})
+	}
+	require.NoError(t, imp.initHealing())
+	return imp
+}
+
+func TestHealDelta(t *testing.T) {
+	h := DeltaHealing{Enabled: true, Wait: time.Millisecond}
+
+	t.Run("sync round reset", func(t *testing.T) {
+		node := &followerNode{}
+		imp := makeHealingImporter(t, h, node)
+		delta, err := imp.healDelta(200)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1234), delta.PrevTimestamp)
+		assert.Equal(t, []string{"GET /v2/ledger/sync", "POST /v2/ledger/sync/200", "GET /v2/deltas/200"}, node.requests)
+	})
+
+	t.Run("node catching up", func(t *testing.T) {
+		node := &followerNode{syncRound: 150, missing: 1}
+		imp := makeHealingImporter(t, h, node)
+		_, err := imp.healDelta(200)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"GET /v2/ledger/sync", "GET /v2/deltas/200", "GET /v2/ledger/sync", "GET /v2/deltas/200"}, node.requests)
+	})
+
+	t.Run("attempts", func(t *testing.T) {
+		node := &followerNode{syncRound: 150, missing: 10}
+		imp := makeHealingImporter(t, DeltaHealing{Enabled: true, Wait: time.Millisecond, Attempts: 2}, node)
+		_, err := imp.healDelta(200)
+		assert.ErrorContains(t, err, "delta-healing failed: ")
+		assert.Len(t, node.requests, 4)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		node := &followerNode{syncRound: 300, missing: 10}
+		imp := makeHealingImporter(t, h, node)
+		_, err := imp.healDelta(200)
+		assert.EqualError(t, err, "delta-healing failed: the sync round of the follower node (300) is past the round, the delta expired")
+		assert.Equal(t, []string{"GET /v2/ledger/sync"}, node.requests)
+	})
+
+	t.Run("sources", func(t *testing.T) {
+		node := &followerNode{syncRound: 300, missing: 10}
+		missing := &followerNode{missing: 10}
+		source := &followerNode{}
+		imp := makeHealingImporter(t, h, node, missing, source)
+		delta, err := imp.healDelta(200)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1234), delta.PrevTimestamp)
+		assert.Equal(t, []string{"GET /v2/deltas/200"}, missing.requests)
+		assert.Equal(t, []string{"GET /v2/deltas/200"}, source.requests)
+
+		imp = makeHealingImporter(t, h, node, missing)
+		_, err = imp.healDelta(200)
+		assert.EqualError(t, err, "delta-healing failed, no source has the delta: the sync round of the follower node (300) is past the round, the delta expired")
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		imp := makeHealingImporter(t, DeltaHealing{Enabled: true, Wait: time.Hour}, &followerNode{syncRound: 150, missing: 10})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		imp.ctx = ctx
+		_, err := imp.healDelta(200)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestDecodeBlockHealing(t *testing.T) {
+	node := &followerNode{missing: 1}
+	imp := makeHealingImporter(t, DeltaHealing{Enabled: true, Wait: time.Millisecond}, node)
+	blockbytes := msgpack.Encode(&models.BlockResponse{Block: sdk.Block{BlockHeader: sdk.BlockHeader{Round: 200}}})
+	blk, err := imp.decodeBlock(200, blockbytes, 200)
+	require.NoError(t, err)
+	require.NotNil(t, blk.Delta)
+	assert.Equal(t, int64(1234), blk.Delta.PrevTimestamp)
+
+	// Without healing the missing delta is an error.
+	node = &followerNode{missing: 1}
+	imp = makeHealingImporter(t, DeltaHealing{}, node)
+	_, err = imp.decodeBlock(200, blockbytes, 200)
+	assert.ErrorContains(t, err, "ledger state delta not found: node round (200), required round (200)")
+}
+
+func TestDeltaHealingValid(t *testing.T) {
+	assert.NoError(t, DeltaHealing{}.valid())
+	assert.EqualError(t, DeltaHealing{Attempts: -1}.valid(), "delta-healing attempts and wait must not be negative")
+	assert.EqualError(t, DeltaHealing{Sources: []DeltaSource{{}}}.valid(), "delta-healing sources[0] netaddr is required")
+	assert.Equal(t, defaultHealingAttempts, DeltaHealing{}.attempts())
+	assert.Equal(t, defaultHealingWait, DeltaHealing{}.wait())
+}
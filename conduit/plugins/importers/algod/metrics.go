@@ -17,3 +17,16 @@ func initGetAlgodRawBlockTimeSeconds(subsystem string) prometheus.Summary {
 			Help:      "Total response time from Algod's raw block endpoint in seconds.",
 		})
 }
+
+// tipLatencySeconds is used to record how long after their timestamp the
+// blocks were received while following the tip of the chain.
+var tipLatencySeconds = initTipLatencySeconds(conduit.DefaultMetricsPrefix)
+
+func initTipLatencySeconds(subsystem string) prometheus.Summary {
+	return prometheus.NewSummary(
+		prometheus.SummaryOpts{
+			Subsystem: subsystem,
+			Name:      "algod_tip_latency_sec",
+			Help:      "Time between the timestamp of a block and when it was received while caught up with the node, in seconds.",
+		})
+}
@@ -0,0 +1,164 @@
+package algodimporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/algorand/conduit/conduit/data"
+)
+
+const (
+	defaultStreamIdleTimeout = time.Minute
+	// streamRetryInterval is how long the importer polls after the stream
+	// failed before it connects again.
+	streamRetryInterval = time.Minute
+)
+
+// errStreamUnsupported is returned when the endpoint rejects the websocket
+// handshake, it is not tried again.
+var errStreamUnsupported = errors.New("the endpoint does not serve the block stream")
+
+func (s Stream) valid(confirmationDepth uint64) error {
+	if s.IdleTimeout < 0 {
+		return fmt.Errorf("stream idle-timeout must not be negative")
+	}
+	if !s.Enabled {
+		return nil
+	}
+	if s.Endpoint == "" {
+		return fmt.Errorf("stream endpoint is required, algod does not serve a block stream and a proxy serving it must be configured")
+	}
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return fmt.Errorf("stream endpoint: %w", err)
+	}
+	switch u.Scheme {
+	case "ws", "wss", "http", "https":
+	default:
+		return fmt.Errorf("stream endpoint '%s' must be a ws, wss, http or https URL", s.Endpoint)
+	}
+	if confirmationDepth > 0 {
+		return fmt.Errorf("stream cannot be used with confirmation-depth, the stream sends the blocks at the tip")
+	}
+	return nil
+}
+
+func (s Stream) idleTimeout() time.Duration {
+	if s.IdleTimeout > 0 {
+		return s.IdleTimeout
+	}
+	return defaultStreamIdleTimeout
+}
+
+// streamState records whether the stream can be used, it is shared by the
+// subscriptions.
+type streamState struct {
+	mu          sync.Mutex
+	unsupported bool
+	// retryAt is when the stream is tried again after it failed.
+	retryAt time.Time
+}
+
+// streamReady reports whether the stream is enabled and may be tried at now.
+func (algodImp *algodImporter) streamReady(now time.Time) bool {
+	if !algodImp.cfg.Stream.Enabled {
+		return false
+	}
+	algodImp.streamState.mu.Lock()
+	defer algodImp.streamState.mu.Unlock()
+	return !algodImp.streamState.unsupported && !now.Before(algodImp.streamState.retryAt)
+}
+
+// streamFailed falls back to polling after the stream ended with err.
+func (algodImp *algodImporter) streamFailed(err error, now time.Time) {
+	algodImp.streamState.mu.Lock()
+	defer algodImp.streamState.mu.Unlock()
+	if errors.Is(err, errStreamUnsupported) {
+		algodImp.streamState.unsupported = true
+		algodImp.logger.Infof("%v, polling the node for blocks", err)
+		return
+	}
+	algodImp.streamState.retryAt = now.Add(streamRetryInterval)
+	algodImp.logger.Warnf("block stream failed, polling the node for %s: %v", streamRetryInterval, err)
+}
+
+// streamURL returns the websocket URL of the stream starting at rnd.
+func (algodImp *algodImporter) streamURL(rnd uint64) (string, error) {
+	u, err := url.Parse(algodImp.cfg.Stream.Endpoint)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("round", strconv.FormatUint(rnd, 10))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// stream sends the blocks pushed by the endpoint from round rnd on blocks,
+// until the stream fails or ctx is cancelled. It returns the first round which
+// was not sent.
+func (algodImp *algodImporter) stream(ctx context.Context, rnd uint64, blocks chan<- data.BlockData) (uint64, error) {
+	location, err := algodImp.streamURL(rnd)
+	if err != nil {
+		return rnd, err
+	}
+	opts := &websocket.DialOptions{}
+	if algodImp.cfg.Token != "" {
+		opts.HTTPHeader = http.Header{"X-Algo-API-Token": {algodImp.cfg.Token}}
+	}
+	timeout := algodImp.cfg.Stream.idleTimeout()
+	dialCtx, dialCancel := context.WithTimeout(ctx, timeout)
+	ws, resp, err := websocket.Dial(dialCtx, location, opts)
+	dialCancel()
+	if err != nil {
+		if ctx.Err() != nil {
+			return rnd, ctx.Err()
+		}
+		if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+			return rnd, fmt.Errorf("%w: %s", errStreamUnsupported, resp.Status)
+		}
+		return rnd, err
+	}
+	defer ws.CloseNow()
+	// The blocks are not limited in size.
+	ws.SetReadLimit(-1)
+	algodImp.logger.Infof("receiving the blocks from the block stream at round %d", rnd)
+
+	for {
+		readCtx, readCancel := context.WithTimeout(ctx, timeout)
+		_, blockbytes, err := ws.Read(readCtx)
+		readCancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return rnd, ctx.Err()
+			}
+			return rnd, fmt.Errorf("stream at round %d: %w", rnd, err)
+		}
+		algodImp.received(algodImp.cfg.Stream.Endpoint, blockbytes)
+		blk, err := algodImp.decodeBlock(rnd, blockbytes, rnd)
+		if err != nil {
+			return rnd, fmt.Errorf("stream at round %d: %w", rnd, err)
+		}
+		if blk.Round() < rnd {
+			continue
+		}
+		if blk.Round() > rnd {
+			return rnd, fmt.Errorf("stream skipped from round %d to round %d", rnd, blk.Round())
+		}
+		observeTipLatency(blk)
+		select {
+		case <-ctx.Done():
+			return rnd, ctx.Err()
+		case blocks <- blk:
+		}
+		rnd++
+	}
+}
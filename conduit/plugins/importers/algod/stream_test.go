@@ -0,0 +1,169 @@
+package algodimporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/coder/websocket"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+)
+
+// encodeBlock returns the msgpack block response of the round.
+func encodeBlock(rnd uint64) []byte {
+	type EncodedBlock struct {
+		_struct struct{}  `codec:""`
+		Block   sdk.Block `codec:"block"`
+	}
+	return msgpack.Encode(&EncodedBlock{Block: sdk.Block{BlockHeader: sdk.BlockHeader{Round: sdk.Round(rnd)}}})
+}
+
+// blockStream is a mock block stream which sends count blocks from the
+// requested round, then waits for the client to close the connection.
+type blockStream struct {
+	mu     sync.Mutex
+	count  uint64
+	rounds []uint64
+	tokens []string
+}
+
+func (s *blockStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ws, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer ws.CloseNow()
+	rnd, _ := strconv.ParseUint(r.URL.Query().Get("round"), 10, 64)
+	s.mu.Lock()
+	s.rounds = append(s.rounds, rnd)
+	s.tokens = append(s.tokens, r.Header.Get("X-Algo-API-Token"))
+	s.mu.Unlock()
+	ctx := r.Context()
+	for r := rnd; r < rnd+s.count; r++ {
+		if err := ws.Write(ctx, websocket.MessageBinary, encodeBlock(r)); err != nil {
+			return
+		}
+	}
+	for {
+		if _, _, err := ws.Read(ctx); err != nil {
+			return
+		}
+	}
+}
+
+func initStreamImporter(t *testing.T, netAddr string, stream Stream) *algodImporter {
+	testLogger, _ := test.NewNullLogger()
+	testImporter := New()
+	cfgStr, err := yaml.Marshal(Config{Mode: archivalModeStr, NetAddr: netAddr, Token: "secret", Stream: stream})
+	require.NoError(t, err)
+	_, err = testImporter.Init(context.Background(), plugins.MakePluginConfig(string(cfgStr)), testLogger)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = testImporter.Close() })
+	return testImporter
+}
+
+// receiveRounds returns the rounds of the next count blocks of a subscription.
+func receiveRounds(t *testing.T, blocks <-chan data.BlockData, errs <-chan error, count int) []uint64 {
+	var rounds []uint64
+	for len(rounds) < count {
+		select {
+		case blk := <-blocks:
+			rounds = append(rounds, blk.Round())
+		case err := <-errs:
+			t.Fatalf("unexpected error: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after rounds %v", rounds)
+		}
+	}
+	return rounds
+}
+
+func TestStreamValid(t *testing.T) {
+	assert.NoError(t, Stream{}.valid(2))
+	assert.NoError(t, Stream{Enabled: true, Endpoint: "ws://localhost:8081/v2/blocks/stream"}.valid(0))
+	assert.NoError(t, Stream{Enabled: true, Endpoint: "https://proxy"}.valid(0))
+	assert.EqualError(t, Stream{IdleTimeout: -time.Second}.valid(0), "stream idle-timeout must not be negative")
+	assert.EqualError(t, Stream{Enabled: true}.valid(0), "stream endpoint is required, algod does not serve a block stream and a proxy serving it must be configured")
+	assert.EqualError(t, Stream{Enabled: true, Endpoint: "localhost:8081"}.valid(0), "stream endpoint 'localhost:8081' must be a ws, wss, http or https URL")
+	assert.EqualError(t, Stream{Enabled: true, Endpoint: "ws://proxy"}.valid(2), "stream cannot be used with confirmation-depth, the stream sends the blocks at the tip")
+}
+
+func TestStreamURL(t *testing.T) {
+	testImporter := &algodImporter{cfg: Config{Stream: Stream{Endpoint: "wss://proxy/stream?format=msgpack"}}}
+	location, err := testImporter.streamURL(12)
+	require.NoError(t, err)
+	assert.Equal(t, "wss://proxy/stream?format=msgpack&round=12", location)
+}
+
+// TestSubscribeStream tests that the blocks are polled until the importer
+// caught up with the node, then received from the stream.
+func TestSubscribeStream(t *testing.T) {
+	stream := &blockStream{count: 3}
+	mux := http.NewServeMux()
+	mux.Handle("/v2/blocks/stream", stream)
+	mux.Handle("/", NewAlgodHandler(GenesisResponder, BlockResponder, MakeBlockAfterResponder(models.NodeStatus{LastRound: 10})))
+	algodServer := httptest.NewServer(mux)
+	defer algodServer.Close()
+	testImporter := initStreamImporter(t, algodServer.URL, Stream{Enabled: true, Endpoint: algodServer.URL + "/v2/blocks/stream"})
+
+	subCtx, subCancel := context.WithCancel(context.Background())
+	blocks, errs := testImporter.Subscribe(subCtx, 10)
+	assert.Equal(t, []uint64{10, 11, 12, 13}, receiveRounds(t, blocks, errs, 4))
+	stream.mu.Lock()
+	assert.Equal(t, []uint64{11}, stream.rounds, "the stream starts after the last round of the node")
+	assert.Equal(t, []string{"secret"}, stream.tokens)
+	stream.mu.Unlock()
+
+	subCancel()
+	for range blocks {
+	}
+}
+
+// TestSubscribeStreamUnsupported tests that the importer keeps polling a node
+// which does not serve the stream.
+func TestSubscribeStreamUnsupported(t *testing.T) {
+	var polls, handshakes int32
+	waitForBlock := func(reqPath string, w http.ResponseWriter) bool {
+		if !strings.Contains(reqPath, "/wait-for-block-after") {
+			return false
+		}
+		// The node adds a round for each long-poll.
+		return MakeBlockAfterResponder(models.NodeStatus{LastRound: 9 + uint64(atomic.AddInt32(&polls, 1))})(reqPath, w)
+	}
+	countHandshakes := func(reqPath string, w http.ResponseWriter) bool {
+		if strings.HasSuffix(reqPath, "/stream") {
+			atomic.AddInt32(&handshakes, 1)
+			w.WriteHeader(http.StatusNotFound)
+			return true
+		}
+		return false
+	}
+	algodServer := NewAlgodServer(GenesisResponder, countHandshakes, BlockResponder, waitForBlock)
+	defer algodServer.Close()
+	testImporter := initStreamImporter(t, algodServer.URL, Stream{Enabled: true, Endpoint: algodServer.URL + "/v2/blocks/stream"})
+
+	subCtx, subCancel := context.WithCancel(context.Background())
+	blocks, errs := testImporter.Subscribe(subCtx, 10)
+	assert.Equal(t, []uint64{10, 11, 12, 13}, receiveRounds(t, blocks, errs, 4))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&handshakes), "the stream is not tried again")
+	assert.False(t, testImporter.streamReady(time.Now()))
+
+	subCancel()
+	for range blocks {
+	}
+}
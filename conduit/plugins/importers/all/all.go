@@ -4,4 +4,8 @@ import (
 	// Call package wide init function
 	_ "github.com/algorand/conduit/conduit/plugins/importers/algod"
 	_ "github.com/algorand/conduit/conduit/plugins/importers/filereader"
+	_ "github.com/algorand/conduit/conduit/plugins/importers/fixtures"
+	_ "github.com/algorand/conduit/conduit/plugins/importers/ipfs"
+	_ "github.com/algorand/conduit/conduit/plugins/importers/pglogical"
+	_ "github.com/algorand/conduit/conduit/plugins/importers/tarreader"
 )
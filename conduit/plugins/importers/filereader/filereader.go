@@ -15,6 +15,7 @@ import (
 	sdk "github.com/algorand/go-algorand-sdk/v2/types"
 
 	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/codec"
 	"github.com/algorand/conduit/conduit/data"
 	"github.com/algorand/conduit/conduit/plugins"
 	"github.com/algorand/conduit/conduit/plugins/exporters/filewriter"
@@ -55,6 +56,7 @@ func init() {
 	importers.Register(PluginName, importers.ImporterConstructorFunc(func() importers.Importer {
 		return &fileReader{}
 	}))
+	plugins.RegisterConfigSchema(plugins.Importer, PluginName, Config{})
 }
 
 func (r *fileReader) Init(ctx context.Context, cfg plugins.PluginConfig, logger *logrus.Logger) (*sdk.Genesis, error) {
@@ -69,6 +71,15 @@ func (r *fileReader) Init(ctx context.Context, cfg plugins.PluginConfig, logger
 	if r.cfg.FilenamePattern == "" {
 		r.cfg.FilenamePattern = filewriter.FilePattern
 	}
+	if r.cfg.ChunkFilenamePattern == "" {
+		r.cfg.ChunkFilenamePattern = filewriter.ChunkFilePattern
+	}
+	if r.cfg.FilenamePattern, err = codec.WithCompression(r.cfg.FilenamePattern, r.cfg.Compression); err != nil {
+		return nil, fmt.Errorf("Init(): filename-pattern: %w", err)
+	}
+	if r.cfg.ChunkFilenamePattern, err = codec.WithCompression(r.cfg.ChunkFilenamePattern, r.cfg.Compression); err != nil {
+		return nil, fmt.Errorf("Init(): chunk-filename-pattern: %w", err)
+	}
 
 	genesisFile := path.Join(r.cfg.BlocksDir, "genesis.json")
 	var genesis sdk.Genesis
@@ -92,13 +103,34 @@ func (r *fileReader) Close() error {
 	return nil
 }
 
+// readBlock decodes a round from its block file or chunk file, returning the filename.
+func (r *fileReader) readBlock(rnd uint64, blockData *data.BlockData) (string, error) {
+	if r.cfg.RoundsPerFile <= 1 {
+		filename := path.Join(r.cfg.BlocksDir, fmt.Sprintf(r.cfg.FilenamePattern, rnd))
+		return filename, filewriter.DecodeJSONFromFile(filename, blockData, false)
+	}
+
+	first := filewriter.ChunkStart(rnd, r.cfg.RoundsPerFile)
+	filename := path.Join(r.cfg.BlocksDir, fmt.Sprintf(r.cfg.ChunkFilenamePattern, first))
+	b, err := filewriter.ReadChunkRecord(filename, rnd)
+	if err != nil {
+		return filename, err
+	}
+	return filename, filewriter.DecodeJSONFromBytes(filename, b, blockData, false)
+}
+
 func (r *fileReader) GetBlock(rnd uint64) (data.BlockData, error) {
 	attempts := r.cfg.RetryCount
 	for {
-		filename := path.Join(r.cfg.BlocksDir, fmt.Sprintf(r.cfg.FilenamePattern, rnd))
-		var blockData data.BlockData
+		// Decode into a released payset to reuse its memory.
+		blockData := data.BlockData{Payset: data.AcquirePayset(0)}
 		start := time.Now()
-		err := filewriter.DecodeJSONFromFile(filename, &blockData, false)
+		filename, err := r.readBlock(rnd, &blockData)
+		if err != nil {
+			blockData.Release()
+		} else if len(blockData.Payset) == 0 {
+			blockData.Payset = nil
+		}
 		if err != nil && errors.Is(err, fs.ErrNotExist) {
 			// If the file read failed because the file didn't exist, wait before trying again
 			if attempts == 0 {
@@ -25,6 +25,20 @@ type Config struct {
 	"%[1]d_block.json"
 	*/
 	FilenamePattern string `yaml:"filename-pattern"`
+	/* <code>rounds-per-file</code> reads chunk files written by the 'file_writer' plugin with the same setting.<br/>
+	A value of 0 or 1 reads one file per round using filename-pattern.
+	*/
+	RoundsPerFile uint64 `yaml:"rounds-per-file"`
+	/* <code>chunk-filename-pattern</code> is the format used to find chunk files. It uses go string formatting and should accept one number for the first round in the chunk.
+	The default pattern is
+
+	"%[1]d_blocks.chunk"
+	*/
+	ChunkFilenamePattern string `yaml:"chunk-filename-pattern"`
+	/* <code>compression</code> is the name of the compression of the files, as configured in the 'file_writer' plugin: none, gzip, zstd, lz4 or snappy.<br/>
+	Its extension is added to the filename patterns which do not have it. By default the compression is chosen by the extension of the patterns.
+	*/
+	Compression string `yaml:"compression"`
 
 	// TODO: Option to delete files after processing them
 }
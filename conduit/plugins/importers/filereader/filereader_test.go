@@ -16,8 +16,10 @@ import (
 
 	"github.com/algorand/conduit/conduit/data"
 	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters"
 	"github.com/algorand/conduit/conduit/plugins/exporters/filewriter"
 	"github.com/algorand/conduit/conduit/plugins/importers"
+	"github.com/algorand/conduit/conduit/plugins/tools/testutil"
 )
 
 var (
@@ -177,3 +179,41 @@ func TestRetryWithCancel(t *testing.T) {
 	// within 1ms of the expected time (but much less than the 3hr configuration.
 	assert.WithinDuration(t, start, time.Now(), 2*delay)
 }
+
+func TestGetBlockChunks(t *testing.T) {
+	tempdir := t.TempDir()
+	initializeTestData(t, tempdir, 0)
+
+	// Write chunk files with the file_writer plugin.
+	writerCfg, err := yaml.Marshal(filewriter.Config{BlocksDir: tempdir, RoundsPerFile: 4})
+	require.NoError(t, err)
+	builder, err := exporters.ExporterBuilderByName(filewriter.PluginName)
+	require.NoError(t, err)
+	writer := builder.New()
+	rnd := sdk.Round(0)
+	err = writer.Init(context.Background(), testutil.MockedInitProvider(&rnd), plugins.MakePluginConfig(string(writerCfg)), logger)
+	require.NoError(t, err)
+	numRounds := 10
+	for i := 0; i < numRounds; i++ {
+		require.NoError(t, writer.Receive(data.BlockData{BlockHeader: sdk.BlockHeader{Round: sdk.Round(i)}}))
+	}
+	require.NoError(t, writer.Close())
+
+	importer := New()
+	cfg := Config{
+		BlocksDir:     tempdir,
+		RoundsPerFile: 4,
+	}
+	cfgStr, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	_, err = importer.Init(context.Background(), plugins.MakePluginConfig(string(cfgStr)), logger)
+	require.NoError(t, err)
+
+	for i := 0; i < numRounds; i++ {
+		block, err := importer.GetBlock(uint64(i))
+		require.NoError(t, err)
+		require.Equal(t, sdk.Round(i), block.BlockHeader.Round)
+	}
+	_, err = importer.GetBlock(uint64(numRounds))
+	assert.ErrorContains(t, err, "GetBlock(): block not found after (0) attempts")
+}
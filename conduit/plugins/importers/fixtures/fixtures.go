@@ -0,0 +1,395 @@
+package fixturesimporter
+
+import (
+	"crypto/sha512"
+	"fmt"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/algorand/indexer/protocol"
+
+	"github.com/algorand/conduit/conduit/data"
+)
+
+const (
+	mainnet = "mainnet"
+	testnet = "testnet"
+)
+
+// hugePaysetSize is the number of payments of the huge-payset fixture, close
+// to the number which fits in a block.
+const hugePaysetSize = 20000
+
+// roundTime is the time between the timestamps of the fixture rounds.
+const roundTime = 3
+
+// maxPaysetSize is the most transactions a block holds, it is used to number
+// the transactions of the rounds.
+const maxPaysetSize = 100000
+
+// fixture builds the transactions of a round.
+type fixture struct {
+	name        string
+	description string
+	payset      func(hdr sdk.BlockHeader) []sdk.SignedTxnInBlock
+	// header optionally adds fields to the block header.
+	header func(hdr *sdk.BlockHeader)
+}
+
+// fixtures are the available fixtures, in the order they are imported by
+// default.
+var fixtures = []fixture{
+	{name: "empty", description: "a block without transactions", payset: func(sdk.BlockHeader) []sdk.SignedTxnInBlock { return nil }},
+	{name: "huge-payset", description: fmt.Sprintf("%d payments, close to the size limit of a block", hugePaysetSize), payset: hugePayset},
+	{name: "inner-txns", description: "application calls with nested inner transactions, logs and state deltas", payset: innerTxns},
+	{name: "state-proof", description: "a state proof transaction with a sparse reveals map", payset: stateProof, header: stateProofTracking},
+	{name: "asset-lifecycle", description: "asset creation, opt-in, transfer, freeze, clawback, close-out and destruction", payset: assetLifecycle},
+	{name: "group-rekey", description: "an atomic group with a rekey, a transaction signed by the rekeyed address and a close-out", payset: groupRekey},
+	{name: "keyreg", description: "online, offline and non-participating key registrations", payset: keyreg},
+}
+
+// Names returns the names of the fixtures, in the order they are imported by
+// default.
+func Names() []string {
+	names := make([]string, 0, len(fixtures))
+	for _, f := range fixtures {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+// Description returns the description of a fixture, or an empty string if it
+// does not exist.
+func Description(name string) string {
+	if f, ok := lookup(name); ok {
+		return f.description
+	}
+	return ""
+}
+
+func lookup(name string) (fixture, bool) {
+	for _, f := range fixtures {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return fixture{}, false
+}
+
+// Genesis returns the genesis of the fixtures of a network, "mainnet" or
+// "testnet". It has the genesis ID of the network, but not its allocation, so
+// the genesis hash differs from the real network.
+func Genesis(network string) (sdk.Genesis, error) {
+	genesis := sdk.Genesis{
+		SchemaID: "v1.0",
+		Network:  network,
+		Proto:    string(protocol.ConsensusCurrentVersion),
+	}
+	switch network {
+	case mainnet:
+		genesis.RewardsPool = "737777777777777777777777777777777777777777777777777UFEJ2CI"
+		genesis.FeeSink = "Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA"
+		genesis.Timestamp = 1560211200
+	case testnet:
+		genesis.RewardsPool = "7777777777777777777777777777777777777777777777777774MSJUVU"
+		genesis.FeeSink = "A7NMWS3NT3IUDMLVO26ULGXGIIOUQ3ND2TXSER6EBGRZNOBOUIQXHIBGDE"
+		genesis.Timestamp = 1560210489
+	default:
+		return sdk.Genesis{}, fmt.Errorf("unknown fixtures network '%s', must be '%s' or '%s'", network, mainnet, testnet)
+	}
+	return genesis, nil
+}
+
+// Block builds the block of a fixture at a round. Blocks are deterministic,
+// the same network, round and name always return the same block.
+func Block(network string, round uint64, name string) (data.BlockData, error) {
+	f, ok := lookup(name)
+	if !ok {
+		return data.BlockData{}, fmt.Errorf("unknown fixture '%s'", name)
+	}
+	genesis, err := Genesis(network)
+	if err != nil {
+		return data.BlockData{}, err
+	}
+	hdr := sdk.BlockHeader{
+		Round:       sdk.Round(round),
+		TimeStamp:   genesis.Timestamp + int64(round)*roundTime,
+		GenesisID:   genesis.ID(),
+		GenesisHash: genesis.Hash(),
+	}
+	hdr.CurrentProtocol = genesis.Proto
+	hdr.FeeSink, _ = sdk.DecodeAddress(genesis.FeeSink)
+	hdr.RewardsPool, _ = sdk.DecodeAddress(genesis.RewardsPool)
+	if f.header != nil {
+		f.header(&hdr)
+	}
+	blk := data.BlockData{BlockHeader: hdr, Payset: f.payset(hdr)}
+	for idx := range blk.Payset {
+		blk.Payset[idx].HasGenesisID = true
+		blk.Payset[idx].HasGenesisHash = true
+	}
+	blk.BlockHeader.TxnCounter = round*maxPaysetSize + uint64(countTxns(blk.Payset))
+	return blk, nil
+}
+
+// countTxns counts the transactions of a payset, including the inner
+// transactions.
+func countTxns(payset []sdk.SignedTxnInBlock) int {
+	var count func(stxn sdk.SignedTxnWithAD) int
+	count = func(stxn sdk.SignedTxnWithAD) int {
+		n := 1
+		for _, inner := range stxn.EvalDelta.InnerTxns {
+			n += count(inner)
+		}
+		return n
+	}
+	total := 0
+	for _, stxn := range payset {
+		total += count(stxn.SignedTxnWithAD)
+	}
+	return total
+}
+
+// address returns a deterministic address for the fixtures.
+func address(seed int) sdk.Address {
+	return sdk.Address(sha512.Sum512_256([]byte(fmt.Sprintf("conduit-fixture-%d", seed))))
+}
+
+// digest returns a deterministic digest for the fixtures.
+func digest(seed string) sdk.GenericDigest {
+	d := sha512.Sum512_256([]byte(seed))
+	return d[:]
+}
+
+// txn returns a transaction of a type, valid from the round of the block.
+func txn(hdr sdk.BlockHeader, txType sdk.TxType, sender sdk.Address) sdk.SignedTxnInBlock {
+	var stxn sdk.SignedTxnInBlock
+	stxn.Txn.Type = txType
+	stxn.Txn.Sender = sender
+	stxn.Txn.Fee = 1000
+	stxn.Txn.FirstValid = hdr.Round
+	stxn.Txn.LastValid = hdr.Round + 1000
+	stxn.Sig = sdk.Signature(sha512.Sum512([]byte(fmt.Sprintf("%s-%d-%d", txType, sender[0], hdr.Round))))
+	return stxn
+}
+
+// inner returns an inner transaction, which has no signature or fee.
+func inner(txType sdk.TxType, sender sdk.Address) sdk.SignedTxnWithAD {
+	var stxn sdk.SignedTxnWithAD
+	stxn.Txn.Type = txType
+	stxn.Txn.Sender = sender
+	return stxn
+}
+
+func hugePayset(hdr sdk.BlockHeader) []sdk.SignedTxnInBlock {
+	payset := make([]sdk.SignedTxnInBlock, hugePaysetSize)
+	for i := range payset {
+		payset[i] = txn(hdr, sdk.PaymentTx, address(i%100))
+		payset[i].Txn.Receiver = address(100 + i%1000)
+		payset[i].Txn.Amount = sdk.MicroAlgos(uint64(i) + 1)
+		payset[i].Txn.Note = []byte(fmt.Sprintf("payment %d", i))
+	}
+	return payset
+}
+
+func innerTxns(hdr sdk.BlockHeader) []sdk.SignedTxnInBlock {
+	const appID = 1000
+	appAddr := sdk.Address(sha512.Sum512_256(append([]byte("appID"), 0, 0, 0, 0, 0, 0, 0x03, 0xe8)))
+
+	owner := address(0)
+	call := txn(hdr, sdk.ApplicationCallTx, owner)
+	call.Txn.ApplicationID = appID
+	call.Txn.ApplicationArgs = [][]byte{[]byte("mint"), {0, 0, 0, 0, 0, 0, 0, 42}}
+	call.Txn.Accounts = []sdk.Address{address(1)}
+	call.Txn.ForeignApps = []sdk.AppIndex{appID + 1}
+	call.Txn.BoxReferences = []sdk.BoxReference{{ForeignAppIdx: 0, Name: []byte("balances")}}
+	call.EvalDelta.GlobalDelta = sdk.StateDelta{
+		"counter": {Action: sdk.SetUintAction, Uint: 7},
+		"owner":   {Action: sdk.SetBytesAction, Bytes: string(owner[:])},
+		"stale":   {Action: sdk.DeleteAction},
+	}
+	call.EvalDelta.LocalDeltas = map[uint64]sdk.StateDelta{
+		1: {"minted": {Action: sdk.SetUintAction, Uint: 42}},
+	}
+	call.EvalDelta.Logs = []string{"minted", string([]byte{0, 1, 2, 255})}
+
+	// The application pays, creates an asset and calls a second application,
+	// which pays in turn.
+	pay := inner(sdk.PaymentTx, appAddr)
+	pay.Txn.Receiver = address(1)
+	pay.Txn.Amount = 100000
+
+	create := inner(sdk.AssetConfigTx, appAddr)
+	create.Txn.AssetParams = sdk.AssetParams{Total: 42, UnitName: "FIX", AssetName: "Fixture", Manager: appAddr}
+	create.ConfigAsset = 2000
+
+	nested := inner(sdk.ApplicationCallTx, appAddr)
+	nested.Txn.ApplicationID = appID + 1
+	nestedPay := inner(sdk.PaymentTx, address(2))
+	nestedPay.Txn.Receiver = address(1)
+	nestedPay.Txn.Amount = 1
+	nested.EvalDelta.InnerTxns = []sdk.SignedTxnWithAD{nestedPay}
+	nested.EvalDelta.Logs = []string{"nested"}
+
+	call.EvalDelta.InnerTxns = []sdk.SignedTxnWithAD{pay, create, nested}
+
+	// An application creation, which has the ID in the apply data.
+	appCreate := txn(hdr, sdk.ApplicationCallTx, address(3))
+	appCreate.Txn.ApprovalProgram = []byte{0x08, 0x81, 0x01}
+	appCreate.Txn.ClearStateProgram = []byte{0x08, 0x81, 0x01}
+	appCreate.Txn.GlobalStateSchema = sdk.StateSchema{NumUint: 1, NumByteSlice: 1}
+	appCreate.Txn.LocalStateSchema = sdk.StateSchema{NumUint: 1}
+	appCreate.Txn.ExtraProgramPages = 1
+	appCreate.ApplicationID = appID + 2
+
+	return []sdk.SignedTxnInBlock{call, appCreate}
+}
+
+func stateProofTracking(hdr *sdk.BlockHeader) {
+	hdr.StateProofTracking = map[sdk.StateProofType]sdk.StateProofTrackingData{
+		sdk.StateProofBasic: {
+			StateProofVotersCommitment:  digest("voters"),
+			StateProofOnlineTotalWeight: 1000000000000,
+			StateProofNextRound:         hdr.Round + 256,
+		},
+	}
+}
+
+func stateProof(hdr sdk.BlockHeader) []sdk.SignedTxnInBlock {
+	// State proof transactions are sent by a special address without a fee
+	// or a signature.
+	var stxn sdk.SignedTxnInBlock
+	stxn.Txn.Type = sdk.StateProofTx
+	stxn.Txn.Sender, _ = sdk.DecodeAddress("XM6FEYVJ2XDU2IBH4OT6VZGW75YM63CM4TC6AV6BD3JZXFJUIICYTVB5EU")
+	stxn.Txn.FirstValid = hdr.Round
+	stxn.Txn.LastValid = hdr.Round + 1000
+
+	var reveal sdk.Reveal
+	reveal.SigSlot.L = 15000000000
+	reveal.SigSlot.Sig.Signature = sdk.MerkleSignature(digest("signature"))
+	reveal.SigSlot.Sig.VectorCommitmentIndex = 3
+	reveal.SigSlot.Sig.Proof.Path = []sdk.GenericDigest{digest("sig-path-0")}
+	reveal.SigSlot.Sig.Proof.TreeDepth = 1
+	reveal.Part.Weight = 3000000000
+	reveal.Part.PK.KeyLifetime = 256
+	reveal.Part.PK.Commitment[0] = 1
+
+	stxn.Txn.StateProofType = sdk.StateProofBasic
+	stxn.Txn.StateProof = sdk.StateProof{
+		SigCommit:    digest("sig-commit"),
+		SignedWeight: 900000000000,
+		SigProofs:    sdk.Proof{Path: []sdk.GenericDigest{digest("sig-proof-0"), digest("sig-proof-1")}, TreeDepth: 2},
+		PartProofs:   sdk.Proof{Path: []sdk.GenericDigest{digest("part-proof-0")}, TreeDepth: 1},
+		// The reveals are sparse, keyed by the position in the participants.
+		Reveals:           map[uint64]sdk.Reveal{3: reveal, 1025: reveal},
+		PositionsToReveal: []uint64{3, 1025, 3},
+	}
+	stxn.Txn.Message = sdk.Message{
+		BlockHeadersCommitment: digest("headers"),
+		VotersCommitment:       digest("voters"),
+		LnProvenWeight:         2334,
+		FirstAttestedRound:     1,
+		LastAttestedRound:      256,
+	}
+	return []sdk.SignedTxnInBlock{stxn}
+}
+
+func assetLifecycle(hdr sdk.BlockHeader) []sdk.SignedTxnInBlock {
+	const assetID = 3000
+	creator, holder := address(0), address(1)
+
+	create := txn(hdr, sdk.AssetConfigTx, creator)
+	create.Txn.AssetParams = sdk.AssetParams{
+		Total:         1 << 63,
+		Decimals:      19,
+		DefaultFrozen: true,
+		UnitName:      "ÜNIT",
+		AssetName:     "Fixture \"asset\" <&>",
+		URL:           "https://example.com/asset#fixture",
+		Manager:       creator,
+		Reserve:       creator,
+		Freeze:        creator,
+		Clawback:      creator,
+	}
+	create.Txn.AssetParams.MetadataHash[0] = 0xff
+	create.ConfigAsset = assetID
+
+	optIn := txn(hdr, sdk.AssetTransferTx, holder)
+	optIn.Txn.XferAsset = assetID
+	optIn.Txn.AssetReceiver = holder
+
+	unfreeze := txn(hdr, sdk.AssetFreezeTx, creator)
+	unfreeze.Txn.FreezeAsset = assetID
+	unfreeze.Txn.FreezeAccount = holder
+
+	transfer := txn(hdr, sdk.AssetTransferTx, creator)
+	transfer.Txn.XferAsset = assetID
+	transfer.Txn.AssetAmount = 1 << 62
+	transfer.Txn.AssetReceiver = holder
+
+	clawback := txn(hdr, sdk.AssetTransferTx, creator)
+	clawback.Txn.XferAsset = assetID
+	clawback.Txn.AssetAmount = 5
+	clawback.Txn.AssetSender = holder
+	clawback.Txn.AssetReceiver = creator
+
+	closeOut := txn(hdr, sdk.AssetTransferTx, holder)
+	closeOut.Txn.XferAsset = assetID
+	closeOut.Txn.AssetReceiver = creator
+	closeOut.Txn.AssetCloseTo = creator
+	closeOut.AssetClosingAmount = 1<<62 - 5
+
+	destroy := txn(hdr, sdk.AssetConfigTx, creator)
+	destroy.Txn.ConfigAsset = assetID
+
+	return []sdk.SignedTxnInBlock{create, optIn, unfreeze, transfer, clawback, closeOut, destroy}
+}
+
+func groupRekey(hdr sdk.BlockHeader) []sdk.SignedTxnInBlock {
+	group := sdk.Digest(sha512.Sum512_256([]byte("group")))
+	account, key := address(0), address(1)
+
+	rekey := txn(hdr, sdk.PaymentTx, account)
+	rekey.Txn.Receiver = account
+	rekey.Txn.RekeyTo = key
+	rekey.Txn.Group = group
+	rekey.Txn.Lease[0] = 1
+
+	// The transaction is signed by the rekeyed address, with a logic
+	// signature.
+	pay := txn(hdr, sdk.PaymentTx, account)
+	pay.Txn.Receiver = address(2)
+	pay.Txn.Amount = 500000
+	pay.Txn.Group = group
+	pay.AuthAddr = key
+	pay.Sig = sdk.Signature{}
+	pay.Lsig.Logic = []byte{0x08, 0x81, 0x01}
+	pay.Lsig.Args = [][]byte{[]byte("arg")}
+
+	closeOut := txn(hdr, sdk.PaymentTx, address(2))
+	closeOut.Txn.Receiver = account
+	closeOut.Txn.Amount = 1
+	closeOut.Txn.CloseRemainderTo = account
+	closeOut.Txn.Group = group
+	closeOut.ClosingAmount = 499000
+	closeOut.SenderRewards = 12
+	closeOut.CloseRewards = 3
+
+	return []sdk.SignedTxnInBlock{rekey, pay, closeOut}
+}
+
+func keyreg(hdr sdk.BlockHeader) []sdk.SignedTxnInBlock {
+	online := txn(hdr, sdk.KeyRegistrationTx, address(0))
+	online.Txn.VotePK[0] = 1
+	online.Txn.SelectionPK[0] = 2
+	online.Txn.StateProofPK[0] = 3
+	online.Txn.VoteFirst = hdr.Round
+	online.Txn.VoteLast = hdr.Round + 3000000
+	online.Txn.VoteKeyDilution = 1733
+
+	offline := txn(hdr, sdk.KeyRegistrationTx, address(1))
+
+	nonpart := txn(hdr, sdk.KeyRegistrationTx, address(2))
+	nonpart.Txn.Nonparticipation = true
+
+	return []sdk.SignedTxnInBlock{online, offline, nonpart}
+}
@@ -0,0 +1,104 @@
+package fixturesimporter
+
+import (
+	"context"
+	_ "embed" // used to embed config
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// PluginName to use when configuring.
+const PluginName = "fixtures"
+
+type fixturesImporter struct {
+	logger *logrus.Logger
+	cfg    Config
+}
+
+// New initializes a fixtures importer
+func New() importers.Importer {
+	return &fixturesImporter{}
+}
+
+//go:embed sample.yaml
+var sampleConfig string
+
+var metadata = conduit.Metadata{
+	Name:         PluginName,
+	Description:  "Importer for built-in blocks covering edge cases such as huge paysets, inner transactions and state proofs, without network access.",
+	Deprecated:   false,
+	SampleConfig: sampleConfig,
+}
+
+func (imp *fixturesImporter) Metadata() conduit.Metadata {
+	return metadata
+}
+
+// package-wide init function
+func init() {
+	importers.Register(PluginName, importers.ImporterConstructorFunc(func() importers.Importer {
+		return &fixturesImporter{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Importer, PluginName, Config{})
+}
+
+func (imp *fixturesImporter) Init(_ context.Context, cfg plugins.PluginConfig, logger *logrus.Logger) (*sdk.Genesis, error) {
+	imp.logger = logger
+	err := cfg.UnmarshalConfig(&imp.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %v", err)
+	}
+	if imp.cfg.Network == "" {
+		imp.cfg.Network = mainnet
+	}
+	if len(imp.cfg.Fixtures) == 0 {
+		imp.cfg.Fixtures = Names()
+	}
+	for _, name := range imp.cfg.Fixtures {
+		if _, ok := lookup(name); !ok {
+			return nil, fmt.Errorf("Init(): unknown fixture '%s', available fixtures are %v", name, Names())
+		}
+	}
+
+	genesis, err := Genesis(imp.cfg.Network)
+	if err != nil {
+		return nil, fmt.Errorf("Init(): %w", err)
+	}
+	return &genesis, nil
+}
+
+func (imp *fixturesImporter) Config() string {
+	s, _ := yaml.Marshal(imp.cfg)
+	return string(s)
+}
+
+func (imp *fixturesImporter) Close() error {
+	return nil
+}
+
+func (imp *fixturesImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	if rnd >= uint64(len(imp.cfg.Fixtures)) {
+		return data.BlockData{}, fmt.Errorf("GetBlock(): round %d is past the last fixture (round %d), set rounds.end to stop the pipeline after it", rnd, len(imp.cfg.Fixtures)-1)
+	}
+	name := imp.cfg.Fixtures[rnd]
+	blk, err := Block(imp.cfg.Network, rnd, name)
+	if err != nil {
+		return data.BlockData{}, fmt.Errorf("GetBlock(): %w", err)
+	}
+	imp.logger.Infof("Block %d is the %s fixture", rnd, name)
+	return blk, nil
+}
+
+// LatestRound returns the round of the last fixture.
+func (imp *fixturesImporter) LatestRound() (uint64, error) {
+	return uint64(len(imp.cfg.Fixtures) - 1), nil
+}
@@ -0,0 +1,17 @@
+package fixturesimporter
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+//Name: conduit_importers_fixtures
+
+// Config specific to the fixtures importer
+type Config struct {
+	/* <code>network</code> is the network of the blocks, either <code>mainnet</code> (default) or <code>testnet</code>.<br/>
+	The blocks have the genesis ID of the network, the genesis hash differs from the real network.
+	*/
+	Network string `yaml:"network"`
+	/* <code>fixtures</code> are the names of the fixtures to import, round 0 is the first fixture.<br/>
+	A fixture may be listed more than once. By default every fixture is imported once.
+	*/
+	Fixtures []string `yaml:"fixtures"`
+}
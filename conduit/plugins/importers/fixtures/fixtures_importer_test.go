@@ -0,0 +1,103 @@
+package fixturesimporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+func initializeImporter(t *testing.T, cfg Config) (importers.Importer, *sdk.Genesis) {
+	logger, _ := test.NewNullLogger()
+	importer := New()
+	cfgStr, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	genesis, err := importer.Init(context.Background(), plugins.MakePluginConfig(string(cfgStr)), logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { importer.Close() })
+	return importer, genesis
+}
+
+func TestImporterMetadata(t *testing.T) {
+	m := New().Metadata()
+	assert.Equal(t, metadata.Name, m.Name)
+	assert.Equal(t, metadata.Description, m.Description)
+}
+
+func TestInitErrors(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	_, err := New().Init(context.Background(), plugins.MakePluginConfig("network: betanet"), logger)
+	assert.ErrorContains(t, err, "unknown fixtures network 'betanet'")
+	_, err = New().Init(context.Background(), plugins.MakePluginConfig("fixtures: [missing]"), logger)
+	assert.ErrorContains(t, err, "unknown fixture 'missing'")
+}
+
+// TestGetBlock tests that each round is the configured fixture, with the
+// genesis of the network.
+func TestGetBlock(t *testing.T) {
+	importer, genesis := initializeImporter(t, Config{Network: testnet, Fixtures: []string{"keyreg", "empty", "keyreg"}})
+	assert.Equal(t, "testnet-v1.0", genesis.ID())
+
+	for rnd, size := range []int{3, 0, 3} {
+		blk, err := importer.GetBlock(uint64(rnd))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(rnd), blk.Round())
+		assert.Equal(t, "testnet-v1.0", blk.BlockHeader.GenesisID)
+		assert.Equal(t, genesis.Hash(), blk.BlockHeader.GenesisHash)
+		assert.Len(t, blk.Payset, size)
+	}
+	_, err := importer.GetBlock(3)
+	assert.EqualError(t, err, "GetBlock(): round 3 is past the last fixture (round 2), set rounds.end to stop the pipeline after it")
+
+	latest, err := importer.(*fixturesImporter).LatestRound()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), latest)
+}
+
+// TestFixtures tests that every fixture is deterministic and survives a
+// msgpack round trip, as blocks do between algod and the pipeline.
+func TestFixtures(t *testing.T) {
+	importer, _ := initializeImporter(t, Config{})
+	for rnd, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			assert.NotEmpty(t, Description(name))
+			blk, err := importer.GetBlock(uint64(rnd))
+			require.NoError(t, err)
+			again, err := Block(mainnet, uint64(rnd), name)
+			require.NoError(t, err)
+			assert.Equal(t, msgpack.Encode(blk), msgpack.Encode(again))
+
+			var decoded sdk.Block
+			require.NoError(t, msgpack.Decode(msgpack.Encode(sdk.Block{BlockHeader: blk.BlockHeader, Payset: blk.Payset}), &decoded))
+			assert.Equal(t, blk.BlockHeader, decoded.BlockHeader)
+			assert.Len(t, decoded.Payset, len(blk.Payset))
+		})
+	}
+}
+
+func TestFixtureContents(t *testing.T) {
+	blk, err := Block(mainnet, 1, "huge-payset")
+	require.NoError(t, err)
+	assert.Len(t, blk.Payset, hugePaysetSize)
+
+	blk, err = Block(mainnet, 2, "inner-txns")
+	require.NoError(t, err)
+	require.Len(t, blk.Payset[0].EvalDelta.InnerTxns, 3)
+	assert.Len(t, blk.Payset[0].EvalDelta.InnerTxns[2].EvalDelta.InnerTxns, 1)
+	assert.Equal(t, uint64(2*maxPaysetSize+6), blk.BlockHeader.TxnCounter)
+
+	blk, err = Block(mainnet, 3, "state-proof")
+	require.NoError(t, err)
+	assert.Equal(t, sdk.StateProofTx, blk.Payset[0].Txn.Type)
+	assert.Len(t, blk.Payset[0].Txn.StateProof.Reveals, 2)
+	assert.Contains(t, blk.BlockHeader.StateProofTracking, sdk.StateProofBasic)
+}
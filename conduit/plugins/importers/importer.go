@@ -32,3 +32,30 @@ type Importer interface {
 	// It returns an object of type BlockData defined in data
 	GetBlock(rnd uint64) (data.BlockData, error)
 }
+
+// SubscribingImporter is for importers which can push blocks as they become
+// available, for example from a node which is following the chain. The
+// pipeline prefers Subscribe over calling GetBlock for each round.
+type SubscribingImporter interface {
+	Importer
+
+	// Subscribe sends the blocks starting at startRound, in round order, on
+	// the returned block channel. A failure is sent on the error channel and
+	// ends the subscription, the pipeline then subscribes again from the
+	// first round it did not receive. The importer stops sending when ctx is
+	// cancelled.
+	Subscribe(ctx context.Context, startRound uint64) (<-chan data.BlockData, <-chan error)
+}
+
+// NetworkIdentifier is for importers of networks which are not identified by
+// the hash of an Algorand genesis, such as private AVM forks whose genesis
+// format differs. By default the network ID is the base64 genesis hash.
+type NetworkIdentifier interface {
+	Importer
+
+	// NetworkID will be called by the Conduit framework after Init with the
+	// returned genesis. It returns an opaque, non-empty ID which is saved in
+	// the pipeline metadata and must be the same on every restart. The
+	// genesis hash of the blocks is not validated for these importers.
+	NetworkID(genesis *sdk.Genesis) (string, error)
+}
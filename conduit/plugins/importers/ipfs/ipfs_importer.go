@@ -0,0 +1,211 @@
+package ipfsimporter
+
+import (
+	"context"
+	_ "embed" // used to embed config
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters/filewriter"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// PluginName to use when configuring.
+const PluginName = "ipfs"
+
+const (
+	defaultGateway         = "https://ipfs.io"
+	defaultTimeout         = 30 * time.Second
+	defaultManifestRefresh = 10 * time.Minute
+	cacheDirName           = "cache"
+	genesisFile            = "genesis.json"
+)
+
+type ipfsImporter struct {
+	logger *logrus.Logger
+	cfg    Config
+	ctx    context.Context
+	cancel context.CancelFunc
+	client *gatewayClient
+
+	mu       sync.Mutex
+	manifest *manifest
+	// refreshed is when the manifest was last fetched.
+	refreshed time.Time
+	// meter counts the traffic with the gateways, it is nil unless the
+	// pipeline accounts for the bandwidth.
+	meter conduit.TrafficMeter
+}
+
+// New initializes an IPFS importer
+func New() importers.Importer {
+	return &ipfsImporter{}
+}
+
+//go:embed sample.yaml
+var sampleConfig string
+
+var metadata = conduit.Metadata{
+	Name:         PluginName,
+	Description:  "Importer for fetching archived block files published to IPFS.",
+	Deprecated:   false,
+	SampleConfig: sampleConfig,
+}
+
+func (r *ipfsImporter) Metadata() conduit.Metadata {
+	return metadata
+}
+
+// package-wide init function
+func init() {
+	importers.Register(PluginName, importers.ImporterConstructorFunc(func() importers.Importer {
+		return &ipfsImporter{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Importer, PluginName, Config{})
+}
+
+func (r *ipfsImporter) Init(ctx context.Context, cfg plugins.PluginConfig, logger *logrus.Logger) (*sdk.Genesis, error) {
+	r.logger = logger
+	err := cfg.UnmarshalConfig(&r.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %v", err)
+	}
+	if !strings.HasPrefix(r.cfg.Manifest, "/ipfs/") && !strings.HasPrefix(r.cfg.Manifest, "/ipns/") {
+		return nil, fmt.Errorf("Init(): manifest must be an /ipfs/ or /ipns/ path, found '%s'", r.cfg.Manifest)
+	}
+	if len(r.cfg.Gateways) == 0 {
+		r.cfg.Gateways = []string{defaultGateway}
+	}
+	if r.cfg.Timeout == 0 {
+		r.cfg.Timeout = defaultTimeout
+	}
+	if r.cfg.ManifestRefresh == 0 {
+		r.cfg.ManifestRefresh = defaultManifestRefresh
+	}
+	if r.cfg.CacheDir == "" && cfg.DataDir != "" {
+		r.cfg.CacheDir = path.Join(cfg.DataDir, cacheDirName)
+	}
+	if r.cfg.FilenamePattern == "" {
+		r.cfg.FilenamePattern = filewriter.FilePattern
+	}
+
+	gateways := make([]string, 0, len(r.cfg.Gateways))
+	for _, gateway := range r.cfg.Gateways {
+		gateways = append(gateways, strings.TrimSuffix(gateway, "/"))
+	}
+	r.client = &gatewayClient{
+		client:   &http.Client{Timeout: r.cfg.Timeout, Transport: conduit.MeteredTransport(r.meter)},
+		gateways: gateways,
+		cacheDir: r.cfg.CacheDir,
+	}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+
+	if err = r.refreshManifest(); err != nil {
+		return nil, fmt.Errorf("Init(): %w", err)
+	}
+	b, _, err := r.client.fetch(r.ctx, "/ipfs/"+r.manifest.Genesis)
+	if err != nil {
+		return nil, fmt.Errorf("Init(): unable to fetch genesis: %w", err)
+	}
+	var genesis sdk.Genesis
+	err = filewriter.DecodeJSONFromBytes(genesisFile, b, &genesis, false)
+	if err != nil {
+		return nil, fmt.Errorf("Init(): failed to process genesis file: %w", err)
+	}
+	if last, ok := r.manifest.lastRound(); ok {
+		r.logger.Infof("Archive manifest %s has %d ranges up to round %d", r.cfg.Manifest, len(r.manifest.Ranges), last)
+	}
+	return &genesis, nil
+}
+
+// refreshManifest fetches the manifest, an "/ipfs/" manifest is served from
+// the cache after the first time.
+func (r *ipfsImporter) refreshManifest() error {
+	b, _, err := r.client.fetch(r.ctx, r.cfg.Manifest)
+	if err != nil {
+		return fmt.Errorf("refreshManifest(): unable to fetch manifest: %w", err)
+	}
+	m, err := decodeManifest(b)
+	if err != nil {
+		return fmt.Errorf("refreshManifest(): %w", err)
+	}
+	r.manifest = m
+	r.refreshed = time.Now()
+	return nil
+}
+
+// findRange returns the range containing rnd. An "/ipns/" manifest is fetched
+// again for rounds which are not in it, at most every manifest-refresh.
+func (r *ipfsImporter) findRange(rnd uint64) (roundRange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rr, ok := r.manifest.find(rnd); ok {
+		return rr, nil
+	}
+	if strings.HasPrefix(r.cfg.Manifest, "/ipns/") && time.Since(r.refreshed) >= r.cfg.ManifestRefresh {
+		if err := r.refreshManifest(); err != nil {
+			return roundRange{}, err
+		}
+		if rr, ok := r.manifest.find(rnd); ok {
+			return rr, nil
+		}
+	}
+	if last, ok := r.manifest.lastRound(); ok {
+		return roundRange{}, fmt.Errorf("round %d is not in the archive manifest, the last archived round is %d", rnd, last)
+	}
+	return roundRange{}, fmt.Errorf("round %d is not in the archive manifest, it has no rounds", rnd)
+}
+
+// SetTrafficMeter counts the bytes exchanged with each gateway.
+func (r *ipfsImporter) SetTrafficMeter(meter conduit.TrafficMeter) {
+	r.meter = meter
+}
+
+func (r *ipfsImporter) Config() string {
+	s, _ := yaml.Marshal(r.cfg)
+	return string(s)
+}
+
+func (r *ipfsImporter) Close() error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	return nil
+}
+
+func (r *ipfsImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	rr, err := r.findRange(rnd)
+	if err != nil {
+		return data.BlockData{}, fmt.Errorf("GetBlock(): %w", err)
+	}
+
+	start := time.Now()
+	name := fmt.Sprintf(r.cfg.FilenamePattern, rnd)
+	b, cached, err := r.client.fetch(r.ctx, "/ipfs/"+rr.CID+"/"+name)
+	if err != nil {
+		return data.BlockData{}, fmt.Errorf("GetBlock(): %w", err)
+	}
+	var blockData data.BlockData
+	err = filewriter.DecodeJSONFromBytes(name, b, &blockData, false)
+	if err != nil {
+		return data.BlockData{}, fmt.Errorf("GetBlock(): unable to decode block file '%s' in %s: %w", name, rr.CID, err)
+	}
+	if cached {
+		r.logger.Infof("Block %d read time: %s (cached)", rnd, time.Since(start))
+	} else {
+		r.logger.Infof("Block %d read time: %s", rnd, time.Since(start))
+	}
+	return blockData, nil
+}
@@ -0,0 +1,36 @@
+package ipfsimporter
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+import "time"
+
+//Name: conduit_importers_ipfs
+
+// Config specific to the IPFS importer
+type Config struct {
+	/* <code>manifest</code> is the IPFS path of the archive manifest, "/ipfs/CID" for a fixed archive or "/ipns/NAME" for an archive which is extended by its publisher.<br/>
+	The manifest is a JSON document with the CID of the genesis file and the CID of the directory holding each range of rounds:
+
+	{"genesis": "CID", "ranges": [{"first": 0, "last": 99999, "cid": "CID"}]}
+	*/
+	Manifest string `yaml:"manifest"`
+	/* <code>gateways</code> are the HTTP gateways which serve the archive, they are tried in order until one returns the file.<br/>
+	Gateways of Filecoin retrieval providers which serve the "/ipfs/" paths are supported as well. The default is "https://ipfs.io".
+	*/
+	Gateways []string `yaml:"gateways"`
+	// <code>timeout</code> is how long a request to a gateway may take, the default is 30s.
+	Timeout time.Duration `yaml:"timeout"`
+	/* <code>cache-dir</code> is where the manifest, genesis and block files are cached, files are identified by their CID so the cache never becomes stale.<br/>
+	The default is the "cache" directory in the plugin data directory.
+	*/
+	CacheDir string `yaml:"cache-dir"`
+	/* <code>manifest-refresh</code> is how often an "/ipns/" manifest is fetched again when a round is past its last range, the default is 10m.
+	 */
+	ManifestRefresh time.Duration `yaml:"manifest-refresh"`
+	/* <code>filename-pattern</code> is the format used to find block files in the range directories. It uses go string formatting and should accept one number for the round.
+	The default pattern is
+
+	"%[1]d_block.json"
+	*/
+	FilenamePattern string `yaml:"filename-pattern"`
+}
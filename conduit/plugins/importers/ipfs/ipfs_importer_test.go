@@ -0,0 +1,206 @@
+package ipfsimporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters/filewriter"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+var testGenesis = sdk.Genesis{
+	SchemaID:    "test",
+	Network:     "test",
+	Proto:       "test",
+	RewardsPool: "AAAAAAA",
+	FeeSink:     "AAAAAAA",
+	Timestamp:   1234,
+}
+
+// gateway serves files by their IPFS path and counts the requests.
+type gateway struct {
+	*httptest.Server
+	mu       sync.Mutex
+	files    map[string][]byte
+	requests map[string]int
+}
+
+func makeGateway(t *testing.T) *gateway {
+	g := &gateway{files: make(map[string][]byte), requests: make(map[string]int)}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.requests[req.URL.Path]++
+		b, ok := g.files[req.URL.Path]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Write(b)
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func (g *gateway) add(ipfsPath string, b []byte) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.files[ipfsPath] = b
+}
+
+func (g *gateway) count(ipfsPath string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.requests[ipfsPath]
+}
+
+func encodeFile(t *testing.T, name string, v interface{}) []byte {
+	b, err := filewriter.EncodeJSONToBytes(name, v, true)
+	require.NoError(t, err)
+	return b
+}
+
+// addArchive publishes the genesis, the block files of each range and the
+// manifest at manifestPath.
+func (g *gateway) addArchive(t *testing.T, manifestPath string, ranges ...roundRange) {
+	g.add("/ipfs/genesis", encodeFile(t, genesisFile, testGenesis))
+	for _, rr := range ranges {
+		for rnd := rr.First; rnd <= rr.Last; rnd++ {
+			name := fmt.Sprintf(filewriter.FilePattern, rnd)
+			g.add("/ipfs/"+rr.CID+"/"+name, encodeFile(t, name, data.BlockData{BlockHeader: sdk.BlockHeader{Round: sdk.Round(rnd)}}))
+		}
+	}
+	b, err := json.Marshal(manifest{Genesis: "genesis", Ranges: ranges})
+	require.NoError(t, err)
+	g.add(manifestPath, b)
+}
+
+func initializeImporter(t *testing.T, dataDir string, cfg Config) (importers.Importer, *test.Hook) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	cfgStr, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	importer := New()
+	genesis, err := importer.Init(context.Background(), plugins.PluginConfig{DataDir: dataDir, Config: string(cfgStr)}, logger)
+	require.NoError(t, err)
+	require.Equal(t, testGenesis, *genesis)
+	t.Cleanup(func() { importer.Close() })
+	return importer, hook
+}
+
+func TestImporterMetadata(t *testing.T) {
+	testImporter := New()
+	m := testImporter.Metadata()
+	assert.Equal(t, metadata.Name, m.Name)
+	assert.Equal(t, metadata.Description, m.Description)
+	assert.Equal(t, metadata.Deprecated, m.Deprecated)
+}
+
+func TestGetBlock(t *testing.T) {
+	g := makeGateway(t)
+	// the ranges are sorted by the importer.
+	g.addArchive(t, "/ipfs/manifest", roundRange{First: 10, Last: 19, CID: "second"}, roundRange{First: 0, Last: 9, CID: "first"})
+	dataDir := t.TempDir()
+
+	importer, hook := initializeImporter(t, dataDir, Config{Manifest: "/ipfs/manifest", Gateways: []string{g.URL + "/"}})
+	for _, rnd := range []uint64{0, 5, 12, 19, 3} {
+		block, err := importer.GetBlock(rnd)
+		require.NoError(t, err)
+		assert.Equal(t, sdk.Round(rnd), block.BlockHeader.Round)
+	}
+	assert.Contains(t, hook.LastEntry().Message, "Block 3 read time")
+	assert.NotContains(t, hook.LastEntry().Message, "(cached)")
+
+	_, err := importer.GetBlock(20)
+	assert.EqualError(t, err, "GetBlock(): round 20 is not in the archive manifest, the last archived round is 19")
+
+	// the files are cached in the plugin data directory.
+	_, err = os.Stat(path.Join(dataDir, cacheDirName, "first", "5_block.json"))
+	require.NoError(t, err)
+	importer, hook = initializeImporter(t, dataDir, Config{Manifest: "/ipfs/manifest", Gateways: []string{g.URL}})
+	_, err = importer.GetBlock(5)
+	require.NoError(t, err)
+	assert.Contains(t, hook.LastEntry().Message, "(cached)")
+	assert.Equal(t, 1, g.count("/ipfs/manifest"))
+	assert.Equal(t, 1, g.count("/ipfs/genesis"))
+	assert.Equal(t, 1, g.count("/ipfs/first/5_block.json"))
+}
+
+// TestGatewayFallback tests that the gateways are tried in order.
+func TestGatewayFallback(t *testing.T) {
+	down := makeGateway(t)
+	g := makeGateway(t)
+	g.addArchive(t, "/ipfs/manifest", roundRange{First: 0, Last: 1, CID: "blocks"})
+
+	importer, _ := initializeImporter(t, t.TempDir(), Config{Manifest: "/ipfs/manifest", Gateways: []string{down.URL, g.URL}})
+	block, err := importer.GetBlock(1)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.Round(1), block.BlockHeader.Round)
+	assert.Equal(t, 1, down.count("/ipfs/blocks/1_block.json"))
+
+	g.Close()
+	_, err = importer.GetBlock(0)
+	assert.ErrorContains(t, err, "GetBlock(): fetch(): /ipfs/blocks/0_block.json not available from any gateway: ")
+	assert.ErrorContains(t, err, "404 Not Found")
+}
+
+// TestIPNSManifestRefresh tests that a mutable manifest is fetched again for
+// rounds past its last range, and never cached.
+func TestIPNSManifestRefresh(t *testing.T) {
+	g := makeGateway(t)
+	first := roundRange{First: 0, Last: 4, CID: "first"}
+	g.addArchive(t, "/ipns/archive", first)
+
+	importer, _ := initializeImporter(t, t.TempDir(), Config{Manifest: "/ipns/archive", Gateways: []string{g.URL}, ManifestRefresh: time.Nanosecond})
+	_, err := importer.GetBlock(5)
+	assert.EqualError(t, err, "GetBlock(): round 5 is not in the archive manifest, the last archived round is 4")
+	assert.Equal(t, 2, g.count("/ipns/archive"))
+
+	g.addArchive(t, "/ipns/archive", first, roundRange{First: 5, Last: 9, CID: "second"})
+	block, err := importer.GetBlock(5)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.Round(5), block.BlockHeader.Round)
+	assert.Equal(t, 3, g.count("/ipns/archive"))
+}
+
+func TestInitErrors(t *testing.T) {
+	g := makeGateway(t)
+	g.add("/ipfs/bad", []byte(`{"genesis": "genesis", "ranges": [{"first": 5, "last": 1, "cid": "blocks"}]}`))
+	g.add("/ipfs/traversal", []byte(`{"genesis": "../genesis"}`))
+	tests := []struct {
+		name     string
+		manifest string
+		err      string
+	}{
+		{"not an ipfs path", "https://example.com/manifest", "Init(): manifest must be an /ipfs/ or /ipns/ path, found 'https://example.com/manifest'"},
+		{"missing", "/ipfs/missing", "Init(): refreshManifest(): unable to fetch manifest: fetch(): /ipfs/missing not available from any gateway"},
+		{"invalid range", "/ipfs/bad", "Init(): refreshManifest(): decodeManifest(): range blocks first round 5 is after its last round 1"},
+		{"invalid cid", "/ipfs/traversal", "Init(): refreshManifest(): decodeManifest(): invalid genesis CID '../genesis'"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfgStr, err := yaml.Marshal(Config{Manifest: tc.manifest, Gateways: []string{g.URL}})
+			require.NoError(t, err)
+			logger, _ := test.NewNullLogger()
+			_, err = New().Init(context.Background(), plugins.PluginConfig{DataDir: t.TempDir(), Config: string(cfgStr)}, logger)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
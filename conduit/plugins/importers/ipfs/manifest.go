@@ -0,0 +1,164 @@
+package ipfsimporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// cidPattern matches the characters of a base32 or base58 CID, so that a CID
+// can be used as a cache path.
+var cidPattern = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+
+// roundRange is a directory of block files for the rounds [First, Last].
+type roundRange struct {
+	First uint64 `json:"first"`
+	Last  uint64 `json:"last"`
+	CID   string `json:"cid"`
+}
+
+// manifest lists the files of an archive.
+type manifest struct {
+	Genesis string       `json:"genesis"`
+	Ranges  []roundRange `json:"ranges"`
+}
+
+// decodeManifest decodes and validates a manifest, the ranges are sorted by
+// their first round.
+func decodeManifest(b []byte) (*manifest, error) {
+	var m manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("decodeManifest(): unable to decode manifest: %w", err)
+	}
+	if !cidPattern.MatchString(m.Genesis) {
+		return nil, fmt.Errorf("decodeManifest(): invalid genesis CID '%s'", m.Genesis)
+	}
+	for _, r := range m.Ranges {
+		if !cidPattern.MatchString(r.CID) {
+			return nil, fmt.Errorf("decodeManifest(): invalid CID '%s' for rounds %d-%d", r.CID, r.First, r.Last)
+		}
+		if r.First > r.Last {
+			return nil, fmt.Errorf("decodeManifest(): range %s first round %d is after its last round %d", r.CID, r.First, r.Last)
+		}
+	}
+	sort.SliceStable(m.Ranges, func(i, j int) bool { return m.Ranges[i].First < m.Ranges[j].First })
+	return &m, nil
+}
+
+// find returns the range which contains rnd.
+func (m *manifest) find(rnd uint64) (roundRange, bool) {
+	idx := sort.Search(len(m.Ranges), func(i int) bool { return m.Ranges[i].First > rnd })
+	for idx--; idx >= 0; idx-- {
+		if m.Ranges[idx].Last >= rnd {
+			return m.Ranges[idx], true
+		}
+	}
+	return roundRange{}, false
+}
+
+// lastRound returns the last round of the archive.
+func (m *manifest) lastRound() (last uint64, ok bool) {
+	for _, r := range m.Ranges {
+		if !ok || r.Last > last {
+			last, ok = r.Last, true
+		}
+	}
+	return last, ok
+}
+
+// gatewayClient fetches IPFS paths from HTTP gateways, immutable "/ipfs/"
+// paths are cached on disk.
+type gatewayClient struct {
+	client   *http.Client
+	gateways []string
+	cacheDir string
+}
+
+// cachePath returns where an "/ipfs/" path is cached, or "" if it is mutable.
+func (c *gatewayClient) cachePath(ipfsPath string) string {
+	if !strings.HasPrefix(ipfsPath, "/ipfs/") || c.cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(c.cacheDir, filepath.FromSlash(strings.TrimPrefix(ipfsPath, "/ipfs/")))
+}
+
+// fetch returns the contents of an IPFS path and whether it was cached.
+func (c *gatewayClient) fetch(ctx context.Context, ipfsPath string) ([]byte, bool, error) {
+	cached := c.cachePath(ipfsPath)
+	if cached != "" {
+		b, err := os.ReadFile(cached)
+		if err == nil {
+			return b, true, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, false, fmt.Errorf("fetch(): %w", err)
+		}
+	}
+
+	var errs []string
+	for _, gateway := range c.gateways {
+		b, err := c.get(ctx, gateway+ipfsPath)
+		if err == nil {
+			if cached != "" {
+				if err = writeCacheFile(cached, b); err != nil {
+					return nil, false, fmt.Errorf("fetch(): %w", err)
+				}
+			}
+			return b, false, nil
+		}
+		if ctx.Err() != nil {
+			return nil, false, fmt.Errorf("fetch(): %w", ctx.Err())
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, false, fmt.Errorf("fetch(): %s not available from any gateway: %s", ipfsPath, strings.Join(errs, ", "))
+}
+
+func (c *gatewayClient) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", url, err)
+	}
+	return b, nil
+}
+
+// writeCacheFile writes a cache file through a temporary file, so that an
+// interrupted write is never read as a complete file.
+func writeCacheFile(name string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,159 @@
+package pglogical
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/algorand/conduit/conduit/plugins/exporters/partition"
+)
+
+// The changes are read with the test_decoding output plugin, which is shipped
+// with Postgres. Each change is a line of text, for example:
+//
+//	BEGIN
+//	table conduit_staging.blocks: INSERT: round[bigint]:5 block[bytea]:'\x81a3...'
+//	COMMIT
+//
+// The partition tables of the blocks, such as conduit_staging.blocks_2024_05,
+// have the same columns.
+
+// parseLSN parses a WAL location printed as two hexadecimal numbers, such as
+// "16/B374D848".
+func parseLSN(s string) (uint64, error) {
+	hi, lo, ok := cut(s, "/")
+	if !ok {
+		return 0, fmt.Errorf("invalid lsn '%s'", s)
+	}
+	h, err := strconv.ParseUint(hi, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid lsn '%s'", s)
+	}
+	l, err := strconv.ParseUint(lo, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid lsn '%s'", s)
+	}
+	return h<<32 | l, nil
+}
+
+func formatLSN(lsn uint64) string {
+	return fmt.Sprintf("%X/%X", lsn>>32, uint32(lsn))
+}
+
+// cut is strings.Cut, which requires go 1.18.
+func cut(s, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+type changeKind int
+
+const (
+	changeOther changeKind = iota
+	changeBegin
+	changeCommit
+	changeStaged
+)
+
+// change is a decoded change, staged changes are the rounds inserted in the
+// blocks table or its partition tables.
+type change struct {
+	lsn   uint64
+	kind  changeKind
+	round uint64
+	block []byte
+}
+
+// isStagingTable reports whether name is the qualified blocks table, or one
+// of its partition tables.
+func isStagingTable(name, table string) bool {
+	if name == table {
+		return true
+	}
+	i := strings.LastIndex(table, ".")
+	schema, base := table[:i+1], table[i+1:]
+	return strings.HasPrefix(name, schema) && partition.IsPartition(base, strings.TrimPrefix(name, schema))
+}
+
+// parseChange parses a change of the test_decoding plugin, table is the
+// qualified name of the blocks table. Changes of other tables are returned as
+// changeOther.
+func parseChange(lsn uint64, line, table string) (change, error) {
+	c := change{lsn: lsn}
+	name, values, insert := cut(strings.TrimPrefix(line, "table "), ": INSERT: ")
+	switch {
+	case line == "BEGIN" || strings.HasPrefix(line, "BEGIN "):
+		c.kind = changeBegin
+	case line == "COMMIT" || strings.HasPrefix(line, "COMMIT "):
+		c.kind = changeCommit
+	case strings.HasPrefix(line, "table ") && insert && isStagingTable(name, table):
+		round, block, ok := cut(values, " ")
+		if !ok || !strings.HasPrefix(round, "round[bigint]:") || !strings.HasPrefix(block, "block[bytea]:'\\x") || !strings.HasSuffix(block, "'") {
+			return c, fmt.Errorf("unexpected insert at %s: %.100s", formatLSN(lsn), line)
+		}
+		var err error
+		if c.round, err = strconv.ParseUint(strings.TrimPrefix(round, "round[bigint]:"), 10, 64); err != nil {
+			return c, fmt.Errorf("invalid round at %s: %w", formatLSN(lsn), err)
+		}
+		encoded := strings.TrimSuffix(strings.TrimPrefix(block, "block[bytea]:'\\x"), "'")
+		if c.block, err = hex.DecodeString(encoded); err != nil {
+			return c, fmt.Errorf("invalid block of round %d at %s: %w", c.round, formatLSN(lsn), err)
+		}
+		c.kind = changeStaged
+	}
+	return c, nil
+}
+
+// stagedRound is a round read from the replication slot, commit is the end of
+// its transaction.
+type stagedRound struct {
+	round  uint64
+	block  []byte
+	commit uint64
+}
+
+// window is what a peek at the replication slot returned.
+type window struct {
+	// rounds are the committed rounds which are not complete.
+	rounds []stagedRound
+	// safe is the end of the last transaction before the first round which is
+	// not complete, the slot can be advanced to it. It is 0 if there is none.
+	safe uint64
+	// last is the last staged round, 0 if there is none.
+	last uint64
+}
+
+// scanWindow collects the rounds of the committed transactions, the rounds
+// before done are complete.
+func scanWindow(changes []change, done uint64) window {
+	var w window
+	var txn []stagedRound
+	blocked := false
+	for _, c := range changes {
+		switch c.kind {
+		case changeBegin:
+			txn = txn[:0]
+		case changeStaged:
+			txn = append(txn, stagedRound{round: c.round, block: c.block})
+		case changeCommit:
+			for _, r := range txn {
+				if r.round > w.last {
+					w.last = r.round
+				}
+				if r.round >= done {
+					r.commit = c.lsn
+					w.rounds = append(w.rounds, r)
+					blocked = true
+				}
+			}
+			if !blocked {
+				w.safe = c.lsn
+			}
+			txn = txn[:0]
+		}
+	}
+	return w
+}
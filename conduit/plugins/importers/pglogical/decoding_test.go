@@ -0,0 +1,117 @@
+package pglogical
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLSN(t *testing.T) {
+	tests := []struct {
+		lsn   string
+		value uint64
+		err   bool
+	}{
+		{"0/0", 0, false},
+		{"16/B374D848", 0x16B374D848, false},
+		{"FFFFFFFF/FFFFFFFF", 1<<64 - 1, false},
+		{"16B374D848", 0, true},
+		{"1/G", 0, true},
+		{"100000000/0", 0, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.lsn, func(t *testing.T) {
+			value, err := parseLSN(tc.lsn)
+			if tc.err {
+				assert.EqualError(t, err, "invalid lsn '"+tc.lsn+"'")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.value, value)
+			assert.Equal(t, tc.lsn, formatLSN(value))
+		})
+	}
+}
+
+func TestParseChange(t *testing.T) {
+	const table = "conduit_staging.blocks"
+	tests := []struct {
+		name  string
+		line  string
+		want  change
+		error string
+	}{
+		{"begin", "BEGIN", change{kind: changeBegin}, ""},
+		{"begin xid", "BEGIN 529", change{kind: changeBegin}, ""},
+		{"commit", "COMMIT", change{kind: changeCommit}, ""},
+		{"staged", `table conduit_staging.blocks: INSERT: round[bigint]:12 block[bytea]:'\x81a3726e64'`, change{kind: changeStaged, round: 12, block: []byte{0x81, 0xa3, 0x72, 0x6e, 0x64}}, ""},
+		{"partition", `table conduit_staging.blocks_2024_05: INSERT: round[bigint]:12 block[bytea]:'\x81'`, change{kind: changeStaged, round: 12, block: []byte{0x81}}, ""},
+		{"other table", `table public.accounts: INSERT: id[integer]:1`, change{kind: changeOther}, ""},
+		{"other schema partition", `table public.blocks_2024_05: INSERT: round[bigint]:12 block[bytea]:'\x81'`, change{kind: changeOther}, ""},
+		{"other table partition", `table conduit_staging.accounts_2024_05: INSERT: id[integer]:1`, change{kind: changeOther}, ""},
+		{"delete", `table conduit_staging.blocks: DELETE: round[bigint]:12`, change{kind: changeOther}, ""},
+		{"message", `message: transactional: 1 prefix: conduit, sz: 2 content:hi`, change{kind: changeOther}, ""},
+		{"columns", `table conduit_staging.blocks: INSERT: block[bytea]:'\x81' round[bigint]:12`, change{}, `unexpected insert at 0/10: table conduit_staging.blocks: INSERT: block[bytea]:'\x81' round[bigint]:12`},
+		{"escaped bytea", `table conduit_staging.blocks: INSERT: round[bigint]:12 block[bytea]:'\201'`, change{}, `unexpected insert at 0/10: table conduit_staging.blocks: INSERT: round[bigint]:12 block[bytea]:'\201'`},
+		{"hex", `table conduit_staging.blocks: INSERT: round[bigint]:12 block[bytea]:'\x8'`, change{}, "invalid block of round 12 at 0/10: encoding/hex: odd length hex string"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := parseChange(16, tc.line, table)
+			if tc.error != "" {
+				assert.EqualError(t, err, tc.error)
+				return
+			}
+			require.NoError(t, err)
+			tc.want.lsn = 16
+			assert.Equal(t, tc.want, c)
+		})
+	}
+}
+
+func TestScanWindow(t *testing.T) {
+	staged := func(lsn, round uint64) change {
+		return change{lsn: lsn, kind: changeStaged, round: round, block: []byte{byte(round)}}
+	}
+	begin := func(lsn uint64) change { return change{lsn: lsn, kind: changeBegin} }
+	commit := func(lsn uint64) change { return change{lsn: lsn, kind: changeCommit} }
+	other := func(lsn uint64) change { return change{lsn: lsn, kind: changeOther} }
+
+	changes := []change{
+		begin(1), other(2), commit(3),
+		begin(4), staged(5, 7), commit(6),
+		begin(7), staged(8, 8), commit(9),
+		begin(10), other(11), commit(12),
+		begin(13), staged(14, 9), commit(15),
+		// A transaction which is not committed yet is not in the window.
+		begin(16), staged(17, 10),
+	}
+	tests := []struct {
+		name   string
+		done   uint64
+		rounds []uint64
+		safe   uint64
+	}{
+		{"all incomplete", 7, []uint64{7, 8, 9}, 3},
+		{"first complete", 8, []uint64{8, 9}, 6},
+		{"all complete", 10, nil, 15},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := scanWindow(changes, tc.done)
+			var rounds []uint64
+			for _, r := range w.rounds {
+				rounds = append(rounds, r.round)
+				assert.Equal(t, []byte{byte(r.round)}, r.block)
+			}
+			assert.Equal(t, tc.rounds, rounds)
+			assert.Equal(t, tc.safe, w.safe)
+			assert.Equal(t, uint64(9), w.last)
+		})
+	}
+
+	w := scanWindow(changes, 7)
+	assert.Equal(t, []uint64{6, 9, 15}, []uint64{w.rounds[0].commit, w.rounds[1].commit, w.rounds[2].commit})
+	assert.Equal(t, window{}, scanWindow(nil, 0))
+}
@@ -0,0 +1,391 @@
+package pglogical
+
+import (
+	"context"
+	_ "embed" // used to embed config
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters/partition"
+	"github.com/algorand/conduit/conduit/plugins/exporters/pgstaging"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// PluginName to use when configuring.
+const PluginName = "postgresql_logical"
+
+const (
+	defaultPollInterval = time.Second
+	defaultMaxChanges   = 1000
+	outputPlugin        = "test_decoding"
+)
+
+// slotPattern is the name of a replication slot, as required by Postgres.
+var slotPattern = regexp.MustCompile(`^[a-z0-9_]{1,63}$`)
+
+type logicalImporter struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	cfg    Config
+	logger *logrus.Logger
+	// blocksTable is the qualified name of the staged blocks table.
+	blocksTable string
+
+	// mu protects the connection and the slot state, GetBlock and OnComplete
+	// are called from different goroutines.
+	mu   sync.Mutex
+	conn *pgx.Conn
+	// confirmed is the position of the slot, the changes before it are
+	// not read again.
+	confirmed uint64
+	// done is set by the first GetBlock, the rounds before done are complete.
+	done    uint64
+	started bool
+	// pending are the rounds read from the slot which were not returned yet.
+	pending map[uint64]stagedRound
+	// commits are the ends of the transactions of the returned rounds, the
+	// slot is advanced to them once the rounds are complete.
+	commits map[uint64]uint64
+}
+
+//go:embed sample.yaml
+var sampleConfig string
+
+var metadata = conduit.Metadata{
+	Name:         PluginName,
+	Description:  "Importer consuming blocks staged in Postgres with logical decoding.",
+	Deprecated:   false,
+	SampleConfig: sampleConfig,
+}
+
+func (imp *logicalImporter) Metadata() conduit.Metadata {
+	return metadata
+}
+
+// ValidateConfig checks the config without connecting.
+func (imp *logicalImporter) ValidateConfig(cfg plugins.PluginConfig) error {
+	var lcfg Config
+	if err := cfg.UnmarshalConfig(&lcfg); err != nil {
+		return fmt.Errorf("ValidateConfig(): %w", err)
+	}
+	if err := lcfg.setDefaults(); err != nil {
+		return fmt.Errorf("ValidateConfig(): %w", err)
+	}
+	return nil
+}
+
+// setDefaults fills in the defaults and validates the configuration.
+func (cfg *Config) setDefaults() error {
+	if cfg.ConnectionString == "" {
+		return fmt.Errorf("connection-string is required")
+	}
+	if cfg.Schema == "" {
+		cfg.Schema = pgstaging.DefaultSchema
+	}
+	if err := pgstaging.ValidSchema(cfg.Schema); err != nil {
+		return err
+	}
+	if !slotPattern.MatchString(cfg.SlotName) {
+		return fmt.Errorf("slot-name '%s' must be 1 to 63 lower case letters, numbers or underscores", cfg.SlotName)
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.MaxChanges == 0 {
+		cfg.MaxChanges = defaultMaxChanges
+	}
+	if cfg.PollInterval < 0 || cfg.MaxChanges < 0 {
+		return fmt.Errorf("poll-interval and max-changes must be positive")
+	}
+	return nil
+}
+
+func (imp *logicalImporter) Init(ctx context.Context, cfg plugins.PluginConfig, logger *logrus.Logger) (*sdk.Genesis, error) {
+	imp.ctx, imp.cancel = context.WithCancel(ctx)
+	imp.logger = logger
+	if err := cfg.UnmarshalConfig(&imp.cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %v", err)
+	}
+	if err := imp.cfg.setDefaults(); err != nil {
+		return nil, fmt.Errorf("Init(): %w", err)
+	}
+	imp.blocksTable = imp.cfg.Schema + "." + pgstaging.BlocksTable
+	imp.pending = make(map[uint64]stagedRound)
+	imp.commits = make(map[uint64]uint64)
+
+	var err error
+	if imp.conn, err = pgx.Connect(imp.ctx, imp.cfg.ConnectionString); err != nil {
+		return nil, fmt.Errorf("Init(): unable to connect: %w", err)
+	}
+	genesis, err := imp.init()
+	if err != nil {
+		imp.conn.Close(imp.ctx)
+		return nil, fmt.Errorf("Init(): %w", err)
+	}
+	return genesis, nil
+}
+
+// init reads the staged genesis and creates the replication slot if it does
+// not exist.
+func (imp *logicalImporter) init() (*sdk.Genesis, error) {
+	// The blocks are parsed from the text output of the bytea columns.
+	if _, err := imp.conn.Exec(imp.ctx, "SET bytea_output = 'hex'"); err != nil {
+		return nil, err
+	}
+
+	var encoded []byte
+	query := fmt.Sprintf("SELECT genesis FROM %s WHERE id = 1", imp.cfg.Schema+"."+pgstaging.GenesisTable)
+	err := imp.conn.QueryRow(imp.ctx, query).Scan(&encoded)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("the staging schema %s has no genesis, the postgresql_staging exporter creates it", imp.cfg.Schema)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the staged genesis: %w", err)
+	}
+	var genesis sdk.Genesis
+	if err = msgpack.Decode(encoded, &genesis); err != nil {
+		return nil, fmt.Errorf("unable to decode the staged genesis: %w", err)
+	}
+
+	var plugin, confirmed string
+	query = "SELECT plugin, coalesce(confirmed_flush_lsn::text, '0/0') FROM pg_replication_slots WHERE slot_name = $1 AND database = current_database()"
+	err = imp.conn.QueryRow(imp.ctx, query, imp.cfg.SlotName).Scan(&plugin, &confirmed)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		query = "SELECT lsn::text FROM pg_create_logical_replication_slot($1, $2)"
+		if err = imp.conn.QueryRow(imp.ctx, query, imp.cfg.SlotName, outputPlugin).Scan(&confirmed); err != nil {
+			return nil, fmt.Errorf("unable to create the replication slot %s: %w", imp.cfg.SlotName, err)
+		}
+		imp.logger.Infof("Created the replication slot %s at %s", imp.cfg.SlotName, confirmed)
+	case err != nil:
+		return nil, fmt.Errorf("unable to read the replication slot %s: %w", imp.cfg.SlotName, err)
+	case plugin != outputPlugin:
+		return nil, fmt.Errorf("the replication slot %s uses the %s plugin, %s is required", imp.cfg.SlotName, plugin, outputPlugin)
+	}
+	if imp.confirmed, err = parseLSN(confirmed); err != nil {
+		return nil, err
+	}
+	return &genesis, nil
+}
+
+func (imp *logicalImporter) Config() string {
+	s, _ := yaml.Marshal(imp.cfg)
+	return string(s)
+}
+
+func (imp *logicalImporter) Close() error {
+	if imp.cancel != nil {
+		imp.cancel()
+	}
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+	if imp.conn == nil {
+		return nil
+	}
+	return imp.conn.Close(context.Background())
+}
+
+// GetBlock returns the round from the replication slot. Rounds which were
+// staged before the slot was created are read from the staging table. It
+// waits until the round is staged.
+func (imp *logicalImporter) GetBlock(rnd uint64) (data.BlockData, error) {
+	for {
+		encoded, err := imp.stagedBlock(rnd)
+		if err != nil {
+			return data.BlockData{}, fmt.Errorf("GetBlock(): %w", err)
+		}
+		if encoded != nil {
+			var blk data.BlockData
+			if err = msgpack.Decode(encoded, &blk); err != nil {
+				return data.BlockData{}, fmt.Errorf("GetBlock(): unable to decode round %d: %w", rnd, err)
+			}
+			return blk, nil
+		}
+		select {
+		case <-time.After(imp.cfg.PollInterval):
+		case <-imp.ctx.Done():
+			return data.BlockData{}, fmt.Errorf("GetBlock() context finished: %w", imp.ctx.Err())
+		}
+	}
+}
+
+// stagedBlock returns the encoded round, or nil if it is not staged yet.
+func (imp *logicalImporter) stagedBlock(rnd uint64) ([]byte, error) {
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+	if !imp.started {
+		imp.started = true
+		imp.done = rnd
+	}
+	if r, ok := imp.take(rnd); ok {
+		return r.block, nil
+	}
+	last, err := imp.peek(rnd)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := imp.take(rnd); ok {
+		return r.block, nil
+	}
+
+	tables, err := imp.stagingTables()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the staging tables: %w", err)
+	}
+	selects := make([]string, len(tables))
+	for i, table := range tables {
+		selects[i] = fmt.Sprintf("SELECT block FROM %s WHERE round = $1", table)
+	}
+	var encoded []byte
+	query := strings.Join(selects, " UNION ALL ") + " LIMIT 1"
+	err = imp.conn.QueryRow(imp.ctx, query, int64(rnd)).Scan(&encoded)
+	switch {
+	case err == nil:
+		return encoded, nil
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("unable to read round %d: %w", rnd, err)
+	case last > rnd:
+		return nil, fmt.Errorf("round %d is neither in the replication slot %s nor in the staging table, later rounds are", rnd, imp.cfg.SlotName)
+	}
+	return nil, nil
+}
+
+// stagingTables returns the qualified blocks table followed by its partition
+// tables, the newest first.
+func (imp *logicalImporter) stagingTables() ([]string, error) {
+	rows, err := imp.conn.Query(imp.ctx, "SELECT table_name FROM information_schema.tables WHERE table_schema = $1", imp.cfg.Schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var partitions []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if partition.IsPartition(pgstaging.BlocksTable, name) {
+			partitions = append(partitions, imp.cfg.Schema+"."+name)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(partitions)))
+	return append([]string{imp.blocksTable}, partitions...), nil
+}
+
+// take removes the round from the pending rounds.
+func (imp *logicalImporter) take(rnd uint64) (stagedRound, bool) {
+	r, ok := imp.pending[rnd]
+	if ok {
+		delete(imp.pending, rnd)
+		imp.commits[rnd] = r.commit
+	}
+	return r, ok
+}
+
+// peek reads the slot without consuming it, the rounds from rnd on are kept
+// until they are requested. The slot is advanced past the transactions which
+// have no incomplete rounds. It returns the last staged round which was read.
+func (imp *logicalImporter) peek(rnd uint64) (uint64, error) {
+	query := "SELECT lsn::text, data FROM pg_logical_slot_peek_changes($1, NULL, $2, 'include-xids', '0', 'skip-empty-xacts', '1')"
+	rows, err := imp.conn.Query(imp.ctx, query, imp.cfg.SlotName, imp.cfg.MaxChanges)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read the replication slot %s: %w", imp.cfg.SlotName, err)
+	}
+	var changes []change
+	for rows.Next() {
+		var lsn, line string
+		if err = rows.Scan(&lsn, &line); err != nil {
+			break
+		}
+		var pos uint64
+		if pos, err = parseLSN(lsn); err != nil {
+			break
+		}
+		var c change
+		if c, err = parseChange(pos, line, imp.blocksTable); err != nil {
+			break
+		}
+		changes = append(changes, c)
+	}
+	rows.Close()
+	if err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to read the replication slot %s: %w", imp.cfg.SlotName, err)
+	}
+
+	w := scanWindow(changes, imp.done)
+	for _, r := range w.rounds {
+		if _, returned := imp.commits[r.round]; r.round >= rnd && !returned {
+			imp.pending[r.round] = r
+		}
+	}
+	if err = imp.advance(w.safe); err != nil {
+		return 0, err
+	}
+	return w.last, nil
+}
+
+// advance moves the slot forward to the position, so that the changes before
+// it are released.
+func (imp *logicalImporter) advance(lsn uint64) error {
+	if lsn <= imp.confirmed {
+		return nil
+	}
+	query := "SELECT pg_replication_slot_advance($1, $2::pg_lsn)"
+	if _, err := imp.conn.Exec(imp.ctx, query, imp.cfg.SlotName, formatLSN(lsn)); err != nil {
+		return fmt.Errorf("unable to advance the replication slot %s: %w", imp.cfg.SlotName, err)
+	}
+	imp.confirmed = lsn
+	return nil
+}
+
+// OnComplete advances the slot past the completed round, it is not read
+// again after a restart.
+func (imp *logicalImporter) OnComplete(input data.BlockData) error {
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+	round := input.Round()
+	if round+1 > imp.done {
+		imp.done = round + 1
+	}
+	commit, ok := imp.commits[round]
+	for r := range imp.commits {
+		if r <= round {
+			delete(imp.commits, r)
+		}
+	}
+	if !ok {
+		return nil
+	}
+	if err := imp.advance(commit); err != nil {
+		return fmt.Errorf("OnComplete(): %w", err)
+	}
+	return nil
+}
+
+func init() {
+	importers.Register(PluginName, importers.ImporterConstructorFunc(func() importers.Importer {
+		return &logicalImporter{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Importer, PluginName, Config{})
+}
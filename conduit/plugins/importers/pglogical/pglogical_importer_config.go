@@ -0,0 +1,26 @@
+package pglogical
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+import "time"
+
+//PluginName: conduit_importers_postgresql_logical
+
+// Config specific to the postgresql logical importer
+type Config struct {
+	/* <code>connection-string</code> is the Postgresql connection string of the staging database.<br/>
+	The user requires the REPLICATION attribute to create and read the replication slot.
+	See https://github.com/jackc/pgconn for more details
+	*/
+	ConnectionString string `yaml:"connection-string"`
+	// <code>schema</code> is the staging schema of the postgresql_staging exporter. Default: "conduit_staging".
+	Schema string `yaml:"schema"`
+	/* <code>slot-name</code> is the logical replication slot of this pipeline, it is created if it does not exist.<br/>
+	Each pipeline consuming the staging schema needs its own slot.
+	*/
+	SlotName string `yaml:"slot-name"`
+	// <code>poll-interval</code> is the delay between reads of the slot while waiting for a round. Default: 1s.
+	PollInterval time.Duration `yaml:"poll-interval"`
+	// <code>max-changes</code> limits the number of changes read from the slot at once. Default: 1000.
+	MaxChanges int `yaml:"max-changes"`
+}
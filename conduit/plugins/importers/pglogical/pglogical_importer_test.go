@@ -0,0 +1,66 @@
+package pglogical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+var logicalCons = importers.ImporterConstructorFunc(func() importers.Importer {
+	return &logicalImporter{}
+})
+
+func TestImporterMetadata(t *testing.T) {
+	imp := logicalCons.New()
+	assert.Equal(t, metadata.Name, imp.Metadata().Name)
+	assert.Equal(t, metadata.Description, imp.Metadata().Description)
+	assert.Equal(t, metadata.Deprecated, imp.Metadata().Deprecated)
+}
+
+func TestValidateConfig(t *testing.T) {
+	valid := Config{ConnectionString: "host=localhost", SlotName: "analytics"}
+	with := func(update func(*Config)) Config {
+		cfg := valid
+		update(&cfg)
+		return cfg
+	}
+	tests := []struct {
+		name string
+		cfg  Config
+		err  string
+	}{
+		{"defaults", valid, ""},
+		{"connection", with(func(cfg *Config) { cfg.ConnectionString = "" }), "ValidateConfig(): connection-string is required"},
+		{"schema", with(func(cfg *Config) { cfg.Schema = "Staging" }), "ValidateConfig(): schema 'Staging' must be a lower case identifier"},
+		{"no slot", with(func(cfg *Config) { cfg.SlotName = "" }), "ValidateConfig(): slot-name '' must be 1 to 63 lower case letters, numbers or underscores"},
+		{"slot", with(func(cfg *Config) { cfg.SlotName = "analytics-1" }), "ValidateConfig(): slot-name 'analytics-1' must be 1 to 63 lower case letters, numbers or underscores"},
+		{"poll interval", with(func(cfg *Config) { cfg.PollInterval = -time.Second }), "ValidateConfig(): poll-interval and max-changes must be positive"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfgStr, err := yaml.Marshal(tc.cfg)
+			require.NoError(t, err)
+			err = logicalCons.New().(conduit.ConfigValidator).ValidateConfig(plugins.MakePluginConfig(string(cfgStr)))
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	cfg := Config{ConnectionString: "host=localhost", SlotName: "analytics"}
+	require.NoError(t, cfg.setDefaults())
+	assert.Equal(t, "conduit_staging", cfg.Schema)
+	assert.Equal(t, defaultPollInterval, cfg.PollInterval)
+	assert.Equal(t, defaultMaxChanges, cfg.MaxChanges)
+}
@@ -0,0 +1,204 @@
+package tarimporter
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/algorand/conduit/conduit/codec"
+)
+
+// indexFile is the name of the index file in the plugin data directory.
+const indexFile = "index.json"
+
+// entry is the location of a file in the uncompressed tar stream.
+type entry struct {
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+}
+
+// archiveIndex lists the files in an archive. Size and ModTime are used to
+// detect archives which were modified after they were indexed.
+type archiveIndex struct {
+	Size    int64            `json:"size"`
+	ModTime int64            `json:"mod-time"`
+	Entries map[string]entry `json:"entries"`
+}
+
+// location of a file in one of the archives.
+type location struct {
+	archive string
+	entry
+}
+
+// index of all configured archives.
+type index struct {
+	Archives map[string]*archiveIndex `json:"archives"`
+	// files maps each file name to the first archive which contains it.
+	files map[string]location
+}
+
+// archiveAliases are the short extensions of compressed tar archives.
+var archiveAliases = map[string]string{
+	".tgz":  codec.Gzip,
+	".tzst": codec.Zstd,
+}
+
+// archiveCompression returns the compression of the archive, from its
+// extension: .tar followed by the extension of a compression, or an alias.
+func archiveCompression(archive string) (codec.Compression, error) {
+	for ext, name := range archiveAliases {
+		if strings.HasSuffix(archive, ext) {
+			return codec.CompressionByName(name)
+		}
+	}
+	comp := codec.CompressionOf(archive)
+	if !strings.HasSuffix(strings.TrimSuffix(archive, comp.Extension()), ".tar") {
+		return nil, fmt.Errorf("archiveCompression(): unsupported archive format: %s", archive)
+	}
+	return comp, nil
+}
+
+// fileStream closes both the decompressed stream and the underlying file.
+type fileStream struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (s fileStream) Close() error {
+	s.ReadCloser.Close()
+	return s.file.Close()
+}
+
+// openStream opens the uncompressed tar stream of an archive.
+func openStream(archive string) (io.ReadCloser, error) {
+	comp, err := archiveCompression(archive)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(archive)
+	if err != nil {
+		return nil, fmt.Errorf("openStream(): %w", err)
+	}
+	if comp.Extension() == "" {
+		return file, nil
+	}
+	reader, err := comp.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("openStream(): unable to read %s: %w", archive, err)
+	}
+	return fileStream{ReadCloser: reader, file: file}, nil
+}
+
+// countingReader tracks the position in the uncompressed tar stream.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// scanArchive reads the tar headers of an archive and records where each file starts.
+func scanArchive(archive string) (*archiveIndex, error) {
+	info, err := os.Stat(archive)
+	if err != nil {
+		return nil, fmt.Errorf("scanArchive(): %w", err)
+	}
+	stream, err := openStream(archive)
+	if err != nil {
+		return nil, fmt.Errorf("scanArchive(): %w", err)
+	}
+	defer stream.Close()
+
+	result := &archiveIndex{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Entries: make(map[string]entry),
+	}
+	// The tar reader consumes exactly the header blocks, so after Next the
+	// counter is at the start of the file data.
+	counter := &countingReader{r: stream}
+	tr := tar.NewReader(counter)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("scanArchive(): failed to read %s: %w", archive, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Base(hdr.Name)
+		if _, ok := result.Entries[name]; !ok {
+			result.Entries[name] = entry{Offset: counter.n, Size: hdr.Size}
+		}
+	}
+	return result, nil
+}
+
+// expandArchives resolves the configured archive patterns.
+func expandArchives(patterns []string) ([]string, error) {
+	var archives []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("expandArchives(): invalid pattern '%s': %w", pattern, err)
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				archives = append(archives, match)
+			}
+		}
+	}
+	return archives, nil
+}
+
+func readIndex(dataDir string) (*index, error) {
+	idx := &index{Archives: make(map[string]*archiveIndex)}
+	if dataDir == "" {
+		return idx, nil
+	}
+	b, err := os.ReadFile(path.Join(dataDir, indexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("readIndex(): %w", err)
+	}
+	if err = json.Unmarshal(b, idx); err != nil {
+		return nil, fmt.Errorf("readIndex(): unable to decode index: %w", err)
+	}
+	if idx.Archives == nil {
+		idx.Archives = make(map[string]*archiveIndex)
+	}
+	return idx, nil
+}
+
+func writeIndex(dataDir string, idx *index) error {
+	if dataDir == "" {
+		return nil
+	}
+	b, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("writeIndex(): unable to encode index: %w", err)
+	}
+	if err = os.WriteFile(path.Join(dataDir, indexFile), b, 0644); err != nil {
+		return fmt.Errorf("writeIndex(): %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package tarimporter
+
+import (
+	"context"
+	_ "embed" // used to embed config
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters/filewriter"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+// PluginName to use when configuring.
+const PluginName = "tar_reader"
+
+const genesisFile = "genesis.json"
+
+// stream is an open compressed archive positioned at pos in the uncompressed tar stream.
+type stream struct {
+	archive string
+	rc      io.ReadCloser
+	pos     int64
+}
+
+type tarReader struct {
+	logger  *logrus.Logger
+	cfg     Config
+	dataDir string
+
+	mu    sync.Mutex
+	index *index
+	// files are the open uncompressed archives, they support random access.
+	files map[string]*os.File
+	// stream is the open compressed archive, only one is kept open at a time.
+	stream *stream
+}
+
+// New initializes a tar importer
+func New() importers.Importer {
+	return &tarReader{}
+}
+
+//go:embed sample.yaml
+var sampleConfig string
+
+var metadata = conduit.Metadata{
+	Name:         PluginName,
+	Description:  "Importer for fetching blocks from tar archives of files created by the 'file_writer' plugin.",
+	Deprecated:   false,
+	SampleConfig: sampleConfig,
+}
+
+func (r *tarReader) Metadata() conduit.Metadata {
+	return metadata
+}
+
+// package-wide init function
+func init() {
+	importers.Register(PluginName, importers.ImporterConstructorFunc(func() importers.Importer {
+		return &tarReader{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Importer, PluginName, Config{})
+}
+
+func (r *tarReader) Init(ctx context.Context, cfg plugins.PluginConfig, logger *logrus.Logger) (*sdk.Genesis, error) {
+	r.logger = logger
+	r.dataDir = cfg.DataDir
+	r.files = make(map[string]*os.File)
+	err := cfg.UnmarshalConfig(&r.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %v", err)
+	}
+
+	if r.cfg.FilenamePattern == "" {
+		r.cfg.FilenamePattern = filewriter.FilePattern
+	}
+
+	r.index, err = readIndex(r.dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("Init(): %w", err)
+	}
+	if err = r.updateIndex(); err != nil {
+		return nil, fmt.Errorf("Init(): %w", err)
+	}
+	if len(r.index.Archives) == 0 {
+		return nil, fmt.Errorf("Init(): no archives found matching %v", r.cfg.Archives)
+	}
+
+	loc, ok := r.index.files[genesisFile]
+	if !ok {
+		return nil, fmt.Errorf("Init(): %s not found in archives", genesisFile)
+	}
+	b, err := r.readFile(loc)
+	if err != nil {
+		return nil, fmt.Errorf("Init(): %w", err)
+	}
+	var genesis sdk.Genesis
+	err = filewriter.DecodeJSONFromBytes(genesisFile, b, &genesis, false)
+	if err != nil {
+		return nil, fmt.Errorf("Init(): failed to process genesis file: %w", err)
+	}
+
+	return &genesis, nil
+}
+
+// updateIndex indexes new or modified archives and saves the index to the
+// data directory. Archives which are unchanged are not read again.
+func (r *tarReader) updateIndex() error {
+	archives, err := expandArchives(r.cfg.Archives)
+	if err != nil {
+		return fmt.Errorf("updateIndex(): %w", err)
+	}
+
+	updated := len(archives) != len(r.index.Archives)
+	indexed := make(map[string]*archiveIndex, len(archives))
+	files := make(map[string]location)
+	for _, archive := range archives {
+		info, err := os.Stat(archive)
+		if err != nil {
+			return fmt.Errorf("updateIndex(): %w", err)
+		}
+		archiveIdx, ok := r.index.Archives[archive]
+		if !ok || archiveIdx.Size != info.Size() || archiveIdx.ModTime != info.ModTime().UnixNano() {
+			start := time.Now()
+			archiveIdx, err = scanArchive(archive)
+			if err != nil {
+				return fmt.Errorf("updateIndex(): %w", err)
+			}
+			r.logger.Infof("Indexed %d files in %s in %s", len(archiveIdx.Entries), archive, time.Since(start))
+			updated = true
+			r.closeArchive(archive)
+		}
+		indexed[archive] = archiveIdx
+		for name, e := range archiveIdx.Entries {
+			if _, ok := files[name]; !ok {
+				files[name] = location{archive: archive, entry: e}
+			}
+		}
+	}
+
+	r.index.Archives = indexed
+	r.index.files = files
+	if updated {
+		return writeIndex(r.dataDir, r.index)
+	}
+	return nil
+}
+
+// readFile reads a file from an archive. Uncompressed archives are read
+// directly at the indexed offset, compressed archives are read forward from
+// the current position and only reopened when reading backwards.
+func (r *tarReader) readFile(loc location) ([]byte, error) {
+	comp, err := archiveCompression(loc.archive)
+	if err != nil {
+		return nil, fmt.Errorf("readFile(): %w", err)
+	}
+	buf := make([]byte, loc.Size)
+
+	if comp.Extension() == "" {
+		file, ok := r.files[loc.archive]
+		if !ok {
+			file, err = os.Open(loc.archive)
+			if err != nil {
+				return nil, fmt.Errorf("readFile(): %w", err)
+			}
+			r.files[loc.archive] = file
+		}
+		if _, err = file.ReadAt(buf, loc.Offset); err != nil {
+			return nil, fmt.Errorf("readFile(): failed to read %s: %w", loc.archive, err)
+		}
+		return buf, nil
+	}
+
+	if r.stream == nil || r.stream.archive != loc.archive || r.stream.pos > loc.Offset {
+		r.closeStream()
+		rc, err := openStream(loc.archive)
+		if err != nil {
+			return nil, fmt.Errorf("readFile(): %w", err)
+		}
+		r.stream = &stream{archive: loc.archive, rc: rc}
+	}
+	if _, err = io.CopyN(io.Discard, r.stream.rc, loc.Offset-r.stream.pos); err == nil {
+		_, err = io.ReadFull(r.stream.rc, buf)
+	}
+	if err != nil {
+		r.closeStream()
+		return nil, fmt.Errorf("readFile(): failed to read %s: %w", loc.archive, err)
+	}
+	r.stream.pos = loc.Offset + loc.Size
+	return buf, nil
+}
+
+func (r *tarReader) closeStream() {
+	if r.stream != nil {
+		r.stream.rc.Close()
+		r.stream = nil
+	}
+}
+
+// closeArchive closes any open handles to an archive so that it is reopened after being modified.
+func (r *tarReader) closeArchive(archive string) {
+	if file, ok := r.files[archive]; ok {
+		file.Close()
+		delete(r.files, archive)
+	}
+	if r.stream != nil && r.stream.archive == archive {
+		r.closeStream()
+	}
+}
+
+func (r *tarReader) Config() string {
+	s, _ := yaml.Marshal(r.cfg)
+	return string(s)
+}
+
+func (r *tarReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for archive := range r.files {
+		r.closeArchive(archive)
+	}
+	r.closeStream()
+	return nil
+}
+
+func (r *tarReader) GetBlock(rnd uint64) (data.BlockData, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := fmt.Sprintf(r.cfg.FilenamePattern, rnd)
+	loc, ok := r.index.files[name]
+	if !ok {
+		// The archival job may have added new archives since the index was built.
+		if err := r.updateIndex(); err != nil {
+			return data.BlockData{}, fmt.Errorf("GetBlock(): %w", err)
+		}
+		if loc, ok = r.index.files[name]; !ok {
+			return data.BlockData{}, fmt.Errorf("GetBlock(): block %d (%s) not found in archives", rnd, name)
+		}
+	}
+
+	start := time.Now()
+	b, err := r.readFile(loc)
+	if err != nil {
+		return data.BlockData{}, fmt.Errorf("GetBlock(): %w", err)
+	}
+	var blockData data.BlockData
+	err = filewriter.DecodeJSONFromBytes(name, b, &blockData, false)
+	if err != nil {
+		return data.BlockData{}, fmt.Errorf("GetBlock(): unable to decode block file '%s' in %s: %w", name, loc.archive, err)
+	}
+	r.logger.Infof("Block %d read time: %s", rnd, time.Since(start))
+	return blockData, nil
+}
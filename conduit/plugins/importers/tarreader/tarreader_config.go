@@ -0,0 +1,20 @@
+package tarimporter
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+//Name: conduit_importers_tarreader
+
+// Config specific to the tar importer
+type Config struct {
+	/* <code>archives</code> is a list of archive paths or glob patterns.<br/>
+	Supported formats are .tar, .tar.gz (or .tgz), .tar.zst (or .tzst), .tar.lz4 and .tar.sz.
+	Uncompressed archives support random access, compressed archives are read sequentially and are fastest when rounds are requested in order.
+	*/
+	Archives []string `yaml:"archives"`
+	/* <code>filename-pattern</code> is the format used to find block files inside the archives. It uses go string formatting and should accept one number for the round.
+	Directories inside the archive are ignored. The default pattern is
+
+	"%[1]d_block.json"
+	*/
+	FilenamePattern string `yaml:"filename-pattern"`
+}
@@ -0,0 +1,205 @@
+package tarimporter
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/exporters/filewriter"
+	"github.com/algorand/conduit/conduit/plugins/importers"
+)
+
+var testGenesis = sdk.Genesis{
+	SchemaID:    "test",
+	Network:     "test",
+	Proto:       "test",
+	RewardsPool: "AAAAAAA",
+	FeeSink:     "AAAAAAA",
+	Timestamp:   1234,
+}
+
+func TestImporterMetadata(t *testing.T) {
+	testImporter := New()
+	m := testImporter.Metadata()
+	assert.Equal(t, metadata.Name, m.Name)
+	assert.Equal(t, metadata.Description, m.Description)
+	assert.Equal(t, metadata.Deprecated, m.Deprecated)
+}
+
+func encodeFile(t *testing.T, name string, v interface{}) []byte {
+	file := path.Join(t.TempDir(), name)
+	require.NoError(t, filewriter.EncodeJSONToFile(file, v, true))
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	return b
+}
+
+// writeArchive creates an archive with the genesis file and block files for
+// rounds [first, last]. The archive is compressed according to its extension.
+func writeArchive(t *testing.T, archive string, pattern string, first, last uint64) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	addFile := func(name string, b []byte) {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "blocks/" + name, Mode: 0644, Size: int64(len(b)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(b)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "blocks/", Mode: 0755, Typeflag: tar.TypeDir}))
+	addFile(genesisFile, encodeFile(t, genesisFile, testGenesis))
+	for i := first; i <= last; i++ {
+		name := fmt.Sprintf(pattern, i)
+		addFile(name, encodeFile(t, name, data.BlockData{BlockHeader: sdk.BlockHeader{Round: sdk.Round(i)}}))
+	}
+	require.NoError(t, tw.Close())
+
+	comp, err := archiveCompression(archive)
+	require.NoError(t, err)
+	if command := map[string]string{".zst": "zstd", ".lz4": "lz4"}[comp.Extension()]; command != "" {
+		if _, err = exec.LookPath(command); err != nil {
+			t.Skip(command + " is not installed")
+		}
+	}
+	var compressed bytes.Buffer
+	writer, err := comp.NewWriter(&compressed)
+	require.NoError(t, err)
+	_, err = io.Copy(writer, &buf)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	buf = compressed
+	require.NoError(t, os.WriteFile(archive, buf.Bytes(), 0644))
+}
+
+func initializeImporter(t *testing.T, dataDir string, cfg Config) (importers.Importer, *test.Hook) {
+	logger, hook := test.NewNullLogger()
+	importer := New()
+	cfgStr, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	genesis, err := importer.Init(context.Background(), plugins.PluginConfig{DataDir: dataDir, Config: string(cfgStr)}, logger)
+	require.NoError(t, err)
+	require.Equal(t, testGenesis, *genesis)
+	t.Cleanup(func() { importer.Close() })
+	return importer, hook
+}
+
+func TestGetBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		ext     string
+		pattern string
+	}{
+		{"tar", ".tar", filewriter.FilePattern},
+		{"tar gzip files", ".tar", "%[1]d_block.json.gz"},
+		{"tar.gz", ".tar.gz", filewriter.FilePattern},
+		{"tgz", ".tgz", filewriter.FilePattern},
+		{"tar.zst", ".tar.zst", filewriter.FilePattern},
+		{"tar.lz4", ".tar.lz4", filewriter.FilePattern},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeArchive(t, path.Join(dir, "0-9"+tc.ext), tc.pattern, 0, 9)
+			writeArchive(t, path.Join(dir, "10-19"+tc.ext), tc.pattern, 10, 19)
+
+			importer, _ := initializeImporter(t, t.TempDir(), Config{
+				Archives:        []string{path.Join(dir, "*"+tc.ext)},
+				FilenamePattern: tc.pattern,
+			})
+			// Read forwards, backwards and across archives.
+			for _, rnd := range []uint64{0, 1, 5, 3, 12, 19, 2, 10} {
+				block, err := importer.GetBlock(rnd)
+				require.NoError(t, err)
+				assert.Equal(t, sdk.Round(rnd), block.BlockHeader.Round)
+			}
+
+			_, err := importer.GetBlock(20)
+			assert.EqualError(t, err, fmt.Sprintf("GetBlock(): block 20 (%s) not found in archives", fmt.Sprintf(tc.pattern, 20)))
+		})
+	}
+}
+
+// TestIndexReused tests that the index is saved and archives are only read again when they change.
+func TestIndexReused(t *testing.T) {
+	dir := t.TempDir()
+	dataDir := t.TempDir()
+	archive := path.Join(dir, "blocks.tar.gz")
+	writeArchive(t, archive, filewriter.FilePattern, 0, 4)
+	cfg := Config{Archives: []string{archive}}
+
+	_, hook := initializeImporter(t, dataDir, cfg)
+	assert.Len(t, hook.AllEntries(), 1)
+	assert.FileExists(t, path.Join(dataDir, indexFile))
+
+	importer, hook := initializeImporter(t, dataDir, cfg)
+	assert.Empty(t, hook.AllEntries())
+	block, err := importer.GetBlock(3)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.Round(3), block.BlockHeader.Round)
+
+	// The archive is indexed again after it is modified.
+	writeArchive(t, archive, filewriter.FilePattern, 0, 9)
+	require.NoError(t, os.Chtimes(archive, time.Now(), time.Now().Add(time.Hour)))
+	importer, hook = initializeImporter(t, dataDir, cfg)
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, logrus.InfoLevel, hook.LastEntry().Level)
+	assert.True(t, strings.HasPrefix(hook.LastEntry().Message, "Indexed 11 files in "))
+	block, err = importer.GetBlock(9)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.Round(9), block.BlockHeader.Round)
+}
+
+// TestNewArchive tests that archives added after Init are found.
+func TestNewArchive(t *testing.T) {
+	dir := t.TempDir()
+	writeArchive(t, path.Join(dir, "0-4.tar"), filewriter.FilePattern, 0, 4)
+	importer, _ := initializeImporter(t, t.TempDir(), Config{Archives: []string{path.Join(dir, "*.tar")}})
+
+	_, err := importer.GetBlock(5)
+	require.Error(t, err)
+
+	writeArchive(t, path.Join(dir, "5-9.tar"), filewriter.FilePattern, 5, 9)
+	block, err := importer.GetBlock(5)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.Round(5), block.BlockHeader.Round)
+}
+
+func TestInitErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeArchive(t, path.Join(dir, "blocks.tar"), filewriter.FilePattern, 0, 1)
+	require.NoError(t, os.WriteFile(path.Join(dir, "blocks.zip"), nil, 0644))
+
+	tests := []struct {
+		name     string
+		archives []string
+		errMsg   string
+	}{
+		{"no archives", []string{path.Join(dir, "*.tar.gz")}, "Init(): no archives found matching"},
+		{"unsupported format", []string{path.Join(dir, "blocks.zip")}, "archiveCompression(): unsupported archive format"},
+		{"invalid pattern", []string{"["}, "expandArchives(): invalid pattern '['"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger, _ := test.NewNullLogger()
+			cfgStr, err := yaml.Marshal(Config{Archives: tc.archives})
+			require.NoError(t, err)
+			_, err = New().Init(context.Background(), plugins.MakePluginConfig(string(cfgStr)), logger)
+			assert.ErrorContains(t, err, tc.errMsg)
+		})
+	}
+}
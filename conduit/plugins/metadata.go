@@ -12,6 +12,9 @@ const (
 
 	// Importer PluginType
 	Importer = "importer"
+
+	// Observer PluginType
+	Observer = "observer"
 )
 
 // PluginMetadata provides static per-plugin data
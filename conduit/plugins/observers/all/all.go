@@ -0,0 +1,6 @@
+package all
+
+import (
+	// Call package wide init function
+	_ "github.com/algorand/conduit/conduit/plugins/observers/noop"
+)
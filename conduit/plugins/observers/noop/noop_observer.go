@@ -0,0 +1,59 @@
+package noop
+
+import (
+	"context"
+	_ "embed" // used to embed config
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/observers"
+)
+
+// PluginName to use when configuring.
+const PluginName = "noop"
+
+// package-wide init function
+func init() {
+	observers.Register(PluginName, observers.ObserverConstructorFunc(func() observers.Observer {
+		return &Observer{}
+	}))
+}
+
+// Observer noop
+type Observer struct{}
+
+//go:embed sample.yaml
+var sampleConfig string
+
+// Metadata noop
+func (o *Observer) Metadata() conduit.Metadata {
+	return conduit.Metadata{
+		Name:         PluginName,
+		Description:  "noop observer",
+		Deprecated:   false,
+		SampleConfig: sampleConfig,
+	}
+}
+
+// Config noop
+func (o *Observer) Config() string {
+	return ""
+}
+
+// Init noop
+func (o *Observer) Init(_ context.Context, _ data.InitProvider, _ plugins.PluginConfig, _ *logrus.Logger) error {
+	return nil
+}
+
+// Close noop
+func (o *Observer) Close() error {
+	return nil
+}
+
+// Observe noop
+func (o *Observer) Observe(_ data.BlockData) error {
+	return nil
+}
@@ -0,0 +1,38 @@
+package observers
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+)
+
+// Observer defines the interface for plugins which tap the exported rounds
+// without taking part in them, such as debuggers, metrics enrichers or audit
+// sinks. Observers are called after a round is exported, they cannot hold back
+// the pipeline and their errors do not cause the round to be retried.
+type Observer interface {
+	// PluginMetadata implement this interface.
+	conduit.PluginMetadata
+
+	// Init will be called during initialization, before block data starts going through the pipeline.
+	// The PluginConfig passed to Init will contain the Unmarhsalled config file specific to this plugin.
+	// Should return an error if it fails--this will result in the Indexer process terminating.
+	Init(ctx context.Context, initProvider data.InitProvider, cfg plugins.PluginConfig, logger *logrus.Logger) error
+
+	// Config returns the configuration options used to create an Observer.
+	// Initialized during Init, it should return nil until the Observer has been initialized.
+	Config() string
+
+	// Close will be called during termination of the Indexer process, after
+	// the rounds waiting for the observer were observed.
+	Close() error
+
+	// Observe is called with a copy of each exported round, in round order.
+	// Errors are logged and counted, the round is not observed again. Rounds
+	// are dropped while the observer is too far behind the pipeline.
+	Observe(exportData data.BlockData) error
+}
@@ -0,0 +1,41 @@
+package observers
+
+import (
+	"fmt"
+)
+
+// ObserverConstructor must be implemented by each Observer.
+// It provides a basic no-arg constructor for instances of an ObserverImpl.
+type ObserverConstructor interface {
+	// New should return an instantiation of an Observer.
+	// Configuration values should be passed and can be processed during `Init()`.
+	New() Observer
+}
+
+// ObserverConstructorFunc is Constructor implementation for observers
+type ObserverConstructorFunc func() Observer
+
+// New initializes an observer constructor
+func (f ObserverConstructorFunc) New() Observer {
+	return f()
+}
+
+// Observers are the constructors to build observer plugins.
+var Observers = make(map[string]ObserverConstructor)
+
+// Register is used to register ObserverConstructor implementations. This mechanism allows
+// for loose coupling between the configuration and the implementation. It is extremely similar to the way sql.DB
+// drivers are configured and used.
+func Register(name string, constructor ObserverConstructor) {
+	Observers[name] = constructor
+}
+
+// ObserverBuilderByName returns an Observer constructor for the name provided
+func ObserverBuilderByName(name string) (ObserverConstructor, error) {
+	constructor, ok := Observers[name]
+	if !ok {
+		return nil, fmt.Errorf("no Observer Constructor for %s", name)
+	}
+
+	return constructor, nil
+}
@@ -0,0 +1,41 @@
+package observers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/algorand/conduit/conduit"
+)
+
+type mockObserver struct {
+	Observer
+}
+
+func (m *mockObserver) Metadata() conduit.Metadata {
+	return conduit.Metadata{}
+}
+
+type mockObserverConstructor struct {
+	mo *mockObserver
+}
+
+func (c *mockObserverConstructor) New() Observer {
+	return c.mo
+}
+
+func TestObserverByNameSuccess(t *testing.T) {
+	mo := mockObserver{}
+	Register("foobar", &mockObserverConstructor{&mo})
+
+	obsC, err := ObserverBuilderByName("foobar")
+	assert.NoError(t, err)
+	obs := obsC.New()
+	assert.Implements(t, (*Observer)(nil), obs)
+}
+
+func TestObserverByNameNotFound(t *testing.T) {
+	_, err := ObserverBuilderByName("barfoo")
+	expectedErr := "no Observer Constructor for barfoo"
+	assert.EqualError(t, err, expectedErr)
+}
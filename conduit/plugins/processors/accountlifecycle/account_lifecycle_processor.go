@@ -0,0 +1,418 @@
+package accountlifecycle
+
+import (
+	"context"
+	_ "embed" // used to embed config
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/algorand/indexer/protocol"
+	"github.com/algorand/indexer/protocol/config"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// PluginName to use when configuring.
+const PluginName = "account_lifecycle"
+
+const (
+	// stateFile is the snapshot of the accounts in the plugin data directory.
+	stateFile = "account_lifecycle_state.json"
+	// journalFile has the account changes of each round completed since the
+	// snapshot, one JSON object per line.
+	journalFile = "account_lifecycle_journal.jsonl"
+	// compactRounds is the number of journal entries after which the journal
+	// is compacted into a new snapshot.
+	compactRounds = 10000
+)
+
+// package-wide init function
+func init() {
+	processors.Register(PluginName, processors.ProcessorConstructorFunc(func() processors.Processor {
+		return &Processor{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Processor, PluginName, Config{})
+}
+
+// account is the lifecycle state of an address which has been seen.
+type account struct {
+	Closed bool `json:"closed,omitempty"`
+	// Funded is true while the balance is above the minimum balance.
+	Funded bool `json:"funded,omitempty"`
+}
+
+// state includes the rounds before NextRound.
+type state struct {
+	NextRound uint64             `json:"next-round"`
+	Accounts  map[string]account `json:"accounts,omitempty"`
+}
+
+// journalEntry has the accounts which changed in a round.
+type journalEntry struct {
+	Round    uint64             `json:"round"`
+	Accounts map[string]account `json:"accounts,omitempty"`
+}
+
+// Processor derives account lifecycle events from the payset and the state
+// delta of each block.
+type Processor struct {
+	cfg         Config
+	logger      *log.Logger
+	statePath   string
+	journalPath string
+	// emit has the event types to add to the blocks.
+	emit map[string]bool
+	// committed is the state after the last completed round. pending has the
+	// accounts which changed in the last processed round.
+	committed state
+	pending   *journalEntry
+	// journaled is the number of entries in the journal.
+	journaled int
+}
+
+//go:embed sample.yaml
+var sampleConfig string
+
+// Metadata returns metadata
+func (p *Processor) Metadata() conduit.Metadata {
+	return conduit.Metadata{
+		Name:         PluginName,
+		Description:  "Emit account lifecycle events: created, funded above the minimum balance, closed and reopened.",
+		Deprecated:   false,
+		SampleConfig: sampleConfig,
+	}
+}
+
+// Config returns the config
+func (p *Processor) Config() string {
+	s, _ := yaml.Marshal(p.cfg)
+	return string(s)
+}
+
+// Init loads the accounts saved by a previous run.
+func (p *Processor) Init(_ context.Context, initProvider data.InitProvider, cfg plugins.PluginConfig, logger *log.Logger) error {
+	p.logger = logger
+	if err := cfg.UnmarshalConfig(&p.cfg); err != nil {
+		return fmt.Errorf("account lifecycle processor init error: %w", err)
+	}
+	p.emit = make(map[string]bool)
+	for _, event := range p.cfg.Events {
+		switch event {
+		case data.AccountCreated, data.AccountFunded, data.AccountClosed, data.AccountReopened:
+			p.emit[event] = true
+		default:
+			return fmt.Errorf("account lifecycle processor Init(): unknown event (%s)", event)
+		}
+	}
+	if len(p.emit) == 0 {
+		for _, event := range []string{data.AccountCreated, data.AccountFunded, data.AccountClosed, data.AccountReopened} {
+			p.emit[event] = true
+		}
+	}
+
+	p.statePath = path.Join(cfg.DataDir, stateFile)
+	p.journalPath = path.Join(cfg.DataDir, journalFile)
+	if err := p.load(); err != nil {
+		return fmt.Errorf("account lifecycle processor Init(): %w", err)
+	}
+	next := uint64(initProvider.NextDBRound())
+	if p.committed.NextRound > next {
+		p.logger.Warnf("account lifecycle processor already includes rounds %d to %d, their events are not emitted again", next, p.committed.NextRound-1)
+	}
+	if p.committed.NextRound == 0 && next > 0 {
+		p.logger.Warnf("account lifecycle processor starts at round %d, accounts which received algos before then are reported as created when they next receive algos", next)
+	}
+	return nil
+}
+
+// load reads the snapshot and replays the journal, then compacts them into a
+// new snapshot.
+func (p *Processor) load() error {
+	b, err := os.ReadFile(p.statePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to read state: %w", err)
+	}
+	if err == nil {
+		if err = json.Unmarshal(b, &p.committed); err != nil {
+			return fmt.Errorf("unable to decode state: %w", err)
+		}
+	}
+	if p.committed.Accounts == nil {
+		p.committed.Accounts = make(map[string]account)
+	}
+
+	f, err := os.Open(p.journalPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read journal: %w", err)
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	for {
+		var entry journalEntry
+		err = dec.Decode(&entry)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// The last entry is incomplete if conduit stopped while writing it,
+			// the round was not committed.
+			p.logger.Warnf("account lifecycle processor ignores the end of the journal after round %d: %v", p.committed.NextRound, err)
+			break
+		}
+		if entry.Round < p.committed.NextRound {
+			continue
+		}
+		p.committed.apply(entry)
+	}
+	return p.compact()
+}
+
+// compact saves the committed state to a new snapshot and removes the journal.
+func (p *Processor) compact() error {
+	b, err := json.Marshal(p.committed)
+	if err != nil {
+		return fmt.Errorf("unable to encode state: %w", err)
+	}
+	tmp := p.statePath + ".tmp"
+	if err = os.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("unable to write state: %w", err)
+	}
+	if err = os.Rename(tmp, p.statePath); err != nil {
+		return fmt.Errorf("unable to write state: %w", err)
+	}
+	if err = os.Remove(p.journalPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to remove journal: %w", err)
+	}
+	p.journaled = 0
+	return nil
+}
+
+// Close does nothing, the accounts were saved when each round completed.
+func (p *Processor) Close() error {
+	return nil
+}
+
+// Process adds the lifecycle events of the block to its account events.
+func (p *Processor) Process(input data.BlockData) (data.BlockData, error) {
+	round := input.Round()
+	if round < p.committed.NextRound {
+		// The round was processed before a restart.
+		return input, nil
+	}
+	// Retries start from the committed state so that events are only derived once.
+	r := roundState{
+		committed: p.committed.Accounts,
+		entry:     journalEntry{Round: round, Accounts: make(map[string]account)},
+		touched:   make(map[string]uint64),
+	}
+	for idx := range input.Payset {
+		r.walk(uint64(idx), &input.Payset[idx].SignedTxnWithAD)
+	}
+	if input.Delta != nil {
+		r.applyDelta(input.Delta, consensusParams(input.BlockHeader.CurrentProtocol))
+	}
+
+	sort.SliceStable(r.events, func(i, j int) bool {
+		return r.events[i].Intra < r.events[j].Intra
+	})
+	for _, event := range r.events {
+		if p.emit[event.Type] {
+			event.Round = round
+			input.AccountEvents = append(input.AccountEvents, event)
+		}
+	}
+	p.pending = &r.entry
+	return input, nil
+}
+
+// OnComplete appends the accounts which changed to the journal once the round
+// has been exported.
+func (p *Processor) OnComplete(input data.BlockData) error {
+	if p.pending == nil || p.pending.Round != input.Round() {
+		return nil
+	}
+	entry := *p.pending
+	p.pending = nil
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("account lifecycle processor OnComplete(): unable to encode journal: %w", err)
+	}
+	f, err := os.OpenFile(p.journalPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("account lifecycle processor OnComplete(): unable to open journal: %w", err)
+	}
+	_, err = f.Write(append(b, '\n'))
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("account lifecycle processor OnComplete(): unable to write journal: %w", err)
+	}
+	p.committed.apply(entry)
+	p.journaled++
+	if p.journaled >= compactRounds {
+		if err = p.compact(); err != nil {
+			return fmt.Errorf("account lifecycle processor OnComplete(): %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *state) apply(entry journalEntry) {
+	for addr, acct := range entry.Accounts {
+		s.Accounts[addr] = acct
+	}
+	s.NextRound = entry.Round + 1
+}
+
+// roundState derives the events of a round.
+type roundState struct {
+	committed map[string]account
+	entry     journalEntry
+	// touched has the intra of the last transaction which paid an address.
+	touched map[string]uint64
+	// opened has the index in events of the created and reopened events, to
+	// add the balances from the delta.
+	opened map[string]int
+	events []data.AccountEvent
+}
+
+func (r *roundState) get(addr string) (account, bool) {
+	if acct, ok := r.entry.Accounts[addr]; ok {
+		return acct, true
+	}
+	acct, ok := r.committed[addr]
+	return acct, ok
+}
+
+func (r *roundState) set(addr string, acct account) {
+	if old, ok := r.get(addr); ok && old == acct {
+		return
+	}
+	r.entry.Accounts[addr] = acct
+}
+
+func (r *roundState) emit(eventType, addr string, intra uint64) {
+	if eventType == data.AccountCreated || eventType == data.AccountReopened {
+		if r.opened == nil {
+			r.opened = make(map[string]int)
+		}
+		r.opened[addr] = len(r.events)
+	}
+	r.events = append(r.events, data.AccountEvent{Type: eventType, Address: addr, Intra: intra})
+}
+
+// walk derives the events of a transaction and its inner transactions.
+func (r *roundState) walk(intra uint64, stxn *sdk.SignedTxnWithAD) {
+	txn := &stxn.Txn
+	// The sender exists, it may have been created before the first round.
+	r.seen(txn.Sender.String())
+	if txn.Type == sdk.PaymentTx {
+		if txn.Amount > 0 {
+			r.paid(txn.Receiver.String(), intra)
+		}
+		if !txn.CloseRemainderTo.IsZero() {
+			if stxn.ClosingAmount > 0 {
+				r.paid(txn.CloseRemainderTo.String(), intra)
+			}
+			addr := txn.Sender.String()
+			r.set(addr, account{Closed: true})
+			r.emit(data.AccountClosed, addr, intra)
+		}
+	}
+	for idx := range stxn.EvalDelta.InnerTxns {
+		r.walk(intra, &stxn.EvalDelta.InnerTxns[idx])
+	}
+}
+
+// seen records an address which must exist without an event.
+func (r *roundState) seen(addr string) {
+	if acct, ok := r.get(addr); !ok || acct.Closed {
+		r.set(addr, account{})
+	}
+}
+
+// paid records a payment of algos to the address.
+func (r *roundState) paid(addr string, intra uint64) {
+	r.touched[addr] = intra
+	acct, ok := r.get(addr)
+	switch {
+	case !ok:
+		r.set(addr, account{})
+		r.emit(data.AccountCreated, addr, intra)
+	case acct.Closed:
+		r.set(addr, account{})
+		r.emit(data.AccountReopened, addr, intra)
+	}
+}
+
+// applyDelta adds the balances to the created and reopened events, and
+// derives the funded events by comparing the balances with the minimum
+// balances.
+func (r *roundState) applyDelta(delta *sdk.LedgerStateDelta, params config.ConsensusParams) {
+	for idx := range delta.Accts.Accts {
+		rec := &delta.Accts.Accts[idx]
+		addr := rec.Addr.String()
+		balance := uint64(rec.MicroAlgos)
+		minBalance := minBalance(params, &rec.AccountData)
+		if i, ok := r.opened[addr]; ok {
+			r.events[i].Balance = balance
+			r.events[i].MinBalance = minBalance
+		}
+		acct, ok := r.get(addr)
+		if !ok && balance == 0 {
+			continue
+		}
+		// Accounts which existed before they were first seen are not reported
+		// as funded.
+		_, known := r.committed[addr]
+		_, opened := r.opened[addr]
+		funded := balance > minBalance
+		if funded && !acct.Funded && (known || opened) {
+			r.events = append(r.events, data.AccountEvent{
+				Type:       data.AccountFunded,
+				Address:    addr,
+				Intra:      r.touched[addr],
+				Balance:    balance,
+				MinBalance: minBalance,
+			})
+		}
+		acct.Funded = funded
+		r.set(addr, acct)
+	}
+}
+
+// consensusParams returns the parameters of the protocol, or the current
+// protocol if conduit does not know it.
+func consensusParams(version string) config.ConsensusParams {
+	if params, ok := config.Consensus[protocol.ConsensusVersion(version)]; ok {
+		return params
+	}
+	return config.Consensus[protocol.ConsensusCurrentVersion]
+}
+
+// minBalance computes the minimum balance of an account, as the ledger does.
+func minBalance(params config.ConsensusParams, acct *sdk.AccountData) uint64 {
+	schema := acct.TotalAppSchema
+	return params.MinBalance*(1+acct.TotalAssets) +
+		params.AppFlatParamsMinBalance*(acct.TotalAppParams+uint64(acct.TotalExtraAppPages)) +
+		params.AppFlatOptInMinBalance*acct.TotalAppLocalStates +
+		params.SchemaMinBalancePerEntry*(schema.NumUint+schema.NumByteSlice) +
+		params.SchemaUintMinBalance*schema.NumUint +
+		params.SchemaBytesMinBalance*schema.NumByteSlice +
+		params.BoxFlatMinBalance*acct.TotalBoxes +
+		params.BoxByteMinBalance*acct.TotalBoxBytes
+}
@@ -0,0 +1,209 @@
+package accountlifecycle
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/algorand/indexer/protocol"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+)
+
+var (
+	alice = sdk.Address{1}
+	bob   = sdk.Address{2}
+	carol = sdk.Address{3}
+)
+
+func makeProcessor(t *testing.T, dataDir string, cfg Config) *Processor {
+	b, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	l, _ := test.NewNullLogger()
+	rnd := sdk.Round(0)
+	p := &Processor{}
+	err = p.Init(context.Background(), conduit.MakePipelineInitProvider(&rnd, &sdk.Genesis{}), plugins.PluginConfig{DataDir: dataDir, Config: string(b)}, l)
+	require.NoError(t, err)
+	return p
+}
+
+func pay(sender, receiver sdk.Address, amount uint64) sdk.SignedTxnInBlock {
+	var stxn sdk.SignedTxnInBlock
+	stxn.Txn.Type = sdk.PaymentTx
+	stxn.Txn.Sender = sender
+	stxn.Txn.Receiver = receiver
+	stxn.Txn.Amount = sdk.MicroAlgos(amount)
+	return stxn
+}
+
+func closeTo(sender, closeTo sdk.Address, closingAmount uint64) sdk.SignedTxnInBlock {
+	stxn := pay(sender, sender, 0)
+	stxn.Txn.CloseRemainderTo = closeTo
+	stxn.ClosingAmount = sdk.MicroAlgos(closingAmount)
+	return stxn
+}
+
+func block(round uint64, payset ...sdk.SignedTxnInBlock) data.BlockData {
+	return data.BlockData{
+		BlockHeader: sdk.BlockHeader{Round: sdk.Round(round)},
+		Payset:      payset,
+	}
+}
+
+func withDelta(blk data.BlockData, accts ...sdk.BalanceRecord) data.BlockData {
+	blk.BlockHeader.CurrentProtocol = string(protocol.ConsensusCurrentVersion)
+	blk.Delta = &sdk.LedgerStateDelta{Accts: sdk.AccountDeltas{Accts: accts}}
+	return blk
+}
+
+func balance(addr sdk.Address, microAlgos, assets uint64) sdk.BalanceRecord {
+	var rec sdk.BalanceRecord
+	rec.Addr = addr
+	rec.MicroAlgos = sdk.MicroAlgos(microAlgos)
+	rec.TotalAssets = assets
+	return rec
+}
+
+// process processes and completes the block.
+func process(t *testing.T, p *Processor, blk data.BlockData) []data.AccountEvent {
+	out, err := p.Process(blk)
+	require.NoError(t, err)
+	require.NoError(t, p.OnComplete(out))
+	return out.AccountEvents
+}
+
+func event(eventType string, addr sdk.Address, round, intra uint64) data.AccountEvent {
+	return data.AccountEvent{Type: eventType, Address: addr.String(), Round: round, Intra: intra}
+}
+
+func TestAccountLifecycle(t *testing.T) {
+	dataDir := t.TempDir()
+	p := makeProcessor(t, dataDir, Config{})
+
+	assert.Equal(t, []data.AccountEvent{
+		event(data.AccountCreated, bob, 1, 0),
+	}, process(t, p, block(1, pay(alice, bob, 200000))))
+	assert.Empty(t, process(t, p, block(2, pay(alice, bob, 1000))), "bob already exists")
+
+	// A retried round emits the same events.
+	blk := block(3, pay(bob, alice, 1000), closeTo(bob, carol, 5000))
+	_, err := p.Process(blk)
+	require.NoError(t, err)
+	expected := []data.AccountEvent{
+		event(data.AccountCreated, carol, 3, 1),
+		event(data.AccountClosed, bob, 3, 1),
+	}
+	assert.Equal(t, expected, process(t, p, blk))
+
+	// The next processor starts from the saved accounts.
+	p = makeProcessor(t, dataDir, Config{})
+	assert.Empty(t, process(t, p, blk), "rounds are not processed twice")
+	assert.Equal(t, []data.AccountEvent{
+		event(data.AccountReopened, bob, 4, 0),
+	}, process(t, p, block(4, pay(carol, bob, 300000))))
+}
+
+func TestAccountLifecycleInnerTxns(t *testing.T) {
+	p := makeProcessor(t, t.TempDir(), Config{})
+	appl := pay(alice, alice, 0)
+	appl.Txn.Type = sdk.ApplicationCallTx
+	appl.EvalDelta.InnerTxns = []sdk.SignedTxnWithAD{pay(alice, carol, 100000).SignedTxnWithAD}
+	assert.Equal(t, []data.AccountEvent{
+		event(data.AccountCreated, carol, 1, 1),
+	}, process(t, p, block(1, pay(alice, bob, 0), appl)))
+}
+
+func TestAccountLifecycleDelta(t *testing.T) {
+	p := makeProcessor(t, t.TempDir(), Config{})
+
+	// bob is created at the minimum balance, then funded above it.
+	events := process(t, p, withDelta(block(1, pay(alice, bob, 100000)), balance(alice, 5000000, 0), balance(bob, 100000, 0)))
+	created := event(data.AccountCreated, bob, 1, 0)
+	created.Balance = 100000
+	created.MinBalance = 100000
+	assert.Equal(t, []data.AccountEvent{created}, events, "alice existed before she was seen, she is not reported as funded")
+
+	events = process(t, p, withDelta(block(2, pay(alice, bob, 50000)), balance(alice, 4950000, 0), balance(bob, 150000, 0)))
+	funded := event(data.AccountFunded, bob, 2, 0)
+	funded.Balance = 150000
+	funded.MinBalance = 100000
+	assert.Equal(t, []data.AccountEvent{funded}, events)
+
+	// An asset opt in raises the minimum balance above the balance, it is
+	// funded again once it receives more algos.
+	optin := pay(bob, bob, 0)
+	optin.Txn.Type = sdk.AssetTransferTx
+	assert.Empty(t, process(t, p, withDelta(block(3, optin), balance(bob, 149000, 1))))
+	events = process(t, p, withDelta(block(4, pay(alice, bob, 100000)), balance(bob, 249000, 1)))
+	funded = event(data.AccountFunded, bob, 4, 0)
+	funded.Balance = 249000
+	funded.MinBalance = 200000
+	assert.Equal(t, []data.AccountEvent{funded}, events)
+}
+
+func TestAccountLifecycleEvents(t *testing.T) {
+	p := makeProcessor(t, t.TempDir(), Config{Events: []string{data.AccountClosed}})
+	assert.Empty(t, process(t, p, block(1, pay(alice, bob, 200000))))
+	assert.Equal(t, []data.AccountEvent{
+		event(data.AccountClosed, bob, 2, 0),
+	}, process(t, p, block(2, closeTo(bob, alice, 100000))))
+}
+
+func TestAccountLifecycleJournal(t *testing.T) {
+	dataDir := t.TempDir()
+	p := makeProcessor(t, dataDir, Config{})
+	process(t, p, block(1, pay(alice, bob, 200000)))
+	process(t, p, block(2, pay(alice, carol, 200000)))
+	require.FileExists(t, path.Join(dataDir, journalFile))
+
+	// An incomplete entry is ignored.
+	f, err := os.OpenFile(path.Join(dataDir, journalFile), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"round":3,"acco`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// The journal is compacted on startup.
+	p = makeProcessor(t, dataDir, Config{})
+	assert.NoFileExists(t, path.Join(dataDir, journalFile))
+	assert.Equal(t, uint64(3), p.committed.NextRound)
+	assert.Equal(t, map[string]account{
+		alice.String(): {},
+		bob.String():   {},
+		carol.String(): {},
+	}, p.committed.Accounts)
+	assert.Equal(t, []data.AccountEvent{
+		event(data.AccountClosed, carol, 3, 0),
+	}, process(t, p, block(3, closeTo(carol, bob, 1))))
+}
+
+func TestMinBalance(t *testing.T) {
+	params := consensusParams(string(protocol.ConsensusCurrentVersion))
+	var acct sdk.AccountData
+	assert.Equal(t, uint64(100000), minBalance(params, &acct))
+	acct.TotalAssets = 2
+	acct.TotalAppParams = 1
+	acct.TotalAppSchema = sdk.StateSchema{NumUint: 1, NumByteSlice: 1}
+	acct.TotalBoxes = 1
+	acct.TotalBoxBytes = 10
+	assert.Equal(t, uint64(300000+100000+28500+50000+2500+4000), minBalance(params, &acct))
+	assert.Equal(t, params, consensusParams("unknown"), "unknown protocols use the current protocol")
+}
+
+func TestAccountLifecycleInitErrors(t *testing.T) {
+	b, err := yaml.Marshal(Config{Events: []string{"deleted"}})
+	require.NoError(t, err)
+	l, _ := test.NewNullLogger()
+	rnd := sdk.Round(0)
+	err = (&Processor{}).Init(context.Background(), conduit.MakePipelineInitProvider(&rnd, &sdk.Genesis{}), plugins.PluginConfig{DataDir: t.TempDir(), Config: string(b)}, l)
+	assert.EqualError(t, err, "account lifecycle processor Init(): unknown event (deleted)")
+}
@@ -0,0 +1,19 @@
+package accountlifecycle
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+//Name: conduit_processors_account_lifecycle
+
+// Config configuration for the account lifecycle processor
+type Config struct {
+	/* <code>events</code> limits the emitted events to these types, by default all of them are emitted:
+	<ul>
+		<li>created</li>
+		<li>funded</li>
+		<li>closed</li>
+		<li>reopened</li>
+	</ul>
+	The lifecycle of every account is tracked regardless.
+	*/
+	Events []string `yaml:"events"`
+}
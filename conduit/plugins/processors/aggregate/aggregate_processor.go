@@ -0,0 +1,245 @@
+package aggregate
+
+import (
+	"context"
+	_ "embed" // used to embed config
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// PluginName to use when configuring.
+const PluginName = "aggregate"
+
+const (
+	defaultName   = "aggregate"
+	defaultBucket = time.Hour
+
+	groupByTxnType = "txn-type"
+	groupBySender  = "sender"
+
+	// stateFile is where the open bucket is saved in the plugin data directory.
+	stateFile = "aggregate_state.json"
+)
+
+// package-wide init function
+func init() {
+	processors.Register(PluginName, processors.ProcessorConstructorFunc(func() processors.Processor {
+		return &Processor{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Processor, PluginName, Config{})
+}
+
+// state is the open bucket, it includes the rounds before NextRound.
+type state struct {
+	NextRound uint64 `json:"next-round"`
+	// Started is false until the first block is aggregated.
+	Started     bool                      `json:"started"`
+	BucketStart int64                     `json:"bucket-start"`
+	Open        map[string]data.Aggregate `json:"open,omitempty"`
+}
+
+// Processor accumulates the transactions of each time bucket and emits the
+// aggregates with the block which closes the bucket.
+type Processor struct {
+	cfg       Config
+	logger    *log.Logger
+	statePath string
+	// committed is the state after the last completed round, it is saved
+	// to statePath. pending is the state after the last processed round.
+	committed state
+	pending   *state
+}
+
+//go:embed sample.yaml
+var sampleConfig string
+
+// Metadata returns metadata
+func (p *Processor) Metadata() conduit.Metadata {
+	return conduit.Metadata{
+		Name:         PluginName,
+		Description:  "Aggregate transactions per time bucket and emit the aggregates when the bucket closes.",
+		Deprecated:   false,
+		SampleConfig: sampleConfig,
+	}
+}
+
+// Config returns the config
+func (p *Processor) Config() string {
+	s, _ := yaml.Marshal(p.cfg)
+	return string(s)
+}
+
+// Init loads the open bucket saved by a previous run.
+func (p *Processor) Init(_ context.Context, initProvider data.InitProvider, cfg plugins.PluginConfig, logger *log.Logger) error {
+	p.logger = logger
+	if err := cfg.UnmarshalConfig(&p.cfg); err != nil {
+		return fmt.Errorf("aggregate processor init error: %w", err)
+	}
+	if p.cfg.Name == "" {
+		p.cfg.Name = defaultName
+	}
+	if p.cfg.Bucket == 0 {
+		p.cfg.Bucket = defaultBucket
+	}
+	if p.cfg.Bucket < time.Second || p.cfg.Bucket%time.Second != 0 {
+		return fmt.Errorf("aggregate processor Init(): bucket (%s) must be a whole number of seconds", p.cfg.Bucket)
+	}
+	switch p.cfg.GroupBy {
+	case "", groupByTxnType, groupBySender:
+	default:
+		return fmt.Errorf("aggregate processor Init(): unknown group-by (%s)", p.cfg.GroupBy)
+	}
+
+	p.statePath = path.Join(cfg.DataDir, stateFile)
+	b, err := os.ReadFile(p.statePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("aggregate processor Init(): unable to read state: %w", err)
+	}
+	if err == nil {
+		if err = json.Unmarshal(b, &p.committed); err != nil {
+			return fmt.Errorf("aggregate processor Init(): unable to decode state: %w", err)
+		}
+	}
+	if next := uint64(initProvider.NextDBRound()); p.committed.NextRound > next {
+		p.logger.Warnf("aggregate processor already includes rounds %d to %d, they are not aggregated again", next, p.committed.NextRound-1)
+	}
+	return nil
+}
+
+// Close does nothing, the open bucket was saved when its last round completed.
+func (p *Processor) Close() error {
+	return nil
+}
+
+// Process adds the block to the open bucket. If the block is in a later bucket
+// the open bucket is closed first, and its aggregates are added to the block.
+func (p *Processor) Process(input data.BlockData) (data.BlockData, error) {
+	round := input.Round()
+	if round < p.committed.NextRound {
+		// The round was aggregated before a restart.
+		return input, nil
+	}
+	// Retries start from the committed state so that a round is only counted once.
+	next := p.committed.clone()
+	bucketSecs := int64(p.cfg.Bucket / time.Second)
+	timestamp := input.BlockHeader.TimeStamp
+	bucketStart := timestamp - timestamp%bucketSecs
+	if timestamp < 0 && timestamp%bucketSecs != 0 {
+		bucketStart -= bucketSecs
+	}
+
+	if next.Started && bucketStart > next.BucketStart {
+		input.Aggregates = append(input.Aggregates, next.aggregates()...)
+		next.Open = make(map[string]data.Aggregate)
+		next.BucketStart = bucketStart
+	} else if !next.Started {
+		next.Started = true
+		next.BucketStart = bucketStart
+	}
+	// Blocks with an earlier timestamp than the open bucket are added to it.
+
+	if p.cfg.GroupBy == "" {
+		next.add(p.cfg.Name, bucketSecs, "", round, nil)
+	}
+	for idx := range input.Payset {
+		stxn := &input.Payset[idx]
+		next.add(p.cfg.Name, bucketSecs, p.group(stxn), round, stxn)
+	}
+	next.NextRound = round + 1
+	p.pending = &next
+	return input, nil
+}
+
+// OnComplete saves the state once the round has been exported.
+func (p *Processor) OnComplete(input data.BlockData) error {
+	if p.pending == nil || p.pending.NextRound != input.Round()+1 {
+		return nil
+	}
+	p.committed = *p.pending
+	p.pending = nil
+	b, err := json.Marshal(p.committed)
+	if err != nil {
+		return fmt.Errorf("aggregate processor OnComplete(): unable to encode state: %w", err)
+	}
+	tmp := p.statePath + ".tmp"
+	if err = os.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("aggregate processor OnComplete(): unable to write state: %w", err)
+	}
+	if err = os.Rename(tmp, p.statePath); err != nil {
+		return fmt.Errorf("aggregate processor OnComplete(): unable to write state: %w", err)
+	}
+	return nil
+}
+
+func (p *Processor) group(stxn *sdk.SignedTxnInBlock) string {
+	switch p.cfg.GroupBy {
+	case groupByTxnType:
+		return string(stxn.Txn.Type)
+	case groupBySender:
+		return stxn.Txn.Sender.String()
+	default:
+		return ""
+	}
+}
+
+func (s state) clone() state {
+	c := s
+	c.Open = make(map[string]data.Aggregate, len(s.Open))
+	for group, agg := range s.Open {
+		c.Open[group] = agg
+	}
+	return c
+}
+
+// add adds the transaction to the aggregate of its group, stxn is nil to only
+// record the round.
+func (s *state) add(name string, bucketSecs int64, group string, round uint64, stxn *sdk.SignedTxnInBlock) {
+	agg, ok := s.Open[group]
+	if !ok {
+		agg = data.Aggregate{
+			Name:        name,
+			BucketStart: s.BucketStart,
+			BucketEnd:   s.BucketStart + bucketSecs,
+			Group:       group,
+			FirstRound:  round,
+		}
+	}
+	agg.LastRound = round
+	if stxn != nil {
+		agg.Txns++
+		agg.Fees += uint64(stxn.Txn.Fee)
+		if stxn.Txn.Type == sdk.PaymentTx {
+			agg.Amount += uint64(stxn.Txn.Amount)
+		}
+	}
+	s.Open[group] = agg
+}
+
+// aggregates returns the aggregates of the open bucket ordered by group.
+func (s state) aggregates() []data.Aggregate {
+	groups := make([]string, 0, len(s.Open))
+	for group := range s.Open {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	aggs := make([]data.Aggregate, 0, len(groups))
+	for _, group := range groups {
+		aggs = append(aggs, s.Open[group])
+	}
+	return aggs
+}
@@ -0,0 +1,128 @@
+package aggregate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+)
+
+const hour = int64(3600)
+
+func makeProcessor(t *testing.T, dataDir string, cfg Config) *Processor {
+	b, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	l, _ := test.NewNullLogger()
+	rnd := sdk.Round(0)
+	p := &Processor{}
+	err = p.Init(context.Background(), conduit.MakePipelineInitProvider(&rnd, &sdk.Genesis{}), plugins.PluginConfig{DataDir: dataDir, Config: string(b)}, l)
+	require.NoError(t, err)
+	return p
+}
+
+func pay(fee, amount uint64) sdk.SignedTxnInBlock {
+	var stxn sdk.SignedTxnInBlock
+	stxn.Txn.Type = sdk.PaymentTx
+	stxn.Txn.Fee = sdk.MicroAlgos(fee)
+	stxn.Txn.Amount = sdk.MicroAlgos(amount)
+	return stxn
+}
+
+func appl(fee uint64) sdk.SignedTxnInBlock {
+	var stxn sdk.SignedTxnInBlock
+	stxn.Txn.Type = sdk.ApplicationCallTx
+	stxn.Txn.Fee = sdk.MicroAlgos(fee)
+	return stxn
+}
+
+func block(round uint64, timestamp int64, payset ...sdk.SignedTxnInBlock) data.BlockData {
+	return data.BlockData{
+		BlockHeader: sdk.BlockHeader{Round: sdk.Round(round), TimeStamp: timestamp},
+		Payset:      payset,
+	}
+}
+
+// process processes and completes the block.
+func process(t *testing.T, p *Processor, blk data.BlockData) []data.Aggregate {
+	out, err := p.Process(blk)
+	require.NoError(t, err)
+	require.NoError(t, p.OnComplete(out))
+	return out.Aggregates
+}
+
+func TestAggregateEmitOnClose(t *testing.T) {
+	dataDir := t.TempDir()
+	p := makeProcessor(t, dataDir, Config{Name: "hourly"})
+
+	assert.Empty(t, process(t, p, block(1, 10*hour+5, pay(1000, 5))))
+	// A retried round is only counted once.
+	_, err := p.Process(block(2, 10*hour+100, pay(1000, 7), appl(2000)))
+	require.NoError(t, err)
+	assert.Empty(t, process(t, p, block(2, 10*hour+100, pay(1000, 7), appl(2000))))
+
+	// The next process starts from the saved state.
+	p = makeProcessor(t, dataDir, Config{Name: "hourly"})
+	assert.Empty(t, process(t, p, block(2, 10*hour+100, pay(1000, 7))), "rounds are not aggregated twice")
+	aggs := process(t, p, block(3, 11*hour+3))
+	require.Equal(t, []data.Aggregate{{
+		Name:        "hourly",
+		BucketStart: 10 * hour,
+		BucketEnd:   11 * hour,
+		FirstRound:  1,
+		LastRound:   2,
+		Txns:        3,
+		Fees:        4000,
+		Amount:      12,
+	}}, aggs)
+
+	// Empty buckets are emitted when not grouping.
+	aggs = process(t, p, block(4, 13*hour))
+	require.Len(t, aggs, 1)
+	assert.Equal(t, data.Aggregate{Name: "hourly", BucketStart: 11 * hour, BucketEnd: 12 * hour, FirstRound: 3, LastRound: 3}, aggs[0])
+}
+
+func TestAggregateGroupBy(t *testing.T) {
+	p := makeProcessor(t, t.TempDir(), Config{Bucket: 24 * time.Hour, GroupBy: groupByTxnType})
+	day := 24 * hour
+
+	assert.Empty(t, process(t, p, block(1, day+5, pay(1000, 5), appl(2000))))
+	assert.Empty(t, process(t, p, block(2, day+10)))
+	// Blocks with an earlier timestamp stay in the open bucket.
+	assert.Empty(t, process(t, p, block(3, day-10, appl(3000))))
+	aggs := process(t, p, block(4, 2*day))
+	assert.Equal(t, []data.Aggregate{
+		{Name: defaultName, BucketStart: day, BucketEnd: 2 * day, Group: "appl", FirstRound: 1, LastRound: 3, Txns: 2, Fees: 5000},
+		{Name: defaultName, BucketStart: day, BucketEnd: 2 * day, Group: "pay", FirstRound: 1, LastRound: 1, Txns: 1, Fees: 1000, Amount: 5},
+	}, aggs)
+}
+
+func TestAggregateInitErrors(t *testing.T) {
+	tests := []struct {
+		cfg    Config
+		errMsg string
+	}{
+		{Config{Bucket: 500 * time.Millisecond}, "aggregate processor Init(): bucket (500ms) must be a whole number of seconds"},
+		{Config{Bucket: 1500 * time.Millisecond}, "aggregate processor Init(): bucket (1.5s) must be a whole number of seconds"},
+		{Config{GroupBy: "receiver"}, "aggregate processor Init(): unknown group-by (receiver)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.errMsg, func(t *testing.T) {
+			b, err := yaml.Marshal(tc.cfg)
+			require.NoError(t, err)
+			l, _ := test.NewNullLogger()
+			rnd := sdk.Round(0)
+			err = (&Processor{}).Init(context.Background(), conduit.MakePipelineInitProvider(&rnd, &sdk.Genesis{}), plugins.PluginConfig{DataDir: t.TempDir(), Config: string(b)}, l)
+			assert.EqualError(t, err, tc.errMsg)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package aggregate
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+import "time"
+
+//Name: conduit_processors_aggregate
+
+// Config configuration for the aggregate processor
+type Config struct {
+	/* <code>name</code> identifies the emitted aggregates, so that several aggregate processors can be told apart.<br/>
+	Default: "aggregate"
+	*/
+	Name string `yaml:"name"`
+	/* <code>bucket</code> is the duration of the time buckets, for example "1h" or "24h".<br/>
+	Buckets are aligned to the unix epoch, so "24h" buckets start at midnight UTC. A bucket is closed, and its
+	aggregates are emitted, by the first block with a timestamp in a later bucket.<br/>
+	Default: "1h"
+	*/
+	Bucket time.Duration `yaml:"bucket"`
+	/* <code>group-by</code> emits one aggregate per bucket for each value of the key:
+	<ul>
+		<li>"" (default) a single aggregate for all transactions.</li>
+		<li>txn-type</li>
+		<li>sender</li>
+	</ul>
+	*/
+	GroupBy string `yaml:"group-by"`
+}
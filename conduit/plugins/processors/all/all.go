@@ -2,6 +2,10 @@ package all
 
 import (
 	// Call package wide init function
+	_ "github.com/algorand/conduit/conduit/plugins/processors/accountlifecycle"
+	_ "github.com/algorand/conduit/conduit/plugins/processors/aggregate"
+	_ "github.com/algorand/conduit/conduit/plugins/processors/anonymize"
+	_ "github.com/algorand/conduit/conduit/plugins/processors/feesponsor"
 	_ "github.com/algorand/conduit/conduit/plugins/processors/filterprocessor"
 	_ "github.com/algorand/conduit/conduit/plugins/processors/noop"
 )
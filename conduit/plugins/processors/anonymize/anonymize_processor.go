@@ -0,0 +1,236 @@
+package anonymize
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	_ "embed" // used to embed config
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// PluginName to use when configuring.
+const PluginName = "anonymize"
+
+// minKeyLength is the minimum length of the HMAC key.
+const minKeyLength = 16
+
+// The prefixes of the HMAC inputs, so that an address and a digest with the
+// same bytes have different pseudonyms.
+const (
+	addressPrefix = "address:"
+	digestPrefix  = "digest:"
+)
+
+// package-wide init function
+func init() {
+	processors.Register(PluginName, processors.ProcessorConstructorFunc(func() processors.Processor {
+		return &Processor{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Processor, PluginName, Config{})
+}
+
+// Processor pseudonymizes the addresses, strips the notes and coarsens the
+// amounts of the blocks according to a policy, to publish datasets without
+// exposing the counterparties.
+type Processor struct {
+	cfg    Config
+	logger *log.Logger
+	policy Policy
+	// keep has the addresses which are not pseudonymized.
+	keep map[sdk.Address]bool
+}
+
+//go:embed sample.yaml
+var sampleConfig string
+
+// Metadata returns metadata
+func (p *Processor) Metadata() conduit.Metadata {
+	return conduit.Metadata{
+		Name:         PluginName,
+		Description:  "Pseudonymize addresses, strip notes and coarsen amounts to publish datasets.",
+		Deprecated:   false,
+		SampleConfig: sampleConfig,
+	}
+}
+
+// Config returns the config, without the key.
+func (p *Processor) Config() string {
+	cfg := p.cfg
+	if cfg.Key != "" {
+		cfg.Key = "<redacted>"
+	}
+	s, _ := yaml.Marshal(cfg)
+	return string(s)
+}
+
+// Init loads the policy and validates the key.
+func (p *Processor) Init(_ context.Context, _ data.InitProvider, cfg plugins.PluginConfig, logger *log.Logger) error {
+	p.logger = logger
+	if err := cfg.UnmarshalConfig(&p.cfg); err != nil {
+		return fmt.Errorf("anonymize processor init error: %w", err)
+	}
+	policy, err := loadPolicy(p.cfg.PolicyFile)
+	if err != nil {
+		return fmt.Errorf("anonymize processor Init(): %w", err)
+	}
+	p.policy = policy
+	if policy.Addresses.Pseudonymize && len(p.cfg.Key) < minKeyLength {
+		return fmt.Errorf("anonymize processor Init(): the key must have at least %d characters", minKeyLength)
+	}
+	p.keep = make(map[sdk.Address]bool)
+	for _, keep := range policy.Addresses.Keep {
+		addr, err := sdk.DecodeAddress(keep)
+		if err != nil {
+			return fmt.Errorf("anonymize processor Init(): invalid keep address (%s): %w", keep, err)
+		}
+		p.keep[addr] = true
+	}
+	return nil
+}
+
+// Close does nothing.
+func (p *Processor) Close() error {
+	return nil
+}
+
+// Process anonymizes the transactions of the block, and the data which the
+// other processors added to it.
+func (p *Processor) Process(input data.BlockData) (data.BlockData, error) {
+	for idx := range input.Payset {
+		p.txn(&input.Payset[idx].SignedTxnWithAD)
+	}
+	pseudonymize := p.policy.Addresses.Pseudonymize
+	if pseudonymize {
+		// The state delta is keyed by the accounts, and the certificate has
+		// the votes of the participating accounts.
+		input.Delta = nil
+		input.Certificate = nil
+	}
+	algos := p.policy.Amounts.MicroAlgos
+	for idx := range input.AccountEvents {
+		event := &input.AccountEvents[idx]
+		if pseudonymize {
+			var err error
+			if event.Address, err = p.addressString(event.Address); err != nil {
+				return input, fmt.Errorf("anonymize processor: account event: %w", err)
+			}
+		}
+		event.Balance = coarsen(event.Balance, algos)
+		event.MinBalance = coarsen(event.MinBalance, algos)
+	}
+	for idx := range input.FeeSponsorships {
+		sponsorship := &input.FeeSponsorships[idx]
+		if pseudonymize {
+			var err error
+			if sponsorship.Sender, err = p.addressString(sponsorship.Sender); err != nil {
+				return input, fmt.Errorf("anonymize processor: fee sponsorship: %w", err)
+			}
+			if sponsorship.Sponsor, err = p.addressString(sponsorship.Sponsor); err != nil {
+				return input, fmt.Errorf("anonymize processor: fee sponsorship: %w", err)
+			}
+		}
+		sponsorship.Amount = coarsen(sponsorship.Amount, algos)
+	}
+	for idx := range input.Aggregates {
+		aggregate := &input.Aggregates[idx]
+		// Aggregates grouped by sender have an address as group.
+		if addr, err := sdk.DecodeAddress(aggregate.Group); pseudonymize && err == nil {
+			aggregate.Group = p.address(addr).String()
+		}
+		aggregate.Fees = coarsen(aggregate.Fees, algos)
+		aggregate.Amount = coarsen(aggregate.Amount, algos)
+	}
+	return input, nil
+}
+
+// txn anonymizes the transaction and its inner transactions.
+func (p *Processor) txn(stxn *sdk.SignedTxnWithAD) {
+	txn := &stxn.Txn
+	if p.policy.Notes.Strip {
+		txn.Note = nil
+	}
+
+	algos, assets := p.policy.Amounts.MicroAlgos, p.policy.Amounts.Assets
+	for _, amount := range []*sdk.MicroAlgos{&txn.Fee, &txn.Amount, &stxn.ClosingAmount, &stxn.SenderRewards, &stxn.ReceiverRewards, &stxn.CloseRewards} {
+		*amount = sdk.MicroAlgos(coarsen(uint64(*amount), algos))
+	}
+	txn.AssetAmount = coarsen(txn.AssetAmount, assets)
+	stxn.AssetClosingAmount = coarsen(stxn.AssetClosingAmount, assets)
+
+	if p.policy.Addresses.Pseudonymize {
+		addresses := []*sdk.Address{
+			&txn.Sender, &txn.Receiver, &txn.CloseRemainderTo, &txn.RekeyTo,
+			&txn.AssetSender, &txn.AssetReceiver, &txn.AssetCloseTo, &txn.FreezeAccount,
+			&txn.AssetParams.Manager, &txn.AssetParams.Reserve, &txn.AssetParams.Freeze, &txn.AssetParams.Clawback,
+			&stxn.AuthAddr,
+		}
+		for idx := range txn.Accounts {
+			addresses = append(addresses, &txn.Accounts[idx])
+		}
+		for _, addr := range addresses {
+			*addr = p.address(*addr)
+		}
+		// The signatures and participation keys identify the accounts, the
+		// group and lease identify the original transactions.
+		stxn.Sig = sdk.Signature{}
+		stxn.Msig = sdk.MultisigSig{}
+		stxn.Lsig = sdk.LogicSig{}
+		txn.VotePK = sdk.VotePK{}
+		txn.SelectionPK = sdk.VRFPK{}
+		txn.StateProofPK = sdk.MerkleVerifier{}
+		if txn.Group != (sdk.Digest{}) {
+			txn.Group = p.digest(txn.Group)
+		}
+		if txn.Lease != ([32]byte{}) {
+			txn.Lease = p.digest(txn.Lease)
+		}
+	}
+
+	for idx := range stxn.EvalDelta.InnerTxns {
+		p.txn(&stxn.EvalDelta.InnerTxns[idx])
+	}
+}
+
+// mac returns the keyed HMAC of the prefixed bytes.
+func (p *Processor) mac(prefix string, b []byte) (sum [32]byte) {
+	mac := hmac.New(sha256.New, []byte(p.cfg.Key))
+	mac.Write([]byte(prefix))
+	mac.Write(b)
+	copy(sum[:], mac.Sum(nil))
+	return sum
+}
+
+// address returns the pseudonym of addr. The zero address, which means that
+// a field is not set, and the addresses to keep are returned as is.
+func (p *Processor) address(addr sdk.Address) sdk.Address {
+	if addr == (sdk.Address{}) || p.keep[addr] {
+		return addr
+	}
+	return p.mac(addressPrefix, addr[:])
+}
+
+// addressString returns the pseudonym of an encoded address.
+func (p *Processor) addressString(addr string) (string, error) {
+	if addr == "" {
+		return "", nil
+	}
+	decoded, err := sdk.DecodeAddress(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid address (%s): %w", addr, err)
+	}
+	return p.address(decoded).String(), nil
+}
+
+func (p *Processor) digest(d [32]byte) [32]byte {
+	return p.mac(digestPrefix, d[:])
+}
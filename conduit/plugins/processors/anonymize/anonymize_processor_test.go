@@ -0,0 +1,175 @@
+package anonymize
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+)
+
+const testKey = "0123456789abcdef"
+
+var (
+	alice = sdk.Address{1}
+	bob   = sdk.Address{2}
+	carol = sdk.Address{3}
+)
+
+func initProcessor(cfg Config) (*Processor, error) {
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	l, _ := test.NewNullLogger()
+	rnd := sdk.Round(0)
+	p := &Processor{}
+	err = p.Init(context.Background(), conduit.MakePipelineInitProvider(&rnd, &sdk.Genesis{}), plugins.PluginConfig{Config: string(b)}, l)
+	return p, err
+}
+
+// writePolicy writes the policy file and returns its path.
+func writePolicy(t *testing.T, policy string) string {
+	file := path.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(policy), 0644))
+	return file
+}
+
+func payment(sender, receiver sdk.Address, amount uint64) sdk.SignedTxnInBlock {
+	var stxn sdk.SignedTxnInBlock
+	stxn.Txn.Type = sdk.PaymentTx
+	stxn.Txn.Sender = sender
+	stxn.Txn.Receiver = receiver
+	stxn.Txn.Amount = sdk.MicroAlgos(amount)
+	stxn.Txn.Fee = 1234
+	stxn.Txn.Note = []byte("invoice 42")
+	stxn.Sig = sdk.Signature{9}
+	return stxn
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		key    string
+		err    string
+	}{
+		{"defaults", "", testKey, ""},
+		{"short key", "", "secret", "anonymize processor Init(): the key must have at least 16 characters"},
+		{"no pseudonyms", "addresses: {pseudonymize: false}", "", ""},
+		{"unknown field", "notes: {remove: true}", testKey, "invalid policy file"},
+		{"invalid keep", "addresses: {keep: [alice]}", testKey, "invalid keep address (alice)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Config{Key: tc.key}
+			if tc.policy != "" {
+				cfg.PolicyFile = writePolicy(t, tc.policy)
+			}
+			p, err := initProcessor(cfg)
+			if tc.err == "" {
+				require.NoError(t, err)
+				if tc.key != "" {
+					assert.NotContains(t, p.Config(), tc.key)
+				}
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+// TestProcess tests that the addresses are replaced with stable pseudonyms,
+// the notes are stripped and the amounts are coarsened.
+func TestProcess(t *testing.T) {
+	policy := writePolicy(t, `
+addresses:
+  keep: [`+carol.String()+`]
+amounts:
+  microalgos: 1000
+  assets: 10
+`)
+	p, err := initProcessor(Config{PolicyFile: policy, Key: testKey})
+	require.NoError(t, err)
+
+	inner := payment(bob, alice, 2500)
+	pay := payment(alice, bob, 1234567)
+	pay.Txn.Group = sdk.Digest{7}
+	pay.EvalDelta.InnerTxns = []sdk.SignedTxnWithAD{inner.SignedTxnWithAD}
+	axfer := payment(bob, carol, 0)
+	axfer.Txn.Type = sdk.AssetTransferTx
+	axfer.Txn.AssetReceiver = carol
+	axfer.Txn.AssetAmount = 123
+	cert := map[string]interface{}{"vote": "alice"}
+	out, err := p.Process(data.BlockData{
+		Payset:          []sdk.SignedTxnInBlock{pay, axfer},
+		Delta:           &sdk.LedgerStateDelta{},
+		Certificate:     &cert,
+		AccountEvents:   []data.AccountEvent{{Type: data.AccountCreated, Address: bob.String(), Balance: 1999}},
+		FeeSponsorships: []data.FeeSponsorship{{Type: data.FeeSponsored, Sender: alice.String(), Sponsor: carol.String(), Amount: 1000}},
+	})
+	require.NoError(t, err)
+
+	anonAlice := out.Payset[0].Txn.Sender
+	anonBob := out.Payset[0].Txn.Receiver
+	assert.NotEqual(t, alice, anonAlice)
+	assert.NotEqual(t, bob, anonBob)
+	assert.NotEqual(t, anonAlice, anonBob)
+	assert.Equal(t, anonBob, out.Payset[1].Txn.Sender, "the pseudonyms are stable")
+	assert.Equal(t, carol, out.Payset[1].Txn.AssetReceiver, "kept addresses are not pseudonymized")
+	assert.Equal(t, sdk.Address{}, out.Payset[0].Txn.CloseRemainderTo, "unset addresses stay unset")
+
+	assert.Nil(t, out.Payset[0].Txn.Note)
+	assert.Equal(t, sdk.Signature{}, out.Payset[0].Sig)
+	assert.NotEqual(t, sdk.Digest{7}, out.Payset[0].Txn.Group)
+	assert.Equal(t, sdk.MicroAlgos(1234000), out.Payset[0].Txn.Amount)
+	assert.Equal(t, sdk.MicroAlgos(1000), out.Payset[0].Txn.Fee)
+	assert.Equal(t, uint64(120), out.Payset[1].Txn.AssetAmount)
+
+	innerOut := out.Payset[0].EvalDelta.InnerTxns[0]
+	assert.Equal(t, anonBob, innerOut.Txn.Sender)
+	assert.Equal(t, anonAlice, innerOut.Txn.Receiver)
+	assert.Equal(t, sdk.MicroAlgos(2000), innerOut.Txn.Amount)
+	assert.Nil(t, innerOut.Txn.Note)
+
+	assert.Nil(t, out.Delta)
+	assert.Nil(t, out.Certificate)
+	assert.Equal(t, []data.AccountEvent{{Type: data.AccountCreated, Address: anonBob.String(), Balance: 1000}}, out.AccountEvents)
+	assert.Equal(t, []data.FeeSponsorship{{Type: data.FeeSponsored, Sender: anonAlice.String(), Sponsor: carol.String(), Amount: 1000}}, out.FeeSponsorships)
+
+	// Another key gives other pseudonyms.
+	other, err := initProcessor(Config{PolicyFile: policy, Key: "fedcba9876543210"})
+	require.NoError(t, err)
+	out, err = other.Process(data.BlockData{Payset: []sdk.SignedTxnInBlock{payment(alice, bob, 0)}})
+	require.NoError(t, err)
+	assert.NotEqual(t, anonAlice, out.Payset[0].Txn.Sender)
+}
+
+// TestProcessKeepAddresses tests a policy which only coarsens the amounts.
+func TestProcessKeepAddresses(t *testing.T) {
+	p, err := initProcessor(Config{PolicyFile: writePolicy(t, `
+addresses: {pseudonymize: false}
+notes: {strip: false}
+amounts: {microalgos: 100}
+`)})
+	require.NoError(t, err)
+	delta := &sdk.LedgerStateDelta{}
+	out, err := p.Process(data.BlockData{Payset: []sdk.SignedTxnInBlock{payment(alice, bob, 150)}, Delta: delta})
+	require.NoError(t, err)
+	assert.Equal(t, alice, out.Payset[0].Txn.Sender)
+	assert.Equal(t, []byte("invoice 42"), out.Payset[0].Txn.Note)
+	assert.Equal(t, sdk.Signature{9}, out.Payset[0].Sig)
+	assert.Equal(t, sdk.MicroAlgos(100), out.Payset[0].Txn.Amount)
+	assert.Equal(t, delta, out.Delta)
+}
@@ -0,0 +1,18 @@
+package anonymize
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+//Name: conduit_processors_anonymize
+
+// Config configuration for the anonymize processor
+type Config struct {
+	/* <code>policy-file</code> is the path of the YAML policy which lists what is pseudonymized, stripped and coarsened.<br/>
+	The defaults of the policy apply when it is not set: addresses are pseudonymized, notes are stripped and amounts are kept.
+	*/
+	PolicyFile string `yaml:"policy-file"`
+	/* <code>key</code> is the secret key of the HMAC which pseudonymizes the addresses, at least 16 characters.<br/>
+	The same key always gives the same pseudonyms, so that datasets published with it can be joined. It must not be
+	published, anyone with the key can tell whether an address is behind a pseudonym.
+	*/
+	Key string `yaml:"key"`
+}
@@ -0,0 +1,78 @@
+package anonymize
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy lists what the processor pseudonymizes, strips and coarsens, it is
+// read from the policy-file.
+type Policy struct {
+	Addresses AddressPolicy `yaml:"addresses"`
+	Notes     NotePolicy    `yaml:"notes"`
+	Amounts   AmountPolicy  `yaml:"amounts"`
+}
+
+// AddressPolicy configures the address pseudonyms.
+type AddressPolicy struct {
+	// Pseudonymize replaces the addresses with their keyed HMAC, the default
+	// is true.
+	Pseudonymize bool `yaml:"pseudonymize"`
+	// Keep are the addresses which are published as is, such as the fee sink
+	// or the accounts of well known applications.
+	Keep []string `yaml:"keep"`
+}
+
+// NotePolicy configures the transaction notes.
+type NotePolicy struct {
+	// Strip removes the notes, the default is true.
+	Strip bool `yaml:"strip"`
+}
+
+// AmountPolicy configures the precision of the amounts, each amount is
+// rounded down to a multiple of its precision. Zero keeps the exact amounts.
+type AmountPolicy struct {
+	// MicroAlgos is the precision of the algo amounts, fees and rewards.
+	MicroAlgos uint64 `yaml:"microalgos"`
+	// Assets is the precision of the asset amounts, in base units.
+	Assets uint64 `yaml:"assets"`
+}
+
+func defaultPolicy() Policy {
+	return Policy{
+		Addresses: AddressPolicy{Pseudonymize: true},
+		Notes:     NotePolicy{Strip: true},
+	}
+}
+
+// loadPolicy reads the policy file, the settings which it does not have keep
+// their default.
+func loadPolicy(file string) (Policy, error) {
+	policy := defaultPolicy()
+	if file == "" {
+		return policy, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return Policy{}, fmt.Errorf("loadPolicy(): %w", err)
+	}
+	defer f.Close()
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return Policy{}, fmt.Errorf("loadPolicy(): invalid policy file %s: %w", file, err)
+	}
+	return policy, nil
+}
+
+// coarsen rounds the amount down to a multiple of precision.
+func coarsen(amount, precision uint64) uint64 {
+	if precision == 0 {
+		return amount
+	}
+	return amount - amount%precision
+}
@@ -0,0 +1,16 @@
+package feesponsor
+
+//go:generate go run ../../../../cmd/conduit-docs/main.go ../../../../conduit-docs/
+
+//Name: conduit_processors_fee_sponsor
+
+// Config configuration for the fee sponsor processor
+type Config struct {
+	/* <code>types</code> limits the emitted fee sponsorships to these types, by default both of them are emitted:
+	<ul>
+		<li>sponsored</li>
+		<li>pooled</li>
+	</ul>
+	*/
+	Types []string `yaml:"types"`
+}
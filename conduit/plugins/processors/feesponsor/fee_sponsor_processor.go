@@ -0,0 +1,193 @@
+package feesponsor
+
+import (
+	"context"
+	_ "embed" // used to embed config
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/algorand/indexer/protocol"
+	"github.com/algorand/indexer/protocol/config"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+	"github.com/algorand/conduit/conduit/plugins/processors"
+)
+
+// PluginName to use when configuring.
+const PluginName = "fee_sponsor"
+
+// package-wide init function
+func init() {
+	processors.Register(PluginName, processors.ProcessorConstructorFunc(func() processors.Processor {
+		return &Processor{}
+	}))
+	plugins.RegisterConfigSchema(plugins.Processor, PluginName, Config{})
+}
+
+// Processor attributes the fees pooled by the transactions of a group to the
+// transactions which they paid for.
+type Processor struct {
+	cfg    Config
+	logger *log.Logger
+	// emit has the sponsorship types to add to the blocks.
+	emit map[string]bool
+}
+
+//go:embed sample.yaml
+var sampleConfig string
+
+// Metadata returns metadata
+func (p *Processor) Metadata() conduit.Metadata {
+	return conduit.Metadata{
+		Name:         PluginName,
+		Description:  "Detect which transactions of a group paid the fees of the others, through fee pooling.",
+		Deprecated:   false,
+		SampleConfig: sampleConfig,
+	}
+}
+
+// Config returns the config
+func (p *Processor) Config() string {
+	s, _ := yaml.Marshal(p.cfg)
+	return string(s)
+}
+
+// Init validates the configured types.
+func (p *Processor) Init(_ context.Context, _ data.InitProvider, cfg plugins.PluginConfig, logger *log.Logger) error {
+	p.logger = logger
+	if err := cfg.UnmarshalConfig(&p.cfg); err != nil {
+		return fmt.Errorf("fee sponsor processor init error: %w", err)
+	}
+	p.emit = make(map[string]bool)
+	for _, sponsorshipType := range p.cfg.Types {
+		switch sponsorshipType {
+		case data.FeeSponsored, data.FeePooled:
+			p.emit[sponsorshipType] = true
+		default:
+			return fmt.Errorf("fee sponsor processor Init(): unknown type (%s)", sponsorshipType)
+		}
+	}
+	if len(p.emit) == 0 {
+		p.emit[data.FeeSponsored] = true
+		p.emit[data.FeePooled] = true
+	}
+	return nil
+}
+
+// Close does nothing.
+func (p *Processor) Close() error {
+	return nil
+}
+
+// Process adds the fee sponsorships of the groups of the block.
+func (p *Processor) Process(input data.BlockData) (data.BlockData, error) {
+	minFee := consensusParams(input.BlockHeader.CurrentProtocol).MinTxnFee
+	round := input.Round()
+	for start := 0; start < len(input.Payset); {
+		end := start + 1
+		group := input.Payset[start].Txn.Group
+		if group != (sdk.Digest{}) {
+			for end < len(input.Payset) && input.Payset[end].Txn.Group == group {
+				end++
+			}
+		}
+		for _, sponsorship := range sponsorships(input.Payset[start:end], uint64(start), minFee) {
+			if p.emit[sponsorship.Type] {
+				sponsorship.Round = round
+				input.FeeSponsorships = append(input.FeeSponsorships, sponsorship)
+			}
+		}
+		start = end
+	}
+	return input, nil
+}
+
+// txnFees are the fees of a top level transaction, including its inner
+// transactions.
+type txnFees struct {
+	intra  uint64
+	sender string
+	// balance is the fee paid minus the minimum fee, it is negative when
+	// other transactions of the group paid for the transaction.
+	balance int64
+}
+
+// sponsorships attributes the surplus fees of the transactions of a group to
+// the transactions which paid less than the minimum fee. The protocol pools
+// the fees without attributing them, so the surpluses are allocated to the
+// deficits in payset order.
+func sponsorships(group []sdk.SignedTxnInBlock, firstIntra uint64, minFee uint64) []data.FeeSponsorship {
+	if len(group) < 2 {
+		return nil
+	}
+	fees := make([]txnFees, len(group))
+	for idx := range group {
+		paid, required := groupFees(&group[idx].SignedTxnWithAD, minFee)
+		fees[idx] = txnFees{
+			intra:   firstIntra + uint64(idx),
+			sender:  group[idx].Txn.Sender.String(),
+			balance: int64(paid) - int64(required),
+		}
+	}
+
+	var result []data.FeeSponsorship
+	payer := 0
+	for idx := range fees {
+		for fees[idx].balance < 0 {
+			for payer < len(fees) && fees[payer].balance <= 0 {
+				payer++
+			}
+			if payer == len(fees) {
+				// The group was underfunded, which the protocol rejects.
+				return result
+			}
+			amount := -fees[idx].balance
+			if fees[payer].balance < amount {
+				amount = fees[payer].balance
+			}
+			fees[payer].balance -= amount
+			fees[idx].balance += amount
+
+			sponsorshipType := data.FeeSponsored
+			if fees[payer].sender == fees[idx].sender {
+				sponsorshipType = data.FeePooled
+			}
+			result = append(result, data.FeeSponsorship{
+				Type:         sponsorshipType,
+				Intra:        fees[idx].intra,
+				Sender:       fees[idx].sender,
+				SponsorIntra: fees[payer].intra,
+				Sponsor:      fees[payer].sender,
+				Amount:       uint64(amount),
+			})
+		}
+	}
+	return result
+}
+
+// groupFees returns the fee paid by a transaction and its inner transactions,
+// and the minimum fee they require.
+func groupFees(stxn *sdk.SignedTxnWithAD, minFee uint64) (paid uint64, required uint64) {
+	paid = uint64(stxn.Txn.Fee)
+	required = minFee
+	for idx := range stxn.EvalDelta.InnerTxns {
+		innerPaid, innerRequired := groupFees(&stxn.EvalDelta.InnerTxns[idx], minFee)
+		paid += innerPaid
+		required += innerRequired
+	}
+	return paid, required
+}
+
+// consensusParams returns the parameters of the protocol, or the current
+// protocol if conduit does not know it.
+func consensusParams(version string) config.ConsensusParams {
+	if params, ok := config.Consensus[protocol.ConsensusVersion(version)]; ok {
+		return params
+	}
+	return config.Consensus[protocol.ConsensusCurrentVersion]
+}
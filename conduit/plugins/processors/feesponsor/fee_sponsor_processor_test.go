@@ -0,0 +1,117 @@
+package feesponsor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+
+	"github.com/algorand/conduit/conduit"
+	"github.com/algorand/conduit/conduit/data"
+	"github.com/algorand/conduit/conduit/plugins"
+)
+
+var (
+	alice = sdk.Address{1}
+	bob   = sdk.Address{2}
+	carol = sdk.Address{3}
+
+	group1 = sdk.Digest{1}
+	group2 = sdk.Digest{2}
+)
+
+func makeProcessor(t *testing.T, cfg Config) *Processor {
+	b, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	l, _ := test.NewNullLogger()
+	rnd := sdk.Round(0)
+	p := &Processor{}
+	err = p.Init(context.Background(), conduit.MakePipelineInitProvider(&rnd, &sdk.Genesis{}), plugins.PluginConfig{Config: string(b)}, l)
+	require.NoError(t, err)
+	return p
+}
+
+func txn(sender sdk.Address, fee uint64, group sdk.Digest) sdk.SignedTxnInBlock {
+	var stxn sdk.SignedTxnInBlock
+	stxn.Txn.Type = sdk.PaymentTx
+	stxn.Txn.Sender = sender
+	stxn.Txn.Fee = sdk.MicroAlgos(fee)
+	stxn.Txn.Group = group
+	return stxn
+}
+
+func process(t *testing.T, p *Processor, payset ...sdk.SignedTxnInBlock) []data.FeeSponsorship {
+	out, err := p.Process(data.BlockData{
+		BlockHeader: sdk.BlockHeader{Round: 10},
+		Payset:      payset,
+	})
+	require.NoError(t, err)
+	return out.FeeSponsorships
+}
+
+func sponsorship(sponsorshipType string, intra uint64, sender sdk.Address, sponsorIntra uint64, sponsor sdk.Address, amount uint64) data.FeeSponsorship {
+	return data.FeeSponsorship{
+		Type:         sponsorshipType,
+		Round:        10,
+		Intra:        intra,
+		Sender:       sender.String(),
+		SponsorIntra: sponsorIntra,
+		Sponsor:      sponsor.String(),
+		Amount:       amount,
+	}
+}
+
+func TestFeeSponsor(t *testing.T) {
+	p := makeProcessor(t, Config{})
+
+	assert.Empty(t, process(t, p, txn(alice, 1000, sdk.Digest{}), txn(bob, 5000, sdk.Digest{})), "ungrouped transactions pay their own fee")
+	assert.Empty(t, process(t, p, txn(alice, 1000, group1), txn(bob, 2000, group1)), "every transaction paid its minimum fee")
+
+	// bob's fee is covered by alice and then carol, who also covers alice's
+	// second transaction. A sponsor may follow the transaction it paid for.
+	assert.Equal(t, []data.FeeSponsorship{
+		sponsorship(data.FeeSponsored, 2, bob, 1, alice, 500),
+		sponsorship(data.FeeSponsored, 2, bob, 3, carol, 500),
+		sponsorship(data.FeeSponsored, 4, alice, 3, carol, 1000),
+		sponsorship(data.FeeSponsored, 5, carol, 6, alice, 1000),
+	}, process(t, p,
+		txn(alice, 1000, sdk.Digest{}),
+		txn(alice, 1500, group1),
+		txn(bob, 0, group1),
+		txn(carol, 2500, group1),
+		txn(alice, 0, group1),
+		txn(carol, 0, group2),
+		txn(alice, 2000, group2),
+	))
+}
+
+func TestFeeSponsorInnerTxns(t *testing.T) {
+	p := makeProcessor(t, Config{})
+	appl := txn(bob, 1000, group1)
+	appl.Txn.Type = sdk.ApplicationCallTx
+	appl.EvalDelta.InnerTxns = []sdk.SignedTxnWithAD{txn(carol, 0, sdk.Digest{}).SignedTxnWithAD}
+	assert.Equal(t, []data.FeeSponsorship{
+		sponsorship(data.FeeSponsored, 1, bob, 0, alice, 1000),
+	}, process(t, p, txn(alice, 2000, group1), appl), "the inner transaction requires a minimum fee")
+}
+
+func TestFeeSponsorTypes(t *testing.T) {
+	p := makeProcessor(t, Config{Types: []string{data.FeePooled}})
+	assert.Equal(t, []data.FeeSponsorship{
+		sponsorship(data.FeePooled, 2, alice, 0, alice, 1000),
+	}, process(t, p, txn(alice, 3000, group1), txn(bob, 0, group1), txn(alice, 0, group1)))
+}
+
+func TestFeeSponsorInitErrors(t *testing.T) {
+	b, err := yaml.Marshal(Config{Types: []string{"refunded"}})
+	require.NoError(t, err)
+	l, _ := test.NewNullLogger()
+	rnd := sdk.Round(0)
+	err = (&Processor{}).Init(context.Background(), conduit.MakePipelineInitProvider(&rnd, &sdk.Genesis{}), plugins.PluginConfig{Config: string(b)}, l)
+	assert.EqualError(t, err, "fee sponsor processor Init(): unknown type (refunded)")
+}
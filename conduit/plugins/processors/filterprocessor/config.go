@@ -33,6 +33,25 @@ type SubConfig struct {
 	Expression string `yaml:"expression"`
 }
 
+// MatchConfig selects transactions by common fields. Every setting which is
+// provided must match, a list matches if any of its values do.
+type MatchConfig struct {
+	// <code>senders</code> are the addresses which may send the transaction.
+	Senders []string `yaml:"senders"`
+	// <code>receivers</code> are the addresses which may receive, or receive the remainder of, a payment or asset transfer.
+	Receivers []string `yaml:"receivers"`
+	// <code>addresses</code> are the addresses which may send or receive the transaction.
+	Addresses []string `yaml:"addresses"`
+	// <code>tx-types</code> are the transaction types to keep: pay, keyreg, acfg, axfer, afrz, appl or stpf.
+	TxTypes []string `yaml:"tx-types"`
+	// <code>app-ids</code> are the applications which may be called or created.
+	AppIDs []uint64 `yaml:"app-ids"`
+	// <code>asset-ids</code> are the assets which may be transferred, configured, frozen or created.
+	AssetIDs []uint64 `yaml:"asset-ids"`
+	// <code>min-amount</code> is the minimum amount of a payment, in microalgos, or of an asset transfer.
+	MinAmount uint64 `yaml:"min-amount"`
+}
+
 // Config configuration for the filter processor
 type Config struct {
 	// <code>search-inner</code> configures the filter processor to recursively search inner transactions for expressions.
@@ -48,4 +67,16 @@ type Config struct {
 			tag: ""
 	*/
 	Filters []map[string][]SubConfig `yaml:"filters"`
+	/* <code>match</code> keeps the transactions which match all of the provided settings, it is applied before the filters.
+
+	match:
+		senders: []
+		receivers: []
+		addresses: []
+		tx-types: []
+		app-ids: []
+		asset-ids: []
+		min-amount: 0
+	*/
+	Match *MatchConfig `yaml:"match"`
 }
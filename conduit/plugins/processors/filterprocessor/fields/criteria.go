@@ -0,0 +1,161 @@
+package fields
+
+import (
+	"fmt"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+)
+
+// Criteria matches transactions by common fields without writing expressions.
+// Every criterion which is set must match, a criterion with several values
+// matches if any of them does.
+type Criteria struct {
+	Senders     map[sdk.Address]bool
+	Receivers   map[sdk.Address]bool
+	Addresses   map[sdk.Address]bool
+	Types       map[sdk.TxType]bool
+	AppIDs      map[uint64]bool
+	AssetIDs    map[uint64]bool
+	MinAmount   uint64
+	SearchInner bool
+}
+
+// MakeCriteria parses the criteria values.
+func MakeCriteria(senders, receivers, addresses, types []string, appIDs, assetIDs []uint64, minAmount uint64, searchInner bool) (*Criteria, error) {
+	c := &Criteria{MinAmount: minAmount, SearchInner: searchInner}
+	var err error
+	if c.Senders, err = addressSet(senders); err != nil {
+		return nil, fmt.Errorf("MakeCriteria(): senders: %w", err)
+	}
+	if c.Receivers, err = addressSet(receivers); err != nil {
+		return nil, fmt.Errorf("MakeCriteria(): receivers: %w", err)
+	}
+	if c.Addresses, err = addressSet(addresses); err != nil {
+		return nil, fmt.Errorf("MakeCriteria(): addresses: %w", err)
+	}
+	if len(types) > 0 {
+		c.Types = make(map[sdk.TxType]bool, len(types))
+		for _, t := range types {
+			switch txType := sdk.TxType(t); txType {
+			case sdk.PaymentTx, sdk.KeyRegistrationTx, sdk.AssetConfigTx, sdk.AssetTransferTx,
+				sdk.AssetFreezeTx, sdk.ApplicationCallTx, sdk.StateProofTx:
+				c.Types[txType] = true
+			default:
+				return nil, fmt.Errorf("MakeCriteria(): unknown transaction type: %s", t)
+			}
+		}
+	}
+	if c.AppIDs, err = idSet(appIDs); err != nil {
+		return nil, fmt.Errorf("MakeCriteria(): app-ids: %w", err)
+	}
+	if c.AssetIDs, err = idSet(assetIDs); err != nil {
+		return nil, fmt.Errorf("MakeCriteria(): asset-ids: %w", err)
+	}
+	return c, nil
+}
+
+func addressSet(addresses []string) (map[sdk.Address]bool, error) {
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+	set := make(map[sdk.Address]bool, len(addresses))
+	for _, a := range addresses {
+		addr, err := sdk.DecodeAddress(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address (%s): %w", a, err)
+		}
+		set[addr] = true
+	}
+	return set, nil
+}
+
+// idSet rejects zero since it is the ID of every transaction which does not
+// reference an app or asset.
+func idSet(ids []uint64) (map[uint64]bool, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	set := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		if id == 0 {
+			return nil, fmt.Errorf("0 is not a valid ID")
+		}
+		set[id] = true
+	}
+	return set, nil
+}
+
+// Empty returns true if no criterion is set.
+func (c *Criteria) Empty() bool {
+	return len(c.Senders) == 0 && len(c.Receivers) == 0 && len(c.Addresses) == 0 &&
+		len(c.Types) == 0 && len(c.AppIDs) == 0 && len(c.AssetIDs) == 0 && c.MinAmount == 0
+}
+
+// receivers returns the accounts which receive funds from the transaction.
+func receivers(txn *sdk.Transaction) []sdk.Address {
+	return []sdk.Address{txn.Receiver, txn.CloseRemainderTo, txn.AssetReceiver, txn.AssetCloseTo}
+}
+
+func containsAny(set map[sdk.Address]bool, addresses ...sdk.Address) bool {
+	for _, addr := range addresses {
+		if addr != (sdk.Address{}) && set[addr] {
+			return true
+		}
+	}
+	return false
+}
+
+// amount returns the amount transferred, in microalgos or asset units.
+func amount(txn *sdk.Transaction) uint64 {
+	switch txn.Type {
+	case sdk.PaymentTx:
+		return uint64(txn.Amount)
+	case sdk.AssetTransferTx:
+		return txn.AssetAmount
+	}
+	return 0
+}
+
+func (c *Criteria) matchesTxn(stxn *sdk.SignedTxnWithAD) bool {
+	txn := &stxn.Txn
+	if len(c.Senders) > 0 && !c.Senders[txn.Sender] {
+		return false
+	}
+	if len(c.Receivers) > 0 && !containsAny(c.Receivers, receivers(txn)...) {
+		return false
+	}
+	if len(c.Addresses) > 0 && !containsAny(c.Addresses, append(receivers(txn), txn.Sender, txn.AssetSender)...) {
+		return false
+	}
+	if len(c.Types) > 0 && !c.Types[txn.Type] {
+		return false
+	}
+	if len(c.AppIDs) > 0 && !c.AppIDs[uint64(txn.ApplicationID)] && !c.AppIDs[stxn.ApplicationID] {
+		return false
+	}
+	if len(c.AssetIDs) > 0 && !c.AssetIDs[uint64(txn.XferAsset)] && !c.AssetIDs[uint64(txn.ConfigAsset)] &&
+		!c.AssetIDs[uint64(txn.FreezeAsset)] && !c.AssetIDs[stxn.ConfigAsset] {
+		return false
+	}
+	if c.MinAmount > 0 && amount(txn) < c.MinAmount {
+		return false
+	}
+	return true
+}
+
+// Matches returns true if the transaction, or with SearchInner one of its
+// inner transactions, matches the criteria. Apps and assets created by the
+// transaction match by the ID in its ApplyData.
+func (c *Criteria) Matches(stxn *sdk.SignedTxnWithAD) (bool, error) {
+	if c.matchesTxn(stxn) {
+		return true, nil
+	}
+	if c.SearchInner {
+		for i := range stxn.EvalDelta.InnerTxns {
+			if match, _ := c.Matches(&stxn.EvalDelta.InnerTxns[i]); match {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
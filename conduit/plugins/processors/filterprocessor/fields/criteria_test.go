@@ -0,0 +1,112 @@
+package fields
+
+import (
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeCriteria(t *testing.T) {
+	addr := sdk.Address{1}.String()
+	tests := []struct {
+		name     string
+		types    []string
+		senders  []string
+		appIDs   []uint64
+		errorMsg string
+	}{
+		{name: "valid", types: []string{"pay", "appl"}, senders: []string{addr}, appIDs: []uint64{5}},
+		{name: "bad type", types: []string{"transfer"}, errorMsg: "unknown transaction type: transfer"},
+		{name: "bad address", senders: []string{"not-an-address"}, errorMsg: "senders: invalid address (not-an-address)"},
+		{name: "zero id", appIDs: []uint64{0}, errorMsg: "app-ids: 0 is not a valid ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := MakeCriteria(tt.senders, nil, nil, tt.types, tt.appIDs, nil, 0, false)
+			if tt.errorMsg != "" {
+				require.ErrorContains(t, err, tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.False(t, c.Empty())
+		})
+	}
+
+	c, err := MakeCriteria(nil, nil, nil, nil, nil, nil, 0, false)
+	require.NoError(t, err)
+	assert.True(t, c.Empty())
+}
+
+func TestCriteriaMatches(t *testing.T) {
+	addr1 := sdk.Address{1}
+	addr2 := sdk.Address{2}
+	addr3 := sdk.Address{3}
+
+	pay := sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{
+		Type:             sdk.PaymentTx,
+		Header:           sdk.Header{Sender: addr1},
+		PaymentTxnFields: sdk.PaymentTxnFields{Receiver: addr2, Amount: 100},
+	}}}
+	axfer := sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{
+		Type:                   sdk.AssetTransferTx,
+		Header:                 sdk.Header{Sender: addr2},
+		AssetTransferTxnFields: sdk.AssetTransferTxnFields{XferAsset: 7, AssetAmount: 5, AssetCloseTo: addr3},
+	}}}
+	appCreate := sdk.SignedTxnWithAD{
+		SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{Type: sdk.ApplicationCallTx, Header: sdk.Header{Sender: addr3}}},
+		ApplyData: sdk.ApplyData{ApplicationID: 9},
+	}
+	appCall := sdk.SignedTxnWithAD{
+		SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{
+			Type:              sdk.ApplicationCallTx,
+			Header:            sdk.Header{Sender: addr3},
+			ApplicationFields: sdk.ApplicationFields{ApplicationCallTxnFields: sdk.ApplicationCallTxnFields{ApplicationID: 11}},
+		}},
+		ApplyData: sdk.ApplyData{EvalDelta: sdk.EvalDelta{InnerTxns: []sdk.SignedTxnWithAD{axfer}}},
+	}
+
+	tests := []struct {
+		name     string
+		criteria Criteria
+		txn      sdk.SignedTxnWithAD
+		match    bool
+	}{
+		{name: "sender", criteria: Criteria{Senders: map[sdk.Address]bool{addr1: true}}, txn: pay, match: true},
+		{name: "sender mismatch", criteria: Criteria{Senders: map[sdk.Address]bool{addr2: true}}, txn: pay},
+		{name: "receiver", criteria: Criteria{Receivers: map[sdk.Address]bool{addr2: true}}, txn: pay, match: true},
+		{name: "close to receiver", criteria: Criteria{Receivers: map[sdk.Address]bool{addr3: true}}, txn: axfer, match: true},
+		{name: "address as sender", criteria: Criteria{Addresses: map[sdk.Address]bool{addr1: true}}, txn: pay, match: true},
+		{name: "address mismatch", criteria: Criteria{Addresses: map[sdk.Address]bool{addr3: true}}, txn: pay},
+		{name: "type", criteria: Criteria{Types: map[sdk.TxType]bool{sdk.AssetTransferTx: true}}, txn: axfer, match: true},
+		{name: "type mismatch", criteria: Criteria{Types: map[sdk.TxType]bool{sdk.AssetTransferTx: true}}, txn: pay},
+		{name: "asset", criteria: Criteria{AssetIDs: map[uint64]bool{7: true}}, txn: axfer, match: true},
+		{name: "created app", criteria: Criteria{AppIDs: map[uint64]bool{9: true}}, txn: appCreate, match: true},
+		{name: "called app", criteria: Criteria{AppIDs: map[uint64]bool{11: true}}, txn: appCall, match: true},
+		{name: "app mismatch", criteria: Criteria{AppIDs: map[uint64]bool{9: true}}, txn: pay},
+		{name: "min amount", criteria: Criteria{MinAmount: 100}, txn: pay, match: true},
+		{name: "below min amount", criteria: Criteria{MinAmount: 101}, txn: pay},
+		{name: "min amount without amount", criteria: Criteria{MinAmount: 1}, txn: appCreate},
+		{
+			name:     "all criteria",
+			criteria: Criteria{Senders: map[sdk.Address]bool{addr1: true}, Types: map[sdk.TxType]bool{sdk.PaymentTx: true}, MinAmount: 50},
+			txn:      pay,
+			match:    true,
+		},
+		{
+			name:     "one criterion fails",
+			criteria: Criteria{Senders: map[sdk.Address]bool{addr1: true}, Types: map[sdk.TxType]bool{sdk.AssetTransferTx: true}},
+			txn:      pay,
+		},
+		{name: "inner not searched", criteria: Criteria{AssetIDs: map[uint64]bool{7: true}}, txn: appCall},
+		{name: "inner", criteria: Criteria{AssetIDs: map[uint64]bool{7: true}, SearchInner: true}, txn: appCall, match: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := tt.criteria.Matches(&tt.txn)
+			require.NoError(t, err)
+			assert.Equal(t, tt.match, match)
+		})
+	}
+}
@@ -55,19 +55,25 @@ func (f Filter) matches(txn *sdk.SignedTxnWithAD) (bool, error) {
 
 // SearchAndFilter searches through the block data and applies the operation to the results
 func (f Filter) SearchAndFilter(payset []sdk.SignedTxnInBlock) ([]sdk.SignedTxnInBlock, error) {
+	return FilterPayset(payset, f.OmitGroup, f.matches)
+}
+
+// FilterPayset returns the transactions of payset which match. Unless omitGroup
+// is set, the whole group of a matching transaction is returned.
+func FilterPayset(payset []sdk.SignedTxnInBlock, omitGroup bool, match func(*sdk.SignedTxnWithAD) (bool, error)) ([]sdk.SignedTxnInBlock, error) {
 	var result []sdk.SignedTxnInBlock
 	firstGroupIdx := 0
 	for i := 0; i < len(payset); i++ {
 		if payset[firstGroupIdx].Txn.Group != payset[i].Txn.Group {
 			firstGroupIdx = i
 		}
-		match, err := f.matches(&payset[i].SignedTxnWithAD)
+		matched, err := match(&payset[i].SignedTxnWithAD)
 		if err != nil {
 			return nil, err
 		}
-		if match {
+		if matched {
 			// if txn.Group is set and omit group is false
-			if payset[i].Txn.Group != (sdk.Digest{}) && !f.OmitGroup {
+			if payset[i].Txn.Group != (sdk.Digest{}) && !omitGroup {
 				j := firstGroupIdx
 				// append all txns with same group ID
 				for ; j < len(payset) && payset[j].Txn.Group == payset[firstGroupIdx].Txn.Group; j++ {
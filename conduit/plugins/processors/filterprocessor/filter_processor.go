@@ -26,11 +26,13 @@ func init() {
 	processors.Register(PluginName, processors.ProcessorConstructorFunc(func() processors.Processor {
 		return &FilterProcessor{}
 	}))
+	plugins.RegisterConfigSchema(plugins.Processor, PluginName, Config{})
 }
 
 // FilterProcessor filters transactions by a variety of means
 type FilterProcessor struct {
 	FieldFilters []fields.Filter
+	Criteria     *fields.Criteria
 
 	logger *log.Logger
 	cfg    Config
@@ -66,6 +68,16 @@ func (a *FilterProcessor) Init(ctx context.Context, _ data.InitProvider, cfg plu
 		return fmt.Errorf("filter processor init error: %w", err)
 	}
 
+	if m := a.cfg.Match; m != nil {
+		criteria, err := fields.MakeCriteria(m.Senders, m.Receivers, m.Addresses, m.TxTypes, m.AppIDs, m.AssetIDs, m.MinAmount, a.cfg.SearchInner)
+		if err != nil {
+			return fmt.Errorf("filter processor Init(): invalid match: %w", err)
+		}
+		if !criteria.Empty() {
+			a.Criteria = criteria
+		}
+	}
+
 	// configMaps is the "- any: ...." portion of the filter config
 	for _, configMaps := range a.cfg.Filters {
 
@@ -123,10 +135,17 @@ func (a *FilterProcessor) Close() error {
 	return nil
 }
 
-// Process processes the input data
+// Process removes the transactions which do not match from the payset. The
+// block header, certificate and state delta are not modified.
 func (a *FilterProcessor) Process(input data.BlockData) (data.BlockData, error) {
 	var err error
 	payset := input.Payset
+	if a.Criteria != nil {
+		payset, err = fields.FilterPayset(payset, a.cfg.OmitGroupTransactions, a.Criteria.Matches)
+		if err != nil {
+			return data.BlockData{}, err
+		}
+	}
 	for _, searcher := range a.FieldFilters {
 		payset, err = searcher.SearchAndFilter(payset)
 		if err != nil {
@@ -8,6 +8,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 
 	"github.com/algorand/conduit/conduit"
 	"github.com/algorand/conduit/conduit/data"
@@ -1490,3 +1491,47 @@ filters:
 		assert.Equal(t, bd.Payset[4], output.Payset[0])
 	}
 }
+
+func TestFilterProcessor_Match(t *testing.T) {
+	sampleAddr1 := sdk.Address{1}
+	sampleAddr2 := sdk.Address{2}
+	cfg := Config{
+		SearchInner: true,
+		Match: &MatchConfig{
+			Addresses: []string{sampleAddr1.String()},
+			TxTypes:   []string{"pay"},
+		},
+	}
+	cfgStr, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+
+	fp := FilterProcessor{}
+	err = fp.Init(context.Background(), &conduit.PipelineInitProvider{}, plugins.MakePluginConfig(string(cfgStr)), logrus.New())
+	require.NoError(t, err)
+	require.NotNil(t, fp.Criteria)
+
+	bd := testBlock(4)
+	bd.BlockHeader.Round = 10
+	bd.Payset[0].Txn.Type = sdk.PaymentTx
+	bd.Payset[0].Txn.Sender = sampleAddr2
+	bd.Payset[1].Txn.Type = sdk.PaymentTx
+	bd.Payset[1].Txn.Receiver = sampleAddr1
+	bd.Payset[2].Txn.Type = sdk.AssetTransferTx
+	bd.Payset[2].Txn.AssetReceiver = sampleAddr1
+	bd.Payset[3].Txn.Type = sdk.ApplicationCallTx
+	bd.Payset[3].EvalDelta.InnerTxns = []sdk.SignedTxnWithAD{bd.Payset[1].SignedTxnWithAD}
+
+	output, err := fp.Process(bd)
+	require.NoError(t, err)
+	assert.Equal(t, []sdk.SignedTxnInBlock{bd.Payset[1], bd.Payset[3]}, output.Payset)
+	assert.Equal(t, bd.BlockHeader, output.BlockHeader)
+}
+
+func TestFilterProcessor_MatchInvalid(t *testing.T) {
+	cfgStr, err := yaml.Marshal(Config{Match: &MatchConfig{TxTypes: []string{"transfer"}}})
+	require.NoError(t, err)
+
+	fp := FilterProcessor{}
+	err = fp.Init(context.Background(), &conduit.PipelineInitProvider{}, plugins.MakePluginConfig(string(cfgStr)), logrus.New())
+	require.ErrorContains(t, err, "invalid match: MakeCriteria(): unknown transaction type: transfer")
+}
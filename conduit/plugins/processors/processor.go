@@ -30,3 +30,23 @@ type Processor interface {
 	// Process will be called with provided optional inputs.  It is up to the plugin to check that required inputs are provided.
 	Process(input data.BlockData) (data.BlockData, error)
 }
+
+// WindowProcessor is for processors which compute over a sliding window of
+// rounds, such as rolling statistics. The pipeline keeps the blocks of the
+// window, in memory and in the data directory so that the window is restored
+// after a restart.
+type WindowProcessor interface {
+	Processor
+
+	// WindowSize returns the number of rounds of the window, including the
+	// round being processed. It is called once after Init.
+	WindowSize() uint64
+
+	// ProcessWindow is called instead of Process with the blocks which the
+	// processor received for the rounds of the window, oldest first. The last
+	// block is the round being processed and is replaced by the returned
+	// block. Rounds which were skipped, or which precede the first processed
+	// round, are missing from the window. The earlier blocks must not be
+	// modified.
+	ProcessWindow(window []data.BlockData) (data.BlockData, error)
+}
@@ -0,0 +1,182 @@
+package plugins
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configSchemas are the typed config structs registered by plugins, by plugin
+// type and name.
+var configSchemas = make(map[PluginType]map[string]reflect.Type)
+
+var (
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	// yamlLinePrefix is removed from decode errors, the line refers to the
+	// re-encoded value rather than the config file.
+	yamlLinePrefix = regexp.MustCompile(`^line \d+: `)
+)
+
+// RegisterConfigSchema registers the typed config struct of a plugin, which is
+// usually the struct its Init unmarshals the config into. The pipeline then
+// rejects configs with unknown keys or values of the wrong type before the
+// plugin is created. Plugins are expected to call it from init().
+func RegisterConfigSchema(pluginType PluginType, name string, schema interface{}) {
+	if configSchemas[pluginType] == nil {
+		configSchemas[pluginType] = make(map[string]reflect.Type)
+	}
+	configSchemas[pluginType][name] = reflect.TypeOf(schema)
+}
+
+// ValidateConfigSchema checks a plugin config against the schema registered
+// for the plugin, errors are qualified with the path of the offending key
+// starting at path. Plugins without a schema are not checked.
+func ValidateConfigSchema(pluginType PluginType, name string, path string, config map[string]interface{}) error {
+	schema, ok := configSchemas[pluginType][name]
+	if !ok || config == nil {
+		return nil
+	}
+	return checkValue(path, config, schema)
+}
+
+// checkValue walks value, as decoded from yaml, alongside the type it is
+// unmarshalled into.
+func checkValue(path string, value interface{}, t reflect.Type) error {
+	if value == nil {
+		return nil
+	}
+	if reflect.PtrTo(t).Implements(yamlUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return decodeValue(path, value, t)
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return checkValue(path, value, t.Elem())
+	case reflect.Struct:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return decodeValue(path, value, t)
+		}
+		fields, anyKey := yamlFields(t)
+		for _, key := range sortedKeys(m) {
+			field, ok := fields[key]
+			if !ok {
+				if anyKey {
+					continue
+				}
+				return fmt.Errorf("%s: unknown key", joinPath(path, key))
+			}
+			if err := checkValue(joinPath(path, key), m[key], field); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			return decodeValue(path, value, t)
+		}
+		for i, item := range items {
+			if err := checkValue(fmt.Sprintf("%s[%d]", path, i), item, t.Elem()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		m, ok := value.(map[string]interface{})
+		if !ok || t.Key().Kind() != reflect.String {
+			return decodeValue(path, value, t)
+		}
+		for _, key := range sortedKeys(m) {
+			if err := checkValue(joinPath(path, key), m[key], t.Elem()); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return decodeValue(path, value, t)
+	}
+}
+
+// decodeValue checks that value can be unmarshalled into a t.
+func decodeValue(path string, value interface{}, t reflect.Type) error {
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	err = yaml.Unmarshal(b, reflect.New(t).Interface())
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		return fmt.Errorf("%s: %s", path, yamlLinePrefix.ReplaceAllString(typeErr.Errors[0], ""))
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// yamlFields returns the types of the struct fields by yaml key, anyKey is
+// true if the struct has an inline map which accepts any other key.
+func yamlFields(t reflect.Type) (fields map[string]reflect.Type, anyKey bool) {
+	fields = make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		name := tag
+		var opts string
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") {
+			inline := f.Type
+			if inline.Kind() == reflect.Ptr {
+				inline = inline.Elem()
+			}
+			if inline.Kind() == reflect.Map {
+				anyKey = true
+				continue
+			}
+			inlineFields, inlineAny := yamlFields(inline)
+			for key, fieldType := range inlineFields {
+				fields[key] = fieldType
+			}
+			anyKey = anyKey || inlineAny
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields, anyKey
+}
+
+// joinPath returns the path of a key, the keys at the top level have no
+// prefix.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
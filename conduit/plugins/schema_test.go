@@ -0,0 +1,118 @@
+package plugins
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testTLS struct {
+	Enabled bool   `yaml:"enabled"`
+	CAFile  string `yaml:"ca-file"`
+}
+
+type testFilter struct {
+	Tag        string `yaml:"tag"`
+	Expression string `yaml:"expression"`
+}
+
+type testCommon struct {
+	Name string `yaml:"name"`
+}
+
+type testConfig struct {
+	testCommon `yaml:",inline"`
+	Brokers    []string                `yaml:"brokers"`
+	Timeout    time.Duration           `yaml:"timeout"`
+	MaxConn    uint32                  `yaml:"max-conn"`
+	TLS        testTLS                 `yaml:"tls"`
+	Match      *testTLS                `yaml:"match"`
+	Filters    []map[string]testFilter `yaml:"filters"`
+	Ignored    string                  `yaml:"-"`
+}
+
+func TestValidateConfigSchema(t *testing.T) {
+	RegisterConfigSchema(Exporter, "schema_test", testConfig{})
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		errMsg string
+	}{
+		{name: "empty"},
+		{
+			name: "valid",
+			config: map[string]interface{}{
+				"name":     "test",
+				"brokers":  []interface{}{"localhost:9092"},
+				"timeout":  "30s",
+				"max-conn": 20,
+				"tls":      map[string]interface{}{"enabled": true},
+				"match":    map[string]interface{}{"ca-file": "ca.pem"},
+				"filters":  []interface{}{map[string]interface{}{"any": map[string]interface{}{"tag": "txn.rcv"}}},
+			},
+		},
+		{
+			name:   "unknown key",
+			config: map[string]interface{}{"broker": "localhost:9092"},
+			errMsg: "exporters[0].config.broker: unknown key",
+		},
+		{
+			name:   "unknown nested key",
+			config: map[string]interface{}{"tls": map[string]interface{}{"enable": true}},
+			errMsg: "exporters[0].config.tls.enable: unknown key",
+		},
+		{
+			name:   "unknown key in a list",
+			config: map[string]interface{}{"filters": []interface{}{map[string]interface{}{"any": map[string]interface{}{"tagg": "txn.rcv"}}}},
+			errMsg: "exporters[0].config.filters[0].any.tagg: unknown key",
+		},
+		{
+			name:   "ignored key",
+			config: map[string]interface{}{"ignored": "value"},
+			errMsg: "exporters[0].config.ignored: unknown key",
+		},
+		{
+			name:   "wrong type",
+			config: map[string]interface{}{"max-conn": "twenty"},
+			errMsg: "exporters[0].config.max-conn: cannot unmarshal !!str `twenty` into uint32",
+		},
+		{
+			name:   "wrong duration",
+			config: map[string]interface{}{"timeout": "soon"},
+			errMsg: "exporters[0].config.timeout: cannot unmarshal !!str `soon` into time.Duration",
+		},
+		{
+			name:   "scalar instead of a map",
+			config: map[string]interface{}{"tls": true},
+			errMsg: "exporters[0].config.tls: cannot unmarshal !!bool `true` into plugins.testTLS",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateConfigSchema(Exporter, "schema_test", "exporters[0].config", tc.config)
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.errMsg)
+			}
+		})
+	}
+}
+
+func TestValidateConfigSchemaUnregistered(t *testing.T) {
+	assert.NoError(t, ValidateConfigSchema(Importer, "unregistered", "importer.config", map[string]interface{}{"any": 1}))
+}
+
+func TestPluginConfigDecode(t *testing.T) {
+	var cfg testTLS
+	require.NoError(t, MakePluginConfig("").Decode(&cfg))
+	require.NoError(t, MakePluginConfig("enabled: true\n").Decode(&cfg))
+	assert.True(t, cfg.Enabled)
+
+	err := MakePluginConfig("enable: true\n").Decode(&cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "field enable not found")
+}
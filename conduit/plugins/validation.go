@@ -0,0 +1,113 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is a config error of a single field, Path is the yaml key path of
+// the field, e.g. "tls.cert-file" or "brokers[1]".
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// FieldErrors are the config errors of a plugin, in the order of the fields.
+type FieldErrors []*FieldError
+
+func (errs FieldErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Addf adds an error of the field at path.
+func (errs *FieldErrors) Addf(path string, format string, args ...interface{}) {
+	*errs = append(*errs, &FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Merge adds the errors of a nested config, their paths are prefixed with
+// the path of the nested config. Other errors are added as the error of path.
+func (errs *FieldErrors) Merge(path string, err error) {
+	if err == nil {
+		return
+	}
+	var nested FieldErrors
+	if !errors.As(err, &nested) {
+		errs.Addf(path, "%v", err)
+		return
+	}
+	for _, ferr := range nested {
+		nestedPath := path
+		switch {
+		case path == "":
+			nestedPath = ferr.Path
+		case strings.HasPrefix(ferr.Path, "["):
+			nestedPath += ferr.Path
+		default:
+			nestedPath += "." + ferr.Path
+		}
+		*errs = append(*errs, &FieldError{Path: nestedPath, Message: ferr.Message})
+	}
+}
+
+// Err returns the errors, or nil if there are none.
+func (errs FieldErrors) Err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Defaulter is implemented by configs with defaults, SetDefaults fills
+// in the fields which are not set.
+type Defaulter interface {
+	SetDefaults()
+}
+
+// Validator is implemented by configs which validate their fields,
+// Validate is expected to return FieldErrors.
+type Validator interface {
+	Validate() error
+}
+
+// DecodeAndValidate unmarshals the plugin config into config, which must be
+// a pointer to a struct, then fills in its defaults and validates it when
+// config implements Defaulter and Validator. The configs
+// generated by conduit-configgen implement both. Values of the wrong type
+// are reported with the path of their key, like the errors of Validate.
+func (pc PluginConfig) DecodeAndValidate(config interface{}) error {
+	t := reflect.TypeOf(config)
+	if t.Kind() != reflect.Ptr {
+		return fmt.Errorf("DecodeAndValidate(): config must be a pointer, not %s", t)
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal([]byte(pc.Config), &raw); err != nil {
+		return err
+	}
+	if raw != nil {
+		if err := checkValue("", raw, t.Elem()); err != nil {
+			return err
+		}
+	}
+	if err := pc.UnmarshalConfig(config); err != nil {
+		return err
+	}
+	if defaulter, ok := config.(Defaulter); ok {
+		defaulter.SetDefaults()
+	}
+	if validator, ok := config.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package plugins
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type validatedTLS struct {
+	CertFile string `yaml:"cert-file"`
+}
+
+func (cfg *validatedTLS) Validate() error {
+	var errs FieldErrors
+	if cfg.CertFile == "" {
+		errs.Addf("cert-file", "is required")
+	}
+	return errs.Err()
+}
+
+type validatedConfig struct {
+	Mode    string         `yaml:"mode"`
+	TLS     validatedTLS   `yaml:"tls"`
+	Brokers []validatedTLS `yaml:"brokers"`
+}
+
+func (cfg *validatedConfig) SetDefaults() {
+	if cfg.Mode == "" {
+		cfg.Mode = "fast"
+	}
+}
+
+func (cfg *validatedConfig) Validate() error {
+	var errs FieldErrors
+	if cfg.Mode != "fast" && cfg.Mode != "slow" {
+		errs.Addf("mode", "must be one of fast, slow")
+	}
+	errs.Merge("tls", cfg.TLS.Validate())
+	for i := range cfg.Brokers {
+		errs.Merge(fmt.Sprintf("brokers[%d]", i), cfg.Brokers[i].Validate())
+	}
+	return errs.Err()
+}
+
+func TestFieldErrorsMerge(t *testing.T) {
+	var nested FieldErrors
+	nested.Addf("cert-file", "is required")
+	nested.Addf("[0]", "must be %d", 1)
+
+	var errs FieldErrors
+	errs.Merge("tls", nested.Err())
+	errs.Merge("", nested.Err())
+	errs.Merge("other", fmt.Errorf("plain error"))
+	errs.Merge("none", nil)
+	assert.EqualError(t, errs.Err(), "tls.cert-file: is required; tls[0]: must be 1; cert-file: is required; [0]: must be 1; other: plain error")
+	assert.Nil(t, FieldErrors(nil).Err())
+}
+
+func TestDecodeAndValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  validatedConfig
+		mode string
+		err  string
+	}{
+		{"defaults", validatedConfig{TLS: validatedTLS{CertFile: "cert.pem"}}, "fast", ""},
+		{"mode", validatedConfig{Mode: "slow", TLS: validatedTLS{CertFile: "cert.pem"}}, "slow", ""},
+		{"field errors", validatedConfig{Mode: "medium", Brokers: []validatedTLS{{CertFile: "a.pem"}, {}}}, "", "mode: must be one of fast, slow; tls.cert-file: is required; brokers[1].cert-file: is required"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfgStr, err := yaml.Marshal(tc.cfg)
+			require.NoError(t, err)
+			var cfg validatedConfig
+			err = MakePluginConfig(string(cfgStr)).DecodeAndValidate(&cfg)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				var errs FieldErrors
+				require.ErrorAs(t, err, &errs)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.mode, cfg.Mode)
+		})
+	}
+}
+
+func TestDecodeAndValidateTypes(t *testing.T) {
+	var cfg validatedConfig
+	err := MakePluginConfig("mode: fast\ntls: true\n").DecodeAndValidate(&cfg)
+	assert.EqualError(t, err, "tls: cannot unmarshal !!bool `true` into plugins.validatedTLS")
+
+	err = MakePluginConfig("mode: fast\ntimeout: 1s\n").DecodeAndValidate(&cfg)
+	assert.EqualError(t, err, "timeout: unknown key")
+
+	err = MakePluginConfig("").DecodeAndValidate(cfg)
+	assert.EqualError(t, err, "DecodeAndValidate(): config must be a pointer, not plugins.validatedConfig")
+}
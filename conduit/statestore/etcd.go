@@ -0,0 +1,58 @@
+package statestore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// etcdDialTimeout bounds the connection to the etcd cluster.
+const etcdDialTimeout = 10 * time.Second
+
+// etcdStore saves the state in an etcd key.
+type etcdStore struct {
+	key    string
+	client *clientv3.Client
+}
+
+func makeEtcdStore(cfg Config) (*etcdStore, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   cfg.Endpoints,
+		Username:    cfg.Username,
+		Password:    cfg.Password,
+		DialTimeout: etcdDialTimeout,
+		// The errors are returned to the pipeline, which logs them.
+		Logger: zap.NewNop(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("makeEtcdStore(): unable to create the etcd client: %w", err)
+	}
+	return &etcdStore{key: cfg.Key, client: client}, nil
+}
+
+// Load returns the value of the key.
+func (s *etcdStore) Load(ctx context.Context) ([]byte, error) {
+	resp, err := s.client.Get(ctx, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("Load(): %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+// Save puts the state in the key.
+func (s *etcdStore) Save(ctx context.Context, state []byte) error {
+	if _, err := s.client.Put(ctx, s.key, string(state)); err != nil {
+		return fmt.Errorf("Save(): %w", err)
+	}
+	return nil
+}
+
+func (s *etcdStore) Close() error {
+	return s.client.Close()
+}
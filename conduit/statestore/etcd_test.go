@@ -0,0 +1,133 @@
+package statestore
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeEtcd implements the parts of the etcd v3 gRPC API used by etcdStore.
+type fakeEtcd struct {
+	etcdserverpb.UnimplementedKVServer
+	etcdserverpb.UnimplementedAuthServer
+
+	mu       sync.Mutex
+	kv       map[string][]byte
+	password string
+	token    string
+	auths    int
+}
+
+// startFakeEtcd serves fake on a local port and returns its endpoint.
+func startFakeEtcd(t *testing.T, fake *fakeEtcd) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	etcdserverpb.RegisterKVServer(server, fake)
+	etcdserverpb.RegisterAuthServer(server, fake)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func (f *fakeEtcd) Authenticate(_ context.Context, req *etcdserverpb.AuthenticateRequest) (*etcdserverpb.AuthenticateResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if req.Password != f.password {
+		return nil, rpctypes.ErrGRPCAuthFailed
+	}
+	f.auths++
+	return &etcdserverpb.AuthenticateResponse{Header: &etcdserverpb.ResponseHeader{}, Token: f.token}, nil
+}
+
+// authorized checks the token of the request, f.mu is held.
+func (f *fakeEtcd) authorized(ctx context.Context) error {
+	if f.password == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if tokens := md.Get(rpctypes.TokenFieldNameGRPC); len(tokens) == 0 || tokens[0] != f.token {
+		return rpctypes.ErrGRPCInvalidAuthToken
+	}
+	return nil
+}
+
+func (f *fakeEtcd) Range(ctx context.Context, req *etcdserverpb.RangeRequest) (*etcdserverpb.RangeResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.authorized(ctx); err != nil {
+		return nil, err
+	}
+	resp := &etcdserverpb.RangeResponse{Header: &etcdserverpb.ResponseHeader{}}
+	if value, ok := f.kv[string(req.Key)]; ok {
+		resp.Kvs = []*mvccpb.KeyValue{{Key: req.Key, Value: value}}
+		resp.Count = 1
+	}
+	return resp, nil
+}
+
+func (f *fakeEtcd) Put(ctx context.Context, req *etcdserverpb.PutRequest) (*etcdserverpb.PutResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.authorized(ctx); err != nil {
+		return nil, err
+	}
+	f.kv[string(req.Key)] = req.Value
+	return &etcdserverpb.PutResponse{Header: &etcdserverpb.ResponseHeader{}}, nil
+}
+
+func TestEtcdStore(t *testing.T) {
+	fake := &fakeEtcd{kv: make(map[string][]byte)}
+	endpoint := startFakeEtcd(t, fake)
+
+	ctx := context.Background()
+	store, err := makeEtcdStore(Config{Key: "pipeline", Endpoints: []string{endpoint}})
+	require.NoError(t, err)
+	defer store.Close()
+
+	state, err := store.Load(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	require.NoError(t, store.Save(ctx, []byte(`{"next-round":5}`)))
+	state, err = store.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `{"next-round":5}`, string(state))
+	assert.Equal(t, `{"next-round":5}`, string(fake.kv["pipeline"]))
+}
+
+func TestEtcdStoreAuth(t *testing.T) {
+	fake := &fakeEtcd{kv: make(map[string][]byte), password: "secret", token: "token1"}
+	endpoint := startFakeEtcd(t, fake)
+
+	ctx := context.Background()
+	store, err := makeEtcdStore(Config{Key: "pipeline", Endpoints: []string{endpoint}, Username: "root", Password: "secret"})
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Save(ctx, []byte("{}")))
+	_, err = store.Load(ctx)
+	require.NoError(t, err)
+	fake.mu.Lock()
+	assert.Equal(t, 1, fake.auths)
+	// the token expired, the client authenticates again
+	fake.token = "token2"
+	fake.mu.Unlock()
+	_, err = store.Load(ctx)
+	require.NoError(t, err)
+	fake.mu.Lock()
+	assert.Equal(t, 2, fake.auths)
+	fake.mu.Unlock()
+
+	// the client authenticates when it is created
+	_, err = makeEtcdStore(Config{Key: "pipeline", Endpoints: []string{endpoint}, Username: "root", Password: "wrong"})
+	assert.ErrorContains(t, err, "makeEtcdStore(): unable to create the etcd client: etcdserver: authentication failed")
+}
@@ -0,0 +1,56 @@
+package statestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+)
+
+// MetadataPath returns the location of the file state store in the data directory.
+func MetadataPath(dataDir string) string {
+	return path.Join(dataDir, "metadata.json")
+}
+
+// fileStore saves the state in a local file. The file is replaced atomically
+// so that a crash never leaves a partially written state.
+type fileStore struct {
+	path string
+}
+
+// MakeFileStore creates a state store which saves the state in filename.
+func MakeFileStore(filename string) StateStore {
+	return &fileStore{path: filename}
+}
+
+// Load returns the file contents. A missing or empty file has no state.
+func (s *fileStore) Load(context.Context) ([]byte, error) {
+	state, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Load(): error reading metadata: %w", err)
+	}
+	if len(state) == 0 {
+		return nil, nil
+	}
+	return state, nil
+}
+
+// Save writes the state to a temporary file and renames it over the file.
+func (s *fileStore) Save(_ context.Context, state []byte) error {
+	tempFilename := fmt.Sprintf("%s.temp", s.path)
+	if err := os.WriteFile(tempFilename, state, 0644); err != nil {
+		return fmt.Errorf("Save(): failed to write temp metadata: %w", err)
+	}
+	if err := os.Rename(tempFilename, s.path); err != nil {
+		return fmt.Errorf("Save(): failed to replace metadata file: %w", err)
+	}
+	return nil
+}
+
+func (s *fileStore) Close() error {
+	return nil
+}
@@ -0,0 +1,48 @@
+package statestore
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	filename := MetadataPath(t.TempDir())
+	store := MakeFileStore(filename)
+
+	// missing file
+	state, err := store.Load(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	// empty file
+	require.NoError(t, os.WriteFile(filename, nil, 0644))
+	state, err = store.Load(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	require.NoError(t, store.Save(ctx, []byte(`{"next-round":5}`)))
+	state, err = store.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `{"next-round":5}`, string(state))
+	_, err = os.Stat(filename + ".temp")
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, store.Close())
+}
+
+func TestFileStoreErrors(t *testing.T) {
+	store := MakeFileStore(path.Join(t.TempDir(), "missing", "metadata.json"))
+	err := store.Save(context.Background(), []byte("{}"))
+	assert.ErrorContains(t, err, "Save(): failed to write temp metadata")
+
+	// a directory cannot be read
+	store = MakeFileStore(t.TempDir())
+	_, err = store.Load(context.Background())
+	assert.ErrorContains(t, err, "Load(): error reading metadata")
+}
@@ -0,0 +1,68 @@
+package statestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// postgresStore saves the state in a row of a postgres table, keyed by the
+// configured key. The table can be in the exporter's database.
+type postgresStore struct {
+	mu    sync.Mutex
+	conn  *pgx.Conn
+	table string
+	key   string
+}
+
+func makePostgresStore(ctx context.Context, cfg Config) (*postgresStore, error) {
+	conn, err := pgx.Connect(ctx, cfg.ConnectionString)
+	if err != nil {
+		return nil, fmt.Errorf("makePostgresStore(): unable to connect: %w", err)
+	}
+	s := &postgresStore{
+		conn:  conn,
+		table: pgx.Identifier{cfg.Table}.Sanitize(),
+		key:   cfg.Key,
+	}
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (key text PRIMARY KEY, state jsonb NOT NULL, updated timestamptz NOT NULL DEFAULT now())", s.table)
+	if _, err = conn.Exec(ctx, query); err != nil {
+		conn.Close(ctx)
+		return nil, fmt.Errorf("makePostgresStore(): unable to create table %s: %w", s.table, err)
+	}
+	return s, nil
+}
+
+// Load returns the state in the row of the key.
+func (s *postgresStore) Load(ctx context.Context) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var state []byte
+	query := fmt.Sprintf("SELECT state FROM %s WHERE key = $1", s.table)
+	err := s.conn.QueryRow(ctx, query, s.key).Scan(&state)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Load(): %w", err)
+	}
+	return state, nil
+}
+
+// Save inserts or replaces the row of the key.
+func (s *postgresStore) Save(ctx context.Context, state []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	query := fmt.Sprintf("INSERT INTO %s (key, state) VALUES ($1, $2) ON CONFLICT (key) DO UPDATE SET state = EXCLUDED.state, updated = now()", s.table)
+	if _, err := s.conn.Exec(ctx, query, s.key, state); err != nil {
+		return fmt.Errorf("Save(): %w", err)
+	}
+	return nil
+}
+
+func (s *postgresStore) Close() error {
+	return s.conn.Close(context.Background())
+}
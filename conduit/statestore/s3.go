@@ -0,0 +1,78 @@
+package statestore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// DefaultS3Endpoint is the endpoint of AWS S3.
+const DefaultS3Endpoint = "https://s3.amazonaws.com"
+
+// s3Store saves the state in an object of an S3 bucket, named by the key.
+type s3Store struct {
+	client *minio.Client
+	bucket string
+	key    string
+}
+
+func makeS3Store(cfg Config) (*s3Store, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("makeS3Store(): invalid endpoint: %w", err)
+	}
+	// Without static keys the credentials are read from the AWS environment
+	// variables, the shared credentials file or the instance role.
+	creds := credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvAWS{},
+		&credentials.FileAWSCredentials{},
+		&credentials.IAM{},
+	})
+	if cfg.AccessKeyID != "" {
+		creds = credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+	}
+	client, err := minio.New(endpoint.Host, &minio.Options{
+		Creds:  creds,
+		Secure: endpoint.Scheme != "http",
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("makeS3Store(): unable to create the S3 client: %w", err)
+	}
+	return &s3Store{client: client, bucket: cfg.Bucket, key: cfg.Key}, nil
+}
+
+// Load returns the content of the object, nil if it does not exist.
+func (s *s3Store) Load(ctx context.Context) ([]byte, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, s.key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("Load(): %w", err)
+	}
+	defer obj.Close()
+	state, err := io.ReadAll(obj)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Load(): unable to read s3://%s/%s: %w", s.bucket, s.key, err)
+	}
+	return state, nil
+}
+
+// Save replaces the object.
+func (s *s3Store) Save(ctx context.Context, state []byte) error {
+	opts := minio.PutObjectOptions{ContentType: "application/json"}
+	if _, err := s.client.PutObject(ctx, s.bucket, s.key, bytes.NewReader(state), int64(len(state)), opts); err != nil {
+		return fmt.Errorf("Save(): unable to write s3://%s/%s: %w", s.bucket, s.key, err)
+	}
+	return nil
+}
+
+func (s *s3Store) Close() error {
+	return nil
+}
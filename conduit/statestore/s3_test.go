@@ -0,0 +1,123 @@
+package statestore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 implements the object GET and PUT requests of the S3 API, with path
+// style bucket addressing.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	auth    []string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	switch r.Method {
+	case http.MethodGet:
+		object, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			return
+		}
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("ETag", `"etag"`)
+		_, _ = w.Write(object)
+	case http.MethodPut:
+		object, err := io.ReadAll(r.Body)
+		if err == nil && r.Header.Get("X-Amz-Content-Sha256") == "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+			object, err = decodeChunks(object)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.objects[r.URL.Path] = object
+		w.Header().Set("ETag", `"etag"`)
+	default:
+		http.Error(w, "unsupported", http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeChunks returns the payload of an aws-chunked body, which the client
+// sends over plain http. The chunk signatures are not verified.
+func decodeChunks(body []byte) ([]byte, error) {
+	var payload []byte
+	for {
+		line, rest, ok := bytes.Cut(body, []byte("\r\n"))
+		if !ok {
+			return nil, fmt.Errorf("truncated chunk header")
+		}
+		size, _, _ := bytes.Cut(line, []byte(";"))
+		n, err := strconv.ParseUint(string(size), 16, 32)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return payload, nil
+		}
+		if uint64(len(rest)) < n+2 {
+			return nil, fmt.Errorf("truncated chunk")
+		}
+		payload = append(payload, rest[:n]...)
+		body = rest[n+2:]
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx := context.Background()
+	store, err := makeS3Store(Config{Key: "pipeline", Bucket: "state", Region: "us-east-1", Endpoint: server.URL, AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	require.NoError(t, err)
+	defer store.Close()
+
+	state, err := store.Load(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	require.NoError(t, store.Save(ctx, []byte(`{"next-round":5}`)))
+	state, err = store.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `{"next-round":5}`, string(state))
+	assert.Equal(t, `{"next-round":5}`, string(fake.objects["/state/pipeline"]))
+	for _, auth := range fake.auth {
+		assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/"), auth)
+	}
+}
+
+func TestS3StoreError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+	}))
+	defer server.Close()
+
+	store, err := makeS3Store(Config{Key: "pipeline", Bucket: "state", Region: "us-east-1", Endpoint: server.URL, AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	require.NoError(t, err)
+	_, err = store.Load(context.Background())
+	assert.ErrorContains(t, err, "Load(): unable to read s3://state/pipeline: Access Denied")
+	err = store.Save(context.Background(), []byte("{}"))
+	assert.ErrorContains(t, err, "Save(): unable to write s3://state/pipeline: Access Denied")
+}
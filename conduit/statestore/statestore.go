@@ -0,0 +1,122 @@
+package statestore
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+const (
+	// FileType saves the state in metadata.json in the data directory.
+	FileType = "file"
+	// PostgresType saves the state in a postgres table.
+	PostgresType = "postgres"
+	// EtcdType saves the state in an etcd key.
+	EtcdType = "etcd"
+	// S3Type saves the state in an S3 object.
+	S3Type = "s3"
+
+	// DefaultKey identifies the pipeline state in shared backends.
+	DefaultKey = "conduit"
+	// DefaultTable is the postgres table which holds the state.
+	DefaultTable = "conduit_state"
+)
+
+// StateStore persists the pipeline state, which is encoded by the pipeline.
+type StateStore interface {
+	// Load returns the saved state, or nil if no state was saved yet.
+	Load(ctx context.Context) ([]byte, error)
+	// Save replaces the saved state.
+	Save(ctx context.Context, state []byte) error
+	// Close releases the resources of the store.
+	Close() error
+}
+
+// Config selects and configures the state store backend.
+type Config struct {
+	// Type is "file" (default), "postgres", "etcd" or "s3".
+	Type string `yaml:"type"`
+	// Key identifies the pipeline state, so that several pipelines can share
+	// a postgres table, an etcd cluster or an S3 bucket, where it is the
+	// object name. Defaults to "conduit".
+	Key string `yaml:"key"`
+
+	// ConnectionString is the postgres connection string.
+	ConnectionString string `yaml:"connection-string"`
+	// Table is the postgres table, it is created if it does not exist.
+	// Defaults to "conduit_state".
+	Table string `yaml:"table"`
+
+	// Endpoints are the etcd client URLs, such as http://localhost:2379.
+	Endpoints []string `yaml:"endpoints"`
+	// Username and Password authenticate with etcd when set.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+
+	// Bucket is the S3 bucket.
+	Bucket string `yaml:"bucket"`
+	// Region is the region of the bucket, it is looked up when empty.
+	Region string `yaml:"region"`
+	// Endpoint is the URL of the S3 API, set it for S3 compatible stores.
+	// Defaults to AWS S3.
+	Endpoint string `yaml:"endpoint"`
+	// AccessKeyID and SecretAccessKey authenticate with S3. Without them the
+	// AWS environment variables, shared credentials file or instance role
+	// are used.
+	AccessKeyID     string `yaml:"access-key-id"`
+	SecretAccessKey string `yaml:"secret-access-key"`
+}
+
+// Valid validates the state store config.
+func (cfg Config) Valid() error {
+	switch cfg.Type {
+	case "", FileType:
+	case PostgresType:
+		if cfg.ConnectionString == "" {
+			return fmt.Errorf("connection-string is required for the %s state store", PostgresType)
+		}
+	case EtcdType:
+		if len(cfg.Endpoints) == 0 {
+			return fmt.Errorf("endpoints are required for the %s state store", EtcdType)
+		}
+	case S3Type:
+		if cfg.Bucket == "" {
+			return fmt.Errorf("bucket is required for the %s state store", S3Type)
+		}
+		if cfg.Endpoint != "" {
+			if u, err := url.Parse(cfg.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("endpoint '%s' must be an http or https URL", cfg.Endpoint)
+			}
+		}
+	default:
+		return fmt.Errorf("type must be '%s', '%s', '%s' or '%s', found '%s'", FileType, PostgresType, EtcdType, S3Type, cfg.Type)
+	}
+	return nil
+}
+
+// New creates the configured state store. The file store saves the state in
+// the data directory.
+func New(ctx context.Context, cfg Config, dataDir string) (StateStore, error) {
+	if err := cfg.Valid(); err != nil {
+		return nil, fmt.Errorf("New(): %w", err)
+	}
+	if cfg.Key == "" {
+		cfg.Key = DefaultKey
+	}
+	switch cfg.Type {
+	case PostgresType:
+		if cfg.Table == "" {
+			cfg.Table = DefaultTable
+		}
+		return makePostgresStore(ctx, cfg)
+	case EtcdType:
+		return makeEtcdStore(cfg)
+	case S3Type:
+		if cfg.Endpoint == "" {
+			cfg.Endpoint = DefaultS3Endpoint
+		}
+		return makeS3Store(cfg)
+	default:
+		return MakeFileStore(MetadataPath(dataDir)), nil
+	}
+}
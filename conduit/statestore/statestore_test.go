@@ -0,0 +1,60 @@
+package statestore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		errMsg string
+	}{
+		{name: "default", cfg: Config{}},
+		{name: "file", cfg: Config{Type: FileType}},
+		{name: "postgres", cfg: Config{Type: PostgresType, ConnectionString: "host=localhost"}},
+		{name: "postgres missing connection", cfg: Config{Type: PostgresType}, errMsg: "connection-string is required for the postgres state store"},
+		{name: "etcd", cfg: Config{Type: EtcdType, Endpoints: []string{"http://localhost:2379"}}},
+		{name: "etcd missing endpoints", cfg: Config{Type: EtcdType}, errMsg: "endpoints are required for the etcd state store"},
+		{name: "s3", cfg: Config{Type: S3Type, Bucket: "conduit"}},
+		{name: "s3 endpoint", cfg: Config{Type: S3Type, Bucket: "conduit", Endpoint: "http://localhost:9000"}},
+		{name: "s3 invalid endpoint", cfg: Config{Type: S3Type, Bucket: "conduit", Endpoint: "localhost:9000"}, errMsg: "endpoint 'localhost:9000' must be an http or https URL"},
+		{name: "s3 missing bucket", cfg: Config{Type: S3Type}, errMsg: "bucket is required for the s3 state store"},
+		{name: "unknown", cfg: Config{Type: "consul"}, errMsg: "type must be 'file', 'postgres', 'etcd' or 's3', found 'consul'"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Valid()
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.errMsg)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := New(context.Background(), Config{}, dataDir)
+	require.NoError(t, err)
+	assert.Equal(t, &fileStore{path: MetadataPath(dataDir)}, store)
+
+	store, err = New(context.Background(), Config{Type: EtcdType, Endpoints: []string{"http://localhost:2379"}}, dataDir)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultKey, store.(*etcdStore).key)
+	require.NoError(t, store.Close())
+
+	store, err = New(context.Background(), Config{Type: S3Type, Bucket: "state"}, dataDir)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultKey, store.(*s3Store).key)
+	assert.Equal(t, "s3.amazonaws.com", store.(*s3Store).client.EndpointURL().Host)
+
+	_, err = New(context.Background(), Config{Type: "consul"}, dataDir)
+	assert.EqualError(t, err, "New(): type must be 'file', 'postgres', 'etcd' or 's3', found 'consul'")
+}
@@ -0,0 +1,155 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// The OTLP/HTTP JSON encoding of an ExportTraceServiceRequest. See
+// https://github.com/open-telemetry/opentelemetry-proto for the schema.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	// IntValue is an int64, which the JSON encoding represents as a string.
+	IntValue  *string `json:"intValue,omitempty"`
+	BoolValue *bool   `json:"boolValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	spanKindInternal = 1
+	statusCodeError  = 2
+	scopeName        = "github.com/algorand/conduit"
+)
+
+func makeKeyValues(attrs []Attribute) []otlpKeyValue {
+	result := make([]otlpKeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		var value otlpAnyValue
+		switch v := attr.Value.(type) {
+		case string:
+			value.StringValue = &v
+		case uint64:
+			i := strconv.FormatUint(v, 10)
+			value.IntValue = &i
+		case bool:
+			value.BoolValue = &v
+		default:
+			str := fmt.Sprintf("%v", v)
+			value.StringValue = &str
+		}
+		result = append(result, otlpKeyValue{Key: attr.Key, Value: value})
+	}
+	return result
+}
+
+func makeOTLPSpan(s *Span) otlpSpan {
+	span := otlpSpan{
+		TraceID:           fmt.Sprintf("%x", s.traceID),
+		SpanID:            fmt.Sprintf("%x", s.spanID),
+		Name:              s.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Attributes:        makeKeyValues(s.attributes),
+	}
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = fmt.Sprintf("%x", s.parentID)
+	}
+	if s.err != nil {
+		span.Status = otlpStatus{Code: statusCodeError, Message: s.err.Error()}
+	}
+	return span
+}
+
+// makeRequest encodes the spans as an export request.
+func (t *Tracer) makeRequest(spans []*Span) otlpRequest {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		otlpSpans = append(otlpSpans, makeOTLPSpan(s))
+	}
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: makeKeyValues([]Attribute{String("service.name", t.cfg.ServiceName)}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: scopeName},
+				Spans: otlpSpans,
+			}},
+		}},
+	}
+}
+
+// post sends the spans to the OTLP endpoint.
+func (t *Tracer) post(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(t.makeRequest(spans))
+	if err != nil {
+		return fmt.Errorf("post(): could not encode spans: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("post(): could not create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post(): %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("post(): unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	mrand "math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultServiceName is the service.name resource attribute when none is configured.
+	DefaultServiceName = "conduit"
+	// maxBatchSize is the number of ended spans which triggers an export.
+	maxBatchSize = 512
+	// maxQueueSize is the number of ended spans kept while the endpoint is
+	// unavailable, newer spans are dropped once it is reached.
+	maxQueueSize = 8192
+	// flushInterval is how often ended spans are exported.
+	flushInterval = 5 * time.Second
+)
+
+// Config configures a Tracer.
+type Config struct {
+	// Endpoint is the OTLP/HTTP traces URL, for example http://localhost:4318/v1/traces.
+	Endpoint string
+	// Headers are added to every export request.
+	Headers map[string]string
+	// ServiceName is the service.name resource attribute.
+	ServiceName string
+	// SampleRate is the fraction of traces which are recorded, between 0 and 1.
+	SampleRate float64
+}
+
+// Tracer records spans and exports them in batches with the OTLP/HTTP JSON
+// encoding. A nil *Tracer records nothing.
+type Tracer struct {
+	cfg    Config
+	client *http.Client
+	logger *log.Logger
+
+	mu      sync.Mutex
+	queue   []*Span
+	dropped int
+
+	flushCh  chan struct{}
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewTracer creates a Tracer and starts exporting spans in the background.
+// Call Shutdown to export the remaining spans.
+func NewTracer(cfg Config, logger *log.Logger) *Tracer {
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = DefaultServiceName
+	}
+	t := &Tracer{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		logger:  logger,
+		flushCh: make(chan struct{}, 1),
+		stopCh:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Start starts a new trace, which is sampled according to the sample rate.
+// It returns nil if the trace is not sampled.
+func (t *Tracer) Start(name string, start time.Time, attrs ...Attribute) *Span {
+	if t == nil || mrand.Float64() >= t.cfg.SampleRate {
+		return nil
+	}
+	s := &Span{
+		tracer:     t,
+		name:       name,
+		start:      start,
+		attributes: attrs,
+	}
+	randomID(s.traceID[:])
+	randomID(s.spanID[:])
+	return s
+}
+
+// Shutdown stops the background export and exports the remaining spans.
+// It is safe to call Shutdown more than once.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.stopOnce.Do(func() { close(t.stopCh) })
+	<-t.done
+	return t.export(ctx)
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stopCh:
+			return
+		case <-ticker.C:
+		case <-t.flushCh:
+		}
+		ctx, cf := context.WithTimeout(context.Background(), t.client.Timeout)
+		if err := t.export(ctx); err != nil {
+			t.logger.Warnf("could not export traces to %s: %v", t.cfg.Endpoint, err)
+		}
+		cf()
+	}
+}
+
+// enqueue adds an ended span to the next export.
+func (t *Tracer) enqueue(s *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.queue) >= maxQueueSize {
+		t.dropped++
+		return
+	}
+	t.queue = append(t.queue, s)
+	if len(t.queue) >= maxBatchSize {
+		select {
+		case t.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// export sends the queued spans. They are kept for the next attempt if the
+// request fails.
+func (t *Tracer) export(ctx context.Context) error {
+	t.mu.Lock()
+	spans := t.queue
+	dropped := t.dropped
+	t.queue = nil
+	t.dropped = 0
+	t.mu.Unlock()
+
+	if dropped > 0 {
+		t.logger.Warnf("dropped %d spans, the export queue was full", dropped)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	err := t.post(ctx, spans)
+	if err != nil {
+		t.mu.Lock()
+		if len(spans)+len(t.queue) <= maxQueueSize {
+			t.queue = append(spans, t.queue...)
+		} else {
+			t.dropped += len(spans)
+		}
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// Span is a timed operation within a trace. A nil *Span records nothing, so
+// callers do not need to check whether the trace was sampled.
+type Span struct {
+	tracer     *Tracer
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes []Attribute
+	err        error
+}
+
+// Child starts a span within the same trace.
+func (s *Span) Child(name string, start time.Time, attrs ...Attribute) *Span {
+	if s == nil {
+		return nil
+	}
+	child := &Span{
+		tracer:     s.tracer,
+		traceID:    s.traceID,
+		parentID:   s.spanID,
+		name:       name,
+		start:      start,
+		attributes: attrs,
+	}
+	randomID(child.spanID[:])
+	return child
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.attributes = append(s.attributes, attrs...)
+}
+
+// End ends the span now, err marks the span as failed if it is not nil.
+func (s *Span) End(err error) {
+	s.EndAt(time.Now(), err)
+}
+
+// EndAt ends the span at the given time, err marks the span as failed if it is not nil.
+func (s *Span) EndAt(end time.Time, err error) {
+	if s == nil {
+		return
+	}
+	s.end = end
+	s.err = err
+	s.tracer.enqueue(s)
+}
+
+// TraceID returns the hex encoded trace ID, or the empty string for a nil span.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", s.traceID)
+}
+
+func randomID(id []byte) {
+	// crypto/rand only fails if the system random source is unavailable.
+	_, _ = rand.Read(id)
+}
+
+// Attribute is a key value pair attached to a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Uint64 returns an integer attribute.
+func Uint64(key string, value uint64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collector is an OTLP/HTTP endpoint which records the exported spans.
+type collector struct {
+	mu      sync.Mutex
+	spans   []otlpSpan
+	headers []http.Header
+	fail    int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail > 0 {
+		c.fail--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var req otlpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.headers = append(c.headers, r.Header)
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+}
+
+func (c *collector) received() []otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]otlpSpan(nil), c.spans...)
+}
+
+func TestTracerExport(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+	logger, _ := test.NewNullLogger()
+
+	tracer := NewTracer(Config{
+		Endpoint:   server.URL,
+		Headers:    map[string]string{"Authorization": "Bearer token"},
+		SampleRate: 1,
+	}, logger)
+	start := time.Unix(100, 0)
+	root := tracer.Start("round", start, Uint64("conduit.round", 5))
+	require.NotNil(t, root)
+	child := root.Child("exporter.Receive", start.Add(time.Second), String("conduit.plugin.name", "noop"))
+	child.EndAt(start.Add(2*time.Second), errors.New("receive failed"))
+	root.SetAttributes(Bool("conduit.prefetched", false))
+	root.EndAt(start.Add(3*time.Second), nil)
+	require.NoError(t, tracer.Shutdown(context.Background()))
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := c.received()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "Bearer token", c.headers[0].Get("Authorization"))
+	assert.Equal(t, "application/json", c.headers[0].Get("Content-Type"))
+
+	assert.Equal(t, "exporter.Receive", spans[0].Name)
+	assert.Equal(t, root.TraceID(), spans[0].TraceID)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+	assert.Equal(t, otlpStatus{Code: statusCodeError, Message: "receive failed"}, spans[0].Status)
+	assert.Equal(t, "101000000000", spans[0].StartTimeUnixNano)
+	assert.Equal(t, "102000000000", spans[0].EndTimeUnixNano)
+
+	assert.Equal(t, "round", spans[1].Name)
+	assert.Len(t, spans[1].TraceID, 32)
+	assert.Len(t, spans[1].SpanID, 16)
+	assert.Empty(t, spans[1].ParentSpanID)
+	assert.Equal(t, otlpStatus{}, spans[1].Status)
+	require.Len(t, spans[1].Attributes, 2)
+	assert.Equal(t, "conduit.round", spans[1].Attributes[0].Key)
+	assert.Equal(t, "5", *spans[1].Attributes[0].Value.IntValue)
+	assert.Equal(t, false, *spans[1].Attributes[1].Value.BoolValue)
+}
+
+func TestTracerRetry(t *testing.T) {
+	c := &collector{fail: 1}
+	server := httptest.NewServer(c)
+	defer server.Close()
+	logger, _ := test.NewNullLogger()
+
+	tracer := NewTracer(Config{Endpoint: server.URL, SampleRate: 1}, logger)
+	tracer.Start("round", time.Now()).End(nil)
+	// the failed export keeps the spans for the next attempt
+	assert.Error(t, tracer.export(context.Background()))
+	require.NoError(t, tracer.Shutdown(context.Background()))
+	assert.Len(t, c.received(), 1)
+}
+
+func TestTracerSampling(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	tracer := NewTracer(Config{Endpoint: "http://localhost:0", SampleRate: 0}, logger)
+	defer tracer.Shutdown(context.Background())
+	span := tracer.Start("round", time.Now())
+	assert.Nil(t, span)
+
+	// nil tracers and spans record nothing
+	var nilTracer *Tracer
+	assert.Nil(t, nilTracer.Start("round", time.Now()))
+	assert.NoError(t, nilTracer.Shutdown(context.Background()))
+	assert.Nil(t, span.Child("child", time.Now()))
+	span.SetAttributes(String("key", "value"))
+	span.End(nil)
+	assert.Equal(t, "", span.TraceID())
+}
@@ -0,0 +1,84 @@
+package conduit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// meteredConn counts the bytes read from and written to a connection.
+type meteredConn struct {
+	net.Conn
+	destination string
+	meter       TrafficMeter
+}
+
+func (c *meteredConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.meter.Add(c.destination, uint64(n), 0)
+	}
+	return n, err
+}
+
+func (c *meteredConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.meter.Add(c.destination, 0, uint64(n))
+	}
+	return n, err
+}
+
+// MeteredConn counts the bytes of the connection as traffic with the
+// destination. The connection is returned unchanged if meter is nil.
+func MeteredConn(conn net.Conn, destination string, meter TrafficMeter) net.Conn {
+	if meter == nil {
+		return conn
+	}
+	return &meteredConn{Conn: conn, destination: destination, meter: meter}
+}
+
+// meteredListener counts the bytes of the connections it accepts.
+type meteredListener struct {
+	net.Listener
+	meter TrafficMeter
+}
+
+func (l *meteredListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return MeteredConn(conn, l.Addr().String(), l.meter), nil
+}
+
+// MeteredListener counts the bytes of the accepted connections as traffic
+// with the listen address, so that the number of clients does not grow the
+// number of destinations. The listener is returned unchanged if meter is nil.
+func MeteredListener(listener net.Listener, meter TrafficMeter) net.Listener {
+	if meter == nil {
+		return listener
+	}
+	return &meteredListener{Listener: listener, meter: meter}
+}
+
+// MeteredTransport returns an HTTP transport which counts the bytes of its
+// connections, including the TLS and HTTP framing, as traffic with the
+// host:port they were opened to. http.DefaultTransport is returned if meter
+// is nil.
+func MeteredTransport(meter TrafficMeter) http.RoundTripper {
+	if meter == nil {
+		return http.DefaultTransport
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return MeteredConn(conn, addr, meter), nil
+	}
+	return transport
+}
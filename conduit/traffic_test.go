@@ -0,0 +1,94 @@
+package conduit
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMeter records the traffic by destination.
+type recordingMeter struct {
+	mu  sync.Mutex
+	in  map[string]uint64
+	out map[string]uint64
+}
+
+func (m *recordingMeter) Add(destination string, in, out uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.in == nil {
+		m.in, m.out = make(map[string]uint64), make(map[string]uint64)
+	}
+	m.in[destination] += in
+	m.out[destination] += out
+}
+
+func (m *recordingMeter) get(destination string) (uint64, uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.in[destination], m.out[destination]
+}
+
+func TestMeteredConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	assert.Same(t, client, MeteredConn(client, "node:8080", nil))
+
+	meter := &recordingMeter{}
+	conn := MeteredConn(client, "node:8080", meter)
+	go func() {
+		buf := make([]byte, 5)
+		io.ReadFull(server, buf)
+		server.Write([]byte("abc"))
+	}()
+	_, err := conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 3)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	in, out := meter.get("node:8080")
+	assert.Equal(t, uint64(3), in)
+	assert.Equal(t, uint64(5), out)
+}
+
+func TestMeteredTransportAndListener(t *testing.T) {
+	body := strings.Repeat("x", 1000)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serverMeter := &recordingMeter{}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	server.Listener = MeteredListener(listener, serverMeter)
+	server.Start()
+	defer server.Close()
+	addr := listener.Addr().String()
+
+	clientMeter := &recordingMeter{}
+	client := &http.Client{Transport: MeteredTransport(clientMeter)}
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("ping"))
+	require.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, body, string(b))
+
+	// the counts include the HTTP framing.
+	in, out := clientMeter.get(addr)
+	assert.Greater(t, in, uint64(len(body)))
+	assert.Greater(t, out, uint64(len("ping")))
+	serverIn, serverOut := serverMeter.get(addr)
+	assert.Equal(t, out, serverIn)
+	assert.Equal(t, in, serverOut)
+
+	assert.Same(t, http.DefaultTransport, MeteredTransport(nil))
+}
@@ -0,0 +1,9 @@
+package conduit
+
+// WriterVersions are the versions of Conduit and of the plugins of the
+// pipeline which exported data.
+type WriterVersions struct {
+	Conduit string `json:"conduit"`
+	// Plugins maps "<plugin type>/<plugin name>" to the version of the plugin.
+	Plugins map[string]string `json:"plugins,omitempty"`
+}